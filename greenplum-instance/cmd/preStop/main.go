@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/blang/vfs"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = ctrllog.Log.WithName("preStop")
+
+func main() {
+	ctrllog.SetLogger(gplog.ForProd(false))
+	startContainerUtils.Log = log
+	preStop := &startContainerUtils.PreStop{
+		App: &starter.App{
+			Command:      exec.Command,
+			StdoutBuffer: os.Stdout,
+			StderrBuffer: os.Stderr,
+			Fs:           vfs.OS(),
+		},
+		Hostname: os.Hostname,
+	}
+	if err := preStop.Run(); err != nil {
+		log.Error(err, "failed to stop greenplum")
+		os.Exit(1)
+	}
+}
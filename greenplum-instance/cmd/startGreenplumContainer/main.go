@@ -16,6 +16,7 @@ import (
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/net/ssh/knownhosts"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/ubuntuUtils"
+	apiwait "k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -49,13 +50,22 @@ func main() {
 		KeyScanner:       keyscanner.NewSSHKeyScanner(),
 		KnownHostsReader: knownhosts.NewReader(),
 		C:                cluster,
+		PollWait:         apiwait.PollImmediate,
 	}
 	sshDaemon := &startContainerUtils.SSHDaemon{App: s}
+	statusDaemon := &startContainerUtils.StatusDaemon{App: s, Hostname: os.Hostname}
+	logShipperDaemon := &startContainerUtils.LogShipperDaemon{App: s, Hostname: os.Hostname}
+	logRetentionDaemon := &startContainerUtils.LogRetentionDaemon{
+		App:         s,
+		Config:      instanceconfig.NewReader(fs),
+		Hostname:    os.Hostname,
+		VolumeUsage: startContainerUtils.StatfsVolumeUsage,
+	}
 	containerStarter := startContainerUtils.GreenplumContainerStarter{
 		App:     s,
 		UID:     os.Getuid(),
 		Root:    &startContainerUtils.RootContainerStarter{App: s, Ubuntu: u},
-		Gpadmin: &startContainerUtils.GpadminContainerStarter{App: s},
+		Gpadmin: &startContainerUtils.GpadminContainerStarter{App: s, Config: instanceconfig.NewReader(fs)},
 		LabelPVC: &startContainerUtils.LabelPvcStarter{
 			App:      s,
 			Hostname: os.Hostname,
@@ -66,10 +76,14 @@ func main() {
 		MultidaemonStarter: &startContainerUtils.MultidaemonStarter{
 			Daemons: []multidaemon.DaemonFunc{
 				knownHostsController.Run,
+				logShipperDaemon.Run,
+				logRetentionDaemon.Run,
 				clusterInitDaemon.Run,
 				sshDaemon.Run,
+				statusDaemon.Run,
 			},
 		},
+		Prober: &startContainerUtils.PodProbe{App: s, Hostname: os.Hostname},
 	}
 
 	os.Exit(containerStarter.Run(os.Args))
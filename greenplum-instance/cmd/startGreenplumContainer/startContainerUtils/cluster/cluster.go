@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/blang/vfs"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
@@ -19,6 +20,9 @@ const (
 type ClusterInterface interface {
 	Initialize() error
 	GPStart() error
+	GetMasterState(host string) (MasterState, error)
+	GetLocalMasterState(host string) (MasterState, error)
+	ReinitializeStandby(master, standby string) error
 	RunPostInitialization() error
 }
 
@@ -154,6 +158,10 @@ func (c *Cluster) RunPostInitialization() error {
 		return fmt.Errorf("gpstop failed: %w", err)
 	}
 
+	if err := c.createTempTablespace(); err != nil {
+		return fmt.Errorf("createTempTablespace failed: %w", err)
+	}
+
 	if err := c.createPXFExtension(); err != nil {
 		return fmt.Errorf("createPXFExtension failed: %w", err)
 	}
@@ -169,6 +177,36 @@ func (c *Cluster) reloadGpConfig() error {
 	return cmd.Run()
 }
 
+// createTempTablespace creates the tablespace named by temp_tablespaces, which gpinitsystem
+// configured before the tablespace could exist.
+func (c *Cluster) createTempTablespace() error {
+	name, err := c.Config.GetTempTablespace()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+
+	cmd := c.greenplumCommand.Command("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-tAc",
+		fmt.Sprintf("SELECT count(*) FROM pg_tablespace WHERE spcname = '%s'", name))
+	cmd.Stderr = c.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(out)) != "0" {
+		return nil
+	}
+
+	PrintMessage(c.Stdout, "Creating temp tablespace "+name)
+	cmd = c.greenplumCommand.Command("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-c",
+		fmt.Sprintf("CREATE TABLESPACE %s LOCATION '%s'", name, instanceconfig.TablespaceLocation(name)))
+	cmd.Stderr = c.Stderr
+	cmd.Stdout = c.Stdout
+	return cmd.Run()
+}
+
 func (c *Cluster) createPXFExtension() error {
 	pxfServiceName, err := c.Config.GetPXFServiceName()
 	if err != nil {
@@ -390,6 +390,47 @@ var _ = Describe("cluster", func() {
 				Expect(exitErr).To(MatchError("createPXFExtension failed: exit status 1"))
 			})
 		})
+		When("a temp tablespace is configured", func() {
+			const countTablespaces = "SELECT count(*) FROM pg_tablespace WHERE spcname = 'scratch'"
+			const createTablespace = "CREATE TABLESPACE scratch LOCATION '/greenplum/volumes/scratch/tablespace'"
+			BeforeEach(func() {
+				mockConfig.TempTablespace = "scratch"
+			})
+			It("creates the tablespace when it does not exist", func() {
+				createTablespaceCount := 0
+				envs := make(chan []string, 1)
+				cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-tAc", countTablespaces).
+					PrintsOutput("0\n")
+				cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-c", createTablespace).
+					CallCounter(&createTablespaceCount).SendEnvironment(envs)
+				Expect(c.RunPostInitialization()).To(Succeed())
+				Expect(outBuffer).To(gbytes.Say("Creating temp tablespace scratch"))
+				Expect(createTablespaceCount).To(Equal(1))
+
+				var env []string
+				Expect(envs).To(Receive(&env))
+				Expect(env).To(ContainGreenplumEnvironment)
+			})
+			It("does not create the tablespace when it already exists", func() {
+				createTablespaceCount := 0
+				cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-tAc", countTablespaces).
+					PrintsOutput("1\n")
+				cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-c", createTablespace).
+					CallCounter(&createTablespaceCount)
+				Expect(c.RunPostInitialization()).To(Succeed())
+				Expect(createTablespaceCount).To(Equal(0))
+			})
+			It("returns an error when creating the tablespace fails", func() {
+				cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-tAc", countTablespaces).
+					PrintsOutput("0\n")
+				cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-c", createTablespace).
+					ReturnsStatus(1).
+					PrintsError("directory does not exist")
+				exitErr = c.RunPostInitialization()
+				Expect(errBuffer).To(gbytes.Say("directory does not exist"))
+				Expect(exitErr).To(MatchError("createTempTablespace failed: exit status 1"))
+			})
+		})
 		It("does not create PXF extension when PXF_HOST is not set", func() {
 			createExtensionCount := 0
 			cmdFake.ExpectCommand("/usr/local/greenplum-db/bin/psql", "-U", "gpadmin", "-d", "gpadmin", "-c", "CREATE EXTENSION IF NOT EXISTS pxf").
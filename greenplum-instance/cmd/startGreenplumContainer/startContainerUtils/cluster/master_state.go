@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MasterState is what the data directory of a master says about its role
+type MasterState struct {
+	Host        string
+	Initialized bool
+	// InRecovery is true for a standby master
+	InRecovery bool
+	TimeLineID int
+	Running    bool
+}
+
+// masterStateScript prints the pg_controldata of the master data directory,
+// followed by a line saying whether the postmaster is running. It prints
+// nothing if the data directory has not been initialized.
+const masterStateScript = `[ -f /greenplum/data-1/PG_VERSION ] || exit 0
+source /usr/local/greenplum-db/greenplum_path.sh
+pg_controldata /greenplum/data-1 || exit 1
+pg_ctl status -D /greenplum/data-1 >/dev/null && echo "Postmaster running: yes" || true
+`
+
+// GetMasterState reads the state of the master on another host
+func (c *Cluster) GetMasterState(host string) (MasterState, error) {
+	return c.masterState(host, c.Command("/usr/bin/ssh", host, "bash", "-s"))
+}
+
+// GetLocalMasterState reads the state of the master on this host, which is named host
+func (c *Cluster) GetLocalMasterState(host string) (MasterState, error) {
+	return c.masterState(host, c.greenplumCommand.Command("/bin/bash", "-s"))
+}
+
+func (c *Cluster) masterState(host string, cmd *exec.Cmd) (MasterState, error) {
+	cmd.Stdin = strings.NewReader(masterStateScript)
+	cmd.Stderr = c.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return MasterState{}, fmt.Errorf("reading the state of %s failed: %w", host, err)
+	}
+
+	state := MasterState{Host: host}
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Database cluster state":
+			state.Initialized = true
+			state.InRecovery = value == "in archive recovery" || value == "shut down in recovery"
+		case "Latest checkpoint's TimeLineID":
+			state.TimeLineID, err = strconv.Atoi(value)
+			if err != nil {
+				return MasterState{}, fmt.Errorf("unexpected TimeLineID of %s: %q", host, value)
+			}
+		case "Postmaster running":
+			state.Running = value == "yes"
+		}
+	}
+	return state, nil
+}
+
+// LastActiveMaster picks the master that was active most recently: a master that
+// is not a standby, on the latest timeline. gpactivatestandby starts a new timeline,
+// so a master that was failed over from stays on an older one. It returns false
+// when no master qualifies, or when two masters cannot be told apart.
+func LastActiveMaster(states []MasterState) (MasterState, bool) {
+	var active MasterState
+	ambiguous := false
+	for _, state := range states {
+		if !state.Initialized || state.InRecovery {
+			continue
+		}
+		switch {
+		case active.Host == "" || state.TimeLineID > active.TimeLineID:
+			active = state
+			ambiguous = false
+		case state.TimeLineID == active.TimeLineID:
+			ambiguous = true
+		}
+	}
+	if active.Host == "" || ambiguous {
+		return MasterState{}, false
+	}
+	return active, true
+}
+
+// ReinitializeStandby turns a master that was failed over from into the standby of
+// the active master. Its old data directory is kept next to the new one, replacing the
+// one kept by an earlier failover, so that the volume does not fill up with old copies.
+func (c *Cluster) ReinitializeStandby(master, standby string) error {
+	PrintMessage(c.Stdout, "Re-initializing "+standby+" as the standby of "+master)
+	cmd := c.Command("/usr/bin/ssh", standby,
+		"rm -rf "+masterDataDir+".stale-* && mv "+masterDataDir+" "+masterDataDir+".stale-$(date +%Y%m%d%H%M%S)")
+	cmd.Stderr = c.Stderr
+	cmd.Stdout = c.Stdout
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("moving the data directory of %s failed: %w", standby, err)
+	}
+
+	cmd = c.Command("/usr/bin/ssh", master,
+		"export MASTER_DATA_DIRECTORY="+masterDataDir+" && source /usr/local/greenplum-db/greenplum_path.sh && "+
+			"gpinitstandby -a -s "+standby)
+	cmd.Stderr = c.Stderr
+	cmd.Stdout = c.Stdout
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gpinitstandby on %s failed: %w", master, err)
+	}
+	return nil
+}
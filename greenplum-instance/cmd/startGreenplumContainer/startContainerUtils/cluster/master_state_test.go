@@ -0,0 +1,124 @@
+package cluster_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils/cluster"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
+)
+
+var _ = Describe("LastActiveMaster", func() {
+	It("picks the master that is not a standby", func() {
+		active, ok := cluster.LastActiveMaster([]cluster.MasterState{
+			{Host: "master-0", Initialized: true, TimeLineID: 1},
+			{Host: "master-1", Initialized: true, InRecovery: true, TimeLineID: 1},
+		})
+		Expect(ok).To(BeTrue())
+		Expect(active.Host).To(Equal("master-0"))
+	})
+
+	It("picks the master on the latest timeline after a failover", func() {
+		active, ok := cluster.LastActiveMaster([]cluster.MasterState{
+			{Host: "master-0", Initialized: true, TimeLineID: 1},
+			{Host: "master-1", Initialized: true, TimeLineID: 2},
+		})
+		Expect(ok).To(BeTrue())
+		Expect(active.Host).To(Equal("master-1"))
+	})
+
+	It("ignores a master that is not initialized", func() {
+		active, ok := cluster.LastActiveMaster([]cluster.MasterState{
+			{Host: "master-0", Initialized: true, TimeLineID: 1},
+			{Host: "master-1"},
+		})
+		Expect(ok).To(BeTrue())
+		Expect(active.Host).To(Equal("master-0"))
+	})
+
+	It("cannot tell two masters on the same timeline apart", func() {
+		_, ok := cluster.LastActiveMaster([]cluster.MasterState{
+			{Host: "master-0", Initialized: true, TimeLineID: 2},
+			{Host: "master-1", Initialized: true, TimeLineID: 2},
+		})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("finds no master among standbys", func() {
+		_, ok := cluster.LastActiveMaster([]cluster.MasterState{
+			{Host: "master-0", Initialized: true, InRecovery: true, TimeLineID: 1},
+		})
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("master state", func() {
+	var (
+		c         *cluster.Cluster
+		cmdFake   *commandable.CommandFake
+		outBuffer *gbytes.Buffer
+	)
+	BeforeEach(func() {
+		cmdFake = commandable.NewFakeCommand()
+		outBuffer = gbytes.NewBuffer()
+		c = cluster.New(nil, cmdFake.Command, outBuffer, outBuffer, nil, nil)
+	})
+
+	Describe("GetMasterState", func() {
+		It("reads pg_controldata of the master on the host", func() {
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-1", "bash", "-s").PrintsOutput(strings.Join([]string{
+				"pg_control version number:            9420600",
+				"Database cluster state:               in production",
+				"Latest checkpoint's TimeLineID:       2",
+				"Postmaster running: yes",
+			}, "\n"))
+			Expect(c.GetMasterState("master-1")).To(Equal(cluster.MasterState{
+				Host: "master-1", Initialized: true, TimeLineID: 2, Running: true,
+			}))
+		})
+		It("recognizes a standby", func() {
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-1", "bash", "-s").PrintsOutput(strings.Join([]string{
+				"Database cluster state:               shut down in recovery",
+				"Latest checkpoint's TimeLineID:       1",
+			}, "\n"))
+			Expect(c.GetMasterState("master-1")).To(Equal(cluster.MasterState{
+				Host: "master-1", Initialized: true, InRecovery: true, TimeLineID: 1,
+			}))
+		})
+		It("reports an uninitialized data directory", func() {
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-1", "bash", "-s")
+			Expect(c.GetMasterState("master-1")).To(Equal(cluster.MasterState{Host: "master-1"}))
+		})
+		It("returns an error when the host cannot be reached", func() {
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-1", "bash", "-s").ReturnsStatus(255)
+			_, err := c.GetMasterState("master-1")
+			Expect(err).To(MatchError("reading the state of master-1 failed: exit status 255"))
+		})
+	})
+
+	Describe("ReinitializeStandby", func() {
+		It("replaces the old data directory kept by an earlier failover, and runs gpinitstandby on the active master", func() {
+			var moved, initialized int
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-0",
+				"rm -rf /greenplum/data-1.stale-* && mv /greenplum/data-1 /greenplum/data-1.stale-$(date +%Y%m%d%H%M%S)").CallCounter(&moved)
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-1",
+				"export MASTER_DATA_DIRECTORY=/greenplum/data-1 && source /usr/local/greenplum-db/greenplum_path.sh && gpinitstandby -a -s master-0").
+				CallCounter(&initialized)
+			Expect(c.ReinitializeStandby("master-1", "master-0")).To(Succeed())
+			Expect(moved).To(Equal(1))
+			Expect(initialized).To(Equal(1))
+		})
+		It("does not run gpinitstandby when the data directory cannot be moved", func() {
+			var initialized int
+			cmdFake.ExpectCommand("/usr/bin/ssh", "master-0",
+				"rm -rf /greenplum/data-1.stale-* && mv /greenplum/data-1 /greenplum/data-1.stale-$(date +%Y%m%d%H%M%S)").ReturnsStatus(1)
+			cmdFake.ExpectCommandMatching(func(path string, args ...string) bool {
+				return path == "/usr/bin/ssh" && args[0] == "master-1"
+			}).CallCounter(&initialized)
+			Expect(c.ReinitializeStandby("master-1", "master-0")).To(MatchError("moving the data directory of master-0 failed: exit status 1"))
+			Expect(initialized).To(BeZero())
+		})
+	})
+})
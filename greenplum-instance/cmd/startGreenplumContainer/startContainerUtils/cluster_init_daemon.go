@@ -2,23 +2,38 @@ package startContainerUtils
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/blang/vfs"
 
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils/cluster"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/fileutil"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/net"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/net/multihost"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/net/ssh/keyscanner"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/net/ssh/knownhosts"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/poll"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/ubuntuUtils"
 )
 
-const knownHostsFilename = "/home/gpadmin/.ssh/known_hosts"
+const (
+	knownHostsFilename = "/home/gpadmin/.ssh/known_hosts"
+	// masterStartupFilename holds the gpstatus.MasterStartup of the last start of a master with a standby.
+	// It is next to the data directory, so that it outlives the pod.
+	masterStartupFilename = "/greenplum/master-startup.json"
+
+	// The other master may still be starting when this master starts, so its state is read until masterStateTimeout
+	masterStateRetryInterval = 5 * time.Second
+	masterStateTimeout       = 5 * time.Minute
+)
 
 type ClusterInitDaemon struct {
 	*starter.App
@@ -28,6 +43,7 @@ type ClusterInitDaemon struct {
 	KeyScanner       keyscanner.SSHKeyScannerInterface
 	KnownHostsReader knownhosts.ReaderInterface
 	C                cluster.ClusterInterface
+	PollWait         poll.PollFunc
 }
 
 func (s *ClusterInitDaemon) Run(_ context.Context) error {
@@ -76,7 +92,7 @@ func (s *ClusterInitDaemon) NewPostgresInitializer(hostname string) PostgresInit
 			dataDir:        "/greenplum/data-1",
 		}}
 	case "master-1":
-		return &segmentPostgresInitializer{postgresInitializer{
+		return &standbyMasterPostgresInitializer{postgresInitializer{
 			clusterStarter: s,
 			hostname:       hostname,
 			dataDir:        "/greenplum/data-1",
@@ -94,26 +110,41 @@ type postgresInitializer struct {
 	clusterStarter *ClusterInitDaemon
 	hostname       string
 	dataDir        string
+	// hostsResolved is set once the DNS entries of all hosts resolve
+	hostsResolved bool
 }
 
 type masterPostgresInitializer struct {
 	postgresInitializer
 }
 
+// standbyMasterPostgresInitializer runs on master-1, which gpinitsystem creates as the standby
+// master. It may have become the active master since.
+type standbyMasterPostgresInitializer struct {
+	postgresInitializer
+}
+
 type segmentPostgresInitializer struct {
 	postgresInitializer
 }
 
 var _ PostgresInitializer = &masterPostgresInitializer{}
 
-func (i *masterPostgresInitializer) InitializePostgres() error {
-	dnsDomainCommand := i.clusterStarter.Command("dnsdomainname")
+func (s *ClusterInitDaemon) dnsSuffix() (string, error) {
+	dnsDomainCommand := s.Command("dnsdomainname")
 	dnsSuffixBytes, err := dnsDomainCommand.Output()
+	if err != nil {
+		return "", err
+	}
+	return "." + strings.TrimSuffix(string(dnsSuffixBytes), "\n"), nil
+}
+
+func (i *masterPostgresInitializer) InitializePostgres() error {
+	dnsSuffix, err := i.clusterStarter.dnsSuffix()
 	if err != nil {
 		Log.Error(err, "scanning for segment host keys: dnsdomainname failed to determine this host's dns name")
 		return err
 	}
-	dnsSuffix := "." + strings.TrimSuffix(string(dnsSuffixBytes), "\n")
 
 	config, err := i.clusterStarter.Config.GetConfigValues()
 	if err != nil {
@@ -135,6 +166,7 @@ func (i *masterPostgresInitializer) InitializePostgres() error {
 		Log.Error(err, "error setting up passwordless SSH")
 		return err
 	}
+	i.hostsResolved = true
 
 	if i.CheckPreinitalizedCluster() {
 		Log.Info("cluster has been initialized before; starting Greenplum Cluster")
@@ -153,12 +185,155 @@ func (i *masterPostgresInitializer) InitializePostgres() error {
 
 func (i *masterPostgresInitializer) checkAndRunGPStart(hasStandby bool) error {
 	if hasStandby {
-		Log.Info("Automatic gpstart is not currently supported with standby masters. Skipping.")
-		return nil
+		return i.startMasterWithStandby()
 	}
 	return i.clusterStarter.C.GPStart()
 }
 
+var _ PostgresInitializer = &standbyMasterPostgresInitializer{}
+
+func (i *standbyMasterPostgresInitializer) InitializePostgres() error {
+	if i.CheckPreinitalizedCluster() {
+		Log.Info("cluster has been initialized before; starting Postgres")
+		return i.startMasterWithStandby()
+	}
+	return nil
+}
+
+// startMasterWithStandby starts this master according to the role its data directory
+// had when it stopped, and records the decision in masterStartupFilename for the status
+// server. A standby starts on its own. Of two masters, only the one that was active last
+// starts the cluster; the other was failed over from, and becomes the new standby.
+func (i *postgresInitializer) startMasterWithStandby() error {
+	decision, message, err := i.restartMaster()
+	if err != nil {
+		decision, message = gpstatus.StartupFailed, err.Error()
+	}
+	if decision != "" {
+		i.writeMasterStartup(decision, message)
+	}
+	return err
+}
+
+func (i *postgresInitializer) restartMaster() (gpstatus.MasterStartupDecision, string, error) {
+	c := i.clusterStarter.C
+	self, err := c.GetLocalMasterState(i.hostname)
+	if err != nil {
+		return "", "", err
+	}
+	if !self.Initialized {
+		Log.Info("master data directory is not initialized; not starting Postgres")
+		return "", "", nil
+	}
+	if self.InRecovery {
+		Log.Info("starting standby master")
+		if err := i.pgCtlRestart(); err != nil {
+			return "", "", err
+		}
+		return gpstatus.StartedStandby, i.hostname + " started as the standby master", nil
+	}
+
+	peer, err := i.otherMasterState()
+	if err != nil {
+		return "", "", err
+	}
+	active, ok := cluster.LastActiveMaster([]cluster.MasterState{self, peer})
+	if !ok {
+		Log.Info("unable to determine which master was active last; start the cluster manually",
+			"timeLineID", self.TimeLineID, "otherMaster", peer.Host, "otherTimeLineID", peer.TimeLineID)
+		return gpstatus.Ambiguous, fmt.Sprintf("unable to determine which master was active last: "+
+			"%s and %s are both on timeline %d; start the cluster manually", i.hostname, peer.Host, self.TimeLineID), nil
+	}
+
+	if active.Host == i.hostname {
+		Log.Info("this master was active last; starting Greenplum Cluster")
+		if err := c.GPStart(); err != nil {
+			return "", "", err
+		}
+		if peer.Initialized && !peer.InRecovery && !peer.Running {
+			Log.Info("re-initializing the master that was failed over from as the standby", "standby", peer.Host)
+			if err := c.ReinitializeStandby(i.hostname, peer.Host); err != nil {
+				return "", "", err
+			}
+			return gpstatus.ReinitializedStandby, fmt.Sprintf("%s started the cluster, and re-initialized %s "+
+				"that was failed over from as the standby", i.hostname, peer.Host), nil
+		}
+		return gpstatus.StartedCluster, i.hostname + " was active last, and started the cluster", nil
+	}
+
+	if !active.Running {
+		Log.Info("this master was failed over from; it becomes the standby once the active master starts",
+			"activeMaster", active.Host)
+		return gpstatus.WaitingForActiveMaster, fmt.Sprintf("%s was failed over from; it becomes the standby "+
+			"once %s starts", i.hostname, active.Host), nil
+	}
+	Log.Info("this master was failed over from; re-initializing it as the standby", "activeMaster", active.Host)
+	if err := c.ReinitializeStandby(active.Host, i.hostname); err != nil {
+		return "", "", err
+	}
+	return gpstatus.ReinitializedStandby, fmt.Sprintf("%s was failed over from, and was re-initialized "+
+		"as the standby of %s", i.hostname, active.Host), nil
+}
+
+// otherMasterState reads the state of the other master. Like master-0 before it starts the
+// cluster, it waits for the DNS entry of the other master first. The other master may still be
+// starting, so reading its state is retried until masterStateTimeout.
+func (i *postgresInitializer) otherMasterState() (cluster.MasterState, error) {
+	host := otherMaster(i.hostname)
+	if !i.hostsResolved {
+		dnsSuffix, err := i.clusterStarter.dnsSuffix()
+		if err != nil {
+			Log.Error(err, "dnsdomainname failed to determine this host's dns name")
+			return cluster.MasterState{}, err
+		}
+		Log.Info("resolving DNS entry for the other master", "host", host)
+		if err := i.clusterStarter.DNSResolver.Execute(host + dnsSuffix); err != nil {
+			return cluster.MasterState{}, fmt.Errorf("failed to resolve dns entry for %s: %w", host, err)
+		}
+	}
+
+	var state cluster.MasterState
+	var stateErr error
+	pollErr := i.clusterStarter.PollWait(masterStateRetryInterval, masterStateTimeout, func() (bool, error) {
+		state, stateErr = i.clusterStarter.C.GetMasterState(host)
+		if stateErr != nil {
+			Log.Info("unable to read the state of the other master; retrying", "host", host, "error", stateErr.Error())
+			return false, nil
+		}
+		return true, nil
+	})
+	if pollErr != nil {
+		if stateErr != nil {
+			return cluster.MasterState{}, stateErr
+		}
+		return cluster.MasterState{}, pollErr
+	}
+	return state, nil
+}
+
+// writeMasterStartup records the decision of startMasterWithStandby. A failure is only
+// logged, since the master has been started or not regardless.
+func (i *postgresInitializer) writeMasterStartup(decision gpstatus.MasterStartupDecision, message string) {
+	startup, err := json.Marshal(gpstatus.MasterStartup{
+		Decision: decision,
+		Message:  message,
+		Time:     time.Now().UTC().Truncate(time.Second),
+	})
+	if err == nil {
+		err = vfs.WriteFile(i.clusterStarter.Fs, masterStartupFilename, startup, 0644)
+	}
+	if err != nil {
+		Log.Error(err, "failed to record the master startup", "decision", decision)
+	}
+}
+
+func otherMaster(master string) string {
+	if master == "master-1" {
+		return "master-0"
+	}
+	return "master-1"
+}
+
 var _ PostgresInitializer = &segmentPostgresInitializer{}
 
 func (i *segmentPostgresInitializer) InitializePostgres() error {
@@ -2,8 +2,10 @@ package startContainerUtils_test
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"os"
+	"time"
 
 	"github.com/blang/vfs"
 	"github.com/blang/vfs/memfs"
@@ -15,11 +17,13 @@ import (
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/fileutil"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	instanceconfigTesting "github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig/testing"
 	fakemultihost "github.com/pivotal/greenplum-for-kubernetes/pkg/net/multihost/testing"
 	fakessh "github.com/pivotal/greenplum-for-kubernetes/pkg/net/ssh/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
 	ubuntuTesting "github.com/pivotal/greenplum-for-kubernetes/pkg/ubuntuUtils/testing"
+	apiwait "k8s.io/apimachinery/pkg/util/wait"
 )
 
 var _ = Describe("ClusterInitDaemon", func() {
@@ -69,9 +73,20 @@ var _ = Describe("ClusterInitDaemon", func() {
 			KeyScanner:       keyScanner,
 			KnownHostsReader: knownHostsReader,
 			C:                c,
+			PollWait: func(_, _ time.Duration, condition apiwait.ConditionFunc) error {
+				return apiwait.PollImmediate(time.Millisecond, 20*time.Millisecond, condition)
+			},
 		}
 	})
 
+	readMasterStartup := func() gpstatus.MasterStartup {
+		contents, err := vfs.ReadFile(memoryfs, "/greenplum/master-startup.json")
+		Expect(err).NotTo(HaveOccurred())
+		var startup gpstatus.MasterStartup
+		Expect(json.Unmarshal(contents, &startup)).To(Succeed())
+		return startup
+	}
+
 	Describe("InitializeCluster", func() {
 		When("obtaining the container hostname fails", func() {
 			BeforeEach(func() {
@@ -186,16 +201,141 @@ var _ = Describe("ClusterInitDaemon", func() {
 				When("standby is ON", func() {
 					BeforeEach(func() {
 						mockConfig.Standby = true
+						c.masterStates = map[string]cluster.MasterState{
+							"master-0": {Initialized: true, TimeLineID: 1},
+							"master-1": {Initialized: true, InRecovery: true, TimeLineID: 1},
+						}
 					})
 
-					It("does not invoke gpstart", func() {
+					It("invokes gpstart, since this master was active last", func() {
 						exitErr := app.InitializeCluster()
-						Expect(c.gpstartStub.wasCalled).To(BeFalse())
 						Expect(exitErr).NotTo(HaveOccurred())
-						Expect(outBuffer).To(gbytes.Say("Automatic gpstart is not currently supported with standby masters. Skipping."))
+						Expect(c.gpstartStub.wasCalled).To(BeTrue())
+						Expect(c.reinitializeStub.standby).To(BeEmpty())
+						Expect(outBuffer).To(gbytes.Say("this master was active last; starting Greenplum Cluster"))
+					})
+
+					It("records that it started the cluster", func() {
+						Expect(app.InitializeCluster()).To(Succeed())
+						startup := readMasterStartup()
+						Expect(startup.Decision).To(Equal(gpstatus.StartedCluster))
+						Expect(startup.Message).To(Equal("master-0 was active last, and started the cluster"))
+						Expect(startup.Time).To(BeTemporally("~", time.Now(), time.Minute))
+					})
+
+					It("does not resolve master-1 again, since all hosts were resolved before", func() {
+						Expect(app.InitializeCluster()).To(Succeed())
+						resolved := map[string]bool{}
+						for _, host := range fakeDNSResolver.HostRecords {
+							Expect(resolved).NotTo(HaveKey(host))
+							resolved[host] = true
+						}
 					})
 
 					It("should run post initialization", ShouldRunPostInitialization)
+
+					When("master-1 was failed over from", func() {
+						BeforeEach(func() {
+							c.masterStates["master-0"] = cluster.MasterState{Initialized: true, TimeLineID: 2}
+							c.masterStates["master-1"] = cluster.MasterState{Initialized: true, TimeLineID: 1}
+						})
+						It("invokes gpstart and re-initializes master-1 as the standby", func() {
+							Expect(app.InitializeCluster()).To(Succeed())
+							Expect(c.gpstartStub.wasCalled).To(BeTrue())
+							Expect(c.reinitializeStub.master).To(Equal("master-0"))
+							Expect(c.reinitializeStub.standby).To(Equal("master-1"))
+							startup := readMasterStartup()
+							Expect(startup.Decision).To(Equal(gpstatus.ReinitializedStandby))
+							Expect(startup.Message).To(Equal("master-0 started the cluster, and re-initialized master-1 that was failed over from as the standby"))
+						})
+					})
+
+					When("master-0 was failed over from", func() {
+						BeforeEach(func() {
+							c.masterStates["master-1"] = cluster.MasterState{Initialized: true, TimeLineID: 2, Running: true}
+						})
+						It("does not invoke gpstart, and re-initializes master-0 as the standby of master-1", func() {
+							Expect(app.InitializeCluster()).To(Succeed())
+							Expect(c.gpstartStub.wasCalled).To(BeFalse())
+							Expect(c.reinitializeStub.master).To(Equal("master-1"))
+							Expect(c.reinitializeStub.standby).To(Equal("master-0"))
+							Expect(outBuffer).To(gbytes.Say("this master was failed over from; re-initializing it as the standby"))
+							startup := readMasterStartup()
+							Expect(startup.Decision).To(Equal(gpstatus.ReinitializedStandby))
+							Expect(startup.Message).To(Equal("master-0 was failed over from, and was re-initialized as the standby of master-1"))
+						})
+
+						When("master-1 is not running yet", func() {
+							BeforeEach(func() {
+								c.masterStates["master-1"] = cluster.MasterState{Initialized: true, TimeLineID: 2}
+							})
+							It("leaves re-initializing to master-1", func() {
+								Expect(app.InitializeCluster()).To(Succeed())
+								Expect(c.gpstartStub.wasCalled).To(BeFalse())
+								Expect(c.reinitializeStub.standby).To(BeEmpty())
+								Expect(outBuffer).To(gbytes.Say("it becomes the standby once the active master starts"))
+								Expect(readMasterStartup().Decision).To(Equal(gpstatus.WaitingForActiveMaster))
+							})
+						})
+					})
+
+					When("both masters are on the same timeline", func() {
+						BeforeEach(func() {
+							c.masterStates["master-1"] = cluster.MasterState{Initialized: true, TimeLineID: 1}
+						})
+						It("does not invoke gpstart", func() {
+							Expect(app.InitializeCluster()).To(Succeed())
+							Expect(c.gpstartStub.wasCalled).To(BeFalse())
+							Expect(c.reinitializeStub.standby).To(BeEmpty())
+							Expect(outBuffer).To(gbytes.Say("unable to determine which master was active last; start the cluster manually"))
+							startup := readMasterStartup()
+							Expect(startup.Decision).To(Equal(gpstatus.Ambiguous))
+							Expect(startup.Message).To(Equal("unable to determine which master was active last: " +
+								"master-0 and master-1 are both on timeline 1; start the cluster manually"))
+						})
+					})
+
+					When("the state of master-1 cannot be read", func() {
+						BeforeEach(func() {
+							c.masterStateErrors = map[string]error{"master-1": errors.New("reading the state of master-1 failed")}
+						})
+						It("returns an error", func() {
+							Expect(app.InitializeCluster()).To(MatchError("reading the state of master-1 failed"))
+							Expect(c.gpstartStub.wasCalled).To(BeFalse())
+						})
+						It("records the failure", func() {
+							Expect(app.InitializeCluster()).NotTo(Succeed())
+							startup := readMasterStartup()
+							Expect(startup.Decision).To(Equal(gpstatus.StartupFailed))
+							Expect(startup.Message).To(Equal("reading the state of master-1 failed"))
+						})
+						When("master-1 becomes reachable", func() {
+							BeforeEach(func() {
+								c.masterStateFailures = 3
+							})
+							It("retries reading its state", func() {
+								Expect(app.InitializeCluster()).To(Succeed())
+								Expect(c.gpstartStub.wasCalled).To(BeTrue())
+								Expect(outBuffer).To(gbytes.Say("unable to read the state of the other master; retrying"))
+							})
+						})
+					})
+
+					When("master-0 is the standby", func() {
+						BeforeEach(func() {
+							c.masterStates["master-0"] = cluster.MasterState{Initialized: true, InRecovery: true, TimeLineID: 2}
+						})
+						It("does not invoke gpstart", func() {
+							fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", masterPgctlArgs...)
+							Expect(app.InitializeCluster()).To(Succeed())
+							Expect(c.gpstartStub.wasCalled).To(BeFalse())
+						})
+						It("runs pg_ctl to start the standby", ShouldRunPgCtl(masterPgctlArgs))
+						It("records that it started as the standby", func() {
+							Expect(app.InitializeCluster()).To(Succeed())
+							Expect(readMasterStartup().Decision).To(Equal(gpstatus.StartedStandby))
+						})
+					})
 				})
 			})
 
@@ -235,6 +375,10 @@ var _ = Describe("ClusterInitDaemon", func() {
 		When("hostname is master-1", func() {
 			BeforeEach(func() {
 				mockUbuntu.HostnameMock.Hostname = "master-1"
+				c.masterStates = map[string]cluster.MasterState{
+					"master-0": {Initialized: true, TimeLineID: 1},
+					"master-1": {Initialized: true, InRecovery: true, TimeLineID: 1},
+				}
 			})
 			When("/greenplum/data-1 exists", func() {
 				BeforeEach(func() {
@@ -263,6 +407,37 @@ var _ = Describe("ClusterInitDaemon", func() {
 					})
 					It("logs error", ShouldFailOnPgCtlError)
 				})
+
+				When("master-1 was active last", func() {
+					BeforeEach(func() {
+						c.masterStates["master-1"] = cluster.MasterState{Initialized: true, TimeLineID: 2}
+					})
+					It("invokes gpstart", func() {
+						Expect(app.InitializeCluster()).To(Succeed())
+						Expect(c.gpstartStub.wasCalled).To(BeTrue())
+						Expect(c.reinitializeStub.master).To(Equal("master-1"))
+						Expect(c.reinitializeStub.standby).To(Equal("master-0"))
+					})
+					It("does not run pg_ctl", ShouldNotRunPgCtl)
+					It("waits for the DNS entry of master-0 before it reads its state", func() {
+						fakeCmd.ExpectCommand("dnsdomainname").PrintsOutput("myheadlessservice.mynamespace.svc.cluster.local")
+						Expect(app.InitializeCluster()).To(Succeed())
+						Expect(fakeDNSResolver.HostRecords).To(Equal([]string{"master-0.myheadlessservice.mynamespace.svc.cluster.local"}))
+					})
+					When("the DNS entry of master-0 does not resolve", func() {
+						BeforeEach(func() {
+							fakeCmd.ExpectCommand("dnsdomainname").PrintsOutput("myheadlessservice.mynamespace.svc.cluster.local")
+							fakeDNSResolver.FakeErrors = map[string]error{
+								"master-0.myheadlessservice.mynamespace.svc.cluster.local": errors.New("dns failure"),
+							}
+						})
+						It("does not start the cluster", func() {
+							Expect(app.InitializeCluster()).To(MatchError("failed to resolve dns entry for master-0: dns failure"))
+							Expect(c.gpstartStub.wasCalled).To(BeFalse())
+							Expect(readMasterStartup().Decision).To(Equal(gpstatus.StartupFailed))
+						})
+					})
+				})
 			})
 			When("/greenplum/data-1 does not exist", func() {
 				It("succeeds", func() {
@@ -407,6 +582,15 @@ type fakeCluster struct {
 		wasCalled bool
 		err       error
 	}
+	masterStates      map[string]cluster.MasterState
+	masterStateErrors map[string]error
+	// masterStateFailures is how many times reading the state of another master fails with masterStateErrors
+	// before it succeeds. Zero fails every time.
+	masterStateFailures int
+	reinitializeStub    struct {
+		master, standby string
+		err             error
+	}
 }
 
 var _ cluster.ClusterInterface = &fakeCluster{}
@@ -425,3 +609,32 @@ func (c *fakeCluster) RunPostInitialization() error {
 	c.runPostInitializationStub.wasCalled = true
 	return c.runPostInitializationStub.err
 }
+
+func (c *fakeCluster) GetMasterState(host string) (cluster.MasterState, error) {
+	if c.masterStateFailures > 0 {
+		c.masterStateFailures--
+		if c.masterStateFailures == 0 {
+			delete(c.masterStateErrors, host)
+		}
+	}
+	return c.masterState(host)
+}
+
+func (c *fakeCluster) GetLocalMasterState(host string) (cluster.MasterState, error) {
+	return c.masterState(host)
+}
+
+func (c *fakeCluster) masterState(host string) (cluster.MasterState, error) {
+	if err := c.masterStateErrors[host]; err != nil {
+		return cluster.MasterState{}, err
+	}
+	state := c.masterStates[host]
+	state.Host = host
+	return state, nil
+}
+
+func (c *fakeCluster) ReinitializeStandby(master, standby string) error {
+	c.reinitializeStub.master = master
+	c.reinitializeStub.standby = standby
+	return c.reinitializeStub.err
+}
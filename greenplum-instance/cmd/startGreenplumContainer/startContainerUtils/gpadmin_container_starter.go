@@ -2,9 +2,12 @@ package startContainerUtils
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/blang/vfs"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/fileutil"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
 )
 
@@ -12,15 +15,18 @@ const bashrcPath = "/home/gpadmin/.bashrc"
 
 type GpadminContainerStarter struct {
 	*starter.App
+	Config instanceconfig.Reader
 }
 
 func (s *GpadminContainerStarter) Run() error {
 	for _, step := range []func() error{
 		s.WriteContentsToBashrc,
 		s.SetupSSHForGpadmin,
+		s.WritePgpass,
 		s.CreateSymLink,
 		s.CreatePsqlHistory,
 		s.CreateMirrorDir,
+		s.CreateTablespaceDirs,
 	} {
 		if err := step(); err != nil {
 			return err
@@ -64,6 +70,23 @@ func (s *GpadminContainerStarter) CreateMirrorDir() error {
 	return vfs.MkdirAll(s.Fs, "/greenplum/mirror", 0755)
 }
 
+// CreateTablespaceDirs creates the tablespace directory of every tablespace volume in the cluster.
+// CREATE TABLESPACE needs the location on all hosts, so pods without the volume get the directory on /greenplum.
+func (s *GpadminContainerStarter) CreateTablespaceDirs() error {
+	volumes, err := s.Config.GetTablespaceVolumes()
+	if err != nil {
+		return fmt.Errorf("failed to read tablespace volumes: %w", err)
+	}
+	for _, volume := range volumes {
+		dir := instanceconfig.TablespaceLocation(volume)
+		Log.Info("creating tablespace dir " + dir)
+		if err := vfs.MkdirAll(s.Fs, dir, 0700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *GpadminContainerStarter) CreatePsqlHistory() error {
 	const filename = "/home/gpadmin/.psql_history"
 	Log.Info("creating " + filename + " file")
@@ -106,3 +129,19 @@ func (s *GpadminContainerStarter) SetupSSHForGpadmin() error {
 
 	return nil
 }
+
+// WritePgpass gives gpadmin the password from the gpadmin-password Secret, so that connections
+// that require a password work. The operator rewrites the file in running pods when the password changes.
+func (s *GpadminContainerStarter) WritePgpass() error {
+	const passwordFile = pgpass.SecretMountPath + "/" + pgpass.PasswordKey
+	password, err := vfs.ReadFile(s.Fs, passwordFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			Log.Info("skipping " + pgpass.Path + ": " + passwordFile + " does not exist")
+			return nil
+		}
+		return err
+	}
+	Log.Info("writing " + pgpass.Path)
+	return vfs.WriteFile(s.Fs, pgpass.Path, []byte(pgpass.Entry("gpadmin", string(password))), 0600)
+}
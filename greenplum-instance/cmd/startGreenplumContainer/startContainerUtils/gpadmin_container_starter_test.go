@@ -14,6 +14,7 @@ import (
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/fileutil"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	instanceconfigtesting "github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig/testing"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
 	. "github.com/pivotal/greenplum-for-kubernetes/pkg/testing/matcher"
 )
@@ -24,6 +25,7 @@ var _ = Describe("GpadminContainerStarter", func() {
 		outBuffer *gbytes.Buffer
 		memoryfs  vfs.Filesystem
 		fakeCmd   *commandable.CommandFake
+		config    *instanceconfigtesting.MockReader
 	)
 
 	BeforeEach(func() {
@@ -31,11 +33,14 @@ var _ = Describe("GpadminContainerStarter", func() {
 		outBuffer = gbytes.NewBuffer()
 		startContainerUtils.Log = gplog.ForTest(outBuffer)
 		memoryfs = memfs.Create()
+		config = &instanceconfigtesting.MockReader{}
 		app = &startContainerUtils.GpadminContainerStarter{
 			App: &starter.App{
 				Command: fakeCmd.Command,
 				Fs:      memoryfs,
-			}}
+			},
+			Config: config,
+		}
 		Expect(vfs.MkdirAll(memoryfs, "/etc/config/", 755)).To(Succeed())
 		Expect(vfs.WriteFile(memoryfs, "/etc/config/pxfServiceName",
 			[]byte("pxf-service"), 0400)).To(Succeed())
@@ -214,6 +219,40 @@ var _ = Describe("GpadminContainerStarter", func() {
 			Expect(err.Error()).To(Equal("failed to create dir /greenplum/mirror"))
 		})
 
+		When("the cluster has tablespace volumes", func() {
+			BeforeEach(func() {
+				config.TablespaceVolumes = []string{"fast", "scratch"}
+			})
+			It("creates a tablespace dir for each volume", func() {
+				Expect(app.Run()).To(Succeed())
+				Expect(outBuffer).To(gbytes.Say(`"creating tablespace dir /greenplum/volumes/fast/tablespace"`))
+				Expect(outBuffer).To(gbytes.Say(`"creating tablespace dir /greenplum/volumes/scratch/tablespace"`))
+				for _, dir := range []string{"/greenplum/volumes/fast/tablespace", "/greenplum/volumes/scratch/tablespace"} {
+					fileInfo, err := memoryfs.Stat(dir)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fileInfo.IsDir()).To(BeTrue())
+				}
+			})
+			It("exits on error creating a dir", func() {
+				fakeFS := fileutil.HookableFilesystem{Filesystem: memoryfs}
+				app.Fs = &fakeFS
+				fakeFS.MkdirHook = func(name string, perm os.FileMode) error {
+					if strings.Contains(name, "scratch") {
+						return errors.New("failed to create dir /greenplum/volumes/scratch")
+					}
+					return memoryfs.Mkdir(name, perm)
+				}
+
+				err := app.Run()
+				Expect(err).To(MatchError("failed to create dir /greenplum/volumes/scratch"))
+			})
+		})
+		It("fails when tablespace volumes cannot be read", func() {
+			config.TablespaceVolumesErr = errors.New("bad config")
+			err := app.Run()
+			Expect(err).To(MatchError("failed to read tablespace volumes: bad config"))
+		})
+
 		It("writes all ~/.ssh files", func() {
 			Expect(app.Run()).To(Succeed())
 			dir, err := memoryfs.Stat(LocalSSHDirPath)
@@ -228,6 +267,36 @@ var _ = Describe("GpadminContainerStarter", func() {
 			fileInfo, _ := memoryfs.Stat(LocalSSHDirPath + "/known_hosts")
 			Expect(int(fileInfo.Mode().Perm())).To(Equal(0600))
 		})
+		It("skips ~/.pgpass when the gpadmin password is not mounted", func() {
+			Expect(app.Run()).To(Succeed())
+			Expect(outBuffer).To(gbytes.Say(`"skipping /home/gpadmin/.pgpass: /etc/gpadmin-password/password does not exist"`))
+			_, err := memoryfs.Stat("/home/gpadmin/.pgpass")
+			Expect(os.IsNotExist(err)).To(BeTrue(), "expected not exist, got %v", err)
+		})
+		When("the gpadmin password is mounted", func() {
+			BeforeEach(func() {
+				Expect(vfs.MkdirAll(memoryfs, "/etc/gpadmin-password", 0755)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/etc/gpadmin-password/password", []byte("s3cret"), 0444)).To(Succeed())
+			})
+			It("writes ~/.pgpass", func() {
+				Expect(app.Run()).To(Succeed())
+				Expect("/home/gpadmin/.pgpass").To(EqualInFilesystem(memoryfs, "*:*:*:gpadmin:s3cret\n"))
+				fileInfo, err := memoryfs.Stat("/home/gpadmin/.pgpass")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fileInfo.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			})
+			It("fails when ~/.pgpass cannot be written", func() {
+				fakeFS := fileutil.HookableFilesystem{Filesystem: memoryfs}
+				app.Fs = &fakeFS
+				fakeFS.OpenFileHook = func(name string, flag int, perm os.FileMode) (vfs.File, error) {
+					if strings.HasSuffix(name, ".pgpass") {
+						return nil, errors.New("failed to write .pgpass")
+					}
+					return memoryfs.OpenFile(name, flag, perm)
+				}
+				Expect(app.Run()).To(MatchError("failed to write .pgpass"))
+			})
+		})
 		It("touch psql_history file successfully", func() {
 			Expect(vfs.MkdirAll(memoryfs, "/home/gpadmin", 0755)).To(Succeed())
 			fileName := ".psql_history"
@@ -39,11 +39,12 @@ func (s *LabelPvcStarter) Run() error {
 		return err
 	}
 
+	// Only the data volume is labeled; additional volumes do not hold a data directory.
 	var pvcName string
 	for _, volume := range thisPod.Spec.Volumes {
-		if volume.PersistentVolumeClaim != nil {
+		if volume.PersistentVolumeClaim != nil && strings.HasSuffix(volume.Name, "-pgdata") {
 			if pvcName != "" {
-				return errors.New("found more pvc volumes than expected")
+				return errors.New("found more pgdata pvc volumes than expected")
 			}
 			pvcName = volume.PersistentVolumeClaim.ClaimName
 		}
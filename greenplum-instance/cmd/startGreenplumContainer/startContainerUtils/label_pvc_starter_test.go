@@ -164,10 +164,37 @@ var _ = Describe("LabelPVCStarter", func() {
 		})
 	})
 
-	When("there is more than one persistent volume in the pod", func() {
+	When("the pod has an additional persistent volume", func() {
 		BeforeEach(func() {
 			extraPvc := corev1.Volume{
-				Name: "extra-pvc",
+				Name: "my-greenplum-fast",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "my-greenplum-fast-master-0",
+					},
+				},
+			}
+			pod.Spec.Volumes = append(pod.Spec.Volumes, extraPvc)
+			pvc = corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "test-ns",
+					Name:      pvcName,
+				},
+			}
+			Expect(reactiveClient.Create(nil, &pvc)).To(Succeed())
+		})
+		It("labels only the pgdata PVC", func() {
+			Expect(subject.Run()).To(Succeed())
+
+			Expect(reactiveClient.Get(nil, types.NamespacedName{Namespace: "test-ns", Name: pvcName}, &pvc)).To(Succeed())
+			Expect(pvc.Labels).To(Equal(map[string]string{pvcLabelKey: greenplumcluster.SupportedGreenplumMajorVersion}))
+		})
+	})
+
+	When("there is more than one pgdata persistent volume in the pod", func() {
+		BeforeEach(func() {
+			extraPvc := corev1.Volume{
+				Name: "extra-pgdata",
 				VolumeSource: corev1.VolumeSource{
 					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
 						ClaimName: "extra-pvc",
@@ -177,7 +204,7 @@ var _ = Describe("LabelPVCStarter", func() {
 			pod.Spec.Volumes = append(pod.Spec.Volumes, extraPvc)
 		})
 		It("fails", func() {
-			Expect(subject.Run()).To(MatchError("found more pvc volumes than expected"))
+			Expect(subject.Run()).To(MatchError("found more pgdata pvc volumes than expected"))
 		})
 	})
 
@@ -0,0 +1,116 @@
+package startContainerUtils
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logretention"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+const (
+	// logRetentionInterval is how often the log retention policy is enforced
+	logRetentionInterval = 5 * time.Minute
+	// diskPressureThreshold is the fraction of the data volume in use above which old logs are
+	// removed even though they are within the policy
+	diskPressureThreshold = 0.9
+)
+
+// LogRetentionDaemon compresses and removes old logs on the data volume, as the logRetention
+// policy of the cluster asks. The policy is read again on each run, so that changes to it apply
+// without a restart.
+type LogRetentionDaemon struct {
+	*starter.App
+	Config   instanceconfig.Reader
+	Hostname func() (string, error)
+	// VolumeUsage returns the fraction of the volume at path that is in use
+	VolumeUsage func(path string) (float64, error)
+	// Interval is logRetentionInterval if zero
+	Interval time.Duration
+}
+
+func (d *LogRetentionDaemon) Run(ctx context.Context) error {
+	hostname, err := d.Hostname()
+	if err != nil {
+		Log.Error(err, "failed to start log retention")
+		return fmt.Errorf("getting hostname: %w", err)
+	}
+	enforcer := d.Enforcer(hostname)
+
+	interval := d.Interval
+	if interval == 0 {
+		interval = logRetentionInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.EnforceLogRetention(enforcer)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforcer returns the enforcer of the policy on the logs of this pod
+func (d *LogRetentionDaemon) Enforcer(hostname string) *logretention.Enforcer {
+	dataDir := dataDirectory(hostname)
+	// The volume the data directory is on: /greenplum, or /greenplum/mirror when mirrors have their own volume
+	dataVolume := filepath.Dir(dataDir)
+	return &logretention.Enforcer{
+		Fs:   d.Fs,
+		Dirs: logDirs(dataDir),
+		UnderPressure: func() bool {
+			used, err := d.VolumeUsage(dataVolume)
+			if err != nil {
+				Log.Error(err, "failed to read data volume usage")
+				return false
+			}
+			return used >= diskPressureThreshold
+		},
+	}
+}
+
+// EnforceLogRetention applies the current policy once. Failures are logged, and retried on the next run.
+func (d *LogRetentionDaemon) EnforceLogRetention(enforcer *logretention.Enforcer) {
+	policy, err := d.Config.GetLogRetention()
+	if err != nil {
+		Log.Error(err, "failed to read log retention policy")
+		return
+	}
+	if !policy.Enabled {
+		return
+	}
+	result, err := enforcer.Enforce(logretention.Policy{MaxAge: policy.MaxAge, MaxSize: policy.MaxSize})
+	if len(result.Compressed) > 0 || len(result.Removed) > 0 {
+		Log.Info("enforced log retention", "compressed", result.Compressed, "removed", result.Removed)
+	}
+	if err != nil {
+		Log.Error(err, "failed to enforce log retention")
+	}
+}
+
+// logDirs are the directories of the server and utility logs of a pod
+func logDirs(dataDir string) []logretention.Dir {
+	return []logretention.Dir{
+		{Path: filepath.Join(dataDir, "pg_log"), Pattern: "*.csv"},
+		{Path: gpAdminLogsDir, Pattern: "*.log", DailyLogs: true},
+	}
+}
+
+// StatfsVolumeUsage returns the fraction of the filesystem at path that is in use
+func StatfsVolumeUsage(path string) (float64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	if stat.Blocks == 0 {
+		return 0, nil
+	}
+	return 1 - float64(stat.Bavail)/float64(stat.Blocks), nil
+}
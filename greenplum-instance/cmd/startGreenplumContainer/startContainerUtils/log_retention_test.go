@@ -0,0 +1,147 @@
+package startContainerUtils_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig"
+	instanceconfigTesting "github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig/testing"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logretention"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+var _ = Describe("LogRetentionDaemon", func() {
+	var (
+		daemon      *startContainerUtils.LogRetentionDaemon
+		config      *instanceconfigTesting.MockReader
+		logBuffer   *gbytes.Buffer
+		memoryfs    vfs.Filesystem
+		volumeUsage float64
+		volumePath  string
+		enforcer    *logretention.Enforcer
+	)
+	BeforeEach(func() {
+		logBuffer = gbytes.NewBuffer()
+		startContainerUtils.Log = gplog.ForTest(logBuffer)
+		memoryfs = memfs.Create()
+		Expect(vfs.MkdirAll(memoryfs, "/greenplum/data/pg_log", 0700)).To(Succeed())
+		Expect(vfs.WriteFile(memoryfs, "/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv", []byte("old entries\n"), 0600)).To(Succeed())
+		time.Sleep(time.Millisecond)
+		Expect(vfs.WriteFile(memoryfs, "/greenplum/data/pg_log/gpdb-2020-06-02_000000.csv", []byte("current entries\n"), 0600)).To(Succeed())
+		config = &instanceconfigTesting.MockReader{}
+		volumeUsage = 0.5
+		volumePath = ""
+		daemon = &startContainerUtils.LogRetentionDaemon{
+			App:      &starter.App{Fs: memoryfs},
+			Config:   config,
+			Hostname: func() (string, error) { return "segment-a-0", nil },
+			VolumeUsage: func(path string) (float64, error) {
+				volumePath = path
+				return volumeUsage, nil
+			},
+		}
+		enforcer = daemon.Enforcer("segment-a-0")
+		enforcer.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	})
+
+	exists := func(path string) bool {
+		_, err := memoryfs.Stat(path)
+		return err == nil
+	}
+
+	It("enforces the policy on the logs of the master", func() {
+		Expect(daemon.Enforcer("master-0").Dirs).To(Equal([]logretention.Dir{
+			{Path: "/greenplum/data-1/pg_log", Pattern: "*.csv"},
+			{Path: "/home/gpadmin/gpAdminLogs", Pattern: "*.log", DailyLogs: true},
+		}))
+	})
+
+	It("does nothing when log retention is disabled", func() {
+		volumeUsage = 1
+		daemon.EnforceLogRetention(enforcer)
+		Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv")).To(BeTrue())
+	})
+
+	When("log retention is enabled", func() {
+		BeforeEach(func() {
+			config.LogRetention = instanceconfig.LogRetention{Enabled: true, MaxAge: 7 * 24 * time.Hour}
+		})
+
+		It("compresses the logs that are no longer written to", func() {
+			daemon.EnforceLogRetention(enforcer)
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz")).To(BeTrue())
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-02_000000.csv")).To(BeTrue())
+			Expect(logBuffer).To(gbytes.Say(`"msg":"enforced log retention","compressed":\["/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv"\]`))
+		})
+
+		It("removes old logs while the data volume is nearly full", func() {
+			volumeUsage = 0.95
+			daemon.EnforceLogRetention(enforcer)
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz")).To(BeFalse())
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-02_000000.csv")).To(BeTrue())
+			Expect(logBuffer).To(gbytes.Say(`"removed":\["/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz"\]`))
+			Expect(volumePath).To(Equal("/greenplum"))
+		})
+
+		When("the pod is a segment-b pod", func() {
+			BeforeEach(func() {
+				Expect(vfs.MkdirAll(memoryfs, "/greenplum/mirror/data/pg_log", 0700)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/greenplum/mirror/data/pg_log/gpdb-2020-06-01_000000.csv", []byte("old entries\n"), 0600)).To(Succeed())
+				time.Sleep(time.Millisecond)
+				Expect(vfs.WriteFile(memoryfs, "/greenplum/mirror/data/pg_log/gpdb-2020-06-02_000000.csv", []byte("current entries\n"), 0600)).To(Succeed())
+				enforcer = daemon.Enforcer("segment-b-0")
+				enforcer.Now = func() time.Time { return time.Now().Add(time.Hour) }
+			})
+			It("enforces the policy on the logs of the mirror", func() {
+				daemon.EnforceLogRetention(enforcer)
+				Expect(exists("/greenplum/mirror/data/pg_log/gpdb-2020-06-01_000000.csv.gz")).To(BeTrue())
+				Expect(exists("/greenplum/mirror/data/pg_log/gpdb-2020-06-02_000000.csv")).To(BeTrue())
+			})
+			It("removes old logs while the mirror volume is nearly full", func() {
+				volumeUsage = 0.95
+				daemon.EnforceLogRetention(enforcer)
+				Expect(exists("/greenplum/mirror/data/pg_log/gpdb-2020-06-01_000000.csv.gz")).To(BeFalse())
+				Expect(exists("/greenplum/mirror/data/pg_log/gpdb-2020-06-02_000000.csv")).To(BeTrue())
+				Expect(volumePath).To(Equal("/greenplum/mirror"))
+			})
+		})
+
+		It("keeps the logs when the volume usage cannot be read", func() {
+			daemon.VolumeUsage = func(string) (float64, error) { return 0, errors.New("statfs failed") }
+			daemon.EnforceLogRetention(enforcer)
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz")).To(BeTrue())
+			Expect(logBuffer).To(gbytes.Say("failed to read data volume usage"))
+		})
+	})
+
+	It("logs why the policy could not be read", func() {
+		config.LogRetentionErr = errors.New("error parsing logRetention")
+		daemon.EnforceLogRetention(enforcer)
+		Expect(logBuffer).To(gbytes.Say("failed to read log retention policy"))
+		Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv")).To(BeTrue())
+	})
+
+	Describe("Run()", func() {
+		It("enforces the policy until it is stopped", func() {
+			config.LogRetention = instanceconfig.LogRetention{Enabled: true}
+			daemon.Interval = time.Millisecond
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			Expect(daemon.Run(ctx)).To(Succeed())
+		})
+
+		It("fails when the hostname is unknown", func() {
+			daemon.Hostname = func() (string, error) { return "", errors.New("no hostname") }
+			Expect(daemon.Run(context.Background())).To(MatchError("getting hostname: no hostname"))
+			Expect(logBuffer).To(gbytes.Say("failed to start log retention"))
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package startContainerUtils
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logship"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+const gpAdminLogsDir = "/home/gpadmin/gpAdminLogs"
+
+// LogShipperDaemon writes the entries of the server logs in pg_log and of the utility logs in
+// gpAdminLogs to stdout as JSON lines, so that they can be collected with the container logs
+type LogShipperDaemon struct {
+	*starter.App
+	Hostname func() (string, error)
+}
+
+func (d *LogShipperDaemon) Run(ctx context.Context) error {
+	shipper, err := d.Shipper()
+	if err != nil {
+		Log.Error(err, "failed to start log shipper")
+		return err
+	}
+	Log.Info("starting log shipper")
+	return shipper.Run(ctx)
+}
+
+// Shipper returns the shipper of the logs of this pod
+func (d *LogShipperDaemon) Shipper() (*logship.Shipper, error) {
+	hostname, err := d.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("getting hostname: %w", err)
+	}
+	dataDir := dataDirectory(hostname)
+	return &logship.Shipper{
+		Fs:  d.Fs,
+		Out: d.StdoutBuffer,
+		Sources: []logship.Source{
+			{Name: "pg_log", Dir: filepath.Join(dataDir, "pg_log"), Pattern: "*.csv", Format: logship.FormatCSV},
+			{Name: "gpAdminLogs", Dir: gpAdminLogsDir, Pattern: "*.log", Format: logship.FormatGpAdminLog},
+		},
+		PollInterval: time.Second,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package startContainerUtils_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logship"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+var _ = Describe("LogShipperDaemon", func() {
+	var (
+		daemon    *startContainerUtils.LogShipperDaemon
+		outBuffer *gbytes.Buffer
+		logBuffer *gbytes.Buffer
+		memoryfs  vfs.Filesystem
+		hostname  string
+	)
+	BeforeEach(func() {
+		outBuffer = gbytes.NewBuffer()
+		logBuffer = gbytes.NewBuffer()
+		startContainerUtils.Log = gplog.ForTest(logBuffer)
+		memoryfs = memfs.Create()
+		hostname = "master-0"
+		daemon = &startContainerUtils.LogShipperDaemon{
+			App: &starter.App{
+				StdoutBuffer: outBuffer,
+				StderrBuffer: logBuffer,
+				Fs:           memoryfs,
+			},
+			Hostname: func() (string, error) { return hostname, nil },
+		}
+	})
+
+	It("tails the master pg_log and gpAdminLogs on a master", func() {
+		shipper, err := daemon.Shipper()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shipper.Sources).To(Equal([]logship.Source{
+			{Name: "pg_log", Dir: "/greenplum/data-1/pg_log", Pattern: "*.csv", Format: logship.FormatCSV},
+			{Name: "gpAdminLogs", Dir: "/home/gpadmin/gpAdminLogs", Pattern: "*.log", Format: logship.FormatGpAdminLog},
+		}))
+	})
+
+	It("tails the segment pg_log on a segment", func() {
+		hostname = "segment-a-0"
+		shipper, err := daemon.Shipper()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shipper.Sources[0].Dir).To(Equal("/greenplum/data/pg_log"))
+	})
+
+	It("tails the mirror pg_log on a segment-b pod", func() {
+		hostname = "segment-b-0"
+		shipper, err := daemon.Shipper()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shipper.Sources[0].Dir).To(Equal("/greenplum/mirror/data/pg_log"))
+	})
+
+	It("writes the entries to stdout", func() {
+		shipper, err := daemon.Shipper()
+		Expect(err).NotTo(HaveOccurred())
+		shipper.Start()
+		Expect(vfs.MkdirAll(memoryfs, "/home/gpadmin/gpAdminLogs", 0755)).To(Succeed())
+		Expect(vfs.WriteFile(memoryfs, "/home/gpadmin/gpAdminLogs/gpstart_20200601.log",
+			[]byte("20200601:12:00:05:001234 gpstart:master-0:gpadmin-[INFO]:-Starting gpstart\n"), 0644)).To(Succeed())
+		shipper.Poll()
+		Expect(outBuffer).To(gbytes.Say(`"logger":"gpAdminLogs","msg":"Starting gpstart"`))
+	})
+
+	It("fails when the hostname is unknown", func() {
+		daemon.Hostname = func() (string, error) { return "", errors.New("no hostname") }
+		Expect(daemon.Run(context.Background())).To(MatchError("getting hostname: no hostname"))
+		Expect(logBuffer).To(gbytes.Say("failed to start log shipper"))
+	})
+})
@@ -0,0 +1,47 @@
+package startContainerUtils
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils/cluster"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+// StopTimeoutSeconds bounds how long pg_ctl waits for postgres to shut down.
+// It must be shorter than the terminationGracePeriodSeconds of the pod.
+const StopTimeoutSeconds = 150
+
+// PreStop shuts down the postgres instance of this pod before the kubelet stops the container.
+// It only stops the local postmaster: the rest of the cluster keeps running, and failing over to the
+// standby master is left to an explicit `kubectl gp failover`.
+type PreStop struct {
+	*starter.App
+	Hostname func() (string, error)
+}
+
+func (p *PreStop) Run() error {
+	hostname, err := p.Hostname()
+	if err != nil {
+		return fmt.Errorf("getting hostname: %w", err)
+	}
+	status := localStatus(p.App, hostname)
+	if !status.DataDirectory.Initialized || status.Postmaster == gpstatus.PostmasterStopped {
+		Log.Info("postmaster is not running")
+		return nil
+	}
+	return p.pgCtlStop(status.DataDirectory.Path)
+}
+
+func (p *PreStop) pgCtlStop(dataDir string) error {
+	Log.Info("stopping postmaster", "dataDirectory", dataDir)
+	cmd := cluster.NewGreenplumCommand(p.Command).Command("/usr/local/greenplum-db/bin/pg_ctl",
+		"stop", "-D", dataDir, "-m", "fast", "-w", "-t", strconv.Itoa(StopTimeoutSeconds))
+	cmd.Stdout = p.StdoutBuffer
+	cmd.Stderr = p.StderrBuffer
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_ctl stop failed: %w", err)
+	}
+	return nil
+}
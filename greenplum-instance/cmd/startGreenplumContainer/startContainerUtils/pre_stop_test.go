@@ -0,0 +1,124 @@
+package startContainerUtils_test
+
+import (
+	"errors"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+var _ = Describe("PreStop", func() {
+	var (
+		preStop   *startContainerUtils.PreStop
+		outBuffer *gbytes.Buffer
+		memoryfs  vfs.Filesystem
+		fakeCmd   *commandable.CommandFake
+		hostname  string
+	)
+	BeforeEach(func() {
+		fakeCmd = commandable.NewFakeCommand()
+		outBuffer = gbytes.NewBuffer()
+		startContainerUtils.Log = gplog.ForTest(outBuffer)
+		memoryfs = memfs.Create()
+		Expect(vfs.MkdirAll(memoryfs, "/greenplum", 0755)).To(Succeed())
+		hostname = "segment-a-0"
+		preStop = &startContainerUtils.PreStop{
+			App: &starter.App{
+				Command:      fakeCmd.Command,
+				StdoutBuffer: outBuffer,
+				StderrBuffer: outBuffer,
+				Fs:           memoryfs,
+			},
+			Hostname: func() (string, error) { return hostname, nil },
+		}
+	})
+
+	initializeDataDir := func(dataDir string) {
+		Expect(vfs.MkdirAll(memoryfs, dataDir, 0700)).To(Succeed())
+		Expect(vfs.WriteFile(memoryfs, dataDir+"/PG_VERSION", []byte("9.4\n"), 0600)).To(Succeed())
+	}
+	expectPgCtlStop := func(dataDir string) *commandable.ExpectedCommand {
+		return fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "stop", "-D", dataDir, "-m", "fast", "-w", "-t", "150")
+	}
+	It("returns an error when the hostname cannot be read", func() {
+		preStop.Hostname = func() (string, error) { return "", errors.New("no hostname") }
+		Expect(preStop.Run()).To(MatchError("getting hostname: no hostname"))
+	})
+
+	When("the data directory is not initialized", func() {
+		It("does nothing", func() {
+			Expect(preStop.Run()).To(Succeed())
+			Expect(outBuffer).To(gbytes.Say("postmaster is not running"))
+			Expect(fakeCmd.CapturedArgs()).To(BeEmpty())
+		})
+	})
+
+	When("the postmaster is stopped", func() {
+		BeforeEach(func() {
+			initializeDataDir("/greenplum/data")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data").ReturnsStatus(3)
+		})
+		It("does nothing", func() {
+			var stopCalls int
+			expectPgCtlStop("/greenplum/data").CallCounter(&stopCalls)
+			Expect(preStop.Run()).To(Succeed())
+			Expect(stopCalls).To(BeZero())
+		})
+	})
+
+	When("the pod is a segment", func() {
+		BeforeEach(func() {
+			initializeDataDir("/greenplum/data")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data")
+		})
+		It("stops its postmaster with a fast shutdown", func() {
+			var stopCalls int
+			expectPgCtlStop("/greenplum/data").CallCounter(&stopCalls)
+			Expect(preStop.Run()).To(Succeed())
+			Expect(stopCalls).To(Equal(1))
+		})
+		It("returns an error when pg_ctl fails", func() {
+			expectPgCtlStop("/greenplum/data").ReturnsStatus(1)
+			Expect(preStop.Run()).To(MatchError("pg_ctl stop failed: exit status 1"))
+		})
+	})
+
+	When("the pod is a segment-b pod", func() {
+		BeforeEach(func() {
+			hostname = "segment-b-0"
+			initializeDataDir("/greenplum/mirror/data")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/mirror/data")
+		})
+		It("stops the postmaster of its mirror", func() {
+			var stopCalls int
+			expectPgCtlStop("/greenplum/mirror/data").CallCounter(&stopCalls)
+			Expect(preStop.Run()).To(Succeed())
+			Expect(stopCalls).To(Equal(1))
+		})
+	})
+
+	When("the pod is the active master", func() {
+		BeforeEach(func() {
+			hostname = "master-0"
+			initializeDataDir("/greenplum/data-1")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1")
+		})
+		It("stops only its own postmaster, without stopping the cluster or failing over", func() {
+			var stopCalls, otherCalls int
+			expectPgCtlStop("/greenplum/data-1").CallCounter(&stopCalls)
+			fakeCmd.ExpectCommandMatching(func(path string, args ...string) bool {
+				return path == "/usr/local/greenplum-db/bin/gpstop" || path == "/usr/bin/ssh"
+			}).CallCounter(&otherCalls)
+			Expect(preStop.Run()).To(Succeed())
+			Expect(stopCalls).To(Equal(1))
+			Expect(otherCalls).To(BeZero())
+		})
+	})
+})
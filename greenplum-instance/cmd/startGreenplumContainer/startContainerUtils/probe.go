@@ -0,0 +1,147 @@
+package startContainerUtils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils/cluster"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+const (
+	StartupProbe   = "startup"
+	ReadinessProbe = "readiness"
+	LivenessProbe  = "liveness"
+)
+
+// exit codes of pg_isready
+const (
+	pgIsReadyAccepting  = 0
+	pgIsReadyRejecting  = 1
+	pgIsReadyNoResponse = 2
+)
+
+// PodProbe implements the startup, readiness and liveness probes of a greenplum container
+type PodProbe struct {
+	*starter.App
+	Hostname func() (string, error)
+}
+
+var _ Prober = &PodProbe{}
+
+func (p *PodProbe) Probe(kind string) error {
+	hostname, err := p.Hostname()
+	if err != nil {
+		return fmt.Errorf("getting hostname: %w", err)
+	}
+	status := localStatus(p.App, hostname)
+
+	switch kind {
+	case StartupProbe:
+		return p.startup(status)
+	case ReadinessProbe:
+		return p.readiness(status)
+	case LivenessProbe:
+		return p.liveness(status)
+	default:
+		return fmt.Errorf("unknown probe %q: expected %s, %s or %s", kind, StartupProbe, ReadinessProbe, LivenessProbe)
+	}
+}
+
+// startup succeeds once a running postmaster has finished starting up. Until it
+// does, the kubelet holds off the liveness probe, so a long crash recovery does
+// not get the pod restarted.
+func (p *PodProbe) startup(status *gpstatus.Status) error {
+	if !status.DataDirectory.Initialized || status.Postmaster == gpstatus.PostmasterStopped {
+		// Nothing is starting. gpinitsystem, gpstart and gprecoverseg run long
+		// after the container has started.
+		return nil
+	}
+	if status.Postmaster == gpstatus.PostmasterUnknown {
+		return errors.New("unable to determine the state of the postmaster")
+	}
+	switch p.pgIsReady(status.Hostname) {
+	case pgIsReadyAccepting:
+		return nil
+	case pgIsReadyRejecting:
+		// A mirror or standby never accepts connections
+		if status.Role == gpstatus.RoleMirror || status.Role == gpstatus.RoleStandbyMaster {
+			return nil
+		}
+		return errors.New("postmaster is starting up")
+	default:
+		return errors.New("postmaster is not responding")
+	}
+}
+
+// readiness succeeds when a master or primary accepts connections, or when a
+// standby or mirror is streaming from its primary
+func (p *PodProbe) readiness(status *gpstatus.Status) error {
+	if !status.DataDirectory.Initialized {
+		return fmt.Errorf("data directory %s is not initialized", status.DataDirectory.Path)
+	}
+	switch status.Role {
+	case gpstatus.RoleActiveMaster, gpstatus.RolePrimary:
+		if p.pgIsReady(status.Hostname) != pgIsReadyAccepting {
+			return errors.New("postmaster is not accepting connections")
+		}
+		return nil
+	default:
+		streaming, err := p.walReceiverStreaming()
+		if err != nil {
+			return err
+		}
+		if !streaming {
+			return errors.New("wal receiver is not streaming")
+		}
+		return nil
+	}
+}
+
+// liveness fails only when a running postmaster does not respond at all. A
+// stopped postmaster is left to gpstart or gprecoverseg.
+func (p *PodProbe) liveness(status *gpstatus.Status) error {
+	if !status.DataDirectory.Initialized || status.Postmaster != gpstatus.PostmasterRunning {
+		return nil
+	}
+	if p.pgIsReady(status.Hostname) == pgIsReadyNoResponse {
+		return errors.New("postmaster is running, but not responding")
+	}
+	return nil
+}
+
+func (p *PodProbe) pgIsReady(hostname string) int {
+	cmd := cluster.NewGreenplumCommand(p.Command).Command("/usr/local/greenplum-db/bin/pg_isready",
+		"-q", "-h", "localhost", "-p", strconv.Itoa(postmasterPort(hostname)), "-t", "5")
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return pgIsReadyAccepting
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	default:
+		Log.Error(err, "pg_isready failed")
+		return pgIsReadyNoResponse
+	}
+}
+
+func (p *PodProbe) walReceiverStreaming() (bool, error) {
+	cmd := p.Command("/bin/ps", "-e", "-o", "args=")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("ps failed: %w", err)
+	}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if strings.Contains(line, "wal receiver process") && strings.Contains(line, "streaming") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,159 @@
+package startContainerUtils_test
+
+import (
+	"errors"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+var _ = Describe("PodProbe", func() {
+	var (
+		probe     *startContainerUtils.PodProbe
+		outBuffer *gbytes.Buffer
+		memoryfs  vfs.Filesystem
+		fakeCmd   *commandable.CommandFake
+		hostname  string
+	)
+	BeforeEach(func() {
+		fakeCmd = commandable.NewFakeCommand()
+		outBuffer = gbytes.NewBuffer()
+		startContainerUtils.Log = gplog.ForTest(outBuffer)
+		memoryfs = memfs.Create()
+		Expect(vfs.MkdirAll(memoryfs, "/greenplum", 0755)).To(Succeed())
+		hostname = "segment-a-0"
+		probe = &startContainerUtils.PodProbe{
+			App: &starter.App{
+				Command:      fakeCmd.Command,
+				StdoutBuffer: outBuffer,
+				StderrBuffer: outBuffer,
+				Fs:           memoryfs,
+			},
+			Hostname: func() (string, error) { return hostname, nil },
+		}
+	})
+
+	initializeDataDir := func(dataDir string) {
+		Expect(vfs.MkdirAll(memoryfs, dataDir, 0700)).To(Succeed())
+		Expect(vfs.WriteFile(memoryfs, dataDir+"/PG_VERSION", []byte("9.4\n"), 0600)).To(Succeed())
+	}
+	makeMirror := func(dataDir string) {
+		Expect(vfs.WriteFile(memoryfs, dataDir+"/recovery.conf", []byte("standby_mode = 'on'\n"), 0600)).To(Succeed())
+	}
+	expectPgIsReady := func(port string) *commandable.ExpectedCommand {
+		return fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_isready", "-q", "-h", "localhost", "-p", port, "-t", "5")
+	}
+	expectPs := func() *commandable.ExpectedCommand {
+		return fakeCmd.ExpectCommand("/bin/ps", "-e", "-o", "args=")
+	}
+
+	It("rejects an unknown probe", func() {
+		Expect(probe.Probe("sideways")).To(MatchError(`unknown probe "sideways": expected startup, readiness or liveness`))
+	})
+
+	It("returns an error when the hostname cannot be read", func() {
+		probe.Hostname = func() (string, error) { return "", errors.New("no hostname") }
+		Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(MatchError("getting hostname: no hostname"))
+	})
+
+	When("the data directory is not initialized", func() {
+		It("is started and live, but not ready", func() {
+			Expect(probe.Probe(startContainerUtils.StartupProbe)).To(Succeed())
+			Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(Succeed())
+			Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(MatchError("data directory /greenplum/data is not initialized"))
+			Expect(fakeCmd.CapturedArgs()).To(BeEmpty())
+		})
+	})
+
+	When("the pod is the active master", func() {
+		BeforeEach(func() {
+			hostname = "master-0"
+			initializeDataDir("/greenplum/data-1")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1")
+		})
+		It("is ready when it accepts connections on 5432", func() {
+			expectPgIsReady("5432")
+			Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(Succeed())
+			Expect(probe.Probe(startContainerUtils.StartupProbe)).To(Succeed())
+			Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(Succeed())
+		})
+		It("is live but not ready while it recovers", func() {
+			expectPgIsReady("5432").ReturnsStatus(1)
+			Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(MatchError("postmaster is not accepting connections"))
+			Expect(probe.Probe(startContainerUtils.StartupProbe)).To(MatchError("postmaster is starting up"))
+			Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(Succeed())
+		})
+		It("is not live when the postmaster does not respond", func() {
+			expectPgIsReady("5432").ReturnsStatus(2)
+			Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(MatchError("postmaster is running, but not responding"))
+			Expect(probe.Probe(startContainerUtils.StartupProbe)).To(MatchError("postmaster is not responding"))
+		})
+	})
+
+	When("the postmaster is stopped", func() {
+		BeforeEach(func() {
+			initializeDataDir("/greenplum/data")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data").ReturnsStatus(3)
+			expectPgIsReady("40000").ReturnsStatus(2)
+		})
+		It("is started and live, but not ready", func() {
+			Expect(probe.Probe(startContainerUtils.StartupProbe)).To(Succeed())
+			Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(Succeed())
+			Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(MatchError("postmaster is not accepting connections"))
+		})
+	})
+
+	When("the pod is a segment-b pod", func() {
+		BeforeEach(func() {
+			hostname = "segment-b-0"
+			initializeDataDir("/greenplum/mirror/data")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/mirror/data")
+		})
+		When("it is a mirror", func() {
+			BeforeEach(func() {
+				makeMirror("/greenplum/mirror/data")
+				expectPgIsReady("50000").ReturnsStatus(1)
+			})
+			It("is started and live although it rejects connections", func() {
+				Expect(probe.Probe(startContainerUtils.StartupProbe)).To(Succeed())
+				Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(Succeed())
+			})
+			It("is ready when its wal receiver is streaming", func() {
+				expectPs().PrintsOutput("postgres:  50000, wal receiver process   streaming 0/C000000\n")
+				Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(Succeed())
+			})
+			It("is not ready when its wal receiver is not streaming", func() {
+				expectPs().PrintsOutput("postgres:  50000, startup process   recovering 000000010000000000000003\n")
+				Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(MatchError("wal receiver is not streaming"))
+			})
+		})
+		When("it was promoted to primary", func() {
+			It("is ready when it accepts connections on 50000", func() {
+				expectPgIsReady("50000")
+				Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(Succeed())
+				Expect(probe.Probe(startContainerUtils.StartupProbe)).To(Succeed())
+				Expect(probe.Probe(startContainerUtils.LivenessProbe)).To(Succeed())
+			})
+		})
+	})
+
+	When("the pod is the standby master", func() {
+		BeforeEach(func() {
+			hostname = "master-1"
+			initializeDataDir("/greenplum/data-1")
+			makeMirror("/greenplum/data-1")
+			fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1")
+		})
+		It("is ready when its wal receiver is streaming", func() {
+			expectPs().PrintsOutput("postgres:  5432, wal receiver process   streaming 0/C000000\n")
+			Expect(probe.Probe(startContainerUtils.ReadinessProbe)).To(Succeed())
+		})
+	})
+})
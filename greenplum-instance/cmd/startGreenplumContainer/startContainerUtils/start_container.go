@@ -6,15 +6,27 @@ import (
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
 )
 
+// Prober runs one of the kubelet probes of the greenplum container
+type Prober interface {
+	Probe(kind string) error
+}
+
 type GreenplumContainerStarter struct {
 	*starter.App
 	UID int
 
 	Root, Gpadmin, LabelPVC, MultidaemonStarter starter.Starter
+	Prober                                      Prober
 }
 
 func (s *GreenplumContainerStarter) Run(args []string) (status int) {
-	if len(args) == 2 && args[1] == "--do-root-startup" {
+	if len(args) == 3 && args[1] == "probe" {
+		if err := s.Prober.Probe(args[2]); err != nil {
+			fmt.Fprintln(s.StderrBuffer, err.Error())
+			return 1
+		}
+		return 0
+	} else if len(args) == 2 && args[1] == "--do-root-startup" {
 		if s.UID != 0 {
 			fmt.Fprintf(s.StderrBuffer, "--do-root-startup was passed, but we are not root")
 			return 1
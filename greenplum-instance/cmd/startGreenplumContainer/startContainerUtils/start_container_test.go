@@ -21,6 +21,16 @@ func (f *fakeStarter) Run() error {
 	return f.err
 }
 
+type fakeProber struct {
+	kind string
+	err  error
+}
+
+func (f *fakeProber) Probe(kind string) error {
+	f.kind = kind
+	return f.err
+}
+
 var _ = Describe("GreenplumContainerStarter", func() {
 	var (
 		args        []string
@@ -33,6 +43,7 @@ var _ = Describe("GreenplumContainerStarter", func() {
 		fakeGpadminStarter     fakeStarter
 		fakeLabelPVCStarter    fakeStarter
 		fakeMultidaemonStarter fakeStarter
+		fakeProbe              fakeProber
 	)
 	BeforeEach(func() {
 		args = []string{"/ourselves"}
@@ -45,6 +56,7 @@ var _ = Describe("GreenplumContainerStarter", func() {
 		fakeGpadminStarter = fakeStarter{}
 		fakeLabelPVCStarter = fakeStarter{}
 		fakeMultidaemonStarter = fakeStarter{}
+		fakeProbe = fakeProber{}
 	})
 
 	var (
@@ -63,6 +75,7 @@ var _ = Describe("GreenplumContainerStarter", func() {
 			Gpadmin:            &fakeGpadminStarter,
 			LabelPVC:           &fakeLabelPVCStarter,
 			MultidaemonStarter: &fakeMultidaemonStarter,
+			Prober:             &fakeProbe,
 		}
 		status = app.Run(args)
 	})
@@ -204,4 +217,26 @@ var _ = Describe("GreenplumContainerStarter", func() {
 			Expect(status).To(Equal(1))
 		})
 	})
+	When("probe is passed", func() {
+		BeforeEach(func() {
+			args = append(args, "probe", "readiness")
+		})
+		It("runs the probe without calling any starters or sudo", func() {
+			Expect(status).To(Equal(0))
+			Expect(fakeProbe.kind).To(Equal("readiness"))
+			Expect(fakeRootStarter.called).To(BeFalse())
+			Expect(fakeGpadminStarter.called).To(BeFalse())
+			Expect(fakeMultidaemonStarter.called).To(BeFalse())
+			Expect(fakeCmd.CapturedArgs()).To(BeEmpty())
+		})
+		When("the probe fails", func() {
+			BeforeEach(func() {
+				fakeProbe.err = errors.New("postmaster is not accepting connections")
+			})
+			It("prints the error and exits non-zero", func() {
+				Expect(errorBuffer).To(gbytes.Say("postmaster is not accepting connections\n"))
+				Expect(status).To(Equal(1))
+			})
+		})
+	})
 })
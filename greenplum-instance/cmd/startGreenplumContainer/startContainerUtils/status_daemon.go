@@ -0,0 +1,235 @@
+package startContainerUtils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils/cluster"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logretention"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+// pg_ctl status exits with 3 when the server is not running
+const pgCtlStatusNotRunning = 3
+
+// StatusDaemon serves the gpstatus.Status of this pod as JSON
+type StatusDaemon struct {
+	*starter.App
+	Hostname func() (string, error)
+	// Addr is the address to listen on. The zero value listens on gpstatus.Port.
+	Addr string
+}
+
+func (d *StatusDaemon) Run(ctx context.Context) error {
+	addr := d.Addr
+	if addr == "" {
+		addr = net.JoinHostPort("", strconv.Itoa(gpstatus.Port))
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		Log.Error(err, "failed to listen for status requests")
+		return fmt.Errorf("status server is not running: %w", err)
+	}
+	Log.Info("starting status server", "address", listener.Addr().String())
+
+	server := &http.Server{Handler: d.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	serveErrorChan := make(chan error, 1)
+	go func() {
+		serveErrorChan <- server.Serve(listener)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			Log.Error(err, "failed to shut down status server")
+		}
+		return nil
+	case err := <-serveErrorChan:
+		Log.Error(err, "status server terminated")
+		return fmt.Errorf("status server is not running: %w", err)
+	}
+}
+
+func (d *StatusDaemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(gpstatus.Path, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		status, err := d.Status()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			Log.Error(err, "failed to write status")
+		}
+	})
+	return mux
+}
+
+func (d *StatusDaemon) Status() (*gpstatus.Status, error) {
+	hostname, err := d.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("getting hostname: %w", err)
+	}
+	status := localStatus(d.App, hostname)
+	if status.IsServing() {
+		status.Segments, err = d.segmentConfiguration()
+		if err != nil {
+			status.SegmentsError = err.Error()
+		}
+	}
+	return status, nil
+}
+
+// dataDirectory is the data directory of the master, primary or mirror on this pod. Mirrors run in a
+// different directory than primaries: see the gpinitsystem config in cluster/gpinitsystem_config.go.
+func dataDirectory(hostname string) string {
+	switch {
+	case strings.HasPrefix(hostname, "master-"):
+		return "/greenplum/data-1"
+	case strings.HasPrefix(hostname, "segment-b-"):
+		return "/greenplum/mirror/data"
+	default:
+		return "/greenplum/data"
+	}
+}
+
+// postmasterPort is the port of the master, primary or mirror on this pod
+func postmasterPort(hostname string) int {
+	switch {
+	case strings.HasPrefix(hostname, "master-"):
+		return 5432
+	case strings.HasPrefix(hostname, "segment-b-"):
+		return 50000
+	default:
+		return 40000
+	}
+}
+
+// localStatus is the status of this pod, without the segment configuration
+func localStatus(app *starter.App, hostname string) *gpstatus.Status {
+	isMaster := strings.HasPrefix(hostname, "master-")
+	dataDir := dataDirectory(hostname)
+
+	status := &gpstatus.Status{
+		Hostname:      hostname,
+		Role:          gpstatus.RoleUnknown,
+		Postmaster:    gpstatus.PostmasterStopped,
+		DataDirectory: dataDirectoryStatus(app, dataDir),
+		Logs:          logUsage(app, dataDir),
+	}
+	if isMaster {
+		status.MasterStartup = masterStartup(app)
+	}
+	if !status.DataDirectory.Initialized {
+		return status
+	}
+
+	_, err := app.Fs.Stat(filepath.Join(dataDir, "recovery.conf"))
+	inRecovery := err == nil
+	switch {
+	case isMaster && inRecovery:
+		status.Role = gpstatus.RoleStandbyMaster
+	case isMaster:
+		status.Role = gpstatus.RoleActiveMaster
+	case inRecovery:
+		status.Role = gpstatus.RoleMirror
+	default:
+		status.Role = gpstatus.RolePrimary
+	}
+
+	status.Postmaster = postmasterState(app, dataDir)
+	return status
+}
+
+// masterStartup reads the decision that the master on this pod recorded when it last started, or nil if
+// it did not record one
+func masterStartup(app *starter.App) *gpstatus.MasterStartup {
+	contents, err := vfs.ReadFile(app.Fs, masterStartupFilename)
+	if err != nil {
+		return nil
+	}
+	var startup gpstatus.MasterStartup
+	if err := json.Unmarshal(contents, &startup); err != nil {
+		Log.Error(err, "failed to read the master startup", "file", masterStartupFilename)
+		return nil
+	}
+	return &startup
+}
+
+func dataDirectoryStatus(app *starter.App, dataDir string) gpstatus.DataDirectoryStatus {
+	status := gpstatus.DataDirectoryStatus{Path: dataDir}
+	if _, err := app.Fs.Stat(filepath.Join(dataDir, "PG_VERSION")); err == nil {
+		status.Initialized = true
+	}
+
+	// Write next to the data directory, since it may not exist yet
+	checkFile := filepath.Join(filepath.Dir(dataDir), ".status-check")
+	if err := vfs.WriteFile(app.Fs, checkFile, []byte(time.Now().UTC().Format(time.RFC3339)), 0600); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if err := app.Fs.Remove(checkFile); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Writable = true
+	return status
+}
+
+func logUsage(app *starter.App, dataDir string) gpstatus.LogUsage {
+	enforcer := &logretention.Enforcer{Fs: app.Fs, Dirs: logDirs(dataDir)}
+	usage, err := enforcer.Usage()
+	if err != nil {
+		return gpstatus.LogUsage{Error: err.Error()}
+	}
+	return gpstatus.LogUsage{Files: usage.Files, Bytes: usage.Bytes}
+}
+
+func postmasterState(app *starter.App, dataDir string) gpstatus.PostmasterState {
+	cmd := cluster.NewGreenplumCommand(app.Command).Command("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", dataDir)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return gpstatus.PostmasterRunning
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == pgCtlStatusNotRunning:
+		return gpstatus.PostmasterStopped
+	default:
+		Log.Error(err, "pg_ctl status failed")
+		return gpstatus.PostmasterUnknown
+	}
+}
+
+func (d *StatusDaemon) segmentConfiguration() ([]gpstatus.Segment, error) {
+	cmd := cluster.NewGreenplumCommand(d.Command).Command("/usr/local/greenplum-db/bin/psql",
+		"-X", "-d", "postgres", "-t", "-A", "-F", "|", "-c", gpstatus.SegmentConfigurationQuery)
+	// Don't let a hung master hold up the status of this pod
+	cmd.Env = append(cmd.Env, "PGCONNECT_TIMEOUT=5", "PGOPTIONS=-c statement_timeout=5000")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("psql failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return gpstatus.ParseSegments(stdout.String())
+}
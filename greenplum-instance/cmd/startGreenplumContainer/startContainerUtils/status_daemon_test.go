@@ -0,0 +1,286 @@
+package startContainerUtils_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-instance/cmd/startGreenplumContainer/startContainerUtils"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/commandable"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/starter"
+)
+
+var _ = Describe("StatusDaemon", func() {
+	var (
+		daemon    *startContainerUtils.StatusDaemon
+		outBuffer *gbytes.Buffer
+		memoryfs  vfs.Filesystem
+		fakeCmd   *commandable.CommandFake
+		hostname  string
+	)
+	BeforeEach(func() {
+		fakeCmd = commandable.NewFakeCommand()
+		outBuffer = gbytes.NewBuffer()
+		startContainerUtils.Log = gplog.ForTest(outBuffer)
+		memoryfs = memfs.Create()
+		Expect(vfs.MkdirAll(memoryfs, "/greenplum", 0755)).To(Succeed())
+		hostname = "master-0"
+		daemon = &startContainerUtils.StatusDaemon{
+			App: &starter.App{
+				Command:      fakeCmd.Command,
+				StdoutBuffer: outBuffer,
+				StderrBuffer: outBuffer,
+				Fs:           memoryfs,
+			},
+			Hostname: func() (string, error) { return hostname, nil },
+		}
+	})
+
+	initializeDataDir := func(dataDir string) {
+		Expect(vfs.MkdirAll(memoryfs, dataDir, 0700)).To(Succeed())
+		Expect(vfs.WriteFile(memoryfs, dataDir+"/PG_VERSION", []byte("9.4\n"), 0600)).To(Succeed())
+	}
+
+	Describe("Status()", func() {
+		When("the data directory is not initialized", func() {
+			It("reports an unknown role and a stopped postmaster", func() {
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status).To(Equal(&gpstatus.Status{
+					Hostname:   "master-0",
+					Role:       gpstatus.RoleUnknown,
+					Postmaster: gpstatus.PostmasterStopped,
+					DataDirectory: gpstatus.DataDirectoryStatus{
+						Path:     "/greenplum/data-1",
+						Writable: true,
+					},
+				}))
+				Expect(fakeCmd.CapturedArgs()).To(BeEmpty())
+				_, err = memoryfs.Stat("/greenplum/.status-check")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		When("the volume cannot be written", func() {
+			BeforeEach(func() {
+				daemon.Fs = vfs.ReadOnly(memoryfs)
+			})
+			It("reports the data directory as not writable", func() {
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.DataDirectory.Writable).To(BeFalse())
+				Expect(status.DataDirectory.Error).To(ContainSubstring("read-only"))
+			})
+		})
+
+		When("the pod has logs", func() {
+			BeforeEach(func() {
+				Expect(vfs.MkdirAll(memoryfs, "/greenplum/data-1/pg_log", 0700)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/greenplum/data-1/pg_log/gpdb-2020-06-01_000000.csv", []byte("0123456789"), 0600)).To(Succeed())
+				Expect(vfs.MkdirAll(memoryfs, "/home/gpadmin/gpAdminLogs", 0700)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/home/gpadmin/gpAdminLogs/gpstart_20200601.log", []byte("01234"), 0600)).To(Succeed())
+			})
+			It("reports the space they take", func() {
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Logs).To(Equal(gpstatus.LogUsage{Files: 2, Bytes: 15}))
+			})
+		})
+
+		When("the pod is the active master", func() {
+			BeforeEach(func() {
+				initializeDataDir("/greenplum/data-1")
+			})
+			It("reports the segment configuration", func() {
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1")
+				fakeCmd.ExpectCommandMatching(func(path string, args ...string) bool {
+					return path == "/usr/local/greenplum-db/bin/psql"
+				}).PrintsOutput("-1|p|p|n|u|master-0|5432\n-1|m|m|s|u|master-1|5432\n0|p|p|s|u|segment-a-0|40000\n0|m|m|s|d|segment-b-0|50000\n")
+
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Role).To(Equal(gpstatus.RoleActiveMaster))
+				Expect(status.Postmaster).To(Equal(gpstatus.PostmasterRunning))
+				Expect(status.DataDirectory).To(Equal(gpstatus.DataDirectoryStatus{Path: "/greenplum/data-1", Initialized: true, Writable: true}))
+				Expect(status.SegmentsError).To(BeEmpty())
+				Expect(status.Segments).To(HaveLen(4))
+				Expect(status.Segments[2]).To(Equal(gpstatus.Segment{
+					ContentID: 0, Role: "p", PreferredRole: "p", Mode: "s", Status: "u", Hostname: "segment-a-0", Port: 40000,
+				}))
+				Expect(status.DownSegments()).To(ConsistOf(HaveField("Hostname", "segment-b-0")))
+			})
+			It("reports why the segment configuration could not be read", func() {
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1")
+				fakeCmd.ExpectCommandMatching(func(path string, args ...string) bool {
+					return path == "/usr/local/greenplum-db/bin/psql"
+				}).PrintsError("psql: FATAL:  the database system is starting up").ReturnsStatus(2)
+
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.IsServing()).To(BeTrue())
+				Expect(status.Segments).To(BeEmpty())
+				Expect(status.SegmentsError).To(Equal("psql failed: exit status 2: psql: FATAL:  the database system is starting up"))
+			})
+			It("reports a stopped postmaster", func() {
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1").ReturnsStatus(3)
+
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Role).To(Equal(gpstatus.RoleActiveMaster))
+				Expect(status.Postmaster).To(Equal(gpstatus.PostmasterStopped))
+				Expect(status.IsServing()).To(BeFalse())
+				Expect(status.Segments).To(BeNil())
+			})
+			It("reports an unknown postmaster state when pg_ctl fails", func() {
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1").ReturnsStatus(4)
+
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Postmaster).To(Equal(gpstatus.PostmasterUnknown))
+			})
+		})
+
+		When("the pod is the standby master", func() {
+			BeforeEach(func() {
+				hostname = "master-1"
+				initializeDataDir("/greenplum/data-1")
+				Expect(vfs.WriteFile(memoryfs, "/greenplum/data-1/recovery.conf", nil, 0600)).To(Succeed())
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data-1")
+			})
+			It("does not report the segment configuration", func() {
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Role).To(Equal(gpstatus.RoleStandbyMaster))
+				Expect(status.Postmaster).To(Equal(gpstatus.PostmasterRunning))
+				Expect(status.Segments).To(BeNil())
+			})
+			It("reports how the master last started", func() {
+				Expect(vfs.WriteFile(memoryfs, "/greenplum/master-startup.json",
+					[]byte(`{"decision":"StartedStandby","message":"master-1 started as the standby master","time":"2026-10-19T08:00:00Z"}`), 0644)).To(Succeed())
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.MasterStartup).To(Equal(&gpstatus.MasterStartup{
+					Decision: gpstatus.StartedStandby,
+					Message:  "master-1 started as the standby master",
+					Time:     time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC),
+				}))
+			})
+		})
+
+		When("the pod is a segment-a pod", func() {
+			BeforeEach(func() {
+				hostname = "segment-a-0"
+				initializeDataDir("/greenplum/data")
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/data")
+			})
+			It("is a primary", func() {
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Role).To(Equal(gpstatus.RolePrimary))
+				Expect(status.Postmaster).To(Equal(gpstatus.PostmasterRunning))
+				Expect(status.DataDirectory.Path).To(Equal("/greenplum/data"))
+			})
+		})
+
+		When("the pod is a segment-b pod", func() {
+			BeforeEach(func() {
+				hostname = "segment-b-0"
+				initializeDataDir("/greenplum/mirror/data")
+				fakeCmd.ExpectCommand("/usr/local/greenplum-db/bin/pg_ctl", "status", "-D", "/greenplum/mirror/data")
+			})
+			It("is a mirror while it replicates", func() {
+				Expect(vfs.WriteFile(memoryfs, "/greenplum/mirror/data/recovery.conf", nil, 0600)).To(Succeed())
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Role).To(Equal(gpstatus.RoleMirror))
+				Expect(status.Postmaster).To(Equal(gpstatus.PostmasterRunning))
+				Expect(status.DataDirectory.Path).To(Equal("/greenplum/mirror/data"))
+			})
+			It("is a primary after it was promoted", func() {
+				status, err := daemon.Status()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Role).To(Equal(gpstatus.RolePrimary))
+				Expect(status.DataDirectory.Path).To(Equal("/greenplum/mirror/data"))
+			})
+		})
+
+		When("the hostname cannot be read", func() {
+			BeforeEach(func() {
+				daemon.Hostname = func() (string, error) { return "", errors.New("no hostname") }
+			})
+			It("returns an error", func() {
+				_, err := daemon.Status()
+				Expect(err).To(MatchError("getting hostname: no hostname"))
+			})
+		})
+	})
+
+	Describe("Handler()", func() {
+		It("serves the status as JSON", func() {
+			recorder := httptest.NewRecorder()
+			daemon.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+			var status gpstatus.Status
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &status)).To(Succeed())
+			Expect(status.Hostname).To(Equal("master-0"))
+			Expect(status.Role).To(Equal(gpstatus.RoleUnknown))
+		})
+		It("only supports GET", func() {
+			recorder := httptest.NewRecorder()
+			daemon.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/status", nil))
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+		It("returns an error when the status cannot be read", func() {
+			daemon.Hostname = func() (string, error) { return "", errors.New("no hostname") }
+			recorder := httptest.NewRecorder()
+			daemon.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("getting hostname: no hostname"))
+		})
+	})
+
+	Describe("Run()", func() {
+		It("serves until the context is done", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			daemon.Addr = listener.Addr().String()
+			Expect(listener.Close()).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			resultChan := make(chan error)
+			go func() {
+				resultChan <- daemon.Run(ctx)
+			}()
+
+			client := &gpstatus.Client{Address: func(string, string) string { return daemon.Addr }}
+			Eventually(func() error {
+				_, err := client.Get(context.Background(), "test-ns", "master-0")
+				return err
+			}, 3*time.Second).Should(Succeed())
+
+			cancel()
+			Eventually(resultChan, 3*time.Second).Should(Receive(BeNil()))
+		})
+		It("returns an error when it cannot listen", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+			daemon.Addr = listener.Addr().String()
+
+			err = daemon.Run(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("status server is not running")))
+		})
+	})
+})
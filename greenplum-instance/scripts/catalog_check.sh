@@ -0,0 +1,31 @@
+#!/usr/bin/env bash
+#
+# Runs gpcheckcat for the catalogCheck of a GreenplumCluster on the active master:
+#
+#   catalog_check.sh [database]...
+#
+# Without databases, every database that allows connections is checked. gpcheckcat
+# runs in online mode, and generates repair scripts under /greenplum/gpcheckcat.
+
+set -uo pipefail
+source /usr/local/greenplum-db/greenplum_path.sh
+
+repairDir=/greenplum/gpcheckcat/repair.$(date -u +%Y%m%d%H%M%S)
+databases=("$@")
+if [ ${#databases[@]} -eq 0 ]; then
+    mapfile -t databases < <(psql -X -d postgres -tAc "SELECT datname FROM pg_database WHERE datallowconn ORDER BY datname")
+    if [ ${#databases[@]} -eq 0 ]; then
+        echo "unable to list databases"
+        exit 1
+    fi
+fi
+
+status=0
+for database in "${databases[@]}"; do
+    echo "Checking database: $database"
+    gpcheckcat -O -g "$repairDir/$database" "$database" || status=1
+done
+if [ -n "$(ls -A "$repairDir" 2>/dev/null)" ]; then
+    echo "Repair scripts: $(hostname):$repairDir"
+fi
+exit $status
@@ -0,0 +1,18 @@
+#!/usr/bin/env bash
+#
+# Runs catalog_check.sh on $CATALOG_CHECK_HOST. The lines of its output that the operator
+# parses are written to the termination message of the container, which is limited to 4096 bytes.
+
+set -o pipefail
+
+mkdir -p /home/gpadmin/.ssh
+ssh-keyscan -H "$CATALOG_CHECK_HOST" >> /home/gpadmin/.ssh/known_hosts
+/usr/bin/ssh -i /etc/ssh-key/id_rsa "$CATALOG_CHECK_HOST" \
+    "/home/gpadmin/tools/catalog_check.sh $(printf '%q ' "$@")" | tee /tmp/catalog_check.log
+status=${PIPESTATUS[0]}
+
+# gpcheckcat prefixes its log lines with "<timestamp> gpcheckcat:<host>:<user>-[<level>]:-"
+sed -E 's/^.*-\[[A-Z]+\]:-//' /tmp/catalog_check.log |
+    grep -E '^(Checking database: |Completed [0-9]+ test|Found [0-9]+ catalog issue|Found no catalog issue| *Test: |Table name: |Name of test which found this issue: |Repair scripts: )' |
+    head -c 4096 > /dev/termination-log
+exit "$status"
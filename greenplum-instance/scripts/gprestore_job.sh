@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+
+mkdir -p /home/gpadmin/.ssh
+ssh-keyscan -H "$GPRESTORE_HOST" >> /home/gpadmin/.ssh/known_hosts
+/usr/bin/ssh -i /etc/ssh-key/id_rsa "$GPRESTORE_HOST" \
+    "source /usr/local/greenplum-db/greenplum_path.sh && gprestore $(printf '%q ' "$@")"
@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+
+mkdir -p /home/gpadmin/.ssh
+ssh-keyscan -H "$GPSTOP_HOST" >> /home/gpadmin/.ssh/known_hosts
+/usr/bin/ssh -i /etc/ssh-key/id_rsa "$GPSTOP_HOST" \
+    "source /usr/local/greenplum-db/greenplum_path.sh && gpstop $(printf '%q ' "$@")"
@@ -0,0 +1,109 @@
+#!/usr/bin/env bash
+#
+# Runs the maintenance tasks of a GreenplumMaintenance on the active master:
+#
+#   maintenance.sh [-p concurrency] [-d database]... [-s schema]... task...
+#
+# Each task is run on each database, in order:
+#   vacuumCatalog  VACUUM every table of pg_catalog
+#   analyze        analyzedb of the given schemas, or of every schema
+#   reindexBloatedTables
+#                  REINDEX TABLE the tables that gp_toolkit.gp_bloat_diag reports as
+#                  bloated. gp_bloat_diag measures table bloat, not index bloat, but
+#                  the updates and deletes that bloat a table usually bloat its
+#                  indexes too.
+# Without -d, every database that allows connections is maintained. Up to
+# "concurrency" databases are maintained at the same time.
+
+set -uo pipefail
+source /usr/local/greenplum-db/greenplum_path.sh
+
+concurrency=1
+databases=()
+schemas=()
+while getopts "p:d:s:" opt; do
+    case "$opt" in
+        p) concurrency="$OPTARG" ;;
+        d) databases+=("$OPTARG") ;;
+        s) schemas+=("$OPTARG") ;;
+        *) exit 2 ;;
+    esac
+done
+shift $((OPTIND - 1))
+tasks=("$@")
+schemaList=$(IFS=,; echo "${schemas[*]}")
+
+log() {
+    echo "$(date -u +%Y-%m-%dT%H:%M:%SZ) $*"
+}
+
+# Each statement that the first query prints is run in its own transaction, as VACUUM requires
+vacuumCatalog() {
+    psql -X -d "$1" -tAc "SELECT 'VACUUM pg_catalog.' || quote_ident(c.relname) || ';'
+        FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE n.nspname = 'pg_catalog' AND c.relkind = 'r'" |
+        psql -X -d "$1" -q -v ON_ERROR_STOP=1
+}
+
+analyze() {
+    if [ ${#schemas[@]} -eq 0 ]; then
+        analyzedb -a -d "$1"
+        return
+    fi
+    local schema
+    for schema in "${schemas[@]}"; do
+        analyzedb -a -d "$1" -s "$schema" || return
+    done
+}
+
+reindexBloatedTables() {
+    psql -X -d "$1" -tA -v schemas="$schemaList" <<'SQL' | psql -X -d "$1" -q -v ON_ERROR_STOP=1
+SELECT 'REINDEX TABLE ' || quote_ident(bdinspname) || '.' || quote_ident(bdirelname) || ';'
+FROM gp_toolkit.gp_bloat_diag
+WHERE :'schemas' = '' OR bdinspname = ANY (string_to_array(:'schemas', ','));
+SQL
+}
+
+maintainDatabase() {
+    local database="$1" task status=0
+    for task in "${tasks[@]}"; do
+        log "$database: started $task"
+        case "$task" in
+            vacuumCatalog) vacuumCatalog "$database" ;;
+            analyze) analyze "$database" ;;
+            reindexBloatedTables) reindexBloatedTables "$database" ;;
+            *) log "$database: unknown task $task"; false ;;
+        esac
+        if [ $? -ne 0 ]; then
+            log "$database: $task failed"
+            status=1
+        else
+            log "$database: finished $task"
+        fi
+    done
+    return $status
+}
+
+if [ ${#databases[@]} -eq 0 ]; then
+    mapfile -t databases < <(psql -X -d postgres -tAc "SELECT datname FROM pg_database WHERE datallowconn ORDER BY datname")
+    if [ ${#databases[@]} -eq 0 ]; then
+        log "unable to list databases"
+        exit 1
+    fi
+fi
+
+status=0
+running=0
+for database in "${databases[@]}"; do
+    if [ "$running" -ge "$concurrency" ]; then
+        wait -n || status=1
+        running=$((running - 1))
+    fi
+    maintainDatabase "$database" &
+    running=$((running + 1))
+done
+while [ "$running" -gt 0 ]; do
+    wait -n || status=1
+    running=$((running - 1))
+done
+exit $status
@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+
+mkdir -p /home/gpadmin/.ssh
+ssh-keyscan -H "$MAINTENANCE_HOST" >> /home/gpadmin/.ssh/known_hosts
+/usr/bin/ssh -i /etc/ssh-key/id_rsa "$MAINTENANCE_HOST" \
+    "/home/gpadmin/tools/maintenance.sh $(printf '%q ' "$@")"
@@ -17,8 +17,81 @@ type GreenplumClusterSpec struct {
 	MasterAndStandby GreenplumMasterAndStandbySpec `json:"masterAndStandby"`
 	Segments         GreenplumSegmentsSpec         `json:"segments"`
 	PXF              GreenplumPXFSpec              `json:"pxf,omitempty"`
+
+	// Whether the PVCs of the cluster are kept or deleted when the cluster is deleted
+	// +kubebuilder:default=Retain
+	// +kubebuilder:validation:Enum=Retain;Delete
+	PersistentVolumeClaimRetentionPolicy GreenplumPVCRetentionPolicy `json:"persistentVolumeClaimRetentionPolicy,omitempty"`
+
+	// Data to create the cluster with, instead of an empty database
+	DataSource *GreenplumDataSource `json:"dataSource,omitempty"`
+
+	// Periodic gpcheckcat runs. Omit to not check the catalog.
+	CatalogCheck *GreenplumCatalogCheckSpec `json:"catalogCheck,omitempty"`
+
+	// How long to keep the server and utility logs on the data volumes. Omit to keep them forever.
+	LogRetention *GreenplumLogRetentionSpec `json:"logRetention,omitempty"`
+}
+
+// GreenplumLogRetentionSpec limits the logs in pg_log and gpAdminLogs of each pod. Logs that are no longer
+// written to are compressed, and removed oldest first once they are past either limit.
+type GreenplumLogRetentionSpec struct {
+	// Logs last written longer ago than this are removed, such as "168h"
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+
+	// Logs are removed, oldest first, while the logs of a pod take more space than this.
+	// Quantity expressed with an SI suffix, like 500Mi, 2Gi, etc.
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+}
+
+// GreenplumCatalogCheckSpec schedules gpcheckcat runs on the cluster
+type GreenplumCatalogCheckSpec struct {
+	// When to run gpcheckcat, in cron format, such as "0 4 * * 6". Times are in the time zone of the operator, usually UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Databases to check. Defaults to every database that allows connections.
+	Databases []string `json:"databases,omitempty"`
+}
+
+// GreenplumDataSource is a snapshot or a backup to create a new cluster from. Specify exactly one.
+type GreenplumDataSource struct {
+	// Name of a Ready GreenplumSnapshot in the same namespace. The PVCs of the cluster are created from its VolumeSnapshots.
+	// +kubebuilder:validation:MinLength=1
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// A gpbackup backup to restore with gprestore once the cluster is initialized
+	Backup *GreenplumBackupSource `json:"backup,omitempty"`
 }
 
+type GreenplumBackupSource struct {
+	// The 14-digit timestamp of the backup
+	// +kubebuilder:validation:Pattern=`^[0-9]{14}$`
+	Timestamp string `json:"timestamp"`
+
+	// Directory holding the backup files on every host, as given to gpbackup --backup-dir
+	// +kubebuilder:validation:MinLength=1
+	BackupDir string `json:"backupDir,omitempty"`
+
+	// Path of a storage plugin configuration file on the master, as given to gpbackup --plugin-config
+	// +kubebuilder:validation:MinLength=1
+	PluginConfig string `json:"pluginConfig,omitempty"`
+
+	// Database to restore into, if not the database that was backed up
+	// +kubebuilder:validation:MinLength=1
+	RedirectDB string `json:"redirectDB,omitempty"`
+}
+
+// GreenplumPVCRetentionPolicy says what happens to the PVCs of a cluster when it is deleted
+type GreenplumPVCRetentionPolicy string
+
+const (
+	// Keep the PVCs, labeled with what is needed to recreate the cluster on them
+	GreenplumPVCRetentionPolicyRetain GreenplumPVCRetentionPolicy = "Retain"
+	// Delete the PVCs once the cluster is stopped
+	GreenplumPVCRetentionPolicyDelete GreenplumPVCRetentionPolicy = "Delete"
+)
+
 type GreenplumPodSpec struct {
 	// Quantity expressed with an SI suffix, like 2Gi, 200m, 3.5, etc.
 	Memory resource.Quantity `json:"memory,omitempty"`
@@ -40,6 +113,48 @@ type GreenplumPodSpec struct {
 	// +kubebuilder:default="no"
 	// +kubebuilder:validation:Pattern=`^(?:yes|Yes|YES|no|No|NO|)$`
 	AntiAffinity string `json:"antiAffinity,omitempty"`
+
+	// Node label whose values are the failure domains that antiAffinity keeps apart,
+	// such as kubernetes.io/hostname, topology.kubernetes.io/zone, or a rack label
+	// +kubebuilder:default="kubernetes.io/hostname"
+	// +kubebuilder:validation:MinLength=1
+	AntiAffinityTopologyKey string `json:"antiAffinityTopologyKey,omitempty"`
+
+	// Extra volumes to provision for each pod, in addition to the data volume
+	// +listType=map
+	// +listMapKey=name
+	AdditionalVolumes []GreenplumAdditionalVolume `json:"additionalVolumes,omitempty"`
+}
+
+type GreenplumVolumeUsage string
+
+const (
+	// A volume for user tablespaces, created with GreenplumTablespace resources
+	GreenplumVolumeUsageTablespace GreenplumVolumeUsage = "tablespace"
+	// A volume holding the tablespace named by temp_tablespaces
+	GreenplumVolumeUsageTemp GreenplumVolumeUsage = "temp"
+	// A volume holding the mirror segment data directories
+	GreenplumVolumeUsageMirror GreenplumVolumeUsage = "mirror"
+)
+
+type GreenplumAdditionalVolume struct {
+	// Name of the volume. The PVC is named <cluster>-<name> and mounted at /greenplum/volumes/<name>
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=32
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9]*$`
+	Name string `json:"name"`
+
+	// Name of storage class to use for the volume's PVs
+	// +kubebuilder:validation:MinLength=1
+	StorageClassName string `json:"storageClassName"`
+
+	// Quantity expressed with an SI suffix, like 2Gi, 200m, 3.5, etc.
+	Storage resource.Quantity `json:"storage"`
+
+	// What the volume is used for: tablespace, temp or mirror
+	// +kubebuilder:default="tablespace"
+	// +kubebuilder:validation:Enum=tablespace;temp;mirror
+	Usage GreenplumVolumeUsage `json:"usage,omitempty"`
 }
 
 type GreenplumMasterAndStandbySpec struct {
@@ -87,11 +202,97 @@ type GreenplumClusterStatus struct {
 	InstanceImage   string                `json:"instanceImage,omitempty"`
 	OperatorVersion string                `json:"operatorVersion,omitempty"`
 	Phase           GreenplumClusterPhase `json:"phase,omitempty"`
+	// ActiveMaster is the master pod that was last seen accepting connections
+	ActiveMaster string `json:"activeMaster,omitempty"`
+	// The last restart requested with the greenplum.pivotal.io/restart annotation
+	Restart *GreenplumOperationStatus `json:"restart,omitempty"`
+	// The last reload requested with the greenplum.pivotal.io/reload annotation
+	Reload *GreenplumOperationStatus `json:"reload,omitempty"`
+	// The last and next runs of the catalogCheck schedule
+	CatalogCheck *GreenplumCatalogCheckStatus `json:"catalogCheck,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CatalogConsistentCondition is True when the last gpcheckcat run found no catalog issues
+const CatalogConsistentCondition = "CatalogConsistent"
+
+// ExpansionFailedCondition is True when the gpexpand Job of the cluster failed. Its message names the Job,
+// which is kept so its logs can be read. Deleting the Job retries the expansion.
+const ExpansionFailedCondition = "ExpansionFailed"
+
+// MasterStartupCondition is how the masters of a cluster with a standby were last started. Its reason is the
+// decision of the master that started last: True if the cluster or the standby started, Unknown while a master
+// that was failed over from waits for the active master, and False if the cluster must be started manually.
+const MasterStartupCondition = "MasterStartup"
+
+const (
+	// RestartAnnotation requests a restart of the cluster with gpstop -r when it is set on a
+	// GreenplumCluster to a value that has not been seen before, such as the time of the request
+	RestartAnnotation = "greenplum.pivotal.io/restart"
+	// ReloadAnnotation requests a reload of the configuration files with gpstop -u, in the same way
+	ReloadAnnotation = "greenplum.pivotal.io/reload"
+	// RetainWithoutStopAnnotation set to "true" lets a GreenplumCluster with the Retain
+	// persistentVolumeClaimRetentionPolicy be deleted when gpstop fails. Its PVCs are retained from a cluster
+	// that was not stopped cleanly.
+	RetainWithoutStopAnnotation = "greenplum.pivotal.io/retain-without-stop"
+)
+
+type GreenplumOperationPhase string
+
+const (
+	GreenplumOperationPhaseRunning   GreenplumOperationPhase = "Running"
+	GreenplumOperationPhaseSucceeded GreenplumOperationPhase = "Succeeded"
+	GreenplumOperationPhaseFailed    GreenplumOperationPhase = "Failed"
+	// The operation was not started, because it conflicts with another operation on the cluster
+	GreenplumOperationPhaseRefused GreenplumOperationPhase = "Refused"
+)
+
+// GreenplumOperationStatus is the progress of an operation requested with an annotation
+type GreenplumOperationStatus struct {
+	// The annotation value of the request
+	RequestedAt string                  `json:"requestedAt"`
+	Phase       GreenplumOperationPhase `json:"phase"`
+	// When the operation succeeded, failed or was refused
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// Why the operation failed or was refused
+	Message string `json:"message,omitempty"`
+	// Name of the Job that runs the operation. It is kept until the next request so its logs can be read.
+	JobName string `json:"jobName,omitempty"`
+}
+
+// GreenplumCatalogCheckStatus is the result of the last gpcheckcat run
+type GreenplumCatalogCheckStatus struct {
+	// Name of the Job of the last run. It is kept until the next run so its logs can be read.
+	JobName string `json:"jobName,omitempty"`
+	// The scheduled time of the last run
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// When the next run is scheduled
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+	// When the last run finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// The checks that found issues in the last run
+	FailedChecks []GreenplumCatalogCheckFailure `json:"failedChecks,omitempty"`
+	// Where gpcheckcat generated SQL scripts to repair the issues, as <master pod>:<directory>
+	RepairScripts string `json:"repairScripts,omitempty"`
+	// Why catalogCheck is not running as scheduled
+	Message string `json:"message,omitempty"`
+}
+
+// GreenplumCatalogCheckFailure is a gpcheckcat test that found issues in a database
+type GreenplumCatalogCheckFailure struct {
+	Database string `json:"database"`
+	// The gpcheckcat test, such as missing_extraneous or foreign_key
+	Check string `json:"check"`
+	// The tables that the issues were found on
+	Tables []string `json:"tables,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`,description="The greenplum instance status"
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description="The greenplum instance age"
+// +kubebuilder:printcolumn:name="Active Master",type=string,JSONPath=`.status.activeMaster`,priority=1,description="The active greenplum master pod"
 // +kubebuilder:resource:categories=all
 
 // GreenplumCluster is the Schema for the greenplumclusters API
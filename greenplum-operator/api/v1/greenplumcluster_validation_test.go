@@ -110,6 +110,13 @@ var _ = Describe("GreenplumCluster validation", func() {
 				Description: "The greenplum instance age",
 				JSONPath:    ".metadata.creationTimestamp",
 			},
+			{
+				Name:        "Active Master",
+				Type:        "string",
+				Description: "The active greenplum master pod",
+				JSONPath:    ".status.activeMaster",
+				Priority:    1,
+			},
 		}
 		Expect(apiCrd.Spec.AdditionalPrinterColumns).To(Equal(expectedAdditionalPrinterColumns))
 	})
@@ -295,6 +302,21 @@ var _ = Describe("GreenplumCluster validation", func() {
 				required := apiCrd.Spec.Validation.OpenAPIV3Schema.Properties["spec"].Required
 				Expect(required).To(ConsistOf("masterAndStandby", "segments"))
 			})
+
+			It("keeps PVCs when the cluster is deleted by default", func() {
+				defaultValueRetain := apiextensions.JSON("Retain")
+				spec := apiCrd.Spec.Validation.OpenAPIV3Schema.Properties["spec"]
+				Expect(spec.Properties["persistentVolumeClaimRetentionPolicy"].Default).To(Equal(&defaultValueRetain))
+			})
+
+			It("accepts Retain and Delete for persistentVolumeClaimRetentionPolicy", func() {
+				for _, policy := range []greenplumv1.GreenplumPVCRetentionPolicy{"Retain", "Delete"} {
+					greenplumCluster.Spec.PersistentVolumeClaimRetentionPolicy = policy
+					Expect(validator.Validate(greenplumCluster).IsValid()).To(BeTrue())
+				}
+				greenplumCluster.Spec.PersistentVolumeClaimRetentionPolicy = "Forever"
+				Expect(validator.Validate(greenplumCluster).IsValid()).To(BeFalse())
+			})
 		})
 
 		Context("status", func() {
@@ -9,16 +9,122 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumAdditionalVolume) DeepCopyInto(out *GreenplumAdditionalVolume) {
+	*out = *in
+	out.Storage = in.Storage.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumAdditionalVolume.
+func (in *GreenplumAdditionalVolume) DeepCopy() *GreenplumAdditionalVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumAdditionalVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumBackupSource) DeepCopyInto(out *GreenplumBackupSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumBackupSource.
+func (in *GreenplumBackupSource) DeepCopy() *GreenplumBackupSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumBackupSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumCatalogCheckFailure) DeepCopyInto(out *GreenplumCatalogCheckFailure) {
+	*out = *in
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumCatalogCheckFailure.
+func (in *GreenplumCatalogCheckFailure) DeepCopy() *GreenplumCatalogCheckFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumCatalogCheckFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumCatalogCheckSpec) DeepCopyInto(out *GreenplumCatalogCheckSpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumCatalogCheckSpec.
+func (in *GreenplumCatalogCheckSpec) DeepCopy() *GreenplumCatalogCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumCatalogCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumCatalogCheckStatus) DeepCopyInto(out *GreenplumCatalogCheckStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FailedChecks != nil {
+		in, out := &in.FailedChecks, &out.FailedChecks
+		*out = make([]GreenplumCatalogCheckFailure, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumCatalogCheckStatus.
+func (in *GreenplumCatalogCheckStatus) DeepCopy() *GreenplumCatalogCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumCatalogCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GreenplumCluster) DeepCopyInto(out *GreenplumCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumCluster.
@@ -77,6 +183,21 @@ func (in *GreenplumClusterSpec) DeepCopyInto(out *GreenplumClusterSpec) {
 	in.MasterAndStandby.DeepCopyInto(&out.MasterAndStandby)
 	in.Segments.DeepCopyInto(&out.Segments)
 	out.PXF = in.PXF
+	if in.DataSource != nil {
+		in, out := &in.DataSource, &out.DataSource
+		*out = new(GreenplumDataSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CatalogCheck != nil {
+		in, out := &in.CatalogCheck, &out.CatalogCheck
+		*out = new(GreenplumCatalogCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogRetention != nil {
+		in, out := &in.LogRetention, &out.LogRetention
+		*out = new(GreenplumLogRetentionSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumClusterSpec.
@@ -92,6 +213,28 @@ func (in *GreenplumClusterSpec) DeepCopy() *GreenplumClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GreenplumClusterStatus) DeepCopyInto(out *GreenplumClusterStatus) {
 	*out = *in
+	if in.Restart != nil {
+		in, out := &in.Restart, &out.Restart
+		*out = new(GreenplumOperationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reload != nil {
+		in, out := &in.Reload, &out.Reload
+		*out = new(GreenplumOperationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CatalogCheck != nil {
+		in, out := &in.CatalogCheck, &out.CatalogCheck
+		*out = new(GreenplumCatalogCheckStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumClusterStatus.
@@ -104,6 +247,51 @@ func (in *GreenplumClusterStatus) DeepCopy() *GreenplumClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumDataSource) DeepCopyInto(out *GreenplumDataSource) {
+	*out = *in
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(GreenplumBackupSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumDataSource.
+func (in *GreenplumDataSource) DeepCopy() *GreenplumDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumLogRetentionSpec) DeepCopyInto(out *GreenplumLogRetentionSpec) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumLogRetentionSpec.
+func (in *GreenplumLogRetentionSpec) DeepCopy() *GreenplumLogRetentionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumLogRetentionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GreenplumMasterAndStandbySpec) DeepCopyInto(out *GreenplumMasterAndStandbySpec) {
 	*out = *in
@@ -120,6 +308,25 @@ func (in *GreenplumMasterAndStandbySpec) DeepCopy() *GreenplumMasterAndStandbySp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumOperationStatus) DeepCopyInto(out *GreenplumOperationStatus) {
+	*out = *in
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumOperationStatus.
+func (in *GreenplumOperationStatus) DeepCopy() *GreenplumOperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumOperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GreenplumPXFSpec) DeepCopyInto(out *GreenplumPXFSpec) {
 	*out = *in
@@ -148,6 +355,13 @@ func (in *GreenplumPodSpec) DeepCopyInto(out *GreenplumPodSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.AdditionalVolumes != nil {
+		in, out := &in.AdditionalVolumes, &out.AdditionalVolumes
+		*out = make([]GreenplumAdditionalVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumPodSpec.
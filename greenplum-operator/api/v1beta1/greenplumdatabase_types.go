@@ -0,0 +1,95 @@
+/*
+.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreenplumDeletionPolicy says what happens to the object in Greenplum when its resource is deleted
+type GreenplumDeletionPolicy string
+
+const (
+	GreenplumDeletionPolicyRetain GreenplumDeletionPolicy = "Retain"
+	GreenplumDeletionPolicyDelete GreenplumDeletionPolicy = "Delete"
+)
+
+// GreenplumDatabaseSpec defines the desired state of GreenplumDatabase
+type GreenplumDatabaseSpec struct {
+	// Name of the GreenplumCluster in the same namespace to create the database in
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// Name of the database in Greenplum. Defaults to the name of this resource. Cannot be changed.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name,omitempty"`
+
+	// Role that owns the database. Defaults to gpadmin.
+	// +kubebuilder:validation:MinLength=1
+	Owner string `json:"owner,omitempty"`
+
+	// Maximum number of concurrent connections to the database. Defaults to -1, no limit.
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// Whether to drop the database when this resource is deleted
+	// +kubebuilder:default=Retain
+	// +kubebuilder:validation:Enum=Retain;Delete
+	DeletionPolicy GreenplumDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+type GreenplumDatabasePhase string
+
+const (
+	GreenplumDatabasePhasePending GreenplumDatabasePhase = "Pending"
+	GreenplumDatabasePhaseCreated GreenplumDatabasePhase = "Created"
+	GreenplumDatabasePhaseFailed  GreenplumDatabasePhase = "Failed"
+)
+
+// GreenplumDatabaseStatus defines the observed state of GreenplumDatabase
+type GreenplumDatabaseStatus struct {
+	Phase GreenplumDatabasePhase `json:"phase,omitempty"`
+
+	// Reason the database is not yet created, or failed
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`,description="The greenplum cluster"
+// +kubebuilder:printcolumn:name="Owner",type=string,JSONPath=`.spec.owner`,description="The owner of the database"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`,description="The greenplum database status"
+// +kubebuilder:resource:categories=all
+
+// GreenplumDatabase is the Schema for the greenplumdatabases API
+type GreenplumDatabase struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreenplumDatabaseSpec   `json:"spec,omitempty"`
+	Status GreenplumDatabaseStatus `json:"status,omitempty"`
+}
+
+// DatabaseName is the name of the database in Greenplum
+func (d *GreenplumDatabase) DatabaseName() string {
+	if d.Spec.Name != "" {
+		return d.Spec.Name
+	}
+	return d.Name
+}
+
+// +kubebuilder:object:root=true
+
+// GreenplumDatabaseList contains a list of GreenplumDatabase
+type GreenplumDatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GreenplumDatabase `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GreenplumDatabase{}, &GreenplumDatabaseList{})
+}
@@ -0,0 +1,124 @@
+/*
+.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreenplumMaintenanceTask is a maintenance operation that a GreenplumMaintenance runs on each database
+// +kubebuilder:validation:Enum=vacuumCatalog;analyze;reindexBloatedTables
+type GreenplumMaintenanceTask string
+
+const (
+	// VACUUM of the system catalog tables
+	GreenplumMaintenanceTaskVacuumCatalog GreenplumMaintenanceTask = "vacuumCatalog"
+	// analyzedb of the tables whose statistics are out of date
+	GreenplumMaintenanceTaskAnalyze GreenplumMaintenanceTask = "analyze"
+	// REINDEX TABLE of the tables that gp_toolkit.gp_bloat_diag reports as bloated. gp_bloat_diag measures the
+	// bloat of tables, not of indexes, but the updates and deletes that bloat a table usually bloat its indexes too.
+	GreenplumMaintenanceTaskReindexBloatedTables GreenplumMaintenanceTask = "reindexBloatedTables"
+)
+
+// GreenplumSchemaName is the name of a schema. Maintenance passes schemas as a comma-separated list.
+// +kubebuilder:validation:Pattern=`^[^,]+$`
+type GreenplumSchemaName string
+
+// GreenplumMaintenanceSpec defines the desired state of GreenplumMaintenance
+type GreenplumMaintenanceSpec struct {
+	// Name of the GreenplumCluster in the same namespace to maintain
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// When to run, in cron format, such as "0 3 * * 0". Times are in the time zone of the operator, usually UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Tasks to run on each database, in order
+	// +kubebuilder:validation:MinItems=1
+	Tasks []GreenplumMaintenanceTask `json:"tasks"`
+
+	// Databases to maintain. Defaults to every database that allows connections.
+	Databases []string `json:"databases,omitempty"`
+
+	// Schemas to analyze and to reindex bloated tables in. Defaults to every schema. vacuumCatalog always vacuums pg_catalog.
+	Schemas []GreenplumSchemaName `json:"schemas,omitempty"`
+
+	// Number of databases to maintain at the same time
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	Concurrency int32 `json:"concurrency,omitempty"`
+}
+
+type GreenplumMaintenanceRunResult string
+
+const (
+	GreenplumMaintenanceRunResultRunning   GreenplumMaintenanceRunResult = "Running"
+	GreenplumMaintenanceRunResultSucceeded GreenplumMaintenanceRunResult = "Succeeded"
+	GreenplumMaintenanceRunResultFailed    GreenplumMaintenanceRunResult = "Failed"
+)
+
+// GreenplumMaintenanceRun is one run of the maintenance tasks
+type GreenplumMaintenanceRun struct {
+	// Name of the Job of the run. The Jobs of the runs in the history are kept so their logs can be read.
+	JobName string `json:"jobName"`
+	// The scheduled time of the run
+	ScheduleTime metav1.Time                   `json:"scheduleTime"`
+	Result       GreenplumMaintenanceRunResult `json:"result"`
+	// When the Job started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// When the Job succeeded or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// How long the Job ran
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// Why the run failed
+	Message string `json:"message,omitempty"`
+}
+
+// GreenplumMaintenanceStatus defines the observed state of GreenplumMaintenance
+type GreenplumMaintenanceStatus struct {
+	// Reason the maintenance is not running as scheduled
+	Message string `json:"message,omitempty"`
+
+	// The scheduled time of the last run, or of the last skipped run
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// When the next run is scheduled
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// The most recent runs, newest first
+	History []GreenplumMaintenanceRun `json:"history,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`,description="The greenplum cluster"
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`,description="When the maintenance runs"
+// +kubebuilder:printcolumn:name="Last Result",type=string,JSONPath=`.status.history[0].result`,description="The result of the most recent run"
+// +kubebuilder:printcolumn:name="Next Run",type=date,JSONPath=`.status.nextScheduleTime`,description="When the next run is scheduled"
+// +kubebuilder:resource:categories=all
+
+// GreenplumMaintenance is the Schema for the greenplummaintenances API
+type GreenplumMaintenance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreenplumMaintenanceSpec   `json:"spec,omitempty"`
+	Status GreenplumMaintenanceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GreenplumMaintenanceList contains a list of GreenplumMaintenance
+type GreenplumMaintenanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GreenplumMaintenance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GreenplumMaintenance{}, &GreenplumMaintenanceList{})
+}
@@ -0,0 +1,116 @@
+/*
+.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreenplumResourceGroupSettings are the limits of a resource group.
+// Exactly one of cpuRateLimit and cpuSet must be given.
+type GreenplumResourceGroupSettings struct {
+	// Maximum number of concurrent transactions in the resource group. Defaults to 20.
+	// +kubebuilder:validation:Minimum=0
+	Concurrency *int32 `json:"concurrency,omitempty"`
+
+	// Percentage of segment host CPU available to the resource group
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	CPURateLimit int32 `json:"cpuRateLimit,omitempty"`
+
+	// CPU cores reserved for the resource group, such as "1" or "1-3,5"
+	// +kubebuilder:validation:Pattern=`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`
+	CPUSet string `json:"cpuSet,omitempty"`
+
+	// Percentage of segment memory reserved for the resource group
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MemoryLimit int32 `json:"memoryLimit,omitempty"`
+
+	// Percentage of a transaction's memory quota at which memory-intensive operators spill to disk
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MemorySpillRatio int32 `json:"memorySpillRatio,omitempty"`
+}
+
+// GreenplumResourceGroupSpec defines the desired state of GreenplumResourceGroup
+type GreenplumResourceGroupSpec struct {
+	// Name of the GreenplumCluster in the same namespace to create the resource group in
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// Name of the resource group in Greenplum. Defaults to the name of this resource. Cannot be changed.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name,omitempty"`
+
+	GreenplumResourceGroupSettings `json:",inline"`
+
+	// Whether to drop the resource group when this resource is deleted
+	// +kubebuilder:default=Retain
+	// +kubebuilder:validation:Enum=Retain;Delete
+	DeletionPolicy GreenplumDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+type GreenplumResourceGroupPhase string
+
+const (
+	GreenplumResourceGroupPhasePending GreenplumResourceGroupPhase = "Pending"
+	GreenplumResourceGroupPhaseCreated GreenplumResourceGroupPhase = "Created"
+	GreenplumResourceGroupPhaseFailed  GreenplumResourceGroupPhase = "Failed"
+)
+
+// GreenplumResourceGroupStatus defines the observed state of GreenplumResourceGroup
+type GreenplumResourceGroupStatus struct {
+	Phase GreenplumResourceGroupPhase `json:"phase,omitempty"`
+
+	// Reason the resource group is not yet created, or failed
+	Message string `json:"message,omitempty"`
+
+	// Settings last applied to the resource group
+	AppliedSettings *GreenplumResourceGroupSettings `json:"appliedSettings,omitempty"`
+
+	// Settings that were found changed outside of this resource, and set back, at the last reconcile
+	Drift []string `json:"drift,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`,description="The greenplum cluster"
+// +kubebuilder:printcolumn:name="Concurrency",type=integer,JSONPath=`.spec.concurrency`,description="Maximum concurrent transactions"
+// +kubebuilder:printcolumn:name="CPU",type=integer,JSONPath=`.spec.cpuRateLimit`,description="Percentage of CPU"
+// +kubebuilder:printcolumn:name="Memory",type=integer,JSONPath=`.spec.memoryLimit`,description="Percentage of memory"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`,description="The greenplum resource group status"
+// +kubebuilder:resource:categories=all
+
+// GreenplumResourceGroup is the Schema for the greenplumresourcegroups API
+type GreenplumResourceGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreenplumResourceGroupSpec   `json:"spec,omitempty"`
+	Status GreenplumResourceGroupStatus `json:"status,omitempty"`
+}
+
+// ResourceGroupName is the name of the resource group in Greenplum
+func (g *GreenplumResourceGroup) ResourceGroupName() string {
+	if g.Spec.Name != "" {
+		return g.Spec.Name
+	}
+	return g.Name
+}
+
+// +kubebuilder:object:root=true
+
+// GreenplumResourceGroupList contains a list of GreenplumResourceGroup
+type GreenplumResourceGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GreenplumResourceGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GreenplumResourceGroup{}, &GreenplumResourceGroupList{})
+}
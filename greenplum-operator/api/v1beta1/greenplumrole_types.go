@@ -0,0 +1,98 @@
+/*
+.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreenplumRoleSpec defines the desired state of GreenplumRole
+type GreenplumRoleSpec struct {
+	// Name of the GreenplumCluster in the same namespace to create the role in
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// Name of the role in Greenplum. Defaults to the name of this resource. Cannot be changed.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name,omitempty"`
+
+	// Whether the role can log in
+	// +kubebuilder:default=true
+	Login *bool `json:"login,omitempty"`
+
+	// Maximum number of concurrent connections of the role. Defaults to -1, no limit.
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// Resource group to assign the role to. The resource group must already exist.
+	// +kubebuilder:validation:MinLength=1
+	ResourceGroup string `json:"resourceGroup,omitempty"`
+
+	// Roles that this role is a member of. Memberships that are not listed are revoked.
+	// +listType=set
+	MemberOf []string `json:"memberOf,omitempty"`
+
+	// Key of a Secret in the same namespace holding the password of the role
+	PasswordSecret *corev1.SecretKeySelector `json:"passwordSecret,omitempty"`
+
+	// Whether to drop the role when this resource is deleted
+	// +kubebuilder:default=Retain
+	// +kubebuilder:validation:Enum=Retain;Delete
+	DeletionPolicy GreenplumDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+type GreenplumRolePhase string
+
+const (
+	GreenplumRolePhasePending GreenplumRolePhase = "Pending"
+	GreenplumRolePhaseCreated GreenplumRolePhase = "Created"
+	GreenplumRolePhaseFailed  GreenplumRolePhase = "Failed"
+)
+
+// GreenplumRoleStatus defines the observed state of GreenplumRole
+type GreenplumRoleStatus struct {
+	Phase GreenplumRolePhase `json:"phase,omitempty"`
+
+	// Reason the role is not yet created, or failed
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`,description="The greenplum cluster"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`,description="The greenplum role status"
+// +kubebuilder:resource:categories=all
+
+// GreenplumRole is the Schema for the greenplumroles API
+type GreenplumRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreenplumRoleSpec   `json:"spec,omitempty"`
+	Status GreenplumRoleStatus `json:"status,omitempty"`
+}
+
+// RoleName is the name of the role in Greenplum
+func (r *GreenplumRole) RoleName() string {
+	if r.Spec.Name != "" {
+		return r.Spec.Name
+	}
+	return r.Name
+}
+
+// +kubebuilder:object:root=true
+
+// GreenplumRoleList contains a list of GreenplumRole
+type GreenplumRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GreenplumRole `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GreenplumRole{}, &GreenplumRoleList{})
+}
@@ -0,0 +1,106 @@
+/*
+.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreenplumSnapshotSpec defines the desired state of GreenplumSnapshot
+type GreenplumSnapshotSpec struct {
+	// Name of the GreenplumCluster in the same namespace to snapshot
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// VolumeSnapshotClass to snapshot the PVCs with. Defaults to the default class of their CSI driver.
+	// +kubebuilder:validation:MinLength=1
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+}
+
+type GreenplumSnapshotPhase string
+
+const (
+	// Waiting for the cluster to be running
+	GreenplumSnapshotPhasePending GreenplumSnapshotPhase = "Pending"
+	// The cluster is stopped, or about to be, while the storage system cuts the snapshots
+	GreenplumSnapshotPhaseQuiesced GreenplumSnapshotPhase = "Quiesced"
+	// The cluster is running again; the snapshots are not yet ready to restore from
+	GreenplumSnapshotPhaseSnapshotting GreenplumSnapshotPhase = "Snapshotting"
+	GreenplumSnapshotPhaseReady        GreenplumSnapshotPhase = "Ready"
+	GreenplumSnapshotPhaseFailed       GreenplumSnapshotPhase = "Failed"
+)
+
+// GreenplumSnapshotVolume is the snapshot of one PVC of the cluster
+type GreenplumSnapshotVolume struct {
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+	VolumeSnapshotName        string `json:"volumeSnapshotName"`
+	ReadyToUse                bool   `json:"readyToUse,omitempty"`
+}
+
+// GreenplumSegmentConfiguration is a row of gp_segment_configuration
+type GreenplumSegmentConfiguration struct {
+	DBID          int32  `json:"dbid"`
+	Content       int32  `json:"content"`
+	Role          string `json:"role"`
+	PreferredRole string `json:"preferredRole"`
+	Mode          string `json:"mode"`
+	Status        string `json:"status"`
+	Port          int32  `json:"port"`
+	Hostname      string `json:"hostname"`
+	Address       string `json:"address"`
+	DataDir       string `json:"dataDir"`
+}
+
+// GreenplumSnapshotStatus defines the observed state of GreenplumSnapshot
+type GreenplumSnapshotStatus struct {
+	Phase GreenplumSnapshotPhase `json:"phase,omitempty"`
+
+	// Reason the snapshot is not yet taken, or failed
+	Message string `json:"message,omitempty"`
+
+	// When the cluster was stopped. The snapshots hold the data as of this time.
+	SnapshotTime *metav1.Time `json:"snapshotTime,omitempty"`
+
+	// Master pod that was stopped, and must be started again. Empty while the cluster is running.
+	StoppedMaster string `json:"stoppedMaster,omitempty"`
+
+	// One VolumeSnapshot for each PVC of the cluster
+	VolumeSnapshots []GreenplumSnapshotVolume `json:"volumeSnapshots,omitempty"`
+
+	// Greenplum major version of the snapshotted data
+	GreenplumMajorVersion string `json:"greenplumMajorVersion,omitempty"`
+
+	// The segments of the cluster at the time of the snapshot
+	SegmentConfiguration []GreenplumSegmentConfiguration `json:"segmentConfiguration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`,description="The greenplum cluster"
+// +kubebuilder:printcolumn:name="Time",type=date,JSONPath=`.status.snapshotTime`,description="The time the snapshot holds the data of"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`,description="The greenplum snapshot status"
+// +kubebuilder:resource:categories=all
+
+// GreenplumSnapshot is the Schema for the greenplumsnapshots API
+type GreenplumSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreenplumSnapshotSpec   `json:"spec,omitempty"`
+	Status GreenplumSnapshotStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GreenplumSnapshotList contains a list of GreenplumSnapshot
+type GreenplumSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GreenplumSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GreenplumSnapshot{}, &GreenplumSnapshotList{})
+}
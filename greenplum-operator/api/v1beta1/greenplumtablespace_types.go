@@ -0,0 +1,74 @@
+/*
+.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GreenplumTablespaceSpec defines the desired state of GreenplumTablespace
+type GreenplumTablespaceSpec struct {
+	// Name of the GreenplumCluster in the same namespace to create the tablespace in
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// Name of an additionalVolume of the cluster with usage "tablespace".
+	// The tablespace is stored in a directory of that volume on every master and segment host.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Volume string `json:"volume"`
+
+	// Role that owns the tablespace. Defaults to the user running CREATE TABLESPACE (gpadmin).
+	// +kubebuilder:validation:MinLength=1
+	Owner string `json:"owner,omitempty"`
+}
+
+type GreenplumTablespacePhase string
+
+const (
+	GreenplumTablespacePhasePending GreenplumTablespacePhase = "Pending"
+	GreenplumTablespacePhaseCreated GreenplumTablespacePhase = "Created"
+	GreenplumTablespacePhaseFailed  GreenplumTablespacePhase = "Failed"
+)
+
+// GreenplumTablespaceStatus defines the observed state of GreenplumTablespace
+type GreenplumTablespaceStatus struct {
+	Phase GreenplumTablespacePhase `json:"phase,omitempty"`
+
+	// Directory holding the tablespace on each host
+	Location string `json:"location,omitempty"`
+
+	// Reason the tablespace is not yet created, or failed
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`,description="The greenplum cluster"
+// +kubebuilder:printcolumn:name="Volume",type=string,JSONPath=`.spec.volume`,description="The additional volume holding the tablespace"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`,description="The greenplum tablespace status"
+// +kubebuilder:resource:categories=all
+
+// GreenplumTablespace is the Schema for the greenplumtablespaces API
+type GreenplumTablespace struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GreenplumTablespaceSpec   `json:"spec,omitempty"`
+	Status GreenplumTablespaceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GreenplumTablespaceList contains a list of GreenplumTablespace
+type GreenplumTablespaceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GreenplumTablespace `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GreenplumTablespace{}, &GreenplumTablespaceList{})
+}
@@ -9,9 +9,253 @@
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumDatabase) DeepCopyInto(out *GreenplumDatabase) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumDatabase.
+func (in *GreenplumDatabase) DeepCopy() *GreenplumDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumDatabase) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumDatabaseList) DeepCopyInto(out *GreenplumDatabaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GreenplumDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumDatabaseList.
+func (in *GreenplumDatabaseList) DeepCopy() *GreenplumDatabaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumDatabaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumDatabaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumDatabaseSpec) DeepCopyInto(out *GreenplumDatabaseSpec) {
+	*out = *in
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumDatabaseSpec.
+func (in *GreenplumDatabaseSpec) DeepCopy() *GreenplumDatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumDatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumDatabaseStatus) DeepCopyInto(out *GreenplumDatabaseStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumDatabaseStatus.
+func (in *GreenplumDatabaseStatus) DeepCopy() *GreenplumDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumMaintenance) DeepCopyInto(out *GreenplumMaintenance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumMaintenance.
+func (in *GreenplumMaintenance) DeepCopy() *GreenplumMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumMaintenance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumMaintenanceList) DeepCopyInto(out *GreenplumMaintenanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GreenplumMaintenance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumMaintenanceList.
+func (in *GreenplumMaintenanceList) DeepCopy() *GreenplumMaintenanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumMaintenanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumMaintenanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumMaintenanceRun) DeepCopyInto(out *GreenplumMaintenanceRun) {
+	*out = *in
+	in.ScheduleTime.DeepCopyInto(&out.ScheduleTime)
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumMaintenanceRun.
+func (in *GreenplumMaintenanceRun) DeepCopy() *GreenplumMaintenanceRun {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumMaintenanceRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumMaintenanceSpec) DeepCopyInto(out *GreenplumMaintenanceSpec) {
+	*out = *in
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = make([]GreenplumMaintenanceTask, len(*in))
+		copy(*out, *in)
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]GreenplumSchemaName, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumMaintenanceSpec.
+func (in *GreenplumMaintenanceSpec) DeepCopy() *GreenplumMaintenanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumMaintenanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumMaintenanceStatus) DeepCopyInto(out *GreenplumMaintenanceStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]GreenplumMaintenanceRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumMaintenanceStatus.
+func (in *GreenplumMaintenanceStatus) DeepCopy() *GreenplumMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GreenplumPXFConf) DeepCopyInto(out *GreenplumPXFConf) {
 	*out = *in
@@ -131,6 +375,457 @@ func (in *GreenplumPXFServiceStatus) DeepCopy() *GreenplumPXFServiceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumResourceGroup) DeepCopyInto(out *GreenplumResourceGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumResourceGroup.
+func (in *GreenplumResourceGroup) DeepCopy() *GreenplumResourceGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumResourceGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumResourceGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumResourceGroupList) DeepCopyInto(out *GreenplumResourceGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GreenplumResourceGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumResourceGroupList.
+func (in *GreenplumResourceGroupList) DeepCopy() *GreenplumResourceGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumResourceGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumResourceGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumResourceGroupSettings) DeepCopyInto(out *GreenplumResourceGroupSettings) {
+	*out = *in
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumResourceGroupSettings.
+func (in *GreenplumResourceGroupSettings) DeepCopy() *GreenplumResourceGroupSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumResourceGroupSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumResourceGroupSpec) DeepCopyInto(out *GreenplumResourceGroupSpec) {
+	*out = *in
+	in.GreenplumResourceGroupSettings.DeepCopyInto(&out.GreenplumResourceGroupSettings)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumResourceGroupSpec.
+func (in *GreenplumResourceGroupSpec) DeepCopy() *GreenplumResourceGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumResourceGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumResourceGroupStatus) DeepCopyInto(out *GreenplumResourceGroupStatus) {
+	*out = *in
+	if in.AppliedSettings != nil {
+		in, out := &in.AppliedSettings, &out.AppliedSettings
+		*out = new(GreenplumResourceGroupSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumResourceGroupStatus.
+func (in *GreenplumResourceGroupStatus) DeepCopy() *GreenplumResourceGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumResourceGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumRole) DeepCopyInto(out *GreenplumRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumRole.
+func (in *GreenplumRole) DeepCopy() *GreenplumRole {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumRoleList) DeepCopyInto(out *GreenplumRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GreenplumRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumRoleList.
+func (in *GreenplumRoleList) DeepCopy() *GreenplumRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumRoleSpec) DeepCopyInto(out *GreenplumRoleSpec) {
+	*out = *in
+	if in.Login != nil {
+		in, out := &in.Login, &out.Login
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumRoleSpec.
+func (in *GreenplumRoleSpec) DeepCopy() *GreenplumRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumRoleStatus) DeepCopyInto(out *GreenplumRoleStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumRoleStatus.
+func (in *GreenplumRoleStatus) DeepCopy() *GreenplumRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumSegmentConfiguration) DeepCopyInto(out *GreenplumSegmentConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumSegmentConfiguration.
+func (in *GreenplumSegmentConfiguration) DeepCopy() *GreenplumSegmentConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumSegmentConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumSnapshot) DeepCopyInto(out *GreenplumSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumSnapshot.
+func (in *GreenplumSnapshot) DeepCopy() *GreenplumSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumSnapshotList) DeepCopyInto(out *GreenplumSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GreenplumSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumSnapshotList.
+func (in *GreenplumSnapshotList) DeepCopy() *GreenplumSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumSnapshotSpec) DeepCopyInto(out *GreenplumSnapshotSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumSnapshotSpec.
+func (in *GreenplumSnapshotSpec) DeepCopy() *GreenplumSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumSnapshotStatus) DeepCopyInto(out *GreenplumSnapshotStatus) {
+	*out = *in
+	if in.SnapshotTime != nil {
+		in, out := &in.SnapshotTime, &out.SnapshotTime
+		*out = (*in).DeepCopy()
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = make([]GreenplumSnapshotVolume, len(*in))
+		copy(*out, *in)
+	}
+	if in.SegmentConfiguration != nil {
+		in, out := &in.SegmentConfiguration, &out.SegmentConfiguration
+		*out = make([]GreenplumSegmentConfiguration, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumSnapshotStatus.
+func (in *GreenplumSnapshotStatus) DeepCopy() *GreenplumSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumSnapshotVolume) DeepCopyInto(out *GreenplumSnapshotVolume) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumSnapshotVolume.
+func (in *GreenplumSnapshotVolume) DeepCopy() *GreenplumSnapshotVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumSnapshotVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumTablespace) DeepCopyInto(out *GreenplumTablespace) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumTablespace.
+func (in *GreenplumTablespace) DeepCopy() *GreenplumTablespace {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumTablespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumTablespace) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumTablespaceList) DeepCopyInto(out *GreenplumTablespaceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GreenplumTablespace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumTablespaceList.
+func (in *GreenplumTablespaceList) DeepCopy() *GreenplumTablespaceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumTablespaceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GreenplumTablespaceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumTablespaceSpec) DeepCopyInto(out *GreenplumTablespaceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumTablespaceSpec.
+func (in *GreenplumTablespaceSpec) DeepCopy() *GreenplumTablespaceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumTablespaceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GreenplumTablespaceStatus) DeepCopyInto(out *GreenplumTablespaceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GreenplumTablespaceStatus.
+func (in *GreenplumTablespaceStatus) DeepCopy() *GreenplumTablespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GreenplumTablespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3Source) DeepCopyInto(out *S3Source) {
 	*out = *in
@@ -0,0 +1,138 @@
+// greenplum-diag collects a support bundle of a Greenplum cluster into a gzipped tarball, and optionally
+// stores it in an S3-compatible bucket. It runs with the permissions of the current kubeconfig context.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Enable auth plugin for GCP
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/diagnostics"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var log = ctrl.Log.WithName("greenplum-diag")
+
+type DiagOptions struct {
+	Namespace         string `short:"n" long:"namespace" default:"default" description:"Namespace of the GreenplumCluster"`
+	OperatorNamespace string `long:"operatorNamespace" description:"Namespace of the Greenplum Operator. Looked up in every namespace if not given"`
+	Output            string `short:"o" long:"output" description:"File to write the bundle to. Defaults to greenplum-diag-<cluster>-<time>.tar.gz in the current directory"`
+	S3Endpoint        string `long:"s3Endpoint" description:"host:port of an S3-compatible service to also store the bundle in. The credentials are read from S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY"`
+	S3Bucket          string `long:"s3Bucket" description:"Bucket to store the bundle in"`
+	S3Folder          string `long:"s3Folder" description:"Folder of the bucket to store the bundle in"`
+	S3Region          string `long:"s3Region" description:"Region of the bucket. Looked up if not given"`
+	S3Insecure        bool   `long:"s3Insecure" description:"Connect to the S3 endpoint over plain HTTP"`
+	Args              struct {
+		ClusterName string `positional-arg-name:"cluster" required:"yes" description:"Name of the GreenplumCluster"`
+	} `positional-args:"yes"`
+}
+
+func main() {
+	ctrl.SetLogger(gplog.ForProd(false))
+	var options DiagOptions
+	parser := flags.NewParser(&options, flags.Default)
+	parser.Name = "greenplum-diag"
+	if _, err := parser.ParseArgs(os.Args[1:]); err != nil {
+		os.Exit(1) // ParseArgs() will have printed the error already.
+	}
+	if err := Run(context.Background(), options); err != nil {
+		log.Error(err, "failed to collect diagnostics")
+		os.Exit(1)
+	}
+}
+
+func Run(ctx context.Context, options DiagOptions) error {
+	var uploader diagnostics.Uploader
+	if options.S3Endpoint != "" || options.S3Bucket != "" {
+		location, err := GetS3LocationFromOptions(options, os.Getenv)
+		if err != nil {
+			return err
+		}
+		if uploader, err = diagnostics.NewS3Uploader(location); err != nil {
+			return err
+		}
+	}
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	k8sClient, err := client.New(config, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	collector := &diagnostics.Collector{
+		Client:            k8sClient,
+		PodExec:           executor.NewPodExec(scheme.Scheme, config),
+		Logs:              &diagnostics.ClientsetLogReader{Clientset: clientset},
+		OperatorNamespace: options.OperatorNamespace,
+	}
+
+	now := time.Now()
+	collector.Now = func() time.Time { return now }
+	output := options.Output
+	if output == "" {
+		output = diagnostics.Name(options.Args.ClusterName, now) + ".tar.gz"
+	}
+	cluster := types.NamespacedName{Namespace: options.Namespace, Name: options.Args.ClusterName}
+	log.Info("collecting diagnostics", "cluster", cluster.String())
+	var bundle bytes.Buffer
+	if err := collector.Collect(ctx, cluster, &bundle); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, bundle.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	log.Info("wrote bundle", "file", output)
+
+	if uploader != nil {
+		if err := uploader.Upload(ctx, filepath.Base(output), &bundle, int64(bundle.Len())); err != nil {
+			return err
+		}
+		log.Info("stored bundle", "bucket", options.S3Bucket, "folder", options.S3Folder)
+	}
+	return nil
+}
+
+// GetS3LocationFromOptions returns where to store the bundle. The credentials are read from the environment,
+// so that they do not show up in the shell history or the process list.
+func GetS3LocationFromOptions(options DiagOptions, getenv func(key string) string) (diagnostics.S3Location, error) {
+	location := diagnostics.S3Location{
+		Endpoint:        options.S3Endpoint,
+		Secure:          !options.S3Insecure,
+		Region:          options.S3Region,
+		Bucket:          options.S3Bucket,
+		Folder:          options.S3Folder,
+		AccessKeyID:     getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	switch {
+	case location.Endpoint == "":
+		return location, errors.New("--s3Endpoint is required to store the bundle in a bucket")
+	case location.Bucket == "":
+		return location, errors.New("--s3Bucket is required to store the bundle in a bucket")
+	case location.AccessKeyID == "":
+		return location, errors.New("S3_ACCESS_KEY_ID cannot be empty")
+	case location.SecretAccessKey == "":
+		return location, errors.New("S3_SECRET_ACCESS_KEY cannot be empty")
+	}
+	return location, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/diagnostics"
+)
+
+var _ = Describe("GetS3LocationFromOptions", func() {
+	var (
+		options DiagOptions
+		env     map[string]string
+	)
+	getenv := func(key string) string {
+		return env[key]
+	}
+	BeforeEach(func() {
+		options = DiagOptions{S3Endpoint: "minio.example.com:9000", S3Bucket: "support", S3Folder: "cases/1234"}
+		env = map[string]string{
+			"S3_ACCESS_KEY_ID":     "id",
+			"S3_SECRET_ACCESS_KEY": "key",
+		}
+	})
+
+	It("reads the credentials from the environment", func() {
+		Expect(GetS3LocationFromOptions(options, getenv)).To(Equal(diagnostics.S3Location{
+			Endpoint:        "minio.example.com:9000",
+			Secure:          true,
+			Bucket:          "support",
+			Folder:          "cases/1234",
+			AccessKeyID:     "id",
+			SecretAccessKey: "key",
+		}))
+	})
+
+	DescribeTable("requires",
+		func(modify func(), expectedMessage string) {
+			modify()
+			_, err := GetS3LocationFromOptions(options, getenv)
+			Expect(err).To(MatchError(expectedMessage))
+		},
+		Entry("an endpoint", func() { options.S3Endpoint = "" }, "--s3Endpoint is required to store the bundle in a bucket"),
+		Entry("a bucket", func() { options.S3Bucket = "" }, "--s3Bucket is required to store the bundle in a bucket"),
+		Entry("an access key ID", func() { delete(env, "S3_ACCESS_KEY_ID") }, "S3_ACCESS_KEY_ID cannot be empty"),
+		Entry("a secret access key", func() { delete(env, "S3_SECRET_ACCESS_KEY") }, "S3_SECRET_ACCESS_KEY cannot be empty"),
+	)
+})
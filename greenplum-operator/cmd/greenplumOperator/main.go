@@ -2,26 +2,34 @@ package main
 
 import (
 	"flag"
+	"net"
 	"os"
 	goruntime "runtime"
 
 	// Enable auth plugin for GCP
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 
+	"github.com/blang/vfs"
 	"github.com/go-logr/logr"
 	"github.com/jessevdk/go-flags"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/admission"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/sshkeygen"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/hostpod"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/multidaemon"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -44,16 +52,45 @@ func Run() error {
 
 	logGoInfo(setupLog)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme.Scheme,
-		MetricsBindAddress: ":8080",
-	})
+	if options.QuerierNetwork != "" {
+		if _, _, err := net.ParseCIDR(options.QuerierNetwork); err != nil {
+			return errors.Wrap(err, "parsing querierNetwork")
+		}
+	}
+	// The operator connects to the masters from its pods, unless querierNetwork is set
+	operatorNamespace, err := hostpod.GetCurrentNamespace(vfs.OS())
+	if err != nil {
+		return errors.Wrap(err, "getting operator namespace")
+	}
+
+	// The webhook runs outside of the manager, so it serves from every replica; only the controllers and the
+	// renewal of the webhook certificate are leader-elected
+	mgrOptions := ctrl.Options{
+		Scheme:                        scheme.Scheme,
+		MetricsBindAddress:            ":8080",
+		LeaderElection:                options.LeaderElect,
+		LeaderElectionID:              LeaderElectionID,
+		LeaderElectionReleaseOnCancel: true,
+		// Nodes are read rarely, and a namespace-scoped operator may not be allowed to watch them.
+		// Reading them directly returns Forbidden instead of waiting forever for an informer to sync.
+		ClientDisableCacheFor: []client.Object{&corev1.Node{}},
+	}
+	if len(options.WatchNamespaces) > 0 {
+		setupLog.Info("watching namespaces", "namespaces", options.WatchNamespaces)
+		mgrOptions.NewCache = cache.MultiNamespacedCacheBuilder(options.WatchNamespaces)
+	}
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		return err
 	}
 
 	podExec := executor.NewPodExec(mgr.GetScheme(), mgr.GetConfig())
+	// The gpadmin-password Secrets are owned by GreenplumClusters, so they are in the cache of the manager
+	greenplumQuerier := &querier.PQQuerier{Reader: mgr.GetClient()}
+	if options.QuerierTLS {
+		greenplumQuerier.SSLMode = "require"
+	}
 
 	instanceImage, err := GetInstanceImageFromEnv(os.Getenv)
 	if err != nil {
@@ -65,36 +102,106 @@ func Run() error {
 		return errors.Wrap(err, "getting operator image name")
 	}
 
-	// mgr.GetClient() will return a caching client. The caches aren't started until mgr.Start, but NewWebhook() calls the client to call GetThisPod.
+	// mgr.GetClient() will return a caching client. The caches aren't started until mgr.Start, but NewWebhook() calls the client to get the CRD.
 	// Instead, create a direct client for the Webhook to use.
 	// TODO: Consider how we could or should use caching for the webhook.
 	apiClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
 	if err != nil {
 		return errors.Wrap(err, "creating API client for webhook")
 	}
-	webhook, err := admission.NewWebhook(apiClient, mgr.GetConfig(), podExec, instanceImage)
+	webhook, err := admission.NewWebhook(apiClient, mgr.GetConfig(), greenplumQuerier, instanceImage, mgr.GetEventRecorderFor("greenplum-validating-webhook"), options.WatchNamespaces)
 	if err != nil {
 		return errors.Wrap(err, "creating webhook")
 	}
 
+	if err = mgr.Add(manager.RunnableFunc(webhook.RenewServingCertificate)); err != nil {
+		return errors.Wrap(err, "adding webhook certificate renewal")
+	}
+
 	if err = (&controllers.GreenplumPXFServiceReconciler{
 		Client:        mgr.GetClient(),
 		Log:           ctrl.Log.WithName("controllers").WithName("GreenplumPXFService"),
 		InstanceImage: instanceImage,
+		Recorder:      mgr.GetEventRecorderFor("greenplumpxfservice-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GreenplumPXFService")
 		return err
 	}
 
 	if err = (&greenplumcluster.GreenplumClusterReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("GreenplumCluster"),
+		SSHCreator:     sshkeygen.New(),
+		InstanceImage:  instanceImage,
+		OperatorImage:  operatorImage,
+		PodExec:        podExec,
+		Querier:        greenplumQuerier,
+		QuerierNetwork: options.QuerierNetwork,
+		QuerierTLS:     options.QuerierTLS,
+		// The operator namespace may not be watched, so its pods are read from the API server
+		OperatorNamespace: operatorNamespace,
+		APIReader:         mgr.GetAPIReader(),
+		Recorder:          mgr.GetEventRecorderFor("greenplumcluster-controller"),
+		StatusGetter:      &gpstatus.Client{},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumCluster")
+		return err
+	}
+
+	if err = (&controllers.GreenplumTablespaceReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("GreenplumTablespace"),
+		PodExec: podExec,
+		Querier: greenplumQuerier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumTablespace")
+		return err
+	}
+
+	if err = (&controllers.GreenplumDatabaseReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("GreenplumDatabase"),
+		PodExec: podExec,
+		Querier: greenplumQuerier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumDatabase")
+		return err
+	}
+
+	if err = (&controllers.GreenplumRoleReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("GreenplumRole"),
+		PodExec: podExec,
+		Querier: greenplumQuerier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumRole")
+		return err
+	}
+	if err = (&controllers.GreenplumResourceGroupReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("GreenplumResourceGroup"),
+		PodExec: podExec,
+		Querier: greenplumQuerier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumResourceGroup")
+		return err
+	}
+	if err = (&controllers.GreenplumSnapshotReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("GreenplumSnapshot"),
+		PodExec: podExec,
+		Querier: greenplumQuerier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumSnapshot")
+		return err
+	}
+	if err = (&controllers.GreenplumMaintenanceReconciler{
 		Client:        mgr.GetClient(),
-		Log:           ctrl.Log.WithName("controllers").WithName("GreenplumCluster"),
-		SSHCreator:    sshkeygen.New(),
+		Log:           ctrl.Log.WithName("controllers").WithName("GreenplumMaintenance"),
+		Querier:       greenplumQuerier,
 		InstanceImage: instanceImage,
-		OperatorImage: operatorImage,
-		PodExec:       podExec,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "GreenplumCluster")
+		setupLog.Error(err, "unable to create controller", "controller", "GreenplumMaintenance")
 		return err
 	}
 	// +kubebuilder:scaffold:builder
@@ -110,8 +217,14 @@ func logGoInfo(log logr.Logger) {
 	log.Info("Go Info", "Version", goruntime.Version(), "GOOS", goruntime.GOOS, "GOARCH", goruntime.GOARCH)
 }
 
+const LeaderElectionID = "greenplum-operator-leader"
+
 type GreenplumOperatorOptions struct {
-	LogLevel string `short:"v" long:"logLevel" default:"info" description:"Log verbosity" choice:"info" choice:"debug"`
+	LogLevel        string   `short:"v" long:"logLevel" default:"info" description:"Log verbosity" choice:"info" choice:"debug"`
+	LeaderElect     bool     `long:"leaderElect" description:"Elect a leader among the operator replicas to run the controllers"`
+	WatchNamespaces []string `long:"watchNamespace" description:"Only manage resources in this namespace. Repeat to watch several namespaces. Watches all namespaces if not given"`
+	QuerierNetwork  string   `long:"querierNetwork" description:"CIDR that the operator pods connect to the Greenplum masters from, such as the pod CIDR of the Kubernetes cluster. Defaults to the addresses of the operator pods"`
+	QuerierTLS      bool     `long:"querierTLS" description:"Connect to the Greenplum masters over SSL, and only allow the operator's role to connect over SSL. The masters must have ssl enabled"`
 }
 
 // Parse with both jessevdk/go-flags and the golang flag package
@@ -0,0 +1,204 @@
+// kubectl-gp is a kubectl plugin for the day-2 operations of a GreenplumCluster. kubectl runs it as "kubectl gp"
+// when it is on the PATH. It runs with the permissions of the current kubeconfig context.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	// Enable auth plugin for GCP
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Options struct {
+	Namespace string `short:"n" long:"namespace" description:"Namespace of the GreenplumCluster. Defaults to the namespace of the kubeconfig context"`
+	Cluster   string `short:"c" long:"cluster" description:"Name of the GreenplumCluster. Defaults to the only GreenplumCluster of the namespace"`
+	Output    string `short:"o" long:"output" default:"table" choice:"table" choice:"json" description:"Output format"`
+
+	Status   StatusCommand   `command:"status" description:"Show the phase of the cluster, and the role, mirror sync and status of each segment"`
+	Psql     PsqlCommand     `command:"psql" description:"Run psql on the active master. Arguments after -- are passed to psql"`
+	Expand   ExpandCommand   `command:"expand" description:"Add primary segments, and follow the log of the gpexpand Job"`
+	Failover FailoverCommand `command:"failover" description:"Promote the standby master with gpactivatestandby"`
+	Logs     LogsCommand     `command:"logs" description:"Print the log of a master or segment pod"`
+	Wait     WaitCommand     `command:"wait" description:"Wait for the cluster to reach a phase"`
+}
+
+type StatusCommand struct{}
+
+type PsqlCommand struct {
+	Database string `short:"d" long:"database" default:"gpadmin" description:"Database to connect to"`
+}
+
+type ExpandCommand struct {
+	Segments int32         `long:"segments" required:"yes" description:"New number of primary segments"`
+	Detach   bool          `long:"detach" description:"Return once the GreenplumCluster is patched, without following the gpexpand Job"`
+	Timeout  time.Duration `long:"timeout" default:"30m" description:"How long to follow the gpexpand Job"`
+}
+
+type FailoverCommand struct {
+	Force bool `long:"force" description:"Promote the standby even though the active master accepts connections"`
+}
+
+type LogsCommand struct {
+	Segment int   `long:"segment" default:"-1" description:"Content ID of the segment. -1 is the active master"`
+	Mirror  bool  `long:"mirror" description:"Print the log of the segment-b pod of the segment, or of the standby master"`
+	Follow  bool  `short:"f" long:"follow" description:"Keep printing the log as it is written"`
+	Tail    int64 `long:"tail" default:"-1" description:"Lines from the end of the log to print. -1 prints all lines"`
+}
+
+type WaitCommand struct {
+	For     string        `long:"for" required:"yes" description:"Phase to wait for: Pending, Running, Failed or Deleting"`
+	Timeout time.Duration `long:"timeout" default:"10m" description:"How long to wait"`
+}
+
+func main() {
+	ctrl.SetLogger(gplog.ForProd(false))
+	options, command, args, err := ParseOptions(os.Args[1:])
+	if err != nil {
+		os.Exit(1) // ParseArgs() will have printed the error already.
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if err := run(ctx, options, command, args); err != nil {
+		var execErr *executor.ExecError
+		if command == "psql" && errors.As(err, &execErr) && execErr.ExitCode > 0 {
+			os.Exit(execErr.ExitCode) // psql will have printed the error already.
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// ParseOptions returns the options, the name of the subcommand, and the arguments to pass to psql
+func ParseOptions(args []string) (*Options, string, []string, error) {
+	var options Options
+	parser := flags.NewParser(&options, flags.Default)
+	parser.Name = "kubectl-gp"
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	command := parser.Active.Name
+	if command != "psql" && len(args) > 0 {
+		err := fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+		fmt.Fprintln(os.Stderr, err)
+		return nil, "", nil, err
+	}
+	return &options, command, args, nil
+}
+
+func run(ctx context.Context, options *Options, command string, args []string) error {
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+	config, err := kubeConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	namespace := options.Namespace
+	if namespace == "" {
+		if namespace, _, err = kubeConfig.Namespace(); err != nil {
+			return fmt.Errorf("reading kubeconfig: %w", err)
+		}
+	}
+	k8sClient, err := client.New(config, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	clusterName := options.Cluster
+	if clusterName == "" {
+		if clusterName, err = kubectlgp.FindCluster(ctx, k8sClient, namespace); err != nil {
+			return err
+		}
+	}
+
+	plugin := &kubectlgp.Plugin{
+		Client:      k8sClient,
+		PodExec:     executor.NewPodExec(scheme.Scheme, config),
+		LogStreamer: &kubectlgp.ClientsetLogStreamer{Clientset: clientset},
+		Cluster:     types.NamespacedName{Namespace: namespace, Name: clusterName},
+		Output:      options.Output,
+		In:          os.Stdin,
+		Out:         os.Stdout,
+		ErrOut:      os.Stderr,
+	}
+	if command == "psql" {
+		stdinFd := int(os.Stdin.Fd())
+		if term.IsTerminal(stdinFd) {
+			state, err := term.MakeRaw(stdinFd)
+			if err != nil {
+				return fmt.Errorf("setting up terminal: %w", err)
+			}
+			defer term.Restore(stdinFd, state)
+			plugin.TTY = true
+			plugin.TerminalSize = &terminalSize{fd: stdinFd}
+		}
+	}
+	return Run(ctx, plugin, options, command, args)
+}
+
+// Run runs the subcommand named command
+func Run(ctx context.Context, plugin *kubectlgp.Plugin, options *Options, command string, args []string) error {
+	switch command {
+	case "status":
+		return plugin.Status(ctx)
+	case "psql":
+		return plugin.Psql(ctx, options.Psql.Database, args)
+	case "expand":
+		return plugin.Expand(ctx, options.Expand.Segments, options.Expand.Detach, options.Expand.Timeout)
+	case "failover":
+		return plugin.Failover(ctx, options.Failover.Force)
+	case "logs":
+		return plugin.Logs(ctx, kubectlgp.LogsOptions{
+			Segment:   options.Logs.Segment,
+			Mirror:    options.Logs.Mirror,
+			Follow:    options.Logs.Follow,
+			TailLines: options.Logs.Tail,
+		})
+	case "wait":
+		phase, err := kubectlgp.ParsePhase(options.Wait.For)
+		if err != nil {
+			return fmt.Errorf("--for: %w", err)
+		}
+		return plugin.Wait(ctx, phase, options.Wait.Timeout)
+	}
+	return fmt.Errorf("unknown command %q", command)
+}
+
+// terminalSize sends the size of the terminal of fd once, when the psql session starts
+type terminalSize struct {
+	fd   int
+	sent bool
+}
+
+func (t *terminalSize) Next() *remotecommand.TerminalSize {
+	if t.sent {
+		return nil
+	}
+	t.sent = true
+	width, height, err := term.GetSize(t.fd)
+	if err != nil {
+		return nil
+	}
+	return &remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ParseOptions", func() {
+	It("parses the global options and the subcommand", func() {
+		options, command, args, err := ParseOptions([]string{"-n", "test-ns", "-o", "json", "expand", "--segments", "4"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command).To(Equal("expand"))
+		Expect(args).To(BeEmpty())
+		Expect(options.Namespace).To(Equal("test-ns"))
+		Expect(options.Output).To(Equal("json"))
+		Expect(options.Expand.Segments).To(Equal(int32(4)))
+		Expect(options.Expand.Timeout).To(Equal(30 * time.Minute))
+	})
+
+	It("has defaults", func() {
+		options, command, _, err := ParseOptions([]string{"logs"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command).To(Equal("logs"))
+		Expect(options.Namespace).To(BeEmpty())
+		Expect(options.Output).To(Equal("table"))
+		Expect(options.Logs.Segment).To(Equal(-1))
+		Expect(options.Logs.Tail).To(Equal(int64(-1)))
+	})
+
+	It("passes the arguments after -- to psql", func() {
+		options, command, args, err := ParseOptions([]string{"psql", "-d", "postgres", "--", "-c", "SELECT 1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command).To(Equal("psql"))
+		Expect(options.Psql.Database).To(Equal("postgres"))
+		Expect(args).To(Equal([]string{"-c", "SELECT 1"}))
+	})
+
+	It("rejects arguments to other subcommands", func() {
+		_, _, _, err := ParseOptions([]string{"status", "extra"})
+		Expect(err).To(MatchError("unexpected arguments: extra"))
+	})
+
+	It("requires the options of a subcommand", func() {
+		_, _, _, err := ParseOptions([]string{"wait"})
+		Expect(err).To(MatchError(ContainSubstring("--for")))
+	})
+
+	It("rejects an unknown output format", func() {
+		_, _, _, err := ParseOptions([]string{"-o", "yaml", "status"})
+		Expect(err).To(MatchError(ContainSubstring("Allowed values are: table or json")))
+	})
+})
+
+var _ = Describe("Run", func() {
+	var (
+		plugin *kubectlgp.Plugin
+		stdout bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		greenplumCluster := &greenplumv1.GreenplumCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"}}
+		greenplumCluster.Status.Phase = greenplumv1.GreenplumClusterPhaseRunning
+		stdout.Reset()
+		plugin = &kubectlgp.Plugin{
+			Client:  fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(greenplumCluster).Build(),
+			Cluster: types.NamespacedName{Namespace: "test-ns", Name: "my-greenplum"},
+			Output:  kubectlgp.OutputTable,
+			Out:     &stdout,
+		}
+	})
+
+	It("waits for the phase given with --for", func() {
+		options, command, args, err := ParseOptions([]string{"wait", "--for=running"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Run(context.Background(), plugin, options, command, args)).To(Succeed())
+		Expect(stdout.String()).To(Equal("greenplumcluster/my-greenplum is Running\n"))
+	})
+
+	It("rejects an unknown phase", func() {
+		options, command, args, err := ParseOptions([]string{"wait", "--for=Ready"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Run(context.Background(), plugin, options, command, args)).To(MatchError(HavePrefix(`--for: unknown phase "Ready"`)))
+	})
+})
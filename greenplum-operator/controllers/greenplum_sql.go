@@ -0,0 +1,102 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pkg/errors"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// How long to wait before checking again on a cluster that is not ready to run SQL
+const clusterRequeueDelay = 30 * time.Second
+
+// activeMasterOf finds the active master of a cluster to run SQL on. If the cluster is not ready for that,
+// activeMaster is empty and waiting says why. cluster is nil if it does not exist.
+func activeMasterOf(ctx context.Context, c client.Client, q querier.GreenplumQuerier, namespace, clusterName string) (
+	cluster *greenplumv1.GreenplumCluster, activeMaster, waiting string, err error) {
+
+	cluster = &greenplumv1.GreenplumCluster{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: clusterName}, cluster); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil, "", fmt.Sprintf("GreenplumCluster %s not found", clusterName), nil
+		}
+		return nil, "", "", errors.Wrap(err, "unable to fetch GreenplumCluster")
+	}
+
+	if cluster.Status.Phase != greenplumv1.GreenplumClusterPhaseRunning {
+		return cluster, "", "waiting for GreenplumCluster to be Running", nil
+	}
+
+	activeMaster, err = querier.ActiveMaster(ctx, q, namespace)
+	if err != nil {
+		return cluster, "", "", errors.Wrap(err, "unable to find active master")
+	}
+	if activeMaster == "" {
+		return cluster, "", "no active master", nil
+	}
+	return cluster, activeMaster, "", nil
+}
+
+// finalizeDrop runs sql to drop an object from its cluster before removing finalizer from the deleted obj.
+// If the cluster is gone or being deleted, so is the object.
+func finalizeDrop(ctx context.Context, c client.Client, podExec executor.PodExecInterface, q querier.GreenplumQuerier, log logr.Logger,
+	obj client.Object, finalizer, clusterName, sql string) (ctrl.Result, error) {
+
+	return finalizeDropFunc(ctx, c, podExec, q, log, obj, finalizer, clusterName, func(query sqlQuery) error {
+		if _, err := query(sql); err != nil {
+			return err
+		}
+		log.Info("dropped", "statement", sql)
+		return nil
+	})
+}
+
+// sqlQuery runs sql on the active master of a cluster
+type sqlQuery func(sql string) (string, error)
+
+// finalizeDropFunc is finalizeDrop for objects that need more than one statement to drop.
+func finalizeDropFunc(ctx context.Context, c client.Client, podExec executor.PodExecInterface, q querier.GreenplumQuerier, log logr.Logger,
+	obj client.Object, finalizer, clusterName string, drop func(query sqlQuery) error) (ctrl.Result, error) {
+
+	if !controllerutil.ContainsFinalizer(obj, finalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	cluster, activeMaster, waiting, err := activeMasterOf(ctx, c, q, obj.GetNamespace(), clusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster != nil && cluster.Status.Phase != greenplumv1.GreenplumClusterPhaseDeleting {
+		if waiting != "" {
+			log.Info("waiting to drop", "reason", waiting)
+			return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+		}
+		query := func(sql string) (string, error) {
+			return executor.Query(ctx, podExec, obj.GetNamespace(), activeMaster, "gpadmin", sql)
+		}
+		if err := drop(query); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to drop: %w", err)
+		}
+	}
+
+	original := obj.DeepCopyObject().(client.Object)
+	controllerutil.RemoveFinalizer(obj, finalizer)
+	if err := c.Patch(ctx, obj, client.MergeFrom(original)); err != nil && !apierrs.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("removing finalizer: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
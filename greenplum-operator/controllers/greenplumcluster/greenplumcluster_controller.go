@@ -6,6 +6,7 @@ package greenplumcluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,23 +14,31 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/configmap"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/service"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/serviceaccount"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/sset"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/sshkeygen"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// How often to check on a gprestore Job
+const restorePollInterval = 30 * time.Second
+
 const (
 	StopClusterFinalizer           = "stopcluster.greenplumcluster.pivotal.io"
 	SupportedGreenplumMajorVersion = "6"
@@ -43,6 +52,20 @@ type GreenplumClusterReconciler struct {
 	InstanceImage string
 	OperatorImage string
 	PodExec       executor.PodExecInterface
+	Querier       querier.GreenplumQuerier
+	// QuerierNetwork is the CIDR that the operator connects to the masters from. Defaults to the addresses of
+	// the operator pods in OperatorNamespace.
+	QuerierNetwork string
+	// QuerierTLS only lets the operator connect to the masters over SSL
+	QuerierTLS        bool
+	OperatorNamespace string
+	// APIReader lists the operator pods, which may be outside of the namespaces of the cache. Defaults to Client.
+	APIReader client.Reader
+	Recorder  record.EventRecorder
+	// StatusGetter reads the status servers of the masters, for the MasterStartup condition. It is not set if nil.
+	StatusGetter gpstatus.Getter
+	// Now returns the current time for the catalogCheck schedule. Defaults to time.Now.
+	Now func() time.Time
 }
 
 var _ client.Client = &GreenplumClusterReconciler{}
@@ -54,6 +77,8 @@ func (r *GreenplumClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&greenplumv1.GreenplumCluster{}).
 		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Secret{}).
+		Owns(&batchv1.Job{}).
 		Complete(r)
 }
 
@@ -70,7 +95,13 @@ func (r *GreenplumClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 	SetDefaultGreenplumClusterValues(&greenplumCluster)
 
-	activeMaster := executor.GetCurrentActiveMaster(r.PodExec, greenplumCluster.Namespace)
+	activeMaster, err := querier.ActiveMaster(ctx, r.Querier, greenplumCluster.Namespace)
+	var authErr *querier.AuthenticationError
+	if errors.As(err, &authErr) {
+		// ensureQuerierAccess sets up the role and pg_hba.conf entry of the operator through exec
+		log.Info("operator cannot query the active master", "error", err.Error())
+		activeMaster = authErr.PodName
+	}
 	log.V(1).Info("current active master", "activeMaster", activeMaster)
 
 	if err := r.handleFinalizer(ctx, &greenplumCluster, &activeMaster); err != nil {
@@ -87,7 +118,17 @@ func (r *GreenplumClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, fmt.Errorf("unable to check if GreenplumCluster resources exist: %w", err)
 	}
 	if !clusterExists {
-		if err := handleAntiAffinity(ctx, r, greenplumCluster); err != nil {
+		if err := handleAntiAffinity(ctx, r, greenplumCluster); apierrs.IsForbidden(err) {
+			// A namespace-scoped operator may not be allowed to list Nodes. The statefulsets still carry
+			// the pod anti-affinity, so the scheduler keeps the pods apart; only the early check is lost.
+			r.Log.Info("skipping antiAffinity failure domain check", "reason", err.Error())
+			r.Recorder.Event(&greenplumCluster, corev1.EventTypeWarning, "AntiAffinityUnchecked",
+				"Cannot list Nodes to count failure domains for antiAffinity; pods will stay Pending if there are too few")
+		} else if err != nil {
+			r.Recorder.Event(&greenplumCluster, corev1.EventTypeWarning, "AntiAffinityFailed", err.Error())
+			return ctrl.Result{}, err
+		}
+		if err := r.createPVCsFromSnapshot(ctx, &greenplumCluster); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
@@ -95,26 +136,66 @@ func (r *GreenplumClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	if err := r.createOrUpdateClusterResources(ctx, greenplumCluster); err != nil {
 		return ctrl.Result{}, err
 	}
+	if !clusterExists {
+		r.Recorder.Event(&greenplumCluster, corev1.EventTypeNormal, "Initializing", "Created the StatefulSets of the greenplum cluster")
+	}
 
-	if err := r.reconcileStatus(ctx, &greenplumCluster); err != nil {
+	if err := r.reconcileStatus(ctx, &greenplumCluster, activeMaster); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// TODO: Decide when to set status to greenplumv1.GreenplumClusterPhaseFailed
-
-	if greenplumCluster.Status.Phase == greenplumv1.GreenplumClusterPhasePending && activeMaster != "" {
-		r.setStatus(ctx, &greenplumCluster, greenplumv1.GreenplumClusterPhaseRunning)
+	// A failed restore is not retried: the gprestore Job is kept for its logs, and the cluster stays Failed.
+	// Nothing else is run against a cluster that holds part of a backup.
+	if greenplumCluster.Status.Phase == greenplumv1.GreenplumClusterPhaseFailed {
+		return ctrl.Result{}, nil
 	}
 
 	if activeMaster == "" {
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
+	if greenplumCluster.Status.Phase == greenplumv1.GreenplumClusterPhasePending {
+		restored, err := r.handleRestore(ctx, &greenplumCluster, activeMaster)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to run gprestore: %w", err)
+		}
+		if !restored {
+			if greenplumCluster.Status.Phase == greenplumv1.GreenplumClusterPhaseFailed {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+		}
+		r.setStatus(ctx, &greenplumCluster, greenplumv1.GreenplumClusterPhaseRunning)
+		r.Recorder.Eventf(&greenplumCluster, corev1.EventTypeNormal, "Running", "Greenplum cluster is running on %s", activeMaster)
+	}
+
+	if err := r.reconcileGpadminPassword(ctx, &greenplumCluster, activeMaster); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to set gpadmin password: %w", err)
+	}
+
+	if err := r.ensureQuerierAccess(ctx, &greenplumCluster, activeMaster); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to allow the operator to connect to %s: %w", activeMaster, err)
+	}
+
+	operationRunning, err := r.handleOperations(ctx, &greenplumCluster, activeMaster)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to run requested operations: %w", err)
+	}
+	if operationRunning {
+		// Nothing else is run while gpstop restarts the cluster. The Job reconciles the cluster again when it finishes.
+		return ctrl.Result{}, nil
+	}
+
 	if err := r.handleExpand(ctx, &greenplumCluster, activeMaster); err != nil {
 		return ctrl.Result{}, fmt.Errorf("unable to run gpexpand: %w", err)
 	}
 
-	return ctrl.Result{}, nil
+	requeueAfter, err := r.handleCatalogCheck(ctx, &greenplumCluster, activeMaster)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to run gpcheckcat: %w", err)
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *GreenplumClusterReconciler) createOrUpdateClusterResources(ctx context.Context, greenplumCluster greenplumv1.GreenplumCluster) error {
@@ -159,6 +240,40 @@ func (r *GreenplumClusterReconciler) createOrUpdateClusterResources(ctx context.
 	}
 	r.logReconcileResult(operationResult, sshSecret)
 
+	gpadminSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      gpadminsecret.SecretName,
+			Namespace: ns,
+		},
+	}
+	operationResult, err = ctrl.CreateOrUpdate(ctx, r, gpadminSecret, func() error {
+		if err := gpadminsecret.ModifySecret(&greenplumCluster, gpadminSecret); err != nil {
+			return err
+		}
+		return ctrl.SetControllerReference(&greenplumCluster, gpadminSecret, r.Scheme())
+	})
+	if err != nil {
+		return err
+	}
+	r.logReconcileResult(operationResult, gpadminSecret)
+
+	querierSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      querier.SecretName,
+			Namespace: ns,
+		},
+	}
+	operationResult, err = ctrl.CreateOrUpdate(ctx, r, querierSecret, func() error {
+		if err := querier.ModifySecret(&greenplumCluster, querierSecret); err != nil {
+			return err
+		}
+		return ctrl.SetControllerReference(&greenplumCluster, querierSecret, r.Scheme())
+	})
+	if err != nil {
+		return err
+	}
+	r.logReconcileResult(operationResult, querierSecret)
+
 	agentService := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "agent",
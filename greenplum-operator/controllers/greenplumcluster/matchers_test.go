@@ -3,6 +3,7 @@ package greenplumcluster_test
 import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gstruct"
+	"k8s.io/client-go/tools/record"
 )
 
 var beOwnedByGreenplum = gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
@@ -10,3 +11,15 @@ var beOwnedByGreenplum = gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Field
 	"Kind":       Equal("GreenplumCluster"),
 	"Controller": gstruct.PointTo(BeTrue()),
 })
+
+// receivedEvents drains the events recorded so far, as "<type> <reason> <message>" strings
+func receivedEvents(recorder *record.FakeRecorder) (events []string) {
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return
+		}
+	}
+}
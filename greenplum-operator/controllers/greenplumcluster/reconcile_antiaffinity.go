@@ -3,15 +3,19 @@ package greenplumcluster
 import (
 	"context"
 	"fmt"
+	"math"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const NodeCountErrorFmt = "there must be at least two nodes available to both master and segments for anti-affinity: the number of nodes available for master is %d and for segment is %d"
+const FailureDomainCountErrorFmt = "there must be at least two failure domains available to both master and segments for anti-affinity: the number of failure domains available for master is %d (by %s) and for segment is %d (by %s)"
+const FailureDomainCapacityErrorFmt = "the failure domains by %s have room for %d of the %d %s pods, with the two pods of each pair in different domains"
 const AntiAffinityMismatchErrorFmt = "master and segment antiAffinity must be the same value: segment antiAffinity is %s, and master antiAffinity is %s"
 
+// handleAntiAffinity checks that pods can be kept apart before the cluster is created.
+// The placement itself is enforced by the pod anti-affinity of the statefulsets.
 func handleAntiAffinity(ctx context.Context, c client.Client, greenplumCluster greenplumv1.GreenplumCluster) (err error) {
 	defer func() {
 		if err != nil {
@@ -36,25 +40,11 @@ func handleAntiAffinity(ctx context.Context, c client.Client, greenplumCluster g
 			return fmt.Errorf("segment node worker selector list: %w", err)
 		}
 
-		// Look at nodes for each anti-affinity set and make sure there are at least two nodes of each type (masterx2 and segmentx2)
+		// Make sure there are at least two failure domains for each anti-affinity set (masterx2 and segmentx2)
 		valid, err := isAntiAffinityValid(greenplumCluster, masterNodeList, segmentNodeList)
 		if !valid {
 			return fmt.Errorf("instance %s does not meet requirements: %w", greenplumCluster.Name, err)
 		}
-
-		// Label every other node for master/standby respectively
-		masterNodeLabelKey := fmt.Sprintf("greenplum-affinity-%s-master", greenplumCluster.Namespace)
-		err = labelAlternateNodes(ctx, c, masterNodeList, masterNodeLabelKey, "true", "true")
-		if err != nil {
-			return err
-		}
-
-		// Label every other node for primary/mirror, respectively
-		segNodeLabelKey := fmt.Sprintf("greenplum-affinity-%s-segment", greenplumCluster.Namespace)
-		err = labelAlternateNodes(ctx, c, segmentNodeList, segNodeLabelKey, "a", "b")
-		if err != nil {
-			return err
-		}
 	}
 
 	return nil
@@ -67,30 +57,101 @@ func isAntiAffinityValid(greenplumCluster greenplumv1.GreenplumCluster, masterNo
 		return false, fmt.Errorf(AntiAffinityMismatchErrorFmt, segAntiAffinity, masterAntiAffinity)
 	}
 
-	numMasterNodes := len(masterNodeList.Items)
-	numSegmentNodes := len(segmentNodeList.Items)
-	if numMasterNodes < 2 || numSegmentNodes < 2 {
-		return false, fmt.Errorf(NodeCountErrorFmt, numMasterNodes, numSegmentNodes)
+	masterTopologyKey := greenplumCluster.Spec.MasterAndStandby.AntiAffinityTopologyKey
+	segmentTopologyKey := greenplumCluster.Spec.Segments.AntiAffinityTopologyKey
+	masterCapacities := failureDomainCapacities(masterNodeList, masterTopologyKey, greenplumCluster.Spec.MasterAndStandby.GreenplumPodSpec)
+	segmentCapacities := failureDomainCapacities(segmentNodeList, segmentTopologyKey, greenplumCluster.Spec.Segments.GreenplumPodSpec)
+	numMasterDomains := len(masterCapacities)
+	numSegmentDomains := len(segmentCapacities)
+	if numMasterDomains < 2 || numSegmentDomains < 2 {
+		return false, fmt.Errorf(FailureDomainCountErrorFmt, numMasterDomains, masterTopologyKey, numSegmentDomains, segmentTopologyKey)
+	}
+
+	// Two domains with room for a pod hold the master and standby. Each primary and its mirror are another pair.
+	segmentPairs := int64(greenplumCluster.Spec.Segments.PrimarySegmentCount)
+	if room := pairRoom(segmentCapacities, segmentPairs); room < 2*segmentPairs {
+		return false, fmt.Errorf(FailureDomainCapacityErrorFmt, segmentTopologyKey, room, 2*segmentPairs, "segment")
 	}
 
 	return true, nil
 }
 
-func labelAlternateNodes(ctx context.Context, c client.Client, nodeList corev1.NodeList, key, evenVal, oddVal string) error {
-	for i, node := range nodeList.Items {
-		v := oddVal
-		if i%2 == 0 {
-			v = evenVal
+// failureDomainCapacities returns, for each value of topologyKey among nodes, how many pods with the
+// cpu and memory requests of podSpec fit on its nodes. It goes by the allocatable resources of the nodes,
+// not by what the pods already running there use, so it can only catch domains that are too small.
+// Domains without room for a pod are left out. The scheduler never places a pod with anti-affinity on a
+// node without the label, nor on a cordoned node.
+func failureDomainCapacities(nodeList corev1.NodeList, topologyKey string, podSpec greenplumv1.GreenplumPodSpec) map[string]int64 {
+	capacities := map[string]int64{}
+	for _, node := range nodeList.Items {
+		domain, ok := node.Labels[topologyKey]
+		if !ok || node.Spec.Unschedulable {
+			continue
 		}
-		labeledNode := node.DeepCopy()
-		if labeledNode.Labels == nil {
-			labeledNode.Labels = make(map[string]string)
+		if capacity := nodeCapacity(node, podSpec); capacity > 0 {
+			capacities[domain] += capacity
 		}
-		labeledNode.Labels[key] = v
-		if err := c.Patch(ctx, labeledNode, client.MergeFrom(&node)); err != nil {
-			return fmt.Errorf("failed to add label '%s=%s' to node '%s': %w", key, v, node.Name, err)
+	}
+	return capacities
+}
+
+// nodeCapacity is how many pods with the requests of podSpec fit on node. A resource that the node does
+// not report, or that podSpec does not request, does not limit it.
+func nodeCapacity(node corev1.Node, podSpec greenplumv1.GreenplumPodSpec) int64 {
+	capacity := int64(math.MaxInt32)
+	limit := func(allocatable, request int64) {
+		if request > 0 && allocatable/request < capacity {
+			capacity = allocatable / request
 		}
 	}
+	if pods, ok := node.Status.Allocatable[corev1.ResourcePods]; ok {
+		limit(pods.Value(), 1)
+	}
+	if cpu, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+		limit(cpu.MilliValue(), podSpec.CPU.MilliValue())
+	}
+	if memory, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+		limit(memory.Value(), podSpec.Memory.Value())
+	}
+	return capacity
+}
+
+// pairRoom returns how many pods of the given number of pairs fit in domains with the given capacities,
+// when the two pods of a pair must be in different domains. A domain then holds at most one pod of each
+// pair, and all the pods fit exactly when pairRoom is 2*pairs.
+func pairRoom(capacities map[string]int64, pairs int64) int64 {
+	var room int64
+	for _, capacity := range capacities {
+		if capacity > pairs {
+			capacity = pairs
+		}
+		room += capacity
+	}
+	return room
+}
 
+// removeAntiAffinityNodeLabels removes the node labels that earlier versions of the operator
+// wrote to enforce antiAffinity.
+func removeAntiAffinityNodeLabels(ctx context.Context, c client.Client, namespace string) error {
+	labelKeys := []string{
+		fmt.Sprintf("greenplum-affinity-%s-master", namespace),
+		fmt.Sprintf("greenplum-affinity-%s-segment", namespace),
+	}
+	var nodeList corev1.NodeList
+	if err := c.List(ctx, &nodeList); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodeList.Items {
+		unlabeledNode := node.DeepCopy()
+		for _, key := range labelKeys {
+			delete(unlabeledNode.Labels, key)
+		}
+		if len(unlabeledNode.Labels) == len(node.Labels) {
+			continue
+		}
+		if err := c.Patch(ctx, unlabeledNode, client.MergeFrom(&node)); err != nil {
+			return fmt.Errorf("failed to remove antiAffinity labels from node '%s': %w", node.Name, err)
+		}
+	}
 	return nil
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,15 +11,23 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	fakeexec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/testing/reactive"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -81,15 +88,17 @@ var _ = Describe("antiAffinity tests", func() {
 	})
 
 	JustBeforeEach(func() {
-		reactiveClient = reactive.NewClient(fake.NewFakeClientWithScheme(scheme.Scheme, testNodes))
+		reactiveClient = reactive.NewClient(fake.NewFakeClientWithScheme(scheme.Scheme, withHostnameLabels(testNodes)))
 		Expect(reactiveClient.Create(nil, fakeGreenplumClusterSpec)).To(Succeed())
 		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
 			Client:        reactiveClient,
 			Log:           gplog.ForTest(logBuf),
 			SSHCreator:    fakeSecretCreator{},
 			PodExec:       &fakeexec.PodExec{},
+			Querier:       &fakequerier.Querier{},
 			InstanceImage: "greenplum-for-kubernetes:tag",
 			OperatorImage: "greenplum-operator:tag",
+			Recorder:      record.NewFakeRecorder(100),
 		}
 	})
 
@@ -101,9 +110,9 @@ var _ = Describe("antiAffinity tests", func() {
 				testNodes = exampleValidNodeList
 			})
 			When("at least 2 worker nodes for master and segment are available", func() {
-				It("succeeds and labels nodes", func() {
+				It("succeeds and does not label nodes", func() {
 					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
-					checkNodeLabels(fakeGreenplumClusterSpec)
+					checkNodesNotLabeled(testNodes)
 				})
 			})
 			When("gpdb cluster resources already exist", func() {
@@ -120,6 +129,24 @@ var _ = Describe("antiAffinity tests", func() {
 					Expect(nodePatched).To(BeFalse())
 				})
 			})
+			When("the operator is not allowed to list nodes", func() {
+				JustBeforeEach(func() {
+					reactiveClient.PrependReactor("list", "nodes", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+						return true, nil, apierrs.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", errors.New("injected error"))
+					})
+				})
+				It("skips the failure domain check and creates the cluster", func() {
+					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+					var sset appsv1.StatefulSet
+					Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: "master"}, &sset)).To(Succeed())
+				})
+				It("emits an AntiAffinityUnchecked event", func() {
+					_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(receivedEvents(greenplumReconciler.Recorder.(*record.FakeRecorder))).To(ContainElement(
+						"Warning AntiAffinityUnchecked Cannot list Nodes to count failure domains for antiAffinity; pods will stay Pending if there are too few"))
+				})
+			})
 			When("there is an error checking if gpdb cluster resources exist", func() {
 				var errMsg string
 				JustBeforeEach(func() {
@@ -153,7 +180,7 @@ var _ = Describe("antiAffinity tests", func() {
 					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 					Expect(reconcileResult).To(Equal(ctrl.Result{}))
 					Expect(err).To(HaveOccurred())
-					expectedErrMsg := fmt.Sprintf(greenplumcluster.NodeCountErrorFmt, 0, 0)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 0, "kubernetes.io/hostname", 0, "kubernetes.io/hostname")
 					Expect(err.Error()).To(Equal("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
 				})
 			})
@@ -186,7 +213,7 @@ var _ = Describe("antiAffinity tests", func() {
 					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 					Expect(reconcileResult).To(Equal(ctrl.Result{}))
 					Expect(err).To(HaveOccurred())
-					expectedErrMsg := fmt.Sprintf(greenplumcluster.NodeCountErrorFmt, 1, 2)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 1, "kubernetes.io/hostname", 2, "kubernetes.io/hostname")
 					Expect(err.Error()).To(Equal("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
 				})
 			})
@@ -219,7 +246,7 @@ var _ = Describe("antiAffinity tests", func() {
 					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 					Expect(reconcileResult).To(Equal(ctrl.Result{}))
 					Expect(err).To(HaveOccurred())
-					expectedErrMsg := fmt.Sprintf(greenplumcluster.NodeCountErrorFmt, 2, 1)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 2, "kubernetes.io/hostname", 1, "kubernetes.io/hostname")
 					Expect(err.Error()).To(Equal("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
 				})
 			})
@@ -263,46 +290,6 @@ var _ = Describe("antiAffinity tests", func() {
 					Expect(err.Error()).To(Equal("antiAffinity: segment node worker selector list: " + errMsg))
 				})
 			})
-			When("patching nodes with master label returns an error", func() {
-				var errMsg string
-				JustBeforeEach(func() {
-					errMsg = "failed to patch node"
-					reactiveClient.PrependReactor("patch", "nodes", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
-						patchAction := action.(testing.PatchAction)
-						patchString := string(patchAction.GetPatch())
-						if strings.Contains(patchString, "greenplum-affinity-test-ns-master") {
-							return true, nil, errors.New(errMsg)
-						}
-						return false, nil, nil
-					})
-				})
-				It("returns an error", func() {
-					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
-					Expect(reconcileResult).To(Equal(ctrl.Result{}))
-					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(MatchRegexp("antiAffinity: failed to add label '.+=.+' to node '.+': " + errMsg))
-				})
-			})
-			When("patching nodes with segment label returns an error", func() {
-				var errMsg string
-				JustBeforeEach(func() {
-					errMsg = "failed to patch node"
-					reactiveClient.PrependReactor("patch", "nodes", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
-						patchAction := action.(testing.PatchAction)
-						patchString := string(patchAction.GetPatch())
-						if strings.Contains(patchString, "greenplum-affinity-test-ns-segment") {
-							return true, nil, errors.New(errMsg)
-						}
-						return false, nil, nil
-					})
-				})
-				It("returns an error", func() {
-					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
-					Expect(reconcileResult).To(Equal(ctrl.Result{}))
-					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(MatchRegexp("antiAffinity: failed to add label '.+=.+' to node '.+': " + errMsg))
-				})
-			})
 		})
 
 		When("masterAndStandby.workerSelector is set but segments.workerSelector is not set", func() {
@@ -311,9 +298,9 @@ var _ = Describe("antiAffinity tests", func() {
 				testNodes = exampleValidNodeList
 			})
 			When("at least 2 worker nodes for master and segment are available", func() {
-				It("succeeds and labels nodes", func() {
+				It("succeeds and does not label nodes", func() {
 					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
-					checkNodeLabels(fakeGreenplumClusterSpec)
+					checkNodesNotLabeled(testNodes)
 				})
 			})
 			When("there are not enough nodes labeled with master worker selector", func() {
@@ -338,7 +325,7 @@ var _ = Describe("antiAffinity tests", func() {
 					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 					Expect(reconcileResult).To(Equal(ctrl.Result{}))
 					Expect(err).To(HaveOccurred())
-					expectedErrMsg := fmt.Sprintf(greenplumcluster.NodeCountErrorFmt, 1, 2)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 1, "kubernetes.io/hostname", 2, "kubernetes.io/hostname")
 					Expect(err.Error()).To(Equal("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
 				})
 			})
@@ -350,9 +337,9 @@ var _ = Describe("antiAffinity tests", func() {
 				testNodes = exampleValidNodeList
 			})
 			When("at least 2 worker nodes for master and segment are available", func() {
-				It("succeeds and labels nodes", func() {
+				It("succeeds and does not label nodes", func() {
 					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
-					checkNodeLabels(fakeGreenplumClusterSpec)
+					checkNodesNotLabeled(testNodes)
 				})
 			})
 			When("there are not enough nodes labeled with segment worker selector", func() {
@@ -377,7 +364,7 @@ var _ = Describe("antiAffinity tests", func() {
 					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 					Expect(reconcileResult).To(Equal(ctrl.Result{}))
 					Expect(err).To(HaveOccurred())
-					expectedErrMsg := fmt.Sprintf(greenplumcluster.NodeCountErrorFmt, 2, 1)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 2, "kubernetes.io/hostname", 1, "kubernetes.io/hostname")
 					Expect(err.Error()).To(Equal("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
 				})
 			})
@@ -401,9 +388,9 @@ var _ = Describe("antiAffinity tests", func() {
 						},
 					}
 				})
-				It("succeeds and labels nodes", func() {
+				It("succeeds and does not label nodes", func() {
 					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
-					checkNodeLabels(fakeGreenplumClusterSpec)
+					checkNodesNotLabeled(testNodes)
 				})
 			})
 			When("there is only one node available (i.e. minikube)", func() {
@@ -422,12 +409,109 @@ var _ = Describe("antiAffinity tests", func() {
 					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 					Expect(reconcileResult).To(Equal(ctrl.Result{}))
 					Expect(err).To(HaveOccurred())
-					expectedErrMsg := fmt.Sprintf(greenplumcluster.NodeCountErrorFmt, 1, 1)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 1, "kubernetes.io/hostname", 1, "kubernetes.io/hostname")
 					Expect(err.Error()).To(Equal("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
 				})
 			})
 		})
 
+		When("antiAffinityTopologyKey is a zone label", func() {
+			BeforeEach(func() {
+				fakeGreenplumClusterSpec.Spec.MasterAndStandby.AntiAffinityTopologyKey = "topology.kubernetes.io/zone"
+				fakeGreenplumClusterSpec.Spec.Segments.AntiAffinityTopologyKey = "topology.kubernetes.io/zone"
+			})
+			When("nodes are in at least two zones", func() {
+				BeforeEach(func() {
+					testNodes = &corev1.NodeList{
+						Items: []corev1.Node{
+							{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-b"}}},
+						},
+					}
+				})
+				It("succeeds and does not label nodes", func() {
+					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+					checkNodesNotLabeled(testNodes)
+				})
+			})
+			When("all nodes with a zone are in the same zone", func() {
+				BeforeEach(func() {
+					testNodes = &corev1.NodeList{
+						Items: []corev1.Node{
+							{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"topology.kubernetes.io/zone": "zone-a"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "node3"}},
+						},
+					}
+				})
+				It("returns an error", func() {
+					reconcileResult, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					Expect(reconcileResult).To(Equal(ctrl.Result{}))
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 1, "topology.kubernetes.io/zone", 1, "topology.kubernetes.io/zone")
+					Expect(err).To(MatchError("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
+				})
+			})
+		})
+
+		When("nodes report their allocatable resources", func() {
+			node := func(name, cpu string) corev1.Node {
+				return corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse("64Gi"),
+						corev1.ResourcePods:   resource.MustParse("110"),
+					}},
+				}
+			}
+			BeforeEach(func() {
+				fakeGreenplumClusterSpec.Spec.Segments.PrimarySegmentCount = 2
+				fakeGreenplumClusterSpec.Spec.Segments.CPU = resource.MustParse("2")
+				fakeGreenplumClusterSpec.Spec.Segments.Memory = resource.MustParse("4Gi")
+			})
+			When("each primary and its mirror fit in different failure domains", func() {
+				BeforeEach(func() {
+					testNodes = &corev1.NodeList{Items: []corev1.Node{node("node1", "4"), node("node2", "4")}}
+				})
+				It("succeeds", func() {
+					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+				})
+			})
+			When("the failure domains are too small for the segments", func() {
+				BeforeEach(func() {
+					testNodes = &corev1.NodeList{Items: []corev1.Node{node("node1", "3"), node("node2", "3")}}
+				})
+				It("returns an error", func() {
+					_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCapacityErrorFmt, "kubernetes.io/hostname", 2, 4, "segment")
+					Expect(err).To(MatchError("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
+				})
+			})
+			When("one failure domain could hold every segment", func() {
+				BeforeEach(func() {
+					testNodes = &corev1.NodeList{Items: []corev1.Node{node("node1", "64"), node("node2", "3")}}
+				})
+				It("returns an error, since a primary and its mirror cannot share it", func() {
+					_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCapacityErrorFmt, "kubernetes.io/hostname", 3, 4, "segment")
+					Expect(err).To(MatchError("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
+				})
+			})
+			When("a node is cordoned", func() {
+				BeforeEach(func() {
+					cordoned := node("node2", "4")
+					cordoned.Spec.Unschedulable = true
+					testNodes = &corev1.NodeList{Items: []corev1.Node{node("node1", "4"), cordoned}}
+				})
+				It("does not count its failure domain", func() {
+					_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					expectedErrMsg := fmt.Sprintf(greenplumcluster.FailureDomainCountErrorFmt, 1, "kubernetes.io/hostname", 1, "kubernetes.io/hostname")
+					Expect(err).To(MatchError("antiAffinity: instance my-greenplum does not meet requirements: " + expectedErrMsg))
+				})
+			})
+		})
+
 		When("master.antiaffinity and segment.antiaffinity are set to different values", func() {
 			BeforeEach(func() {
 				fakeGreenplumClusterSpec.Spec.MasterAndStandby.AntiAffinity = "yes"
@@ -445,20 +529,26 @@ var _ = Describe("antiAffinity tests", func() {
 	})
 })
 
-func checkNodeLabels(greenplumCluster *greenplumv1.GreenplumCluster) {
-	var nodeList corev1.NodeList
-	Expect(reactiveClient.List(nil, &nodeList)).To(Succeed())
-	masterNodeLabelKey := fmt.Sprintf("greenplum-affinity-%s-master", greenplumCluster.Namespace)
-	segNodeLabelKey := fmt.Sprintf("greenplum-affinity-%s-segment", greenplumCluster.Namespace)
-	for _, node := range nodeList.Items {
-		nodeLabels := node.GetLabels()
-		if greenplumCluster.Spec.MasterAndStandby.WorkerSelector == nil ||
-			nodeLabels["worker"] == greenplumCluster.Spec.MasterAndStandby.WorkerSelector["worker"] {
-			Expect(nodeLabels[masterNodeLabelKey] == "true").To(BeTrue())
-		}
-		if greenplumCluster.Spec.Segments.WorkerSelector == nil ||
-			nodeLabels["worker"] == greenplumCluster.Spec.Segments.WorkerSelector["worker"] {
-			Expect((nodeLabels[segNodeLabelKey] == "a") || (nodeLabels[segNodeLabelKey] == "b")).To(BeTrue())
+// withHostnameLabels gives each node the hostname label that the kubelet sets on real nodes
+func withHostnameLabels(nodeList *corev1.NodeList) *corev1.NodeList {
+	labeledNodeList := nodeList.DeepCopy()
+	for i := range labeledNodeList.Items {
+		node := &labeledNodeList.Items[i]
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
 		}
+		node.Labels["kubernetes.io/hostname"] = node.Name
+	}
+	return labeledNodeList
+}
+
+func checkNodesNotLabeled(nodeList *corev1.NodeList) {
+	var reconciledNodeList corev1.NodeList
+	Expect(reactiveClient.List(nil, &reconciledNodeList)).To(Succeed())
+	Expect(reconciledNodeList.Items).To(HaveLen(len(nodeList.Items)))
+	for _, node := range withHostnameLabels(nodeList).Items {
+		var reconciledNode corev1.Node
+		Expect(reactiveClient.Get(nil, client.ObjectKeyFromObject(&node), &reconciledNode)).To(Succeed())
+		Expect(reconciledNode.Labels).To(Equal(node.Labels))
 	}
 }
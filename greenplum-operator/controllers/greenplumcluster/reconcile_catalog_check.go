@@ -0,0 +1,233 @@
+package greenplumcluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/catalogcheckjob"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/cronschedule"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// handleCatalogCheck records the result of a finished gpcheckcat Job, and starts a new one when the
+// catalogCheck schedule is due. It returns how long to wait until the next scheduled run.
+func (r *GreenplumClusterReconciler) handleCatalogCheck(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) (time.Duration, error) {
+	spec := greenplumCluster.Spec.CatalogCheck
+	if spec == nil {
+		return 0, nil
+	}
+	originalGreenplumCluster := greenplumCluster.DeepCopy()
+	if greenplumCluster.Status.CatalogCheck == nil {
+		greenplumCluster.Status.CatalogCheck = &greenplumv1.GreenplumCatalogCheckStatus{}
+	}
+
+	requeueAfter, err := r.reconcileCatalogCheck(ctx, greenplumCluster, activeMaster)
+	if err != nil {
+		return 0, err
+	}
+
+	if !equality.Semantic.DeepEqual(greenplumCluster.Status, originalGreenplumCluster.Status) {
+		if err := r.Patch(ctx, greenplumCluster, client.MergeFrom(originalGreenplumCluster)); err != nil {
+			return 0, fmt.Errorf("updating catalogCheck status: %w", err)
+		}
+	}
+	return requeueAfter, nil
+}
+
+func (r *GreenplumClusterReconciler) reconcileCatalogCheck(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) (time.Duration, error) {
+	status := greenplumCluster.Status.CatalogCheck
+	schedule, err := cronschedule.Parse(greenplumCluster.Spec.CatalogCheck.Schedule)
+	if err != nil {
+		status.Message = "invalid schedule: " + err.Error()
+		status.NextScheduleTime = nil
+		return 0, nil
+	}
+
+	running, err := r.updateCatalogCheckResult(ctx, greenplumCluster)
+	if err != nil {
+		return 0, err
+	}
+
+	now := r.now()
+	last := greenplumCluster.CreationTimestamp.Time
+	if status.LastScheduleTime != nil {
+		last = status.LastScheduleTime.Time
+	}
+	runs := schedule.Runs(last, now)
+	if runs.Next.IsZero() {
+		status.NextScheduleTime = nil
+	} else {
+		status.NextScheduleTime = &metav1.Time{Time: runs.Next}
+	}
+
+	if runs.Due.IsZero() {
+		if runs.NeverRuns() {
+			status.Message = cronschedule.NeverRunsMessage
+		}
+		return runs.RequeueAfter, nil
+	}
+
+	skipReason := ""
+	if running {
+		skipReason = fmt.Sprintf("Job %s is still running", status.JobName)
+	} else if expanding, err := r.expansionInProgress(ctx, greenplumCluster); err != nil {
+		return 0, err
+	} else if expanding {
+		skipReason = fmt.Sprintf("gpexpand Job %s is running", gpexpandJobKey(greenplumCluster).Name)
+	}
+	if skipReason != "" {
+		status.LastScheduleTime = &metav1.Time{Time: runs.Due}
+		status.Message = runs.SkippedMessage(skipReason)
+		return runs.RequeueAfter, nil
+	}
+
+	// Only the Job of the last run is kept
+	if status.JobName != "" {
+		oldJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: greenplumCluster.Namespace, Name: status.JobName}}
+		if err := r.Delete(ctx, oldJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrs.IsNotFound(err) {
+			return 0, fmt.Errorf("unable to delete Job %s: %w", status.JobName, err)
+		}
+	}
+
+	activeMasterFQDN := fmt.Sprintf("%s.agent.%s.svc.cluster.local", activeMaster, greenplumCluster.Namespace)
+	job := catalogcheckjob.GenerateJob(r.InstanceImage, activeMasterFQDN, *greenplumCluster.Spec.CatalogCheck)
+	job.Namespace = greenplumCluster.Namespace
+	job.Name = runs.JobName(greenplumCluster.Name + "-gpcheckcat")
+	job.Labels = map[string]string{"greenplum-cluster": greenplumCluster.Name}
+	if err := ctrl.SetControllerReference(greenplumCluster, &job, r.Scheme()); err != nil {
+		// not tested: not really possible to fail here
+		return 0, err
+	}
+	if err := r.Create(ctx, &job); err != nil && !apierrs.IsAlreadyExists(err) {
+		return 0, fmt.Errorf("unable to create Job %s: %w", job.Name, err)
+	}
+	r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "CatalogCheckStarted",
+		"Created gpcheckcat Job %s", job.Name)
+
+	status.JobName = job.Name
+	status.LastScheduleTime = &metav1.Time{Time: runs.Due}
+	status.CompletionTime = nil
+	status.Message = ""
+	return runs.RequeueAfter, nil
+}
+
+// updateCatalogCheckResult records the summary of the last gpcheckcat Job once it has finished,
+// and returns whether it is still running
+func (r *GreenplumClusterReconciler) updateCatalogCheckResult(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster) (bool, error) {
+	status := greenplumCluster.Status.CatalogCheck
+	if status.JobName == "" || status.CompletionTime != nil {
+		return false, nil
+	}
+
+	var job batchv1.Job
+	err := r.Get(ctx, types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: status.JobName}, &job)
+	if apierrs.IsNotFound(err) {
+		r.setCatalogCheckResult(greenplumCluster, catalogcheckjob.Summary{},
+			fmt.Sprintf("gpcheckcat Job %s was deleted before it finished", status.JobName))
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to fetch Job %s: %w", status.JobName, err)
+	}
+	if job.Status.Succeeded < 1 && job.Status.Failed == 0 {
+		return true, nil
+	}
+
+	message, err := r.terminationMessage(ctx, &job)
+	if err != nil {
+		return false, err
+	}
+	summary := catalogcheckjob.ParseSummary(message)
+	r.setCatalogCheckResult(greenplumCluster, summary,
+		fmt.Sprintf("gpcheckcat Job %s did not report a summary. Check its logs", job.Name))
+	return false, nil
+}
+
+// setCatalogCheckResult sets the CatalogConsistent condition from the summary of a gpcheckcat Job. When
+// gpcheckcat did not complete on any database, the condition is Unknown, with the given message.
+func (r *GreenplumClusterReconciler) setCatalogCheckResult(greenplumCluster *greenplumv1.GreenplumCluster, summary catalogcheckjob.Summary, incompleteMessage string) {
+	status := greenplumCluster.Status.CatalogCheck
+	now := metav1.NewTime(r.now())
+	status.CompletionTime = &now
+	status.FailedChecks = summary.FailedChecks
+	status.RepairScripts = summary.RepairScripts
+
+	condition := metav1.Condition{
+		Type:               greenplumv1.CatalogConsistentCondition,
+		ObservedGeneration: greenplumCluster.Generation,
+		LastTransitionTime: now,
+	}
+	switch {
+	case len(summary.Databases) == 0:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "CheckIncomplete"
+		condition.Message = incompleteMessage
+		r.Recorder.Event(greenplumCluster, corev1.EventTypeWarning, "CatalogCheckFailed", incompleteMessage)
+	case summary.Consistent():
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "NoIssuesFound"
+		condition.Message = "gpcheckcat found no catalog issues in " + strings.Join(summary.Databases, ", ")
+		r.Recorder.Event(greenplumCluster, corev1.EventTypeNormal, "CatalogCheckSucceeded", condition.Message)
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IssuesFound"
+		issueCount := summary.IssueCount
+		if issueCount == 0 {
+			issueCount = len(summary.FailedChecks)
+		}
+		condition.Message = fmt.Sprintf("gpcheckcat found %d catalog issue(s): %s", issueCount, describeFailedChecks(summary.FailedChecks))
+		repairScripts := "gpcheckcat generated no repair scripts"
+		if summary.RepairScripts != "" {
+			repairScripts = "Repair scripts are in " + summary.RepairScripts
+		}
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, "CatalogIssuesFound",
+			"%s. %s", condition.Message, repairScripts)
+	}
+	meta.SetStatusCondition(&greenplumCluster.Status.Conditions, condition)
+}
+
+// describeFailedChecks lists the failed checks as database/check
+func describeFailedChecks(failedChecks []greenplumv1.GreenplumCatalogCheckFailure) string {
+	if len(failedChecks) == 0 {
+		return "see the logs of the gpcheckcat Job"
+	}
+	var checks []string
+	for _, failure := range failedChecks {
+		checks = append(checks, failure.Database+"/"+failure.Check)
+	}
+	return strings.Join(checks, ", ")
+}
+
+// terminationMessage returns the termination message of the container of the pod of a finished Job
+func (r *GreenplumClusterReconciler) terminationMessage(ctx context.Context, job *batchv1.Job) (string, error) {
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", fmt.Errorf("unable to list pods of Job %s: %w", job.Name, err)
+	}
+	for _, pod := range podList.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if terminated := containerStatus.State.Terminated; terminated != nil && terminated.Message != "" {
+				return terminated.Message, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (r *GreenplumClusterReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
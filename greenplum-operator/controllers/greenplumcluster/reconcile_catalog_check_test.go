@@ -0,0 +1,328 @@
+package greenplumcluster_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Reconcile catalogCheck", func() {
+	var (
+		ctx                 context.Context
+		fakeQuerier         *fakequerier.Querier
+		recorder            *record.FakeRecorder
+		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
+		greenplumCluster    *greenplumv1.GreenplumCluster
+		now                 time.Time
+	)
+
+	// Saturday 4am, in minutes since the epoch, is the suffix of the Job name
+	const firstRunJob = "my-greenplum-gpcheckcat-26523600"
+
+	created := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+	firstRun := time.Date(2020, time.June, 6, 4, 0, 0, 0, time.UTC)
+	secondRun := firstRun.AddDate(0, 0, 7)
+
+	getGreenplumCluster := func() *greenplumv1.GreenplumCluster {
+		var gc greenplumv1.GreenplumCluster
+		Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &gc)).To(Succeed())
+		return &gc
+	}
+
+	listJobs := func() (names []string) {
+		var jobList batchv1.JobList
+		Expect(reactiveClient.List(ctx, &jobList, client.InNamespace(namespaceName))).To(Succeed())
+		for _, job := range jobList.Items {
+			names = append(names, job.Name)
+		}
+		return
+	}
+
+	getJob := func(name string) *batchv1.Job {
+		var job batchv1.Job
+		Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: name}, &job)).To(Succeed())
+		return &job
+	}
+
+	// finishJob marks the Job as finished, with a pod that has the given termination message
+	finishJob := func(name string, succeeded bool, terminationMessage string) {
+		job := getJob(name)
+		if succeeded {
+			job.Status.Succeeded = 1
+		} else {
+			job.Status.Failed = 1
+		}
+		Expect(reactiveClient.Update(ctx, job)).To(Succeed())
+		Expect(reactiveClient.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespaceName,
+				Name:      name + "-abcde",
+				Labels:    map[string]string{"job-name": name},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "gpcheckcat",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						Message: terminationMessage,
+					}},
+				}},
+			},
+		})).To(Succeed())
+	}
+
+	reconcileAt := func(t time.Time) reconcile.Result {
+		now = t
+		result, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	catalogConsistent := func() *metav1.Condition {
+		return meta.FindStatusCondition(getGreenplumCluster().Status.Conditions, greenplumv1.CatalogConsistentCondition)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeQuerier = &fakequerier.Querier{}
+		recorder = record.NewFakeRecorder(100)
+		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
+			Client:        reactiveClient,
+			Log:           gplog.ForTest(gbytes.NewBuffer()),
+			SSHCreator:    fakeSecretCreator{},
+			InstanceImage: "greenplum-for-kubernetes:latest",
+			OperatorImage: "greenplum-operator:latest",
+			PodExec:       &fake.PodExec{},
+			Querier:       fakeQuerier,
+			Recorder:      recorder,
+			Now:           func() time.Time { return now },
+		}
+		greenplumCluster = exampleGreenplumCluster.DeepCopy()
+		greenplumCluster.CreationTimestamp = metav1.Time{Time: created}
+		greenplumCluster.Spec.CatalogCheck = &greenplumv1.GreenplumCatalogCheckSpec{
+			Schedule:  "0 4 * * 6",
+			Databases: []string{"postgres", "sales"},
+		}
+	})
+
+	var result reconcile.Result
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, greenplumCluster)).To(Succeed())
+		result = reconcileAt(created.Add(time.Hour))
+	})
+
+	It("waits for the next scheduled time", func() {
+		Expect(result.RequeueAfter).To(Equal(firstRun.Sub(created.Add(time.Hour))))
+		Expect(listJobs()).To(BeEmpty())
+		catalogCheck := getGreenplumCluster().Status.CatalogCheck
+		Expect(catalogCheck.NextScheduleTime.Time).To(BeTemporally("==", firstRun))
+		Expect(catalogCheck.LastScheduleTime).To(BeNil())
+		Expect(catalogConsistent()).To(BeNil())
+	})
+
+	When("catalogCheck is not specified", func() {
+		BeforeEach(func() {
+			greenplumCluster.Spec.CatalogCheck = nil
+		})
+		It("does not schedule gpcheckcat", func() {
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(getGreenplumCluster().Status.CatalogCheck).To(BeNil())
+		})
+	})
+
+	When("a run is due", func() {
+		JustBeforeEach(func() {
+			result = reconcileAt(firstRun.Add(10 * time.Second))
+		})
+
+		It("creates a gpcheckcat Job against the active master", func() {
+			job := getJob(firstRunJob)
+			Expect(job.Labels).To(Equal(map[string]string{"greenplum-cluster": clusterName}))
+			Expect(job.OwnerReferences).To(HaveLen(1))
+			Expect(job.OwnerReferences[0].Kind).To(Equal("GreenplumCluster"))
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElement(corev1.EnvVar{
+				Name: "CATALOG_CHECK_HOST", Value: "master-0.agent.test-ns.svc.cluster.local",
+			}))
+			Expect(container.Args).To(Equal([]string{"postgres", "sales"}))
+			Expect(receivedEvents(recorder)).To(ContainElement("Normal CatalogCheckStarted Created gpcheckcat Job " + firstRunJob))
+		})
+
+		It("records the run in the status", func() {
+			catalogCheck := getGreenplumCluster().Status.CatalogCheck
+			Expect(catalogCheck.JobName).To(Equal(firstRunJob))
+			Expect(catalogCheck.LastScheduleTime.Time).To(BeTemporally("==", firstRun))
+			Expect(catalogCheck.NextScheduleTime.Time).To(BeTemporally("==", secondRun))
+			Expect(catalogCheck.CompletionTime).To(BeNil())
+			Expect(result.RequeueAfter).To(Equal(secondRun.Sub(firstRun.Add(10 * time.Second))))
+		})
+
+		When("gpcheckcat finds no issues", func() {
+			JustBeforeEach(func() {
+				finishJob(firstRunJob, true, `Checking database: postgres
+Completed 14 test(s) on database 'postgres' at 2020-06-06 04:00:12 with elapsed time 0:00:11
+Found no catalog issue
+Checking database: sales
+Completed 14 test(s) on database 'sales' at 2020-06-06 04:00:25 with elapsed time 0:00:13
+Found no catalog issue
+`)
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+
+			It("sets CatalogConsistent to True", func() {
+				condition := catalogConsistent()
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal("NoIssuesFound"))
+				Expect(condition.Message).To(Equal("gpcheckcat found no catalog issues in postgres, sales"))
+				catalogCheck := getGreenplumCluster().Status.CatalogCheck
+				Expect(catalogCheck.CompletionTime.Time).To(BeTemporally("==", firstRun.Add(time.Hour)))
+				Expect(catalogCheck.FailedChecks).To(BeEmpty())
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Normal CatalogCheckSucceeded gpcheckcat found no catalog issues in postgres, sales"))
+			})
+
+			It("replaces the Job at the next scheduled time", func() {
+				reconcileAt(secondRun)
+				Expect(listJobs()).To(ConsistOf("my-greenplum-gpcheckcat-26533680"))
+				Expect(getGreenplumCluster().Status.CatalogCheck.CompletionTime).To(BeNil())
+				Expect(catalogConsistent().Status).To(Equal(metav1.ConditionTrue))
+			})
+		})
+
+		When("gpcheckcat finds issues", func() {
+			JustBeforeEach(func() {
+				finishJob(firstRunJob, false, `Checking database: postgres
+Completed 14 test(s) on database 'postgres' at 2020-06-06 04:00:12 with elapsed time 0:00:11
+Found no catalog issue
+Checking database: sales
+Completed 14 test(s) on database 'sales' at 2020-06-06 04:00:25 with elapsed time 0:00:13
+Found 2 catalog issue(s)
+  Test: missing_extraneous
+Table name: public.orders
+Name of test which found this issue: missing_extraneous
+Table name: public.customers
+Name of test which found this issue: missing_extraneous
+Repair scripts: master-0:/greenplum/gpcheckcat/repair.20200606040000
+`)
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+
+			It("records the failed checks and the affected tables", func() {
+				catalogCheck := getGreenplumCluster().Status.CatalogCheck
+				Expect(catalogCheck.FailedChecks).To(Equal([]greenplumv1.GreenplumCatalogCheckFailure{
+					{Database: "sales", Check: "missing_extraneous", Tables: []string{"public.orders", "public.customers"}},
+				}))
+				Expect(catalogCheck.RepairScripts).To(Equal("master-0:/greenplum/gpcheckcat/repair.20200606040000"))
+			})
+
+			It("sets CatalogConsistent to False", func() {
+				condition := catalogConsistent()
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("IssuesFound"))
+				Expect(condition.Message).To(Equal("gpcheckcat found 2 catalog issue(s): sales/missing_extraneous"))
+			})
+
+			It("emits a Warning event with the location of the repair scripts", func() {
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Warning CatalogIssuesFound gpcheckcat found 2 catalog issue(s): sales/missing_extraneous. " +
+						"Repair scripts are in master-0:/greenplum/gpcheckcat/repair.20200606040000"))
+			})
+
+			It("does not record the result again", func() {
+				receivedEvents(recorder)
+				reconcileAt(firstRun.Add(2 * time.Hour))
+				Expect(receivedEvents(recorder)).NotTo(ContainElement(HavePrefix("Warning CatalogIssuesFound")))
+			})
+		})
+
+		When("the Job fails without a summary", func() {
+			JustBeforeEach(func() {
+				finishJob(firstRunJob, false, "")
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+			It("sets CatalogConsistent to Unknown", func() {
+				condition := catalogConsistent()
+				Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+				Expect(condition.Reason).To(Equal("CheckIncomplete"))
+				Expect(condition.Message).To(Equal("gpcheckcat Job " + firstRunJob + " did not report a summary. Check its logs"))
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Warning CatalogCheckFailed gpcheckcat Job " + firstRunJob + " did not report a summary. Check its logs"))
+			})
+		})
+
+		When("the Job is deleted before it finishes", func() {
+			JustBeforeEach(func() {
+				Expect(reactiveClient.Delete(ctx, getJob(firstRunJob))).To(Succeed())
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+			It("sets CatalogConsistent to Unknown", func() {
+				condition := catalogConsistent()
+				Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+				Expect(condition.Message).To(Equal("gpcheckcat Job " + firstRunJob + " was deleted before it finished"))
+			})
+		})
+
+		When("the Job is still running at the next scheduled time", func() {
+			It("skips the run", func() {
+				reconcileAt(secondRun)
+				Expect(listJobs()).To(ConsistOf(firstRunJob))
+				catalogCheck := getGreenplumCluster().Status.CatalogCheck
+				Expect(catalogCheck.LastScheduleTime.Time).To(BeTemporally("==", secondRun))
+				Expect(catalogCheck.Message).To(Equal(
+					"skipped the run scheduled at 2020-06-13T04:00:00Z: Job " + firstRunJob + " is still running"))
+			})
+		})
+
+		When("master-1 is the active master", func() {
+			BeforeEach(func() {
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			})
+			It("runs gpcheckcat against master-1", func() {
+				Expect(getJob(firstRunJob).Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+					Name: "CATALOG_CHECK_HOST", Value: "master-1.agent.test-ns.svc.cluster.local",
+				}))
+			})
+		})
+	})
+
+	When("a gpexpand Job is running", func() {
+		JustBeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: clusterName + "-gpexpand-job"},
+			})).To(Succeed())
+			reconcileAt(firstRun)
+		})
+		It("skips the run", func() {
+			Expect(listJobs()).To(ConsistOf(clusterName + "-gpexpand-job"))
+			Expect(getGreenplumCluster().Status.CatalogCheck.Message).To(Equal(
+				"skipped the run scheduled at 2020-06-06T04:00:00Z: gpexpand Job my-greenplum-gpexpand-job is running"))
+		})
+	})
+
+	When("the schedule is invalid", func() {
+		BeforeEach(func() {
+			greenplumCluster.Spec.CatalogCheck.Schedule = "0 4 * *"
+		})
+		It("reports it without retrying", func() {
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(getGreenplumCluster().Status.CatalogCheck.Message).To(Equal(
+				`invalid schedule: expected 5 fields in schedule "0 4 * *", found 4`))
+		})
+	})
+})
@@ -0,0 +1,175 @@
+package greenplumcluster
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gprestorejob"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/sset"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/volumesnapshot"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// The part of a PVC name after "<cluster>-": <volume>-<statefulset>-<ordinal>
+var snapshotPVCNameRegexp = regexp.MustCompile(`^([a-z][a-z0-9]*)-(master|segment-a|segment-b)-([0-9]+)$`)
+
+// SnapshotPVCs returns the PVCs to create for a new cluster from the VolumeSnapshots of a GreenplumSnapshot.
+// They are named and labeled like the PVCs of the StatefulSets, which then start on them.
+func SnapshotPVCs(greenplumCluster *greenplumv1.GreenplumCluster, snapshot *greenplumv1beta1.GreenplumSnapshot) ([]corev1.PersistentVolumeClaim, error) {
+	var pvcs []corev1.PersistentVolumeClaim
+	for _, volumeSnapshot := range snapshot.Status.VolumeSnapshots {
+		suffix := strings.TrimPrefix(volumeSnapshot.PersistentVolumeClaimName, snapshot.Spec.ClusterName+"-")
+		match := snapshotPVCNameRegexp.FindStringSubmatch(suffix)
+		if match == nil {
+			return nil, fmt.Errorf("GreenplumSnapshot %s has PVC %s, which does not belong to a greenplum StatefulSet",
+				snapshot.Name, volumeSnapshot.PersistentVolumeClaimName)
+		}
+		volumeName, typ := match[1], sset.StatefulSetType(match[2])
+
+		podSpec := greenplumCluster.Spec.Segments.GreenplumPodSpec
+		if typ == sset.TypeMaster {
+			podSpec = greenplumCluster.Spec.MasterAndStandby.GreenplumPodSpec
+		}
+		storageClassName, storage := podSpec.StorageClassName, podSpec.Storage
+		labels := map[string]string{
+			"app":               greenplumv1.AppName,
+			"type":              string(typ),
+			"greenplum-cluster": greenplumCluster.Name,
+		}
+		if volumeName != "pgdata" {
+			volume, ok := findAdditionalVolume(podSpec, volumeName)
+			if !ok {
+				return nil, fmt.Errorf("GreenplumSnapshot %s has PVC %s, but the cluster has no additionalVolume %q",
+					snapshot.Name, volumeSnapshot.PersistentVolumeClaimName, volumeName)
+			}
+			storageClassName, storage = volume.StorageClassName, volume.Storage
+			labels["greenplum-volume"] = volumeName
+		}
+		if snapshot.Status.GreenplumMajorVersion != "" {
+			labels[PVCMajorVersionLabel] = snapshot.Status.GreenplumMajorVersion
+		}
+
+		pvcs = append(pvcs, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: greenplumCluster.Namespace,
+				Name:      greenplumCluster.Name + "-" + suffix,
+				Labels:    labels,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClassName,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Limits:   corev1.ResourceList{corev1.ResourceStorage: storage},
+					Requests: corev1.ResourceList{corev1.ResourceStorage: storage},
+				},
+				DataSource: &corev1.TypedLocalObjectReference{
+					APIGroup: &volumesnapshot.GroupVersion.Group,
+					Kind:     "VolumeSnapshot",
+					Name:     volumeSnapshot.VolumeSnapshotName,
+				},
+			},
+		})
+	}
+	return pvcs, nil
+}
+
+func findAdditionalVolume(podSpec greenplumv1.GreenplumPodSpec, name string) (greenplumv1.GreenplumAdditionalVolume, bool) {
+	for _, volume := range podSpec.AdditionalVolumes {
+		if volume.Name == name {
+			return volume, true
+		}
+	}
+	return greenplumv1.GreenplumAdditionalVolume{}, false
+}
+
+// createPVCsFromSnapshot creates the PVCs of a new cluster from its dataSource snapshot. It must run before
+// the StatefulSets are created, or they would provision empty PVCs.
+func (r *GreenplumClusterReconciler) createPVCsFromSnapshot(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster) error {
+	if greenplumCluster.Spec.DataSource == nil || greenplumCluster.Spec.DataSource.SnapshotName == "" {
+		return nil
+	}
+
+	var snapshot greenplumv1beta1.GreenplumSnapshot
+	snapshotKey := types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: greenplumCluster.Spec.DataSource.SnapshotName}
+	if err := r.Get(ctx, snapshotKey, &snapshot); err != nil {
+		return fmt.Errorf("unable to get GreenplumSnapshot %s: %w", snapshotKey.Name, err)
+	}
+	if snapshot.Status.Phase != greenplumv1beta1.GreenplumSnapshotPhaseReady {
+		err := fmt.Errorf("GreenplumSnapshot %s is not Ready", snapshot.Name)
+		r.Recorder.Event(greenplumCluster, corev1.EventTypeWarning, "SnapshotNotReady", err.Error())
+		return err
+	}
+
+	pvcs, err := SnapshotPVCs(greenplumCluster, &snapshot)
+	if err != nil {
+		return err
+	}
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		if err := r.Create(ctx, pvc); err != nil && !apierrs.IsAlreadyExists(err) {
+			return fmt.Errorf("unable to create PVC %s: %w", pvc.Name, err)
+		}
+	}
+	r.Log.Info("created PVCs from snapshot", "snapshot", snapshot.Name, "count", len(pvcs))
+	r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "CreatedPVCsFromSnapshot",
+		"Created %d PVCs from GreenplumSnapshot %s", len(pvcs), snapshot.Name)
+	return nil
+}
+
+// handleRestore restores the dataSource backup of a new cluster with a gprestore Job.
+// It returns true once there is nothing left to restore.
+func (r *GreenplumClusterReconciler) handleRestore(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) (bool, error) {
+	if greenplumCluster.Spec.DataSource == nil || greenplumCluster.Spec.DataSource.Backup == nil {
+		return true, nil
+	}
+
+	jobKey := types.NamespacedName{
+		Namespace: greenplumCluster.Namespace,
+		Name:      fmt.Sprintf("%s-gprestore-job", greenplumCluster.Name),
+	}
+
+	var existingJob batchv1.Job
+	if err := r.Get(ctx, jobKey, &existingJob); err == nil {
+		if existingJob.Status.Failed > 0 {
+			// The Job is kept so its logs can be read
+			r.setStatus(ctx, greenplumCluster, greenplumv1.GreenplumClusterPhaseFailed)
+			r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, "RestoreFailed",
+				"gprestore Job %s failed. Check its logs", existingJob.Name)
+			return false, nil
+		}
+		// The Job is not deleted once it succeeds, so that the backup is never restored twice
+		if existingJob.Status.Succeeded > 0 {
+			r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "RestoreSucceeded",
+				"Restored backup %s", greenplumCluster.Spec.DataSource.Backup.Timestamp)
+			return true, nil
+		}
+		return false, nil
+	} else if !apierrs.IsNotFound(err) {
+		return false, err
+	}
+
+	activeMasterFQDN := fmt.Sprintf("%s.agent.%s.svc.cluster.local", activeMaster, greenplumCluster.Namespace)
+	job := gprestorejob.GenerateJob(r.InstanceImage, activeMasterFQDN, *greenplumCluster.Spec.DataSource.Backup)
+	job.Namespace = jobKey.Namespace
+	job.Name = jobKey.Name
+
+	if err := ctrl.SetControllerReference(greenplumCluster, &job, r.Scheme()); err != nil {
+		// not tested: not really possible to fail here
+		return false, err
+	}
+	if err := r.Create(ctx, &job); err != nil {
+		return false, err
+	}
+	r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "RestoreStarted",
+		"Created gprestore Job %s to restore backup %s", job.Name, greenplumCluster.Spec.DataSource.Backup.Timestamp)
+	return false, nil
+}
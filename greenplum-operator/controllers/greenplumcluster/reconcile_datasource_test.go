@@ -0,0 +1,274 @@
+package greenplumcluster_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Reconcile dataSource", func() {
+	var (
+		ctx                 context.Context
+		logBuf              *gbytes.Buffer
+		podExec             *fake.PodExec
+		fakeQuerier         *fakequerier.Querier
+		recorder            *record.FakeRecorder
+		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
+		greenplumCluster    *greenplumv1.GreenplumCluster
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		podExec = &fake.PodExec{}
+		fakeQuerier = &fakequerier.Querier{}
+		recorder = record.NewFakeRecorder(100)
+		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
+			Client:        reactiveClient,
+			Log:           gplog.ForTest(logBuf),
+			SSHCreator:    fakeSecretCreator{},
+			InstanceImage: "greenplum-for-kubernetes:latest",
+			OperatorImage: "greenplum-operator:latest",
+			PodExec:       podExec,
+			Querier:       fakeQuerier,
+			Recorder:      recorder,
+		}
+		greenplumCluster = exampleGreenplumCluster.DeepCopy()
+	})
+
+	getCluster := func() *greenplumv1.GreenplumCluster {
+		var current greenplumv1.GreenplumCluster
+		Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &current)).To(Succeed())
+		return &current
+	}
+
+	listPVCs := func() []corev1.PersistentVolumeClaim {
+		var pvcList corev1.PersistentVolumeClaimList
+		Expect(reactiveClient.List(ctx, &pvcList, client.InNamespace(namespaceName))).To(Succeed())
+		return pvcList.Items
+	}
+
+	When("the dataSource is a snapshot", func() {
+		var snapshot *greenplumv1beta1.GreenplumSnapshot
+
+		BeforeEach(func() {
+			greenplumCluster.Spec.DataSource = &greenplumv1.GreenplumDataSource{SnapshotName: "nightly"}
+			greenplumCluster.Spec.Segments.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+				{Name: "fast", StorageClassName: "ssd", Storage: resource.MustParse("5G"), Usage: greenplumv1.GreenplumVolumeUsageTablespace},
+			}
+			snapshot = &greenplumv1beta1.GreenplumSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: "nightly"},
+				Spec:       greenplumv1beta1.GreenplumSnapshotSpec{ClusterName: "old-greenplum"},
+				Status: greenplumv1beta1.GreenplumSnapshotStatus{
+					Phase:                 greenplumv1beta1.GreenplumSnapshotPhaseReady,
+					GreenplumMajorVersion: greenplumcluster.SupportedGreenplumMajorVersion,
+					VolumeSnapshots: []greenplumv1beta1.GreenplumSnapshotVolume{
+						{PersistentVolumeClaimName: "old-greenplum-pgdata-master-0", VolumeSnapshotName: "nightly-old-greenplum-pgdata-master-0"},
+						{PersistentVolumeClaimName: "old-greenplum-pgdata-segment-a-0", VolumeSnapshotName: "nightly-old-greenplum-pgdata-segment-a-0"},
+						{PersistentVolumeClaimName: "old-greenplum-fast-segment-a-0", VolumeSnapshotName: "nightly-old-greenplum-fast-segment-a-0"},
+					},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, snapshot)).To(Succeed())
+			Expect(reactiveClient.Create(ctx, greenplumCluster)).To(Succeed())
+		})
+
+		It("creates the PVCs of the new cluster from the VolumeSnapshots", func() {
+			_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			pvcs := listPVCs()
+			Expect(pvcs).To(HaveLen(3))
+			var masterPVC, fastPVC corev1.PersistentVolumeClaim
+			pvcKey := types.NamespacedName{Namespace: namespaceName, Name: "my-greenplum-pgdata-master-0"}
+			Expect(reactiveClient.Get(ctx, pvcKey, &masterPVC)).To(Succeed())
+			Expect(masterPVC.Labels).To(Equal(map[string]string{
+				"app":                     "greenplum",
+				"type":                    "master",
+				"greenplum-cluster":       "my-greenplum",
+				"greenplum-major-version": greenplumcluster.SupportedGreenplumMajorVersion,
+			}))
+			Expect(*masterPVC.Spec.StorageClassName).To(Equal("standard"))
+			Expect(masterPVC.Spec.Resources.Requests[corev1.ResourceStorage]).To(Equal(resource.MustParse("1G")))
+			Expect(*masterPVC.Spec.DataSource.APIGroup).To(Equal("snapshot.storage.k8s.io"))
+			Expect(masterPVC.Spec.DataSource.Kind).To(Equal("VolumeSnapshot"))
+			Expect(masterPVC.Spec.DataSource.Name).To(Equal("nightly-old-greenplum-pgdata-master-0"))
+
+			pvcKey.Name = "my-greenplum-fast-segment-a-0"
+			Expect(reactiveClient.Get(ctx, pvcKey, &fastPVC)).To(Succeed())
+			Expect(fastPVC.Labels).To(HaveKeyWithValue("greenplum-volume", "fast"))
+			Expect(fastPVC.Labels).To(HaveKeyWithValue("type", "segment-a"))
+			Expect(*fastPVC.Spec.StorageClassName).To(Equal("ssd"))
+			Expect(fastPVC.Spec.Resources.Requests[corev1.ResourceStorage]).To(Equal(resource.MustParse("5G")))
+			Expect(fastPVC.Spec.DataSource.Name).To(Equal("nightly-old-greenplum-fast-segment-a-0"))
+
+			Expect(logBuf).To(gbytes.Say("created PVCs from snapshot"))
+			Expect(receivedEvents(recorder)).To(ContainElements(
+				"Normal CreatedPVCsFromSnapshot Created 3 PVCs from GreenplumSnapshot nightly",
+				"Normal Initializing Created the StatefulSets of the greenplum cluster"))
+		})
+
+		It("does not create the PVCs again once the cluster exists", func() {
+			_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+			Expect(err).NotTo(HaveOccurred())
+			for _, pvc := range listPVCs() {
+				Expect(reactiveClient.Delete(ctx, &pvc)).To(Succeed())
+			}
+
+			_, err = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listPVCs()).To(BeEmpty())
+		})
+
+		When("the snapshot is not Ready", func() {
+			BeforeEach(func() {
+				snapshot.Status.Phase = greenplumv1beta1.GreenplumSnapshotPhaseSnapshotting
+			})
+			It("does not create the cluster", func() {
+				_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+				Expect(err).To(MatchError("GreenplumSnapshot nightly is not Ready"))
+				Expect(receivedEvents(recorder)).To(ContainElement("Warning SnapshotNotReady GreenplumSnapshot nightly is not Ready"))
+				Expect(listPVCs()).To(BeEmpty())
+				var ssetList appsv1.StatefulSetList
+				Expect(reactiveClient.List(ctx, &ssetList)).To(Succeed())
+				Expect(ssetList.Items).To(BeEmpty())
+			})
+		})
+
+		When("the cluster does not have an additional volume of the snapshot", func() {
+			BeforeEach(func() {
+				greenplumCluster.Spec.Segments.AdditionalVolumes = nil
+			})
+			It("does not create the cluster", func() {
+				_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+				Expect(err).To(MatchError(`GreenplumSnapshot nightly has PVC old-greenplum-fast-segment-a-0, but the cluster has no additionalVolume "fast"`))
+				Expect(listPVCs()).To(BeEmpty())
+			})
+		})
+	})
+
+	When("the dataSource is a backup", func() {
+		var jobKey = types.NamespacedName{Namespace: namespaceName, Name: "my-greenplum-gprestore-job"}
+
+		BeforeEach(func() {
+			greenplumCluster.Spec.DataSource = &greenplumv1.GreenplumDataSource{
+				Backup: &greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", BackupDir: "/greenplum/volumes/backups"},
+			}
+			Expect(reactiveClient.Create(ctx, greenplumCluster)).To(Succeed())
+		})
+
+		It("waits for the master before restoring", func() {
+			fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			fakeQuerier.ErrorMsgOnMaster1 = "not active"
+			_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+			Expect(err).NotTo(HaveOccurred())
+			var job batchv1.Job
+			Expect(reactiveClient.Get(ctx, jobKey, &job)).To(MatchError(`jobs.batch "my-greenplum-gprestore-job" not found`))
+		})
+
+		It("runs gprestore on the active master and stays Pending", func() {
+			result, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+
+			var job batchv1.Job
+			Expect(reactiveClient.Get(ctx, jobKey, &job)).To(Succeed())
+			gprestoreContainer := job.Spec.Template.Spec.Containers[0]
+			Expect(gprestoreContainer.Image).To(Equal("greenplum-for-kubernetes:latest"))
+			Expect(gprestoreContainer.Env).To(ContainElement(corev1.EnvVar{
+				Name:  "GPRESTORE_HOST",
+				Value: "master-0.agent.test-ns.svc.cluster.local",
+			}))
+			Expect(gprestoreContainer.Args).To(ContainElements("20200102030405", "/greenplum/volumes/backups"))
+			Expect(job.GetOwnerReferences()).To(ConsistOf(beOwnedByGreenplum))
+			Expect(getCluster().Status.Phase).To(Equal(greenplumv1.GreenplumClusterPhasePending))
+			Expect(receivedEvents(recorder)).To(ContainElement(
+				"Normal RestoreStarted Created gprestore Job my-greenplum-gprestore-job to restore backup 20200102030405"))
+		})
+
+		When("gprestore is running", func() {
+			BeforeEach(func() {
+				_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("stays Pending", func() {
+				_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getCluster().Status.Phase).To(Equal(greenplumv1.GreenplumClusterPhasePending))
+			})
+
+			It("is Running once gprestore succeeds, and keeps the Job", func() {
+				var job batchv1.Job
+				Expect(reactiveClient.Get(ctx, jobKey, &job)).To(Succeed())
+				job.Status.Succeeded = 1
+				Expect(reactiveClient.Update(ctx, &job)).To(Succeed())
+
+				result, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeZero())
+				Expect(getCluster().Status.Phase).To(Equal(greenplumv1.GreenplumClusterPhaseRunning))
+				Expect(reactiveClient.Get(ctx, jobKey, &job)).To(Succeed())
+				Expect(receivedEvents(recorder)).To(ContainElements(
+					"Normal RestoreSucceeded Restored backup 20200102030405",
+					"Normal Running Greenplum cluster is running on master-0"))
+			})
+
+			It("is Failed when gprestore fails", func() {
+				var job batchv1.Job
+				Expect(reactiveClient.Get(ctx, jobKey, &job)).To(Succeed())
+				job.Status.Failed = 1
+				Expect(reactiveClient.Update(ctx, &job)).To(Succeed())
+
+				result, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(getCluster().Status.Phase).To(Equal(greenplumv1.GreenplumClusterPhaseFailed))
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Warning RestoreFailed gprestore Job my-greenplum-gprestore-job failed. Check its logs"))
+			})
+
+			When("gprestore has failed", func() {
+				BeforeEach(func() {
+					var job batchv1.Job
+					Expect(reactiveClient.Get(ctx, jobKey, &job)).To(Succeed())
+					job.Status.Failed = 1
+					Expect(reactiveClient.Update(ctx, &job)).To(Succeed())
+					_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					Expect(err).NotTo(HaveOccurred())
+					receivedEvents(recorder)
+					podExec.RecordedCommands = nil
+				})
+
+				It("stays Failed, and does not run anything against the cluster", func() {
+					result, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(ctrl.Result{}))
+					Expect(getCluster().Status.Phase).To(Equal(greenplumv1.GreenplumClusterPhaseFailed))
+					Expect(podExec.RecordedCommands).To(BeEmpty())
+					Expect(receivedEvents(recorder)).To(BeEmpty())
+				})
+			})
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func SetDefaultGreenplumClusterValues(greenplumCluster *greenplumv1.GreenplumCluster) {
@@ -17,4 +18,15 @@ func SetDefaultGreenplumClusterValues(greenplumCluster *greenplumv1.GreenplumClu
 		// It will be easier to deal with these properties later if they are guaranteed to be lowercase
 		*p = strings.ToLower(*p)
 	}
+
+	topologyKeyFields := []*string{
+		&greenplumCluster.Spec.MasterAndStandby.AntiAffinityTopologyKey,
+		&greenplumCluster.Spec.Segments.AntiAffinityTopologyKey,
+	}
+	for _, p := range topologyKeyFields {
+		// Clusters created before antiAffinityTopologyKey existed kept pods on separate hosts
+		if *p == "" {
+			*p = corev1.LabelHostname
+		}
+	}
 }
@@ -51,4 +51,18 @@ var _ = Describe("greenplumcluster.SetDefaultGreenplumClusterValues", func() {
 			}
 		})
 	})
+	When("given a greenplumCluster without antiAffinityTopologyKey", func() {
+		It("keeps master and standby, and primaries and mirrors, on different hosts", func() {
+			greenplumcluster.SetDefaultGreenplumClusterValues(fakeGreenplumCluster)
+			Expect(fakeGreenplumCluster.Spec.MasterAndStandby.AntiAffinityTopologyKey).To(Equal("kubernetes.io/hostname"))
+			Expect(fakeGreenplumCluster.Spec.Segments.AntiAffinityTopologyKey).To(Equal("kubernetes.io/hostname"))
+		})
+	})
+	When("given a greenplumCluster with antiAffinityTopologyKey", func() {
+		It("keeps the given key", func() {
+			fakeGreenplumCluster.Spec.Segments.AntiAffinityTopologyKey = "topology.kubernetes.io/zone"
+			greenplumcluster.SetDefaultGreenplumClusterValues(fakeGreenplumCluster)
+			Expect(fakeGreenplumCluster.Spec.Segments.AntiAffinityTopologyKey).To(Equal("topology.kubernetes.io/zone"))
+		})
+	})
 })
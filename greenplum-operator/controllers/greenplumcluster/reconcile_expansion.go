@@ -1,16 +1,17 @@
 package greenplumcluster
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpexpandjob"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -18,21 +19,20 @@ import (
 )
 
 func (r *GreenplumClusterReconciler) handleExpand(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) error {
-	segmentCount, err := r.getCurrentSegmentCount(greenplumCluster.Namespace, activeMaster)
+	segmentCount, err := r.getCurrentSegmentCount(ctx, greenplumCluster.Namespace, activeMaster)
 	if err != nil {
 		return err
 	}
-	if greenplumCluster.Spec.Segments.PrimarySegmentCount <= segmentCount {
-		return nil
-	}
 
-	jobKey := types.NamespacedName{
-		Namespace: greenplumCluster.Namespace,
-		Name:      fmt.Sprintf("%s-gpexpand-job", greenplumCluster.Name),
-	}
+	jobKey := gpexpandJobKey(greenplumCluster)
 
 	var existingJob batchv1.Job
 	if err := r.Get(ctx, jobKey, &existingJob); err == nil {
+		if existingJob.Status.Failed > 0 {
+			// The Job is kept so its logs can be read. Deleting it retries the expansion.
+			return r.setExpansionFailedCondition(ctx, greenplumCluster, metav1.ConditionTrue, "GpexpandJobFailed",
+				fmt.Sprintf("gpexpand Job %s failed. Check its logs and gpAdminLogs on the master, then delete the Job to retry", existingJob.Name))
+		}
 		// Job already exists, and is not complete yet
 		if existingJob.Status.Succeeded < 1 {
 			return nil
@@ -43,10 +43,22 @@ func (r *GreenplumClusterReconciler) handleExpand(ctx context.Context, greenplum
 		if err != nil {
 			return err
 		}
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "ExpansionSucceeded",
+			"Expanded to %d primary segments", segmentCount)
 	} else {
 		if !apierrs.IsNotFound(err) {
 			return err
 		}
+		if meta.IsStatusConditionTrue(greenplumCluster.Status.Conditions, greenplumv1.ExpansionFailedCondition) {
+			if err := r.setExpansionFailedCondition(ctx, greenplumCluster, metav1.ConditionFalse, "GpexpandJobDeleted",
+				"the failed gpexpand Job was deleted"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if greenplumCluster.Spec.Segments.PrimarySegmentCount <= segmentCount {
+		return nil
 	}
 
 	activeMasterFQDN := fmt.Sprintf("%s.agent.%s.svc.cluster.local", activeMaster, greenplumCluster.Namespace)
@@ -58,25 +70,66 @@ func (r *GreenplumClusterReconciler) handleExpand(ctx context.Context, greenplum
 		// not tested: not really possible to fail here
 		return err
 	}
-	return r.Create(ctx, &job)
+	if err := r.Create(ctx, &job); err != nil {
+		return err
+	}
+	r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "ExpansionStarted",
+		"Created gpexpand Job %s to expand from %d to %d primary segments", job.Name, segmentCount, greenplumCluster.Spec.Segments.PrimarySegmentCount)
+	return nil
+}
+
+// setExpansionFailedCondition records the condition in the status. A failure is reported with an event once,
+// when the condition becomes True.
+func (r *GreenplumClusterReconciler) setExpansionFailedCondition(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, status metav1.ConditionStatus, reason, message string) error {
+	originalGreenplumCluster := greenplumCluster.DeepCopy()
+	meta.SetStatusCondition(&greenplumCluster.Status.Conditions, metav1.Condition{
+		Type:               greenplumv1.ExpansionFailedCondition,
+		Status:             status,
+		ObservedGeneration: greenplumCluster.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	if equality.Semantic.DeepEqual(greenplumCluster.Status, originalGreenplumCluster.Status) {
+		return nil
+	}
+	if err := r.Patch(ctx, greenplumCluster, client.MergeFrom(originalGreenplumCluster)); err != nil {
+		return fmt.Errorf("updating %s condition: %w", greenplumv1.ExpansionFailedCondition, err)
+	}
+	if status == metav1.ConditionTrue {
+		r.Recorder.Event(greenplumCluster, corev1.EventTypeWarning, "ExpansionFailed", message)
+	}
+	return nil
 }
 
-func (r *GreenplumClusterReconciler) getCurrentSegmentCount(namespace, masterPodName string) (int32, error) {
-	getSegmentCountCommand := []string{
-		"/bin/bash",
-		"-c",
-		"--",
-		`source /usr/local/greenplum-db/greenplum_path.sh && psql -t -U gpadmin -c "SELECT COUNT(*) FROM gp_segment_configuration WHERE hostname LIKE 'segment-a%'"`,
+func gpexpandJobKey(greenplumCluster *greenplumv1.GreenplumCluster) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: greenplumCluster.Namespace,
+		Name:      gpexpandjob.JobName(greenplumCluster.Name),
 	}
-	stdoutBuf := &bytes.Buffer{}
-	stderrBuf := &bytes.Buffer{}
-	err := r.PodExec.Execute(getSegmentCountCommand, namespace, masterPodName, stdoutBuf, stderrBuf)
-	if err != nil {
-		return 0, err
+}
+
+// expansionInProgress returns whether a gpexpand Job exists that has neither succeeded nor failed
+func (r *GreenplumClusterReconciler) expansionInProgress(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster) (bool, error) {
+	return expansionInProgress(ctx, r, greenplumCluster)
+}
+
+func expansionInProgress(ctx context.Context, c client.Reader, greenplumCluster *greenplumv1.GreenplumCluster) (bool, error) {
+	var job batchv1.Job
+	if err := c.Get(ctx, gpexpandJobKey(greenplumCluster), &job); err != nil {
+		if apierrs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
 	}
-	segCount, err := strconv.ParseInt(strings.TrimSpace(stdoutBuf.String()), 10, 32)
+	return job.Status.Succeeded < 1 && job.Status.Failed == 0, nil
+}
+
+func (r *GreenplumClusterReconciler) getCurrentSegmentCount(ctx context.Context, namespace, masterPodName string) (int32, error) {
+	var segCount int32
+	err := r.Querier.QueryRow(ctx, querier.Master{Namespace: namespace, PodName: masterPodName},
+		"SELECT COUNT(*) FROM gp_segment_configuration WHERE hostname LIKE 'segment-a%'").Scan(&segCount)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to count segments: %w", err)
 	}
-	return int32(segCount), nil
+	return segCount, nil
 }
@@ -14,13 +14,17 @@ import (
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/configmap"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpexpandjob"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -29,6 +33,8 @@ var _ = Describe("Reconcile expansion", func() {
 		logBuf              *gbytes.Buffer
 		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
 		podExec             *fake.PodExec
+		fakeQuerier         *fakequerier.Querier
+		recorder            *record.FakeRecorder
 		ctx                 context.Context
 	)
 	BeforeEach(func() {
@@ -36,6 +42,8 @@ var _ = Describe("Reconcile expansion", func() {
 		ctx = context.WithValue(context.Background(), struct{ key string }{"test"}, CurrentGinkgoTestDescription().TestText)
 
 		podExec = &fake.PodExec{}
+		fakeQuerier = &fakequerier.Querier{}
+		recorder = record.NewFakeRecorder(100)
 		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
 			Client:        reactiveClient,
 			Log:           gplog.ForTest(logBuf),
@@ -43,6 +51,8 @@ var _ = Describe("Reconcile expansion", func() {
 			InstanceImage: "greenplum-for-kubernetes:latest",
 			OperatorImage: "greenplum-operator:latest",
 			PodExec:       podExec,
+			Querier:       fakeQuerier,
+			Recorder:      recorder,
 		}
 	})
 
@@ -55,15 +65,15 @@ var _ = Describe("Reconcile expansion", func() {
 		firstGreenplumClusterSpec = exampleGreenplumCluster.DeepCopy()
 		firstGreenplumClusterSpec.Spec.Segments.PrimarySegmentCount = 5
 
-		podExec.ErrorMsgOnMaster0 = "not active"
-		podExec.ErrorMsgOnMaster1 = "not active"
+		fakeQuerier.ErrorMsgOnMaster0 = "not active"
+		fakeQuerier.ErrorMsgOnMaster1 = "not active"
 		Expect(reactiveClient.Create(nil, firstGreenplumClusterSpec)).To(Succeed())
 		_, err := greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 		Expect(err).NotTo(HaveOccurred())
 
-		podExec.ErrorMsgOnMaster0 = ""
-		podExec.ErrorMsgOnMaster1 = ""
-		podExec.SegmentCount = "5\n"
+		fakeQuerier.ErrorMsgOnMaster0 = ""
+		fakeQuerier.ErrorMsgOnMaster1 = ""
+		fakeQuerier.SegmentCount = 5
 	})
 
 	When("gpexpand-job does not exist", func() {
@@ -136,28 +146,31 @@ var _ = Describe("Reconcile expansion", func() {
 			})
 			When("there is an error getting the segment count from the master", func() {
 				BeforeEach(func() {
-					podExec.SegmentCountErr = errors.New("psql is borken")
+					fakeQuerier.SegmentCountErr = errors.New("psql is borken")
 				})
 				It("returns an error", func() {
-					Expect(reconcileErr).To(MatchError("unable to run gpexpand: psql is borken"))
+					Expect(reconcileErr).To(MatchError("unable to run gpexpand: failed to count segments: psql is borken"))
 				})
 			})
-			When("the segment count is not a number", func() {
-				BeforeEach(func() {
-					podExec.SegmentCount = "I'm a free man\n"
-				})
-				It("returns an error", func() {
-					Expect(reconcileErr).To(MatchError(`unable to run gpexpand: strconv.ParseInt: parsing "I'm a free man": invalid syntax`))
-				})
+		})
+
+		When("the cluster has fewer segments than the spec", func() {
+			BeforeEach(func() {
+				fakeQuerier.SegmentCount = 4
+			})
+			It("emits an ExpansionStarted event", func() {
+				Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Normal ExpansionStarted Created gpexpand Job my-greenplum-gpexpand-job to expand from 4 to 5 primary segments"))
 			})
 		})
 
 		When("gpdb cluster size is unchanged", func() {
 			var reconcileErr error
 			BeforeEach(func() {
-				podExec.ErrorMsgOnMaster0 = ""
-				podExec.ErrorMsgOnMaster1 = ""
-				podExec.SegmentCount = "5\n"
+				fakeQuerier.ErrorMsgOnMaster0 = ""
+				fakeQuerier.ErrorMsgOnMaster1 = ""
+				fakeQuerier.SegmentCount = 5
 				_, reconcileErr = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 			})
 			It("does not create a new job", func() {
@@ -174,9 +187,9 @@ var _ = Describe("Reconcile expansion", func() {
 		When("there's no active master", func() {
 			var reconcileErr error
 			BeforeEach(func() {
-				podExec.ErrorMsgOnMaster0 = "not active"
-				podExec.ErrorMsgOnMaster1 = "not active"
-				podExec.SegmentCount = ""
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
+				fakeQuerier.ErrorMsgOnMaster1 = "not active"
+				fakeQuerier.SegmentCount = 0
 				_, reconcileErr = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
 			})
 			It("does not create a new job", func() {
@@ -280,6 +293,65 @@ var _ = Describe("Reconcile expansion", func() {
 				})
 			})
 		})
+		When("the job has succeeded and the cluster has the requested segments", func() {
+			BeforeEach(func() {
+				existingJob.Status.Succeeded = 1
+			})
+			It("deletes the job and emits an ExpansionSucceeded event", func() {
+				Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+
+				var job batchv1.Job
+				Expect(reactiveClient.Get(nil, types.NamespacedName{Namespace: existingJob.Namespace, Name: existingJob.Name}, &job)).
+					To(MatchError(`jobs.batch "my-greenplum-gpexpand-job" not found`))
+				Expect(receivedEvents(recorder)).To(ContainElement("Normal ExpansionSucceeded Expanded to 5 primary segments"))
+			})
+		})
+		When("the job has failed", func() {
+			BeforeEach(func() {
+				existingJob.Status.Failed = 1
+			})
+			It("keeps the job and emits an ExpansionFailed event", func() {
+				Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+
+				var job batchv1.Job
+				Expect(reactiveClient.Get(nil, types.NamespacedName{Namespace: existingJob.Namespace, Name: existingJob.Name}, &job)).To(Succeed())
+				Expect(job.Status.Failed).To(BeNumerically("==", 1))
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Warning ExpansionFailed gpexpand Job my-greenplum-gpexpand-job failed. Check its logs and gpAdminLogs on the master, then delete the Job to retry"))
+			})
+			It("records the failure in the ExpansionFailed condition", func() {
+				Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+
+				var greenplumCluster greenplumv1.GreenplumCluster
+				Expect(reactiveClient.Get(nil, greenplumClusterRequest.NamespacedName, &greenplumCluster)).To(Succeed())
+				condition := meta.FindStatusCondition(greenplumCluster.Status.Conditions, greenplumv1.ExpansionFailedCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal("GpexpandJobFailed"))
+				Expect(condition.Message).To(ContainSubstring("gpexpand Job my-greenplum-gpexpand-job failed"))
+			})
+			It("emits the ExpansionFailed event only once", func() {
+				Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+				Expect(receivedEvents(recorder)).To(ContainElement(HavePrefix("Warning ExpansionFailed")))
+
+				Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+				Expect(receivedEvents(recorder)).NotTo(ContainElement(HavePrefix("Warning ExpansionFailed")))
+			})
+			When("the failed job is deleted", func() {
+				It("clears the ExpansionFailed condition", func() {
+					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+					Expect(reactiveClient.Delete(nil, &existingJob)).To(Succeed())
+					Expect(greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)).To(Equal(ctrl.Result{}))
+
+					var greenplumCluster greenplumv1.GreenplumCluster
+					Expect(reactiveClient.Get(nil, greenplumClusterRequest.NamespacedName, &greenplumCluster)).To(Succeed())
+					condition := meta.FindStatusCondition(greenplumCluster.Status.Conditions, greenplumv1.ExpansionFailedCondition)
+					Expect(condition).NotTo(BeNil())
+					Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+					Expect(condition.Reason).To(Equal("GpexpandJobDeleted"))
+				})
+			})
+		})
 		When("the job has not yet succeeded", func() {
 			var sawCreate bool
 			JustBeforeEach(func() {
@@ -2,10 +2,12 @@ package greenplumcluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -22,9 +24,35 @@ func (r *GreenplumClusterReconciler) handleFinalizer(ctx context.Context, greenp
 		}
 	} else {
 		if sliceContainsString(greenplumCluster.Finalizers, StopClusterFinalizer) {
+			if err := removeAntiAffinityNodeLabels(ctx, r, greenplumCluster.Namespace); apierrs.IsForbidden(err) {
+				// Nothing selects on the legacy labels anymore, so a namespace-scoped operator leaves them behind.
+				r.Log.Info("skipping removal of legacy antiAffinity node labels", "reason", err.Error())
+			} else if err != nil {
+				r.Recorder.Event(greenplumCluster, corev1.EventTypeWarning, "AntiAffinityFailed", err.Error())
+				return err
+			}
 			r.setStatus(ctx, greenplumCluster, greenplumv1.GreenplumClusterPhaseDeleting)
-			r.ensureGreenplumClusterStopped(greenplumCluster, *activeMaster)
+			// Retained PVCs are labeled for reuse by a new cluster, so they must come from a cleanly stopped one:
+			// gpstop -M fast rolls back the transactions in progress and checkpoints. Deleted PVCs do not need
+			// a clean stop, so gpstop -M immediate just terminates the postmasters.
+			retain := greenplumCluster.Spec.PersistentVolumeClaimRetentionPolicy != greenplumv1.GreenplumPVCRetentionPolicyDelete
+			stopMode := "immediate"
+			if retain {
+				stopMode = "fast"
+			}
+			stopErr := r.ensureGreenplumClusterStopped(ctx, greenplumCluster, *activeMaster, stopMode)
+			if stopErr != nil && retain {
+				if greenplumCluster.Annotations[greenplumv1.RetainWithoutStopAnnotation] != "true" {
+					return fmt.Errorf("unable to stop the cluster before retaining its PVCs (set the %s annotation to \"true\" to retain them anyway): %w",
+						greenplumv1.RetainWithoutStopAnnotation, stopErr)
+				}
+				r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, "RetainingUnstopped",
+					"Retaining the PVCs of a cluster that was not stopped cleanly, as requested by the %s annotation", greenplumv1.RetainWithoutStopAnnotation)
+			}
 			*activeMaster = ""
+			if err := r.handlePVCRetention(ctx, greenplumCluster); err != nil {
+				return err
+			}
 			greenplumCluster.Finalizers = removeStringFromSlice(greenplumCluster.Finalizers, StopClusterFinalizer)
 			verb = "removing"
 			needsPatch = true
@@ -61,21 +89,34 @@ func removeStringFromSlice(slice []string, s string) (result []string) {
 	return
 }
 
-func (r *GreenplumClusterReconciler) ensureGreenplumClusterStopped(greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) {
+// ensureGreenplumClusterStopped runs gpstop with the shutdown mode on activeMaster, if there is one, and returns its error
+func (r *GreenplumClusterReconciler) ensureGreenplumClusterStopped(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster, mode string) error {
 	if activeMaster != "" {
 		r.Log.Info("initiating shutdown of the greenplum cluster")
 		gpStopCommand := []string{
 			"/bin/bash",
 			"-c",
 			"--",
-			"source /usr/local/greenplum-db/greenplum_path.sh && gpstop -aM immediate",
+			"source /usr/local/greenplum-db/greenplum_path.sh && gpstop -aM " + mode,
 		}
-		stdout, stderr := ioutil.Discard, ioutil.Discard
-		err := r.PodExec.Execute(gpStopCommand, greenplumCluster.Namespace, activeMaster, stdout, stderr)
+		err := r.PodExec.Execute(ctx, executor.ExecRequest{
+			Namespace: greenplumCluster.Namespace,
+			PodName:   activeMaster,
+			Command:   gpStopCommand,
+		})
 		if err != nil {
-			r.Log.Info("greenplum cluster did not shutdown cleanly. Please check gpAdminLogs for more info.")
-		} else {
-			r.Log.Info("success shutting down the greenplum cluster")
+			var stderr string
+			var execErr *executor.ExecError
+			if errors.As(err, &execErr) {
+				stderr = execErr.Stderr
+			}
+			r.Log.Error(err, "greenplum cluster did not shutdown cleanly. Please check gpAdminLogs for more info.", "stderr", stderr)
+			r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, "StopFailed",
+				"gpstop on %s failed: %v. Check gpAdminLogs for more info", activeMaster, err)
+			return err
 		}
+		r.Log.Info("success shutting down the greenplum cluster")
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "Stopped", "gpstop on %s succeeded", activeMaster)
 	}
+	return nil
 }
@@ -14,12 +14,17 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	. "github.com/pivotal/greenplum-for-kubernetes/pkg/gplog/testing"
+	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 var _ = Describe("Reconcile stopcluster.greenplumclusters.pivotal.io finalizer", func() {
@@ -29,21 +34,31 @@ var _ = Describe("Reconcile stopcluster.greenplumclusters.pivotal.io finalizer",
 		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
 		greenplumCluster    *greenplumv1.GreenplumCluster
 		podExec             *fake.PodExec
+		fakeQuerier         *fakequerier.Querier
+		recorder            *record.FakeRecorder
 	)
 
-	const gpstopCommand = "/bin/bash -c -- source /usr/local/greenplum-db/greenplum_path.sh && gpstop -aM immediate"
+	const (
+		// A cluster whose PVCs are retained is stopped cleanly
+		gpstopCommand          = "/bin/bash -c -- source /usr/local/greenplum-db/greenplum_path.sh && gpstop -aM fast"
+		gpstopImmediateCommand = "/bin/bash -c -- source /usr/local/greenplum-db/greenplum_path.sh && gpstop -aM immediate"
+	)
 
 	BeforeEach(func() {
 		ctx = context.WithValue(context.Background(), struct{ key string }{"test"}, CurrentGinkgoTestDescription().TestText)
 		logBuf = gbytes.NewBuffer()
 
 		podExec = &fake.PodExec{}
+		fakeQuerier = &fakequerier.Querier{}
+		recorder = record.NewFakeRecorder(100)
 		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
 			Client:        reactiveClient,
 			Log:           gplog.ForTest(logBuf),
 			SSHCreator:    fakeSecretCreator{},
 			InstanceImage: "greenplum-for-kubernetes:v1.0",
 			PodExec:       podExec,
+			Querier:       fakeQuerier,
+			Recorder:      recorder,
 		}
 
 		greenplumCluster = exampleGreenplumCluster.DeepCopy()
@@ -148,6 +163,9 @@ var _ = Describe("Reconcile stopcluster.greenplumclusters.pivotal.io finalizer",
 				Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("initiating shutdown of the greenplum cluster")}))
 				Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("success shutting down the greenplum cluster")}))
 			})
+			It("emits a Stopped event", func() {
+				Expect(receivedEvents(recorder)).To(ContainElement("Normal Stopped gpstop on master-0 succeeded"))
+			})
 			It("sets greenplumcluster status to `Deleting`", func() {
 				var reconciledCluster greenplumv1.GreenplumCluster
 				Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
@@ -161,13 +179,18 @@ var _ = Describe("Reconcile stopcluster.greenplumclusters.pivotal.io finalizer",
 					logs, err := DecodeLogs(bytes.NewReader(logBuf.Contents()))
 					Expect(err).NotTo(HaveOccurred())
 					Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("initiating shutdown of the greenplum cluster")}))
-					Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("greenplum cluster did not shutdown cleanly. Please check gpAdminLogs for more info.")}))
+					Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("greenplum cluster did not shutdown cleanly. Please check gpAdminLogs for more info."),
+						"stderr": Equal("failed to run gpstop")}))
+				})
+				It("emits a StopFailed event", func() {
+					Expect(receivedEvents(recorder)).To(ContainElement(
+						"Warning StopFailed gpstop on master-0 failed: failed to run gpstop. Check gpAdminLogs for more info"))
 				})
 			})
 		})
 		When("master-1 is active master", func() {
 			BeforeEach(func() {
-				podExec.ErrorMsgOnMaster0 = "not active"
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
 			})
 			It("runs gpstop on master-1", func() {
 				Expect(podExec.CalledPodName).To(Equal("master-1"))
@@ -176,8 +199,8 @@ var _ = Describe("Reconcile stopcluster.greenplumclusters.pivotal.io finalizer",
 		})
 		When("there is no active master", func() {
 			BeforeEach(func() {
-				podExec.ErrorMsgOnMaster0 = "not active"
-				podExec.ErrorMsgOnMaster1 = "not active"
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
+				fakeQuerier.ErrorMsgOnMaster1 = "not active"
 			})
 			It("does not run gpstop", func() {
 				Expect(podExec.RecordedCommands).NotTo(ContainElement(gpstopCommand))
@@ -216,6 +239,201 @@ var _ = Describe("Reconcile stopcluster.greenplumclusters.pivotal.io finalizer",
 				Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("attempted to remove finalizer, but GreenplumCluster was not found")}))
 			})
 		})
+		When("nodes were labeled for antiAffinity by a previous-version operator", func() {
+			var (
+				masterLabel  = "greenplum-affinity-" + namespaceName + "-master"
+				segmentLabel = "greenplum-affinity-" + namespaceName + "-segment"
+			)
+			BeforeEach(func() {
+				Expect(reactiveClient.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   "node1",
+					Labels: map[string]string{"worker": "gp", masterLabel: "true", segmentLabel: "a"},
+				}})).To(Succeed())
+				Expect(reactiveClient.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   "node2",
+					Labels: map[string]string{"greenplum-affinity-other-ns-segment": "b", segmentLabel: "b"},
+				}})).To(Succeed())
+			})
+			It("removes the labels of this namespace", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				var node corev1.Node
+				Expect(reactiveClient.Get(ctx, types.NamespacedName{Name: "node1"}, &node)).To(Succeed())
+				Expect(node.Labels).To(Equal(map[string]string{"worker": "gp"}))
+				Expect(reactiveClient.Get(ctx, types.NamespacedName{Name: "node2"}, &node)).To(Succeed())
+				Expect(node.Labels).To(Equal(map[string]string{"greenplum-affinity-other-ns-segment": "b"}))
+			})
+			When("removing the labels fails", func() {
+				BeforeEach(func() {
+					reactiveClient.PrependReactor("patch", "nodes", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+						return true, nil, errors.New("injected error")
+					})
+				})
+				It("keeps the finalizer, to try again", func() {
+					Expect(reconcileErr).To(MatchError("failed to remove antiAffinity labels from node 'node1': injected error"))
+					Expect(receivedEvents(recorder)).To(ContainElement(
+						"Warning AntiAffinityFailed failed to remove antiAffinity labels from node 'node1': injected error"))
+					var reconciledCluster greenplumv1.GreenplumCluster
+					Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+					Expect(reconciledCluster.Finalizers).To(ContainElement(greenplumcluster.StopClusterFinalizer))
+				})
+			})
+			When("the operator is not allowed to list nodes", func() {
+				BeforeEach(func() {
+					reactiveClient.PrependReactor("list", "nodes", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+						return true, nil, apierrs.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", errors.New("injected error"))
+					})
+				})
+				It("leaves the labels and removes the finalizer", func() {
+					Expect(reconcileErr).NotTo(HaveOccurred())
+					var node corev1.Node
+					Expect(reactiveClient.Get(ctx, types.NamespacedName{Name: "node1"}, &node)).To(Succeed())
+					Expect(node.Labels).To(HaveKey(masterLabel))
+					var reconciledCluster greenplumv1.GreenplumCluster
+					Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+					Expect(reconciledCluster.Finalizers).NotTo(ContainElement(greenplumcluster.StopClusterFinalizer))
+				})
+				It("logs that it skipped the labels", func() {
+					logs, err := DecodeLogs(bytes.NewReader(logBuf.Contents()))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("skipping removal of legacy antiAffinity node labels")}))
+				})
+			})
+		})
+		When("the cluster has PVCs", func() {
+			pvcKey := func(name string) types.NamespacedName {
+				return types.NamespacedName{Namespace: namespaceName, Name: name}
+			}
+			BeforeEach(func() {
+				greenplumCluster.Spec.Segments.PrimarySegmentCount = 2
+				greenplumCluster.Spec.Segments.Mirrors = "yes"
+				Expect(reactiveClient.Create(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "my-greenplum-pgdata-master-0",
+					Labels:    map[string]string{"app": "greenplum", "type": "master", "greenplum-cluster": clusterName},
+				}})).To(Succeed())
+				Expect(reactiveClient.Create(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "my-greenplum-pgdata-segment-a-0",
+					Labels: map[string]string{"app": "greenplum", "type": "segment-a", "greenplum-cluster": clusterName,
+						"greenplum-major-version": "6"},
+				}})).To(Succeed())
+				Expect(reactiveClient.Create(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "unrelated",
+				}})).To(Succeed())
+			})
+			When("persistentVolumeClaimRetentionPolicy is Retain", func() {
+				BeforeEach(func() {
+					greenplumCluster.Spec.PersistentVolumeClaimRetentionPolicy = greenplumv1.GreenplumPVCRetentionPolicyRetain
+				})
+				It("labels the PVCs with the shape of the cluster", func() {
+					Expect(reconcileErr).NotTo(HaveOccurred())
+					var pvc corev1.PersistentVolumeClaim
+					for _, name := range []string{"my-greenplum-pgdata-master-0", "my-greenplum-pgdata-segment-a-0"} {
+						Expect(reactiveClient.Get(ctx, pvcKey(name), &pvc)).To(Succeed())
+						Expect(pvc.Labels).To(HaveKeyWithValue("greenplum-primary-segment-count", "2"))
+						Expect(pvc.Labels).To(HaveKeyWithValue("greenplum-mirrors", "yes"))
+						Expect(pvc.Labels).To(HaveKeyWithValue("greenplum-standby", "yes"))
+						Expect(pvc.Labels).To(HaveKeyWithValue("greenplum-major-version", "6"))
+					}
+					Expect(reactiveClient.Get(ctx, pvcKey("unrelated"), &pvc)).To(Succeed())
+					Expect(pvc.Labels).To(BeEmpty())
+				})
+				It("emits a RetainedPVCs event", func() {
+					Expect(receivedEvents(recorder)).To(ContainElement("Normal RetainedPVCs Retained 2 PVCs"))
+				})
+				When("labeling a PVC fails", func() {
+					BeforeEach(func() {
+						reactiveClient.PrependReactor("patch", "persistentvolumeclaims", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+							return true, nil, errors.New("injected error")
+						})
+					})
+					It("keeps the finalizer, to try again", func() {
+						Expect(reconcileErr).To(MatchError(ContainSubstring("unable to label PVC my-greenplum-pgdata-")))
+						var reconciledCluster greenplumv1.GreenplumCluster
+						Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+						Expect(reconciledCluster.Finalizers).To(ContainElement(greenplumcluster.StopClusterFinalizer))
+					})
+				})
+				When("gpstop fails", func() {
+					BeforeEach(func() {
+						podExec.ErrorMsgOnCommand = "failed to run gpstop"
+					})
+					It("does not label the PVCs, and keeps the finalizer to stop the cluster again", func() {
+						Expect(reconcileErr).To(MatchError(`unable to stop the cluster before retaining its PVCs (set the greenplum.pivotal.io/retain-without-stop annotation to "true" to retain them anyway): failed to run gpstop`))
+						var pvc corev1.PersistentVolumeClaim
+						Expect(reactiveClient.Get(ctx, pvcKey("my-greenplum-pgdata-master-0"), &pvc)).To(Succeed())
+						Expect(pvc.Labels).NotTo(HaveKey("greenplum-primary-segment-count"))
+						var reconciledCluster greenplumv1.GreenplumCluster
+						Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+						Expect(reconciledCluster.Finalizers).To(ContainElement(greenplumcluster.StopClusterFinalizer))
+					})
+					When("the cluster is annotated to be retained without a stop", func() {
+						BeforeEach(func() {
+							greenplumCluster.Annotations = map[string]string{greenplumv1.RetainWithoutStopAnnotation: "true"}
+						})
+						It("labels the PVCs and removes the finalizer", func() {
+							Expect(reconcileErr).NotTo(HaveOccurred())
+							var pvc corev1.PersistentVolumeClaim
+							Expect(reactiveClient.Get(ctx, pvcKey("my-greenplum-pgdata-master-0"), &pvc)).To(Succeed())
+							Expect(pvc.Labels).To(HaveKeyWithValue("greenplum-primary-segment-count", "2"))
+							var reconciledCluster greenplumv1.GreenplumCluster
+							Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+							Expect(reconciledCluster.Finalizers).NotTo(ContainElement(greenplumcluster.StopClusterFinalizer))
+						})
+						It("emits a RetainingUnstopped event", func() {
+							Expect(receivedEvents(recorder)).To(ContainElement(
+								"Warning RetainingUnstopped Retaining the PVCs of a cluster that was not stopped cleanly, as requested by the greenplum.pivotal.io/retain-without-stop annotation"))
+						})
+					})
+				})
+			})
+			When("persistentVolumeClaimRetentionPolicy is Delete", func() {
+				BeforeEach(func() {
+					greenplumCluster.Spec.PersistentVolumeClaimRetentionPolicy = greenplumv1.GreenplumPVCRetentionPolicyDelete
+				})
+				It("deletes the PVCs of the cluster after stopping it immediately", func() {
+					Expect(reconcileErr).NotTo(HaveOccurred())
+					Expect(podExec.RecordedCommands).To(ContainElement(gpstopImmediateCommand))
+					var pvcList corev1.PersistentVolumeClaimList
+					Expect(reactiveClient.List(ctx, &pvcList)).To(Succeed())
+					Expect(pvcList.Items).To(ConsistOf(
+						gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+							"ObjectMeta": gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{"Name": Equal("unrelated")}),
+						})))
+				})
+				It("emits a DeletedPVCs event", func() {
+					Expect(receivedEvents(recorder)).To(ContainElement("Normal DeletedPVCs Deleted 2 PVCs"))
+				})
+				When("deleting a PVC fails", func() {
+					BeforeEach(func() {
+						reactiveClient.PrependReactor("delete", "persistentvolumeclaims", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+							return true, nil, errors.New("injected error")
+						})
+					})
+					It("keeps the finalizer, to try again", func() {
+						Expect(reconcileErr).To(MatchError(ContainSubstring("unable to delete PVC my-greenplum-pgdata-")))
+						var reconciledCluster greenplumv1.GreenplumCluster
+						Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+						Expect(reconciledCluster.Finalizers).To(ContainElement(greenplumcluster.StopClusterFinalizer))
+					})
+				})
+				When("gpstop fails", func() {
+					BeforeEach(func() {
+						podExec.ErrorMsgOnCommand = "failed to run gpstop"
+					})
+					It("still deletes the PVCs and removes the finalizer", func() {
+						Expect(reconcileErr).NotTo(HaveOccurred())
+						var pvcList corev1.PersistentVolumeClaimList
+						Expect(reactiveClient.List(ctx, &pvcList)).To(Succeed())
+						Expect(pvcList.Items).To(HaveLen(1))
+						var reconciledCluster greenplumv1.GreenplumCluster
+						Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+						Expect(reconciledCluster.Finalizers).NotTo(ContainElement(greenplumcluster.StopClusterFinalizer))
+					})
+				})
+			})
+		})
 		When("the cluster was created by a previous-version operator", func() {
 			BeforeEach(func() {
 				greenplumCluster.Status.InstanceImage = "old-image"
@@ -0,0 +1,105 @@
+package greenplumcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/net"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileGpadminPassword makes the password of gpadmin match the gpadmin-password Secret.
+// The password file of every pod is updated before the role, so that clients in the pods
+// keep working throughout a rotation. The applied password is recorded on the Secret, so that
+// nothing is run in the pods until the password in the Secret changes.
+func (r *GreenplumClusterReconciler) reconcileGpadminPassword(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) error {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: gpadminsecret.SecretName}
+	if err := r.Get(ctx, secretKey, &secret); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	password := string(secret.Data[pgpass.PasswordKey])
+	if password == "" {
+		return nil
+	}
+
+	digest := gpadminsecret.PasswordDigest(password)
+	if secret.Annotations[gpadminsecret.AppliedPasswordAnnotation] == digest {
+		return nil
+	}
+
+	// A cluster that was created before the password was recorded may have it already
+	passwordHash := executor.MD5Password("gpadmin", password)
+	currentHash, err := executor.Query(ctx, r.PodExec, greenplumCluster.Namespace, activeMaster, "gpadmin",
+		"SELECT coalesce(rolpassword, '') FROM pg_authid WHERE rolname = 'gpadmin'")
+	if err != nil {
+		return err
+	}
+	if currentHash == passwordHash {
+		return r.recordAppliedPassword(ctx, &secret, digest)
+	}
+
+	entry := pgpass.Entry("gpadmin", password)
+	hosts := net.GenerateHostList(
+		int(greenplumCluster.Spec.Segments.PrimarySegmentCount),
+		greenplumCluster.Spec.Segments.Mirrors == "yes",
+		greenplumCluster.Spec.MasterAndStandby.Standby == "yes",
+		"")
+	for _, host := range hosts {
+		if err := r.writePgpass(ctx, greenplumCluster.Namespace, host, entry); err != nil {
+			return fmt.Errorf("failed to write %s on %s: %w", pgpass.Path, host, err)
+		}
+	}
+
+	if _, err := executor.Query(ctx, r.PodExec, greenplumCluster.Namespace, activeMaster, "gpadmin",
+		"ALTER ROLE gpadmin PASSWORD '"+passwordHash+"'"); err != nil {
+		return err
+	}
+	r.Log.Info("updated gpadmin password", "greenplumcluster", types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: greenplumCluster.Name})
+	return r.recordAppliedPassword(ctx, &secret, digest)
+}
+
+func (r *GreenplumClusterReconciler) recordAppliedPassword(ctx context.Context, secret *corev1.Secret, digest string) error {
+	original := secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[gpadminsecret.AppliedPasswordAnnotation] = digest
+	if err := r.Patch(ctx, secret, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("failed to record the applied password on secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+// writePgpassTimeout bounds writing the password file of one pod
+const writePgpassTimeout = 30 * time.Second
+
+func (r *GreenplumClusterReconciler) writePgpass(ctx context.Context, namespace, podName, entry string) error {
+	writePgpassCommand := []string{
+		"/bin/bash",
+		"-c",
+		"--",
+		`umask 077 && printf "%s" "$1" > "$2.new" && mv "$2.new" "$2"`,
+		"pgpass",
+		entry,
+		pgpass.Path,
+	}
+	err := r.PodExec.Execute(ctx, executor.ExecRequest{
+		Namespace: namespace,
+		PodName:   podName,
+		Command:   writePgpassCommand,
+		Timeout:   writePgpassTimeout,
+	})
+	return executor.WithStderr(err)
+}
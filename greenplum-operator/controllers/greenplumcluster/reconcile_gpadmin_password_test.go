@@ -0,0 +1,191 @@
+package greenplumcluster_test
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Reconcile gpadmin password", func() {
+	var (
+		ctx                 context.Context
+		logBuf              *gbytes.Buffer
+		podExec             *fake.PodExec
+		fakeQuerier         *fakequerier.Querier
+		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
+		greenplumCluster    *greenplumv1.GreenplumCluster
+
+		gpadminSecretKey = types.NamespacedName{Namespace: namespaceName, Name: gpadminsecret.SecretName}
+	)
+
+	getSecret := func() *corev1.Secret {
+		var secret corev1.Secret
+		Expect(reactiveClient.Get(ctx, gpadminSecretKey, &secret)).To(Succeed())
+		return &secret
+	}
+	getPassword := func() string {
+		return string(getSecret().Data["password"])
+	}
+
+	commandsContaining := func(substr string) []string {
+		var commands []string
+		for _, command := range podExec.RecordedCommands {
+			if strings.Contains(command, substr) {
+				commands = append(commands, command)
+			}
+		}
+		return commands
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		podExec = &fake.PodExec{}
+		fakeQuerier = &fakequerier.Querier{}
+		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
+			Client:        reactiveClient,
+			Log:           gplog.ForTest(logBuf),
+			SSHCreator:    fakeSecretCreator{},
+			InstanceImage: "greenplum-for-kubernetes:latest",
+			OperatorImage: "greenplum-operator:latest",
+			PodExec:       podExec,
+			Querier:       fakeQuerier,
+			Recorder:      record.NewFakeRecorder(100),
+		}
+		greenplumCluster = exampleGreenplumCluster.DeepCopy()
+		greenplumCluster.Spec.MasterAndStandby.Standby = "yes"
+		greenplumCluster.Spec.Segments.Mirrors = "yes"
+	})
+
+	var reconcileErr error
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, greenplumCluster)).To(Succeed())
+		_, reconcileErr = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+	})
+
+	It("creates a gpadmin-password Secret owned by the cluster", func() {
+		Expect(reconcileErr).NotTo(HaveOccurred())
+		var secret corev1.Secret
+		Expect(reactiveClient.Get(ctx, gpadminSecretKey, &secret)).To(Succeed())
+		Expect(secret.Data["password"]).To(HaveLen(gpadminsecret.PasswordLength))
+		Expect(secret.Labels).To(HaveKeyWithValue("greenplum-cluster", clusterName))
+		Expect(secret.GetOwnerReferences()).To(ConsistOf(beOwnedByGreenplum))
+	})
+
+	It("writes the password file on every pod, then sets the password of gpadmin", func() {
+		Expect(reconcileErr).NotTo(HaveOccurred())
+		password := getPassword()
+		writes := commandsContaining("/home/gpadmin/.pgpass")
+		Expect(writes).To(HaveLen(4))
+		for _, write := range writes {
+			Expect(write).To(ContainSubstring("*:*:*:gpadmin:" + password))
+		}
+		last := podExec.RecordedCommands[len(podExec.RecordedCommands)-1]
+		Expect(last).To(HaveSuffix("ALTER ROLE gpadmin PASSWORD '" + executor.MD5Password("gpadmin", password) + "'"))
+		Expect(logBuf).To(gbytes.Say("updated gpadmin password"))
+		Expect(string(logBuf.Contents())).NotTo(ContainSubstring(password))
+	})
+
+	It("records the applied password on the Secret", func() {
+		Expect(reconcileErr).NotTo(HaveOccurred())
+		Expect(getSecret().Annotations).To(HaveKeyWithValue(gpadminsecret.AppliedPasswordAnnotation, gpadminsecret.PasswordDigest(getPassword())))
+	})
+
+	When("the password in the Secret was applied", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: gpadminsecret.SecretName,
+					Annotations: map[string]string{gpadminsecret.AppliedPasswordAnnotation: gpadminsecret.PasswordDigest("chosen-by-user")}},
+				Data: map[string][]byte{"password": []byte("chosen-by-user")},
+			})).To(Succeed())
+		})
+		It("does not run anything in the pods", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(commandsContaining("gpadmin")).To(BeEmpty())
+		})
+
+		When("rotation is requested", func() {
+			BeforeEach(func() {
+				greenplumCluster.Annotations = map[string]string{gpadminsecret.RotateAnnotation: "1"}
+			})
+			It("applies the new password and records it", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				password := getPassword()
+				Expect(password).NotTo(Equal("chosen-by-user"))
+				Expect(commandsContaining("ALTER ROLE")).To(ConsistOf(
+					HaveSuffix("ALTER ROLE gpadmin PASSWORD '" + executor.MD5Password("gpadmin", password) + "'")))
+				Expect(getSecret().Annotations).To(HaveKeyWithValue(gpadminsecret.AppliedPasswordAnnotation, gpadminsecret.PasswordDigest(password)))
+			})
+		})
+	})
+
+	When("gpadmin already has the password", func() {
+		BeforeEach(func() {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: gpadminsecret.SecretName},
+				Data:       map[string][]byte{"password": []byte("chosen-by-user")},
+			}
+			Expect(reactiveClient.Create(ctx, secret)).To(Succeed())
+			podExec.CommandStdout = map[string]string{
+				"FROM pg_authid": executor.MD5Password("gpadmin", "chosen-by-user") + "\n",
+			}
+		})
+		It("keeps the password and only records it", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(getPassword()).To(Equal("chosen-by-user"))
+			Expect(commandsContaining(".pgpass")).To(BeEmpty())
+			Expect(commandsContaining("ALTER ROLE")).To(BeEmpty())
+			Expect(getSecret().Annotations).To(HaveKeyWithValue(gpadminsecret.AppliedPasswordAnnotation, gpadminsecret.PasswordDigest("chosen-by-user")))
+		})
+
+		When("rotation is requested", func() {
+			BeforeEach(func() {
+				greenplumCluster.Annotations = map[string]string{gpadminsecret.RotateAnnotation: "1"}
+			})
+			It("applies a new password", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				password := getPassword()
+				Expect(password).NotTo(Equal("chosen-by-user"))
+				Expect(commandsContaining("ALTER ROLE")).To(ConsistOf(
+					HaveSuffix("ALTER ROLE gpadmin PASSWORD '" + executor.MD5Password("gpadmin", password) + "'")))
+			})
+		})
+	})
+
+	When("writing the password file fails", func() {
+		BeforeEach(func() {
+			podExec.CommandErrors = map[string]string{".pgpass": "No space left on device"}
+		})
+		It("does not change the password of gpadmin", func() {
+			Expect(reconcileErr).To(MatchError(
+				"unable to set gpadmin password: failed to write /home/gpadmin/.pgpass on segment-a-0: No space left on device: No space left on device"))
+			Expect(commandsContaining("ALTER ROLE")).To(BeEmpty())
+			Expect(getSecret().Annotations).NotTo(HaveKey(gpadminsecret.AppliedPasswordAnnotation))
+		})
+	})
+
+	When("there is no active master", func() {
+		BeforeEach(func() {
+			fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			fakeQuerier.ErrorMsgOnMaster1 = "not active"
+		})
+		It("waits to set the password", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(podExec.RecordedCommands).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,185 @@
+package greenplumcluster
+
+import (
+	"context"
+	"fmt"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpstopjob"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterOperation is a gpstop run on the active master, requested with an annotation.
+// gpstop runs in a Job, so that a long restart neither blocks the reconcile loop nor is lost when the operator restarts.
+type clusterOperation struct {
+	annotation string
+	// Name of the operation in the names of its Jobs
+	name       string
+	gpstopArgs string
+	// Prefix of the event reasons: <reason>Refused, <reason>Started, <reason>Failed and <reason>Succeeded
+	reason string
+	// Whether the operation must wait for a running gpexpand to finish
+	conflictsWithExpansion bool
+	status                 func(*greenplumv1.GreenplumClusterStatus) **greenplumv1.GreenplumOperationStatus
+}
+
+var clusterOperations = []clusterOperation{
+	{
+		annotation:             greenplumv1.RestartAnnotation,
+		name:                   "restart",
+		gpstopArgs:             "-a -r -M fast",
+		reason:                 "Restart",
+		conflictsWithExpansion: true,
+		status: func(s *greenplumv1.GreenplumClusterStatus) **greenplumv1.GreenplumOperationStatus {
+			return &s.Restart
+		},
+	},
+	{
+		annotation: greenplumv1.ReloadAnnotation,
+		name:       "reload",
+		gpstopArgs: "-a -u",
+		reason:     "Reload",
+		status: func(s *greenplumv1.GreenplumClusterStatus) **greenplumv1.GreenplumOperationStatus {
+			return &s.Reload
+		},
+	},
+}
+
+// RunningOperation describes the gpexpand Job or gpstop operation that is running on greenplumCluster,
+// or returns "" if none is. The cluster must not be stopped by anything else while one runs.
+func RunningOperation(ctx context.Context, c client.Reader, greenplumCluster *greenplumv1.GreenplumCluster) (string, error) {
+	for _, operation := range clusterOperations {
+		status := *operation.status(&greenplumCluster.Status)
+		if status != nil && status.Phase == greenplumv1.GreenplumOperationPhaseRunning {
+			return fmt.Sprintf("gpstop %s Job %s is running", operation.name, status.JobName), nil
+		}
+	}
+	expanding, err := expansionInProgress(ctx, c, greenplumCluster)
+	if err != nil {
+		return "", fmt.Errorf("unable to check for a gpexpand Job: %w", err)
+	}
+	if expanding {
+		return fmt.Sprintf("gpexpand Job %s is running", gpexpandJobKey(greenplumCluster).Name), nil
+	}
+	return "", nil
+}
+
+// handleOperations starts a Job for each operation whose annotation has a value that is not recorded in the status yet,
+// and records the results of the Jobs that finished. Only one gpstop runs at a time: it returns whether one is running.
+func (r *GreenplumClusterReconciler) handleOperations(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) (bool, error) {
+	for _, operation := range clusterOperations {
+		running, err := r.handleOperation(ctx, greenplumCluster, activeMaster, operation)
+		if err != nil || running {
+			return running, err
+		}
+	}
+	return false, nil
+}
+
+func (r *GreenplumClusterReconciler) handleOperation(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string, operation clusterOperation) (bool, error) {
+	status := *operation.status(&greenplumCluster.Status)
+	if status != nil && status.Phase == greenplumv1.GreenplumOperationPhaseRunning {
+		running, err := r.updateOperation(ctx, greenplumCluster, operation, status)
+		if err != nil || running {
+			return running, err
+		}
+		status = *operation.status(&greenplumCluster.Status)
+	}
+
+	requestedAt := greenplumCluster.Annotations[operation.annotation]
+	if requestedAt == "" || (status != nil && status.RequestedAt == requestedAt) {
+		return false, nil
+	}
+
+	if operation.conflictsWithExpansion {
+		expanding, err := r.expansionInProgress(ctx, greenplumCluster)
+		if err != nil {
+			return false, err
+		}
+		if expanding {
+			message := fmt.Sprintf("gpexpand Job %s is running", gpexpandJobKey(greenplumCluster).Name)
+			r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, operation.reason+"Refused",
+				"Not running gpstop %s: %s", operation.gpstopArgs, message)
+			return false, r.recordOperation(ctx, greenplumCluster, operation, requestedAt, "", greenplumv1.GreenplumOperationPhaseRefused, message)
+		}
+	}
+
+	// Only the Job of the last request is kept
+	if status != nil && status.JobName != "" {
+		oldJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: greenplumCluster.Namespace, Name: status.JobName}}
+		if err := r.Delete(ctx, oldJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrs.IsNotFound(err) {
+			return false, fmt.Errorf("unable to delete Job %s: %w", status.JobName, err)
+		}
+	}
+
+	activeMasterFQDN := fmt.Sprintf("%s.agent.%s.svc.cluster.local", activeMaster, greenplumCluster.Namespace)
+	job := gpstopjob.GenerateJob(r.InstanceImage, activeMasterFQDN, operation.gpstopArgs)
+	job.Namespace = greenplumCluster.Namespace
+	job.Name = gpstopjob.JobName(greenplumCluster.Name, operation.name, requestedAt)
+	job.Labels = map[string]string{"greenplum-cluster": greenplumCluster.Name}
+	if err := ctrl.SetControllerReference(greenplumCluster, &job, r.Scheme()); err != nil {
+		// not tested: not really possible to fail here
+		return false, err
+	}
+	if err := r.Create(ctx, &job); err != nil && !apierrs.IsAlreadyExists(err) {
+		return false, fmt.Errorf("unable to create Job %s: %w", job.Name, err)
+	}
+	r.Log.Info("started gpstop", "args", operation.gpstopArgs, "activeMaster", activeMaster, "job", job.Name)
+	r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, operation.reason+"Started",
+		"Created Job %s to run gpstop %s on %s", job.Name, operation.gpstopArgs, activeMaster)
+	return true, r.recordOperation(ctx, greenplumCluster, operation, requestedAt, job.Name, greenplumv1.GreenplumOperationPhaseRunning, "")
+}
+
+// updateOperation records the result of the Job of a running operation once it has finished,
+// and returns whether it is still running
+func (r *GreenplumClusterReconciler) updateOperation(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, operation clusterOperation, status *greenplumv1.GreenplumOperationStatus) (bool, error) {
+	var job batchv1.Job
+	err := r.Get(ctx, types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: status.JobName}, &job)
+	if apierrs.IsNotFound(err) {
+		message := fmt.Sprintf("gpstop Job %s was deleted before it finished", status.JobName)
+		r.Recorder.Event(greenplumCluster, corev1.EventTypeWarning, operation.reason+"Failed", message)
+		return false, r.recordOperation(ctx, greenplumCluster, operation, status.RequestedAt, status.JobName, greenplumv1.GreenplumOperationPhaseFailed, message)
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to fetch Job %s: %w", status.JobName, err)
+	}
+
+	switch {
+	case job.Status.Failed > 0:
+		message := fmt.Sprintf("gpstop Job %s failed. Check its logs and gpAdminLogs on the master", job.Name)
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, operation.reason+"Failed",
+			"gpstop %s failed. Check the logs of Job %s and gpAdminLogs on the master", operation.gpstopArgs, job.Name)
+		return false, r.recordOperation(ctx, greenplumCluster, operation, status.RequestedAt, job.Name, greenplumv1.GreenplumOperationPhaseFailed, message)
+	case job.Status.Succeeded > 0:
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, operation.reason+"Succeeded",
+			"gpstop %s succeeded", operation.gpstopArgs)
+		return false, r.recordOperation(ctx, greenplumCluster, operation, status.RequestedAt, job.Name, greenplumv1.GreenplumOperationPhaseSucceeded, "")
+	default:
+		return true, nil
+	}
+}
+
+func (r *GreenplumClusterReconciler) recordOperation(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, operation clusterOperation, requestedAt, jobName string, phase greenplumv1.GreenplumOperationPhase, message string) error {
+	originalGreenplumCluster := greenplumCluster.DeepCopy()
+	status := &greenplumv1.GreenplumOperationStatus{
+		RequestedAt: requestedAt,
+		Phase:       phase,
+		Message:     message,
+		JobName:     jobName,
+	}
+	if phase != greenplumv1.GreenplumOperationPhaseRunning {
+		now := metav1.Now()
+		status.CompletedAt = &now
+	}
+	*operation.status(&greenplumCluster.Status) = status
+	if err := r.Patch(ctx, greenplumCluster, client.MergeFrom(originalGreenplumCluster)); err != nil {
+		return fmt.Errorf("updating %s status: %w", operation.annotation, err)
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package greenplumcluster_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpstopjob"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Reconcile restart and reload operations", func() {
+	var (
+		ctx                 context.Context
+		fakeQuerier         *fakequerier.Querier
+		recorder            *record.FakeRecorder
+		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
+		greenplumCluster    *greenplumv1.GreenplumCluster
+	)
+
+	restartJob := gpstopjob.JobName(clusterName, "restart", "2020-06-01T12:00:00Z")
+	reloadJob := gpstopjob.JobName(clusterName, "reload", "2020-06-01T12:00:00Z")
+
+	gpstopJobs := func() []string {
+		var jobs batchv1.JobList
+		Expect(reactiveClient.List(ctx, &jobs, client.InNamespace(namespaceName))).To(Succeed())
+		var names []string
+		for _, job := range jobs.Items {
+			if containers := job.Spec.Template.Spec.Containers; len(containers) > 0 && containers[0].Name == "gpstop" {
+				names = append(names, job.Name)
+			}
+		}
+		return names
+	}
+
+	getJob := func(name string) *batchv1.Job {
+		var job batchv1.Job
+		Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: name}, &job)).To(Succeed())
+		return &job
+	}
+
+	finishJob := func(name string, jobStatus batchv1.JobStatus) {
+		job := getJob(name)
+		job.Status = jobStatus
+		Expect(reactiveClient.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	getGreenplumCluster := func() *greenplumv1.GreenplumCluster {
+		var gc greenplumv1.GreenplumCluster
+		Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &gc)).To(Succeed())
+		return &gc
+	}
+
+	annotate := func(annotation, value string) {
+		gc := getGreenplumCluster()
+		if gc.Annotations == nil {
+			gc.Annotations = map[string]string{}
+		}
+		gc.Annotations[annotation] = value
+		Expect(reactiveClient.Update(ctx, gc)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeQuerier = &fakequerier.Querier{}
+		recorder = record.NewFakeRecorder(100)
+		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
+			Client:        reactiveClient,
+			Log:           gplog.ForTest(gbytes.NewBuffer()),
+			SSHCreator:    fakeSecretCreator{},
+			InstanceImage: "greenplum-for-kubernetes:latest",
+			OperatorImage: "greenplum-operator:latest",
+			PodExec:       &fake.PodExec{},
+			Querier:       fakeQuerier,
+			Recorder:      recorder,
+		}
+		greenplumCluster = exampleGreenplumCluster.DeepCopy()
+		greenplumCluster.Annotations = map[string]string{}
+	})
+
+	var reconcileErr error
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, greenplumCluster)).To(Succeed())
+		_, reconcileErr = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+		Expect(reconcileErr).NotTo(HaveOccurred())
+		receivedEvents(recorder)
+	})
+
+	reconcile := func() {
+		_, reconcileErr = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+	}
+
+	It("does not run gpstop without a request", func() {
+		Expect(gpstopJobs()).To(BeEmpty())
+		Expect(getGreenplumCluster().Status.Restart).To(BeNil())
+		Expect(getGreenplumCluster().Status.Reload).To(BeNil())
+	})
+
+	When("a restart is requested", func() {
+		JustBeforeEach(func() {
+			annotate(greenplumv1.RestartAnnotation, "2020-06-01T12:00:00Z")
+			reconcile()
+		})
+
+		It("starts a Job that restarts the cluster on the active master", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(gpstopJobs()).To(ConsistOf(restartJob))
+			job := getJob(restartJob)
+			Expect(job.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"-d", "/greenplum/data-1", "-a", "-r", "-M", "fast"}))
+			Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+				HaveField("Value", "master-0.agent.test-ns.svc.cluster.local")))
+			Expect(metav1.IsControlledBy(job, getGreenplumCluster())).To(BeTrue())
+			Expect(receivedEvents(recorder)).To(ContainElement(
+				"Normal RestartStarted Created Job " + restartJob + " to run gpstop -a -r -M fast on master-0"))
+		})
+
+		It("records the running restart in the status", func() {
+			restart := getGreenplumCluster().Status.Restart
+			Expect(restart).NotTo(BeNil())
+			Expect(restart.RequestedAt).To(Equal("2020-06-01T12:00:00Z"))
+			Expect(restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseRunning))
+			Expect(restart.JobName).To(Equal(restartJob))
+			Expect(restart.CompletedAt).To(BeNil())
+		})
+
+		It("does not start another Job while it runs", func() {
+			reconcile()
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(gpstopJobs()).To(HaveLen(1))
+			Expect(getGreenplumCluster().Status.Restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseRunning))
+		})
+
+		When("the Job succeeds", func() {
+			JustBeforeEach(func() {
+				finishJob(restartJob, batchv1.JobStatus{Succeeded: 1})
+				receivedEvents(recorder)
+				reconcile()
+			})
+
+			It("records the completed restart in the status", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				restart := getGreenplumCluster().Status.Restart
+				Expect(restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseSucceeded))
+				Expect(restart.CompletedAt).NotTo(BeNil())
+				Expect(restart.Message).To(BeEmpty())
+				Expect(restart.JobName).To(Equal(restartJob))
+				Expect(receivedEvents(recorder)).To(ContainElement("Normal RestartSucceeded gpstop -a -r -M fast succeeded"))
+			})
+
+			It("does not restart again for the same request", func() {
+				receivedEvents(recorder)
+				reconcile()
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(gpstopJobs()).To(ConsistOf(restartJob))
+				Expect(receivedEvents(recorder)).To(BeEmpty())
+			})
+
+			It("restarts again for a new request, and deletes the Job of the previous one", func() {
+				annotate(greenplumv1.RestartAnnotation, "2020-06-02T12:00:00Z")
+				reconcile()
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				newJob := gpstopjob.JobName(clusterName, "restart", "2020-06-02T12:00:00Z")
+				Expect(gpstopJobs()).To(ConsistOf(newJob))
+				restart := getGreenplumCluster().Status.Restart
+				Expect(restart.RequestedAt).To(Equal("2020-06-02T12:00:00Z"))
+				Expect(restart.JobName).To(Equal(newJob))
+			})
+		})
+
+		When("the Job fails", func() {
+			JustBeforeEach(func() {
+				finishJob(restartJob, batchv1.JobStatus{Failed: 1})
+				receivedEvents(recorder)
+				reconcile()
+			})
+			It("records the failure once, without retrying", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				restart := getGreenplumCluster().Status.Restart
+				Expect(restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseFailed))
+				Expect(restart.Message).To(Equal("gpstop Job " + restartJob + " failed. Check its logs and gpAdminLogs on the master"))
+				Expect(restart.CompletedAt).NotTo(BeNil())
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Warning RestartFailed gpstop -a -r -M fast failed. Check the logs of Job " + restartJob + " and gpAdminLogs on the master"))
+
+				reconcile()
+				Expect(gpstopJobs()).To(ConsistOf(restartJob))
+				Expect(receivedEvents(recorder)).To(BeEmpty())
+			})
+		})
+
+		When("the Job is deleted before it finishes", func() {
+			JustBeforeEach(func() {
+				Expect(reactiveClient.Delete(ctx, getJob(restartJob))).To(Succeed())
+				reconcile()
+			})
+			It("records the restart as failed", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				restart := getGreenplumCluster().Status.Restart
+				Expect(restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseFailed))
+				Expect(restart.Message).To(Equal("gpstop Job " + restartJob + " was deleted before it finished"))
+			})
+		})
+
+		When("a reload is requested while the restart runs", func() {
+			JustBeforeEach(func() {
+				annotate(greenplumv1.ReloadAnnotation, "2020-06-01T12:00:00Z")
+				reconcile()
+			})
+			It("waits for the restart to finish", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(gpstopJobs()).To(ConsistOf(restartJob))
+				Expect(getGreenplumCluster().Status.Reload).To(BeNil())
+
+				finishJob(restartJob, batchv1.JobStatus{Succeeded: 1})
+				reconcile()
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(gpstopJobs()).To(ConsistOf(restartJob, reloadJob))
+				Expect(getGreenplumCluster().Status.Reload.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseRunning))
+			})
+		})
+
+		When("master-1 is the active master", func() {
+			BeforeEach(func() {
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			})
+			It("restarts the cluster on master-1", func() {
+				Expect(getJob(restartJob).Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+					HaveField("Value", "master-1.agent.test-ns.svc.cluster.local")))
+				Expect(receivedEvents(recorder)).To(ContainElement(HaveSuffix("on master-1")))
+			})
+		})
+
+		When("a gpexpand Job is running", func() {
+			BeforeEach(func() {
+				Expect(reactiveClient.Create(ctx, &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: clusterName + "-gpexpand-job"},
+				})).To(Succeed())
+			})
+			It("refuses the restart", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(gpstopJobs()).To(BeEmpty())
+				restart := getGreenplumCluster().Status.Restart
+				Expect(restart.RequestedAt).To(Equal("2020-06-01T12:00:00Z"))
+				Expect(restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseRefused))
+				Expect(restart.Message).To(Equal("gpexpand Job my-greenplum-gpexpand-job is running"))
+				Expect(receivedEvents(recorder)).To(ContainElement(
+					"Warning RestartRefused Not running gpstop -a -r -M fast: gpexpand Job my-greenplum-gpexpand-job is running"))
+			})
+		})
+
+		When("the gpexpand Job has completed", func() {
+			BeforeEach(func() {
+				Expect(reactiveClient.Create(ctx, &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: clusterName + "-gpexpand-job"},
+					Status:     batchv1.JobStatus{Succeeded: 1},
+				})).To(Succeed())
+			})
+			It("restarts the cluster", func() {
+				Expect(gpstopJobs()).To(ConsistOf(restartJob))
+				Expect(getGreenplumCluster().Status.Restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseRunning))
+			})
+		})
+	})
+
+	When("the operator restarts while a restart is running", func() {
+		BeforeEach(func() {
+			greenplumCluster.Annotations[greenplumv1.RestartAnnotation] = "2020-06-01T12:00:00Z"
+			greenplumCluster.Status.Restart = &greenplumv1.GreenplumOperationStatus{
+				RequestedAt: "2020-06-01T12:00:00Z",
+				Phase:       greenplumv1.GreenplumOperationPhaseRunning,
+				JobName:     restartJob,
+			}
+			Expect(reactiveClient.Create(ctx, &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: restartJob},
+				Status:     batchv1.JobStatus{Succeeded: 1},
+			})).To(Succeed())
+		})
+		It("records the result of the Job", func() {
+			restart := getGreenplumCluster().Status.Restart
+			Expect(restart.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseSucceeded))
+		})
+	})
+
+	When("a reload is requested", func() {
+		JustBeforeEach(func() {
+			annotate(greenplumv1.ReloadAnnotation, "2020-06-01T12:00:00Z")
+			reconcile()
+		})
+
+		It("starts a Job that reloads the configuration files on the active master", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(gpstopJobs()).To(ConsistOf(reloadJob))
+			Expect(getJob(reloadJob).Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"-d", "/greenplum/data-1", "-a", "-u"}))
+			Expect(receivedEvents(recorder)).To(ContainElement(
+				"Normal ReloadStarted Created Job " + reloadJob + " to run gpstop -a -u on master-0"))
+
+			finishJob(reloadJob, batchv1.JobStatus{Succeeded: 1})
+			reconcile()
+			Expect(receivedEvents(recorder)).To(ContainElement("Normal ReloadSucceeded gpstop -a -u succeeded"))
+			reload := getGreenplumCluster().Status.Reload
+			Expect(reload.RequestedAt).To(Equal("2020-06-01T12:00:00Z"))
+			Expect(reload.Phase).To(Equal(greenplumv1.GreenplumOperationPhaseSucceeded))
+			Expect(getGreenplumCluster().Status.Restart).To(BeNil())
+		})
+
+		When("a gpexpand Job is running", func() {
+			BeforeEach(func() {
+				Expect(reactiveClient.Create(ctx, &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: clusterName + "-gpexpand-job"},
+				})).To(Succeed())
+			})
+			It("reloads anyway", func() {
+				Expect(gpstopJobs()).To(ConsistOf(reloadJob))
+			})
+		})
+	})
+})
@@ -0,0 +1,66 @@
+package greenplumcluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Labels on retained PVCs that record the shape of the deleted cluster
+const (
+	PVCPrimarySegmentCountLabel = "greenplum-primary-segment-count"
+	PVCMirrorsLabel             = "greenplum-mirrors"
+	PVCStandbyLabel             = "greenplum-standby"
+	PVCMajorVersionLabel        = "greenplum-major-version"
+)
+
+// handlePVCRetention applies the persistentVolumeClaimRetentionPolicy of a cluster that is being deleted
+func (r *GreenplumClusterReconciler) handlePVCRetention(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster) error {
+	var pvcList corev1.PersistentVolumeClaimList
+	err := r.List(ctx, &pvcList, client.InNamespace(greenplumCluster.Namespace),
+		client.MatchingLabels{"greenplum-cluster": greenplumCluster.Name})
+	if err != nil {
+		return fmt.Errorf("unable to list PVCs: %w", err)
+	}
+
+	if greenplumCluster.Spec.PersistentVolumeClaimRetentionPolicy == greenplumv1.GreenplumPVCRetentionPolicyDelete {
+		for i := range pvcList.Items {
+			pvc := &pvcList.Items[i]
+			if err := r.Delete(ctx, pvc); err != nil && !apierrs.IsNotFound(err) {
+				return fmt.Errorf("unable to delete PVC %s: %w", pvc.Name, err)
+			}
+		}
+		r.Log.Info("deleted PVCs of the greenplum cluster", "count", len(pvcList.Items))
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "DeletedPVCs", "Deleted %d PVCs", len(pvcList.Items))
+		return nil
+	}
+
+	retainedLabels := map[string]string{
+		PVCPrimarySegmentCountLabel: strconv.Itoa(int(greenplumCluster.Spec.Segments.PrimarySegmentCount)),
+		PVCMirrorsLabel:             greenplumCluster.Spec.Segments.Mirrors,
+		PVCStandbyLabel:             greenplumCluster.Spec.MasterAndStandby.Standby,
+	}
+	for _, pvc := range pvcList.Items {
+		labeledPVC := pvc.DeepCopy()
+		if labeledPVC.Labels == nil {
+			labeledPVC.Labels = make(map[string]string)
+		}
+		for key, value := range retainedLabels {
+			labeledPVC.Labels[key] = value
+		}
+		// Normally set by the pod when it first starts on the PVC
+		if _, ok := labeledPVC.Labels[PVCMajorVersionLabel]; !ok {
+			labeledPVC.Labels[PVCMajorVersionLabel] = SupportedGreenplumMajorVersion
+		}
+		if err := r.Patch(ctx, labeledPVC, client.MergeFrom(&pvc)); err != nil {
+			return fmt.Errorf("unable to label PVC %s: %w", pvc.Name, err)
+		}
+	}
+	r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "RetainedPVCs", "Retained %d PVCs", len(pvcList.Items))
+	return nil
+}
@@ -0,0 +1,145 @@
+package greenplumcluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ensureQuerierAccess sets up querier.Role with the password from the gpoperator-password Secret, and adds
+// querier.HBAEntry to pg_hba.conf of the masters, when the operator cannot authenticate to activeMaster.
+// The role is missing on clusters that were initialized before the operator connected over the network,
+// and has the old password after the Secret is changed. The entries are also replaced when the networks
+// that the operator connects from change, for example when an operator pod is replaced.
+func (r *GreenplumClusterReconciler) ensureQuerierAccess(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) error {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: querier.SecretName}
+	if err := r.Get(ctx, secretKey, &secret); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	password := string(secret.Data[pgpass.PasswordKey])
+	if password == "" {
+		return nil
+	}
+
+	networks, err := r.querierNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	master := querier.Master{Namespace: greenplumCluster.Namespace, PodName: activeMaster}
+	err = r.Querier.Ping(ctx, master)
+	authFailed := querier.IsAuthenticationError(err)
+	if err != nil && !authFailed {
+		return nil
+	}
+	if !authFailed && (len(networks) == 0 || secret.Annotations[querier.NetworksAnnotation] == strings.Join(networks, ",")) {
+		return nil
+	}
+	if len(networks) == 0 {
+		return fmt.Errorf("no network to allow the operator from: the operator has no pod address, and --querierNetwork is not set")
+	}
+
+	if authFailed {
+		if err := r.setQuerierRole(ctx, greenplumCluster.Namespace, activeMaster, password); err != nil {
+			return err
+		}
+	}
+	masters := []string{"master-0"}
+	if greenplumCluster.Spec.MasterAndStandby.Standby == "yes" {
+		masters = append(masters, "master-1")
+	}
+	for _, podName := range masters {
+		if err := r.setHBAEntries(ctx, greenplumCluster.Namespace, podName, networks); err != nil {
+			return err
+		}
+	}
+	original := secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[querier.NetworksAnnotation] = strings.Join(networks, ",")
+	if err := r.Patch(ctx, &secret, client.MergeFrom(original)); err != nil {
+		return err
+	}
+	r.Log.Info("set up role and pg_hba.conf entries for the operator", "greenplumcluster", types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: greenplumCluster.Name},
+		"networks", networks)
+
+	if authFailed {
+		if err := r.Querier.Ping(ctx, master); querier.IsAuthenticationError(err) {
+			r.Recorder.Eventf(greenplumCluster, corev1.EventTypeWarning, "QuerierAuthenticationFailed",
+				"%s rejects the operator after setting up its role and pg_hba.conf entries: %s", activeMaster, err)
+			return fmt.Errorf("%s still rejects the operator: %w", activeMaster, err)
+		}
+	}
+	return nil
+}
+
+// querierNetworks returns QuerierNetwork, or the addresses of the operator pods if it is not set
+func (r *GreenplumClusterReconciler) querierNetworks(ctx context.Context) ([]string, error) {
+	if r.QuerierNetwork != "" {
+		return []string{r.QuerierNetwork}, nil
+	}
+	if r.OperatorNamespace == "" {
+		return nil, nil
+	}
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	return querier.OperatorNetworks(ctx, reader, r.OperatorNamespace)
+}
+
+// setQuerierRole creates querier.Role if it does not exist, and sets its password. The role can only log in.
+func (r *GreenplumClusterReconciler) setQuerierRole(ctx context.Context, namespace, activeMaster, password string) error {
+	count, err := executor.Query(ctx, r.PodExec, namespace, activeMaster, "gpadmin",
+		"SELECT count(*) FROM pg_roles WHERE rolname = '"+querier.Role+"'")
+	if err != nil {
+		return err
+	}
+	statement := "ALTER ROLE "
+	if count == "0" {
+		statement = "CREATE ROLE "
+	}
+	_, err = executor.Query(ctx, r.PodExec, namespace, activeMaster, "gpadmin",
+		fmt.Sprintf("%s%s LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE PASSWORD '%s'", statement, querier.Role, executor.MD5Password(querier.Role, password)))
+	return err
+}
+
+// setHBAEntries replaces the entries for querier.Role in pg_hba.conf of podName with a querier.HBAEntry
+// for each of networks, and reloads the configuration if it changed
+func (r *GreenplumClusterReconciler) setHBAEntries(ctx context.Context, namespace, podName string, networks []string) error {
+	setHBAEntriesCommand := []string{
+		"/bin/bash",
+		"-c",
+		"--",
+		`f="$1/pg_hba.conf"; [ -f "$f" ] || exit 0; role=$2; shift 2; ` +
+			`{ grep -vE "^host(ssl)? [^ ]* $role " "$f"; printf '%s\n' "$@"; } > "$f.new" || exit 1; ` +
+			`cmp -s "$f" "$f.new" && { rm -f "$f.new"; exit 0; }; ` +
+			`mv "$f.new" "$f" && source /usr/local/greenplum-db/greenplum_path.sh && pg_ctl reload -D "${f%/*}"`,
+		"hba",
+		"/greenplum/data-1",
+		querier.Role,
+	}
+	for _, network := range networks {
+		setHBAEntriesCommand = append(setHBAEntriesCommand, querier.HBAEntry(network, r.QuerierTLS))
+	}
+	err := r.PodExec.Execute(ctx, executor.ExecRequest{
+		Namespace: namespace,
+		PodName:   podName,
+		Command:   setHBAEntriesCommand,
+		Timeout:   executor.QueryTimeout,
+	})
+	return executor.WithStderr(err)
+}
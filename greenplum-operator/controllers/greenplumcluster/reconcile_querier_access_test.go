@@ -0,0 +1,246 @@
+package greenplumcluster_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Reconcile operator access to the masters", func() {
+	var (
+		ctx                 context.Context
+		logBuf              *gbytes.Buffer
+		podExec             *fake.PodExec
+		fakeQuerier         *fakequerier.Querier
+		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
+		greenplumCluster    *greenplumv1.GreenplumCluster
+	)
+
+	commandsContaining := func(substr string) []string {
+		var commands []string
+		for _, command := range podExec.RecordedCommands {
+			if strings.Contains(command, substr) {
+				commands = append(commands, command)
+			}
+		}
+		return commands
+	}
+	hbaCommands := func() []string {
+		return commandsContaining("pg_hba.conf")
+	}
+
+	querierSecret := func() *corev1.Secret {
+		var secret corev1.Secret
+		Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: querier.SecretName}, &secret)).To(Succeed())
+		return &secret
+	}
+	querierPassword := func() string {
+		return string(querierSecret().Data["password"])
+	}
+	operatorPod := func(name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "greenplum-system", Name: name, Labels: map[string]string{"app": "greenplum-operator"}},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		podExec = &fake.PodExec{CommandStdout: map[string]string{"FROM pg_roles": "0"}}
+		fakeQuerier = &fakequerier.Querier{}
+		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
+			Client:        reactiveClient,
+			Log:           gplog.ForTest(logBuf),
+			SSHCreator:    fakeSecretCreator{},
+			InstanceImage: "greenplum-for-kubernetes:latest",
+			OperatorImage: "greenplum-operator:latest",
+			PodExec:       podExec,
+			Querier:       fakeQuerier,
+			Recorder:      record.NewFakeRecorder(100),
+
+			OperatorNamespace: "greenplum-system",
+		}
+		greenplumCluster = exampleGreenplumCluster.DeepCopy()
+		Expect(reactiveClient.Create(ctx, operatorPod("greenplum-operator-a", "10.244.1.7"))).To(Succeed())
+	})
+
+	var reconcileErr error
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, greenplumCluster)).To(Succeed())
+		_, reconcileErr = greenplumReconciler.Reconcile(ctx, greenplumClusterRequest)
+	})
+
+	When("the operator can connect from the networks of its entries", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: querier.SecretName,
+					Annotations: map[string]string{querier.NetworksAnnotation: "10.244.1.7/32"}},
+				Data: map[string][]byte{"password": []byte("secret")},
+			})).To(Succeed())
+		})
+		It("does not touch the role or pg_hba.conf", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(commandsContaining(querier.Role)).To(BeEmpty())
+		})
+
+		When("an operator pod is added", func() {
+			BeforeEach(func() {
+				Expect(reactiveClient.Create(ctx, operatorPod("greenplum-operator-b", "10.244.2.8"))).To(Succeed())
+			})
+			It("replaces the entries on the masters without changing the role", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(commandsContaining("ROLE gpoperator")).To(BeEmpty())
+				Expect(hbaCommands()).To(HaveLen(1))
+				Expect(hbaCommands()[0]).To(ContainSubstring("host gpadmin,postgres gpoperator 10.244.1.7/32 md5 host gpadmin,postgres gpoperator 10.244.2.8/32 md5"))
+				Expect(querierSecret().Annotations).To(HaveKeyWithValue(querier.NetworksAnnotation, "10.244.1.7/32,10.244.2.8/32"))
+			})
+		})
+	})
+
+	It("generates the password of the operator's role", func() {
+		Expect(reconcileErr).NotTo(HaveOccurred())
+		Expect(querierPassword()).To(HaveLen(gpadminsecret.PasswordLength))
+	})
+
+	When("the active master has no pg_hba.conf entry for the operator", func() {
+		BeforeEach(func() {
+			fakeQuerier.PingErr = &pq.Error{Code: "28000", Message: `no pg_hba.conf entry for host "10.244.1.7", user "gpoperator", database "gpadmin"`}
+			// ActiveMaster and ensureQuerierAccess are rejected; the ping after setting up access succeeds
+			fakeQuerier.PingErrCount = 2
+		})
+
+		It("creates the operator's role with only the privilege to log in", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			hash := executor.MD5Password("gpoperator", querierPassword())
+			Expect(commandsContaining("CREATE ROLE gpoperator LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE PASSWORD '" + hash + "'")).To(HaveLen(1))
+			Expect(commandsContaining(querierPassword())).To(BeEmpty())
+		})
+
+		It("adds the entry for the operator's role on master-0 and reloads its configuration", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(hbaCommands()).To(HaveLen(1))
+			Expect(podExec.CalledPodName).To(Equal("master-0"))
+			Expect(hbaCommands()[0]).To(ContainSubstring("host gpadmin,postgres gpoperator 10.244.1.7/32 md5"))
+			Expect(hbaCommands()[0]).To(ContainSubstring("pg_ctl reload"))
+			Expect(logBuf).To(gbytes.Say("set up role and pg_hba.conf entries for the operator"))
+		})
+
+		It("records the networks of the entries on the Secret", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(querierSecret().Annotations).To(HaveKeyWithValue(querier.NetworksAnnotation, "10.244.1.7/32"))
+		})
+
+		When("the master still rejects the operator", func() {
+			BeforeEach(func() {
+				fakeQuerier.PingErrCount = 0
+			})
+			It("returns an error and records a warning", func() {
+				Expect(reconcileErr).To(MatchError(ContainSubstring("master-0 still rejects the operator: pq: no pg_hba.conf entry")))
+				Expect(receivedEvents(greenplumReconciler.Recorder.(*record.FakeRecorder))).To(ContainElement(
+					HavePrefix("Warning QuerierAuthenticationFailed master-0 rejects the operator after setting up its role and pg_hba.conf entries")))
+			})
+		})
+
+		When("the role exists", func() {
+			BeforeEach(func() {
+				podExec.CommandStdout["FROM pg_roles"] = "1"
+			})
+			It("sets its password", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(commandsContaining("CREATE ROLE")).To(BeEmpty())
+				Expect(commandsContaining("ALTER ROLE gpoperator LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE PASSWORD")).To(HaveLen(1))
+			})
+		})
+
+		When("the operator connects from the pod network", func() {
+			BeforeEach(func() {
+				greenplumReconciler.QuerierNetwork = "10.244.0.0/16"
+			})
+			It("only allows that network", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(hbaCommands()[0]).To(ContainSubstring("host gpadmin,postgres gpoperator 10.244.0.0/16 md5"))
+				Expect(hbaCommands()[0]).NotTo(ContainSubstring("10.244.1.7"))
+			})
+		})
+
+		When("the operator connects over SSL", func() {
+			BeforeEach(func() {
+				greenplumReconciler.QuerierTLS = true
+			})
+			It("only allows connections over SSL", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(hbaCommands()[0]).To(ContainSubstring("hostssl gpadmin,postgres gpoperator 10.244.1.7/32 md5"))
+			})
+		})
+
+		When("the operator runs outside of the cluster without a network", func() {
+			BeforeEach(func() {
+				greenplumReconciler.OperatorNamespace = ""
+			})
+			It("returns an error without changing the role", func() {
+				Expect(reconcileErr).To(MatchError(ContainSubstring("no network to allow the operator from")))
+				Expect(commandsContaining("ROLE gpoperator")).To(BeEmpty())
+			})
+		})
+
+		When("creating the role fails", func() {
+			BeforeEach(func() {
+				podExec.CommandErrors = map[string]string{"CREATE ROLE": "permission denied"}
+			})
+			It("returns an error without adding the entry", func() {
+				Expect(reconcileErr).To(MatchError(ContainSubstring("unable to allow the operator to connect to master-0: permission denied")))
+				Expect(hbaCommands()).To(BeEmpty())
+			})
+		})
+
+		When("the cluster has a standby master", func() {
+			BeforeEach(func() {
+				greenplumCluster.Spec.MasterAndStandby.Standby = "yes"
+			})
+			It("adds the entry on both masters, so it survives a failover", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(hbaCommands()).To(HaveLen(2))
+				Expect(podExec.CalledPodName).To(Equal("master-1"))
+			})
+		})
+
+		When("adding the entry fails", func() {
+			BeforeEach(func() {
+				podExec.CommandErrors = map[string]string{"pg_hba.conf": "Permission denied"}
+			})
+			It("returns an error", func() {
+				Expect(reconcileErr).To(MatchError("unable to allow the operator to connect to master-0: Permission denied: Permission denied"))
+			})
+		})
+	})
+
+	When("the active master cannot be reached", func() {
+		BeforeEach(func() {
+			fakeQuerier.PingErr = errors.New("dial tcp: i/o timeout")
+			fakeQuerier.ErrorMsgOnMaster1 = "dial tcp: i/o timeout"
+			fakeQuerier.ErrorMsgOnMaster0 = ""
+		})
+		It("does not touch the role or pg_hba.conf", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			Expect(commandsContaining(querier.Role)).To(BeEmpty())
+		})
+	})
+})
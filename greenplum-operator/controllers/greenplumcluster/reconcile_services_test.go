@@ -10,6 +10,7 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -17,6 +18,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 var _ = Describe("Reconcile services for GreenplumCluster", func() {
@@ -35,6 +37,8 @@ var _ = Describe("Reconcile services for GreenplumCluster", func() {
 			Log:        gplog.ForTest(logBuf),
 			SSHCreator: fakeSecretCreator{},
 			PodExec:    &fake.PodExec{},
+			Querier:    &fakequerier.Querier{},
+			Recorder:   record.NewFakeRecorder(100),
 		}
 
 		greenplumCluster = exampleGreenplumCluster.DeepCopy()
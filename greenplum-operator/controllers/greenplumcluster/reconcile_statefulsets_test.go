@@ -10,6 +10,7 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -19,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 var _ = Describe("Reconcile statefulsets for GreenplumCluster", func() {
@@ -40,7 +42,9 @@ var _ = Describe("Reconcile statefulsets for GreenplumCluster", func() {
 			Log:           gplog.ForTest(logBuf),
 			SSHCreator:    fakeSecretCreator{},
 			PodExec:       &fake.PodExec{},
+			Querier:       &fakequerier.Querier{},
 			InstanceImage: "greenplum-for-kubernetes:v1.0",
+			Recorder:      record.NewFakeRecorder(100),
 		}
 
 		greenplumCluster = exampleGreenplumCluster.DeepCopy()
@@ -5,17 +5,30 @@ import (
 	"fmt"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func (r *GreenplumClusterReconciler) reconcileStatus(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster) error {
+func (r *GreenplumClusterReconciler) reconcileStatus(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, activeMaster string) error {
 	originalGreenplumCluster := greenplumCluster.DeepCopy()
 	greenplumCluster.Status.OperatorVersion = r.OperatorImage
 	greenplumCluster.Status.InstanceImage = r.InstanceImage
 	if greenplumCluster.Status.Phase == "" {
 		greenplumCluster.Status.Phase = greenplumv1.GreenplumClusterPhasePending
 	}
+	// Keep the last known active master while neither master accepts connections
+	if activeMaster != "" {
+		greenplumCluster.Status.ActiveMaster = activeMaster
+	}
+	startup := r.lastMasterStartup(ctx, greenplumCluster)
+	newStartup := startup != nil && isNewMasterStartup(greenplumCluster, startup)
+	if newStartup {
+		setMasterStartupCondition(greenplumCluster, startup)
+	}
 
 	if equality.Semantic.DeepEqual(greenplumCluster, originalGreenplumCluster) {
 		return nil
@@ -25,9 +38,71 @@ func (r *GreenplumClusterReconciler) reconcileStatus(ctx context.Context, greenp
 		return fmt.Errorf("updating status: %w", err)
 	}
 
+	previousMaster := originalGreenplumCluster.Status.ActiveMaster
+	if previousMaster != "" && previousMaster != greenplumCluster.Status.ActiveMaster {
+		r.Recorder.Eventf(greenplumCluster, corev1.EventTypeNormal, "ActiveMasterChanged",
+			"Active master changed from %s to %s", previousMaster, greenplumCluster.Status.ActiveMaster)
+	}
+	if newStartup {
+		eventType := corev1.EventTypeNormal
+		if startup.Decision == gpstatus.Ambiguous || startup.Decision == gpstatus.StartupFailed {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Event(greenplumCluster, eventType, string(startup.Decision), startup.Message)
+	}
+
 	return nil
 }
 
+// lastMasterStartup returns the most recent startup decision of the masters of a cluster with a standby,
+// or nil if neither master reports one
+func (r *GreenplumClusterReconciler) lastMasterStartup(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster) *gpstatus.MasterStartup {
+	if r.StatusGetter == nil || greenplumCluster.Spec.MasterAndStandby.Standby != "yes" {
+		return nil
+	}
+	var last *gpstatus.MasterStartup
+	for _, master := range []string{"master-0", "master-1"} {
+		status, err := r.StatusGetter.Get(ctx, greenplumCluster.Namespace, master)
+		if err != nil || status.MasterStartup == nil {
+			continue
+		}
+		if last == nil || status.MasterStartup.Time.After(last.Time) {
+			last = status.MasterStartup
+		}
+	}
+	return last
+}
+
+// isNewMasterStartup is true if the MasterStartup condition does not record startup yet
+func isNewMasterStartup(greenplumCluster *greenplumv1.GreenplumCluster, startup *gpstatus.MasterStartup) bool {
+	condition := meta.FindStatusCondition(greenplumCluster.Status.Conditions, greenplumv1.MasterStartupCondition)
+	return condition == nil ||
+		condition.Reason != string(startup.Decision) ||
+		condition.Message != startup.Message ||
+		!condition.LastTransitionTime.Time.Equal(startup.Time)
+}
+
+// setMasterStartupCondition records startup in the MasterStartup condition. The transition time is when the
+// master made the decision, so that the same startup is recognized on later reconciles.
+func setMasterStartupCondition(greenplumCluster *greenplumv1.GreenplumCluster, startup *gpstatus.MasterStartup) {
+	condition := metav1.Condition{
+		Type:               greenplumv1.MasterStartupCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: greenplumCluster.Generation,
+		Reason:             string(startup.Decision),
+		Message:            startup.Message,
+	}
+	switch startup.Decision {
+	case gpstatus.WaitingForActiveMaster:
+		condition.Status = metav1.ConditionUnknown
+	case gpstatus.Ambiguous, gpstatus.StartupFailed:
+		condition.Status = metav1.ConditionFalse
+	}
+	meta.SetStatusCondition(&greenplumCluster.Status.Conditions, condition)
+	// SetStatusCondition keeps the transition time while the status does not change
+	meta.FindStatusCondition(greenplumCluster.Status.Conditions, greenplumv1.MasterStartupCondition).LastTransitionTime = metav1.NewTime(startup.Time)
+}
+
 func (r *GreenplumClusterReconciler) setStatus(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, status greenplumv1.GreenplumClusterPhase) {
 	if greenplumCluster.Status.Phase != status {
 		originalGreenplumCluster := greenplumCluster.DeepCopy()
@@ -12,9 +12,15 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+	fakegpstatus "github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -25,19 +31,23 @@ var _ = Describe("Reconcile GreenplumCluster status", func() {
 		greenplumReconciler *greenplumcluster.GreenplumClusterReconciler
 		greenplumCluster    *greenplumv1.GreenplumCluster
 		podExec             *fake.PodExec
+		fakeQuerier         *fakequerier.Querier
 	)
 	BeforeEach(func() {
 		ctx = context.WithValue(context.Background(), struct{ key string }{"test"}, CurrentGinkgoTestDescription().TestText)
 		logBuf = gbytes.NewBuffer()
 
 		podExec = &fake.PodExec{}
+		fakeQuerier = &fakequerier.Querier{}
 		greenplumReconciler = &greenplumcluster.GreenplumClusterReconciler{
 			Client:        reactiveClient,
 			Log:           gplog.ForTest(logBuf),
 			SSHCreator:    fakeSecretCreator{},
 			PodExec:       podExec,
+			Querier:       fakeQuerier,
 			InstanceImage: "greenplum-for-kubernetes:greenplumv1.0",
 			OperatorImage: "greenplum-operator:greenplumv1.0",
+			Recorder:      record.NewFakeRecorder(100),
 		}
 
 		greenplumCluster = exampleGreenplumCluster.DeepCopy()
@@ -56,8 +66,8 @@ var _ = Describe("Reconcile GreenplumCluster status", func() {
 		var reconciledCluster greenplumv1.GreenplumCluster
 		BeforeEach(func() {
 			greenplumCluster.Status = greenplumv1.GreenplumClusterStatus{}
-			podExec.ErrorMsgOnMaster0 = "not active"
-			podExec.ErrorMsgOnMaster1 = "not active"
+			fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			fakeQuerier.ErrorMsgOnMaster1 = "not active"
 		})
 		JustBeforeEach(func() {
 			Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
@@ -110,8 +120,8 @@ var _ = Describe("Reconcile GreenplumCluster status", func() {
 				}
 				return true, nil, nil
 			})
-			podExec.ErrorMsgOnMaster0 = "not active"
-			podExec.ErrorMsgOnMaster1 = "not active"
+			fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			fakeQuerier.ErrorMsgOnMaster1 = "not active"
 		})
 		It("succeeds", func() {
 			Expect(reconcileErr).NotTo(HaveOccurred())
@@ -140,5 +150,170 @@ var _ = Describe("Reconcile GreenplumCluster status", func() {
 			Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
 			Expect(reconciledCluster.Status.Phase).To(Equal(greenplumv1.GreenplumClusterPhaseRunning))
 		})
+		It("records the active master", func() {
+			var reconciledCluster greenplumv1.GreenplumCluster
+			Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+			Expect(reconciledCluster.Status.ActiveMaster).To(Equal("master-0"))
+		})
+	})
+
+	When("the active master changes", func() {
+		var recorder *record.FakeRecorder
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			greenplumReconciler.Recorder = recorder
+			greenplumCluster.Status = greenplumv1.GreenplumClusterStatus{
+				InstanceImage:   greenplumReconciler.InstanceImage,
+				OperatorVersion: greenplumReconciler.OperatorImage,
+				Phase:           greenplumv1.GreenplumClusterPhaseRunning,
+				ActiveMaster:    "master-0",
+			}
+			fakeQuerier.ErrorMsgOnMaster0 = "not active"
+		})
+		It("records the new active master in the status", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			var reconciledCluster greenplumv1.GreenplumCluster
+			Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+			Expect(reconciledCluster.Status.ActiveMaster).To(Equal("master-1"))
+		})
+		It("emits an event", func() {
+			Expect(receivedEvents(recorder)).To(ContainElement("Normal ActiveMasterChanged Active master changed from master-0 to master-1"))
+		})
+	})
+
+	When("no master is active", func() {
+		var recorder *record.FakeRecorder
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			greenplumReconciler.Recorder = recorder
+			greenplumCluster.Status = greenplumv1.GreenplumClusterStatus{
+				InstanceImage:   greenplumReconciler.InstanceImage,
+				OperatorVersion: greenplumReconciler.OperatorImage,
+				Phase:           greenplumv1.GreenplumClusterPhaseRunning,
+				ActiveMaster:    "master-0",
+			}
+			fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			fakeQuerier.ErrorMsgOnMaster1 = "not active"
+		})
+		It("keeps the last known active master", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			var reconciledCluster greenplumv1.GreenplumCluster
+			Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+			Expect(reconciledCluster.Status.ActiveMaster).To(Equal("master-0"))
+			Expect(receivedEvents(recorder)).NotTo(ContainElement(ContainSubstring("ActiveMasterChanged")))
+		})
+	})
+
+	When("the masters report how they started", func() {
+		var (
+			recorder          *record.FakeRecorder
+			statusGetter      *fakegpstatus.Getter
+			reconciledCluster greenplumv1.GreenplumCluster
+			startupTime       time.Time
+		)
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			greenplumReconciler.Recorder = recorder
+			startupTime = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+			statusGetter = &fakegpstatus.Getter{Statuses: map[string]*gpstatus.Status{
+				"master-0": {MasterStartup: &gpstatus.MasterStartup{
+					Decision: gpstatus.ReinitializedStandby,
+					Message:  "master-0 started the cluster, and re-initialized master-1 that was failed over from as the standby",
+					Time:     startupTime,
+				}},
+				"master-1": {MasterStartup: &gpstatus.MasterStartup{
+					Decision: gpstatus.WaitingForActiveMaster,
+					Message:  "master-1 was failed over from; it becomes the standby once master-0 starts",
+					Time:     startupTime.Add(-time.Minute),
+				}},
+			}}
+			greenplumReconciler.StatusGetter = statusGetter
+			greenplumCluster.Spec.MasterAndStandby.Standby = "yes"
+			greenplumCluster.Status = greenplumv1.GreenplumClusterStatus{
+				InstanceImage:   greenplumReconciler.InstanceImage,
+				OperatorVersion: greenplumReconciler.OperatorImage,
+				Phase:           greenplumv1.GreenplumClusterPhaseRunning,
+				ActiveMaster:    "master-0",
+			}
+		})
+		JustBeforeEach(func() {
+			Expect(reactiveClient.Get(ctx, greenplumClusterRequest.NamespacedName, &reconciledCluster)).To(Succeed())
+		})
+		It("sets the MasterStartup condition from the latest decision", func() {
+			Expect(reconcileErr).NotTo(HaveOccurred())
+			condition := meta.FindStatusCondition(reconciledCluster.Status.Conditions, greenplumv1.MasterStartupCondition)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("ReinitializedStandby"))
+			Expect(condition.Message).To(Equal("master-0 started the cluster, and re-initialized master-1 that was failed over from as the standby"))
+			Expect(condition.LastTransitionTime.Time).To(BeTemporally("==", startupTime))
+		})
+		It("emits an event", func() {
+			Expect(receivedEvents(recorder)).To(ContainElement("Normal ReinitializedStandby master-0 started the cluster, " +
+				"and re-initialized master-1 that was failed over from as the standby"))
+		})
+
+		When("the decision is already recorded", func() {
+			BeforeEach(func() {
+				greenplumCluster.Status.Conditions = []metav1.Condition{{
+					Type:               greenplumv1.MasterStartupCondition,
+					Status:             metav1.ConditionTrue,
+					Reason:             "ReinitializedStandby",
+					Message:            "master-0 started the cluster, and re-initialized master-1 that was failed over from as the standby",
+					LastTransitionTime: metav1.NewTime(startupTime),
+				}}
+			})
+			It("does not emit the event again", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(receivedEvents(recorder)).NotTo(ContainElement(ContainSubstring("ReinitializedStandby")))
+			})
+		})
+
+		When("the cluster has to be started manually", func() {
+			BeforeEach(func() {
+				statusGetter.Statuses["master-1"].MasterStartup = &gpstatus.MasterStartup{
+					Decision: gpstatus.Ambiguous,
+					Message:  "unable to determine which master was active last: master-1 and master-0 are both on timeline 1; start the cluster manually",
+					Time:     startupTime.Add(time.Minute),
+				}
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
+				fakeQuerier.ErrorMsgOnMaster1 = "not active"
+			})
+			It("sets the MasterStartup condition to False", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(reconcileResult).To(Equal(ctrl.Result{RequeueAfter: 5 * time.Second}))
+				condition := meta.FindStatusCondition(reconciledCluster.Status.Conditions, greenplumv1.MasterStartupCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("Ambiguous"))
+			})
+			It("emits a warning", func() {
+				Expect(receivedEvents(recorder)).To(ContainElement("Warning Ambiguous unable to determine which master was active last: " +
+					"master-1 and master-0 are both on timeline 1; start the cluster manually"))
+			})
+		})
+
+		When("the cluster has no standby", func() {
+			BeforeEach(func() {
+				greenplumCluster.Spec.MasterAndStandby.Standby = "no"
+			})
+			It("does not set the MasterStartup condition", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(meta.FindStatusCondition(reconciledCluster.Status.Conditions, greenplumv1.MasterStartupCondition)).To(BeNil())
+			})
+		})
+
+		When("the status of the masters cannot be read", func() {
+			BeforeEach(func() {
+				statusGetter.Errors = map[string]error{
+					"master-0": errors.New("connection refused"),
+					"master-1": errors.New("connection refused"),
+				}
+			})
+			It("does not set the MasterStartup condition", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(meta.FindStatusCondition(reconciledCluster.Status.Conditions, greenplumv1.MasterStartupCondition)).To(BeNil())
+			})
+		})
 	})
 })
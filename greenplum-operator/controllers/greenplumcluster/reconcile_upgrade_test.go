@@ -10,9 +10,11 @@ import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 )
 
 var _ = Describe("Reconcile GreenplumCluster status", func() {
@@ -31,8 +33,10 @@ var _ = Describe("Reconcile GreenplumCluster status", func() {
 			Log:           gplog.ForTest(logBuf),
 			SSHCreator:    fakeSecretCreator{},
 			PodExec:       &fake.PodExec{},
+			Querier:       &fakequerier.Querier{},
 			InstanceImage: "greenplum-for-kubernetes:new",
 			OperatorImage: "greenplum-operator:new",
+			Recorder:      record.NewFakeRecorder(100),
 		}
 
 		CreateClusterWithOldImages(*newGreenplumReconciler)
@@ -10,7 +10,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
-	fakePodExec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/testing/reactive"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -63,7 +63,7 @@ var exampleGreenplumCluster = &greenplumv1.GreenplumCluster{
 				StorageClassName: "standard",
 				Storage:          resource.MustParse("1G"),
 			},
-			PrimarySegmentCount: fakePodExec.DefaultSegmentCount,
+			PrimarySegmentCount: fakequerier.DefaultSegmentCount,
 		},
 	},
 }
@@ -0,0 +1,173 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/lib/pq"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const DropDatabaseFinalizer = "dropdatabase.greenplumdatabase.pivotal.io"
+
+// GreenplumDatabaseReconciler reconciles a GreenplumDatabase object
+type GreenplumDatabaseReconciler struct {
+	client.Client
+	Log     logr.Logger
+	PodExec executor.PodExecInterface
+	Querier querier.GreenplumQuerier
+}
+
+var _ client.Client = &GreenplumDatabaseReconciler{}
+
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumdatabases,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumdatabases/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+
+func (r *GreenplumDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("greenplumdatabase", req.NamespacedName)
+
+	var database greenplumv1beta1.GreenplumDatabase
+	if err := r.Get(ctx, req.NamespacedName, &database); err != nil {
+		if apierrs.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to fetch GreenplumDatabase")
+	}
+
+	if !database.DeletionTimestamp.IsZero() {
+		dropDatabase := "DROP DATABASE IF EXISTS " + pq.QuoteIdentifier(database.DatabaseName())
+		return finalizeDrop(ctx, r, r.PodExec, r.Querier, log, &database, DropDatabaseFinalizer, database.Spec.ClusterName, dropDatabase)
+	}
+
+	newDatabase := database.DeepCopy()
+	if database.Spec.DeletionPolicy == greenplumv1beta1.GreenplumDeletionPolicyDelete {
+		controllerutil.AddFinalizer(newDatabase, DropDatabaseFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(newDatabase, DropDatabaseFinalizer)
+	}
+	result, err := r.reconcileDatabase(ctx, log, newDatabase)
+	if err != nil {
+		newDatabase.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(newDatabase.Finalizers, database.Finalizers) ||
+		!equality.Semantic.DeepEqual(newDatabase.Status, database.Status) {
+		if patchErr := r.Patch(ctx, newDatabase, client.MergeFrom(&database)); patchErr != nil {
+			log.Error(patchErr, "update failed")
+			return ctrl.Result{}, patchErr
+		}
+	}
+
+	return result, err
+}
+
+// reconcileDatabase records the progress of the database in its status. A returned error is retried.
+func (r *GreenplumDatabaseReconciler) reconcileDatabase(ctx context.Context, log logr.Logger, database *greenplumv1beta1.GreenplumDatabase) (ctrl.Result, error) {
+	status := &database.Status
+	_, activeMaster, waiting, err := activeMasterOf(ctx, r, r.Querier, database.Namespace, database.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if waiting != "" {
+		if status.Phase != greenplumv1beta1.GreenplumDatabasePhaseCreated {
+			status.Phase = greenplumv1beta1.GreenplumDatabasePhasePending
+			status.Message = waiting
+		}
+		return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+	}
+	if status.Phase == "" {
+		status.Phase = greenplumv1beta1.GreenplumDatabasePhasePending
+	}
+
+	name := database.DatabaseName()
+	query := func(sql string) (string, error) {
+		return executor.Query(ctx, r.PodExec, database.Namespace, activeMaster, "gpadmin", sql)
+	}
+	existing, err := query("SELECT pg_get_userbyid(datdba) || '|' || datconnlimit FROM pg_database WHERE datname = " + pq.QuoteLiteral(name))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to query database: %w", err)
+	}
+
+	connectionLimit := int32(-1)
+	if database.Spec.ConnectionLimit != nil {
+		connectionLimit = *database.Spec.ConnectionLimit
+	}
+
+	var statements []string
+	if existing == "" {
+		sql := "CREATE DATABASE " + pq.QuoteIdentifier(name)
+		if database.Spec.Owner != "" {
+			sql += " OWNER " + pq.QuoteIdentifier(database.Spec.Owner)
+		}
+		sql += " CONNECTION LIMIT " + strconv.Itoa(int(connectionLimit))
+		statements = append(statements, sql)
+	} else {
+		separator := strings.LastIndex(existing, "|")
+		owner, existingLimit := existing[:separator], existing[separator+1:]
+		if database.Spec.Owner != "" && database.Spec.Owner != owner {
+			statements = append(statements, "ALTER DATABASE "+pq.QuoteIdentifier(name)+" OWNER TO "+pq.QuoteIdentifier(database.Spec.Owner))
+		}
+		if existingLimit != strconv.Itoa(int(connectionLimit)) {
+			statements = append(statements, "ALTER DATABASE "+pq.QuoteIdentifier(name)+" CONNECTION LIMIT "+strconv.Itoa(int(connectionLimit)))
+		}
+	}
+
+	// CREATE DATABASE cannot run in a transaction, so each statement is run on its own
+	for _, sql := range statements {
+		if _, err := query(sql); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile database: %w", err)
+		}
+		log.Info("reconciled database", "statement", sql)
+	}
+
+	status.Phase = greenplumv1beta1.GreenplumDatabasePhaseCreated
+	status.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// databasesForCluster enqueues the databases of a cluster, so that they are revisited when it becomes Running.
+func (r *GreenplumDatabaseReconciler) databasesForCluster(cluster client.Object) []reconcile.Request {
+	var databases greenplumv1beta1.GreenplumDatabaseList
+	if err := r.List(context.Background(), &databases, client.InNamespace(cluster.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list GreenplumDatabases", "namespace", cluster.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, database := range databases.Items {
+		if database.Spec.ClusterName == cluster.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: database.Namespace, Name: database.Name},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *GreenplumDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&greenplumv1beta1.GreenplumDatabase{}).
+		Watches(&source.Kind{Type: &greenplumv1.GreenplumCluster{}}, handler.EnqueueRequestsFromMapFunc(r.databasesForCluster)).
+		Complete(r)
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	fakeExecutor "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("GreenplumDatabase controller", func() {
+	var (
+		ctx                context.Context
+		logBuf             *gbytes.Buffer
+		fakePodExec        *fakeExecutor.PodExec
+		fakeQuerier        *fakequerier.Querier
+		databaseReconciler *GreenplumDatabaseReconciler
+		database           *v1beta1.GreenplumDatabase
+		cluster            *greenplumv1.GreenplumCluster
+		databaseRequest    = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: "sales-db"},
+		}
+	)
+
+	getDatabase := func() *v1beta1.GreenplumDatabase {
+		var current v1beta1.GreenplumDatabase
+		Expect(reactiveClient.Get(ctx, databaseRequest.NamespacedName, &current)).To(Succeed())
+		return &current
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		fakeQuerier = &fakequerier.Querier{}
+		fakePodExec = &fakeExecutor.PodExec{}
+		databaseReconciler = &GreenplumDatabaseReconciler{
+			Client:  reactiveClient,
+			Log:     gplog.ForTest(logBuf),
+			PodExec: fakePodExec,
+			Querier: fakeQuerier,
+		}
+		database = &v1beta1.GreenplumDatabase{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "sales-db"},
+			Spec: v1beta1.GreenplumDatabaseSpec{
+				ClusterName: "my-greenplum",
+				Name:        "sales",
+				Owner:       "sales_app",
+			},
+		}
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+			Status:     greenplumv1.GreenplumClusterStatus{Phase: greenplumv1.GreenplumClusterPhaseRunning},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, database)).To(Succeed())
+	})
+
+	When("the cluster is running", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+
+		It("creates the database", func() {
+			_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakePodExec.CalledPodName).To(Equal("master-0"))
+			Expect(fakePodExec.RecordedCommands).To(HaveLen(2))
+			Expect(fakePodExec.RecordedCommands[0]).To(HaveSuffix("FROM pg_database WHERE datname = 'sales'"))
+			Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(`CREATE DATABASE "sales" OWNER "sales_app" CONNECTION LIMIT -1`))
+			Expect(logBuf).To(gbytes.Say("reconciled database"))
+
+			current := getDatabase()
+			Expect(current.Status).To(Equal(v1beta1.GreenplumDatabaseStatus{Phase: v1beta1.GreenplumDatabasePhaseCreated}))
+			Expect(current.Finalizers).To(BeEmpty())
+		})
+
+		When("the database exists with another owner and connection limit", func() {
+			BeforeEach(func() {
+				var limit int32 = 10
+				database.Spec.ConnectionLimit = &limit
+				fakePodExec.CommandStdout = map[string]string{"FROM pg_database": "gpadmin|-1\n"}
+			})
+			It("alters the database", func() {
+				_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(3))
+				Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(`ALTER DATABASE "sales" OWNER TO "sales_app"`))
+				Expect(fakePodExec.RecordedCommands[2]).To(HaveSuffix(`ALTER DATABASE "sales" CONNECTION LIMIT 10`))
+			})
+		})
+
+		When("the database is up to date", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandStdout = map[string]string{"FROM pg_database": "sales_app|-1\n"}
+			})
+			It("does not change it", func() {
+				_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(1))
+				Expect(getDatabase().Status.Phase).To(Equal(v1beta1.GreenplumDatabasePhaseCreated))
+			})
+		})
+
+		When("creating the database fails", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandErrors = map[string]string{"CREATE DATABASE": `ERROR:  role "sales_app" does not exist`}
+			})
+			It("returns the error and records it in the status", func() {
+				_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+				Expect(err).To(MatchError(`failed to reconcile database: ERROR:  role "sales_app" does not exist: ERROR:  role "sales_app" does not exist`))
+				Expect(getDatabase().Status).To(Equal(v1beta1.GreenplumDatabaseStatus{
+					Phase:   v1beta1.GreenplumDatabasePhasePending,
+					Message: err.Error(),
+				}))
+			})
+		})
+
+		When("the deletion policy is Delete", func() {
+			BeforeEach(func() {
+				database.Spec.DeletionPolicy = v1beta1.GreenplumDeletionPolicyDelete
+			})
+			It("adds a finalizer", func() {
+				_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getDatabase().Finalizers).To(ConsistOf(DropDatabaseFinalizer))
+			})
+
+			When("the GreenplumDatabase is deleted", func() {
+				JustBeforeEach(func() {
+					_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(reactiveClient.Delete(ctx, getDatabase())).To(Succeed())
+					fakePodExec.RecordedCommands = nil
+				})
+				It("drops the database and removes the finalizer", func() {
+					_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakePodExec.RecordedCommands).To(ConsistOf(HaveSuffix(`DROP DATABASE IF EXISTS "sales"`)))
+					err = reactiveClient.Get(ctx, databaseRequest.NamespacedName, &v1beta1.GreenplumDatabase{})
+					Expect(apierrs.IsNotFound(err)).To(BeTrue(), "expected NotFound, got %v", err)
+				})
+
+				When("dropping fails", func() {
+					BeforeEach(func() {
+						fakePodExec.CommandErrors = map[string]string{"DROP DATABASE": `ERROR:  database "sales" is being accessed by other users`}
+					})
+					It("keeps the finalizer", func() {
+						_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+						Expect(err).To(MatchError(ContainSubstring("failed to drop: ")))
+						Expect(getDatabase().Finalizers).To(ConsistOf(DropDatabaseFinalizer))
+					})
+				})
+			})
+		})
+
+		When("the deletion policy is Retain and the GreenplumDatabase is deleted", func() {
+			JustBeforeEach(func() {
+				_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reactiveClient.Delete(ctx, getDatabase())).To(Succeed())
+			})
+			It("does not drop the database", func() {
+				_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).NotTo(ContainElement(ContainSubstring("DROP")))
+			})
+		})
+	})
+
+	When("the cluster is being deleted", func() {
+		BeforeEach(func() {
+			cluster.Status.Phase = greenplumv1.GreenplumClusterPhaseDeleting
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+			database.Spec.DeletionPolicy = v1beta1.GreenplumDeletionPolicyDelete
+			database.Finalizers = []string{DropDatabaseFinalizer}
+		})
+		It("removes the finalizer without dropping the database", func() {
+			Expect(reactiveClient.Delete(ctx, getDatabase())).To(Succeed())
+			_, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+			err = reactiveClient.Get(ctx, databaseRequest.NamespacedName, &v1beta1.GreenplumDatabase{})
+			Expect(apierrs.IsNotFound(err)).To(BeTrue(), "expected NotFound, got %v", err)
+		})
+	})
+
+	When("the cluster is not running", func() {
+		BeforeEach(func() {
+			cluster.Status.Phase = greenplumv1.GreenplumClusterPhasePending
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+		It("waits for the cluster", func() {
+			result, err := databaseReconciler.Reconcile(ctx, databaseRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(clusterRequeueDelay))
+			Expect(getDatabase().Status).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Phase":   Equal(v1beta1.GreenplumDatabasePhasePending),
+				"Message": Equal("waiting for GreenplumCluster to be Running"),
+			}))
+		})
+	})
+
+	Describe("databasesForCluster", func() {
+		It("enqueues the databases of the cluster", func() {
+			Expect(databaseReconciler.databasesForCluster(cluster)).To(ConsistOf(databaseRequest))
+		})
+	})
+})
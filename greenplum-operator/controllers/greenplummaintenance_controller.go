@@ -0,0 +1,215 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/cronschedule"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/maintenancejob"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// How many runs are kept in the status of a GreenplumMaintenance. The Jobs of older runs are deleted.
+const maintenanceHistoryLimit = 5
+
+// GreenplumMaintenanceReconciler reconciles a GreenplumMaintenance object
+type GreenplumMaintenanceReconciler struct {
+	client.Client
+	Log           logr.Logger
+	Querier       querier.GreenplumQuerier
+	InstanceImage string
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+var _ client.Client = &GreenplumMaintenanceReconciler{}
+
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplummaintenances,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplummaintenances/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+func (r *GreenplumMaintenanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("greenplummaintenance", req.NamespacedName)
+
+	var maintenance greenplumv1beta1.GreenplumMaintenance
+	if err := r.Get(ctx, req.NamespacedName, &maintenance); err != nil {
+		if apierrs.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to fetch GreenplumMaintenance")
+	}
+
+	newMaintenance := maintenance.DeepCopy()
+	result, err := r.reconcileMaintenance(ctx, log, newMaintenance)
+	if err != nil {
+		newMaintenance.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(newMaintenance.Status, maintenance.Status) {
+		if patchErr := r.Patch(ctx, newMaintenance, client.MergeFrom(&maintenance)); patchErr != nil && !apierrs.IsNotFound(patchErr) {
+			log.Error(patchErr, "update failed")
+			return ctrl.Result{}, patchErr
+		}
+	}
+
+	return result, err
+}
+
+// reconcileMaintenance records the results of finished runs, and starts a Job when a run is due.
+// A run that is due while the previous run is still going is skipped. Of several runs missed
+// while the operator was down, only the latest is started. A returned error is retried.
+func (r *GreenplumMaintenanceReconciler) reconcileMaintenance(ctx context.Context, log logr.Logger, maintenance *greenplumv1beta1.GreenplumMaintenance) (ctrl.Result, error) {
+	status := &maintenance.Status
+	schedule, err := cronschedule.Parse(maintenance.Spec.Schedule)
+	if err != nil {
+		status.Message = "invalid schedule: " + err.Error()
+		status.NextScheduleTime = nil
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.updateHistory(ctx, maintenance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now := r.now()
+	last := maintenance.CreationTimestamp.Time
+	if status.LastScheduleTime != nil {
+		last = status.LastScheduleTime.Time
+	}
+	runs := schedule.Runs(last, now)
+	if runs.Next.IsZero() {
+		status.NextScheduleTime = nil
+	} else {
+		status.NextScheduleTime = &metav1.Time{Time: runs.Next}
+	}
+	result := ctrl.Result{RequeueAfter: runs.RequeueAfter}
+
+	if runs.Due.IsZero() {
+		if runs.NeverRuns() {
+			status.Message = cronschedule.NeverRunsMessage
+		}
+		return result, nil
+	}
+
+	if len(status.History) > 0 && status.History[0].Result == greenplumv1beta1.GreenplumMaintenanceRunResultRunning {
+		status.LastScheduleTime = &metav1.Time{Time: runs.Due}
+		status.Message = runs.SkippedMessage("Job " + status.History[0].JobName + " is still running")
+		log.Info("skipped maintenance run", "scheduleTime", runs.Due, "job", status.History[0].JobName)
+		return result, nil
+	}
+
+	_, activeMaster, waiting, err := activeMasterOf(ctx, r, r.Querier, maintenance.Namespace, maintenance.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if waiting != "" {
+		status.Message = waiting
+		return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+	}
+
+	activeMasterFQDN := fmt.Sprintf("%s.agent.%s.svc.cluster.local", activeMaster, maintenance.Namespace)
+	job := maintenancejob.GenerateJob(r.InstanceImage, activeMasterFQDN, maintenance.Spec)
+	job.Namespace = maintenance.Namespace
+	job.Name = runs.JobName(maintenance.Name)
+	job.Labels = map[string]string{
+		"greenplum-cluster":     maintenance.Spec.ClusterName,
+		"greenplum-maintenance": maintenance.Name,
+	}
+	if err := controllerutil.SetControllerReference(maintenance, &job, r.Scheme()); err != nil {
+		// not tested: not really possible to fail here
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, &job); err != nil && !apierrs.IsAlreadyExists(err) {
+		return ctrl.Result{}, fmt.Errorf("unable to create Job %s: %w", job.Name, err)
+	}
+	log.Info("started maintenance run", "scheduleTime", runs.Due, "job", job.Name, "activeMaster", activeMaster)
+
+	status.LastScheduleTime = &metav1.Time{Time: runs.Due}
+	status.Message = ""
+	status.History = append([]greenplumv1beta1.GreenplumMaintenanceRun{{
+		JobName:      job.Name,
+		ScheduleTime: metav1.Time{Time: runs.Due},
+		Result:       greenplumv1beta1.GreenplumMaintenanceRunResultRunning,
+	}}, status.History...)
+	for len(status.History) > maintenanceHistoryLimit {
+		oldest := status.History[len(status.History)-1]
+		oldJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: maintenance.Namespace, Name: oldest.JobName}}
+		if err := r.Delete(ctx, oldJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrs.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("unable to delete Job %s: %w", oldest.JobName, err)
+		}
+		status.History = status.History[:len(status.History)-1]
+	}
+	return result, nil
+}
+
+// updateHistory records the start, end and result of each run whose Job was still running
+func (r *GreenplumMaintenanceReconciler) updateHistory(ctx context.Context, maintenance *greenplumv1beta1.GreenplumMaintenance) error {
+	for i := range maintenance.Status.History {
+		run := &maintenance.Status.History[i]
+		if run.Result != greenplumv1beta1.GreenplumMaintenanceRunResultRunning {
+			continue
+		}
+		var job batchv1.Job
+		if err := r.Get(ctx, types.NamespacedName{Namespace: maintenance.Namespace, Name: run.JobName}, &job); err != nil {
+			if apierrs.IsNotFound(err) {
+				run.Result = greenplumv1beta1.GreenplumMaintenanceRunResultFailed
+				run.Message = "Job " + run.JobName + " was deleted"
+				continue
+			}
+			return fmt.Errorf("unable to fetch Job %s: %w", run.JobName, err)
+		}
+		run.StartTime = job.Status.StartTime
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				run.Result = greenplumv1beta1.GreenplumMaintenanceRunResultSucceeded
+			case batchv1.JobFailed:
+				run.Result = greenplumv1beta1.GreenplumMaintenanceRunResultFailed
+				run.Message = condition.Message
+			default:
+				continue
+			}
+			completionTime := condition.LastTransitionTime
+			run.CompletionTime = &completionTime
+			if run.StartTime != nil {
+				run.Duration = &metav1.Duration{Duration: completionTime.Sub(run.StartTime.Time)}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *GreenplumMaintenanceReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *GreenplumMaintenanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&greenplumv1beta1.GreenplumMaintenance{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
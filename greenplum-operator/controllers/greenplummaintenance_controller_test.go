@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("GreenplumMaintenance controller", func() {
+	var (
+		ctx                   context.Context
+		fakeQuerier           *fakequerier.Querier
+		maintenanceReconciler *GreenplumMaintenanceReconciler
+		maintenance           *v1beta1.GreenplumMaintenance
+		cluster               *greenplumv1.GreenplumCluster
+		now                   time.Time
+		maintenanceRequest    = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: "weekly"},
+		}
+	)
+
+	// Sunday 3am, in minutes since the epoch, is the suffix of the Job names
+	const firstRunJob = "weekly-26514900"
+
+	created := time.Date(2020, time.May, 27, 12, 0, 0, 0, time.UTC)
+	firstRun := time.Date(2020, time.May, 31, 3, 0, 0, 0, time.UTC)
+	secondRun := firstRun.AddDate(0, 0, 7)
+
+	getMaintenance := func() *v1beta1.GreenplumMaintenance {
+		var current v1beta1.GreenplumMaintenance
+		Expect(reactiveClient.Get(ctx, maintenanceRequest.NamespacedName, &current)).To(Succeed())
+		return &current
+	}
+
+	listJobs := func() []batchv1.Job {
+		var jobList batchv1.JobList
+		Expect(reactiveClient.List(ctx, &jobList, client.InNamespace("test-ns"))).To(Succeed())
+		return jobList.Items
+	}
+
+	getJob := func(name string) *batchv1.Job {
+		var job batchv1.Job
+		Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: name}, &job)).To(Succeed())
+		return &job
+	}
+
+	finishJob := func(name string, conditionType batchv1.JobConditionType, message string) {
+		job := getJob(name)
+		job.Status.StartTime = &metav1.Time{Time: firstRun.Add(time.Minute)}
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type:               conditionType,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Time{Time: firstRun.Add(26 * time.Minute)},
+			Message:            message,
+		}}
+		Expect(reactiveClient.Update(ctx, job)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeQuerier = &fakequerier.Querier{}
+		now = created.Add(time.Hour)
+		maintenanceReconciler = &GreenplumMaintenanceReconciler{
+			Client:        reactiveClient,
+			Log:           gplog.ForTest(gbytes.NewBuffer()),
+			Querier:       fakeQuerier,
+			InstanceImage: "greenplum-for-kubernetes:latest",
+			Now:           func() time.Time { return now },
+		}
+		maintenance = &v1beta1.GreenplumMaintenance{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "test-ns",
+				Name:              "weekly",
+				CreationTimestamp: metav1.Time{Time: created},
+			},
+			Spec: v1beta1.GreenplumMaintenanceSpec{
+				ClusterName: "my-greenplum",
+				Schedule:    "0 3 * * 0",
+				Tasks: []v1beta1.GreenplumMaintenanceTask{
+					v1beta1.GreenplumMaintenanceTaskVacuumCatalog,
+					v1beta1.GreenplumMaintenanceTaskAnalyze,
+				},
+				Concurrency: 2,
+			},
+		}
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+			Status:     greenplumv1.GreenplumClusterStatus{Phase: greenplumv1.GreenplumClusterPhaseRunning},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		Expect(reactiveClient.Create(ctx, maintenance)).To(Succeed())
+	})
+
+	reconcileAt := func(t time.Time) reconcile.Result {
+		now = t
+		result, err := maintenanceReconciler.Reconcile(ctx, maintenanceRequest)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	When("no run is due yet", func() {
+		It("waits for the next scheduled time", func() {
+			result := reconcileAt(created.Add(time.Hour))
+			Expect(result.RequeueAfter).To(Equal(firstRun.Sub(created.Add(time.Hour))))
+			Expect(listJobs()).To(BeEmpty())
+			Expect(getMaintenance().Status.NextScheduleTime.Time).To(BeTemporally("==", firstRun))
+			Expect(getMaintenance().Status.LastScheduleTime).To(BeNil())
+		})
+	})
+
+	When("a run is due", func() {
+		var result reconcile.Result
+		JustBeforeEach(func() {
+			result = reconcileAt(firstRun.Add(10 * time.Second))
+		})
+
+		It("creates a Job against the active master", func() {
+			job := getJob(firstRunJob)
+			Expect(job.Labels).To(Equal(map[string]string{
+				"greenplum-cluster":     "my-greenplum",
+				"greenplum-maintenance": "weekly",
+			}))
+			Expect(job.OwnerReferences).To(HaveLen(1))
+			Expect(job.OwnerReferences[0].Kind).To(Equal("GreenplumMaintenance"))
+			Expect(job.OwnerReferences[0].Name).To(Equal("weekly"))
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("greenplum-for-kubernetes:latest"))
+			Expect(container.Env).To(ContainElement(corev1.EnvVar{
+				Name: "MAINTENANCE_HOST", Value: "master-0.agent.test-ns.svc.cluster.local",
+			}))
+			Expect(container.Args).To(Equal([]string{"-p", "2", "vacuumCatalog", "analyze"}))
+		})
+
+		It("records the run in the status", func() {
+			status := getMaintenance().Status
+			Expect(status.Message).To(BeEmpty())
+			Expect(status.LastScheduleTime.Time).To(BeTemporally("==", firstRun))
+			Expect(status.NextScheduleTime.Time).To(BeTemporally("==", secondRun))
+			Expect(status.History).To(HaveLen(1))
+			Expect(status.History[0].JobName).To(Equal(firstRunJob))
+			Expect(status.History[0].ScheduleTime.Time).To(BeTemporally("==", firstRun))
+			Expect(status.History[0].Result).To(Equal(v1beta1.GreenplumMaintenanceRunResultRunning))
+			Expect(result.RequeueAfter).To(Equal(secondRun.Sub(firstRun.Add(10 * time.Second))))
+		})
+
+		It("does not run again until the next scheduled time", func() {
+			reconcileAt(firstRun.Add(time.Hour))
+			Expect(listJobs()).To(HaveLen(1))
+		})
+
+		When("the Job succeeds", func() {
+			JustBeforeEach(func() {
+				finishJob(firstRunJob, batchv1.JobComplete, "")
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+			It("records the result and the duration of the run", func() {
+				run := getMaintenance().Status.History[0]
+				Expect(run.Result).To(Equal(v1beta1.GreenplumMaintenanceRunResultSucceeded))
+				Expect(run.StartTime.Time).To(BeTemporally("==", firstRun.Add(time.Minute)))
+				Expect(run.CompletionTime.Time).To(BeTemporally("==", firstRun.Add(26*time.Minute)))
+				Expect(run.Duration.Duration).To(Equal(25 * time.Minute))
+				Expect(run.Message).To(BeEmpty())
+			})
+			It("runs again at the next scheduled time", func() {
+				reconcileAt(secondRun)
+				history := getMaintenance().Status.History
+				Expect(history).To(HaveLen(2))
+				Expect(history[0].ScheduleTime.Time).To(BeTemporally("==", secondRun))
+				Expect(history[0].Result).To(Equal(v1beta1.GreenplumMaintenanceRunResultRunning))
+				Expect(history[1].Result).To(Equal(v1beta1.GreenplumMaintenanceRunResultSucceeded))
+				Expect(listJobs()).To(HaveLen(2))
+			})
+		})
+
+		When("the Job fails", func() {
+			JustBeforeEach(func() {
+				finishJob(firstRunJob, batchv1.JobFailed, "Job has reached the specified backoff limit")
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+			It("records the failure", func() {
+				run := getMaintenance().Status.History[0]
+				Expect(run.Result).To(Equal(v1beta1.GreenplumMaintenanceRunResultFailed))
+				Expect(run.Message).To(Equal("Job has reached the specified backoff limit"))
+				Expect(run.Duration.Duration).To(Equal(25 * time.Minute))
+			})
+		})
+
+		When("the Job is deleted", func() {
+			JustBeforeEach(func() {
+				Expect(reactiveClient.Delete(ctx, getJob(firstRunJob))).To(Succeed())
+				reconcileAt(firstRun.Add(time.Hour))
+			})
+			It("records the run as failed", func() {
+				run := getMaintenance().Status.History[0]
+				Expect(run.Result).To(Equal(v1beta1.GreenplumMaintenanceRunResultFailed))
+				Expect(run.Message).To(Equal("Job " + firstRunJob + " was deleted"))
+			})
+		})
+
+		When("the Job is still running at the next scheduled time", func() {
+			It("skips the run", func() {
+				reconcileAt(secondRun)
+				Expect(listJobs()).To(HaveLen(1))
+				status := getMaintenance().Status
+				Expect(status.LastScheduleTime.Time).To(BeTemporally("==", secondRun))
+				Expect(status.Message).To(Equal("skipped the run scheduled at 2020-06-07T03:00:00Z: Job " + firstRunJob + " is still running"))
+				Expect(status.History).To(HaveLen(1))
+			})
+		})
+
+		When("master-1 is the active master", func() {
+			BeforeEach(func() {
+				fakeQuerier.ErrorMsgOnMaster0 = "not active"
+			})
+			It("runs against master-1", func() {
+				Expect(getJob(firstRunJob).Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+					Name: "MAINTENANCE_HOST", Value: "master-1.agent.test-ns.svc.cluster.local",
+				}))
+			})
+		})
+
+		When("the cluster is not running", func() {
+			BeforeEach(func() {
+				cluster.Status.Phase = greenplumv1.GreenplumClusterPhasePending
+			})
+			It("waits for it", func() {
+				Expect(result.RequeueAfter).To(Equal(clusterRequeueDelay))
+				Expect(listJobs()).To(BeEmpty())
+				status := getMaintenance().Status
+				Expect(status.Message).To(Equal("waiting for GreenplumCluster to be Running"))
+				Expect(status.LastScheduleTime).To(BeNil())
+			})
+		})
+	})
+
+	When("several runs were missed", func() {
+		It("starts only the latest", func() {
+			reconcileAt(secondRun.Add(time.Hour))
+			jobs := listJobs()
+			Expect(jobs).To(HaveLen(1))
+			history := getMaintenance().Status.History
+			Expect(history).To(HaveLen(1))
+			Expect(history[0].ScheduleTime.Time).To(BeTemporally("==", secondRun))
+		})
+	})
+
+	When("the history is full", func() {
+		It("deletes the Job of the oldest run", func() {
+			for week := 0; week <= maintenanceHistoryLimit; week++ {
+				scheduleTime := firstRun.AddDate(0, 0, 7*week)
+				reconcileAt(scheduleTime)
+				history := getMaintenance().Status.History
+				finishJob(history[0].JobName, batchv1.JobComplete, "")
+			}
+			history := getMaintenance().Status.History
+			Expect(history).To(HaveLen(maintenanceHistoryLimit))
+			Expect(history[0].ScheduleTime.Time).To(BeTemporally("==", firstRun.AddDate(0, 0, 7*maintenanceHistoryLimit)))
+			Expect(listJobs()).To(HaveLen(maintenanceHistoryLimit))
+			var job batchv1.Job
+			Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: firstRunJob}, &job)).NotTo(Succeed())
+		})
+	})
+
+	When("the schedule is invalid", func() {
+		BeforeEach(func() {
+			maintenance.Spec.Schedule = "0 3 * *"
+		})
+		It("reports it without retrying", func() {
+			result := reconcileAt(firstRun)
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(listJobs()).To(BeEmpty())
+			Expect(getMaintenance().Status.Message).To(Equal(`invalid schedule: expected 5 fields in schedule "0 3 * *", found 4`))
+		})
+	})
+})
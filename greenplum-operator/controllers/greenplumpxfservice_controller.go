@@ -14,6 +14,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -24,6 +25,7 @@ type GreenplumPXFServiceReconciler struct {
 	client.Client
 	Log           logr.Logger
 	InstanceImage string
+	Recorder      record.EventRecorder
 }
 
 var _ client.Client = &GreenplumPXFServiceReconciler{}
@@ -83,6 +85,7 @@ func (r *GreenplumPXFServiceReconciler) Reconcile(ctx context.Context, req ctrl.
 	}
 	if result != controllerutil.OperationResultNone {
 		log.Info("PXF Deployment " + string(result))
+		r.Recorder.Eventf(&greenplumPXF, corev1.EventTypeNormal, "RollingOut", "PXF Deployment %s", result)
 	}
 
 	// update status
@@ -104,6 +107,12 @@ func (r *GreenplumPXFServiceReconciler) Reconcile(ctx context.Context, req ctrl.
 			log.Error(err, "update failed")
 			return ctrl.Result{}, err
 		}
+		switch newPXF.Status.Phase {
+		case greenplumv1beta1.GreenplumPXFServicePhaseRunning:
+			r.Recorder.Eventf(newPXF, corev1.EventTypeNormal, "RolloutComplete", "%d of %d PXF replicas are ready", readyReplicas, desiredReplicas)
+		case greenplumv1beta1.GreenplumPXFServicePhaseDegraded:
+			r.Recorder.Eventf(newPXF, corev1.EventTypeWarning, "Degraded", "%d of %d PXF replicas are ready", readyReplicas, desiredReplicas)
+		}
 	}
 
 	return ctrl.Result{}, nil
@@ -20,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -28,6 +29,7 @@ var _ = Describe("PXF controller", func() {
 		ctx           context.Context
 		logBuf        *gbytes.Buffer
 		pxfReconciler *GreenplumPXFServiceReconciler
+		recorder      *record.FakeRecorder
 		pxf           *v1beta1.GreenplumPXFService
 		examplePxf    = &v1beta1.GreenplumPXFService{
 			ObjectMeta: metav1.ObjectMeta{
@@ -52,11 +54,13 @@ var _ = Describe("PXF controller", func() {
 	BeforeEach(func() {
 		ctx = context.WithValue(context.Background(), struct{ key string }{"test"}, CurrentGinkgoTestDescription().TestText)
 		logBuf = gbytes.NewBuffer()
+		recorder = record.NewFakeRecorder(100)
 
 		pxfReconciler = &GreenplumPXFServiceReconciler{
 			Client:        reactiveClient,
 			Log:           gplog.ForTest(logBuf),
 			InstanceImage: "greenplum-for-kubernetes:v1.7.5",
+			Recorder:      recorder,
 		}
 
 		pxf = examplePxf.DeepCopy()
@@ -88,6 +92,7 @@ var _ = Describe("PXF controller", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("PXF Service created")}))
 				Expect(logs).To(ContainLogEntry(gstruct.Keys{"msg": Equal("PXF Deployment created")}))
+				Expect(recorder.Events).To(Receive(Equal("Normal RollingOut PXF Deployment created")))
 			})
 		})
 
@@ -248,6 +253,9 @@ var _ = Describe("PXF controller", func() {
 				Expect(reactiveClient.Get(ctx, myPxfKey, &resultGreenplumPXF)).To(Succeed())
 				Expect(resultGreenplumPXF.Status.Phase).To(Equal(v1beta1.GreenplumPXFServicePhaseDegraded))
 			})
+			It("emits a Degraded event", func() {
+				Eventually(recorder.Events).Should(Receive(Equal("Warning Degraded 1 of 2 PXF replicas are ready")))
+			})
 		})
 		When("Deployment readyReplicas > 0 and updatedReplicas < PXF desired replicas", func() {
 			BeforeEach(func() {
@@ -280,6 +288,9 @@ var _ = Describe("PXF controller", func() {
 				Expect(reactiveClient.Get(ctx, myPxfKey, &resultGreenplumPXF)).To(Succeed())
 				Expect(resultGreenplumPXF.Status.Phase).To(Equal(v1beta1.GreenplumPXFServicePhaseRunning))
 			})
+			It("emits a RolloutComplete event", func() {
+				Eventually(recorder.Events).Should(Receive(Equal("Normal RolloutComplete 2 of 2 PXF replicas are ready")))
+			})
 		})
 		When("there is no need for a status change", func() {
 			var patchCalled bool
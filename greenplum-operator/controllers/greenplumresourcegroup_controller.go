@@ -0,0 +1,310 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/lib/pq"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const DropResourceGroupFinalizer = "dropresourcegroup.greenplumresourcegroup.pivotal.io"
+
+// Resource groups can be changed with SQL without any event in Kubernetes, so they are checked for drift this often
+const resourceGroupDriftCheckInterval = 5 * time.Minute
+
+const defaultResourceGroupConcurrency = 20
+
+// GreenplumResourceGroupReconciler reconciles a GreenplumResourceGroup object
+type GreenplumResourceGroupReconciler struct {
+	client.Client
+	Log     logr.Logger
+	PodExec executor.PodExecInterface
+	Querier querier.GreenplumQuerier
+}
+
+var _ client.Client = &GreenplumResourceGroupReconciler{}
+
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumresourcegroups,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumresourcegroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+
+func (r *GreenplumResourceGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("greenplumresourcegroup", req.NamespacedName)
+
+	var group greenplumv1beta1.GreenplumResourceGroup
+	if err := r.Get(ctx, req.NamespacedName, &group); err != nil {
+		if apierrs.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to fetch GreenplumResourceGroup")
+	}
+
+	if !group.DeletionTimestamp.IsZero() {
+		name := group.ResourceGroupName()
+		// DROP RESOURCE GROUP has no IF EXISTS
+		return finalizeDropFunc(ctx, r, r.PodExec, r.Querier, log, &group, DropResourceGroupFinalizer, group.Spec.ClusterName, func(query sqlQuery) error {
+			exists, err := query("SELECT 1 FROM pg_resgroup WHERE rsgname = " + pq.QuoteLiteral(name))
+			if err != nil || exists == "" {
+				return err
+			}
+			dropResourceGroup := "DROP RESOURCE GROUP " + pq.QuoteIdentifier(name)
+			if _, err := query(dropResourceGroup); err != nil {
+				return err
+			}
+			log.Info("dropped", "statement", dropResourceGroup)
+			return nil
+		})
+	}
+
+	newGroup := group.DeepCopy()
+	if group.Spec.DeletionPolicy == greenplumv1beta1.GreenplumDeletionPolicyDelete {
+		controllerutil.AddFinalizer(newGroup, DropResourceGroupFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(newGroup, DropResourceGroupFinalizer)
+	}
+	result, err := r.reconcileResourceGroup(ctx, log, newGroup)
+	if err != nil {
+		newGroup.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(newGroup.Finalizers, group.Finalizers) ||
+		!equality.Semantic.DeepEqual(newGroup.Status, group.Status) {
+		if patchErr := r.Patch(ctx, newGroup, client.MergeFrom(&group)); patchErr != nil {
+			log.Error(patchErr, "update failed")
+			return ctrl.Result{}, patchErr
+		}
+	}
+
+	return result, err
+}
+
+// resourceGroupConfig is a row of gp_toolkit.gp_resgroup_config. A limit that is not in use is -1.
+type resourceGroupConfig struct {
+	concurrency, cpuRateLimit, memoryLimit, memorySpillRatio int
+	cpuSet                                                   string
+}
+
+// reconcileResourceGroup records the progress of the resource group in its status. A returned error is retried.
+func (r *GreenplumResourceGroupReconciler) reconcileResourceGroup(ctx context.Context, log logr.Logger, group *greenplumv1beta1.GreenplumResourceGroup) (ctrl.Result, error) {
+	status := &group.Status
+	fail := func(message string) (ctrl.Result, error) {
+		status.Phase = greenplumv1beta1.GreenplumResourceGroupPhaseFailed
+		status.Message = message
+		return ctrl.Result{}, nil
+	}
+
+	settings := group.Spec.GreenplumResourceGroupSettings
+	if (settings.CPURateLimit == 0) == (settings.CPUSet == "") {
+		return fail("exactly one of cpuRateLimit and cpuSet must be set")
+	}
+
+	_, activeMaster, waiting, err := activeMasterOf(ctx, r, r.Querier, group.Namespace, group.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if waiting != "" {
+		if status.Phase != greenplumv1beta1.GreenplumResourceGroupPhaseCreated {
+			status.Phase = greenplumv1beta1.GreenplumResourceGroupPhasePending
+			status.Message = waiting
+		}
+		return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+	}
+	if status.Phase == "" {
+		status.Phase = greenplumv1beta1.GreenplumResourceGroupPhasePending
+	}
+
+	query := func(sql string) (string, error) {
+		return executor.Query(ctx, r.PodExec, group.Namespace, activeMaster, "gpadmin", sql)
+	}
+	configs, err := query("SELECT groupname || '|' || concurrency || '|' || cpu_rate_limit || '|' || memory_limit || '|' || " +
+		"memory_spill_ratio || '|' || cpuset FROM gp_toolkit.gp_resgroup_config")
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to query resource groups: %w", err)
+	}
+	groups, err := parseResourceGroupConfigs(configs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	name := group.ResourceGroupName()
+	want := resourceGroupConfig{
+		concurrency:      defaultResourceGroupConcurrency,
+		cpuRateLimit:     int(settings.CPURateLimit),
+		memoryLimit:      int(settings.MemoryLimit),
+		memorySpillRatio: int(settings.MemorySpillRatio),
+		cpuSet:           settings.CPUSet,
+	}
+	if settings.Concurrency != nil {
+		want.concurrency = int(*settings.Concurrency)
+	}
+	if want.cpuSet == "" {
+		want.cpuSet = "-1"
+	} else {
+		want.cpuRateLimit = -1
+	}
+
+	// Greenplum rejects limits that total more than 100%, but checking first gives a clearer message
+	totalCPU, totalMemory := want.cpuRateLimit, want.memoryLimit
+	if totalCPU < 0 {
+		totalCPU = 0
+	}
+	for otherName, other := range groups {
+		if otherName != name {
+			if other.cpuRateLimit > 0 {
+				totalCPU += other.cpuRateLimit
+			}
+			totalMemory += other.memoryLimit
+		}
+	}
+	if totalCPU > 100 {
+		return fail(fmt.Sprintf("cpuRateLimit of all resource groups would total %d%%, more than 100%%", totalCPU))
+	}
+	if totalMemory > 100 {
+		return fail(fmt.Sprintf("memoryLimit of all resource groups would total %d%%, more than 100%%", totalMemory))
+	}
+
+	quotedName := pq.QuoteIdentifier(name)
+	var statements, drift []string
+	current, exists := groups[name]
+	if !exists {
+		cpu := "CPU_RATE_LIMIT=" + strconv.Itoa(want.cpuRateLimit)
+		if want.cpuRateLimit < 0 {
+			cpu = "CPUSET=" + pq.QuoteLiteral(want.cpuSet)
+		}
+		statements = append(statements, fmt.Sprintf("CREATE RESOURCE GROUP %s WITH (CONCURRENCY=%d, %s, MEMORY_LIMIT=%d, MEMORY_SPILL_RATIO=%d)",
+			quotedName, want.concurrency, cpu, want.memoryLimit, want.memorySpillRatio))
+	} else {
+		// A setting that differs from the spec, while the spec of that setting is unchanged since it was
+		// last applied, was changed outside of this resource
+		applied := status.AppliedSettings
+		type change struct {
+			setting, current, want string
+			specChanged            bool
+		}
+		changes := []change{{
+			"CONCURRENCY", strconv.Itoa(current.concurrency), strconv.Itoa(want.concurrency),
+			applied == nil || !equality.Semantic.DeepEqual(applied.Concurrency, settings.Concurrency),
+		}}
+		if want.cpuRateLimit < 0 {
+			changes = append(changes, change{
+				"CPUSET", pq.QuoteLiteral(current.cpuSet), pq.QuoteLiteral(want.cpuSet),
+				applied == nil || applied.CPUSet != settings.CPUSet,
+			})
+		} else {
+			changes = append(changes, change{
+				"CPU_RATE_LIMIT", strconv.Itoa(current.cpuRateLimit), strconv.Itoa(want.cpuRateLimit),
+				applied == nil || applied.CPURateLimit != settings.CPURateLimit,
+			})
+		}
+		changes = append(changes, change{
+			"MEMORY_LIMIT", strconv.Itoa(current.memoryLimit), strconv.Itoa(want.memoryLimit),
+			applied == nil || applied.MemoryLimit != settings.MemoryLimit,
+		}, change{
+			"MEMORY_SPILL_RATIO", strconv.Itoa(current.memorySpillRatio), strconv.Itoa(want.memorySpillRatio),
+			applied == nil || applied.MemorySpillRatio != settings.MemorySpillRatio,
+		})
+		for _, c := range changes {
+			if c.current == c.want {
+				continue
+			}
+			statements = append(statements, "ALTER RESOURCE GROUP "+quotedName+" SET "+c.setting+" "+c.want)
+			if !c.specChanged {
+				drift = append(drift, fmt.Sprintf("%s was %s, set back to %s", c.setting, c.current, c.want))
+			}
+		}
+	}
+	if len(drift) > 0 {
+		log.Info("resource group was changed outside of its GreenplumResourceGroup", "drift", drift)
+	}
+
+	// Resource group statements cannot run in a transaction, so each statement is run on its own
+	for _, sql := range statements {
+		if _, err := query(sql); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile resource group: %w", err)
+		}
+		log.Info("reconciled resource group", "statement", sql)
+	}
+
+	status.Phase = greenplumv1beta1.GreenplumResourceGroupPhaseCreated
+	status.Message = ""
+	status.AppliedSettings = settings.DeepCopy()
+	status.Drift = drift
+	return ctrl.Result{RequeueAfter: resourceGroupDriftCheckInterval}, nil
+}
+
+func parseResourceGroupConfigs(output string) (map[string]resourceGroupConfig, error) {
+	groups := map[string]resourceGroupConfig{}
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected resource group query output: %q", line)
+		}
+		var numbers [4]int
+		for i := range numbers {
+			n, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected resource group query output: %q", line)
+			}
+			numbers[i] = n
+		}
+		groups[fields[0]] = resourceGroupConfig{
+			concurrency:      numbers[0],
+			cpuRateLimit:     numbers[1],
+			memoryLimit:      numbers[2],
+			memorySpillRatio: numbers[3],
+			cpuSet:           fields[5],
+		}
+	}
+	return groups, nil
+}
+
+// resourceGroupsForCluster enqueues the resource groups of a cluster, so that they are revisited when it becomes Running.
+func (r *GreenplumResourceGroupReconciler) resourceGroupsForCluster(cluster client.Object) []reconcile.Request {
+	var groups greenplumv1beta1.GreenplumResourceGroupList
+	if err := r.List(context.Background(), &groups, client.InNamespace(cluster.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list GreenplumResourceGroups", "namespace", cluster.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, group := range groups.Items {
+		if group.Spec.ClusterName == cluster.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: group.Namespace, Name: group.Name},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *GreenplumResourceGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&greenplumv1beta1.GreenplumResourceGroup{}).
+		Watches(&source.Kind{Type: &greenplumv1.GreenplumCluster{}}, handler.EnqueueRequestsFromMapFunc(r.resourceGroupsForCluster)).
+		Complete(r)
+}
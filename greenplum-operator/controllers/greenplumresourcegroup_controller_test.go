@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	fakeExecutor "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("GreenplumResourceGroup controller", func() {
+	const builtinGroups = "default_group|20|30|0|80|-1\nadmin_group|10|10|10|80|-1\n"
+
+	var (
+		ctx             context.Context
+		logBuf          *gbytes.Buffer
+		fakePodExec     *fakeExecutor.PodExec
+		fakeQuerier     *fakequerier.Querier
+		groupReconciler *GreenplumResourceGroupReconciler
+		group           *v1beta1.GreenplumResourceGroup
+		cluster         *greenplumv1.GreenplumCluster
+		groupRequest    = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: "etl"},
+		}
+	)
+
+	getGroup := func() *v1beta1.GreenplumResourceGroup {
+		var current v1beta1.GreenplumResourceGroup
+		Expect(reactiveClient.Get(ctx, groupRequest.NamespacedName, &current)).To(Succeed())
+		return &current
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		fakeQuerier = &fakequerier.Querier{}
+		fakePodExec = &fakeExecutor.PodExec{
+			CommandStdout: map[string]string{"gp_resgroup_config": builtinGroups},
+		}
+		groupReconciler = &GreenplumResourceGroupReconciler{
+			Client:  reactiveClient,
+			Log:     gplog.ForTest(logBuf),
+			PodExec: fakePodExec,
+			Querier: fakeQuerier,
+		}
+		group = &v1beta1.GreenplumResourceGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "etl"},
+			Spec: v1beta1.GreenplumResourceGroupSpec{
+				ClusterName: "my-greenplum",
+				GreenplumResourceGroupSettings: v1beta1.GreenplumResourceGroupSettings{
+					CPURateLimit: 20,
+					MemoryLimit:  30,
+				},
+			},
+		}
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+			Status:     greenplumv1.GreenplumClusterStatus{Phase: greenplumv1.GreenplumClusterPhaseRunning},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, group)).To(Succeed())
+	})
+
+	When("the cluster is running", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+
+		It("creates the resource group", func() {
+			result, err := groupReconciler.Reconcile(ctx, groupRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(resourceGroupDriftCheckInterval))
+			Expect(fakePodExec.CalledPodName).To(Equal("master-0"))
+			Expect(fakePodExec.RecordedCommands).To(HaveLen(2))
+			Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(
+				`CREATE RESOURCE GROUP "etl" WITH (CONCURRENCY=20, CPU_RATE_LIMIT=20, MEMORY_LIMIT=30, MEMORY_SPILL_RATIO=0)`))
+			Expect(getGroup().Status).To(Equal(v1beta1.GreenplumResourceGroupStatus{
+				Phase:           v1beta1.GreenplumResourceGroupPhaseCreated,
+				AppliedSettings: &group.Spec.GreenplumResourceGroupSettings,
+			}))
+		})
+
+		When("the resource group uses a cpuset", func() {
+			BeforeEach(func() {
+				group.Spec.CPURateLimit = 0
+				group.Spec.CPUSet = "1-3"
+			})
+			It("creates the resource group with the cpuset", func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(
+					`CREATE RESOURCE GROUP "etl" WITH (CONCURRENCY=20, CPUSET='1-3', MEMORY_LIMIT=30, MEMORY_SPILL_RATIO=0)`))
+			})
+		})
+
+		When("the resource group exists with other settings", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandStdout["gp_resgroup_config"] = builtinGroups + "etl|5|10|30|80|-1\n"
+			})
+			It("alters the resource group", func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(4))
+				Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(`ALTER RESOURCE GROUP "etl" SET CONCURRENCY 20`))
+				Expect(fakePodExec.RecordedCommands[2]).To(HaveSuffix(`ALTER RESOURCE GROUP "etl" SET CPU_RATE_LIMIT 20`))
+				Expect(fakePodExec.RecordedCommands[3]).To(HaveSuffix(`ALTER RESOURCE GROUP "etl" SET MEMORY_SPILL_RATIO 0`))
+				Expect(getGroup().Status.Drift).To(BeEmpty())
+			})
+
+			When("the settings were applied before", func() {
+				BeforeEach(func() {
+					group.Status = v1beta1.GreenplumResourceGroupStatus{
+						Phase:           v1beta1.GreenplumResourceGroupPhaseCreated,
+						AppliedSettings: group.Spec.GreenplumResourceGroupSettings.DeepCopy(),
+					}
+				})
+				It("sets them back and reports the drift", func() {
+					_, err := groupReconciler.Reconcile(ctx, groupRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakePodExec.RecordedCommands).To(HaveLen(4))
+					Expect(getGroup().Status.Drift).To(Equal([]string{
+						"CONCURRENCY was 5, set back to 20",
+						"CPU_RATE_LIMIT was 10, set back to 20",
+						"MEMORY_SPILL_RATIO was 80, set back to 0",
+					}))
+					Expect(logBuf).To(gbytes.Say("resource group was changed outside of its GreenplumResourceGroup"))
+				})
+			})
+		})
+
+		When("the resource group is up to date", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandStdout["gp_resgroup_config"] = builtinGroups + "etl|20|20|30|0|-1\n"
+			})
+			It("does not change it", func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(1))
+				Expect(getGroup().Status.Phase).To(Equal(v1beta1.GreenplumResourceGroupPhaseCreated))
+			})
+		})
+
+		When("the limits of all resource groups would total more than 100%", func() {
+			BeforeEach(func() {
+				group.Spec.CPURateLimit = 61
+			})
+			It("fails without creating the resource group", func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(1))
+				Expect(getGroup().Status).To(Equal(v1beta1.GreenplumResourceGroupStatus{
+					Phase:   v1beta1.GreenplumResourceGroupPhaseFailed,
+					Message: "cpuRateLimit of all resource groups would total 101%, more than 100%",
+				}))
+			})
+		})
+
+		When("both cpuRateLimit and cpuSet are set", func() {
+			BeforeEach(func() {
+				group.Spec.CPUSet = "1"
+			})
+			It("fails", func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+				Expect(getGroup().Status).To(Equal(v1beta1.GreenplumResourceGroupStatus{
+					Phase:   v1beta1.GreenplumResourceGroupPhaseFailed,
+					Message: "exactly one of cpuRateLimit and cpuSet must be set",
+				}))
+			})
+		})
+
+		When("creating the resource group fails", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandErrors = map[string]string{"CREATE RESOURCE GROUP": "ERROR:  resource group is disabled"}
+			})
+			It("returns the error and records it in the status", func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).To(MatchError("failed to reconcile resource group: ERROR:  resource group is disabled: ERROR:  resource group is disabled"))
+				Expect(getGroup().Status).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+					"Phase":   Equal(v1beta1.GreenplumResourceGroupPhasePending),
+					"Message": Equal(err.Error()),
+				}))
+			})
+		})
+
+		When("the deletion policy is Delete and the GreenplumResourceGroup is deleted", func() {
+			BeforeEach(func() {
+				group.Spec.DeletionPolicy = v1beta1.GreenplumDeletionPolicyDelete
+			})
+			JustBeforeEach(func() {
+				_, err := groupReconciler.Reconcile(ctx, groupRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getGroup().Finalizers).To(ConsistOf(DropResourceGroupFinalizer))
+				Expect(reactiveClient.Delete(ctx, getGroup())).To(Succeed())
+				fakePodExec.RecordedCommands = nil
+			})
+
+			When("the resource group exists", func() {
+				BeforeEach(func() {
+					fakePodExec.CommandStdout["FROM pg_resgroup WHERE"] = "1\n"
+				})
+				It("drops the resource group", func() {
+					_, err := groupReconciler.Reconcile(ctx, groupRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakePodExec.RecordedCommands).To(HaveLen(2))
+					Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(`DROP RESOURCE GROUP "etl"`))
+					err = reactiveClient.Get(ctx, groupRequest.NamespacedName, &v1beta1.GreenplumResourceGroup{})
+					Expect(apierrs.IsNotFound(err)).To(BeTrue(), "expected NotFound, got %v", err)
+				})
+			})
+
+			When("the resource group does not exist", func() {
+				It("removes the finalizer without dropping", func() {
+					_, err := groupReconciler.Reconcile(ctx, groupRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakePodExec.RecordedCommands).NotTo(ContainElement(ContainSubstring("DROP")))
+					err = reactiveClient.Get(ctx, groupRequest.NamespacedName, &v1beta1.GreenplumResourceGroup{})
+					Expect(apierrs.IsNotFound(err)).To(BeTrue(), "expected NotFound, got %v", err)
+				})
+			})
+		})
+	})
+
+	When("the cluster is not running", func() {
+		BeforeEach(func() {
+			cluster.Status.Phase = greenplumv1.GreenplumClusterPhasePending
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+		It("waits for the cluster", func() {
+			result, err := groupReconciler.Reconcile(ctx, groupRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(clusterRequeueDelay))
+			Expect(getGroup().Status).To(Equal(v1beta1.GreenplumResourceGroupStatus{
+				Phase:   v1beta1.GreenplumResourceGroupPhasePending,
+				Message: "waiting for GreenplumCluster to be Running",
+			}))
+		})
+	})
+
+	Describe("resourceGroupsForCluster", func() {
+		It("enqueues the resource groups of the cluster", func() {
+			Expect(groupReconciler.resourceGroupsForCluster(cluster)).To(ConsistOf(groupRequest))
+		})
+	})
+})
@@ -0,0 +1,260 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/lib/pq"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const DropRoleFinalizer = "droprole.greenplumrole.pivotal.io"
+
+// GreenplumRoleReconciler reconciles a GreenplumRole object
+type GreenplumRoleReconciler struct {
+	client.Client
+	Log     logr.Logger
+	PodExec executor.PodExecInterface
+	Querier querier.GreenplumQuerier
+}
+
+var _ client.Client = &GreenplumRoleReconciler{}
+
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumroles,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumroles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+
+func (r *GreenplumRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("greenplumrole", req.NamespacedName)
+
+	var role greenplumv1beta1.GreenplumRole
+	if err := r.Get(ctx, req.NamespacedName, &role); err != nil {
+		if apierrs.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to fetch GreenplumRole")
+	}
+
+	if !role.DeletionTimestamp.IsZero() {
+		dropRole := "DROP ROLE IF EXISTS " + pq.QuoteIdentifier(role.RoleName())
+		return finalizeDrop(ctx, r, r.PodExec, r.Querier, log, &role, DropRoleFinalizer, role.Spec.ClusterName, dropRole)
+	}
+
+	newRole := role.DeepCopy()
+	if role.Spec.DeletionPolicy == greenplumv1beta1.GreenplumDeletionPolicyDelete {
+		controllerutil.AddFinalizer(newRole, DropRoleFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(newRole, DropRoleFinalizer)
+	}
+	result, err := r.reconcileRole(ctx, log, newRole)
+	if err != nil {
+		newRole.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(newRole.Finalizers, role.Finalizers) ||
+		!equality.Semantic.DeepEqual(newRole.Status, role.Status) {
+		if patchErr := r.Patch(ctx, newRole, client.MergeFrom(&role)); patchErr != nil {
+			log.Error(patchErr, "update failed")
+			return ctrl.Result{}, patchErr
+		}
+	}
+
+	return result, err
+}
+
+// reconcileRole records the progress of the role in its status. A returned error is retried.
+func (r *GreenplumRoleReconciler) reconcileRole(ctx context.Context, log logr.Logger, role *greenplumv1beta1.GreenplumRole) (ctrl.Result, error) {
+	status := &role.Status
+	pending := func(message string) {
+		if status.Phase != greenplumv1beta1.GreenplumRolePhaseCreated {
+			status.Phase = greenplumv1beta1.GreenplumRolePhasePending
+			status.Message = message
+		}
+	}
+
+	_, activeMaster, waiting, err := activeMasterOf(ctx, r, r.Querier, role.Namespace, role.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if waiting != "" {
+		pending(waiting)
+		return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+	}
+	if status.Phase == "" {
+		status.Phase = greenplumv1beta1.GreenplumRolePhasePending
+	}
+
+	name := role.RoleName()
+	var passwordHash string
+	if role.Spec.PasswordSecret != nil {
+		password, err := r.getPassword(ctx, role)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if password == "" {
+			pending(fmt.Sprintf("waiting for key %s in Secret %s", role.Spec.PasswordSecret.Key, role.Spec.PasswordSecret.Name))
+			return ctrl.Result{}, nil
+		}
+		passwordHash = executor.MD5Password(name, password)
+	}
+
+	query := func(sql string) (string, error) {
+		return executor.Query(ctx, r.PodExec, role.Namespace, activeMaster, "gpadmin", sql)
+	}
+	existing, err := query("SELECT rolcanlogin || '|' || rolconnlimit || '|' || coalesce(rolpassword, '') || '|' || " +
+		"coalesce((SELECT rsgname FROM pg_resgroup WHERE oid = rolresgroup), '') FROM pg_authid WHERE rolname = " + pq.QuoteLiteral(name))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to query role: %w", err)
+	}
+	var canLogin, connectionLimit, currentPassword, resourceGroup string
+	if existing != "" {
+		fields := strings.SplitN(existing, "|", 4)
+		if len(fields) != 4 {
+			return ctrl.Result{}, fmt.Errorf("unexpected role query output: %q", existing)
+		}
+		canLogin, connectionLimit, currentPassword, resourceGroup = fields[0], fields[1], fields[2], fields[3]
+	}
+	currentMemberOf, err := query("SELECT g.rolname FROM pg_auth_members m JOIN pg_roles g ON g.oid = m.roleid " +
+		"JOIN pg_roles u ON u.oid = m.member WHERE u.rolname = " + pq.QuoteLiteral(name))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to query role membership: %w", err)
+	}
+
+	// statements to run, with the form of each that is safe to log
+	type statement struct{ sql, logged string }
+	var statements []statement
+	add := func(sql string) {
+		statements = append(statements, statement{sql: sql, logged: sql})
+	}
+	quotedName := pq.QuoteIdentifier(name)
+
+	if existing == "" {
+		add("CREATE ROLE " + quotedName)
+	}
+	wantLogin := role.Spec.Login == nil || *role.Spec.Login
+	wantLimit := int32(-1)
+	if role.Spec.ConnectionLimit != nil {
+		wantLimit = *role.Spec.ConnectionLimit
+	}
+	if canLogin != strconv.FormatBool(wantLogin) || connectionLimit != strconv.Itoa(int(wantLimit)) {
+		login := "NOLOGIN"
+		if wantLogin {
+			login = "LOGIN"
+		}
+		add("ALTER ROLE " + quotedName + " WITH " + login + " CONNECTION LIMIT " + strconv.Itoa(int(wantLimit)))
+	}
+	if passwordHash != "" && passwordHash != currentPassword {
+		statements = append(statements, statement{
+			sql:    "ALTER ROLE " + quotedName + " PASSWORD " + pq.QuoteLiteral(passwordHash),
+			logged: "ALTER ROLE " + quotedName + " PASSWORD ...",
+		})
+	}
+	if role.Spec.ResourceGroup != "" && role.Spec.ResourceGroup != resourceGroup {
+		add("ALTER ROLE " + quotedName + " RESOURCE GROUP " + pq.QuoteIdentifier(role.Spec.ResourceGroup))
+	}
+
+	isMember := map[string]bool{}
+	for _, group := range strings.Split(currentMemberOf, "\n") {
+		if group != "" {
+			isMember[group] = true
+		}
+	}
+	wantMember := map[string]bool{}
+	for _, group := range role.Spec.MemberOf {
+		wantMember[group] = true
+		if !isMember[group] {
+			add("GRANT " + pq.QuoteIdentifier(group) + " TO " + quotedName)
+		}
+	}
+	for _, group := range strings.Split(currentMemberOf, "\n") {
+		if group != "" && !wantMember[group] {
+			add("REVOKE " + pq.QuoteIdentifier(group) + " FROM " + quotedName)
+		}
+	}
+
+	for _, s := range statements {
+		if _, err := query(s.sql); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile role: %s: %w", s.logged, err)
+		}
+		log.Info("reconciled role", "statement", s.logged)
+	}
+
+	status.Phase = greenplumv1beta1.GreenplumRolePhaseCreated
+	status.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// getPassword returns the password of the role, or "" if its Secret or key does not exist yet.
+func (r *GreenplumRoleReconciler) getPassword(ctx context.Context, role *greenplumv1beta1.GreenplumRole) (string, error) {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: role.Namespace, Name: role.Spec.PasswordSecret.Name}
+	if err := r.Get(ctx, secretKey, &secret); err != nil {
+		if apierrs.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "unable to fetch password Secret")
+	}
+	return string(secret.Data[role.Spec.PasswordSecret.Key]), nil
+}
+
+// rolesForCluster enqueues the roles of a cluster, so that they are revisited when it becomes Running.
+func (r *GreenplumRoleReconciler) rolesForCluster(cluster client.Object) []reconcile.Request {
+	return r.rolesMatching(cluster.GetNamespace(), func(role *greenplumv1beta1.GreenplumRole) bool {
+		return role.Spec.ClusterName == cluster.GetName()
+	})
+}
+
+// rolesForSecret enqueues the roles whose password is in a Secret, so that a changed password is applied.
+func (r *GreenplumRoleReconciler) rolesForSecret(secret client.Object) []reconcile.Request {
+	return r.rolesMatching(secret.GetNamespace(), func(role *greenplumv1beta1.GreenplumRole) bool {
+		return role.Spec.PasswordSecret != nil && role.Spec.PasswordSecret.Name == secret.GetName()
+	})
+}
+
+func (r *GreenplumRoleReconciler) rolesMatching(namespace string, matches func(*greenplumv1beta1.GreenplumRole) bool) []reconcile.Request {
+	var roles greenplumv1beta1.GreenplumRoleList
+	if err := r.List(context.Background(), &roles, client.InNamespace(namespace)); err != nil {
+		r.Log.Error(err, "unable to list GreenplumRoles", "namespace", namespace)
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range roles.Items {
+		if matches(&roles.Items[i]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: roles.Items[i].Namespace, Name: roles.Items[i].Name},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *GreenplumRoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&greenplumv1beta1.GreenplumRole{}).
+		Watches(&source.Kind{Type: &greenplumv1.GreenplumCluster{}}, handler.EnqueueRequestsFromMapFunc(r.rolesForCluster)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.rolesForSecret)).
+		Complete(r)
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	fakeExecutor "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("GreenplumRole controller", func() {
+	const oldPasswordHash = "md500000000000000000000000000000000"
+
+	var (
+		ctx            context.Context
+		logBuf         *gbytes.Buffer
+		fakePodExec    *fakeExecutor.PodExec
+		fakeQuerier    *fakequerier.Querier
+		roleReconciler *GreenplumRoleReconciler
+		role           *v1beta1.GreenplumRole
+		cluster        *greenplumv1.GreenplumCluster
+		roleRequest    = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: "sales-app"},
+		}
+	)
+
+	getRole := func() *v1beta1.GreenplumRole {
+		var current v1beta1.GreenplumRole
+		Expect(reactiveClient.Get(ctx, roleRequest.NamespacedName, &current)).To(Succeed())
+		return &current
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		fakeQuerier = &fakequerier.Querier{}
+		fakePodExec = &fakeExecutor.PodExec{}
+		roleReconciler = &GreenplumRoleReconciler{
+			Client:  reactiveClient,
+			Log:     gplog.ForTest(logBuf),
+			PodExec: fakePodExec,
+			Querier: fakeQuerier,
+		}
+		role = &v1beta1.GreenplumRole{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "sales-app"},
+			Spec: v1beta1.GreenplumRoleSpec{
+				ClusterName: "my-greenplum",
+				Name:        "sales_app",
+			},
+		}
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+			Status:     greenplumv1.GreenplumClusterStatus{Phase: greenplumv1.GreenplumClusterPhaseRunning},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, role)).To(Succeed())
+	})
+
+	When("the cluster is running", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+
+		It("creates a login role", func() {
+			_, err := roleReconciler.Reconcile(ctx, roleRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakePodExec.CalledPodName).To(Equal("master-0"))
+			Expect(fakePodExec.RecordedCommands).To(HaveLen(4))
+			Expect(fakePodExec.RecordedCommands[0]).To(HaveSuffix("FROM pg_authid WHERE rolname = 'sales_app'"))
+			Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix("WHERE u.rolname = 'sales_app'"))
+			Expect(fakePodExec.RecordedCommands[2]).To(HaveSuffix(`CREATE ROLE "sales_app"`))
+			Expect(fakePodExec.RecordedCommands[3]).To(HaveSuffix(`ALTER ROLE "sales_app" WITH LOGIN CONNECTION LIMIT -1`))
+			Expect(getRole().Status).To(Equal(v1beta1.GreenplumRoleStatus{Phase: v1beta1.GreenplumRolePhaseCreated}))
+		})
+
+		When("the role has a password, resource group and memberships", func() {
+			BeforeEach(func() {
+				noLogin := false
+				var limit int32 = 5
+				role.Spec.Login = &noLogin
+				role.Spec.ConnectionLimit = &limit
+				role.Spec.ResourceGroup = "etl"
+				role.Spec.MemberOf = []string{"readers", "writers"}
+				role.Spec.PasswordSecret = &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "sales-app-password"},
+					Key:                  "password",
+				}
+				secret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "sales-app-password"},
+					Data:       map[string][]byte{"password": []byte("s3cret")},
+				}
+				Expect(reactiveClient.Create(ctx, secret)).To(Succeed())
+				fakePodExec.CommandStdout = map[string]string{
+					"FROM pg_authid":       "true|-1|" + oldPasswordHash + "|default_group\n",
+					"FROM pg_auth_members": "writers\nadmins\n",
+				}
+			})
+
+			It("brings the role up to date", func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(7))
+				Expect(fakePodExec.RecordedCommands[2]).To(HaveSuffix(`ALTER ROLE "sales_app" WITH NOLOGIN CONNECTION LIMIT 5`))
+				Expect(fakePodExec.RecordedCommands[3]).To(HaveSuffix(`ALTER ROLE "sales_app" PASSWORD '` + executor.MD5Password("sales_app", "s3cret") + `'`))
+				Expect(fakePodExec.RecordedCommands[4]).To(HaveSuffix(`ALTER ROLE "sales_app" RESOURCE GROUP "etl"`))
+				Expect(fakePodExec.RecordedCommands[5]).To(HaveSuffix(`GRANT "readers" TO "sales_app"`))
+				Expect(fakePodExec.RecordedCommands[6]).To(HaveSuffix(`REVOKE "admins" FROM "sales_app"`))
+			})
+
+			It("does not log the password", func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(logBuf.Contents())).To(ContainSubstring(`ALTER ROLE \"sales_app\" PASSWORD ...`))
+				Expect(string(logBuf.Contents())).NotTo(ContainSubstring(executor.MD5Password("sales_app", "s3cret")))
+				Expect(string(logBuf.Contents())).NotTo(ContainSubstring("s3cret"))
+			})
+		})
+
+		When("the role is up to date", func() {
+			BeforeEach(func() {
+				role.Spec.MemberOf = []string{"readers"}
+				fakePodExec.CommandStdout = map[string]string{
+					"FROM pg_authid":       "true|-1||default_group\n",
+					"FROM pg_auth_members": "readers\n",
+				}
+			})
+			It("does not change it", func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(2))
+			})
+		})
+
+		When("the password Secret does not exist yet", func() {
+			BeforeEach(func() {
+				role.Spec.PasswordSecret = &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "sales-app-password"},
+					Key:                  "password",
+				}
+			})
+			It("waits for it", func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+				Expect(getRole().Status).To(Equal(v1beta1.GreenplumRoleStatus{
+					Phase:   v1beta1.GreenplumRolePhasePending,
+					Message: "waiting for key password in Secret sales-app-password",
+				}))
+			})
+			It("is enqueued when the Secret changes", func() {
+				secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "sales-app-password"}}
+				Expect(roleReconciler.rolesForSecret(secret)).To(ConsistOf(roleRequest))
+				secret.Name = "other"
+				Expect(roleReconciler.rolesForSecret(secret)).To(BeEmpty())
+			})
+		})
+
+		When("altering the role fails", func() {
+			BeforeEach(func() {
+				role.Spec.ResourceGroup = "missing"
+				fakePodExec.CommandStdout = map[string]string{"FROM pg_authid": "true|-1||default_group\n"}
+				fakePodExec.CommandErrors = map[string]string{"RESOURCE GROUP": `ERROR:  resource group "missing" does not exist`}
+			})
+			It("returns the error and records it in the status", func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).To(MatchError(`failed to reconcile role: ALTER ROLE "sales_app" RESOURCE GROUP "missing": ` +
+					`ERROR:  resource group "missing" does not exist: ERROR:  resource group "missing" does not exist`))
+				Expect(getRole().Status).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+					"Phase":   Equal(v1beta1.GreenplumRolePhasePending),
+					"Message": Equal(err.Error()),
+				}))
+			})
+		})
+
+		When("the deletion policy is Delete and the GreenplumRole is deleted", func() {
+			BeforeEach(func() {
+				role.Spec.DeletionPolicy = v1beta1.GreenplumDeletionPolicyDelete
+			})
+			JustBeforeEach(func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getRole().Finalizers).To(ConsistOf(DropRoleFinalizer))
+				Expect(reactiveClient.Delete(ctx, getRole())).To(Succeed())
+				fakePodExec.RecordedCommands = nil
+			})
+			It("drops the role", func() {
+				_, err := roleReconciler.Reconcile(ctx, roleRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(ConsistOf(HaveSuffix(`DROP ROLE IF EXISTS "sales_app"`)))
+				err = reactiveClient.Get(ctx, roleRequest.NamespacedName, &v1beta1.GreenplumRole{})
+				Expect(apierrs.IsNotFound(err)).To(BeTrue(), "expected NotFound, got %v", err)
+			})
+		})
+	})
+
+	When("the cluster does not exist", func() {
+		It("waits for the cluster", func() {
+			_, err := roleReconciler.Reconcile(ctx, roleRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getRole().Status).To(Equal(v1beta1.GreenplumRoleStatus{
+				Phase:   v1beta1.GreenplumRolePhasePending,
+				Message: "GreenplumCluster my-greenplum not found",
+			}))
+		})
+	})
+
+	Describe("rolesForCluster", func() {
+		It("enqueues the roles of the cluster", func() {
+			Expect(roleReconciler.rolesForCluster(cluster)).To(ConsistOf(roleRequest))
+		})
+	})
+})
@@ -0,0 +1,397 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/volumesnapshot"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ResumeClusterFinalizer is on a GreenplumSnapshot while it has its cluster stopped
+const ResumeClusterFinalizer = "resumecluster.greenplumsnapshot.pivotal.io"
+
+const (
+	// How often to check whether the snapshots are cut while the cluster is stopped
+	snapshotCutPollInterval = 5 * time.Second
+	// How often to check whether the snapshots are ready to use once the cluster is running again
+	snapshotReadyPollInterval = 30 * time.Second
+	// How long the cluster may stay stopped waiting for the snapshots to be cut
+	snapshotQuiesceTimeout = 5 * time.Minute
+)
+
+// GreenplumSnapshotReconciler reconciles a GreenplumSnapshot object
+type GreenplumSnapshotReconciler struct {
+	client.Client
+	Log     logr.Logger
+	PodExec executor.PodExecInterface
+	Querier querier.GreenplumQuerier
+}
+
+var _ client.Client = &GreenplumSnapshotReconciler{}
+
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumsnapshots,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumsnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+
+func (r *GreenplumSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("greenplumsnapshot", req.NamespacedName)
+
+	var snapshot greenplumv1beta1.GreenplumSnapshot
+	if err := r.Get(ctx, req.NamespacedName, &snapshot); err != nil {
+		if apierrs.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to fetch GreenplumSnapshot")
+	}
+
+	newSnapshot := snapshot.DeepCopy()
+	var result ctrl.Result
+	var err error
+	if snapshot.DeletionTimestamp.IsZero() {
+		result, err = r.reconcileSnapshot(ctx, log, newSnapshot)
+	} else {
+		err = r.resumeCluster(ctx, log, newSnapshot)
+	}
+	if err != nil {
+		newSnapshot.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(newSnapshot.Status, snapshot.Status) ||
+		!equality.Semantic.DeepEqual(newSnapshot.Finalizers, snapshot.Finalizers) {
+		if patchErr := r.Patch(ctx, newSnapshot, client.MergeFrom(&snapshot)); patchErr != nil && !apierrs.IsNotFound(patchErr) {
+			log.Error(patchErr, "update failed")
+			return ctrl.Result{}, patchErr
+		}
+	}
+
+	return result, err
+}
+
+// reconcileSnapshot moves the snapshot through its phases, recording its progress in its status.
+// A returned error is retried.
+func (r *GreenplumSnapshotReconciler) reconcileSnapshot(ctx context.Context, log logr.Logger, snapshot *greenplumv1beta1.GreenplumSnapshot) (ctrl.Result, error) {
+	switch snapshot.Status.Phase {
+	case "", greenplumv1beta1.GreenplumSnapshotPhasePending:
+		return r.quiesceAndSnapshot(ctx, log, snapshot)
+	case greenplumv1beta1.GreenplumSnapshotPhaseQuiesced, greenplumv1beta1.GreenplumSnapshotPhaseSnapshotting:
+		return r.checkVolumeSnapshots(ctx, log, snapshot)
+	}
+	return ctrl.Result{}, nil
+}
+
+// quiesceAndSnapshot records the cluster's configuration and the VolumeSnapshots to take of its PVCs.
+// The finalizer is saved first, so that the cluster is started again even if the snapshot is deleted.
+// The cluster is stopped by checkVolumeSnapshots once the Quiesced phase is saved, so that a later
+// reconcile knows to start it again even if the operator stops while it is stopped.
+func (r *GreenplumSnapshotReconciler) quiesceAndSnapshot(ctx context.Context, log logr.Logger, snapshot *greenplumv1beta1.GreenplumSnapshot) (ctrl.Result, error) {
+	status := &snapshot.Status
+	status.Phase = greenplumv1beta1.GreenplumSnapshotPhasePending
+
+	cluster, activeMaster, waiting, err := activeMasterOf(ctx, r, r.Querier, snapshot.Namespace, snapshot.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if waiting == "" {
+		operation, err := greenplumcluster.RunningOperation(ctx, r, cluster)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if operation != "" {
+			waiting = "waiting for cluster operation to finish: " + operation
+		}
+	}
+	if waiting != "" {
+		status.Message = waiting
+		return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(snapshot, ResumeClusterFinalizer) {
+		controllerutil.AddFinalizer(snapshot, ResumeClusterFinalizer)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	segments, err := r.segmentConfiguration(ctx, snapshot.Namespace, activeMaster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for _, segment := range segments {
+		if segment.Status != "u" {
+			return r.fail(ctx, log, snapshot, fmt.Sprintf("segment with dbid %d is down", segment.DBID))
+		}
+	}
+
+	var pvcList corev1.PersistentVolumeClaimList
+	if err := r.List(ctx, &pvcList, client.InNamespace(snapshot.Namespace),
+		client.MatchingLabels{"greenplum-cluster": snapshot.Spec.ClusterName}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to list PVCs: %w", err)
+	}
+	if len(pvcList.Items) == 0 {
+		return r.fail(ctx, log, snapshot, "GreenplumCluster "+snapshot.Spec.ClusterName+" has no PVCs")
+	}
+	sort.Slice(pvcList.Items, func(i, j int) bool { return pvcList.Items[i].Name < pvcList.Items[j].Name })
+
+	now := metav1.Now()
+	status.Phase = greenplumv1beta1.GreenplumSnapshotPhaseQuiesced
+	status.Message = ""
+	status.SnapshotTime = &now
+	status.StoppedMaster = activeMaster
+	status.SegmentConfiguration = segments
+	status.GreenplumMajorVersion = greenplumcluster.SupportedGreenplumMajorVersion
+	status.VolumeSnapshots = nil
+	for _, pvc := range pvcList.Items {
+		status.VolumeSnapshots = append(status.VolumeSnapshots, greenplumv1beta1.GreenplumSnapshotVolume{
+			PersistentVolumeClaimName: pvc.Name,
+			VolumeSnapshotName:        snapshot.Name + "-" + pvc.Name,
+		})
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// createVolumeSnapshots creates the VolumeSnapshots listed in the status that do not exist yet
+func (r *GreenplumSnapshotReconciler) createVolumeSnapshots(ctx context.Context, snapshot *greenplumv1beta1.GreenplumSnapshot) error {
+	for _, volume := range snapshot.Status.VolumeSnapshots {
+		pvcName := volume.PersistentVolumeClaimName
+		volumeSnapshot := &volumesnapshot.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: snapshot.Namespace,
+				Name:      volume.VolumeSnapshotName,
+				Labels: map[string]string{
+					"greenplum-cluster":  snapshot.Spec.ClusterName,
+					"greenplum-snapshot": snapshot.Name,
+				},
+			},
+			Spec: volumesnapshot.VolumeSnapshotSpec{
+				Source: volumesnapshot.VolumeSnapshotSource{PersistentVolumeClaimName: &pvcName},
+			},
+		}
+		if snapshot.Spec.VolumeSnapshotClassName != "" {
+			volumeSnapshot.Spec.VolumeSnapshotClassName = &snapshot.Spec.VolumeSnapshotClassName
+		}
+		if err := controllerutil.SetControllerReference(snapshot, volumeSnapshot, r.Scheme()); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, volumeSnapshot); err != nil && !apierrs.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create VolumeSnapshot %s: %s", volumeSnapshot.Name, err)
+		}
+	}
+	return nil
+}
+
+// ensureStopped checkpoints and stops the cluster if its master still accepts connections.
+// If gpstop fails, the cluster is started again and the snapshot goes back to Pending to be retried.
+// It also goes back to Pending, without stopping the cluster, if a gpexpand or gpstop Job started since it was quiesced.
+func (r *GreenplumSnapshotReconciler) ensureStopped(ctx context.Context, log logr.Logger, snapshot *greenplumv1beta1.GreenplumSnapshot) error {
+	status := &snapshot.Status
+	if !r.masterAcceptsConnections(ctx, snapshot.Namespace, status.StoppedMaster) {
+		return nil
+	}
+	var cluster greenplumv1.GreenplumCluster
+	if err := r.Get(ctx, types.NamespacedName{Namespace: snapshot.Namespace, Name: snapshot.Spec.ClusterName}, &cluster); err != nil {
+		return fmt.Errorf("unable to fetch GreenplumCluster: %w", err)
+	}
+	operation, err := greenplumcluster.RunningOperation(ctx, r, &cluster)
+	if err != nil {
+		return err
+	}
+	if operation != "" {
+		*status = greenplumv1beta1.GreenplumSnapshotStatus{Phase: greenplumv1beta1.GreenplumSnapshotPhasePending}
+		return fmt.Errorf("not stopping cluster: %s", operation)
+	}
+	// Flushing dirty buffers while the cluster is up keeps the time it is stopped short
+	if _, err := executor.Query(ctx, r.PodExec, snapshot.Namespace, status.StoppedMaster, "gpadmin", "CHECKPOINT"); err != nil {
+		return fmt.Errorf("failed to checkpoint: %w", err)
+	}
+	if err := r.runUtility(ctx, snapshot.Namespace, status.StoppedMaster, "gpstop -a -M fast"); err != nil {
+		stopErr := fmt.Errorf("failed to stop cluster: %w", err)
+		// gpstop may have stopped part of the cluster
+		if err := r.resumeCluster(ctx, log, snapshot); err != nil {
+			return fmt.Errorf("%s, and then %w", stopErr, err)
+		}
+		*status = greenplumv1beta1.GreenplumSnapshotStatus{Phase: greenplumv1beta1.GreenplumSnapshotPhasePending}
+		controllerutil.AddFinalizer(snapshot, ResumeClusterFinalizer)
+		return stopErr
+	}
+	log.Info("stopped cluster for snapshot")
+	return nil
+}
+
+func (r *GreenplumSnapshotReconciler) masterAcceptsConnections(ctx context.Context, namespace, master string) bool {
+	_, err := executor.Query(ctx, r.PodExec, namespace, master, "gpadmin", "SELECT 1")
+	return err == nil
+}
+
+// checkVolumeSnapshots stops the cluster and creates its VolumeSnapshots, starts the cluster again once
+// every snapshot is cut, and marks the GreenplumSnapshot Ready once every snapshot can be restored from.
+func (r *GreenplumSnapshotReconciler) checkVolumeSnapshots(ctx context.Context, log logr.Logger, snapshot *greenplumv1beta1.GreenplumSnapshot) (ctrl.Result, error) {
+	status := &snapshot.Status
+	if status.StoppedMaster != "" {
+		if err := r.ensureStopped(ctx, log, snapshot); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.createVolumeSnapshots(ctx, snapshot); err != nil {
+			return r.fail(ctx, log, snapshot, err.Error())
+		}
+	}
+	allCut, allReady := true, true
+	for i := range status.VolumeSnapshots {
+		volume := &status.VolumeSnapshots[i]
+		var volumeSnapshot volumesnapshot.VolumeSnapshot
+		key := types.NamespacedName{Namespace: snapshot.Namespace, Name: volume.VolumeSnapshotName}
+		if err := r.Get(ctx, key, &volumeSnapshot); err != nil {
+			if apierrs.IsNotFound(err) {
+				return r.fail(ctx, log, snapshot, "VolumeSnapshot "+volume.VolumeSnapshotName+" was deleted")
+			}
+			return ctrl.Result{}, fmt.Errorf("unable to fetch VolumeSnapshot %s: %w", volume.VolumeSnapshotName, err)
+		}
+		vsStatus := volumeSnapshot.Status
+		if vsStatus == nil {
+			vsStatus = &volumesnapshot.VolumeSnapshotStatus{}
+		}
+		if vsStatus.Error != nil && vsStatus.Error.Message != nil {
+			return r.fail(ctx, log, snapshot, fmt.Sprintf("VolumeSnapshot %s failed: %s", volume.VolumeSnapshotName, *vsStatus.Error.Message))
+		}
+		volume.ReadyToUse = vsStatus.ReadyToUse != nil && *vsStatus.ReadyToUse
+		allCut = allCut && vsStatus.CreationTime != nil
+		allReady = allReady && volume.ReadyToUse
+	}
+
+	if status.StoppedMaster != "" {
+		if !allCut {
+			if time.Since(status.SnapshotTime.Time) > snapshotQuiesceTimeout {
+				return r.fail(ctx, log, snapshot, fmt.Sprintf("VolumeSnapshots were not taken within %s", snapshotQuiesceTimeout))
+			}
+			return ctrl.Result{RequeueAfter: snapshotCutPollInterval}, nil
+		}
+		if err := r.resumeCluster(ctx, log, snapshot); err != nil {
+			return ctrl.Result{}, err
+		}
+		status.Phase = greenplumv1beta1.GreenplumSnapshotPhaseSnapshotting
+	}
+
+	if !allReady {
+		return ctrl.Result{RequeueAfter: snapshotReadyPollInterval}, nil
+	}
+	status.Phase = greenplumv1beta1.GreenplumSnapshotPhaseReady
+	status.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// fail marks the snapshot Failed, after starting the cluster if it was stopped
+func (r *GreenplumSnapshotReconciler) fail(ctx context.Context, log logr.Logger, snapshot *greenplumv1beta1.GreenplumSnapshot, message string) (ctrl.Result, error) {
+	if err := r.resumeCluster(ctx, log, snapshot); err != nil {
+		return ctrl.Result{}, err
+	}
+	snapshot.Status.Phase = greenplumv1beta1.GreenplumSnapshotPhaseFailed
+	snapshot.Status.Message = message
+	return ctrl.Result{}, nil
+}
+
+// resumeCluster starts the cluster if this snapshot stopped it, then drops the finalizer.
+// A cluster that is gone or being deleted, or whose master already accepts connections, is left alone.
+func (r *GreenplumSnapshotReconciler) resumeCluster(ctx context.Context, log logr.Logger, snapshot *greenplumv1beta1.GreenplumSnapshot) error {
+	if snapshot.Status.StoppedMaster != "" {
+		var cluster greenplumv1.GreenplumCluster
+		err := r.Get(ctx, types.NamespacedName{Namespace: snapshot.Namespace, Name: snapshot.Spec.ClusterName}, &cluster)
+		if err != nil && !apierrs.IsNotFound(err) {
+			return errors.Wrap(err, "unable to fetch GreenplumCluster")
+		}
+		if err == nil && cluster.DeletionTimestamp.IsZero() && !r.masterAcceptsConnections(ctx, snapshot.Namespace, snapshot.Status.StoppedMaster) {
+			if err := r.runUtility(ctx, snapshot.Namespace, snapshot.Status.StoppedMaster, "gpstart -a"); err != nil {
+				return fmt.Errorf("failed to start cluster: %w", err)
+			}
+			log.Info("started cluster after snapshot")
+		}
+		snapshot.Status.StoppedMaster = ""
+	}
+	controllerutil.RemoveFinalizer(snapshot, ResumeClusterFinalizer)
+	return nil
+}
+
+// runUtility runs a Greenplum management utility, such as gpstop, on a master pod
+func (r *GreenplumSnapshotReconciler) runUtility(ctx context.Context, namespace, master, utility string) error {
+	command := []string{
+		"/bin/bash",
+		"-c",
+		"--",
+		"source /usr/local/greenplum-db/greenplum_path.sh && " + utility,
+	}
+	err := r.PodExec.Execute(ctx, executor.ExecRequest{
+		Namespace: namespace,
+		PodName:   master,
+		Command:   command,
+	})
+	return executor.WithStderr(err)
+}
+
+func (r *GreenplumSnapshotReconciler) segmentConfiguration(ctx context.Context, namespace, activeMaster string) ([]greenplumv1beta1.GreenplumSegmentConfiguration, error) {
+	out, err := executor.Query(ctx, r.PodExec, namespace, activeMaster, "gpadmin",
+		"SELECT dbid || '|' || content || '|' || role || '|' || preferred_role || '|' || mode || '|' || status || '|' || "+
+			"port || '|' || hostname || '|' || address || '|' || datadir FROM gp_segment_configuration ORDER BY dbid")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query segment configuration: %w", err)
+	}
+	var segments []greenplumv1beta1.GreenplumSegmentConfiguration
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 10 {
+			return nil, fmt.Errorf("unexpected segment configuration: %q", line)
+		}
+		var numbers [3]int64
+		for i, field := range []string{fields[0], fields[1], fields[6]} {
+			if numbers[i], err = strconv.ParseInt(field, 10, 32); err != nil {
+				return nil, fmt.Errorf("unexpected segment configuration: %q", line)
+			}
+		}
+		segments = append(segments, greenplumv1beta1.GreenplumSegmentConfiguration{
+			DBID:          int32(numbers[0]),
+			Content:       int32(numbers[1]),
+			Role:          fields[2],
+			PreferredRole: fields[3],
+			Mode:          fields[4],
+			Status:        fields[5],
+			Port:          int32(numbers[2]),
+			Hostname:      fields[7],
+			Address:       fields[8],
+			DataDir:       fields[9],
+		})
+	}
+	return segments, nil
+}
+
+func (r *GreenplumSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// VolumeSnapshots are polled rather than watched, so that the operator starts on clusters
+	// without the CSI snapshot CRDs.
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&greenplumv1beta1.GreenplumSnapshot{}).
+		Complete(r)
+}
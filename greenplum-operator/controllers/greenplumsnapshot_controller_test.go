@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
+	fakeExecutor "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/volumesnapshot"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("GreenplumSnapshot controller", func() {
+	const (
+		segmentConfiguration = "1|-1|p|p|n|u|5432|master-0|master-0|/greenplum/data-1\n" +
+			"2|0|p|p|s|u|40000|segment-a-0|segment-a-0|/greenplum/data\n" +
+			"3|0|m|m|s|u|50000|segment-b-0|segment-b-0|/greenplum/mirror/data\n"
+		gpstop        = "gpstop -a -M fast"
+		gpstart       = "gpstart -a"
+		masterIsAlive = "SELECT 1"
+	)
+
+	var (
+		ctx                context.Context
+		logBuf             *gbytes.Buffer
+		fakePodExec        *fakeExecutor.PodExec
+		fakeQuerier        *fakequerier.Querier
+		snapshotReconciler *GreenplumSnapshotReconciler
+		snapshot           *v1beta1.GreenplumSnapshot
+		cluster            *greenplumv1.GreenplumCluster
+		snapshotRequest    = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: "nightly"},
+		}
+	)
+
+	getSnapshot := func() *v1beta1.GreenplumSnapshot {
+		var current v1beta1.GreenplumSnapshot
+		Expect(reactiveClient.Get(ctx, snapshotRequest.NamespacedName, &current)).To(Succeed())
+		return &current
+	}
+
+	commandsContaining := func(substr string) []string {
+		var commands []string
+		for _, command := range fakePodExec.RecordedCommands {
+			if strings.Contains(command, substr) {
+				commands = append(commands, command)
+			}
+		}
+		return commands
+	}
+
+	createPVC := func(name string) {
+		Expect(reactiveClient.Create(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test-ns",
+			Name:      name,
+			Labels:    map[string]string{"greenplum-cluster": "my-greenplum"},
+		}})).To(Succeed())
+	}
+
+	createVolumeSnapshot := func(name string, status *volumesnapshot.VolumeSnapshotStatus) {
+		Expect(reactiveClient.Create(ctx, &volumesnapshot.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: name},
+			Status:     status,
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		fakeQuerier = &fakequerier.Querier{}
+		fakePodExec = &fakeExecutor.PodExec{
+			CommandStdout: map[string]string{"ORDER BY dbid": segmentConfiguration},
+		}
+		snapshotReconciler = &GreenplumSnapshotReconciler{
+			Client:  reactiveClient,
+			Log:     gplog.ForTest(logBuf),
+			PodExec: fakePodExec,
+			Querier: fakeQuerier,
+		}
+		snapshot = &v1beta1.GreenplumSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "nightly"},
+			Spec: v1beta1.GreenplumSnapshotSpec{
+				ClusterName:             "my-greenplum",
+				VolumeSnapshotClassName: "csi-hostpath-snapclass",
+			},
+		}
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+			Status:     greenplumv1.GreenplumClusterStatus{Phase: greenplumv1.GreenplumClusterPhaseRunning},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, snapshot)).To(Succeed())
+	})
+
+	When("the cluster is not running", func() {
+		It("waits for it", func() {
+			result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(clusterRequeueDelay))
+			Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhasePending))
+			Expect(getSnapshot().Status.Message).To(Equal("GreenplumCluster my-greenplum not found"))
+			Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+		})
+	})
+
+	When("the cluster is running", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+			createPVC("my-greenplum-pgdata-master-0")
+			createPVC("my-greenplum-pgdata-segment-a-0")
+			createPVC("my-greenplum-pgdata-segment-b-0")
+		})
+
+		It("adds its finalizer before stopping the cluster", func() {
+			result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(BeTrue())
+			Expect(getSnapshot().Finalizers).To(ConsistOf(ResumeClusterFinalizer))
+			Expect(commandsContaining("gpstop")).To(BeEmpty())
+		})
+
+		When("the finalizer is saved", func() {
+			BeforeEach(func() {
+				snapshot.Finalizers = []string{ResumeClusterFinalizer}
+			})
+
+			It("saves the Quiesced phase before stopping the cluster", func() {
+				result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Requeue).To(BeTrue())
+				Expect(commandsContaining("CHECKPOINT")).To(BeEmpty())
+				Expect(commandsContaining(gpstop)).To(BeEmpty())
+
+				status := getSnapshot().Status
+				Expect(status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseQuiesced))
+				Expect(status.StoppedMaster).To(Equal("master-0"))
+				Expect(status.SnapshotTime).NotTo(BeNil())
+				Expect(status.GreenplumMajorVersion).To(Equal(greenplumcluster.SupportedGreenplumMajorVersion))
+				Expect(status.SegmentConfiguration).To(HaveLen(3))
+				Expect(status.SegmentConfiguration[2]).To(Equal(v1beta1.GreenplumSegmentConfiguration{
+					DBID: 3, Content: 0, Role: "m", PreferredRole: "m", Mode: "s", Status: "u", Port: 50000,
+					Hostname: "segment-b-0", Address: "segment-b-0", DataDir: "/greenplum/mirror/data",
+				}))
+				Expect(status.VolumeSnapshots).To(Equal([]v1beta1.GreenplumSnapshotVolume{
+					{PersistentVolumeClaimName: "my-greenplum-pgdata-master-0", VolumeSnapshotName: "nightly-my-greenplum-pgdata-master-0"},
+					{PersistentVolumeClaimName: "my-greenplum-pgdata-segment-a-0", VolumeSnapshotName: "nightly-my-greenplum-pgdata-segment-a-0"},
+					{PersistentVolumeClaimName: "my-greenplum-pgdata-segment-b-0", VolumeSnapshotName: "nightly-my-greenplum-pgdata-segment-b-0"},
+				}))
+			})
+
+			It("then checkpoints and stops the cluster, and snapshots every PVC", func() {
+				Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{Requeue: true}))
+				fakePodExec.RecordedCommands = nil
+				result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(snapshotCutPollInterval))
+
+				alive := commandsContaining(masterIsAlive)
+				checkpoint := commandsContaining("CHECKPOINT")
+				stop := commandsContaining(gpstop)
+				Expect(alive).To(HaveLen(1))
+				Expect(checkpoint).To(HaveLen(1))
+				Expect(stop).To(HaveLen(1))
+				Expect(fakePodExec.RecordedCommands).To(Equal([]string{alive[0], checkpoint[0], stop[0]}))
+				Expect(fakePodExec.CalledPodName).To(Equal("master-0"))
+				Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseQuiesced))
+
+				var volumeSnapshot volumesnapshot.VolumeSnapshot
+				Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: "nightly-my-greenplum-pgdata-segment-a-0"}, &volumeSnapshot)).To(Succeed())
+				Expect(*volumeSnapshot.Spec.Source.PersistentVolumeClaimName).To(Equal("my-greenplum-pgdata-segment-a-0"))
+				Expect(*volumeSnapshot.Spec.VolumeSnapshotClassName).To(Equal("csi-hostpath-snapclass"))
+				Expect(volumeSnapshot.Labels).To(HaveKeyWithValue("greenplum-snapshot", "nightly"))
+				Expect(volumeSnapshot.OwnerReferences).To(HaveLen(1))
+				Expect(volumeSnapshot.OwnerReferences[0].Name).To(Equal("nightly"))
+			})
+
+			When("a segment is down", func() {
+				BeforeEach(func() {
+					fakePodExec.CommandStdout["ORDER BY dbid"] = strings.Replace(segmentConfiguration, "|m|m|s|u|", "|m|m|n|d|", 1)
+				})
+				It("fails without stopping the cluster", func() {
+					Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{}))
+					Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseFailed))
+					Expect(getSnapshot().Status.Message).To(Equal("segment with dbid 3 is down"))
+					Expect(getSnapshot().Finalizers).To(BeEmpty())
+					Expect(commandsContaining("gpstop")).To(BeEmpty())
+				})
+			})
+
+			When("a gpexpand Job is running", func() {
+				BeforeEach(func() {
+					Expect(reactiveClient.Create(ctx, &batchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum-gpexpand-job"},
+					})).To(Succeed())
+				})
+				It("waits for it without stopping the cluster", func() {
+					result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.RequeueAfter).To(Equal(clusterRequeueDelay))
+					Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhasePending))
+					Expect(getSnapshot().Status.Message).To(Equal("waiting for cluster operation to finish: gpexpand Job my-greenplum-gpexpand-job is running"))
+					Expect(commandsContaining("CHECKPOINT")).To(BeEmpty())
+					Expect(commandsContaining("gpstop")).To(BeEmpty())
+				})
+			})
+
+			When("a restart starts after the Quiesced phase is saved", func() {
+				It("goes back to Pending without stopping the cluster", func() {
+					Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{Requeue: true}))
+					Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: "my-greenplum"}, cluster)).To(Succeed())
+					cluster.Status.Restart = &greenplumv1.GreenplumOperationStatus{
+						RequestedAt: "1", Phase: greenplumv1.GreenplumOperationPhaseRunning, JobName: "my-greenplum-restart-0a1b2c3d",
+					}
+					Expect(reactiveClient.Status().Update(ctx, cluster)).To(Succeed())
+
+					_, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+					Expect(err).To(MatchError("not stopping cluster: gpstop restart Job my-greenplum-restart-0a1b2c3d is running"))
+					Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhasePending))
+					Expect(getSnapshot().Status.StoppedMaster).To(BeEmpty())
+					Expect(getSnapshot().Finalizers).To(ConsistOf(ResumeClusterFinalizer))
+					Expect(commandsContaining("CHECKPOINT")).To(BeEmpty())
+					Expect(commandsContaining("gpstop")).To(BeEmpty())
+				})
+			})
+
+			When("stopping the cluster fails", func() {
+				BeforeEach(func() {
+					fakePodExec.CommandErrors = map[string]string{gpstop: "gpstop failed"}
+				})
+				It("retries", func() {
+					Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{Requeue: true}))
+					_, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+					Expect(err).To(MatchError("failed to stop cluster: gpstop failed: gpstop failed"))
+					Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhasePending))
+					Expect(getSnapshot().Status.StoppedMaster).To(BeEmpty())
+					Expect(getSnapshot().Status.VolumeSnapshots).To(BeEmpty())
+					Expect(getSnapshot().Finalizers).To(ConsistOf(ResumeClusterFinalizer))
+				})
+			})
+		})
+	})
+
+	When("the cluster is stopped for the snapshot", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+			snapshotTime := metav1.Now()
+			snapshot.Finalizers = []string{ResumeClusterFinalizer}
+			snapshot.Status = v1beta1.GreenplumSnapshotStatus{
+				Phase:         v1beta1.GreenplumSnapshotPhaseQuiesced,
+				SnapshotTime:  &snapshotTime,
+				StoppedMaster: "master-1",
+				VolumeSnapshots: []v1beta1.GreenplumSnapshotVolume{
+					{PersistentVolumeClaimName: "my-greenplum-pgdata-master-0", VolumeSnapshotName: "nightly-my-greenplum-pgdata-master-0"},
+					{PersistentVolumeClaimName: "my-greenplum-pgdata-segment-a-0", VolumeSnapshotName: "nightly-my-greenplum-pgdata-segment-a-0"},
+				},
+			}
+			fakePodExec.CommandErrors = map[string]string{masterIsAlive: "connection refused"}
+		})
+
+		When("the operator stopped before the snapshots were created", func() {
+			It("creates them without stopping the cluster again", func() {
+				result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(snapshotCutPollInterval))
+				Expect(commandsContaining(gpstop)).To(BeEmpty())
+				Expect(commandsContaining(gpstart)).To(BeEmpty())
+				Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseQuiesced))
+				var volumeSnapshot volumesnapshot.VolumeSnapshot
+				Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: "nightly-my-greenplum-pgdata-master-0"}, &volumeSnapshot)).To(Succeed())
+				Expect(reactiveClient.Get(ctx, types.NamespacedName{Namespace: "test-ns", Name: "nightly-my-greenplum-pgdata-segment-a-0"}, &volumeSnapshot)).To(Succeed())
+			})
+		})
+
+		When("not every snapshot is cut", func() {
+			BeforeEach(func() {
+				cut := metav1.Now()
+				createVolumeSnapshot("nightly-my-greenplum-pgdata-master-0", &volumesnapshot.VolumeSnapshotStatus{CreationTime: &cut})
+				createVolumeSnapshot("nightly-my-greenplum-pgdata-segment-a-0", nil)
+			})
+			It("keeps the cluster stopped", func() {
+				result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(snapshotCutPollInterval))
+				Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseQuiesced))
+				Expect(commandsContaining(gpstop)).To(BeEmpty())
+				Expect(commandsContaining(gpstart)).To(BeEmpty())
+			})
+
+			When("the cluster has been stopped for too long", func() {
+				BeforeEach(func() {
+					snapshot.Status.SnapshotTime.Time = time.Now().Add(-snapshotQuiesceTimeout - time.Second)
+				})
+				It("starts the cluster and fails", func() {
+					Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{}))
+					Expect(fakePodExec.CalledPodName).To(Equal("master-1"))
+					Expect(commandsContaining(gpstart)).To(HaveLen(1))
+					Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseFailed))
+					Expect(getSnapshot().Status.Message).To(Equal("VolumeSnapshots were not taken within 5m0s"))
+					Expect(getSnapshot().Status.StoppedMaster).To(BeEmpty())
+					Expect(getSnapshot().Finalizers).To(BeEmpty())
+				})
+			})
+		})
+
+		When("every snapshot is cut", func() {
+			BeforeEach(func() {
+				cut := metav1.Now()
+				ready := true
+				createVolumeSnapshot("nightly-my-greenplum-pgdata-master-0", &volumesnapshot.VolumeSnapshotStatus{CreationTime: &cut, ReadyToUse: &ready})
+				createVolumeSnapshot("nightly-my-greenplum-pgdata-segment-a-0", &volumesnapshot.VolumeSnapshotStatus{CreationTime: &cut})
+			})
+			It("starts the cluster and waits for the snapshots to be ready", func() {
+				result, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(snapshotReadyPollInterval))
+				Expect(fakePodExec.CalledPodName).To(Equal("master-1"))
+				Expect(commandsContaining(gpstart)).To(HaveLen(1))
+				status := getSnapshot().Status
+				Expect(status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseSnapshotting))
+				Expect(status.StoppedMaster).To(BeEmpty())
+				Expect(status.VolumeSnapshots[0].ReadyToUse).To(BeTrue())
+				Expect(status.VolumeSnapshots[1].ReadyToUse).To(BeFalse())
+				Expect(getSnapshot().Finalizers).To(BeEmpty())
+				Expect(logBuf).To(gbytes.Say("started cluster after snapshot"))
+			})
+
+			When("starting the cluster fails", func() {
+				BeforeEach(func() {
+					fakePodExec.CommandErrors[gpstart] = "gpstart failed"
+				})
+				It("retries", func() {
+					_, err := snapshotReconciler.Reconcile(ctx, snapshotRequest)
+					Expect(err).To(MatchError("failed to start cluster: gpstart failed: gpstart failed"))
+					Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseQuiesced))
+					Expect(getSnapshot().Status.StoppedMaster).To(Equal("master-1"))
+					Expect(getSnapshot().Finalizers).To(ConsistOf(ResumeClusterFinalizer))
+				})
+			})
+		})
+
+		When("a snapshot fails", func() {
+			BeforeEach(func() {
+				message := "snapshot controller failed to update"
+				createVolumeSnapshot("nightly-my-greenplum-pgdata-master-0", &volumesnapshot.VolumeSnapshotStatus{
+					Error: &volumesnapshot.VolumeSnapshotError{Message: &message},
+				})
+			})
+			It("starts the cluster and fails", func() {
+				Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{}))
+				Expect(commandsContaining(gpstart)).To(HaveLen(1))
+				Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseFailed))
+				Expect(getSnapshot().Status.Message).To(Equal(
+					"VolumeSnapshot nightly-my-greenplum-pgdata-master-0 failed: snapshot controller failed to update"))
+			})
+		})
+
+		When("the GreenplumSnapshot is deleted", func() {
+			It("starts the cluster", func() {
+				Expect(reactiveClient.Delete(ctx, getSnapshot())).To(Succeed())
+				Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{}))
+				Expect(commandsContaining(gpstart)).To(HaveLen(1))
+				var deleted v1beta1.GreenplumSnapshot
+				err := reactiveClient.Get(ctx, snapshotRequest.NamespacedName, &deleted)
+				Expect(apierrs.IsNotFound(err)).To(BeTrue(), "expected not found, got %v", err)
+			})
+
+			When("the cluster was already started", func() {
+				BeforeEach(func() {
+					delete(fakePodExec.CommandErrors, masterIsAlive)
+				})
+				It("does not start it again", func() {
+					Expect(reactiveClient.Delete(ctx, getSnapshot())).To(Succeed())
+					Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{}))
+					Expect(commandsContaining(gpstart)).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	When("the snapshots are being made ready", func() {
+		BeforeEach(func() {
+			snapshot.Status = v1beta1.GreenplumSnapshotStatus{
+				Phase: v1beta1.GreenplumSnapshotPhaseSnapshotting,
+				VolumeSnapshots: []v1beta1.GreenplumSnapshotVolume{
+					{PersistentVolumeClaimName: "my-greenplum-pgdata-master-0", VolumeSnapshotName: "nightly-my-greenplum-pgdata-master-0"},
+				},
+			}
+			cut := metav1.Now()
+			ready := true
+			createVolumeSnapshot("nightly-my-greenplum-pgdata-master-0", &volumesnapshot.VolumeSnapshotStatus{CreationTime: &cut, ReadyToUse: &ready})
+		})
+		It("is Ready once every snapshot is", func() {
+			Expect(snapshotReconciler.Reconcile(ctx, snapshotRequest)).To(Equal(reconcile.Result{}))
+			Expect(getSnapshot().Status.Phase).To(Equal(v1beta1.GreenplumSnapshotPhaseReady))
+			Expect(getSnapshot().Status.VolumeSnapshots[0].ReadyToUse).To(BeTrue())
+			Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,226 @@
+/*
+.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/lib/pq"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/net"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// GreenplumTablespaceReconciler reconciles a GreenplumTablespace object
+type GreenplumTablespaceReconciler struct {
+	client.Client
+	Log     logr.Logger
+	PodExec executor.PodExecInterface
+	Querier querier.GreenplumQuerier
+}
+
+var _ client.Client = &GreenplumTablespaceReconciler{}
+
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumtablespaces,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumtablespaces/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=greenplum.pivotal.io,resources=greenplumclusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+
+func (r *GreenplumTablespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("greenplumtablespace", req.NamespacedName)
+
+	var tablespace greenplumv1beta1.GreenplumTablespace
+	if err := r.Get(ctx, req.NamespacedName, &tablespace); err != nil {
+		if apierrs.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to fetch GreenplumTablespace")
+	}
+
+	newTablespace := tablespace.DeepCopy()
+	result, err := r.reconcileTablespace(ctx, log, newTablespace)
+	if err != nil {
+		newTablespace.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(newTablespace.Status, tablespace.Status) {
+		if patchErr := r.Patch(ctx, newTablespace, client.MergeFrom(&tablespace)); patchErr != nil {
+			log.Error(patchErr, "update failed")
+			return ctrl.Result{}, patchErr
+		}
+	}
+
+	return result, err
+}
+
+// reconcileTablespace records the progress of the tablespace in its status. A returned error is retried.
+func (r *GreenplumTablespaceReconciler) reconcileTablespace(ctx context.Context, log logr.Logger, tablespace *greenplumv1beta1.GreenplumTablespace) (ctrl.Result, error) {
+	status := &tablespace.Status
+	if status.Phase == "" {
+		status.Phase = greenplumv1beta1.GreenplumTablespacePhasePending
+	}
+	// waitFor leaves a created tablespace as it is while its cluster is unavailable
+	waitFor := func(message string) {
+		if status.Phase != greenplumv1beta1.GreenplumTablespacePhaseCreated {
+			status.Phase = greenplumv1beta1.GreenplumTablespacePhasePending
+			status.Message = message
+		}
+	}
+
+	cluster, activeMaster, waiting, err := activeMasterOf(ctx, r, r.Querier, tablespace.Namespace, tablespace.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if cluster != nil && !hasTablespaceVolume(*cluster, tablespace.Spec.Volume) {
+		status.Phase = greenplumv1beta1.GreenplumTablespacePhaseFailed
+		status.Message = fmt.Sprintf("volume %s must be an additionalVolume with usage %s",
+			tablespace.Spec.Volume, greenplumv1.GreenplumVolumeUsageTablespace)
+		return ctrl.Result{}, nil
+	}
+
+	if waiting != "" {
+		waitFor(waiting)
+		return ctrl.Result{RequeueAfter: clusterRequeueDelay}, nil
+	}
+
+	location := TablespaceLocation(tablespace)
+	hosts := net.GenerateHostList(
+		int(cluster.Spec.Segments.PrimarySegmentCount),
+		strings.ToLower(cluster.Spec.Segments.Mirrors) == "yes",
+		strings.ToLower(cluster.Spec.MasterAndStandby.Standby) == "yes",
+		"")
+	if err := r.createTablespaceDirs(ctx, tablespace.Namespace, activeMaster, location, hosts); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	name := tablespace.Name
+	existing, err := executor.Query(ctx, r.PodExec, tablespace.Namespace, activeMaster, "gpadmin",
+		"SELECT pg_get_userbyid(spcowner) || '|' || pg_tablespace_location(oid) FROM pg_tablespace WHERE spcname = "+pq.QuoteLiteral(name))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to query tablespace: %w", err)
+	}
+
+	if existing == "" {
+		sql := "CREATE TABLESPACE " + pq.QuoteIdentifier(name)
+		if tablespace.Spec.Owner != "" {
+			sql += " OWNER " + pq.QuoteIdentifier(tablespace.Spec.Owner)
+		}
+		sql += " LOCATION " + pq.QuoteLiteral(location)
+		if _, err := executor.Query(ctx, r.PodExec, tablespace.Namespace, activeMaster, "gpadmin", sql); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to create tablespace: %w", err)
+		}
+		log.Info("created tablespace", "location", location)
+	} else {
+		separator := strings.LastIndex(existing, "|")
+		owner, existingLocation := existing[:separator], existing[separator+1:]
+		if existingLocation != location {
+			status.Phase = greenplumv1beta1.GreenplumTablespacePhaseFailed
+			status.Message = fmt.Sprintf("tablespace %s already exists at %s", name, existingLocation)
+			return ctrl.Result{}, nil
+		}
+		if tablespace.Spec.Owner != "" && tablespace.Spec.Owner != owner {
+			sql := "ALTER TABLESPACE " + pq.QuoteIdentifier(name) + " OWNER TO " + pq.QuoteIdentifier(tablespace.Spec.Owner)
+			if _, err := executor.Query(ctx, r.PodExec, tablespace.Namespace, activeMaster, "gpadmin", sql); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to change tablespace owner: %w", err)
+			}
+			log.Info("changed tablespace owner", "owner", tablespace.Spec.Owner)
+		}
+	}
+
+	status.Phase = greenplumv1beta1.GreenplumTablespacePhaseCreated
+	status.Location = location
+	status.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// createTablespaceDirs creates location on every host from the active master, using the ssh keys
+// that the cluster shares between its pods.
+func (r *GreenplumTablespaceReconciler) createTablespaceDirs(ctx context.Context, namespace, activeMaster, location string, hosts []string) error {
+	mkdirCommand := []string{
+		"/bin/bash",
+		"-c",
+		"--",
+		`for host in "${@:2}"; do ssh "$host" mkdir -p "$1" || exit 1; done`,
+		"mkdir",
+		location,
+	}
+	mkdirCommand = append(mkdirCommand, hosts...)
+	var stderr bytes.Buffer
+	err := r.PodExec.Execute(ctx, executor.ExecRequest{
+		Namespace: namespace,
+		PodName:   activeMaster,
+		Command:   mkdirCommand,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tablespace directory: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// TablespaceLocation is the directory of a GreenplumTablespace on each host. Every tablespace gets
+// its own directory, since CREATE TABLESPACE requires an empty one.
+func TablespaceLocation(tablespace *greenplumv1beta1.GreenplumTablespace) string {
+	return instanceconfig.TablespaceLocation(tablespace.Spec.Volume) + "/" + tablespace.Name
+}
+
+// hasTablespaceVolume is true if either masterAndStandby or segments has the volume for tablespaces.
+// Pods without it keep the tablespace directory on their data volume.
+func hasTablespaceVolume(cluster greenplumv1.GreenplumCluster, volumeName string) bool {
+	for _, volumes := range [][]greenplumv1.GreenplumAdditionalVolume{
+		cluster.Spec.MasterAndStandby.AdditionalVolumes,
+		cluster.Spec.Segments.AdditionalVolumes,
+	} {
+		for _, volume := range volumes {
+			if volume.Name == volumeName && volume.Usage == greenplumv1.GreenplumVolumeUsageTablespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tablespacesForCluster enqueues the tablespaces of a cluster, so that they are revisited when it
+// becomes Running or is expanded with new segment hosts.
+func (r *GreenplumTablespaceReconciler) tablespacesForCluster(cluster client.Object) []reconcile.Request {
+	var tablespaces greenplumv1beta1.GreenplumTablespaceList
+	if err := r.List(context.Background(), &tablespaces, client.InNamespace(cluster.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list GreenplumTablespaces", "namespace", cluster.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, tablespace := range tablespaces.Items {
+		if tablespace.Spec.ClusterName == cluster.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: tablespace.Namespace, Name: tablespace.Name},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *GreenplumTablespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&greenplumv1beta1.GreenplumTablespace{}).
+		Watches(&source.Kind{Type: &greenplumv1.GreenplumCluster{}}, handler.EnqueueRequestsFromMapFunc(r.tablespacesForCluster)).
+		Complete(r)
+}
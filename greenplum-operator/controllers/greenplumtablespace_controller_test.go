@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	fakeExecutor "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("GreenplumTablespace controller", func() {
+	var (
+		ctx                  context.Context
+		logBuf               *gbytes.Buffer
+		fakePodExec          *fakeExecutor.PodExec
+		fakeQuerier          *fakequerier.Querier
+		tablespaceReconciler *GreenplumTablespaceReconciler
+		tablespace           *v1beta1.GreenplumTablespace
+		cluster              *greenplumv1.GreenplumCluster
+		tablespaceRequest    = reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: "fast-space"},
+		}
+	)
+
+	fastVolume := greenplumv1.GreenplumAdditionalVolume{
+		Name:             "fast",
+		StorageClassName: "ssd",
+		Storage:          resource.MustParse("10G"),
+		Usage:            greenplumv1.GreenplumVolumeUsageTablespace,
+	}
+
+	getStatus := func() v1beta1.GreenplumTablespaceStatus {
+		var current v1beta1.GreenplumTablespace
+		Expect(reactiveClient.Get(ctx, tablespaceRequest.NamespacedName, &current)).To(Succeed())
+		return current.Status
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		logBuf = gbytes.NewBuffer()
+		fakeQuerier = &fakequerier.Querier{}
+		fakePodExec = &fakeExecutor.PodExec{}
+		tablespaceReconciler = &GreenplumTablespaceReconciler{
+			Client:  reactiveClient,
+			Log:     gplog.ForTest(logBuf),
+			PodExec: fakePodExec,
+			Querier: fakeQuerier,
+		}
+		tablespace = &v1beta1.GreenplumTablespace{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "fast-space"},
+			Spec: v1beta1.GreenplumTablespaceSpec{
+				ClusterName: "my-greenplum",
+				Volume:      "fast",
+			},
+		}
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+			Spec: greenplumv1.GreenplumClusterSpec{
+				MasterAndStandby: greenplumv1.GreenplumMasterAndStandbySpec{
+					GreenplumPodSpec: greenplumv1.GreenplumPodSpec{
+						AdditionalVolumes: []greenplumv1.GreenplumAdditionalVolume{fastVolume},
+					},
+					Standby: "yes",
+				},
+				Segments: greenplumv1.GreenplumSegmentsSpec{
+					GreenplumPodSpec: greenplumv1.GreenplumPodSpec{
+						AdditionalVolumes: []greenplumv1.GreenplumAdditionalVolume{fastVolume},
+					},
+					PrimarySegmentCount: 2,
+					Mirrors:             "yes",
+				},
+			},
+			Status: greenplumv1.GreenplumClusterStatus{Phase: greenplumv1.GreenplumClusterPhaseRunning},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reactiveClient.Create(ctx, tablespace)).To(Succeed())
+	})
+
+	When("the cluster is running", func() {
+		BeforeEach(func() {
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+
+		It("creates the directory on every host and then the tablespace", func() {
+			result, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+
+			Expect(fakePodExec.CalledPodName).To(Equal("master-0"))
+			Expect(fakePodExec.RecordedCommands).To(HaveLen(3))
+			Expect(fakePodExec.RecordedCommands[0]).To(HaveSuffix("mkdir /greenplum/volumes/fast/tablespace/fast-space " +
+				"segment-a-0 segment-b-0 segment-a-1 segment-b-1 master-0 master-1"))
+			Expect(fakePodExec.RecordedCommands[1]).To(HaveSuffix(
+				"gpadmin SELECT pg_get_userbyid(spcowner) || '|' || pg_tablespace_location(oid) FROM pg_tablespace WHERE spcname = 'fast-space'"))
+			Expect(fakePodExec.RecordedCommands[2]).To(HaveSuffix(
+				`gpadmin CREATE TABLESPACE "fast-space" LOCATION '/greenplum/volumes/fast/tablespace/fast-space'`))
+			Expect(logBuf).To(gbytes.Say("created tablespace"))
+
+			Expect(getStatus()).To(Equal(v1beta1.GreenplumTablespaceStatus{
+				Phase:    v1beta1.GreenplumTablespacePhaseCreated,
+				Location: "/greenplum/volumes/fast/tablespace/fast-space",
+			}))
+		})
+
+		When("only the segments have the volume", func() {
+			BeforeEach(func() {
+				cluster.Spec.MasterAndStandby.AdditionalVolumes = nil
+			})
+			It("creates the tablespace", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getStatus().Phase).To(Equal(v1beta1.GreenplumTablespacePhaseCreated))
+			})
+		})
+
+		When("an owner is given", func() {
+			BeforeEach(func() {
+				tablespace.Spec.Owner = "app"
+			})
+			It("creates the tablespace with that owner", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands[2]).To(ContainSubstring(`CREATE TABLESPACE "fast-space" OWNER "app" LOCATION`))
+			})
+		})
+
+		When("the tablespace already exists", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandStdout = map[string]string{
+					"FROM pg_tablespace": "gpadmin|/greenplum/volumes/fast/tablespace/fast-space\n",
+				}
+			})
+			It("does not create it again", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(2))
+				Expect(getStatus().Phase).To(Equal(v1beta1.GreenplumTablespacePhaseCreated))
+			})
+
+			When("it has a different owner", func() {
+				BeforeEach(func() {
+					tablespace.Spec.Owner = "app"
+				})
+				It("changes the owner", func() {
+					_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakePodExec.RecordedCommands).To(HaveLen(3))
+					Expect(fakePodExec.RecordedCommands[2]).To(HaveSuffix(`ALTER TABLESPACE "fast-space" OWNER TO "app"`))
+				})
+			})
+		})
+
+		When("a tablespace with the same name exists elsewhere", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandStdout = map[string]string{
+					"FROM pg_tablespace": "gpadmin|/somewhere/else\n",
+				}
+			})
+			It("reports the tablespace as failed", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getStatus()).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+					"Phase":   Equal(v1beta1.GreenplumTablespacePhaseFailed),
+					"Message": Equal("tablespace fast-space already exists at /somewhere/else"),
+				}))
+			})
+		})
+
+		When("creating the directories fails", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandErrors = map[string]string{
+					"ssh": "ssh: Could not resolve hostname segment-a-1",
+				}
+			})
+			It("returns the error and records it in the status", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).To(MatchError("failed to create tablespace directory: ssh: Could not resolve hostname segment-a-1: " +
+					"ssh: Could not resolve hostname segment-a-1"))
+				Expect(fakePodExec.RecordedCommands).To(HaveLen(1))
+				Expect(getStatus()).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+					"Phase":   Equal(v1beta1.GreenplumTablespacePhasePending),
+					"Message": Equal(err.Error()),
+				}))
+			})
+		})
+
+		When("CREATE TABLESPACE fails", func() {
+			BeforeEach(func() {
+				fakePodExec.CommandErrors = map[string]string{
+					"CREATE TABLESPACE": `ERROR:  role "app" does not exist`,
+				}
+			})
+			It("returns the error", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).To(MatchError(`failed to create tablespace: ERROR:  role "app" does not exist: ERROR:  role "app" does not exist`))
+			})
+		})
+
+		When("there is no active master", func() {
+			BeforeEach(func() {
+				fakeQuerier.ErrorMsgOnMaster0 = "postgres not running"
+				fakeQuerier.ErrorMsgOnMaster1 = "postgres not running"
+			})
+			It("waits for one", func() {
+				result, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(clusterRequeueDelay))
+				Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+				Expect(getStatus()).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+					"Phase":   Equal(v1beta1.GreenplumTablespacePhasePending),
+					"Message": Equal("no active master"),
+				}))
+			})
+		})
+		When("the active master rejects the operator", func() {
+			BeforeEach(func() {
+				fakeQuerier.PingErr = &pq.Error{Code: "28P01", Message: `password authentication failed for user "gpoperator"`}
+			})
+			It("returns an error without running SQL", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).To(MatchError(ContainSubstring("active master master-0 rejects the operator")))
+				Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+			})
+		})
+	})
+
+	When("the cluster is not running", func() {
+		BeforeEach(func() {
+			cluster.Status.Phase = greenplumv1.GreenplumClusterPhasePending
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+		It("waits for the cluster", func() {
+			_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakePodExec.RecordedCommands).To(BeEmpty())
+			Expect(getStatus()).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Phase":   Equal(v1beta1.GreenplumTablespacePhasePending),
+				"Message": Equal("waiting for GreenplumCluster to be Running"),
+			}))
+		})
+
+		When("the tablespace was already created", func() {
+			BeforeEach(func() {
+				tablespace.Status.Phase = v1beta1.GreenplumTablespacePhaseCreated
+			})
+			It("keeps it Created", func() {
+				_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getStatus().Phase).To(Equal(v1beta1.GreenplumTablespacePhaseCreated))
+			})
+		})
+	})
+
+	When("the volume is not a tablespace volume", func() {
+		BeforeEach(func() {
+			cluster.Spec.MasterAndStandby.AdditionalVolumes = nil
+			cluster.Spec.Segments.AdditionalVolumes[0].Usage = greenplumv1.GreenplumVolumeUsageTemp
+			Expect(reactiveClient.Create(ctx, cluster)).To(Succeed())
+		})
+		It("reports the tablespace as failed", func() {
+			_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getStatus()).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Phase":   Equal(v1beta1.GreenplumTablespacePhaseFailed),
+				"Message": Equal("volume fast must be an additionalVolume with usage tablespace"),
+			}))
+		})
+	})
+
+	When("the cluster does not exist", func() {
+		It("waits for the cluster", func() {
+			_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getStatus()).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Phase":   Equal(v1beta1.GreenplumTablespacePhasePending),
+				"Message": Equal("GreenplumCluster my-greenplum not found"),
+			}))
+		})
+	})
+
+	When("getting the tablespace fails", func() {
+		BeforeEach(func() {
+			reactiveClient.PrependReactor("get", "greenplumtablespaces", func(action testing.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.New("injected error")
+			})
+		})
+		It("returns the error", func() {
+			_, err := tablespaceReconciler.Reconcile(ctx, tablespaceRequest)
+			Expect(err).To(MatchError("unable to fetch GreenplumTablespace: injected error"))
+		})
+	})
+
+	Describe("tablespacesForCluster", func() {
+		BeforeEach(func() {
+			other := &v1beta1.GreenplumTablespace{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "other-space"},
+				Spec:       v1beta1.GreenplumTablespaceSpec{ClusterName: "other-greenplum", Volume: "fast"},
+			}
+			Expect(reactiveClient.Create(ctx, other)).To(Succeed())
+		})
+		It("enqueues the tablespaces of the cluster", func() {
+			Expect(tablespaceReconciler.tablespacesForCluster(cluster)).To(ConsistOf(tablespaceRequest))
+		})
+	})
+})
@@ -328,6 +328,7 @@ var _ = Describe("Happy path integration greenplum operator", func() {
 		It("deletes owned resources", func() {
 			EventuallyResourceShouldBeDeleted("validatingwebhookconfiguration/" + admission.WebhookConfigName)
 			EventuallyResourceShouldBeDeleted("service/" + admission.ServiceName)
+			EventuallyResourceShouldBeDeleted("secret/" + admission.CertSecretName)
 			EventuallyResourceShouldBeDeleted("certificatesigningrequest/" + admission.CSRName)
 			EventuallyResourceShouldBeDeleted("customresourcedefinition/greenplumclusters.greenplum.pivotal.io")
 		})
@@ -0,0 +1,11 @@
+{{/*
+A namespace-scoped operator names its cluster-wide RBAC objects after its release namespace,
+so that it can be installed next to other operators.
+*/}}
+{{- define "greenplum-operator.roleName" -}}
+{{- if .Values.watchNamespaces -}}
+greenplum-system-operator-{{ .Release.Namespace }}
+{{- else -}}
+greenplum-system-operator
+{{- end -}}
+{{- end -}}
@@ -8,6 +8,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"time"
 
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
@@ -15,8 +16,8 @@ import (
 	certificates "k8s.io/api/certificates/v1beta1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
-	k8scsr "k8s.io/client-go/util/certificate/csr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -93,12 +94,32 @@ func (g *CertificateGenerator) ApproveCertificateSigningRequest(csr *v1beta1.Cer
 func (g *CertificateGenerator) WaitForSignedCertificate(csr *v1beta1.CertificateSigningRequest, timeout time.Duration) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return k8scsr.WaitForCertificate(ctx, g.KubeClientSet.CertificatesV1beta1().CertificateSigningRequests(), csr)
+	var certificate []byte
+	err := wait.PollImmediateUntil(100*time.Millisecond, func() (bool, error) {
+		current, err := g.KubeClientSet.CertificatesV1beta1().CertificateSigningRequests().Get(ctx, csr.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if current.UID != csr.UID {
+			return false, fmt.Errorf("csr %q changed UIDs", csr.Name)
+		}
+		for _, c := range current.Status.Conditions {
+			if c.Type == certificates.CertificateDenied {
+				return false, fmt.Errorf("certificate signing request is denied, reason: %v, message: %v", c.Reason, c.Message)
+			}
+		}
+		certificate = current.Status.Certificate
+		return len(certificate) > 0, nil
+	}, ctx.Done())
+	return certificate, err
 }
 
 func (g *CertificateGenerator) GetCertificate(cert []byte, rsaKey *rsa.PrivateKey) (tls.Certificate, error) {
-	rsaPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
-	return tls.X509KeyPair(cert, rsaPem)
+	return tls.X509KeyPair(cert, EncodePrivateKey(rsaKey))
+}
+
+func EncodePrivateKey(rsaKey *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
 }
 
 // helper functions:
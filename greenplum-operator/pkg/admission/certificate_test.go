@@ -317,7 +317,7 @@ var _ = Describe("CertificateGenerator", func() {
 				rsaPem.D.Rem(big.NewInt(100), big.NewInt(10))
 				_, err := subject.GetCertificate(certPem, rsaPem)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("tls: failed to parse private key"))
+				Expect(err.Error()).To(HavePrefix("tls: failed to parse private key"))
 			})
 		})
 
@@ -27,7 +27,7 @@ func SampleAdmissionReviewRequest() AdmissionReviewRequestBuilder {
 func (b AdmissionReviewRequestBuilder) Kind(o runtime.Object) AdmissionReviewRequestBuilder {
 	gvks, _, err := scheme.Scheme.ObjectKinds(o)
 	Expect(err).NotTo(HaveOccurred())
-	Expect(scheme.Scheme.Convert(&gvks[0], &b.r.Request.Kind, nil)).To(Succeed())
+	b.r.Request.Kind = metav1.GroupVersionKind{Group: gvks[0].Group, Version: gvks[0].Version, Kind: gvks[0].Kind}
 	return b
 }
 
@@ -10,20 +10,25 @@ import (
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
-	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
-	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 )
 
 type Handler struct {
-	KubeClient     client.Client
-	InstanceImage  string
-	RestClient     rest.Interface
-	PodCmdExecutor executor.PodExecInterface
-	KubeClientSet  *kubernetes.Clientset
+	KubeClient    client.Client
+	InstanceImage string
+	RestClient    rest.Interface
+	Querier       querier.GreenplumQuerier
+	StatusGetter  gpstatus.Getter
+	KubeClientSet *kubernetes.Clientset
+	Recorder      record.EventRecorder
 }
 
 func (h *Handler) Handler() http.Handler {
@@ -57,6 +62,7 @@ func (h *Handler) HandleValidate(out http.ResponseWriter, req *http.Request) {
 	}
 
 	var reviewResponse admissionv1beta1.AdmissionReview
+	var reviewedObject runtime.Object
 	reviewResponse.Response = func() (response *admissionv1beta1.AdmissionResponse) {
 		var reviewRequest admissionv1beta1.AdmissionReview
 		response = &admissionv1beta1.AdmissionResponse{}
@@ -74,12 +80,8 @@ func (h *Handler) HandleValidate(out http.ResponseWriter, req *http.Request) {
 
 		response.UID = reviewRequest.Request.UID
 
-		var reqGVK schema.GroupVersionKind
-		if err := scheme.Scheme.Convert(&reviewRequest.Request.Kind, &reqGVK, nil); err != nil {
-			// Note: this isn't covered by our unit tests, since it's nearly impossible to either happen or to test.
-			response.Result = &metav1.Status{Message: "failed to convert metav1.GVK to schema.GVK: " + err.Error()}
-			return
-		}
+		reqKind := reviewRequest.Request.Kind
+		reqGVK := schema.GroupVersionKind{Group: reqKind.Group, Version: reqKind.Version, Kind: reqKind.Kind}
 
 		switch reqGVK {
 		case greenplumv1.GroupVersion.WithKind("GreenplumCluster"):
@@ -88,6 +90,7 @@ func (h *Handler) HandleValidate(out http.ResponseWriter, req *http.Request) {
 				response.Result = &metav1.Status{Message: "failed to unmarshal Request.Object into GreenplumCluster: " + err.Error()}
 				return
 			}
+			reviewedObject = &newGreenplum
 			op := reviewRequest.Request.Operation
 			switch op {
 			case admissionv1beta1.Create:
@@ -109,6 +112,7 @@ func (h *Handler) HandleValidate(out http.ResponseWriter, req *http.Request) {
 				response.Result = &metav1.Status{Message: "failed to unmarshal Request.Object into GreenplumPXFService: " + err.Error()}
 				return
 			}
+			reviewedObject = &newPXF
 			switch op {
 			case admissionv1beta1.Create:
 				response.Allowed, response.Result = h.validateGreenplumPXFService(ctx, nil, &newPXF)
@@ -134,6 +138,13 @@ func (h *Handler) HandleValidate(out http.ResponseWriter, req *http.Request) {
 	if reviewResponse.Response.Result != nil && reviewResponse.Response.Result.Message != "" {
 		log = log.WithValues("Message", reviewResponse.Response.Result.Message)
 	}
+	if !reviewResponse.Response.Allowed && reviewedObject != nil {
+		var message string
+		if reviewResponse.Response.Result != nil {
+			message = reviewResponse.Response.Result.Message
+		}
+		h.Recorder.Event(reviewedObject, corev1.EventTypeWarning, "AdmissionRejected", message)
+	}
 
 	outBytes, _ := json.Marshal(reviewResponse)
 	_, err = out.Write(outBytes)
@@ -15,30 +15,36 @@ import (
 	"github.com/onsi/gomega/gbytes"
 	. "github.com/onsi/gomega/gstruct"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/admission"
-	fakePodExec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/testing/reactive"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	. "github.com/pivotal/greenplum-for-kubernetes/pkg/gplog/testing"
+	fakegpstatus "github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus/fake"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var _ = Describe("HandleValidate", func() {
 	var (
-		req     *http.Request
-		resp    http.ResponseWriter
-		subject admission.Handler
-		logBuf  *gbytes.Buffer
+		req      *http.Request
+		resp     http.ResponseWriter
+		subject  admission.Handler
+		logBuf   *gbytes.Buffer
+		recorder *record.FakeRecorder
 	)
 	JustBeforeEach(func() {
 		reactiveClient := reactive.NewClient(fakeClient.NewFakeClientWithScheme(scheme.Scheme))
+		recorder = record.NewFakeRecorder(100)
 		subject = admission.Handler{
-			KubeClient:     reactiveClient,
-			PodCmdExecutor: &fakePodExec.PodExec{StdoutResult: "0\n"},
+			KubeClient:   reactiveClient,
+			Querier:      &fakequerier.Querier{Results: noExpansionSchema},
+			StatusGetter: fakegpstatus.NewHealthyCluster(),
+			Recorder:     recorder,
 		}
 		logBuf = gbytes.NewBuffer()
 		admission.Log = gplog.ForTest(logBuf)
@@ -142,6 +148,7 @@ var _ = Describe("HandleValidate", func() {
 				"Allowed":   BeFalse(),
 				"Message":   Equal("unexpected validation request for object: core/v1, Kind=Pod"),
 			}))
+			Expect(recorder.Events).NotTo(Receive(), "should not emit an event for an object it does not validate")
 		})
 	})
 	When("the request contains a new GreenplumCluster that cannot be unmarshalled", func() {
@@ -169,6 +176,7 @@ var _ = Describe("HandleValidate", func() {
 				"Allowed":   BeFalse(),
 				"Message":   Equal("failed to unmarshal Request.Object into GreenplumCluster: json: cannot unmarshal array into Go value of type v1.GreenplumCluster"),
 			}))
+			Expect(recorder.Events).NotTo(Receive(), "should not emit an event without an object")
 		})
 	})
 	When("the request contains an old GreenplumCluster that cannot be unmarshalled", func() {
@@ -196,6 +204,7 @@ var _ = Describe("HandleValidate", func() {
 				"Allowed":   BeFalse(),
 				"Message":   Equal("failed to unmarshal Request.OldObject into GreenplumCluster: json: cannot unmarshal array into Go value of type v1.GreenplumCluster"),
 			}))
+			Expect(recorder.Events).To(Receive(Equal("Warning AdmissionRejected failed to unmarshal Request.OldObject into GreenplumCluster: json: cannot unmarshal array into Go value of type v1.GreenplumCluster")))
 		})
 	})
 
@@ -294,6 +303,7 @@ var _ = Describe("HandleValidate", func() {
 					"Allowed":   BeFalse(),
 					"Message":   Equal("unexpected operation for validation: " + operation),
 				}))
+				Expect(recorder.Events).To(Receive(Equal("Warning AdmissionRejected unexpected operation for validation: " + operation)))
 			})
 		})
 	}
@@ -338,6 +348,7 @@ var _ = Describe("HandleValidate", func() {
 					"Allowed":   BeFalse(),
 					"Message":   Equal("unexpected operation for validation: " + operation),
 				}))
+				Expect(recorder.Events).To(Receive(Equal("Warning AdmissionRejected unexpected operation for validation: " + operation)))
 			})
 		})
 	}
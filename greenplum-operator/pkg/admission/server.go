@@ -8,7 +8,7 @@ import (
 )
 
 type Server interface {
-	Start(stopCh <-chan struct{}, cert tls.Certificate, addr string, handler http.Handler) error
+	Start(stopCh <-chan struct{}, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error), addr string, handler http.Handler) error
 	Shutdown() error
 }
 
@@ -22,11 +22,13 @@ func NewTLSServer() Server {
 	return &tlsServer{}
 }
 
-func (srv *tlsServer) Start(stopCh <-chan struct{}, cert tls.Certificate, addr string, handler http.Handler) error {
+// Start serves handler on addr until stopCh is closed. getCertificate is called for every connection,
+// so that a renewed certificate is served without restarting the server.
+func (srv *tlsServer) Start(stopCh <-chan struct{}, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error), addr string, handler http.Handler) error {
 	srv.Addr = addr
 	srv.Handler = handler
 	srv.TLSConfig = &tls.Config{
-		Certificates: []tls.Certificate{cert},
+		GetCertificate: getCertificate,
 	}
 
 	go func() {
@@ -47,7 +47,7 @@ var _ = Describe("Server", func() {
 			stopCh := make(chan struct{})
 
 			go func() {
-				err := subject.Start(stopCh, cert, srvAddr, ah.Handler())
+				err := subject.Start(stopCh, func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &cert, nil }, srvAddr, ah.Handler())
 				Expect(err).To(Equal(http.ErrServerClosed))
 				close(doneCh)
 			}()
@@ -66,7 +66,7 @@ var _ = Describe("Server", func() {
 			stopCh = make(chan struct{})
 			startCh = make(chan struct{})
 			go func() {
-				err := subject.Start(stopCh, tls.Certificate{}, srvAddr, ah.Handler())
+				err := subject.Start(stopCh, func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &tls.Certificate{}, nil }, srvAddr, ah.Handler())
 				Expect(err).To(Equal(http.ErrServerClosed))
 				close(doneCh)
 			}()
@@ -3,11 +3,16 @@ package admission
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -22,6 +27,11 @@ func (h *Handler) validateCreateGreenplumCluster(ctx context.Context, newGreenpl
 	if result != nil {
 		return
 	}
+	// The PVC validations below also apply to the PVCs that will be created from a snapshot
+	result = h.validateDataSource(ctx, newGreenplum)
+	if result != nil {
+		return
+	}
 	result = h.validateGreenplumStorageFromPVCs(ctx, newGreenplum)
 	if result != nil {
 		return
@@ -50,6 +60,10 @@ func (h *Handler) validateCreateGreenplumCluster(ctx context.Context, newGreenpl
 	if result != nil {
 		return
 	}
+	result = h.validateRetainedClusterShape(ctx, newGreenplum)
+	if result != nil {
+		return
+	}
 
 	result = validateWorkerSelector(newGreenplum.Spec.MasterAndStandby.WorkerSelector, "masterAndStandby")
 	if result != nil {
@@ -87,6 +101,21 @@ func (h *Handler) validateCreateGreenplumCluster(ctx context.Context, newGreenpl
 		return
 	}
 
+	result = validateAdditionalVolumes(newGreenplum)
+	if result != nil {
+		return
+	}
+
+	result = validateCatalogCheck(newGreenplum)
+	if result != nil {
+		return
+	}
+
+	result = validateLogRetention(newGreenplum)
+	if result != nil {
+		return
+	}
+
 	allowed = true
 	return
 }
@@ -112,7 +141,7 @@ func (h *Handler) validatePvcGreenplumVersion(ctx context.Context, newGreenplum
 	}
 
 	for _, pvc := range pvcList.Items {
-		ver, ok := pvc.Labels["greenplum-major-version"]
+		ver, ok := pvc.Labels[greenplumcluster.PVCMajorVersionLabel]
 		if !ok {
 			errMsg := fmt.Sprintf(pvcVersionErrFmt, greenplumcluster.SupportedGreenplumMajorVersion, "no label")
 			result = &metav1.Status{Message: errMsg}
@@ -232,13 +261,193 @@ func (h *Handler) validateMirrors(ctx context.Context, newGreenplum greenplumv1.
 	return
 }
 
+// validateRetainedClusterShape compares the cluster with the shape that the operator labeled its PVCs with when a
+// previous cluster of the same name was deleted. Unlike the PVC counts, the labels still hold when some PVCs were
+// deleted by hand.
+func (h *Handler) validateRetainedClusterShape(ctx context.Context, newGreenplum greenplumv1.GreenplumCluster) (result *metav1.Status) {
+	for _, typ := range []string{"master", "segment-a", "segment-b"} {
+		pvcList, err := h.getGreenplumPVCs(ctx, newGreenplum, typ)
+		if err != nil {
+			result = &metav1.Status{Message: err.Error()}
+			return
+		}
+		for _, pvc := range pvcList.Items {
+			if standby, ok := pvc.Labels[greenplumcluster.PVCStandbyLabel]; ok && !strings.EqualFold(standby, newGreenplum.Spec.MasterAndStandby.Standby) {
+				result = &metav1.Status{Message: generateRetainedPVCErrStr(newGreenplum.Name, "masterAndStandby.standby", standby, "changed")}
+				return
+			}
+			if mirrors, ok := pvc.Labels[greenplumcluster.PVCMirrorsLabel]; ok && !strings.EqualFold(mirrors, newGreenplum.Spec.Segments.Mirrors) {
+				result = &metav1.Status{Message: generateRetainedPVCErrStr(newGreenplum.Name, "segments.mirrors", mirrors, "changed")}
+				return
+			}
+			if count, ok := pvc.Labels[greenplumcluster.PVCPrimarySegmentCountLabel]; ok {
+				previousCount, err := strconv.Atoi(count)
+				if err == nil && int(newGreenplum.Spec.Segments.PrimarySegmentCount) < previousCount {
+					result = &metav1.Status{Message: generateRetainedPVCErrStr(newGreenplum.Name, "segments.primarySegmentCount", count, "decreased")}
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+func validateAdditionalVolumes(newGreenplum greenplumv1.GreenplumCluster) (result *metav1.Status) {
+	usages := map[string]greenplumv1.GreenplumVolumeUsage{}
+	var tempVolume string
+	for _, podSpec := range []struct {
+		typ     string
+		volumes []greenplumv1.GreenplumAdditionalVolume
+	}{
+		{"masterAndStandby", newGreenplum.Spec.MasterAndStandby.AdditionalVolumes},
+		{"segments", newGreenplum.Spec.Segments.AdditionalVolumes},
+	} {
+		names := map[string]bool{}
+		mirrorVolumes := 0
+		for _, volume := range podSpec.volumes {
+			if volume.Name == "pgdata" {
+				result = &metav1.Status{Message: fmt.Sprintf(`%s additionalVolumes name "pgdata" is reserved for the data volume`, podSpec.typ)}
+				return
+			}
+			if names[volume.Name] {
+				result = &metav1.Status{Message: fmt.Sprintf(`%s additionalVolumes name "%s" is used more than once`, podSpec.typ, volume.Name)}
+				return
+			}
+			names[volume.Name] = true
+
+			result = validateResourceQuantity(volume.Storage, podSpec.typ, "additionalVolumes storage")
+			if result != nil {
+				return
+			}
+
+			usage := volume.Usage
+			if usage == "" {
+				usage = greenplumv1.GreenplumVolumeUsageTablespace
+			}
+			switch usage {
+			case greenplumv1.GreenplumVolumeUsageMirror:
+				if podSpec.typ != "segments" || newGreenplum.Spec.Segments.Mirrors != "yes" {
+					result = &metav1.Status{Message: `additionalVolumes with usage "mirror" are only allowed on segments when mirrors is set to "yes"`}
+					return
+				}
+				if mirrorVolumes++; mirrorVolumes > 1 {
+					result = &metav1.Status{Message: `segments can have only one additionalVolume with usage "mirror"`}
+					return
+				}
+				continue
+			case greenplumv1.GreenplumVolumeUsageTemp:
+				if tempVolume != "" && tempVolume != volume.Name {
+					result = &metav1.Status{Message: `only one additionalVolume name can have usage "temp"`}
+					return
+				}
+				tempVolume = volume.Name
+			}
+			if previousUsage, ok := usages[volume.Name]; ok && previousUsage != usage {
+				result = &metav1.Status{Message: fmt.Sprintf(`additionalVolume "%s" must have the same usage on masterAndStandby and segments`, volume.Name)}
+				return
+			}
+			usages[volume.Name] = usage
+		}
+	}
+	return
+}
+
+func (h *Handler) validateDataSource(ctx context.Context, newGreenplum greenplumv1.GreenplumCluster) (result *metav1.Status) {
+	dataSource := newGreenplum.Spec.DataSource
+	if dataSource == nil {
+		return
+	}
+	if (dataSource.SnapshotName == "") == (dataSource.Backup == nil) {
+		result = &metav1.Status{Message: "dataSource must have exactly one of snapshotName and backup"}
+		return
+	}
+	if dataSource.Backup != nil {
+		if (dataSource.Backup.BackupDir == "") == (dataSource.Backup.PluginConfig == "") {
+			result = &metav1.Status{Message: "dataSource backup must have exactly one of backupDir and pluginConfig"}
+		}
+		return
+	}
+
+	snapshot, err := h.getDataSourceSnapshot(ctx, newGreenplum)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			result = &metav1.Status{Message: "GreenplumSnapshot " + dataSource.SnapshotName + " not found"}
+			return
+		}
+		result = &metav1.Status{Message: "could not get GreenplumSnapshot " + dataSource.SnapshotName + ". " + err.Error()}
+		return
+	}
+	if snapshot.Status.Phase != greenplumv1beta1.GreenplumSnapshotPhaseReady {
+		result = &metav1.Status{Message: "GreenplumSnapshot " + snapshot.Name + " is not Ready"}
+		return
+	}
+	if _, err := greenplumcluster.SnapshotPVCs(&newGreenplum, snapshot); err != nil {
+		result = &metav1.Status{Message: err.Error()}
+		return
+	}
+
+	pvcList, err := h.getPVCs(ctx, newGreenplum.Namespace, client.MatchingLabels{"greenplum-cluster": newGreenplum.Name})
+	if err != nil {
+		result = &metav1.Status{Message: err.Error()}
+		return
+	}
+	if len(pvcList.Items) > 0 {
+		result = &metav1.Status{
+			Message: fmt.Sprintf("%s has PVCs from a previous cluster. dataSource cannot be used without first deleting PVCs", newGreenplum.Name),
+		}
+		return
+	}
+	return
+}
+
+func (h *Handler) getDataSourceSnapshot(ctx context.Context, newGreenplum greenplumv1.GreenplumCluster) (*greenplumv1beta1.GreenplumSnapshot, error) {
+	var snapshot greenplumv1beta1.GreenplumSnapshot
+	snapshotKey := types.NamespacedName{Namespace: newGreenplum.Namespace, Name: newGreenplum.Spec.DataSource.SnapshotName}
+	if err := h.KubeClient.Get(ctx, snapshotKey, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// getGreenplumPVCs returns the data volume PVCs of each pod; additional volumes are not counted.
+// For a cluster created from a snapshot, these are the PVCs the operator will create from it.
 func (h *Handler) getGreenplumPVCs(ctx context.Context, newGreenplum greenplumv1.GreenplumCluster, typ string) (*corev1.PersistentVolumeClaimList, error) {
 	labelMatcher := client.MatchingLabels{
 		"app":               "greenplum",
 		"greenplum-cluster": newGreenplum.Name,
 		"type":              typ,
 	}
-	return h.getPVCs(ctx, newGreenplum.Namespace, labelMatcher)
+	var pvcList *corev1.PersistentVolumeClaimList
+	if newGreenplum.Spec.DataSource != nil && newGreenplum.Spec.DataSource.SnapshotName != "" {
+		snapshot, err := h.getDataSourceSnapshot(ctx, newGreenplum)
+		if err != nil {
+			return nil, err
+		}
+		snapshotPVCs, err := greenplumcluster.SnapshotPVCs(&newGreenplum, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		pvcList = &corev1.PersistentVolumeClaimList{}
+		for _, pvc := range snapshotPVCs {
+			if pvc.Labels["type"] == typ {
+				pvcList.Items = append(pvcList.Items, pvc)
+			}
+		}
+	} else {
+		var err error
+		pvcList, err = h.getPVCs(ctx, newGreenplum.Namespace, labelMatcher)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dataPVCs := pvcList.Items[:0]
+	for _, pvc := range pvcList.Items {
+		if _, ok := pvc.Labels["greenplum-volume"]; !ok {
+			dataPVCs = append(dataPVCs, pvc)
+		}
+	}
+	pvcList.Items = dataPVCs
+	return pvcList, nil
 }
 
 func (h *Handler) clusterExistsInNamespace(ctx context.Context, greenplumCluster greenplumv1.GreenplumCluster) (bool, error) {
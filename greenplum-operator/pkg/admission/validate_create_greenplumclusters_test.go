@@ -3,6 +3,7 @@ package admission_test
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -11,6 +12,7 @@ import (
 	. "github.com/onsi/gomega/gstruct"
 	"github.com/onsi/gomega/types"
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/controllers/greenplumcluster"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/admission"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
@@ -23,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -30,14 +33,17 @@ import (
 var _ = Describe("validateCreateGreenplumCluster", func() {
 
 	var (
-		subject admission.Handler
-		logBuf  *gbytes.Buffer
+		subject  admission.Handler
+		logBuf   *gbytes.Buffer
+		recorder *record.FakeRecorder
 	)
 
 	BeforeEach(func() {
 		reactiveClient := reactive.NewClient(fakeClient.NewFakeClientWithScheme(scheme.Scheme))
+		recorder = record.NewFakeRecorder(100)
 		subject = admission.Handler{
 			KubeClient: reactiveClient,
+			Recorder:   recorder,
 		}
 		logBuf = gbytes.NewBuffer()
 		admission.Log = gplog.ForTest(logBuf)
@@ -725,6 +731,352 @@ var _ = Describe("validateCreateGreenplumCluster", func() {
 		Entry("storage = 0", resource.MustParse("0")),
 		Entry("storage = 1", resource.MustParse("1")),
 	)
+
+	When("additionalVolumes are specified", func() {
+		var newGreenplum *greenplumv1.GreenplumCluster
+		BeforeEach(func() {
+			newGreenplum = exampleGreenplum.DeepCopy()
+			newGreenplum.Spec.MasterAndStandby.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+				{Name: "scratch", StorageClassName: "local", Storage: resource.MustParse("1G"), Usage: greenplumv1.GreenplumVolumeUsageTemp},
+			}
+			newGreenplum.Spec.Segments.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+				{Name: "scratch", StorageClassName: "local", Storage: resource.MustParse("1G"), Usage: greenplumv1.GreenplumVolumeUsageTemp},
+				{Name: "fast", StorageClassName: "ssd", Storage: resource.MustParse("1G"), Usage: greenplumv1.GreenplumVolumeUsageTablespace},
+				{Name: "mirrordata", StorageClassName: "cheap", Storage: resource.MustParse("1G"), Usage: greenplumv1.GreenplumVolumeUsageMirror},
+			}
+		})
+
+		It("allows valid volumes", func() {
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+			Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+			Expect(outputReview.Response.Result).To(BeNil())
+			Expect(DecodeLogs(logBuf)).To(ContainAllowedGreenplumClusterEntry())
+		})
+
+		When("PVCs exist for a deleted cluster with additional volumes", func() {
+			BeforeEach(func() {
+				createGPDBTestPVCs(subject.KubeClient, 2, 2, 2,
+					map[string]string{"greenplum-major-version": greenplumcluster.SupportedGreenplumMajorVersion})
+				pvcTemplate := generateTestPVCTemplate(generateGPDBLabels(map[string]string{"greenplum-volume": "fast"}))
+				for i := 0; i < 4; i++ {
+					pvc := pvcTemplate.DeepCopy()
+					pvc.Name = fmt.Sprintf("my-gp-instance-fast-segment-a-%d", i)
+					pvc.Labels["type"] = "segment-a"
+					Expect(subject.KubeClient.Create(nil, pvc)).To(Succeed())
+				}
+			})
+			It("only compares against the data PVCs", func() {
+				outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+				Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+				Expect(outputReview.Response.Result).To(BeNil())
+			})
+		})
+
+		DescribeTable("rejects invalid volumes",
+			func(modify func(*greenplumv1.GreenplumCluster), expectedMessage string) {
+				modify(newGreenplum)
+				outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+				Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+				Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Message": Equal(expectedMessage),
+				})))
+				Expect(DecodeLogs(logBuf)).To(ContainDisallowedGreenplumClusterEntry(expectedMessage))
+			},
+			Entry("name is pgdata", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.Segments.AdditionalVolumes[1].Name = "pgdata"
+			}, `segments additionalVolumes name "pgdata" is reserved for the data volume`),
+			Entry("name is duplicated", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.Segments.AdditionalVolumes[1].Name = "scratch"
+			}, `segments additionalVolumes name "scratch" is used more than once`),
+			Entry("storage < 0", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.MasterAndStandby.AdditionalVolumes[0].Storage = resource.MustParse("-1")
+			}, `invalid masterAndStandby additionalVolumes storage value: "-1": must be greater than or equal to 0`),
+			Entry("mirror volume on masters", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.MasterAndStandby.AdditionalVolumes[0].Usage = greenplumv1.GreenplumVolumeUsageMirror
+			}, `additionalVolumes with usage "mirror" are only allowed on segments when mirrors is set to "yes"`),
+			Entry("mirror volume without mirrors", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.Segments.Mirrors = "no"
+				gp.Spec.MasterAndStandby.AntiAffinity = "no"
+				gp.Spec.Segments.AntiAffinity = "no"
+			}, `additionalVolumes with usage "mirror" are only allowed on segments when mirrors is set to "yes"`),
+			Entry("two mirror volumes", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.Segments.AdditionalVolumes[1].Usage = greenplumv1.GreenplumVolumeUsageMirror
+			}, `segments can have only one additionalVolume with usage "mirror"`),
+			Entry("two temp volumes", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.Segments.AdditionalVolumes[1].Usage = greenplumv1.GreenplumVolumeUsageTemp
+			}, `only one additionalVolume name can have usage "temp"`),
+			Entry("usage differs between masters and segments", func(gp *greenplumv1.GreenplumCluster) {
+				gp.Spec.MasterAndStandby.AdditionalVolumes[0].Usage = greenplumv1.GreenplumVolumeUsageTablespace
+			}, `additionalVolume "scratch" must have the same usage on masterAndStandby and segments`),
+		)
+	})
+
+	When("catalogCheck is specified", func() {
+		var newGreenplum *greenplumv1.GreenplumCluster
+		BeforeEach(func() {
+			newGreenplum = exampleGreenplum.DeepCopy()
+			newGreenplum.Spec.CatalogCheck = &greenplumv1.GreenplumCatalogCheckSpec{Schedule: "0 4 * * sat"}
+		})
+
+		It("allows a valid schedule", func() {
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+			Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+			Expect(outputReview.Response.Result).To(BeNil())
+		})
+
+		It("rejects an invalid schedule", func() {
+			newGreenplum.Spec.CatalogCheck.Schedule = "0 4 * *"
+			expectedMessage := `invalid catalogCheck schedule: expected 5 fields in schedule "0 4 * *", found 4`
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+			Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+			Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Message": Equal(expectedMessage),
+			})))
+			Expect(DecodeLogs(logBuf)).To(ContainDisallowedGreenplumClusterEntry(expectedMessage))
+		})
+	})
+
+	When("logRetention is specified", func() {
+		var newGreenplum *greenplumv1.GreenplumCluster
+		BeforeEach(func() {
+			newGreenplum = exampleGreenplum.DeepCopy()
+			maxSize := resource.MustParse("1Gi")
+			newGreenplum.Spec.LogRetention = &greenplumv1.GreenplumLogRetentionSpec{
+				MaxAge:  &metav1.Duration{Duration: 168 * time.Hour},
+				MaxSize: &maxSize,
+			}
+		})
+
+		It("allows positive limits", func() {
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+			Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+			Expect(outputReview.Response.Result).To(BeNil())
+		})
+
+		DescribeTable("rejects limits that are not positive",
+			func(modify func(*greenplumv1.GreenplumLogRetentionSpec), expectedMessage string) {
+				modify(newGreenplum.Spec.LogRetention)
+				outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+				Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+				Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"Message": Equal(expectedMessage),
+				})))
+				Expect(DecodeLogs(logBuf)).To(ContainDisallowedGreenplumClusterEntry(expectedMessage))
+			},
+			Entry("maxAge", func(spec *greenplumv1.GreenplumLogRetentionSpec) {
+				spec.MaxAge.Duration = 0
+			}, "logRetention maxAge must be positive, got 0s"),
+			Entry("maxSize", func(spec *greenplumv1.GreenplumLogRetentionSpec) {
+				maxSize := resource.MustParse("-1Mi")
+				spec.MaxSize = &maxSize
+			}, "logRetention maxSize must be positive, got -1Mi"),
+		)
+	})
+
+	When("dataSource is specified", func() {
+		var newGreenplum *greenplumv1.GreenplumCluster
+		BeforeEach(func() {
+			newGreenplum = exampleGreenplum.DeepCopy()
+			newGreenplum.Spec.Segments.PrimarySegmentCount = 2
+		})
+
+		expectDisallowed := func(expectedMessage string) {
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+			Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+			Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Message": Equal(expectedMessage),
+			})))
+			Expect(DecodeLogs(logBuf)).To(ContainDisallowedGreenplumClusterEntry(expectedMessage))
+			Expect(recorder.Events).To(Receive(Equal("Warning AdmissionRejected " + expectedMessage)))
+		}
+
+		It("rejects both a snapshot and a backup", func() {
+			newGreenplum.Spec.DataSource = &greenplumv1.GreenplumDataSource{
+				SnapshotName: "nightly",
+				Backup:       &greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", BackupDir: "/backups"},
+			}
+			expectDisallowed("dataSource must have exactly one of snapshotName and backup")
+		})
+
+		It("rejects an empty dataSource", func() {
+			newGreenplum.Spec.DataSource = &greenplumv1.GreenplumDataSource{}
+			expectDisallowed("dataSource must have exactly one of snapshotName and backup")
+		})
+
+		DescribeTable("checks where the backup is",
+			func(backup greenplumv1.GreenplumBackupSource, allowed bool) {
+				newGreenplum.Spec.DataSource = &greenplumv1.GreenplumDataSource{Backup: &backup}
+				outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+				Expect(outputReview.Response.Allowed).To(Equal(allowed))
+				if !allowed {
+					Expect(outputReview.Response.Result.Message).To(Equal("dataSource backup must have exactly one of backupDir and pluginConfig"))
+				}
+			},
+			Entry("backupDir", greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", BackupDir: "/backups"}, true),
+			Entry("pluginConfig", greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", PluginConfig: "/backups/s3.yaml"}, true),
+			Entry("neither", greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405"}, false),
+			Entry("both", greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", BackupDir: "/backups", PluginConfig: "/backups/s3.yaml"}, false),
+		)
+
+		When("the dataSource is a snapshot", func() {
+			var snapshot *greenplumv1beta1.GreenplumSnapshot
+			BeforeEach(func() {
+				newGreenplum.Spec.DataSource = &greenplumv1.GreenplumDataSource{SnapshotName: "nightly"}
+				snapshot = &greenplumv1beta1.GreenplumSnapshot{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "nightly"},
+					Spec:       greenplumv1beta1.GreenplumSnapshotSpec{ClusterName: "old-gp-instance"},
+					Status: greenplumv1beta1.GreenplumSnapshotStatus{
+						Phase:                 greenplumv1beta1.GreenplumSnapshotPhaseReady,
+						GreenplumMajorVersion: greenplumcluster.SupportedGreenplumMajorVersion,
+					},
+				}
+				for _, name := range []string{"master-0", "master-1", "segment-a-0", "segment-a-1", "segment-b-0", "segment-b-1"} {
+					snapshot.Status.VolumeSnapshots = append(snapshot.Status.VolumeSnapshots, greenplumv1beta1.GreenplumSnapshotVolume{
+						PersistentVolumeClaimName: "old-gp-instance-pgdata-" + name,
+						VolumeSnapshotName:        "nightly-old-gp-instance-pgdata-" + name,
+					})
+				}
+			})
+			JustBeforeEach(func() {
+				if snapshot != nil {
+					Expect(subject.KubeClient.Create(nil, snapshot)).To(Succeed())
+				}
+			})
+
+			It("allows a cluster of the same shape", func() {
+				outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+				Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+				Expect(DecodeLogs(logBuf)).To(ContainAllowedGreenplumClusterEntry())
+			})
+
+			It("disallows decreasing primarySegmentCount", func() {
+				newGreenplum.Spec.Segments.PrimarySegmentCount = 1
+				expectDisallowed("my-gp-instance has PVCs for 2 segments. segments.primarySegmentCount cannot be decreased without first deleting PVCs. This will result in a new, empty Greenplum cluster")
+			})
+
+			It("disallows changing standby", func() {
+				newGreenplum.Spec.MasterAndStandby.Standby = "no"
+				newGreenplum.Spec.MasterAndStandby.AntiAffinity = "no"
+				newGreenplum.Spec.Segments.AntiAffinity = "no"
+				expectDisallowed("my-gp-instance has PVCs for 2 masters. masterAndStandby.standby cannot be changed without first deleting PVCs. This will result in a new, empty Greenplum cluster")
+			})
+
+			It("disallows changing mirrors", func() {
+				newGreenplum.Spec.Segments.Mirrors = "no"
+				newGreenplum.Spec.MasterAndStandby.AntiAffinity = "no"
+				newGreenplum.Spec.Segments.AntiAffinity = "no"
+				expectDisallowed("my-gp-instance has PVCs for 2 mirrors. segments.mirrors cannot be changed without first deleting PVCs. This will result in a new, empty Greenplum cluster")
+			})
+
+			When("the snapshot is of another greenplum major version", func() {
+				BeforeEach(func() {
+					snapshot.Status.GreenplumMajorVersion = "5"
+				})
+				It("disallows the request", func() {
+					expectDisallowed(fmt.Sprintf("the existing PVCs for my-gp-instance are not compatible with this controller. Expected PVCs to have greenplum-major-version=%s; found greenplum-major-version=5", greenplumcluster.SupportedGreenplumMajorVersion))
+				})
+			})
+
+			When("the snapshot does not exist", func() {
+				BeforeEach(func() {
+					snapshot = nil
+				})
+				It("disallows the request", func() {
+					expectDisallowed("GreenplumSnapshot nightly not found")
+				})
+			})
+
+			When("the snapshot is not Ready", func() {
+				BeforeEach(func() {
+					snapshot.Status.Phase = greenplumv1beta1.GreenplumSnapshotPhaseQuiesced
+				})
+				It("disallows the request", func() {
+					expectDisallowed("GreenplumSnapshot nightly is not Ready")
+				})
+			})
+
+			When("the snapshot has a volume the cluster does not", func() {
+				BeforeEach(func() {
+					snapshot.Status.VolumeSnapshots = append(snapshot.Status.VolumeSnapshots, greenplumv1beta1.GreenplumSnapshotVolume{
+						PersistentVolumeClaimName: "old-gp-instance-fast-segment-a-0",
+						VolumeSnapshotName:        "nightly-old-gp-instance-fast-segment-a-0",
+					})
+				})
+				It("disallows the request", func() {
+					expectDisallowed(`GreenplumSnapshot nightly has PVC old-gp-instance-fast-segment-a-0, but the cluster has no additionalVolume "fast"`)
+				})
+			})
+
+			When("PVCs of a previous cluster exist", func() {
+				BeforeEach(func() {
+					createGPDBTestPVCs(subject.KubeClient, 2, 2, 2,
+						map[string]string{"greenplum-major-version": greenplumcluster.SupportedGreenplumMajorVersion})
+				})
+				It("disallows the request", func() {
+					expectDisallowed("my-gp-instance has PVCs from a previous cluster. dataSource cannot be used without first deleting PVCs")
+				})
+			})
+		})
+	})
+
+	When("a cluster was deleted with its PVCs retained, and some PVCs were deleted since", func() {
+		var newGreenplum *greenplumv1.GreenplumCluster
+		BeforeEach(func() {
+			newGreenplum = exampleGreenplum.DeepCopy()
+		})
+		retainedLabels := map[string]string{
+			"greenplum-major-version":         greenplumcluster.SupportedGreenplumMajorVersion,
+			"greenplum-primary-segment-count": "2",
+			"greenplum-mirrors":               "yes",
+			"greenplum-standby":               "yes",
+		}
+		expectDisallowed := func(message string) {
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+			Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+			Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{"Message": Equal(message)})))
+			Expect(DecodeLogs(logBuf)).To(ContainDisallowedGreenplumClusterEntry(message))
+		}
+
+		When("the mirror PVCs were deleted", func() {
+			BeforeEach(func() {
+				createGPDBTestPVCs(subject.KubeClient, 2, 2, 0, retainedLabels)
+			})
+			It("disallows turning off mirrors", func() {
+				newGreenplum.Spec.Segments.Mirrors = "no"
+				newGreenplum.Spec.MasterAndStandby.AntiAffinity = "no"
+				newGreenplum.Spec.Segments.AntiAffinity = "no"
+				expectDisallowed("my-gp-instance has PVCs of a deleted cluster with segments.mirrors=yes. segments.mirrors cannot be changed " +
+					"without first deleting PVCs. This will result in a new, empty Greenplum cluster")
+			})
+		})
+		When("the standby PVC was deleted", func() {
+			BeforeEach(func() {
+				createGPDBTestPVCs(subject.KubeClient, 1, 2, 2, retainedLabels)
+			})
+			It("disallows turning off the standby", func() {
+				newGreenplum.Spec.MasterAndStandby.Standby = "no"
+				newGreenplum.Spec.MasterAndStandby.AntiAffinity = "no"
+				newGreenplum.Spec.Segments.AntiAffinity = "no"
+				expectDisallowed("my-gp-instance has PVCs of a deleted cluster with masterAndStandby.standby=yes. masterAndStandby.standby cannot be changed " +
+					"without first deleting PVCs. This will result in a new, empty Greenplum cluster")
+			})
+		})
+		When("the PVCs of a segment were deleted", func() {
+			BeforeEach(func() {
+				createGPDBTestPVCs(subject.KubeClient, 2, 1, 1, retainedLabels)
+			})
+			It("disallows decreasing primarySegmentCount", func() {
+				newGreenplum.Spec.Segments.PrimarySegmentCount = 1
+				expectDisallowed("my-gp-instance has PVCs of a deleted cluster with segments.primarySegmentCount=2. segments.primarySegmentCount cannot be decreased " +
+					"without first deleting PVCs. This will result in a new, empty Greenplum cluster")
+			})
+			It("allows the shape of the deleted cluster", func() {
+				outputReview := postValidateReview(subject.Handler(), newGreenplum, nil)
+				Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+				Expect(DecodeLogs(logBuf)).To(ContainAllowedGreenplumClusterEntry())
+			})
+		})
+	})
 })
 
 func generateGPDBLabels(additionalLabels map[string]string) map[string]string {
@@ -17,6 +17,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -30,6 +31,7 @@ var _ = Describe("validateGreenplumPXFService", func() {
 		reactiveClient := reactive.NewClient(fakeClient.NewFakeClientWithScheme(scheme.Scheme))
 		subject = admission.Handler{
 			KubeClient: reactiveClient,
+			Recorder:   record.NewFakeRecorder(100),
 		}
 		logBuf = gbytes.NewBuffer()
 		admission.Log = gplog.ForTest(logBuf)
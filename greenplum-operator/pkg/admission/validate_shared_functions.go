@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/cronschedule"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
@@ -30,6 +32,32 @@ func validateResourceQuantity(quantity resource.Quantity, typ, field string) (re
 	return
 }
 
+func validateCatalogCheck(newGreenplum greenplumv1.GreenplumCluster) (result *metav1.Status) {
+	catalogCheck := newGreenplum.Spec.CatalogCheck
+	if catalogCheck == nil {
+		return
+	}
+	if _, err := cronschedule.Parse(catalogCheck.Schedule); err != nil {
+		result = &metav1.Status{Message: fmt.Sprintf("invalid catalogCheck schedule: %s", err)}
+	}
+	return
+}
+
+func validateLogRetention(newGreenplum greenplumv1.GreenplumCluster) (result *metav1.Status) {
+	logRetention := newGreenplum.Spec.LogRetention
+	if logRetention == nil {
+		return
+	}
+	if logRetention.MaxAge != nil && logRetention.MaxAge.Duration <= 0 {
+		result = &metav1.Status{Message: fmt.Sprintf("logRetention maxAge must be positive, got %s", logRetention.MaxAge.Duration)}
+		return
+	}
+	if logRetention.MaxSize != nil && logRetention.MaxSize.Sign() <= 0 {
+		result = &metav1.Status{Message: fmt.Sprintf("logRetention maxSize must be positive, got %s", logRetention.MaxSize.String())}
+	}
+	return
+}
+
 func (h *Handler) validateStorageHelper(pvcList *corev1.PersistentVolumeClaimList, newStorage resource.Quantity, newStorageClassName, parentObjectType string) (result *metav1.Status) {
 	if len(pvcList.Items) > 0 {
 		pvc := &pvcList.Items[0]
@@ -49,6 +77,7 @@ func (h *Handler) validateStorageHelper(pvcList *corev1.PersistentVolumeClaimLis
 
 const (
 	pvcInfoFmt       = "%s has PVCs for %d %s."
+	pvcRetainedFmt   = "%s has PVCs of a deleted cluster with %s=%s."
 	pvcErrFmt        = "%s cannot be %s without first deleting PVCs. This will result in a new, empty %s cluster"
 	pvcVersionErrFmt = "the existing PVCs for my-gp-instance are not compatible with this controller. Expected PVCs to have greenplum-major-version=%s; found %s"
 )
@@ -59,6 +88,11 @@ func generateLongPVCErrStr(parentObjectType, parentObject string, previousCount
 	return info + " " + err
 }
 
+func generateRetainedPVCErrStr(parentObject, childObjectPath, previousValue, verb string) string {
+	info := fmt.Sprintf(pvcRetainedFmt, parentObject, childObjectPath, previousValue)
+	return info + " " + generateShortPVCErrStr(childObjectPath, verb, "Greenplum")
+}
+
 func generateShortPVCErrStr(childObjectPath, verb, parentObjectType string) string {
 	return fmt.Sprintf(pvcErrFmt, childObjectPath, verb, parentObjectType)
 }
@@ -1,14 +1,16 @@
 package admission
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
-	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -62,6 +64,12 @@ func (h *Handler) validateUpdateGreenplumCluster(ctx context.Context, oldGreenpl
 		return
 	}
 
+	if antiAffinityTopologyKey(newGreenplum.Spec.MasterAndStandby.AntiAffinityTopologyKey) != antiAffinityTopologyKey(oldGreenplum.Spec.MasterAndStandby.AntiAffinityTopologyKey) ||
+		antiAffinityTopologyKey(newGreenplum.Spec.Segments.AntiAffinityTopologyKey) != antiAffinityTopologyKey(oldGreenplum.Spec.Segments.AntiAffinityTopologyKey) {
+		result = &metav1.Status{Message: "antiAffinityTopologyKey cannot be changed after the cluster has been created"}
+		return
+	}
+
 	if strings.ToLower(newGreenplum.Spec.Segments.Mirrors) != strings.ToLower(oldGreenplum.Spec.Segments.Mirrors) {
 		result = &metav1.Status{Message: "mirrors cannot be changed after the cluster has been created"}
 		return
@@ -79,6 +87,12 @@ func (h *Handler) validateUpdateGreenplumCluster(ctx context.Context, oldGreenpl
 		return
 	}
 
+	if !equality.Semantic.DeepEqual(newGreenplum.Spec.MasterAndStandby.AdditionalVolumes, oldGreenplum.Spec.MasterAndStandby.AdditionalVolumes) ||
+		!equality.Semantic.DeepEqual(newGreenplum.Spec.Segments.AdditionalVolumes, oldGreenplum.Spec.Segments.AdditionalVolumes) {
+		result = &metav1.Status{Message: "additionalVolumes cannot be changed after the cluster has been created"}
+		return
+	}
+
 	if newGreenplum.Spec.Segments.PrimarySegmentCount < oldGreenplum.Spec.Segments.PrimarySegmentCount {
 		result = &metav1.Status{Message: "primarySegmentCount cannot be decreased after the cluster has been created"}
 		return
@@ -89,44 +103,69 @@ func (h *Handler) validateUpdateGreenplumCluster(ctx context.Context, oldGreenpl
 		return
 	}
 
+	if !equality.Semantic.DeepEqual(newGreenplum.Spec.DataSource, oldGreenplum.Spec.DataSource) {
+		result = &metav1.Status{Message: "dataSource cannot be changed after the cluster has been created"}
+		return
+	}
+
 	if newGreenplum.Spec.PXF.ServiceName != oldGreenplum.Spec.PXF.ServiceName {
 		result = &metav1.Status{Message: "PXF serviceName cannot be changed after the cluster has been created"}
 		return
 	}
 
+	result = validateCatalogCheck(newGreenplum)
+	if result != nil {
+		return
+	}
+
+	result = validateLogRetention(newGreenplum)
+	if result != nil {
+		return
+	}
+
 	allowed = true
 	return
 }
 
+// expandSchemaCheckTimeout keeps the check for an expansion schema within the timeout
+// that the API server gives the webhook
+const expandSchemaCheckTimeout = 5 * time.Second
+
 func (h *Handler) validateExpand(ctx context.Context, oldGreenplum, newGreenplum greenplumv1.GreenplumCluster) (result *metav1.Status) {
 	if newGreenplum.Spec.Segments.PrimarySegmentCount > oldGreenplum.Spec.Segments.PrimarySegmentCount {
-		// TODO: Actually query the gpdb status server (once it's implemented)
 		if oldGreenplum.Status.Phase != greenplumv1.GreenplumClusterPhaseRunning {
 			result = &metav1.Status{Message: "updates only supported when cluster is Running"}
 			return
 		}
 
-		activeMaster := executor.GetCurrentActiveMaster(h.PodCmdExecutor, newGreenplum.Namespace)
+		ctx, cancel := context.WithTimeout(ctx, expandSchemaCheckTimeout)
+		defer cancel()
+		activeMaster, masterStatus := gpstatus.ActiveMaster(ctx, h.StatusGetter, newGreenplum.Namespace)
 		if activeMaster == "" {
 			result = &metav1.Status{Message: "failed to contact an active gpdb master"}
 			return
 		}
-
-		checkExpandSchemaQuery := "SELECT count(*) FROM information_schema.schemata WHERE schema_name = 'gpexpand'"
-		checkExpandSchemaCmd := []string{
-			"/bin/bash",
-			"-c",
-			"--",
-			fmt.Sprintf(`source /usr/local/greenplum-db/greenplum_path.sh && psql -d postgres -tAc "%s"`, checkExpandSchemaQuery),
+		if masterStatus.SegmentsError != "" {
+			result = &metav1.Status{Message: "failed to read segment configuration: " + masterStatus.SegmentsError}
+			return
 		}
-		var stdout, stderr bytes.Buffer
-		err := h.PodCmdExecutor.Execute(checkExpandSchemaCmd, newGreenplum.Namespace, activeMaster, &stdout, &stderr)
+		if down := masterStatus.DownSegments(); len(down) > 0 {
+			var hostnames []string
+			for _, segment := range down {
+				hostnames = append(hostnames, segment.Hostname)
+			}
+			result = &metav1.Status{Message: "cannot expand cluster while segments are down: " + strings.Join(hostnames, ", ")}
+			return
+		}
+
+		var expandSchemaCount int
+		err := h.Querier.QueryRow(ctx, querier.Master{Namespace: newGreenplum.Namespace, PodName: activeMaster, Database: "postgres"},
+			"SELECT count(*) FROM pg_namespace WHERE nspname = 'gpexpand'").Scan(&expandSchemaCount)
 		if err != nil {
 			result = &metav1.Status{Message: "failed to check for expansion schema: " + err.Error()}
 			return
 		}
-		queryResult := strings.TrimSpace(stdout.String())
-		if queryResult != "0" {
+		if expandSchemaCount != 0 {
 			result = &metav1.Status{Message: "previous expansion schema exists. you must redistribute data and clean up expansion schema prior to performing another expansion"}
 			return
 		}
@@ -158,3 +197,12 @@ func (h *Handler) validateExpand(ctx context.Context, oldGreenplum, newGreenplum
 	}
 	return
 }
+
+// antiAffinityTopologyKey returns the topology key that a cluster uses, so that filling in the default of a cluster
+// created before antiAffinityTopologyKey existed is not a change
+func antiAffinityTopologyKey(topologyKey string) string {
+	if topologyKey == "" {
+		return corev1.LabelHostname
+	}
+	return topologyKey
+}
@@ -2,6 +2,7 @@ package admission_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -11,19 +12,25 @@ import (
 	"github.com/onsi/gomega/types"
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/admission"
-	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpexpandjob"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/testing/reactive"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
 	. "github.com/pivotal/greenplum-for-kubernetes/pkg/gplog/testing"
+	fakegpstatus "github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus/fake"
 	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
+// noExpansionSchema answers the check for a gpexpand schema on the master
+var noExpansionSchema = map[string][]interface{}{"pg_namespace": {int64(0)}}
+
 var _ = Describe("validateUpdateGreenplumCluster", func() {
 
 	var (
@@ -33,8 +40,10 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 	BeforeEach(func() {
 		reactiveClient := reactive.NewClient(fakeClient.NewFakeClientWithScheme(scheme.Scheme))
 		subject = admission.Handler{
-			KubeClient:     reactiveClient,
-			PodCmdExecutor: &fake.PodExec{StdoutResult: "0\n"},
+			KubeClient:   reactiveClient,
+			Querier:      &fakequerier.Querier{Results: noExpansionSchema},
+			StatusGetter: fakegpstatus.NewHealthyCluster(),
+			Recorder:     record.NewFakeRecorder(100),
 		}
 		logBuf = gbytes.NewBuffer()
 		admission.Log = gplog.ForTest(logBuf)
@@ -179,18 +188,37 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 		})
 		When("webhook fails to contact an active gpdb master", func() {
 			BeforeEach(func() {
-				subject.PodCmdExecutor = &fake.PodExec{
-					ErrorMsgOnMaster0: "fake error",
-					ErrorMsgOnMaster1: "fake error",
-				}
+				subject.StatusGetter = &fakegpstatus.Getter{Errors: map[string]error{
+					"master-0": errors.New("fake error"),
+					"master-1": errors.New("fake error"),
+				}}
 			})
 			It("does not allow increasing primarySegmentCount",
 				Disallowed("failed to contact an active gpdb master"))
 		})
+		When("a segment is down", func() {
+			BeforeEach(func() {
+				getter := fakegpstatus.NewHealthyCluster()
+				getter.Statuses["master-0"].Segments[1].Status = "d"
+				subject.StatusGetter = getter
+			})
+			It("does not allow increasing primarySegmentCount",
+				Disallowed("cannot expand cluster while segments are down: segment-a-0"))
+		})
+		When("the active master cannot read its segment configuration", func() {
+			BeforeEach(func() {
+				getter := fakegpstatus.NewHealthyCluster()
+				getter.Statuses["master-0"].Segments = nil
+				getter.Statuses["master-0"].SegmentsError = "psql failed: exit status 2"
+				subject.StatusGetter = getter
+			})
+			It("does not allow increasing primarySegmentCount",
+				Disallowed("failed to read segment configuration: psql failed: exit status 2"))
+		})
 		When("webhook fails to run query on gpdb master", func() {
 			BeforeEach(func() {
-				subject.PodCmdExecutor = &fake.PodExec{
-					ErrorMsgOnCommand: "fake error",
+				subject.Querier = &fakequerier.Querier{
+					Errors: map[string]error{"pg_namespace": errors.New("fake error")},
 				}
 			})
 			It("does not allow increasing primarySegmentCount",
@@ -198,8 +226,8 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 		})
 		When("previous expansion schema exists", func() {
 			BeforeEach(func() {
-				subject.PodCmdExecutor = &fake.PodExec{
-					StdoutResult: "1\n",
+				subject.Querier = &fakequerier.Querier{
+					Results: map[string][]interface{}{"pg_namespace": {int64(1)}},
 				}
 			})
 			It("does not allow increasing primarySegmentCount",
@@ -394,6 +422,43 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("antiAffinity cannot be changed after the cluster has been created"))
 	})
 
+	DescribeTable("disallows requests that change antiAffinityTopologyKey",
+		func(modify func(spec *greenplumv1.GreenplumClusterSpec)) {
+			oldGreenplum := exampleGreenplum.DeepCopy()
+			oldGreenplum.Spec.MasterAndStandby.AntiAffinityTopologyKey = "kubernetes.io/hostname"
+			oldGreenplum.Spec.Segments.AntiAffinityTopologyKey = "kubernetes.io/hostname"
+			newGreenplum := oldGreenplum.DeepCopy()
+			modify(&newGreenplum.Spec)
+
+			outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+			Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+			Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Message": Equal("antiAffinityTopologyKey cannot be changed after the cluster has been created"),
+			})))
+			Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("antiAffinityTopologyKey cannot be changed after the cluster has been created"))
+		},
+		Entry("masterAndStandby", func(spec *greenplumv1.GreenplumClusterSpec) {
+			spec.MasterAndStandby.AntiAffinityTopologyKey = "topology.kubernetes.io/zone"
+		}),
+		Entry("segments", func(spec *greenplumv1.GreenplumClusterSpec) {
+			spec.Segments.AntiAffinityTopologyKey = "topology.kubernetes.io/zone"
+		}),
+	)
+
+	It("allows requests that fill in the default antiAffinityTopologyKey", func() {
+		oldGreenplum := exampleGreenplum.DeepCopy()
+		oldGreenplum.Spec.MasterAndStandby.AntiAffinityTopologyKey = ""
+		oldGreenplum.Spec.Segments.AntiAffinityTopologyKey = ""
+		newGreenplum := oldGreenplum.DeepCopy()
+		newGreenplum.Spec.MasterAndStandby.AntiAffinityTopologyKey = "kubernetes.io/hostname"
+		newGreenplum.Spec.Segments.AntiAffinityTopologyKey = "kubernetes.io/hostname"
+
+		outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+		Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+	})
+
 	It("disallows requests that change segments mirrors", func() {
 		oldGreenplum := exampleGreenplum.DeepCopy()
 		oldGreenplum.Spec.Segments.Mirrors = "no"
@@ -470,6 +535,23 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("storage cannot be changed after the cluster has been created"))
 	})
 
+	It("disallows requests that change additionalVolumes", func() {
+		oldGreenplum := exampleGreenplum.DeepCopy()
+		oldGreenplum.Spec.Segments.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+			{Name: "fast", StorageClassName: "ssd", Storage: resource.MustParse("1G"), Usage: greenplumv1.GreenplumVolumeUsageTablespace},
+		}
+		newGreenplum := oldGreenplum.DeepCopy()
+		newGreenplum.Spec.Segments.AdditionalVolumes[0].Storage = resource.MustParse("2G")
+
+		outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+		Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+		Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Message": Equal("additionalVolumes cannot be changed after the cluster has been created"),
+		})))
+		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("additionalVolumes cannot be changed after the cluster has been created"))
+	})
+
 	It("disallows requests that change masterAndStandby storageClassName", func() {
 		oldGreenplum := exampleGreenplum.DeepCopy()
 		oldGreenplum.Spec.MasterAndStandby.StorageClassName = "foo"
@@ -500,6 +582,21 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("storageClassName cannot be changed after the cluster has been created"))
 	})
 
+	It("disallows requests that change dataSource", func() {
+		oldGreenplum := exampleGreenplum.DeepCopy()
+		oldGreenplum.Spec.DataSource = &greenplumv1.GreenplumDataSource{SnapshotName: "nightly"}
+		newGreenplum := oldGreenplum.DeepCopy()
+		newGreenplum.Spec.DataSource = nil
+
+		outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+		Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+		Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Message": Equal("dataSource cannot be changed after the cluster has been created"),
+		})))
+		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("dataSource cannot be changed after the cluster has been created"))
+	})
+
 	It("disallows requests that change pxf serviceName", func() {
 		oldGreenplum := exampleGreenplum.DeepCopy()
 		oldGreenplum.Spec.PXF.ServiceName = "foo"
@@ -514,4 +611,43 @@ var _ = Describe("validateUpdateGreenplumCluster", func() {
 		})))
 		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("PXF serviceName cannot be changed after the cluster has been created"))
 	})
+
+	It("allows requests that add a catalogCheck schedule", func() {
+		oldGreenplum := exampleGreenplum.DeepCopy()
+		newGreenplum := oldGreenplum.DeepCopy()
+		newGreenplum.Spec.CatalogCheck = &greenplumv1.GreenplumCatalogCheckSpec{Schedule: "0 4 * * 6"}
+
+		outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+		Expect(outputReview.Response.Allowed).To(BeTrue(), "should be allowed")
+		Expect(outputReview.Response.Result).To(BeNil())
+	})
+
+	It("disallows requests with an invalid catalogCheck schedule", func() {
+		oldGreenplum := exampleGreenplum.DeepCopy()
+		newGreenplum := oldGreenplum.DeepCopy()
+		newGreenplum.Spec.CatalogCheck = &greenplumv1.GreenplumCatalogCheckSpec{Schedule: "0 25 * * *"}
+
+		outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+		Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+		Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Message": Equal(`invalid catalogCheck schedule: invalid value "25" in hour field "25"`),
+		})))
+		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry(`invalid catalogCheck schedule: invalid value "25" in hour field "25"`))
+	})
+
+	It("disallows requests with a logRetention maxAge that is not positive", func() {
+		oldGreenplum := exampleGreenplum.DeepCopy()
+		newGreenplum := oldGreenplum.DeepCopy()
+		newGreenplum.Spec.LogRetention = &greenplumv1.GreenplumLogRetentionSpec{MaxAge: &metav1.Duration{Duration: -time.Hour}}
+
+		outputReview := postValidateReview(subject.Handler(), newGreenplum, oldGreenplum)
+
+		Expect(outputReview.Response.Allowed).To(BeFalse(), "should not be allowed")
+		Expect(outputReview.Response.Result).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Message": Equal("logRetention maxAge must be positive, got -1h0m0s"),
+		})))
+		Expect(DecodeLogs(logBuf)).To(ContainDisallowedEntry("logRetention maxAge must be positive, got -1h0m0s"))
+	})
 })
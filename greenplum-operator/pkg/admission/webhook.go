@@ -1,10 +1,13 @@
 package admission
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
@@ -12,8 +15,12 @@ import (
 	"github.com/pkg/errors"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -21,10 +28,19 @@ import (
 const (
 	WebhookConfigName = "greenplum-validating-webhook-config"
 	ServiceName       = "greenplum-validating-webhook-service"
+	CertSecretName    = "greenplum-validating-webhook-cert"
+)
+
+const (
+	// The leader renews the serving certificate when it expires within this duration
+	certRenewBefore = 30 * 24 * time.Hour
+	// How often the certificate Secret is checked for a renewed certificate, when CertCheckInterval is not set
+	defaultCertCheckInterval = time.Minute
 )
 
 type ValidatingWebhook interface {
 	Run(ctx context.Context) error
+	RenewServingCertificate(ctx context.Context) error
 }
 
 type Webhook struct {
@@ -32,10 +48,17 @@ type Webhook struct {
 	Namespace       string
 	ServiceOwner    metav1.Object
 	WebhookCfgOwner metav1.Object
-	NameSuffix      string
 	Server          Server
 	Handler         http.Handler
 	CertGenerator   CertGenerator
+	// WatchNamespaces restricts the webhook to these namespaces. Empty means all namespaces.
+	WatchNamespaces []string
+	// CertCheckInterval is how often the certificate Secret is read to reload or renew the serving certificate
+	CertCheckInterval time.Duration
+
+	mu             sync.Mutex
+	servingCertPEM []byte
+	servingCert    *tls.Certificate
 }
 
 var _ ValidatingWebhook = &Webhook{}
@@ -43,18 +66,22 @@ var _ ValidatingWebhook = &Webhook{}
 func (w *Webhook) Run(ctx context.Context) error {
 	Log.Info("starting greenplum validating admission webhook server")
 
-	signedCertPEM, signedCertX509, err := w.GenerateAndSignTLSCertificate()
+	signedCertPEM, signedCertX509, err := w.GetServingCertificate(ctx)
 	if err != nil {
 		return fmt.Errorf("getting certificate for webhook: %w", err)
 	}
+	w.setServingCertificate(signedCertPEM, signedCertX509)
 
-	err = w.ReconcileValidatingWebhookConfiguration(context.Background(), signedCertPEM)
+	err = w.ReconcileValidatingWebhookConfiguration(ctx, signedCertPEM)
 	if err != nil {
 		Log.Error(err, "Error creating ValidatingWebhookConfiguration")
 		return fmt.Errorf("creating ValidatingWebhookConfiguration: %w", err)
 	}
 
-	err = w.Server.Start(ctx.Done(), *signedCertX509, ":https", w.Handler)
+	// The leader renews the certificate in the Secret. Every replica serves the renewed one once it reads the Secret again.
+	go wait.UntilWithContext(ctx, w.reloadServingCertificate, w.certCheckInterval())
+
+	err = w.Server.Start(ctx.Done(), w.getServingCertificate, ":https", w.Handler)
 	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("validating admission webhook server start failed: %w", err)
 	}
@@ -62,48 +89,248 @@ func (w *Webhook) Run(ctx context.Context) error {
 	return nil
 }
 
-func (w *Webhook) GenerateAndSignTLSCertificate() ([]byte, *tls.Certificate, error) {
-	svcCommonName := fmt.Sprintf("%s.%s.svc", ServiceName+w.NameSuffix, w.Namespace)
-	rsaKey, csrPEM, err := w.CertGenerator.GenerateX509CertificateSigningRequest(svcCommonName)
+// GetServingCertificate returns the serving certificate that is shared by all replicas of the operator.
+// It is kept in a Secret, so that every replica matches the caBundle of the ValidatingWebhookConfiguration.
+// A new certificate is only signed when the Secret is missing or its certificate cannot be served.
+// A certificate that is about to expire is renewed by RenewServingCertificate instead.
+func (w *Webhook) GetServingCertificate(ctx context.Context) ([]byte, *tls.Certificate, error) {
+	secret, err := w.getCertSecret(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret != nil {
+		certPEM, cert, err := w.parseServingCertificate(secret, 0)
+		if err == nil {
+			Log.Info("using serving certificate from Secret " + CertSecretName)
+			return certPEM, cert, nil
+		}
+		Log.Info("replacing serving certificate", "reason", err.Error())
+	}
+
+	certPEM, keyPEM, cert, err := w.GenerateAndSignTLSCertificate()
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to generate certificate signing request")
+		return nil, nil, err
+	}
+
+	if secret == nil {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      CertSecretName,
+				Namespace: w.Namespace,
+				Labels:    map[string]string{"app": "greenplum-operator"},
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+		if err := controllerutil.SetControllerReference(w.ServiceOwner, secret, scheme.Scheme); err != nil {
+			return nil, nil, errors.Wrap(err, "couldn't set OwnerReferences on webhook certificate Secret")
+		}
+	}
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	if secret.ResourceVersion == "" {
+		err = w.KubeClient.Create(ctx, secret)
+	} else {
+		err = w.KubeClient.Update(ctx, secret)
+	}
+	if apierrs.IsAlreadyExists(err) || apierrs.IsConflict(err) {
+		// Another replica stored its certificate first. Serve that one instead.
+		secret, err = w.getCertSecret(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if secret == nil {
+			return nil, nil, errors.New("Secret " + CertSecretName + " was deleted while storing the serving certificate")
+		}
+		return w.parseServingCertificate(secret, 0)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to store serving certificate in Secret "+CertSecretName)
+	}
+	Log.Info("stored serving certificate in Secret " + CertSecretName)
+	return certPEM, cert, nil
+}
+
+func (w *Webhook) getCertSecret(ctx context.Context) (*corev1.Secret, error) {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: w.Namespace, Name: CertSecretName}
+	if err := w.KubeClient.Get(ctx, secretKey, &secret); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get Secret "+CertSecretName)
+	}
+	return &secret, nil
+}
+
+// RenewServingCertificate replaces the certificate in the Secret before it expires, until ctx is done.
+// Only the leader runs it, so that the replicas do not race to replace each other's certificates.
+func (w *Webhook) RenewServingCertificate(ctx context.Context) error {
+	for {
+		if err := w.renewServingCertificate(ctx); err != nil {
+			Log.Error(err, "failed to renew serving certificate")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.certCheckInterval()):
+		}
+	}
+}
+
+func (w *Webhook) renewServingCertificate(ctx context.Context) error {
+	secret, err := w.getCertSecret(ctx)
+	if err != nil || secret == nil {
+		// A missing Secret is created by Run
+		return err
+	}
+	_, _, err = w.parseServingCertificate(secret, certRenewBefore)
+	if err == nil {
+		return nil
+	}
+	Log.Info("renewing serving certificate", "reason", err.Error())
+
+	certPEM, keyPEM, _, err := w.GenerateAndSignTLSCertificate()
+	if err != nil {
+		return err
+	}
+	// Trust both certificates until every replica has reloaded the new one
+	caBundle := append(append([]byte{}, certPEM...), secret.Data[corev1.TLSCertKey]...)
+	if err := w.ReconcileValidatingWebhookConfiguration(ctx, caBundle); err != nil {
+		return err
+	}
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	if err := w.KubeClient.Update(ctx, secret); err != nil {
+		return errors.Wrap(err, "failed to store serving certificate in Secret "+CertSecretName)
+	}
+	Log.Info("renewed serving certificate in Secret " + CertSecretName)
+	return nil
+}
+
+func (w *Webhook) reloadServingCertificate(ctx context.Context) {
+	secret, err := w.getCertSecret(ctx)
+	if err != nil {
+		Log.Error(err, "failed to reload serving certificate")
+		return
+	}
+	if secret == nil {
+		return
+	}
+	w.mu.Lock()
+	current := w.servingCertPEM
+	w.mu.Unlock()
+	if bytes.Equal(secret.Data[corev1.TLSCertKey], current) {
+		return
+	}
+	certPEM, cert, err := w.parseServingCertificate(secret, 0)
+	if err != nil {
+		Log.Error(err, "failed to reload serving certificate")
+		return
+	}
+	w.setServingCertificate(certPEM, cert)
+	Log.Info("reloaded serving certificate from Secret " + CertSecretName)
+}
+
+func (w *Webhook) setServingCertificate(certPEM []byte, cert *tls.Certificate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.servingCertPEM = certPEM
+	w.servingCert = cert
+}
+
+func (w *Webhook) getServingCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.servingCert, nil
+}
+
+func (w *Webhook) certCheckInterval() time.Duration {
+	if w.CertCheckInterval == 0 {
+		return defaultCertCheckInterval
+	}
+	return w.CertCheckInterval
+}
+
+// parseServingCertificate returns an error if the certificate in the Secret cannot be served,
+// or if it expires within renewBefore.
+func (w *Webhook) parseServingCertificate(secret *corev1.Secret, renewBefore time.Duration) ([]byte, *tls.Certificate, error) {
+	certPEM := secret.Data[corev1.TLSCertKey]
+	cert, err := tls.X509KeyPair(certPEM, secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid certificate in Secret "+CertSecretName)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid certificate in Secret "+CertSecretName)
+	}
+	if leaf.Subject.CommonName != w.serviceCommonName() {
+		return nil, nil, fmt.Errorf("certificate in Secret %s is for %s", CertSecretName, leaf.Subject.CommonName)
+	}
+	if time.Now().Add(renewBefore).After(leaf.NotAfter) {
+		return nil, nil, fmt.Errorf("certificate in Secret %s expires at %s", CertSecretName, leaf.NotAfter.Format(time.RFC3339))
+	}
+	return certPEM, &cert, nil
+}
+
+func (w *Webhook) serviceCommonName() string {
+	return fmt.Sprintf("%s.%s.svc", ServiceName, w.Namespace)
+}
+
+func (w *Webhook) GenerateAndSignTLSCertificate() (certPEM []byte, keyPEM []byte, cert *tls.Certificate, err error) {
+	rsaKey, csrPEM, err := w.CertGenerator.GenerateX509CertificateSigningRequest(w.serviceCommonName())
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to generate certificate signing request")
 	}
 
 	csr, err := w.CertGenerator.CreateCertificateSigningRequest(csrPEM)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to create certificate signing request")
+		return nil, nil, nil, errors.Wrap(err, "failed to create certificate signing request")
 	}
 
 	approvedCSR, err := w.CertGenerator.ApproveCertificateSigningRequest(csr)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to approve certificate signing request")
+		return nil, nil, nil, errors.Wrap(err, "failed to approve certificate signing request")
 	}
 
 	signedCertPEM, err := w.CertGenerator.WaitForSignedCertificate(approvedCSR, 30*time.Second)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failure while waiting for approval")
+		return nil, nil, nil, errors.Wrap(err, "failure while waiting for approval")
 	}
 
 	signedCertX509, err := w.CertGenerator.GetCertificate(signedCertPEM, rsaKey)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "error loading keypair")
+		return nil, nil, nil, errors.Wrap(err, "error loading keypair")
 	}
 
-	return signedCertPEM, &signedCertX509, nil
+	return signedCertPEM, EncodePrivateKey(rsaKey), &signedCertX509, nil
 }
 
 func (w *Webhook) ReconcileValidatingWebhookConfiguration(ctx context.Context, signedCert []byte) error {
 	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: WebhookConfigName,
+			Name: w.WebhookConfigName(),
 		},
 	}
-	result, err := controllerutil.CreateOrUpdate(ctx, w.KubeClient, webhookConfig, func() error {
-		w.ModifyWebhookConfiguration(webhookConfig, signedCert)
-		if err := controllerutil.SetControllerReference(w.WebhookCfgOwner, webhookConfig, scheme.Scheme); err != nil {
-			return errors.Wrap(err, "couldn't set OwnerReferences on ValidatingWebhookConfig")
-		}
-		return nil
+	// Every replica of the operator reconciles the same objects when it starts, so retry when another one wins the race
+	var result controllerutil.OperationResult
+	err := retry.OnError(retry.DefaultRetry, isConcurrentModification, func() (err error) {
+		result, err = controllerutil.CreateOrUpdate(ctx, w.KubeClient, webhookConfig, func() error {
+			caBundle := signedCert
+			if len(webhookConfig.Webhooks) > 0 && bytes.Contains(webhookConfig.Webhooks[0].ClientConfig.CABundle, signedCert) {
+				// Keep trusting the previous certificate while the leader renews it
+				caBundle = webhookConfig.Webhooks[0].ClientConfig.CABundle
+			}
+			w.ModifyWebhookConfiguration(webhookConfig, caBundle)
+			if err := controllerutil.SetControllerReference(w.WebhookCfgOwner, webhookConfig, scheme.Scheme); err != nil {
+				return errors.Wrap(err, "couldn't set OwnerReferences on ValidatingWebhookConfig")
+			}
+			return nil
+		})
+		return
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to create ValidatingWebhookConfiguration")
@@ -112,18 +339,43 @@ func (w *Webhook) ReconcileValidatingWebhookConfiguration(ctx context.Context, s
 		Log.Info("ValidatingWebhookConfiguration: " + string(result))
 	}
 
-	webhookService := w.CreateSVCForValidatingWebhookConfiguration()
-	err = controllerutil.SetControllerReference(w.ServiceOwner, webhookService, scheme.Scheme)
-	if err != nil {
-		return errors.Wrap(err, "couldn't set OwnerReferences on webhook Service")
-	}
-	err = w.KubeClient.Create(ctx, webhookService)
+	desiredService := w.CreateSVCForValidatingWebhookConfiguration()
+	webhookService := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: desiredService.Name, Namespace: desiredService.Namespace}}
+	err = retry.OnError(retry.DefaultRetry, isConcurrentModification, func() (err error) {
+		result, err = controllerutil.CreateOrUpdate(ctx, w.KubeClient, webhookService, func() error {
+			webhookService.Labels = desiredService.Labels
+			webhookService.Spec.Ports = desiredService.Spec.Ports
+			webhookService.Spec.Selector = desiredService.Spec.Selector
+			webhookService.Spec.Type = desiredService.Spec.Type
+			if err := controllerutil.SetControllerReference(w.ServiceOwner, webhookService, scheme.Scheme); err != nil {
+				return errors.Wrap(err, "couldn't set OwnerReferences on webhook Service")
+			}
+			return nil
+		})
+		return
+	})
 	if err != nil {
 		return errors.Wrap(err, "error creating Service for Webhook")
 	}
+	if result != controllerutil.OperationResultNone {
+		Log.Info("webhook Service: " + string(result))
+	}
 	return nil
 }
 
+func isConcurrentModification(err error) bool {
+	return apierrs.IsAlreadyExists(err) || apierrs.IsConflict(err)
+}
+
+// WebhookConfigName is the name of the ValidatingWebhookConfiguration of this operator.
+// A namespace-scoped operator names it after its own namespace, so that several of them can run in one cluster.
+func (w *Webhook) WebhookConfigName() string {
+	if len(w.WatchNamespaces) == 0 {
+		return WebhookConfigName
+	}
+	return WebhookConfigName + "-" + w.Namespace
+}
+
 func (w *Webhook) ModifyWebhookConfiguration(webhookConfig *admissionregistrationv1.ValidatingWebhookConfiguration, signedCertBundle []byte) {
 	fail := admissionregistrationv1.Fail
 	sideEffectClassNone := admissionregistrationv1.SideEffectClassNone
@@ -132,13 +384,25 @@ func (w *Webhook) ModifyWebhookConfiguration(webhookConfig *admissionregistratio
 		webhookConfig.Labels = make(map[string]string)
 	}
 	webhookConfig.Labels["app"] = "greenplum-operator"
+	var namespaceSelector *metav1.LabelSelector
+	if len(w.WatchNamespaces) > 0 {
+		namespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{
+					Key:      corev1.LabelMetadataName,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   w.WatchNamespaces,
+				},
+			},
+		}
+	}
 	webhookConfig.Webhooks = []admissionregistrationv1.ValidatingWebhook{
 		{
 			Name: "greenplum.pivotal.io",
 			ClientConfig: admissionregistrationv1.WebhookClientConfig{
 				Service: &admissionregistrationv1.ServiceReference{
 					Namespace: w.Namespace,
-					Name:      ServiceName + w.NameSuffix,
+					Name:      ServiceName,
 					Path:      heapvalue.NewString("/validate"),
 				},
 				CABundle: signedCertBundle,
@@ -161,6 +425,7 @@ func (w *Webhook) ModifyWebhookConfiguration(webhookConfig *admissionregistratio
 					},
 				},
 			},
+			NamespaceSelector:       namespaceSelector,
 			FailurePolicy:           &fail,
 			SideEffects:             &sideEffectClassNone,
 			AdmissionReviewVersions: []string{"v1beta1"},
@@ -175,7 +440,7 @@ func (w *Webhook) CreateSVCForValidatingWebhookConfiguration() *corev1.Service {
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      ServiceName + w.NameSuffix,
+			Name:      ServiceName,
 			Namespace: w.Namespace,
 			Labels:    map[string]string{"app": "greenplum-operator"},
 		},
@@ -5,14 +5,20 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"time"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gstruct"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/admission"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/testing/reactive"
@@ -21,8 +27,10 @@ import (
 	"k8s.io/api/certificates/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/testing"
 	fakeClient "sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -44,7 +52,7 @@ var _ = Describe("Validating Admission Webhook", func() {
 		cg = &StubCertGenerator{}
 
 		hashString := "-hash123-hash456"
-		serviceName = admission.ServiceName + hashString
+		serviceName = admission.ServiceName
 
 		fakeOwnerPod = &corev1.Pod{
 			TypeMeta: metav1.TypeMeta{
@@ -75,7 +83,6 @@ var _ = Describe("Validating Admission Webhook", func() {
 			Namespace:       "test-ns",
 			ServiceOwner:    fakeOwnerPod,
 			WebhookCfgOwner: fakeOwnerCRD,
-			NameSuffix:      hashString,
 			CertGenerator:   cg,
 		}
 		logBuf = gbytes.NewBuffer()
@@ -83,9 +90,16 @@ var _ = Describe("Validating Admission Webhook", func() {
 	})
 
 	Describe("Run", func() {
-		var mockServer *MockServer
+		var (
+			mockServer *MockServer
+			stoppedCtx context.Context
+		)
 
 		BeforeEach(func() {
+			// Run returns as soon as the server has started when its context is already done
+			var cancel context.CancelFunc
+			stoppedCtx, cancel = context.WithCancel(context.Background())
+			cancel()
 			mockServer = &MockServer{}
 			subject.Server = mockServer
 			subject.Handler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
@@ -95,14 +109,14 @@ var _ = Describe("Validating Admission Webhook", func() {
 		})
 		When("all is good", func() {
 			It("logs startup and shutdown messages", func() {
-				Expect(subject.Run(nil)).To(Succeed())
+				Expect(subject.Run(stoppedCtx)).To(Succeed())
 				Expect(logBuf).To(gbytes.Say("starting greenplum validating admission webhook server"))
 				// shut down
 				Expect(logBuf).To(gbytes.Say("shutting down greenplum validating admission webhook server"))
 			})
 
 			It("Creates validatingwebhookconfiguration", func() {
-				Expect(subject.Run(nil)).To(Succeed())
+				Expect(subject.Run(stoppedCtx)).To(Succeed())
 				var webhookConfig admissionregistrationv1.ValidatingWebhookConfiguration
 				webhookKey := types.NamespacedName{Name: admission.WebhookConfigName}
 				Expect(reactiveClient.Get(nil, webhookKey, &webhookConfig)).To(Succeed())
@@ -116,7 +130,7 @@ var _ = Describe("Validating Admission Webhook", func() {
 				go subject.Run(ctx)
 
 				Eventually(mockServer.started, 5*time.Second).Should(BeClosed())
-				Expect(mockServer.cert).To(Equal(cg.getCertStub.returnedX509))
+				Expect(mockServer.getCertificate(nil)).To(Equal(&cg.getCertStub.returnedX509))
 				Expect(mockServer.addr).To(Equal(":https"))
 				resp := httptest.NewRecorder()
 				mockServer.handler.ServeHTTP(resp, nil)
@@ -126,12 +140,44 @@ var _ = Describe("Validating Admission Webhook", func() {
 				cancel()
 				Eventually(logBuf).Should(gbytes.Say("shutting down greenplum validating admission webhook server"))
 			})
+
+			It("serves the certificate that the leader renews in the Secret", func() {
+				subject.CertCheckInterval = 10 * time.Millisecond
+				mockServer.started = make(chan struct{})
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					_ = subject.Run(ctx)
+				}()
+				defer func() {
+					cancel()
+					Eventually(done).Should(BeClosed())
+				}()
+				Eventually(mockServer.started, 5*time.Second).Should(BeClosed())
+
+				By("renewing the certificate in the Secret")
+				renewedCertPEM, renewedKeyPEM := selfSignedCert(serviceName+".test-ns.svc", time.Now().Add(365*24*time.Hour))
+				var secret corev1.Secret
+				secretKey := types.NamespacedName{Namespace: "test-ns", Name: admission.CertSecretName}
+				Expect(reactiveClient.Get(nil, secretKey, &secret)).To(Succeed())
+				secret.Data = map[string][]byte{"tls.crt": renewedCertPEM, "tls.key": renewedKeyPEM}
+				Expect(reactiveClient.Update(nil, &secret)).To(Succeed())
+
+				renewedCert, err := tls.X509KeyPair(renewedCertPEM, renewedKeyPEM)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(func() [][]byte {
+					cert, _ := mockServer.getCertificate(nil)
+					return cert.Certificate
+				}).Should(Equal(renewedCert.Certificate))
+				Expect(logBuf).To(gbytes.Say("reloaded serving certificate from Secret greenplum-validating-webhook-cert"))
+			})
 		})
 
 		When("GenerateAndSignTLSCertificate fails", func() {
 			It("does not start a webhook server", func() {
 				cg.getCertStub.err = errors.New("injected failure")
-				err := subject.Run(nil)
+				err := subject.Run(stoppedCtx)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(MatchRegexp(`getting certificate for webhook: [^"]*: injected failure`))
 				Expect(logBuf).NotTo(gbytes.Say("shutting down greenplum validating admission webhook server"))
@@ -145,7 +191,7 @@ var _ = Describe("Validating Admission Webhook", func() {
 				})
 			})
 			It("returns an error", func() {
-				err := subject.Run(nil)
+				err := subject.Run(stoppedCtx)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(MatchRegexp(`creating ValidatingWebhookConfiguration: [^"]*: intentional failure`))
 				Expect(logBuf).NotTo(gbytes.Say("shutting down greenplum validating admission webhook server"))
@@ -157,7 +203,7 @@ var _ = Describe("Validating Admission Webhook", func() {
 				subject.Server = &MockServer{err: errors.New("intentional failure")}
 			})
 			It("returns an error", func() {
-				Expect(subject.Run(nil)).To(MatchError("validating admission webhook server start failed: intentional failure"))
+				Expect(subject.Run(stoppedCtx)).To(MatchError("validating admission webhook server start failed: intentional failure"))
 				Expect(logBuf).NotTo(gbytes.Say("shutting down greenplum validating admission webhook server"))
 			})
 		})
@@ -166,7 +212,7 @@ var _ = Describe("Validating Admission Webhook", func() {
 	Describe("GenerateAndSignTLSCertificate", func() {
 		When("all is good", func() {
 			It("generates a certificate", func() {
-				signedCertPEM, signedCertX509, err := subject.GenerateAndSignTLSCertificate()
+				signedCertPEM, keyPEM, signedCertX509, err := subject.GenerateAndSignTLSCertificate()
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(cg.generateStub.receivedCommonName).To(Equal(serviceName + ".test-ns.svc"))
@@ -178,13 +224,14 @@ var _ = Describe("Validating Admission Webhook", func() {
 				Expect(cg.getCertStub.receivedKey).To(And(Not(BeNil()), Equal(cg.generateStub.returnedKey)))
 
 				Expect(signedCertPEM).To(And(Not(BeNil()), Equal(cg.waitStub.returnedCert)))
+				Expect(keyPEM).To(Equal(admission.EncodePrivateKey(cg.generateStub.returnedKey)))
 				Expect(signedCertX509).To(Equal(&cg.getCertStub.returnedX509))
 			})
 		})
 
 		ItReturnsAnError := func(expectedError string) {
 			It("returns an error", func() {
-				_, _, err := subject.GenerateAndSignTLSCertificate()
+				_, _, _, err := subject.GenerateAndSignTLSCertificate()
 				Expect(err).To(MatchError(expectedError))
 			})
 		}
@@ -222,12 +269,222 @@ var _ = Describe("Validating Admission Webhook", func() {
 				cg.getCertStub.err = errors.New("error")
 			})
 			It("returns an error", func() {
-				_, _, err := subject.GenerateAndSignTLSCertificate()
+				_, _, _, err := subject.GenerateAndSignTLSCertificate()
 				Expect(err).To(MatchError("error loading keypair: error"))
 			})
 		})
 	})
 
+	Describe("GetServingCertificate", func() {
+		var secretKey types.NamespacedName
+		BeforeEach(func() {
+			secretKey = types.NamespacedName{Namespace: "test-ns", Name: admission.CertSecretName}
+		})
+		createCertSecret := func(certPEM, keyPEM []byte) {
+			Expect(reactiveClient.Create(nil, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: secretKey.Namespace, Name: secretKey.Name},
+				Type:       corev1.SecretTypeTLS,
+				Data:       map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM},
+			})).To(Succeed())
+		}
+
+		When("there is no stored certificate", func() {
+			It("signs a certificate and stores it in a Secret", func() {
+				certPEM, cert, err := subject.GetServingCertificate(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(certPEM).To(Equal(cg.waitStub.returnedCert))
+				Expect(cert).To(Equal(&cg.getCertStub.returnedX509))
+
+				var secret corev1.Secret
+				Expect(reactiveClient.Get(nil, secretKey, &secret)).To(Succeed())
+				Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+				Expect(secret.Data).To(Equal(map[string][]byte{
+					"tls.crt": cg.waitStub.returnedCert,
+					"tls.key": admission.EncodePrivateKey(cg.generateStub.returnedKey),
+				}))
+				Expect(metav1.IsControlledBy(&secret, fakeOwnerPod)).To(BeTrue())
+				Expect(logBuf).To(gbytes.Say("stored serving certificate in Secret greenplum-validating-webhook-cert"))
+			})
+
+			When("another replica stores its certificate first", func() {
+				var otherCertPEM []byte
+				BeforeEach(func() {
+					var otherKeyPEM []byte
+					otherCertPEM, otherKeyPEM = selfSignedCert(serviceName+".test-ns.svc", time.Now().Add(365*24*time.Hour))
+					createCertSecret(otherCertPEM, otherKeyPEM)
+					By("not finding the Secret until the other replica has created it")
+					firstGet := true
+					reactiveClient.PrependReactor("get", "secrets", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+						if !firstGet {
+							return false, nil, nil
+						}
+						firstGet = false
+						return true, nil, apierrs.NewNotFound(schema.GroupResource{Resource: "secrets"}, admission.CertSecretName)
+					})
+				})
+				It("serves the certificate of the other replica", func() {
+					certPEM, _, err := subject.GetServingCertificate(context.Background())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(certPEM).To(Equal(otherCertPEM))
+				})
+			})
+
+			When("storing the certificate fails", func() {
+				BeforeEach(func() {
+					reactiveClient.PrependReactor("create", "secrets", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+						return true, nil, errors.New("injected error")
+					})
+				})
+				It("returns an error", func() {
+					_, _, err := subject.GetServingCertificate(context.Background())
+					Expect(err).To(MatchError("failed to store serving certificate in Secret greenplum-validating-webhook-cert: injected error"))
+				})
+			})
+
+			When("signing the certificate fails", func() {
+				BeforeEach(func() {
+					cg.waitStub.err = errors.New("error")
+				})
+				It("returns an error", func() {
+					_, _, err := subject.GetServingCertificate(context.Background())
+					Expect(err).To(MatchError("failure while waiting for approval: error"))
+				})
+			})
+		})
+
+		DescribeTable("serves a valid stored certificate without signing a new one",
+			func(notAfter time.Time) {
+				storedCertPEM, storedKeyPEM := selfSignedCert(serviceName+".test-ns.svc", notAfter)
+				createCertSecret(storedCertPEM, storedKeyPEM)
+
+				certPEM, cert, err := subject.GetServingCertificate(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(certPEM).To(Equal(storedCertPEM))
+				Expect(cert.Certificate).To(HaveLen(1))
+				Expect(cg.createStub.receivedCert).To(BeNil(), "should not create a CSR")
+				Expect(logBuf).To(gbytes.Say("using serving certificate from Secret greenplum-validating-webhook-cert"))
+			},
+			Entry("valid for a year", time.Now().Add(365*24*time.Hour)),
+			Entry("expiring soon, so that only the leader renews it", time.Now().Add(24*time.Hour)),
+		)
+
+		DescribeTable("replaces a stored certificate that cannot be used",
+			func(commonName string, notAfter time.Time, expectedReason string) {
+				storedCertPEM, storedKeyPEM := selfSignedCert(commonName, notAfter)
+				createCertSecret(storedCertPEM, storedKeyPEM)
+
+				certPEM, _, err := subject.GetServingCertificate(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(certPEM).To(Equal(cg.waitStub.returnedCert))
+				var secret corev1.Secret
+				Expect(reactiveClient.Get(nil, secretKey, &secret)).To(Succeed())
+				Expect(secret.Data["tls.crt"]).To(Equal(cg.waitStub.returnedCert))
+				Expect(logBuf).To(gbytes.Say(expectedReason))
+			},
+			Entry("expired", admission.ServiceName+".test-ns.svc", time.Now().Add(-time.Minute),
+				"certificate in Secret greenplum-validating-webhook-cert expires at"),
+			Entry("for another service", "another-service.test-ns.svc", time.Now().Add(365*24*time.Hour),
+				"certificate in Secret greenplum-validating-webhook-cert is for another-service.test-ns.svc"),
+		)
+
+		When("getting the Secret fails", func() {
+			BeforeEach(func() {
+				reactiveClient.PrependReactor("get", "secrets", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, errors.New("injected error")
+				})
+			})
+			It("returns an error", func() {
+				_, _, err := subject.GetServingCertificate(context.Background())
+				Expect(err).To(MatchError("failed to get Secret greenplum-validating-webhook-cert: injected error"))
+			})
+		})
+	})
+
+	Describe("RenewServingCertificate", func() {
+		var (
+			secretKey     types.NamespacedName
+			storedCertPEM []byte
+			storedKeyPEM  []byte
+		)
+		BeforeEach(func() {
+			secretKey = types.NamespacedName{Namespace: "test-ns", Name: admission.CertSecretName}
+			subject.CertCheckInterval = 10 * time.Millisecond
+		})
+		JustBeforeEach(func() {
+			Expect(reactiveClient.Create(nil, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: secretKey.Namespace, Name: secretKey.Name},
+				Type:       corev1.SecretTypeTLS,
+				Data:       map[string][]byte{"tls.crt": storedCertPEM, "tls.key": storedKeyPEM},
+			})).To(Succeed())
+			Expect(subject.ReconcileValidatingWebhookConfiguration(nil, storedCertPEM)).To(Succeed())
+		})
+		runOnce := func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			// The certificate is checked once before the canceled context is noticed
+			Expect(subject.RenewServingCertificate(ctx)).To(Succeed())
+		}
+		getCABundle := func() []byte {
+			var webhookConfig admissionregistrationv1.ValidatingWebhookConfiguration
+			Expect(reactiveClient.Get(nil, types.NamespacedName{Name: admission.WebhookConfigName}, &webhookConfig)).To(Succeed())
+			return webhookConfig.Webhooks[0].ClientConfig.CABundle
+		}
+
+		When("the stored certificate expires soon", func() {
+			BeforeEach(func() {
+				storedCertPEM, storedKeyPEM = selfSignedCert(serviceName+".test-ns.svc", time.Now().Add(24*time.Hour))
+			})
+			It("stores a new certificate, and trusts both the old and the new one", func() {
+				runOnce()
+				var secret corev1.Secret
+				Expect(reactiveClient.Get(nil, secretKey, &secret)).To(Succeed())
+				Expect(secret.Data).To(Equal(map[string][]byte{
+					"tls.crt": cg.waitStub.returnedCert,
+					"tls.key": admission.EncodePrivateKey(cg.generateStub.returnedKey),
+				}))
+				Expect(getCABundle()).To(Equal(append(append([]byte{}, cg.waitStub.returnedCert...), storedCertPEM...)))
+				Expect(logBuf).To(gbytes.Say("renewing serving certificate.*expires at"))
+				Expect(logBuf).To(gbytes.Say("renewed serving certificate in Secret greenplum-validating-webhook-cert"))
+			})
+
+			When("a replica starts before the Secret is updated", func() {
+				It("keeps trusting the new certificate", func() {
+					runOnce()
+					caBundle := getCABundle()
+					Expect(subject.ReconcileValidatingWebhookConfiguration(nil, storedCertPEM)).To(Succeed())
+					Expect(getCABundle()).To(Equal(caBundle))
+				})
+			})
+
+			When("signing the certificate fails", func() {
+				BeforeEach(func() {
+					cg.waitStub.err = errors.New("injected error")
+				})
+				It("keeps the stored certificate, and logs the error", func() {
+					runOnce()
+					var secret corev1.Secret
+					Expect(reactiveClient.Get(nil, secretKey, &secret)).To(Succeed())
+					Expect(secret.Data["tls.crt"]).To(Equal(storedCertPEM))
+					Expect(getCABundle()).To(Equal(storedCertPEM))
+					Expect(logBuf).To(gbytes.Say("failed to renew serving certificate.*failure while waiting for approval: injected error"))
+				})
+			})
+		})
+
+		When("the stored certificate is valid for longer", func() {
+			BeforeEach(func() {
+				storedCertPEM, storedKeyPEM = selfSignedCert(serviceName+".test-ns.svc", time.Now().Add(365*24*time.Hour))
+			})
+			It("keeps it", func() {
+				runOnce()
+				var secret corev1.Secret
+				Expect(reactiveClient.Get(nil, secretKey, &secret)).To(Succeed())
+				Expect(secret.Data["tls.crt"]).To(Equal(storedCertPEM))
+				Expect(cg.createStub.receivedCert).To(BeNil(), "should not create a CSR")
+			})
+		})
+	})
+
 	Describe("ReconcileValidatingWebhookConfiguration", func() {
 		var reactiveClient *reactive.Client
 		BeforeEach(func() {
@@ -260,6 +517,20 @@ var _ = Describe("Validating Admission Webhook", func() {
 			})
 		})
 
+		When("the webhook watches some namespaces", func() {
+			BeforeEach(func() {
+				subject.WatchNamespaces = []string{"tenant-a"}
+				Expect(subject.ReconcileValidatingWebhookConfiguration(nil, []byte("signed cert"))).To(Succeed())
+			})
+			It("names the ValidatingWebhookConfiguration after the operator namespace", func() {
+				Expect(subject.WebhookConfigName()).To(Equal(admission.WebhookConfigName + "-test-ns"))
+				var webhookConfig admissionregistrationv1.ValidatingWebhookConfiguration
+				Expect(reactiveClient.Get(nil, types.NamespacedName{Name: admission.WebhookConfigName + "-test-ns"}, &webhookConfig)).To(Succeed())
+				Expect(webhookConfig.Webhooks[0].NamespaceSelector).NotTo(BeNil())
+				err := reactiveClient.Get(nil, types.NamespacedName{Name: admission.WebhookConfigName}, &webhookConfig)
+				Expect(apierrs.IsNotFound(err)).To(BeTrue())
+			})
+		})
 		When("create ValidatingWebhookConfiguration fails", func() {
 			BeforeEach(func() {
 				reactiveClient.PrependReactor("create", "validatingwebhookconfigurations", func(action testing.Action) (handled bool, ret runtime.Object, err error) {
@@ -284,6 +555,50 @@ var _ = Describe("Validating Admission Webhook", func() {
 			})
 		})
 
+		When("the Service already exists", func() {
+			BeforeEach(func() {
+				Expect(reactiveClient.Create(nil, &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: serviceName},
+					Spec: corev1.ServiceSpec{
+						ClusterIP: "10.0.0.1",
+						Ports:     []corev1.ServicePort{{Name: "old", Port: 8443}},
+					},
+				})).To(Succeed())
+			})
+			It("updates it", func() {
+				Expect(subject.ReconcileValidatingWebhookConfiguration(nil, []byte("signed cert"))).To(Succeed())
+				var service corev1.Service
+				serviceKey := types.NamespacedName{Namespace: "test-ns", Name: serviceName}
+				Expect(reactiveClient.Get(nil, serviceKey, &service)).To(Succeed())
+				Expect(service.Spec.ClusterIP).To(Equal("10.0.0.1"))
+				Expect(service.Spec.Ports).To(ConsistOf(MatchFields(IgnoreExtras, Fields{"Name": Equal("webhook"), "Port": BeEquivalentTo(443)})))
+				Expect(service.Labels).To(Equal(map[string]string{"app": "greenplum-operator"}))
+				Expect(metav1.IsControlledBy(&service, fakeOwnerPod)).To(BeTrue())
+			})
+		})
+
+		When("another replica creates the objects at the same time", func() {
+			BeforeEach(func() {
+				for _, resource := range []string{"validatingwebhookconfigurations", "services"} {
+					raced := false
+					reactiveClient.PrependReactor("create", resource, func(action testing.Action) (handled bool, ret runtime.Object, err error) {
+						if raced {
+							return false, nil, nil
+						}
+						raced = true
+						return true, nil, apierrs.NewAlreadyExists(schema.GroupResource{Resource: action.GetResource().Resource}, "raced")
+					})
+				}
+			})
+			It("retries", func() {
+				Expect(subject.ReconcileValidatingWebhookConfiguration(nil, []byte("signed cert"))).To(Succeed())
+				var webhookConfig admissionregistrationv1.ValidatingWebhookConfiguration
+				Expect(reactiveClient.Get(nil, types.NamespacedName{Name: admission.WebhookConfigName}, &webhookConfig)).To(Succeed())
+				var service corev1.Service
+				Expect(reactiveClient.Get(nil, types.NamespacedName{Namespace: "test-ns", Name: serviceName}, &service)).To(Succeed())
+			})
+		})
+
 		When("setting the validatingwebhookconfiguration owner reference fails", func() {
 			BeforeEach(func() {
 				subject.WebhookCfgOwner = nil
@@ -316,8 +631,7 @@ var _ = Describe("Validating Admission Webhook", func() {
 			})
 
 			JustBeforeEach(func() {
-				By("creating a new operator pod")
-				subject.NameSuffix = "-new"
+				By("starting another operator replica")
 				err = subject.ReconcileValidatingWebhookConfiguration(nil, []byte("new cert"))
 			})
 			It("succeeds", func() {
@@ -332,12 +646,11 @@ var _ = Describe("Validating Admission Webhook", func() {
 				Expect(webhookConfig.Webhooks[0].ClientConfig.CABundle).To(Equal([]byte("new cert")))
 			})
 
-			It("updates the service name", func() {
-				var webhookConfig admissionregistrationv1.ValidatingWebhookConfiguration
-				webhookKey := types.NamespacedName{Name: admission.WebhookConfigName}
-				Expect(reactiveClient.Get(nil, webhookKey, &webhookConfig)).To(Succeed())
-				Expect(webhookConfig).NotTo(BeNil())
-				Expect(webhookConfig.Webhooks[0].ClientConfig.Service.Name).To(Equal(admission.ServiceName + "-new"))
+			It("keeps the existing Service", func() {
+				var serviceList corev1.ServiceList
+				Expect(reactiveClient.List(nil, &serviceList)).To(Succeed())
+				Expect(serviceList.Items).To(HaveLen(1))
+				Expect(serviceList.Items[0].Name).To(Equal(admission.ServiceName))
 			})
 
 			When("Update fails", func() {
@@ -403,6 +716,27 @@ var _ = Describe("Validating Admission Webhook", func() {
 			Expect(validatingWebhookConfig.Labels).To(HaveKeyWithValue("cool-tool", "soldering-iron"))
 
 		})
+
+		It("applies to all namespaces by default", func() {
+			validatingWebhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+			subject.ModifyWebhookConfiguration(validatingWebhookConfig, []byte("some cert bytes"))
+			Expect(validatingWebhookConfig.Webhooks[0].NamespaceSelector).To(BeNil())
+		})
+
+		When("the webhook watches some namespaces", func() {
+			BeforeEach(func() {
+				subject.WatchNamespaces = []string{"tenant-a", "tenant-b"}
+			})
+			It("applies only to those namespaces", func() {
+				validatingWebhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+				subject.ModifyWebhookConfiguration(validatingWebhookConfig, []byte("some cert bytes"))
+				Expect(validatingWebhookConfig.Webhooks[0].NamespaceSelector).To(Equal(&metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpIn, Values: []string{"tenant-a", "tenant-b"}},
+					},
+				}))
+			})
+		})
 	})
 
 	Describe("CreateSVCForValidatingWebhookConfiguration", func() {
@@ -424,17 +758,17 @@ var _ = Describe("Validating Admission Webhook", func() {
 })
 
 type MockServer struct {
-	started chan struct{}
-	cert    tls.Certificate
-	addr    string
-	handler http.Handler
-	err     error
+	started        chan struct{}
+	getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	addr           string
+	handler        http.Handler
+	err            error
 }
 
 var _ admission.Server = &MockServer{}
 
-func (s *MockServer) Start(stopCh <-chan struct{}, cert tls.Certificate, addr string, handler http.Handler) error {
-	s.cert = cert
+func (s *MockServer) Start(stopCh <-chan struct{}, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error), addr string, handler http.Handler) error {
+	s.getCertificate = getCertificate
 	s.addr = addr
 	s.handler = handler
 	if s.started != nil {
@@ -525,3 +859,17 @@ func (fcg *StubCertGenerator) GetCertificate(cert []byte, key *rsa.PrivateKey) (
 	fcg.getCertStub.returnedX509 = tls.Certificate{Certificate: [][]byte{[]byte("cert PEM")}}
 	return fcg.getCertStub.returnedX509, fcg.getCertStub.err
 }
+
+func selfSignedCert(commonName string, notAfter time.Time) (certPEM, keyPEM []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).NotTo(HaveOccurred())
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), admission.EncodePrivateKey(key)
+}
@@ -0,0 +1,56 @@
+package catalogcheckjob
+
+import (
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func GenerateJob(image, hostname string, spec greenplumv1.GreenplumCatalogCheckSpec) (job batchv1.Job) {
+	job.Spec.BackoffLimit = heapvalue.NewInt32(0)
+
+	catalogCheckPod := &job.Spec.Template.Spec
+	catalogCheckPod.RestartPolicy = corev1.RestartPolicyNever
+
+	catalogCheckPod.Volumes = []corev1.Volume{
+		{
+			Name: "ssh-key",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  "ssh-secrets",
+					DefaultMode: heapvalue.NewInt32(0444),
+				},
+			},
+		},
+	}
+	catalogCheckPod.ImagePullSecrets = []corev1.LocalObjectReference{
+		{
+			Name: "regsecret",
+		},
+	}
+	catalogCheckPod.Containers = []corev1.Container{
+		{
+			Name:    "gpcheckcat",
+			Image:   image,
+			Command: []string{"/home/gpadmin/tools/catalog_check_job.sh"},
+			Args:    spec.Databases,
+			Env: []corev1.EnvVar{
+				{
+					Name:  "CATALOG_CHECK_HOST",
+					Value: hostname,
+				},
+			},
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "ssh-key",
+					MountPath: "/etc/ssh-key",
+				},
+			},
+		},
+	}
+
+	return
+}
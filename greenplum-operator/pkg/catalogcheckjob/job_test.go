@@ -0,0 +1,49 @@
+package catalogcheckjob
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("GenerateJob", func() {
+	It("sets properties on the job", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local",
+			greenplumv1.GreenplumCatalogCheckSpec{
+				Schedule:  "0 4 * * 6",
+				Databases: []string{"postgres", "sales"},
+			})
+		Expect(job.Spec.BackoffLimit).To(gstruct.PointTo(Equal(int32(0))))
+
+		catalogCheckPod := job.Spec.Template.Spec
+		Expect(catalogCheckPod.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+
+		sshSecretVolume := catalogCheckPod.Volumes[0]
+		Expect(sshSecretVolume.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolume.VolumeSource.Secret.SecretName).To(Equal("ssh-secrets"))
+		Expect(sshSecretVolume.VolumeSource.Secret.DefaultMode).To(gstruct.PointTo(Equal(int32(0444))))
+
+		Expect(catalogCheckPod.ImagePullSecrets[0].Name).To(Equal("regsecret"))
+		catalogCheckContainer := catalogCheckPod.Containers[0]
+		Expect(catalogCheckContainer.Name).To(Equal("gpcheckcat"))
+		Expect(catalogCheckContainer.Env[0].Name).To(Equal("CATALOG_CHECK_HOST"))
+		Expect(catalogCheckContainer.Env[0].Value).To(Equal("master-0.agent.default.svc.cluster.local"))
+		Expect(catalogCheckContainer.Image).To(Equal("greenplum-for-kubernetes:magic"))
+		Expect(catalogCheckContainer.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(catalogCheckContainer.TerminationMessagePolicy).To(Equal(corev1.TerminationMessageReadFile))
+		Expect(catalogCheckContainer.Command).To(Equal([]string{"/home/gpadmin/tools/catalog_check_job.sh"}))
+		Expect(catalogCheckContainer.Args).To(Equal([]string{"postgres", "sales"}))
+
+		sshSecretVolumeMount := catalogCheckContainer.VolumeMounts[0]
+		Expect(sshSecretVolumeMount.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolumeMount.MountPath).To(Equal("/etc/ssh-key"))
+	})
+
+	It("checks every database when none are specified", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local",
+			greenplumv1.GreenplumCatalogCheckSpec{Schedule: "0 4 * * 6"})
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(BeEmpty())
+	})
+})
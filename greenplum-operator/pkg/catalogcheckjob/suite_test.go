@@ -0,0 +1,13 @@
+package catalogcheckjob
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCatalogcheckjob(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "catalogcheckjob Suite")
+}
@@ -0,0 +1,94 @@
+package catalogcheckjob
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+)
+
+// Summary is what a catalog check Job reports in the termination message of its container.
+// greenplum-instance/scripts/catalog_check_job.sh keeps only the lines of the gpcheckcat
+// summary reports that are parsed here.
+type Summary struct {
+	// The databases that gpcheckcat completed its tests on
+	Databases []string
+	// The number of issues that gpcheckcat reported, over all databases
+	IssueCount   int
+	FailedChecks []greenplumv1.GreenplumCatalogCheckFailure
+	// <master pod>:<directory> of the repair scripts, if gpcheckcat generated any
+	RepairScripts string
+}
+
+var (
+	logPrefix      = regexp.MustCompile(`^.*-\[[A-Z]+\]:-`)
+	completedTests = regexp.MustCompile(`^Completed \d+ test\(s\) on database '(.*)'`)
+	foundIssues    = regexp.MustCompile(`^Found (\d+) catalog issue`)
+)
+
+// Consistent returns whether gpcheckcat checked at least one database and found no issues
+func (s Summary) Consistent() bool {
+	return len(s.Databases) > 0 && s.IssueCount == 0 && len(s.FailedChecks) == 0
+}
+
+// ParseSummary parses the termination message of a catalog check Job
+func ParseSummary(message string) Summary {
+	var (
+		summary  Summary
+		database string
+		table    string
+	)
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(logPrefix.ReplaceAllString(line, ""))
+		switch {
+		case strings.HasPrefix(line, "Checking database: "):
+			database = strings.TrimPrefix(line, "Checking database: ")
+			table = ""
+		case completedTests.MatchString(line):
+			summary.Databases = append(summary.Databases, completedTests.FindStringSubmatch(line)[1])
+		case foundIssues.MatchString(line):
+			count, _ := strconv.Atoi(foundIssues.FindStringSubmatch(line)[1])
+			summary.IssueCount += count
+		case strings.HasPrefix(line, "Test: "):
+			summary.addFailure(database, strings.TrimPrefix(line, "Test: "), "")
+		case strings.HasPrefix(line, "Table name: "):
+			table = strings.TrimPrefix(line, "Table name: ")
+		case strings.HasPrefix(line, "Name of test which found this issue: "):
+			summary.addFailure(database, strings.TrimPrefix(line, "Name of test which found this issue: "), table)
+			table = ""
+		case strings.HasPrefix(line, "Repair scripts: "):
+			summary.RepairScripts = strings.TrimPrefix(line, "Repair scripts: ")
+		}
+	}
+	return summary
+}
+
+// addFailure records a failed check once per database, with each table once
+func (s *Summary) addFailure(database, check, table string) {
+	check = strings.TrimSpace(check)
+	for i := range s.FailedChecks {
+		failure := &s.FailedChecks[i]
+		if failure.Database != database || failure.Check != check {
+			continue
+		}
+		if table != "" && !contains(failure.Tables, table) {
+			failure.Tables = append(failure.Tables, table)
+		}
+		return
+	}
+	failure := greenplumv1.GreenplumCatalogCheckFailure{Database: database, Check: check}
+	if table != "" {
+		failure.Tables = []string{table}
+	}
+	s.FailedChecks = append(s.FailedChecks, failure)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package catalogcheckjob
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+)
+
+var _ = Describe("ParseSummary", func() {
+	It("parses a summary without issues", func() {
+		summary := ParseSummary(`Checking database: postgres
+Completed 14 test(s) on database 'postgres' at 2020-06-06 04:00:12 with elapsed time 0:00:11
+Found no catalog issue
+Checking database: sales
+Completed 14 test(s) on database 'sales' at 2020-06-06 04:00:25 with elapsed time 0:00:13
+Found no catalog issue
+`)
+		Expect(summary.Databases).To(Equal([]string{"postgres", "sales"}))
+		Expect(summary.IssueCount).To(Equal(0))
+		Expect(summary.FailedChecks).To(BeEmpty())
+		Expect(summary.RepairScripts).To(BeEmpty())
+		Expect(summary.Consistent()).To(BeTrue())
+	})
+
+	It("parses the failed checks and the affected tables of each database", func() {
+		summary := ParseSummary(`Checking database: postgres
+Completed 14 test(s) on database 'postgres' at 2020-06-06 04:00:12 with elapsed time 0:00:11
+Found no catalog issue
+Checking database: sales
+Completed 14 test(s) on database 'sales' at 2020-06-06 04:00:25 with elapsed time 0:00:13
+Found 3 catalog issue(s)
+  Test: missing_extraneous
+  Test: foreign_key
+Table name: public.orders
+Name of test which found this issue: missing_extraneous
+Table name: public.customers
+Name of test which found this issue: missing_extraneous
+Table name: public.orders
+Name of test which found this issue: foreign_key
+Table name: public.orders
+Name of test which found this issue: missing_extraneous
+Repair scripts: master-0:/greenplum/gpcheckcat/repair.20200606040000
+`)
+		Expect(summary.Databases).To(Equal([]string{"postgres", "sales"}))
+		Expect(summary.IssueCount).To(Equal(3))
+		Expect(summary.FailedChecks).To(Equal([]greenplumv1.GreenplumCatalogCheckFailure{
+			{Database: "sales", Check: "missing_extraneous", Tables: []string{"public.orders", "public.customers"}},
+			{Database: "sales", Check: "foreign_key", Tables: []string{"public.orders"}},
+		}))
+		Expect(summary.RepairScripts).To(Equal("master-0:/greenplum/gpcheckcat/repair.20200606040000"))
+		Expect(summary.Consistent()).To(BeFalse())
+	})
+
+	It("ignores the gplog prefix of gpcheckcat lines", func() {
+		summary := ParseSummary(`Checking database: postgres
+20200606:04:00:12:001234 gpcheckcat:master-0:gpadmin-[INFO]:-Completed 14 test(s) on database 'postgres' at 2020-06-06 04:00:12 with elapsed time 0:00:11
+20200606:04:00:12:001234 gpcheckcat:master-0:gpadmin-[ERROR]:-Found 1 catalog issue(s)
+20200606:04:00:12:001234 gpcheckcat:master-0:gpadmin-[ERROR]:-  Test: orphaned_toast_tables
+`)
+		Expect(summary.Databases).To(Equal([]string{"postgres"}))
+		Expect(summary.IssueCount).To(Equal(1))
+		Expect(summary.FailedChecks).To(Equal([]greenplumv1.GreenplumCatalogCheckFailure{
+			{Database: "postgres", Check: "orphaned_toast_tables"},
+		}))
+	})
+
+	It("is not consistent when gpcheckcat did not complete", func() {
+		summary := ParseSummary("Checking database: postgres\n")
+		Expect(summary.Databases).To(BeEmpty())
+		Expect(summary.Consistent()).To(BeFalse())
+	})
+})
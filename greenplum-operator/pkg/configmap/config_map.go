@@ -2,6 +2,7 @@ package configmap
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
@@ -15,6 +16,11 @@ const (
 	HostBasedAuthentication = "hostBasedAuthentication"
 	GUCs                    = "GUCs"
 	PXFServiceName          = "pxfServiceName"
+	TablespaceVolumes       = "tablespaceVolumes"
+	TempTablespace          = "tempTablespace"
+	LogRetention            = "logRetention"
+	LogRetentionMaxAge      = "logRetentionMaxAge"
+	LogRetentionMaxSize     = "logRetentionMaxSize"
 )
 
 func ModifyConfigMap(cluster *greenplumv1.GreenplumCluster, config *corev1.ConfigMap) {
@@ -26,6 +32,10 @@ func ModifyConfigMap(cluster *greenplumv1.GreenplumCluster, config *corev1.Confi
 		"gp_resource_manager = group",
 		"gp_resource_group_memory_limit = 1.0",
 	}
+	tablespaceVolumes, tempTablespace := getTablespaceVolumes(cluster)
+	if tempTablespace != "" {
+		gucsList = append(gucsList, "temp_tablespaces = "+tempTablespace)
+	}
 	gucs := strings.Join(gucsList, "\n")
 
 	labels := map[string]string{
@@ -45,5 +55,47 @@ func ModifyConfigMap(cluster *greenplumv1.GreenplumCluster, config *corev1.Confi
 		HostBasedAuthentication: cluster.Spec.MasterAndStandby.HostBasedAuthentication,
 		GUCs:                    gucs,
 		PXFServiceName:          cluster.Spec.PXF.ServiceName,
+		TablespaceVolumes:       strings.Join(tablespaceVolumes, "\n"),
+		TempTablespace:          tempTablespace,
+	}
+	addLogRetention(cluster.Spec.LogRetention, config.Data)
+}
+
+// addLogRetention adds the log retention policy. The limits that are not set are empty.
+func addLogRetention(logRetention *greenplumv1.GreenplumLogRetentionSpec, data map[string]string) {
+	data[LogRetention] = fmt.Sprint(logRetention != nil)
+	data[LogRetentionMaxAge] = ""
+	data[LogRetentionMaxSize] = ""
+	if logRetention == nil {
+		return
+	}
+	if logRetention.MaxAge != nil {
+		data[LogRetentionMaxAge] = logRetention.MaxAge.Duration.String()
+	}
+	if logRetention.MaxSize != nil {
+		data[LogRetentionMaxSize] = fmt.Sprint(logRetention.MaxSize.Value())
+	}
+}
+
+// getTablespaceVolumes returns the names of the tablespace and temp volumes of both the masters and the segments.
+// Every host needs a directory for each of them, because a tablespace has to exist on all segments.
+func getTablespaceVolumes(cluster *greenplumv1.GreenplumCluster) (names []string, temp string) {
+	seen := map[string]bool{}
+	for _, podSpec := range []greenplumv1.GreenplumPodSpec{
+		cluster.Spec.MasterAndStandby.GreenplumPodSpec,
+		cluster.Spec.Segments.GreenplumPodSpec,
+	} {
+		for _, volume := range podSpec.AdditionalVolumes {
+			if volume.Usage == greenplumv1.GreenplumVolumeUsageMirror || seen[volume.Name] {
+				continue
+			}
+			seen[volume.Name] = true
+			names = append(names, volume.Name)
+			if volume.Usage == greenplumv1.GreenplumVolumeUsageTemp {
+				temp = volume.Name
+			}
+		}
 	}
+	sort.Strings(names)
+	return names, temp
 }
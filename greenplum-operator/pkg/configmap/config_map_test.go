@@ -1,6 +1,8 @@
 package configmap_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
@@ -68,8 +70,58 @@ var _ = Describe("GreenplumCluster configmap spec", func() {
 		Expect(configMap.Data[configmap.HostBasedAuthentication]).To(Equal("host based authentication"))
 		Expect(configMap.Data[configmap.GUCs]).To(Equal("gp_resource_manager = group\ngp_resource_group_memory_limit = 1.0"))
 		Expect(configMap.Data[configmap.PXFServiceName]).To(Equal("my-pxf-service"))
+		Expect(configMap.Data[configmap.TablespaceVolumes]).To(Equal(""))
+		Expect(configMap.Data[configmap.TempTablespace]).To(Equal(""))
+		Expect(configMap.Data[configmap.LogRetention]).To(Equal("false"))
+		Expect(configMap.Data[configmap.LogRetentionMaxAge]).To(Equal(""))
+		Expect(configMap.Data[configmap.LogRetentionMaxSize]).To(Equal(""))
 		Expect(configMap.ObjectMeta.Labels["app"]).To(Equal("greenplum"))
 		Expect(configMap.ObjectMeta.Labels["greenplum-cluster"]).To(Equal("my-test-cluster-name"))
 
 	})
+
+	When("logRetention is specified", func() {
+		BeforeEach(func() {
+			maxSize := resource.MustParse("500Mi")
+			cluster.Spec.LogRetention = &greenplumv1.GreenplumLogRetentionSpec{
+				MaxAge:  &v1.Duration{Duration: 168 * time.Hour},
+				MaxSize: &maxSize,
+			}
+		})
+		It("sets the limits, with the size in bytes", func() {
+			Expect(configMap.Data[configmap.LogRetention]).To(Equal("true"))
+			Expect(configMap.Data[configmap.LogRetentionMaxAge]).To(Equal("168h0m0s"))
+			Expect(configMap.Data[configmap.LogRetentionMaxSize]).To(Equal("524288000"))
+		})
+		When("no limits are set", func() {
+			BeforeEach(func() {
+				cluster.Spec.LogRetention = &greenplumv1.GreenplumLogRetentionSpec{}
+			})
+			It("leaves the limits empty", func() {
+				Expect(configMap.Data[configmap.LogRetention]).To(Equal("true"))
+				Expect(configMap.Data[configmap.LogRetentionMaxAge]).To(Equal(""))
+				Expect(configMap.Data[configmap.LogRetentionMaxSize]).To(Equal(""))
+			})
+		})
+	})
+
+	When("additional volumes are specified", func() {
+		BeforeEach(func() {
+			cluster.Spec.MasterAndStandby.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+				{Name: "scratch", Usage: greenplumv1.GreenplumVolumeUsageTemp},
+			}
+			cluster.Spec.Segments.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+				{Name: "scratch", Usage: greenplumv1.GreenplumVolumeUsageTemp},
+				{Name: "fast", Usage: greenplumv1.GreenplumVolumeUsageTablespace},
+				{Name: "mirrordata", Usage: greenplumv1.GreenplumVolumeUsageMirror},
+			}
+		})
+		It("lists the tablespace and temp volumes", func() {
+			Expect(configMap.Data[configmap.TablespaceVolumes]).To(Equal("fast\nscratch"))
+		})
+		It("sets temp_tablespaces to the temp volume", func() {
+			Expect(configMap.Data[configmap.TempTablespace]).To(Equal("scratch"))
+			Expect(configMap.Data[configmap.GUCs]).To(HaveSuffix("\ntemp_tablespaces = scratch"))
+		})
+	})
 })
@@ -0,0 +1,64 @@
+package cronschedule
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// NeverRunsMessage is reported for a schedule that does not run within five years, such as "0 0 30 2 *"
+const NeverRunsMessage = "the schedule does not run within five years"
+
+// The pods of a Job are labeled with its name, and label values are at most 63 characters
+const maxJobNameLength = 63
+
+// Runs is when a scheduled Job runs, as seen at one point in time
+type Runs struct {
+	// Due is the time of the run to start now, or the zero time if none is due
+	Due time.Time
+	// Next is the time of the next run, or the zero time if the schedule does not run within five years
+	Next time.Time
+	// RequeueAfter is how long to wait for the next run, or zero if there is none
+	RequeueAfter time.Duration
+}
+
+// Runs returns the run that is due at now, given the time of the last run, and the run after it.
+// Of several runs that were missed, only the latest is due.
+func (s *Schedule) Runs(last, now time.Time) Runs {
+	runs := Runs{
+		Due:  s.Latest(last, now),
+		Next: s.Next(now),
+	}
+	if !runs.Next.IsZero() {
+		runs.RequeueAfter = runs.Next.Sub(now)
+	}
+	return runs
+}
+
+// NeverRuns returns whether no run is due now or later
+func (r Runs) NeverRuns() bool {
+	return r.Due.IsZero() && r.Next.IsZero()
+}
+
+// SkippedMessage reports that the due run was not started, for the given reason
+func (r Runs) SkippedMessage(reason string) string {
+	return fmt.Sprintf("skipped the run scheduled at %s: %s", r.Due.UTC().Format(time.RFC3339), reason)
+}
+
+// JobName returns the name of the Job of the due run: the prefix, followed by the minute it was scheduled for.
+func (r Runs) JobName(prefix string) string {
+	return JobName(prefix, fmt.Sprintf("-%d", r.Due.Unix()/60))
+}
+
+// JobName returns prefix+suffix, kept within the length of a label value. A prefix that is too long is
+// truncated, and a hash of it is added, so that the name stays unique.
+func JobName(prefix, suffix string) string {
+	if len(prefix)+len(suffix) <= maxJobNameLength {
+		return prefix + suffix
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(prefix))
+	suffix = fmt.Sprintf("-%08x%s", hash.Sum32(), suffix)
+	return strings.TrimRight(prefix[:maxJobNameLength-len(suffix)], "-.") + suffix
+}
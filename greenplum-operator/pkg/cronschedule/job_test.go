@@ -0,0 +1,77 @@
+package cronschedule_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/cronschedule"
+)
+
+var _ = Describe("Runs", func() {
+	// A Wednesday
+	start := time.Date(2020, time.June, 3, 10, 17, 42, 0, time.UTC)
+
+	var schedule *cronschedule.Schedule
+	BeforeEach(func() {
+		var err error
+		schedule, err = cronschedule.Parse("0 3 * * *")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns the latest missed run and the next run", func() {
+		now := start.AddDate(0, 0, 3)
+		runs := schedule.Runs(start, now)
+		Expect(runs.Due).To(Equal(time.Date(2020, time.June, 6, 3, 0, 0, 0, time.UTC)))
+		Expect(runs.Next).To(Equal(time.Date(2020, time.June, 7, 3, 0, 0, 0, time.UTC)))
+		Expect(runs.RequeueAfter).To(Equal(runs.Next.Sub(now)))
+		Expect(runs.NeverRuns()).To(BeFalse())
+	})
+
+	It("reports a schedule that never runs", func() {
+		never, err := cronschedule.Parse("0 0 30 2 *")
+		Expect(err).NotTo(HaveOccurred())
+		runs := never.Runs(start, start.AddDate(0, 0, 3))
+		Expect(runs.Due).To(BeZero())
+		Expect(runs.Next).To(BeZero())
+		Expect(runs.RequeueAfter).To(BeZero())
+		Expect(runs.NeverRuns()).To(BeTrue())
+	})
+
+	It("reports a skipped run", func() {
+		runs := schedule.Runs(start, start.AddDate(0, 0, 1))
+		Expect(runs.SkippedMessage("Job my-job is still running")).To(Equal(
+			"skipped the run scheduled at 2020-06-04T03:00:00Z: Job my-job is still running"))
+	})
+
+	Describe("JobName", func() {
+		var runs cronschedule.Runs
+		BeforeEach(func() {
+			runs = schedule.Runs(start, start.AddDate(0, 0, 1))
+		})
+
+		It("adds the minute of the run to the prefix", func() {
+			Expect(runs.JobName("my-greenplum-gpcheckcat")).To(Equal("my-greenplum-gpcheckcat-26520660"))
+		})
+
+		It("keeps names of long prefixes within 63 characters, and unique", func() {
+			prefix := strings.Repeat("a", 60)
+			name := runs.JobName(prefix + "-gpcheckcat")
+			Expect(name).To(HaveLen(63))
+			Expect(name).To(HavePrefix(strings.Repeat("a", 36)))
+			Expect(name).To(HaveSuffix("-26520660"))
+			Expect(runs.JobName(prefix + "-other")).NotTo(Equal(name))
+		})
+
+		It("keeps any suffix within 63 characters", func() {
+			Expect(cronschedule.JobName("my-greenplum-restart", "-0123abcd")).To(Equal("my-greenplum-restart-0123abcd"))
+			Expect(cronschedule.JobName(strings.Repeat("a", 60), "-0123abcd")).To(MatchRegexp(`^a{45}-[0-9a-f]{8}-0123abcd$`))
+		})
+
+		It("does not end the truncated prefix with a separator", func() {
+			name := runs.JobName(strings.Repeat("a", 44) + "-" + strings.Repeat("b", 30))
+			Expect(name).To(MatchRegexp(`^a{44}-[0-9a-f]{8}-26520660$`))
+		})
+	})
+})
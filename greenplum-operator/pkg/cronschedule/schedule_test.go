@@ -0,0 +1,75 @@
+package cronschedule_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/cronschedule"
+)
+
+var _ = Describe("Schedule", func() {
+	// A Wednesday
+	start := time.Date(2020, time.June, 3, 10, 17, 42, 0, time.UTC)
+
+	DescribeTable("Next",
+		func(spec string, expected time.Time) {
+			schedule, err := cronschedule.Parse(spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(schedule.Next(start)).To(Equal(expected))
+		},
+		Entry("every minute", "* * * * *", time.Date(2020, time.June, 3, 10, 18, 0, 0, time.UTC)),
+		Entry("every 15 minutes", "*/15 * * * *", time.Date(2020, time.June, 3, 10, 30, 0, 0, time.UTC)),
+		Entry("later today", "30 14 * * *", time.Date(2020, time.June, 3, 14, 30, 0, 0, time.UTC)),
+		Entry("tomorrow", "0 3 * * *", time.Date(2020, time.June, 4, 3, 0, 0, 0, time.UTC)),
+		Entry("on Sundays", "0 3 * * 0", time.Date(2020, time.June, 7, 3, 0, 0, 0, time.UTC)),
+		Entry("on Sundays, as 7", "0 3 * * 7", time.Date(2020, time.June, 7, 3, 0, 0, 0, time.UTC)),
+		Entry("on weekdays by name", "0 3 * * mon-fri", time.Date(2020, time.June, 4, 3, 0, 0, 0, time.UTC)),
+		Entry("on a list of hours", "0 2,11,20 * * *", time.Date(2020, time.June, 3, 11, 0, 0, 0, time.UTC)),
+		Entry("every other hour in a range", "0 8-18/2 * * *", time.Date(2020, time.June, 3, 12, 0, 0, 0, time.UTC)),
+		Entry("monthly", "@monthly", time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("in a later month", "0 0 1 jan *", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("on the 15th or on Fridays", "0 0 15 * fri", time.Date(2020, time.June, 5, 0, 0, 0, 0, time.UTC)),
+		Entry("on leap days", "0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)),
+		Entry("never", "0 0 30 2 *", time.Time{}),
+	)
+
+	It("keeps the location of the time", func() {
+		india := time.FixedZone("IST", 5*60*60+30*60)
+		schedule, err := cronschedule.Parse("0 * * * *")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule.Next(start.In(india))).To(Equal(time.Date(2020, time.June, 3, 16, 0, 0, 0, india)))
+	})
+
+	Describe("Latest", func() {
+		var schedule *cronschedule.Schedule
+		BeforeEach(func() {
+			var err error
+			schedule, err = cronschedule.Parse("0 3 * * *")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("returns the last time that was missed", func() {
+			Expect(schedule.Latest(start, start.AddDate(0, 0, 3))).To(Equal(time.Date(2020, time.June, 6, 3, 0, 0, 0, time.UTC)))
+		})
+		It("includes now", func() {
+			now := time.Date(2020, time.June, 4, 3, 0, 0, 0, time.UTC)
+			Expect(schedule.Latest(start, now)).To(Equal(now))
+		})
+		It("returns the zero time when nothing was missed", func() {
+			Expect(schedule.Latest(start, start.Add(time.Hour))).To(BeZero())
+		})
+	})
+
+	DescribeTable("Parse errors",
+		func(spec, message string) {
+			_, err := cronschedule.Parse(spec)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("too few fields", "0 3 * *", `expected 5 fields in schedule "0 3 * *", found 4`),
+		Entry("out of range", "60 * * * *", `invalid value "60" in minute field "60"`),
+		Entry("not a number", "0 x * * *", `invalid value "x" in hour field "x"`),
+		Entry("bad step", "*/0 * * * *", `invalid step "0" in minute field "*/0"`),
+		Entry("backwards range", "0 0 * * 5-1", `invalid range "5-1" in day of week field "5-1"`),
+	)
+})
@@ -0,0 +1,13 @@
+package cronschedule_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCronschedule(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "cronschedule Suite")
+}
@@ -0,0 +1,299 @@
+// Package diagnostics collects a support bundle of a Greenplum cluster: its resources, the pods and events of
+// its namespace, the segment configuration, the server and utility logs of every master and segment pod, and
+// the log of the operator. Credentials are redacted from every file of the bundle.
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultParallelism is how many pods are collected from at a time
+	DefaultParallelism = 8
+	// logTailLines is how much of each log is collected
+	logTailLines = 1000
+	// collectTimeout bounds each command that is run in a pod
+	collectTimeout = time.Minute
+)
+
+const segmentConfigurationQuery = "SELECT * FROM gp_segment_configuration ORDER BY content, role DESC"
+
+// pgLogCommand prints the last $2 lines of the two newest server logs in the data directory $1
+const pgLogCommand = `cd "$1/pg_log" 2>/dev/null || exit 0
+for f in $(ls -t *.csv 2>/dev/null | head -n 2); do echo "==> $f <=="; tail -n "$2" "$f"; done`
+
+// gpAdminLogsCommand prints the last $1 lines of the five newest utility logs
+const gpAdminLogsCommand = `cd /home/gpadmin/gpAdminLogs 2>/dev/null || exit 0
+for f in $(ls -t *.log 2>/dev/null | head -n 5); do echo "==> $f <=="; tail -n "$1" "$f"; done`
+
+// PodLogReader reads the end of the log of a container
+type PodLogReader interface {
+	PodLog(ctx context.Context, namespace, podName, container string, tailLines int64) ([]byte, error)
+}
+
+// Collector collects the bundle of a GreenplumCluster
+type Collector struct {
+	Client  client.Client
+	PodExec executor.PodExecInterface
+	Logs    PodLogReader
+	// OperatorNamespace is where the operator runs. The operator pods are looked up in every namespace if empty.
+	OperatorNamespace string
+	// Parallelism is DefaultParallelism if zero
+	Parallelism int
+	// Now is time.Now if nil
+	Now func() time.Time
+}
+
+// bundle is a tarball that the files of the bundle are written to as they are collected
+type bundle struct {
+	mu      sync.Mutex
+	prefix  string
+	modTime time.Time
+	tw      *tar.Writer
+	errors  []string
+	err     error
+}
+
+func (b *bundle) add(name string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return
+	}
+	header := &tar.Header{
+		Name:    path.Join(b.prefix, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		b.err = err
+		return
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		b.err = err
+	}
+}
+
+// addText adds a file of logs or command output, without its credentials
+func (b *bundle) addText(name string, data []byte) {
+	b.add(name, Redact(data))
+}
+
+// addObject adds a Kubernetes object as YAML
+func (b *bundle) addObject(name string, obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+// fail records that a file could not be collected. The rest of the bundle is still collected.
+func (b *bundle) fail(name string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errors = append(b.errors, fmt.Sprintf("%s: %s", name, Redact([]byte(err.Error()))))
+}
+
+// Name is the name of the bundle of a cluster collected at a time, without the .tar.gz extension
+func Name(clusterName string, at time.Time) string {
+	return fmt.Sprintf("greenplum-diag-%s-%s", clusterName, at.UTC().Format("20060102-150405"))
+}
+
+// Collect writes the bundle of the GreenplumCluster as a gzipped tarball to w. Files that cannot be
+// collected, such as the logs of a pod that is not running, are listed in errors.txt in the bundle.
+// An error is only returned if the cluster cannot be read or the bundle cannot be written.
+func (c *Collector) Collect(ctx context.Context, cluster types.NamespacedName, w io.Writer) error {
+	var greenplumCluster greenplumv1.GreenplumCluster
+	if err := c.Client.Get(ctx, cluster, &greenplumCluster); err != nil {
+		return fmt.Errorf("unable to fetch GreenplumCluster %s: %w", cluster.Name, err)
+	}
+
+	gw := gzip.NewWriter(w)
+	now := c.now()
+	b := &bundle{prefix: Name(cluster.Name, now), modTime: now, tw: tar.NewWriter(gw)}
+
+	greenplumCluster.ManagedFields = nil
+	b.addObject("greenplumcluster.yaml", &greenplumCluster)
+	pods := c.collectPods(ctx, b, cluster)
+	c.collectEvents(ctx, b, cluster.Namespace)
+	c.collectSegmentConfiguration(ctx, b, cluster.Namespace)
+	c.collectOperatorLogs(ctx, b)
+	c.fanOut(pods, func(pod corev1.Pod) {
+		c.collectPodLogs(ctx, b, pod)
+	})
+
+	if len(b.errors) > 0 {
+		sort.Strings(b.errors)
+		b.add("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n"))
+	}
+	if b.err != nil {
+		return fmt.Errorf("writing bundle: %w", b.err)
+	}
+	if err := b.tw.Close(); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	return nil
+}
+
+// collectPods adds the pods of the namespace, and returns the master and segment pods of the cluster
+func (c *Collector) collectPods(ctx context.Context, b *bundle, cluster types.NamespacedName) []corev1.Pod {
+	var podList corev1.PodList
+	if err := c.Client.List(ctx, &podList, client.InNamespace(cluster.Namespace)); err != nil {
+		b.fail("pods", err)
+		return nil
+	}
+	var greenplumPods []corev1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		redactPod(pod)
+		b.addObject(path.Join("pods", pod.Name+".yaml"), pod)
+		if pod.Labels["greenplum-cluster"] == cluster.Name && isGreenplumPodType(pod.Labels["type"]) {
+			greenplumPods = append(greenplumPods, *pod)
+		}
+	}
+	return greenplumPods
+}
+
+func isGreenplumPodType(podType string) bool {
+	return podType == "master" || podType == "segment-a" || podType == "segment-b"
+}
+
+func (c *Collector) collectEvents(ctx context.Context, b *bundle, namespace string) {
+	var eventList corev1.EventList
+	if err := c.Client.List(ctx, &eventList, client.InNamespace(namespace)); err != nil {
+		b.fail("events.yaml", err)
+		return
+	}
+	sort.SliceStable(eventList.Items, func(i, j int) bool {
+		return eventList.Items[i].LastTimestamp.Before(&eventList.Items[j].LastTimestamp)
+	})
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		event.ManagedFields = nil
+		event.Message = string(Redact([]byte(event.Message)))
+	}
+	b.addObject("events.yaml", &eventList)
+}
+
+// collectSegmentConfiguration queries gp_segment_configuration on whichever master is active
+func (c *Collector) collectSegmentConfiguration(ctx context.Context, b *bundle, namespace string) {
+	var errs []string
+	for _, master := range []string{"master-0", "master-1"} {
+		output, err := executor.Query(ctx, c.PodExec, namespace, master, "postgres", segmentConfigurationQuery)
+		if err == nil {
+			b.addText("gp_segment_configuration.txt", []byte(output+"\n"))
+			return
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", master, err))
+	}
+	b.fail("gp_segment_configuration.txt", fmt.Errorf("no master answered: %s", strings.Join(errs, "; ")))
+}
+
+func (c *Collector) collectOperatorLogs(ctx context.Context, b *bundle) {
+	var podList corev1.PodList
+	opts := []client.ListOption{client.MatchingLabels{"app": "greenplum-operator"}}
+	if c.OperatorNamespace != "" {
+		opts = append(opts, client.InNamespace(c.OperatorNamespace))
+	}
+	if err := c.Client.List(ctx, &podList, opts...); err != nil {
+		b.fail("operator", err)
+		return
+	}
+	if len(podList.Items) == 0 {
+		b.fail("operator", fmt.Errorf("no pods labeled app=greenplum-operator"))
+		return
+	}
+	for _, pod := range podList.Items {
+		name := path.Join("operator", pod.Name+".log")
+		log, err := c.Logs.PodLog(ctx, pod.Namespace, pod.Name, "", logTailLines)
+		if err != nil {
+			b.fail(name, err)
+			continue
+		}
+		b.addText(name, log)
+	}
+}
+
+// collectPodLogs adds the end of the container log, the server logs and the utility logs of a pod
+func (c *Collector) collectPodLogs(ctx context.Context, b *bundle, pod corev1.Pod) {
+	dir := path.Join("pods", pod.Name)
+	if log, err := c.Logs.PodLog(ctx, pod.Namespace, pod.Name, greenplumv1.AppName, logTailLines); err != nil {
+		b.fail(path.Join(dir, "container.log"), err)
+	} else {
+		b.addText(path.Join(dir, "container.log"), log)
+	}
+
+	dataDir := "/greenplum/data"
+	if pod.Labels["type"] == "master" {
+		dataDir = "/greenplum/data-1"
+	}
+	c.collectCommand(ctx, b, pod, path.Join(dir, "pg_log.txt"), []string{"/bin/bash", "-c", pgLogCommand, "pg_log", dataDir, fmt.Sprint(logTailLines)})
+	c.collectCommand(ctx, b, pod, path.Join(dir, "gpAdminLogs.txt"), []string{"/bin/bash", "-c", gpAdminLogsCommand, "gpAdminLogs", fmt.Sprint(logTailLines)})
+}
+
+func (c *Collector) collectCommand(ctx context.Context, b *bundle, pod corev1.Pod, name string, command []string) {
+	var stdout, stderr bytes.Buffer
+	err := c.PodExec.Execute(ctx, executor.ExecRequest{
+		Namespace: pod.Namespace,
+		PodName:   pod.Name,
+		Command:   command,
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+		Timeout:   collectTimeout,
+	})
+	if err != nil {
+		b.fail(name, executor.WithStderr(err))
+		return
+	}
+	b.addText(name, stdout.Bytes())
+}
+
+// fanOut runs collect for each pod, Parallelism pods at a time
+func (c *Collector) fanOut(pods []corev1.Pod, collect func(corev1.Pod)) {
+	parallelism := c.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			collect(pod)
+		}(pod)
+	}
+	wg.Wait()
+}
+
+func (c *Collector) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,181 @@
+package diagnostics_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/diagnostics"
+	fakePodExec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeLogReader struct {
+	logs map[string]string
+}
+
+func (r *fakeLogReader) PodLog(_ context.Context, namespace, podName, container string, _ int64) ([]byte, error) {
+	log, ok := r.logs[namespace+"/"+podName+"/"+container]
+	if !ok {
+		return nil, errors.New("container not found")
+	}
+	return []byte(log), nil
+}
+
+func greenplumPod(name, podType string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test-ns",
+			Name:      name,
+			Labels:    map[string]string{"greenplum-cluster": "my-greenplum", "type": podType},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "greenplum",
+			Env: []corev1.EnvVar{
+				{Name: "S3_SECRET_ACCESS_KEY", Value: "abc123"},
+				{Name: "PGPASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "gpadmin-password"}, Key: "password"}}},
+				{Name: "TZ", Value: "UTC"},
+			},
+		}}},
+	}
+}
+
+// readBundle returns the files of a bundle by name
+func readBundle(data []byte) map[string]string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	Expect(err).NotTo(HaveOccurred())
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(tr)
+		Expect(err).NotTo(HaveOccurred())
+		files[header.Name] = string(content)
+	}
+}
+
+var _ = Describe("Collector", func() {
+	const prefix = "greenplum-diag-my-greenplum-20200601-120000/"
+	var (
+		k8sClient client.Client
+		podExec   *fakePodExec.PodExec
+		logs      *fakeLogReader
+		collector *diagnostics.Collector
+		cluster   = types.NamespacedName{Namespace: "test-ns", Name: "my-greenplum"}
+	)
+
+	BeforeEach(func() {
+		greenplumCluster := &greenplumv1.GreenplumCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"}}
+		operatorPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "greenplum", Name: "greenplum-operator-123", Labels: map[string]string{"app": "greenplum-operator"},
+		}}
+		pxfPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test-ns", Name: "my-greenplum-pxf-456", Labels: map[string]string{"app": "greenplum-pxf"},
+		}}
+		event := &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum.1"},
+			InvolvedObject: corev1.ObjectReference{Kind: "GreenplumCluster", Name: "my-greenplum"},
+			Reason:         "CreatedGreenplumCluster",
+			Message:        "connecting with password=changeme",
+		}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			greenplumCluster, operatorPod, pxfPod, event,
+			greenplumPod("master-0", "master"), greenplumPod("segment-a-0", "segment-a"),
+		).Build()
+		podExec = &fakePodExec.PodExec{CommandStdout: map[string]string{
+			"gp_segment_configuration": "-1|-1|p|p|n|u|5432|master-0|master-0|/greenplum/data-1",
+			"pg_log":                   "==> gpdb.csv <==\nstatement: ALTER ROLE app PASSWORD 'changeme'\n",
+			"gpAdminLogs":              "==> gpstart_20200601.log <==\n20200601:12:00:05:001234 gpstart:master-0:gpadmin-[INFO]:-Starting gpstart\n",
+		}}
+		logs = &fakeLogReader{logs: map[string]string{
+			"greenplum/greenplum-operator-123/": "operator log\n",
+			"test-ns/master-0/greenplum":        "master-0 container log\n",
+			"test-ns/segment-a-0/greenplum":     "segment-a-0 container log\n",
+		}}
+		collector = &diagnostics.Collector{
+			Client:      k8sClient,
+			PodExec:     podExec,
+			Logs:        logs,
+			Parallelism: 1,
+			Now:         func() time.Time { return time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC) },
+		}
+	})
+
+	collect := func() map[string]string {
+		var buf bytes.Buffer
+		Expect(collector.Collect(context.Background(), cluster, &buf)).To(Succeed())
+		return readBundle(buf.Bytes())
+	}
+
+	It("collects the cluster, its pods, events and logs", func() {
+		files := collect()
+		Expect(files).To(HaveKey(prefix + "greenplumcluster.yaml"))
+		Expect(files).To(HaveKey(prefix + "pods/master-0.yaml"))
+		Expect(files).To(HaveKey(prefix + "pods/my-greenplum-pxf-456.yaml"))
+		Expect(files[prefix+"events.yaml"]).To(ContainSubstring("CreatedGreenplumCluster"))
+		Expect(files[prefix+"gp_segment_configuration.txt"]).To(ContainSubstring("master-0"))
+		Expect(files[prefix+"operator/greenplum-operator-123.log"]).To(Equal("operator log\n"))
+		Expect(files[prefix+"pods/master-0/container.log"]).To(Equal("master-0 container log\n"))
+		Expect(files[prefix+"pods/segment-a-0/gpAdminLogs.txt"]).To(ContainSubstring("Starting gpstart"))
+		Expect(files).NotTo(HaveKey(prefix + "errors.txt"))
+	})
+
+	It("runs the commands on every master and segment pod", func() {
+		collect()
+		Expect(podExec.RecordedCommands).To(ContainElement(ContainSubstring("pg_log /greenplum/data-1 1000")))
+		Expect(podExec.RecordedCommands).To(ContainElement(ContainSubstring("pg_log /greenplum/data 1000")))
+		Expect(podExec.RecordedCommands).To(HaveLen(5))
+	})
+
+	It("redacts credentials", func() {
+		files := collect()
+		Expect(files[prefix+"pods/master-0/pg_log.txt"]).To(ContainSubstring("PASSWORD '[REDACTED]'"))
+		Expect(files[prefix+"events.yaml"]).To(ContainSubstring("password=[REDACTED]"))
+		Expect(files[prefix+"pods/master-0.yaml"]).To(ContainSubstring("value: '[REDACTED]'"))
+		Expect(files[prefix+"pods/master-0.yaml"]).To(ContainSubstring("name: gpadmin-password"))
+		Expect(files[prefix+"pods/master-0.yaml"]).To(ContainSubstring("value: UTC"))
+		for name, content := range files {
+			Expect(content).NotTo(ContainSubstring("changeme"), name)
+			Expect(content).NotTo(ContainSubstring("abc123"), name)
+		}
+	})
+
+	It("lists what it could not collect, and collects the rest", func() {
+		podExec.CommandErrors = map[string]string{"gp_segment_configuration": "psql: could not connect to server"}
+		delete(logs.logs, "test-ns/segment-a-0/greenplum")
+		files := collect()
+		Expect(files[prefix+"errors.txt"]).To(HavePrefix("gp_segment_configuration.txt: no master answered: master-0: psql: could not connect to server"))
+		Expect(files[prefix+"errors.txt"]).To(ContainSubstring("; master-1: psql: could not connect to server"))
+		Expect(files[prefix+"errors.txt"]).To(HaveSuffix("\npods/segment-a-0/container.log: container not found\n"))
+		Expect(files).To(HaveKey(prefix + "pods/segment-a-0/pg_log.txt"))
+	})
+
+	It("only reads the operator pods of the operator namespace, if given", func() {
+		collector.OperatorNamespace = "other-ns"
+		files := collect()
+		Expect(files[prefix+"errors.txt"]).To(Equal("operator: no pods labeled app=greenplum-operator\n"))
+	})
+
+	It("fails when the cluster does not exist", func() {
+		var buf bytes.Buffer
+		err := collector.Collect(context.Background(), types.NamespacedName{Namespace: "test-ns", Name: "missing"}, &buf)
+		Expect(err).To(MatchError(ContainSubstring("unable to fetch GreenplumCluster missing")))
+	})
+})
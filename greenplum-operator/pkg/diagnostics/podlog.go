@@ -0,0 +1,31 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClientsetLogReader reads pod logs through the Kubernetes API, like kubectl logs
+type ClientsetLogReader struct {
+	Clientset kubernetes.Interface
+}
+
+var _ PodLogReader = &ClientsetLogReader{}
+
+func (r *ClientsetLogReader) PodLog(ctx context.Context, namespace, podName, container string, tailLines int64) ([]byte, error) {
+	req := r.Clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{Container: container, TailLines: &tailLines})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading log of %s: %w", podName, err)
+	}
+	defer stream.Close()
+	log, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("reading log of %s: %w", podName, err)
+	}
+	return log, nil
+}
@@ -0,0 +1,53 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// S3Location is a folder of an S3-compatible bucket to store bundles in
+type S3Location struct {
+	Endpoint        string
+	Secure          bool
+	Region          string
+	Bucket          string
+	Folder          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Uploader stores a bundle
+type Uploader interface {
+	Upload(ctx context.Context, name string, r io.Reader, size int64) error
+}
+
+// S3Uploader stores bundles in an S3Location
+type S3Uploader struct {
+	Client *minio.Client
+	Bucket string
+	Folder string
+}
+
+var _ Uploader = &S3Uploader{}
+
+func NewS3Uploader(location S3Location) (*S3Uploader, error) {
+	s3Client, err := minio.NewWithRegion(location.Endpoint, location.AccessKeyID, location.SecretAccessKey, location.Secure, location.Region)
+	if err != nil {
+		return nil, fmt.Errorf("creating S3 client: %w", err)
+	}
+	return &S3Uploader{Client: s3Client, Bucket: location.Bucket, Folder: location.Folder}, nil
+}
+
+// Upload stores the bundle as the object name in the folder
+func (u *S3Uploader) Upload(ctx context.Context, name string, r io.Reader, size int64) error {
+	objectName := path.Join(u.Folder, name)
+	_, err := u.Client.PutObjectWithContext(ctx, u.Bucket, objectName, r, size, minio.PutObjectOptions{ContentType: "application/gzip"})
+	if err != nil {
+		return fmt.Errorf("uploading %s to bucket %s: %w", objectName, u.Bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package diagnostics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/diagnostics"
+)
+
+var _ = Describe("S3Uploader", func() {
+	var (
+		server   *httptest.Server
+		status   int
+		uploaded map[string]string
+		uploader *diagnostics.S3Uploader
+	)
+	BeforeEach(func() {
+		status = http.StatusOK
+		uploaded = map[string]string{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.Method).To(Equal(http.MethodPut))
+			body, err := io.ReadAll(req.Body)
+			Expect(err).NotTo(HaveOccurred())
+			uploaded[req.URL.Path] = string(body)
+			w.WriteHeader(status)
+		}))
+		var err error
+		uploader, err = diagnostics.NewS3Uploader(diagnostics.S3Location{
+			Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+			Region:          "us-east-1",
+			Bucket:          "support",
+			Folder:          "cases/1234",
+			AccessKeyID:     "id",
+			SecretAccessKey: "key",
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("puts the bundle in the folder of the bucket", func() {
+		Expect(uploader.Upload(context.Background(), "bundle.tar.gz", strings.NewReader("bundle"), 6)).To(Succeed())
+		// Over plain HTTP, the body is sent in signed chunks
+		Expect(uploaded).To(HaveKeyWithValue("/support/cases/1234/bundle.tar.gz", ContainSubstring("\r\nbundle\r\n")))
+	})
+
+	It("fails when the bucket refuses the bundle", func() {
+		status = http.StatusForbidden
+		err := uploader.Upload(context.Background(), "bundle.tar.gz", strings.NewReader("bundle"), 6)
+		Expect(err).To(MatchError(HavePrefix("uploading cases/1234/bundle.tar.gz to bucket support: ")))
+	})
+})
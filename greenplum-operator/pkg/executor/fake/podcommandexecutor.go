@@ -1,51 +1,67 @@
 package fake
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"io/ioutil"
 	"strings"
-)
+	"time"
 
-const DefaultSegmentCount = 1 // Used as the primarySegmentCount of exampleGreenplumCluster
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+)
 
 type PodExec struct {
-	ErrorMsgOnMaster0 string
-	ErrorMsgOnMaster1 string
-
-	SegmentCount    string
-	SegmentCountErr error
-
 	ErrorMsgOnCommand string
 	CalledPodName     string
 
 	RecordedCommands []string
 	StdoutResult     string
+
+	// Keyed by a substring of the command. A matching command is recorded and
+	// writes the given stdout, or fails with the given error message.
+	CommandStdout map[string]string
+	CommandErrors map[string]string
+
+	// The Stdin and Timeout of the last recorded command
+	RecordedStdin   string
+	RecordedTimeout time.Duration
 }
 
-// TODO: break import cycle so we can make this assertion
-//var _ executor.PodExecInterface = &PodExec{}
+var _ executor.PodExecInterface = &PodExec{}
 
-func (f *PodExec) Execute(command []string, namespace, podName string, stdout, stderr io.Writer) error {
+func (f *PodExec) Execute(ctx context.Context, req executor.ExecRequest) error {
+	if err := ctx.Err(); err != nil {
+		return &executor.ExecError{PodName: req.PodName, ExitCode: -1, Err: err}
+	}
+	command, podName := req.Command, req.PodName
+	stdout, stderr := orDiscard(req.Stdout), orDiscard(req.Stderr)
+	if req.Stdin != nil {
+		stdin, err := ioutil.ReadAll(req.Stdin)
+		if err != nil {
+			return err
+		}
+		f.RecordedStdin = string(stdin)
+	}
+	f.RecordedTimeout = req.Timeout
 	cmdStr := strings.Join(command, " ")
 	switch {
-	case isActiveMasterQuery(cmdStr):
-		return f.handleActiveMasterQuery(command, podName)
-	case isSegmentCountQuery(cmdStr):
-		if f.SegmentCountErr != nil {
-			return f.SegmentCountErr
-		}
-		segCount := strconv.Itoa(DefaultSegmentCount) + "\n"
-		if f.SegmentCount != "" {
-			segCount = f.SegmentCount
-		}
-		_, err := io.WriteString(stdout, segCount)
+	case f.matchCommand(f.CommandErrors, cmdStr) != "":
+		f.CalledPodName = podName
+		f.RecordedCommands = append(f.RecordedCommands, cmdStr)
+		msg := f.CommandErrors[f.matchCommand(f.CommandErrors, cmdStr)]
+		fmt.Fprint(stderr, msg)
+		return commandError(podName, msg)
+	case f.matchCommand(f.CommandStdout, cmdStr) != "":
+		f.CalledPodName = podName
+		f.RecordedCommands = append(f.RecordedCommands, cmdStr)
+		_, err := io.WriteString(stdout, f.CommandStdout[f.matchCommand(f.CommandStdout, cmdStr)])
 		return err
 	case f.ErrorMsgOnCommand != "":
 		f.CalledPodName = podName
 		fmt.Fprintf(stderr, f.ErrorMsgOnCommand)
-		return errors.New(f.ErrorMsgOnCommand)
+		return commandError(podName, f.ErrorMsgOnCommand)
 	default:
 		f.CalledPodName = podName
 		f.RecordedCommands = append(f.RecordedCommands, cmdStr)
@@ -54,20 +70,23 @@ func (f *PodExec) Execute(command []string, namespace, podName string, stdout, s
 	}
 }
 
-func isSegmentCountQuery(cmdStr string) bool {
-	return strings.Contains(cmdStr, "SELECT COUNT(*) FROM gp_segment_configuration")
+// commandError is how a command that exits with 1 after writing msg to stderr fails
+func commandError(podName, msg string) error {
+	return &executor.ExecError{PodName: podName, ExitCode: 1, Stderr: msg, Err: errors.New(msg)}
 }
 
-func isActiveMasterQuery(cmdStr string) bool {
-	return strings.Contains(cmdStr, "psql -U gpadmin -c 'select * from gp_segment_configuration'")
+func orDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return ioutil.Discard
+	}
+	return w
 }
 
-func (f *PodExec) handleActiveMasterQuery(command []string, podName string) error {
-	if f.ErrorMsgOnMaster0 != "" && podName == "master-0" {
-		return errors.New(f.ErrorMsgOnMaster0)
-	} else if f.ErrorMsgOnMaster1 != "" && podName == "master-1" {
-		return errors.New(f.ErrorMsgOnMaster1)
+func (f *PodExec) matchCommand(m map[string]string, cmdStr string) string {
+	for key := range m {
+		if strings.Contains(cmdStr, key) {
+			return key
+		}
 	}
-
-	return nil
+	return ""
 }
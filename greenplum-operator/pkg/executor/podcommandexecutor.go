@@ -1,21 +1,44 @@
 package executor
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var log = ctrllog.Log.WithName("PodExec")
 
+// DefaultTimeout bounds an exec that does not set ExecRequest.Timeout
+const DefaultTimeout = 5 * time.Minute
+
+// DefaultBackoff paces the retries of an exec whose stream could not be set up
+var DefaultBackoff = wait.Backoff{
+	Steps:    3,
+	Duration: 500 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
 func NewPodExec(scheme *runtime.Scheme, config *rest.Config) PodExecInterface {
 	codecFactory := serializer.NewCodecFactory(scheme)
 	//parameterCodec := runtime.NewParameterCodec(scheme)
@@ -26,55 +49,217 @@ func NewPodExec(scheme *runtime.Scheme, config *rest.Config) PodExecInterface {
 		RestCfg:    config,
 		RestClient: podRestInterface,
 		Upgrader:   &RealSPDYExecutorUpgrader{},
+		Backoff:    DefaultBackoff,
 	}
 	return podExec
 }
 
 type RemoteExecutorUpgrader interface {
-	NewSPDYExecutor(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error)
+	// NewSPDYExecutor returns an executor whose stream ends when ctx is done
+	NewSPDYExecutor(ctx context.Context, config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error)
 }
 
 type RealSPDYExecutorUpgrader struct{}
 
 var _ RemoteExecutorUpgrader = &RealSPDYExecutorUpgrader{}
 
-func (s *RealSPDYExecutorUpgrader) NewSPDYExecutor(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
-	return remotecommand.NewSPDYExecutor(config, method, url)
+func (s *RealSPDYExecutorUpgrader) NewSPDYExecutor(ctx context.Context, config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	contextUpgrader := &contextUpgrader{Upgrader: upgrader, ctx: ctx}
+	executor, err := remotecommand.NewSPDYExecutorForTransports(transport, contextUpgrader, method, url)
+	if err != nil {
+		return nil, err
+	}
+	return &setupErrorExecutor{Executor: executor, upgrader: contextUpgrader}, nil
+}
+
+// contextUpgrader closes the exec connection when ctx is done. client-go v0.25 has no
+// StreamWithContext, and closing the connection is what makes Stream return.
+type contextUpgrader struct {
+	spdy.Upgrader
+	ctx context.Context
+
+	mu        sync.Mutex
+	connected bool
+}
+
+func (u *contextUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := u.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	u.connected = true
+	u.mu.Unlock()
+	go func() {
+		select {
+		case <-u.ctx.Done():
+			_ = conn.Close()
+		case <-conn.CloseChan():
+		}
+	}()
+	return conn, nil
+}
+
+func (u *contextUpgrader) isConnected() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.connected
 }
 
+// setupErrorExecutor marks the errors of a stream that never got connected, since the command did not run
+type setupErrorExecutor struct {
+	remotecommand.Executor
+	upgrader *contextUpgrader
+}
+
+func (e *setupErrorExecutor) Stream(options remotecommand.StreamOptions) error {
+	err := e.Executor.Stream(options)
+	if err != nil && !e.upgrader.isConnected() {
+		return &StreamSetupError{Err: err}
+	}
+	return err
+}
+
+// StreamSetupError is returned by a remotecommand.Executor when the exec stream to the pod could not be
+// set up, so the command was not started.
+type StreamSetupError struct {
+	Err error
+}
+
+func (e *StreamSetupError) Error() string { return e.Err.Error() }
+func (e *StreamSetupError) Unwrap() error { return e.Err }
+
+// ExecRequest is a command to run in the default container of a pod
+type ExecRequest struct {
+	Namespace string
+	PodName   string
+	Command   []string
+	// Stdin is sent to the command if it is not nil, for example to pass a SQL script to psql
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// TTY allocates a terminal for the command, so that an interactive program such as psql can be used.
+	// The terminal writes the stderr of the command to Stdout.
+	TTY bool
+	// TerminalSizeQueue sets the size of the terminal, if TTY is set
+	TerminalSizeQueue remotecommand.TerminalSizeQueue
+	// Timeout bounds this call, in addition to any deadline of the context. Zero means DefaultTimeout.
+	Timeout time.Duration
+}
+
+// ExecError is returned when a command fails, or cannot be run, in a pod.
+type ExecError struct {
+	PodName string
+	// ExitCode is the exit code of the command, or -1 if it did not exit by itself
+	ExitCode int
+	// Stderr is what the command wrote to stderr
+	Stderr string
+	Err    error
+}
+
+func (e *ExecError) Error() string { return e.Err.Error() }
+func (e *ExecError) Unwrap() error { return e.Err }
+
 type PodExecInterface interface {
-	Execute(command []string, namespace, podName string, stdout, stderr io.Writer) error
+	Execute(ctx context.Context, req ExecRequest) error
 }
 
 type PodExecRESTClient struct {
 	RestCfg    *rest.Config
 	RestClient rest.Interface
 	Upgrader   RemoteExecutorUpgrader
+	// Backoff paces the retries of a command whose stream could not be set up. The zero value means DefaultBackoff.
+	Backoff wait.Backoff
 }
 
 var _ PodExecInterface = &PodExecRESTClient{}
 
-func (p *PodExecRESTClient) Execute(command []string, namespace, podName string, stdout, stderr io.Writer) error {
-	remoteCommandExecutor, err := p.Executor(namespace, podName, command)
-	if err != nil {
+// Execute runs req.Command and waits until it exits, ctx is done, or req.Timeout passes. A command is only retried
+// when its stream could not be set up, so a command that may have started never runs twice.
+func (p *PodExecRESTClient) Execute(ctx context.Context, req ExecRequest) error {
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := p.Backoff
+	if backoff.Steps == 0 {
+		backoff = DefaultBackoff
+	}
+
+	var stderr bytes.Buffer
+	err := retry.OnError(backoff, isTransient, func() error {
+		stderr.Reset()
+		remoteCommandExecutor, err := p.Executor(ctx, req)
+		if err != nil {
+			return err
+		}
+		err = remoteCommandExecutor.Stream(remotecommand.StreamOptions{
+			Stdin:             req.Stdin,
+			Stdout:            req.Stdout,
+			Stderr:            teeWriter(req.Stderr, &stderr),
+			Tty:               req.TTY,
+			TerminalSizeQueue: req.TerminalSizeQueue,
+		})
+		if isTransient(err) && ctx.Err() == nil {
+			log.V(1).Info("retrying exec", "namespace", req.Namespace, "pod", req.PodName, "error", err.Error())
+		}
 		return err
+	})
+	if err == nil {
+		return nil
+	}
+
+	execErr := &ExecError{PodName: req.PodName, ExitCode: -1, Stderr: stderr.String(), Err: err}
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		execErr.ExitCode = exitErr.ExitStatus()
+	} else if ctx.Err() != nil {
+		execErr.Err = fmt.Errorf("command in pod %s did not finish: %w", req.PodName, ctx.Err())
 	}
-	return remoteCommandExecutor.Stream(remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr})
+	return execErr
 }
 
-func (p *PodExecRESTClient) Executor(namespace, podName string, command []string) (remotecommand.Executor, error) {
+func (p *PodExecRESTClient) Executor(ctx context.Context, req ExecRequest) (remotecommand.Executor, error) {
 	url := p.RestClient.Post().
 		Resource("pods").
-		Name(podName).
-		Namespace(namespace).
+		Name(req.PodName).
+		Namespace(req.Namespace).
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
-			Command: command,
-			Stdin:   false,
+			Command: req.Command,
+			Stdin:   req.Stdin != nil,
 			Stdout:  true,
-			Stderr:  true,
-			TTY:     false,
+			Stderr:  !req.TTY,
+			TTY:     req.TTY,
 		}, scheme.ParameterCodec).
 		URL()
-	return p.Upgrader.NewSPDYExecutor(p.RestCfg, "POST", url)
+	return p.Upgrader.NewSPDYExecutor(ctx, p.RestCfg, "POST", url)
+}
+
+// isTransient reports whether the command was not started because of a failure that may go away, such as
+// the API server being unreachable. A pod that does not exist, or a container that is not running, is not transient.
+func isTransient(err error) bool {
+	var setupErr *StreamSetupError
+	if !errors.As(err, &setupErr) {
+		return false
+	}
+	var status apierrs.APIStatus
+	if errors.As(setupErr.Err, &status) {
+		return status.Status().Code >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func teeWriter(w io.Writer, buf *bytes.Buffer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
 }
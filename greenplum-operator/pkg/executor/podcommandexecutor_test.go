@@ -2,14 +2,21 @@ package executor_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
@@ -23,6 +30,7 @@ var _ = Describe("podcommandexecutor", func() {
 		restConfig           *rest.Config
 		fakeRESTClient       *fake.RESTClient
 		fakeExecutorUpgrader *FakeSPDYExecutorUpgrader
+		ctx                  context.Context
 	)
 
 	BeforeEach(func() {
@@ -36,7 +44,9 @@ var _ = Describe("podcommandexecutor", func() {
 			RestCfg:    restConfig,
 			RestClient: fakeRESTClient,
 			Upgrader:   fakeExecutorUpgrader,
+			Backoff:    wait.Backoff{Steps: 3, Duration: time.Millisecond},
 		}
+		ctx = context.Background()
 	})
 
 	Context("NewPodCommandExecutor", func() {
@@ -46,9 +56,14 @@ var _ = Describe("podcommandexecutor", func() {
 	})
 
 	Context("Executor", func() {
+		var req executor.ExecRequest
+		BeforeEach(func() {
+			req = executor.ExecRequest{Namespace: "testNamespace", PodName: "testPod", Command: []string{"fakeCmd"}}
+		})
+
 		When("succeeds to Executor", func() {
 			It("returns a valid executor", func() {
-				executor, err := podcommandexecutor.Executor("testNamespace", "testPod", []string{})
+				executor, err := podcommandexecutor.Executor(ctx, req)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(executor).ToNot(BeNil())
 			})
@@ -59,7 +74,7 @@ var _ = Describe("podcommandexecutor", func() {
 				fakeExecutorUpgrader.SPDYError = errors.New("custom error")
 			})
 			It("returns an error and a nil executor", func() {
-				executor, err := podcommandexecutor.Executor("testNamespace", "testPod", []string{})
+				executor, err := podcommandexecutor.Executor(ctx, req)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("custom error"))
 				Expect(executor).To(BeNil())
@@ -68,7 +83,7 @@ var _ = Describe("podcommandexecutor", func() {
 
 		When("a command, podname, and namespace are specified", func() {
 			It("returns a valid request issuing the command to the pod in the namespace specified", func() {
-				_, err := podcommandexecutor.Executor("testNamespace", "testPod", []string{"fakeCmd"})
+				_, err := podcommandexecutor.Executor(ctx, req)
 				Expect(err).NotTo(HaveOccurred())
 				requestURL := fakeExecutorUpgrader.URL
 				parameters, _ := url.ParseQuery(requestURL.RawQuery)
@@ -83,74 +98,221 @@ var _ = Describe("podcommandexecutor", func() {
 			})
 		})
 
+		When("stdin is given", func() {
+			It("requests a stdin stream", func() {
+				req.Stdin = strings.NewReader("SELECT 1;")
+				_, err := podcommandexecutor.Executor(ctx, req)
+				Expect(err).NotTo(HaveOccurred())
+				parameters, _ := url.ParseQuery(fakeExecutorUpgrader.URL.RawQuery)
+				Expect(parameters["stdin"]).To(Equal([]string{"true"}))
+			})
+		})
+
+		When("a terminal is requested", func() {
+			It("requests a tty instead of a stderr stream", func() {
+				req.TTY = true
+				_, err := podcommandexecutor.Executor(ctx, req)
+				Expect(err).NotTo(HaveOccurred())
+				parameters, _ := url.ParseQuery(fakeExecutorUpgrader.URL.RawQuery)
+				Expect(parameters["tty"]).To(Equal([]string{"true"}))
+				Expect(parameters["stderr"]).To(BeNil())
+			})
+		})
+
 		It("passes the RESTConfig to the ExecutorUpgrader", func() {
-			_, err := podcommandexecutor.Executor("testNamespace", "testPod", []string{"fakeCmd"})
+			_, err := podcommandexecutor.Executor(ctx, req)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeExecutorUpgrader.Config).To(BeIdenticalTo(restConfig))
 		})
 	})
 
 	Context("Execute", func() {
+		var req executor.ExecRequest
+		BeforeEach(func() {
+			req = executor.ExecRequest{Namespace: "testNamespace", PodName: "testPod"}
+		})
+
 		It("succeeds when remote command executor runs command successfully", func() {
-			err := podcommandexecutor.Execute(nil, "testNamespace", "testPod", nil, nil)
+			err := podcommandexecutor.Execute(ctx, req)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("streams stdin to the command", func() {
+			req.Stdin = strings.NewReader("SELECT 1;")
+			Expect(podcommandexecutor.Execute(ctx, req)).To(Succeed())
+			Expect(fakeExecutorUpgrader.Stdin).To(Equal("SELECT 1;"))
+		})
+
+		It("streams to a terminal if one is requested", func() {
+			req.TTY = true
+			Expect(podcommandexecutor.Execute(ctx, req)).To(Succeed())
+			Expect(fakeExecutorUpgrader.Tty).To(BeTrue())
+		})
+
 		When("Executor fails", func() {
 			BeforeEach(func() {
 				fakeExecutorUpgrader.SPDYError = errors.New("SPDY error")
 			})
 			It("returns an error", func() {
-				err := podcommandexecutor.Execute(nil, "testNamespace", "testPod", nil, nil)
+				err := podcommandexecutor.Execute(ctx, req)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("SPDY error"))
 			})
 		})
 		When("executor stream has an error", func() {
 			BeforeEach(func() {
-				fakeExecutorUpgrader.ExecError = errors.New("stream error")
+				fakeExecutorUpgrader.ExecErrors = []error{errors.New("stream error")}
 			})
 			It("returns an error", func() {
 				var stderr bytes.Buffer
-				err := podcommandexecutor.Execute(nil, "testNamespace", "testPod", nil, &stderr)
+				req.Stderr = &stderr
+				err := podcommandexecutor.Execute(ctx, req)
 				Expect(err).To(MatchError("stream error"))
 				Expect(stderr.String()).To(Equal("stream error"))
 			})
+			It("does not retry, since the command may have run", func() {
+				Expect(podcommandexecutor.Execute(ctx, req)).NotTo(Succeed())
+				Expect(fakeExecutorUpgrader.Streams).To(Equal(1))
+			})
+		})
+		When("the command exits with an error", func() {
+			BeforeEach(func() {
+				fakeExecutorUpgrader.ExecErrors = []error{exitError{code: 3}}
+			})
+			It("returns an ExecError with the exit code and stderr", func() {
+				err := podcommandexecutor.Execute(ctx, req)
+				var execErr *executor.ExecError
+				Expect(errors.As(err, &execErr)).To(BeTrue())
+				Expect(execErr.PodName).To(Equal("testPod"))
+				Expect(execErr.ExitCode).To(Equal(3))
+				Expect(execErr.Stderr).To(Equal("command terminated with exit code 3"))
+				Expect(err).To(MatchError("command terminated with exit code 3"))
+			})
+		})
+		When("the stream cannot be set up", func() {
+			BeforeEach(func() {
+				fakeExecutorUpgrader.ExecErrors = []error{
+					&executor.StreamSetupError{Err: errors.New("error dialing backend: EOF")},
+				}
+			})
+			It("retries", func() {
+				Expect(podcommandexecutor.Execute(ctx, req)).To(Succeed())
+				Expect(fakeExecutorUpgrader.Streams).To(Equal(2))
+			})
+			When("it keeps failing", func() {
+				BeforeEach(func() {
+					for i := 0; i < 5; i++ {
+						fakeExecutorUpgrader.ExecErrors = append(fakeExecutorUpgrader.ExecErrors,
+							&executor.StreamSetupError{Err: errors.New("error dialing backend: EOF")})
+					}
+				})
+				It("gives up after the backoff steps", func() {
+					err := podcommandexecutor.Execute(ctx, req)
+					Expect(err).To(MatchError("error dialing backend: EOF"))
+					Expect(fakeExecutorUpgrader.Streams).To(Equal(3))
+				})
+			})
+			When("the pod does not exist", func() {
+				BeforeEach(func() {
+					fakeExecutorUpgrader.ExecErrors = []error{
+						&executor.StreamSetupError{Err: apierrs.NewNotFound(schema.GroupResource{Resource: "pods"}, "testPod")},
+					}
+				})
+				It("does not retry", func() {
+					Expect(podcommandexecutor.Execute(ctx, req)).To(MatchError(`pods "testPod" not found`))
+					Expect(fakeExecutorUpgrader.Streams).To(Equal(1))
+				})
+			})
+		})
+		When("the command does not finish in time", func() {
+			BeforeEach(func() {
+				fakeExecutorUpgrader.Block = true
+				req.Timeout = 10 * time.Millisecond
+			})
+			It("returns an ExecError for the deadline", func() {
+				err := podcommandexecutor.Execute(ctx, req)
+				Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+				Expect(err).To(MatchError("command in pod testPod did not finish: context deadline exceeded"))
+				var execErr *executor.ExecError
+				Expect(errors.As(err, &execErr)).To(BeTrue())
+				Expect(execErr.ExitCode).To(Equal(-1))
+			})
+		})
+		When("the context is canceled", func() {
+			BeforeEach(func() {
+				fakeExecutorUpgrader.Block = true
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			})
+			It("returns the error of the context", func() {
+				err := podcommandexecutor.Execute(ctx, req)
+				Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			})
 		})
 	})
 
 })
 
+// exitError is how remotecommand reports the exit code of a command
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string   { return fmt.Sprintf("command terminated with exit code %d", e.code) }
+func (e exitError) ExitStatus() int { return e.code }
+
 // fake implementation of RemoteExecutorUpgrader interface
 type FakeSPDYExecutorUpgrader struct {
 	Config    *rest.Config
 	URL       *url.URL
 	SPDYError error
-	ExecError error
+	// Each Stream fails with the next error, and then succeeds
+	ExecErrors []error
+	// Block makes Stream wait until the context is done, like a hung command
+	Block   bool
+	Streams int
+	Stdin   string
+	Tty     bool
 }
 
 var _ executor.RemoteExecutorUpgrader = &FakeSPDYExecutorUpgrader{}
 
-func (s *FakeSPDYExecutorUpgrader) NewSPDYExecutor(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+func (s *FakeSPDYExecutorUpgrader) NewSPDYExecutor(ctx context.Context, config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
 	s.Config = config
 	s.URL = url
 	if s.SPDYError != nil {
 		return nil, s.SPDYError
 	}
-	return &FakeCommandExecutor{Err: s.ExecError}, nil
+	return &FakeCommandExecutor{ctx: ctx, upgrader: s}, nil
 }
 
 // fake implementation of Executor interface
 type FakeCommandExecutor struct {
-	Err error
+	ctx      context.Context
+	upgrader *FakeSPDYExecutorUpgrader
 }
 
 var _ remotecommand.Executor = &FakeCommandExecutor{}
 
 func (f *FakeCommandExecutor) Stream(options remotecommand.StreamOptions) error {
-	if f.Err != nil {
-		Expect(io.WriteString(options.Stderr, f.Err.Error())).To(Equal(len(f.Err.Error())))
-		return f.Err
+	s := f.upgrader
+	s.Streams++
+	s.Tty = options.Tty
+	if options.Stdin != nil {
+		stdin, err := ioutil.ReadAll(options.Stdin)
+		Expect(err).NotTo(HaveOccurred())
+		s.Stdin = string(stdin)
+	}
+	if s.Block {
+		<-f.ctx.Done()
+		return errors.New("connection closed")
+	}
+	if len(s.ExecErrors) > 0 {
+		err := s.ExecErrors[0]
+		s.ExecErrors = s.ExecErrors[1:]
+		Expect(io.WriteString(options.Stderr, err.Error())).To(Equal(len(err.Error())))
+		return err
 	}
 	return nil
 }
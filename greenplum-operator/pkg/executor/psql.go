@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PsqlCommand returns a command that runs sql against database on a master pod.
+// The sql is passed to bash as a positional argument so that it needs no shell quoting.
+func PsqlCommand(database, sql string) []string {
+	return []string{
+		"/bin/bash",
+		"-c",
+		"--",
+		`source /usr/local/greenplum-db/greenplum_path.sh && psql -U gpadmin -d "$1" -v ON_ERROR_STOP=1 -tAc "$2"`,
+		"psql",
+		database,
+		sql,
+	}
+}
+
+// Query runs sql on podName and returns its unaligned, tuples-only output.
+func Query(ctx context.Context, p PodExecInterface, namespace, podName, database, sql string) (string, error) {
+	var stdout bytes.Buffer
+	err := p.Execute(ctx, ExecRequest{
+		Namespace: namespace,
+		PodName:   podName,
+		Command:   PsqlCommand(database, sql),
+		Stdout:    &stdout,
+		Timeout:   QueryTimeout,
+	})
+	if err != nil {
+		return "", WithStderr(err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// QueryTimeout bounds a Query. The statements that the operator runs are catalog queries and DDL.
+const QueryTimeout = 2 * time.Minute
+
+// WithStderr appends the stderr of a failed command to its error, if err is an *ExecError
+func WithStderr(err error) error {
+	var execErr *ExecError
+	if errors.As(err, &execErr) {
+		if msg := strings.TrimSpace(execErr.Stderr); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+	}
+	return err
+}
+
+// MD5Password is the form of a password that Greenplum stores in pg_authid. Setting a password in this
+// form keeps the clear text out of commands and server logs.
+func MD5Password(role, password string) string {
+	sum := md5.Sum([]byte(password + role))
+	return "md5" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,45 @@
+package executor_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	fakeExecutor "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+)
+
+var _ = Describe("Query", func() {
+	var fakePodExec *fakeExecutor.PodExec
+
+	BeforeEach(func() {
+		fakePodExec = &fakeExecutor.PodExec{}
+	})
+
+	It("runs psql with the sql as an argument and returns its trimmed output", func() {
+		fakePodExec.StdoutResult = "1\n"
+		out, err := executor.Query(context.Background(), fakePodExec, "test-ns", "master-0", "gpadmin", "SELECT 'it''s'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("1"))
+		Expect(fakePodExec.CalledPodName).To(Equal("master-0"))
+		Expect(fakePodExec.RecordedTimeout).To(Equal(executor.QueryTimeout))
+		Expect(executor.PsqlCommand("gpadmin", "SELECT 'it''s'")).To(Equal([]string{
+			"/bin/bash", "-c", "--",
+			`source /usr/local/greenplum-db/greenplum_path.sh && psql -U gpadmin -d "$1" -v ON_ERROR_STOP=1 -tAc "$2"`,
+			"psql", "gpadmin", "SELECT 'it''s'",
+		}))
+	})
+
+	It("includes stderr in the error", func() {
+		fakePodExec.ErrorMsgOnCommand = `ERROR:  syntax error at or near "SELEC"`
+		_, err := executor.Query(context.Background(), fakePodExec, "test-ns", "master-0", "gpadmin", "SELEC 1")
+		Expect(err).To(MatchError(`ERROR:  syntax error at or near "SELEC": ERROR:  syntax error at or near "SELEC"`))
+	})
+})
+
+var _ = Describe("MD5Password", func() {
+	It("hashes the password salted with the role name, as Greenplum stores it", func() {
+		// SELECT 'md5' || md5('s3cret' || 'sales_app')
+		Expect(executor.MD5Password("sales_app", "s3cret")).To(Equal("md543dbb34786893bf675b89f367e3116b6"))
+	})
+})
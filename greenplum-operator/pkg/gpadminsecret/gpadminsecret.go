@@ -0,0 +1,84 @@
+package gpadminsecret
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	SecretName = "gpadmin-password"
+
+	// RotateAnnotation requests a new gpadmin password when it is set on a GreenplumCluster to a value
+	// that has not been seen before. The value last acted on is recorded on the Secret.
+	RotateAnnotation = "greenplum.pivotal.io/rotate-gpadmin-password"
+	// AppliedPasswordAnnotation records on the Secret the PasswordDigest of the password that gpadmin was last given
+	AppliedPasswordAnnotation = "greenplum.pivotal.io/applied-password-sha256"
+
+	PasswordLength = 32
+)
+
+const passwordCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// ModifySecret fills in a generated password if the Secret does not have one, or if rotation was requested.
+// Removing the rotation request does not rotate the password.
+// A password that was put in the Secret by the user is kept.
+func ModifySecret(greenplumCluster *greenplumv1.GreenplumCluster, secret *corev1.Secret) error {
+	rotateRequest := greenplumCluster.Annotations[RotateAnnotation]
+	rotate := rotateRequest != "" && secret.Annotations[RotateAnnotation] != rotateRequest
+	if len(secret.Data[pgpass.PasswordKey]) == 0 || rotate {
+		password, err := GeneratePassword()
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[pgpass.PasswordKey] = []byte(password)
+	}
+	// The request last acted on is kept when the annotation is removed, so that setting it back is not a new request
+	if rotateRequest != "" {
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[RotateAnnotation] = rotateRequest
+	}
+
+	labels := map[string]string{
+		"app":               greenplumv1.AppName,
+		"greenplum-cluster": greenplumCluster.Name,
+	}
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	for key, value := range labels {
+		secret.Labels[key] = value
+	}
+	secret.Type = corev1.SecretTypeOpaque
+	return nil
+}
+
+// PasswordDigest identifies a password without revealing it. Unlike the md5 hash in pg_authid, it cannot be used to log in.
+func PasswordDigest(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// GeneratePassword returns a random alphanumeric password, so that it needs no quoting in a password file or URL.
+func GeneratePassword() (string, error) {
+	max := big.NewInt(int64(len(passwordCharacters)))
+	password := make([]byte, PasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordCharacters[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,13 @@
+package gpadminsecret_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGpadminsecret(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gpadminsecret Suite")
+}
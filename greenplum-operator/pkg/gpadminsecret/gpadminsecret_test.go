@@ -0,0 +1,92 @@
+package gpadminsecret_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ModifySecret", func() {
+	var (
+		cluster *greenplumv1.GreenplumCluster
+		secret  *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: gpadminsecret.SecretName},
+		}
+	})
+
+	It("generates a password and labels the secret", func() {
+		Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+		Expect(secret.Data["password"]).To(HaveLen(gpadminsecret.PasswordLength))
+		Expect(secret.Labels).To(Equal(map[string]string{
+			"app":               "greenplum",
+			"greenplum-cluster": "my-greenplum",
+		}))
+		Expect(secret.Type).To(Equal(corev1.SecretTypeOpaque))
+		Expect(secret.Annotations).NotTo(HaveKey(gpadminsecret.RotateAnnotation))
+	})
+
+	When("the secret has a password", func() {
+		BeforeEach(func() {
+			secret.Data = map[string][]byte{"password": []byte("chosen-by-user")}
+		})
+		It("keeps it", func() {
+			Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+			Expect(string(secret.Data["password"])).To(Equal("chosen-by-user"))
+		})
+
+		When("rotation is requested", func() {
+			BeforeEach(func() {
+				cluster.Annotations = map[string]string{gpadminsecret.RotateAnnotation: "2020-06-01"}
+			})
+			It("generates a new password and records the request", func() {
+				Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+				Expect(string(secret.Data["password"])).NotTo(Equal("chosen-by-user"))
+				Expect(secret.Annotations).To(HaveKeyWithValue(gpadminsecret.RotateAnnotation, "2020-06-01"))
+			})
+			It("does not rotate again for the same request", func() {
+				Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+				rotated := string(secret.Data["password"])
+				Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+				Expect(string(secret.Data["password"])).To(Equal(rotated))
+			})
+
+			When("the request is removed", func() {
+				It("keeps the password and the recorded request", func() {
+					Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+					rotated := string(secret.Data["password"])
+					delete(cluster.Annotations, gpadminsecret.RotateAnnotation)
+					Expect(gpadminsecret.ModifySecret(cluster, secret)).To(Succeed())
+					Expect(string(secret.Data["password"])).To(Equal(rotated))
+					Expect(secret.Annotations).To(HaveKeyWithValue(gpadminsecret.RotateAnnotation, "2020-06-01"))
+				})
+			})
+		})
+	})
+})
+
+var _ = Describe("GeneratePassword", func() {
+	It("generates distinct alphanumeric passwords", func() {
+		first, err := gpadminsecret.GeneratePassword()
+		Expect(err).NotTo(HaveOccurred())
+		second, err := gpadminsecret.GeneratePassword()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).To(MatchRegexp("^[A-Za-z0-9]{32}$"))
+		Expect(first).NotTo(Equal(second))
+	})
+})
+
+var _ = Describe("PasswordDigest", func() {
+	It("is the hex SHA-256 of the password", func() {
+		Expect(gpadminsecret.PasswordDigest("secret")).To(Equal("2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"))
+	})
+})
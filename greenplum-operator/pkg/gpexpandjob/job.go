@@ -1,6 +1,7 @@
 package gpexpandjob
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
@@ -8,6 +9,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+// JobName is the name of the gpexpand Job of a GreenplumCluster
+func JobName(clusterName string) string {
+	return fmt.Sprintf("%s-gpexpand-job", clusterName)
+}
+
 func GenerateJob(image, hostname string, newSegCount int32) (job batchv1.Job) {
 	job.Spec.BackoffLimit = heapvalue.NewInt32(0)
 
@@ -38,3 +38,9 @@ var _ = Describe("GenerateJob", func() {
 		Expect(sshSecretVolumeMount.MountPath).To(Equal("/etc/ssh-key"))
 	})
 })
+
+var _ = Describe("JobName", func() {
+	It("is named after the cluster", func() {
+		Expect(JobName("my-greenplum")).To(Equal("my-greenplum-gpexpand-job"))
+	})
+})
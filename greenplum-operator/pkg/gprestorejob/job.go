@@ -0,0 +1,69 @@
+package gprestorejob
+
+import (
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func GenerateJob(image, hostname string, backup greenplumv1.GreenplumBackupSource) (job batchv1.Job) {
+	job.Spec.BackoffLimit = heapvalue.NewInt32(0)
+
+	gprestorePod := &job.Spec.Template.Spec
+	gprestorePod.RestartPolicy = corev1.RestartPolicyNever
+
+	gprestorePod.Volumes = []corev1.Volume{
+		{
+			Name: "ssh-key",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  "ssh-secrets",
+					DefaultMode: heapvalue.NewInt32(0444),
+				},
+			},
+		},
+	}
+	gprestorePod.ImagePullSecrets = []corev1.LocalObjectReference{
+		{
+			Name: "regsecret",
+		},
+	}
+	gprestorePod.Containers = []corev1.Container{
+		{
+			Name:    "gprestore",
+			Image:   image,
+			Command: []string{"/home/gpadmin/tools/gprestore_job.sh"},
+			Args:    gprestoreArgs(backup),
+			Env: []corev1.EnvVar{
+				{
+					Name:  "GPRESTORE_HOST",
+					Value: hostname,
+				},
+			},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "ssh-key",
+					MountPath: "/etc/ssh-key",
+				},
+			},
+		},
+	}
+
+	return
+}
+
+func gprestoreArgs(backup greenplumv1.GreenplumBackupSource) []string {
+	args := []string{"--timestamp", backup.Timestamp}
+	if backup.BackupDir != "" {
+		args = append(args, "--backup-dir", backup.BackupDir)
+	}
+	if backup.PluginConfig != "" {
+		args = append(args, "--plugin-config", backup.PluginConfig)
+	}
+	if backup.RedirectDB != "" {
+		args = append(args, "--redirect-db", backup.RedirectDB)
+	}
+	return append(args, "--create-db")
+}
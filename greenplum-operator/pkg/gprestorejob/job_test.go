@@ -0,0 +1,49 @@
+package gprestorejob
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("GenerateJob", func() {
+	It("sets properties on the job", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local",
+			greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", BackupDir: "/greenplum/volumes/backups"})
+		Expect(job.Spec.BackoffLimit).To(gstruct.PointTo(Equal(int32(0))))
+
+		gprestorePod := job.Spec.Template.Spec
+		Expect(gprestorePod.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+
+		sshSecretVolume := gprestorePod.Volumes[0]
+		Expect(sshSecretVolume.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolume.VolumeSource.Secret.SecretName).To(Equal("ssh-secrets"))
+		Expect(sshSecretVolume.VolumeSource.Secret.DefaultMode).To(gstruct.PointTo(Equal(int32(0444))))
+
+		Expect(gprestorePod.ImagePullSecrets[0].Name).To(Equal("regsecret"))
+		gprestoreContainer := gprestorePod.Containers[0]
+		Expect(gprestoreContainer.Name).To(Equal("gprestore"))
+		Expect(gprestoreContainer.Env[0].Name).To(Equal("GPRESTORE_HOST"))
+		Expect(gprestoreContainer.Env[0].Value).To(Equal("master-0.agent.default.svc.cluster.local"))
+		Expect(gprestoreContainer.Image).To(Equal("greenplum-for-kubernetes:magic"))
+		Expect(gprestoreContainer.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(gprestoreContainer.Command).To(Equal([]string{"/home/gpadmin/tools/gprestore_job.sh"}))
+		Expect(gprestoreContainer.Args).To(Equal([]string{
+			"--timestamp", "20200102030405", "--backup-dir", "/greenplum/volumes/backups", "--create-db",
+		}))
+
+		sshSecretVolumeMount := gprestoreContainer.VolumeMounts[0]
+		Expect(sshSecretVolumeMount.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolumeMount.MountPath).To(Equal("/etc/ssh-key"))
+	})
+
+	It("restores with a storage plugin into another database", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local",
+			greenplumv1.GreenplumBackupSource{Timestamp: "20200102030405", PluginConfig: "/greenplum/volumes/backups/s3.yaml", RedirectDB: "restored"})
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{
+			"--timestamp", "20200102030405", "--plugin-config", "/greenplum/volumes/backups/s3.yaml", "--redirect-db", "restored", "--create-db",
+		}))
+	})
+})
@@ -0,0 +1,13 @@
+package gprestorejob
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGprestorejob(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gprestorejob Suite")
+}
@@ -0,0 +1,70 @@
+package gpstopjob
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/cronschedule"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gpstop runs over ssh, which does not pass on the MASTER_DATA_DIRECTORY of the master container
+const masterDataDirectory = "/greenplum/data-1"
+
+// JobName is the name of the gpstop Job of an operation on a GreenplumCluster, such as "restart".
+// Each request of the operation gets its own Job, named after a hash of its annotation value.
+func JobName(clusterName, operation, requestedAt string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(requestedAt))
+	return cronschedule.JobName(clusterName+"-"+operation, fmt.Sprintf("-%08x", hash.Sum32()))
+}
+
+func GenerateJob(image, hostname, gpstopArgs string) (job batchv1.Job) {
+	job.Spec.BackoffLimit = heapvalue.NewInt32(0)
+
+	gpstopPod := &job.Spec.Template.Spec
+	gpstopPod.RestartPolicy = corev1.RestartPolicyNever
+
+	gpstopPod.Volumes = []corev1.Volume{
+		{
+			Name: "ssh-key",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  "ssh-secrets",
+					DefaultMode: heapvalue.NewInt32(0444),
+				},
+			},
+		},
+	}
+	gpstopPod.ImagePullSecrets = []corev1.LocalObjectReference{
+		{
+			Name: "regsecret",
+		},
+	}
+	gpstopPod.Containers = []corev1.Container{
+		{
+			Name:    "gpstop",
+			Image:   image,
+			Command: []string{"/home/gpadmin/tools/gpstop_job.sh"},
+			Args:    append([]string{"-d", masterDataDirectory}, strings.Fields(gpstopArgs)...),
+			Env: []corev1.EnvVar{
+				{
+					Name:  "GPSTOP_HOST",
+					Value: hostname,
+				},
+			},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "ssh-key",
+					MountPath: "/etc/ssh-key",
+				},
+			},
+		},
+	}
+
+	return
+}
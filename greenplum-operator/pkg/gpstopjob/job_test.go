@@ -0,0 +1,57 @@
+package gpstopjob
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("GenerateJob", func() {
+	It("sets properties on the job", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local", "-a -r -M fast")
+		Expect(job.Spec.BackoffLimit).To(gstruct.PointTo(Equal(int32(0))))
+
+		gpstopPod := job.Spec.Template.Spec
+		Expect(gpstopPod.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+
+		sshSecretVolume := gpstopPod.Volumes[0]
+		Expect(sshSecretVolume.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolume.VolumeSource.Secret.SecretName).To(Equal("ssh-secrets"))
+		Expect(sshSecretVolume.VolumeSource.Secret.DefaultMode).To(gstruct.PointTo(Equal(int32(0444))))
+
+		Expect(gpstopPod.ImagePullSecrets[0].Name).To(Equal("regsecret"))
+		gpstopContainer := gpstopPod.Containers[0]
+		Expect(gpstopContainer.Name).To(Equal("gpstop"))
+		Expect(gpstopContainer.Env[0].Name).To(Equal("GPSTOP_HOST"))
+		Expect(gpstopContainer.Env[0].Value).To(Equal("master-0.agent.default.svc.cluster.local"))
+		Expect(gpstopContainer.Image).To(Equal("greenplum-for-kubernetes:magic"))
+		Expect(gpstopContainer.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(gpstopContainer.Command).To(Equal([]string{"/home/gpadmin/tools/gpstop_job.sh"}))
+		// ssh does not pass on the MASTER_DATA_DIRECTORY of the master container
+		Expect(gpstopContainer.Args).To(Equal([]string{"-d", "/greenplum/data-1", "-a", "-r", "-M", "fast"}))
+
+		sshSecretVolumeMount := gpstopContainer.VolumeMounts[0]
+		Expect(sshSecretVolumeMount.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolumeMount.MountPath).To(Equal("/etc/ssh-key"))
+	})
+})
+
+var _ = Describe("JobName", func() {
+	It("is named after the cluster, the operation and the request", func() {
+		Expect(JobName("my-greenplum", "restart", "2020-06-01T12:00:00Z")).To(MatchRegexp(`^my-greenplum-restart-[0-9a-f]{8}$`))
+	})
+	It("keeps names of long clusters within 63 characters, and unique", func() {
+		clusterName := strings.Repeat("a", 60)
+		name := JobName(clusterName, "restart", "2020-06-01T12:00:00Z")
+		Expect(name).To(HaveLen(63))
+		Expect(name).To(HaveSuffix(JobName("my-greenplum", "restart", "2020-06-01T12:00:00Z")[len("my-greenplum-restart"):]))
+		Expect(JobName(clusterName, "reload", "2020-06-01T12:00:00Z")).NotTo(Equal(name))
+	})
+	It("differs for each request", func() {
+		Expect(JobName("my-greenplum", "restart", "2020-06-01T12:00:00Z")).NotTo(
+			Equal(JobName("my-greenplum", "restart", "2020-06-02T12:00:00Z")))
+	})
+})
@@ -0,0 +1,13 @@
+package gpstopjob
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGpstopjob(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gpstopjob Suite")
+}
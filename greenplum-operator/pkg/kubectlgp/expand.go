@@ -0,0 +1,160 @@
+package kubectlgp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpexpandjob"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	apiwait "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPollInterval paces the reads of the gpexpand Job and its pod
+const DefaultPollInterval = 2 * time.Second
+
+// ExpandResult is what kubectl gp expand prints
+type ExpandResult struct {
+	Name                 string `json:"name"`
+	PreviousSegmentCount int32  `json:"previousSegmentCount"`
+	SegmentCount         int32  `json:"segmentCount"`
+	// Job is the gpexpand Job that was followed. It is empty if the expansion was not followed.
+	Job string `json:"job,omitempty"`
+}
+
+// Expand raises the primarySegmentCount of the cluster to segments. Unless detach is set, it then follows the
+// log of the gpexpand Job that the operator creates for it, and fails if the Job fails or timeout passes.
+func (p *Plugin) Expand(ctx context.Context, segments int32, detach bool, timeout time.Duration) error {
+	greenplumCluster, err := p.greenplumCluster(ctx)
+	if err != nil {
+		return err
+	}
+	previousSegmentCount := greenplumCluster.Spec.Segments.PrimarySegmentCount
+	if segments <= previousSegmentCount {
+		return fmt.Errorf("GreenplumCluster %s already has %d primary segments; --segments must be more",
+			greenplumCluster.Name, previousSegmentCount)
+	}
+
+	// The operator keeps a failed gpexpand Job so that its logs can be read, and does not expand until it is deleted
+	jobKey := types.NamespacedName{Namespace: greenplumCluster.Namespace, Name: gpexpandjob.JobName(greenplumCluster.Name)}
+	var previousJob batchv1.Job
+	if err := p.Client.Get(ctx, jobKey, &previousJob); err == nil {
+		if previousJob.Status.Failed > 0 {
+			return fmt.Errorf("gpexpand Job %s failed. Delete it once you have read its logs, and retry", previousJob.Name)
+		}
+		if previousJob.Status.Succeeded < 1 {
+			return fmt.Errorf("gpexpand Job %s is still running", previousJob.Name)
+		}
+	} else if !apierrs.IsNotFound(err) {
+		return fmt.Errorf("getting gpexpand Job %s: %w", jobKey.Name, err)
+	}
+
+	patch := client.MergeFrom(greenplumCluster.DeepCopy())
+	greenplumCluster.Spec.Segments.PrimarySegmentCount = segments
+	if err := p.Client.Patch(ctx, greenplumCluster, patch); err != nil {
+		return fmt.Errorf("patching GreenplumCluster %s: %w", greenplumCluster.Name, err)
+	}
+	p.progress("greenplumcluster/%s patched: primarySegmentCount %d -> %d", greenplumCluster.Name, previousSegmentCount, segments)
+
+	result := ExpandResult{Name: greenplumCluster.Name, PreviousSegmentCount: previousSegmentCount, SegmentCount: segments}
+	if !detach {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		job, err := p.waitForJob(ctx, jobKey, previousJob.UID)
+		if err != nil {
+			return err
+		}
+		result.Job = job.Name
+		if err := p.followJob(ctx, job); err != nil {
+			return err
+		}
+	}
+	return p.print(result, func(w io.Writer) {
+		if detach {
+			fmt.Fprintf(w, "greenplumcluster/%s is expanding from %d to %d primary segments\n", result.Name, result.PreviousSegmentCount, result.SegmentCount)
+			return
+		}
+		fmt.Fprintf(w, "greenplumcluster/%s expanded from %d to %d primary segments\n", result.Name, result.PreviousSegmentCount, result.SegmentCount)
+	})
+}
+
+// waitForJob waits for the operator to create the gpexpand Job. A Job with previousUID was left over from the
+// last expansion, and is about to be deleted.
+func (p *Plugin) waitForJob(ctx context.Context, key types.NamespacedName, previousUID types.UID) (*batchv1.Job, error) {
+	p.progress("waiting for gpexpand Job %s", key.Name)
+	var job batchv1.Job
+	err := p.poll(ctx, func(ctx context.Context) (bool, error) {
+		if err := p.Client.Get(ctx, key, &job); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return job.UID != previousUID, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for gpexpand Job %s: %w", key.Name, err)
+	}
+	return &job, nil
+}
+
+// followJob streams the log of the pod of the Job until the pod stops, and returns whether the Job succeeded
+func (p *Plugin) followJob(ctx context.Context, job *batchv1.Job) error {
+	var pod corev1.Pod
+	err := p.poll(ctx, func(ctx context.Context) (bool, error) {
+		var podList corev1.PodList
+		if err := p.Client.List(ctx, &podList, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+			return false, err
+		}
+		for _, candidate := range podList.Items {
+			if ownedBy(&candidate, job.UID) && candidate.Status.Phase != corev1.PodPending {
+				pod = candidate
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for the pod of gpexpand Job %s to start: %w", job.Name, err)
+	}
+
+	logOptions := &corev1.PodLogOptions{Container: pod.Spec.Containers[0].Name, Follow: true}
+	if err := p.LogStreamer.StreamLog(ctx, pod.Namespace, pod.Name, logOptions, p.progressWriter()); err != nil {
+		return err
+	}
+
+	var succeeded bool
+	err = p.poll(ctx, func(ctx context.Context) (bool, error) {
+		if err := p.Client.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+			return false, err
+		}
+		succeeded = job.Status.Succeeded > 0
+		return succeeded || job.Status.Failed > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for gpexpand Job %s to finish: %w", job.Name, err)
+	}
+	if !succeeded {
+		return fmt.Errorf("gpexpand Job %s failed. Check its logs and gpAdminLogs on the master", job.Name)
+	}
+	return nil
+}
+
+func ownedBy(pod *corev1.Pod, uid types.UID) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plugin) poll(ctx context.Context, condition apiwait.ConditionWithContextFunc) error {
+	interval := p.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+	return apiwait.PollImmediateUntilWithContext(ctx, interval, condition)
+}
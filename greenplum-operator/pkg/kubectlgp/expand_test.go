@@ -0,0 +1,158 @@
+package kubectlgp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// expandingClient creates a gpexpand Job and its pod when the GreenplumCluster is patched, like the operator.
+// The Job of the last expansion, if any, is replaced.
+type expandingClient struct {
+	client.Client
+	jobStatus batchv1.JobStatus
+}
+
+func (c *expandingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	previousJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum-gpexpand-job"}}
+	if err := c.Client.Delete(ctx, previousJob); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum-gpexpand-job", UID: "new-job"},
+		Status:     c.jobStatus,
+	}
+	if err := c.Client.Create(ctx, job); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, gpexpandPod("my-greenplum-gpexpand-job-abcde", "new-job"))
+}
+
+func gpexpandPod(name string, jobUID types.UID) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "test-ns",
+			Name:            name,
+			Labels:          map[string]string{"job-name": "my-greenplum-gpexpand-job"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "my-greenplum-gpexpand-job", UID: jobUID}},
+		},
+		Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "gpexpand"}}},
+		Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+	}
+}
+
+var _ = Describe("Expand", func() {
+	var (
+		ctx  = context.Background()
+		logs *fakeLogStreamer
+	)
+
+	BeforeEach(func() {
+		logs = &fakeLogStreamer{logs: map[string]string{
+			"test-ns/my-greenplum-gpexpand-job-abcde": "Expansion has completed successfully\n",
+			"test-ns/my-greenplum-gpexpand-job-old":   "log of the last expansion\n",
+		}}
+	})
+
+	// newExpandingPlugin returns a Plugin whose gpexpand Job ends with jobStatus
+	newExpandingPlugin := func(jobStatus batchv1.JobStatus, objects ...runtime.Object) (*kubectlgp.Plugin, *bytes.Buffer, *bytes.Buffer) {
+		plugin, stdout, stderr := newPlugin(nil, logs, append([]runtime.Object{exampleGreenplumCluster()}, objects...)...)
+		plugin.Client = &expandingClient{Client: plugin.Client, jobStatus: jobStatus}
+		return plugin, stdout, stderr
+	}
+
+	getSegmentCount := func(plugin *kubectlgp.Plugin) int32 {
+		var greenplumCluster greenplumv1.GreenplumCluster
+		Expect(plugin.Client.Get(ctx, plugin.Cluster, &greenplumCluster)).To(Succeed())
+		return greenplumCluster.Spec.Segments.PrimarySegmentCount
+	}
+
+	It("patches primarySegmentCount and follows the log of the gpexpand Job", func() {
+		plugin, stdout, stderr := newExpandingPlugin(batchv1.JobStatus{Succeeded: 1})
+		Expect(plugin.Expand(ctx, 4, false, time.Minute)).To(Succeed())
+		Expect(getSegmentCount(plugin)).To(Equal(int32(4)))
+		Expect(logs.podName).To(Equal("my-greenplum-gpexpand-job-abcde"))
+		Expect(logs.options.Container).To(Equal("gpexpand"))
+		Expect(logs.options.Follow).To(BeTrue())
+		Expect(stderr.String()).To(Equal("greenplumcluster/my-greenplum patched: primarySegmentCount 2 -> 4\n" +
+			"waiting for gpexpand Job my-greenplum-gpexpand-job\n" +
+			"Expansion has completed successfully\n"))
+		Expect(stdout.String()).To(Equal("greenplumcluster/my-greenplum expanded from 2 to 4 primary segments\n"))
+	})
+
+	It("prints JSON", func() {
+		plugin, stdout, _ := newExpandingPlugin(batchv1.JobStatus{Succeeded: 1})
+		plugin.Output = kubectlgp.OutputJSON
+		Expect(plugin.Expand(ctx, 4, false, time.Minute)).To(Succeed())
+		var result kubectlgp.ExpandResult
+		Expect(json.Unmarshal(stdout.Bytes(), &result)).To(Succeed())
+		Expect(result).To(Equal(kubectlgp.ExpandResult{
+			Name: "my-greenplum", PreviousSegmentCount: 2, SegmentCount: 4, Job: "my-greenplum-gpexpand-job",
+		}))
+	})
+
+	It("does not follow the gpexpand Job when detached", func() {
+		plugin, stdout, _ := newExpandingPlugin(batchv1.JobStatus{Succeeded: 1})
+		Expect(plugin.Expand(ctx, 4, true, time.Minute)).To(Succeed())
+		Expect(getSegmentCount(plugin)).To(Equal(int32(4)))
+		Expect(logs.podName).To(BeEmpty())
+		Expect(stdout.String()).To(Equal("greenplumcluster/my-greenplum is expanding from 2 to 4 primary segments\n"))
+	})
+
+	It("follows the new gpexpand Job rather than the one of the last expansion", func() {
+		previousJob := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum-gpexpand-job", UID: "old-job"},
+			Status:     batchv1.JobStatus{Succeeded: 1},
+		}
+		plugin, _, _ := newExpandingPlugin(batchv1.JobStatus{Succeeded: 1}, previousJob, gpexpandPod("my-greenplum-gpexpand-job-old", "old-job"))
+		Expect(plugin.Expand(ctx, 4, false, time.Minute)).To(Succeed())
+		Expect(logs.podName).To(Equal("my-greenplum-gpexpand-job-abcde"))
+	})
+
+	It("fails if the gpexpand Job fails", func() {
+		plugin, _, stderr := newExpandingPlugin(batchv1.JobStatus{Failed: 1})
+		Expect(plugin.Expand(ctx, 4, false, time.Minute)).To(MatchError(
+			"gpexpand Job my-greenplum-gpexpand-job failed. Check its logs and gpAdminLogs on the master"))
+		Expect(stderr.String()).To(ContainSubstring("Expansion has completed successfully"))
+	})
+
+	It("fails if the gpexpand Job does not finish in time", func() {
+		plugin, _, _ := newExpandingPlugin(batchv1.JobStatus{Active: 1})
+		Expect(plugin.Expand(ctx, 4, false, 50*time.Millisecond)).To(MatchError(
+			"waiting for gpexpand Job my-greenplum-gpexpand-job to finish: timed out waiting for the condition"))
+	})
+
+	DescribeTable("refuses to expand",
+		func(segments int32, objects []runtime.Object, expectedError string) {
+			plugin, _, _ := newExpandingPlugin(batchv1.JobStatus{Succeeded: 1}, objects...)
+			Expect(plugin.Expand(ctx, segments, false, time.Minute)).To(MatchError(expectedError))
+			Expect(getSegmentCount(plugin)).To(Equal(int32(2)))
+		},
+		Entry("to fewer segments", int32(2), nil,
+			"GreenplumCluster my-greenplum already has 2 primary segments; --segments must be more"),
+		Entry("while the last gpexpand Job runs", int32(4), []runtime.Object{&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum-gpexpand-job"},
+			Status:     batchv1.JobStatus{Active: 1},
+		}}, "gpexpand Job my-greenplum-gpexpand-job is still running"),
+		Entry("until a failed gpexpand Job is deleted", int32(4), []runtime.Object{&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum-gpexpand-job"},
+			Status:     batchv1.JobStatus{Failed: 1},
+		}}, "gpexpand Job my-greenplum-gpexpand-job failed. Delete it once you have read its logs, and retry"),
+	)
+})
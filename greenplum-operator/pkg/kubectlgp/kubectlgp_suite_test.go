@@ -0,0 +1,13 @@
+package kubectlgp_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestKubectlgp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Kubectlgp Suite")
+}
@@ -0,0 +1,94 @@
+package kubectlgp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LogStreamer copies the log of a container to w, until its end or, with options.Follow, until the container stops
+type LogStreamer interface {
+	StreamLog(ctx context.Context, namespace, podName string, options *corev1.PodLogOptions, w io.Writer) error
+}
+
+// ClientsetLogStreamer streams pod logs through the Kubernetes API, like kubectl logs
+type ClientsetLogStreamer struct {
+	Clientset kubernetes.Interface
+}
+
+var _ LogStreamer = &ClientsetLogStreamer{}
+
+func (s *ClientsetLogStreamer) StreamLog(ctx context.Context, namespace, podName string, options *corev1.PodLogOptions, w io.Writer) error {
+	stream, err := s.Clientset.CoreV1().Pods(namespace).GetLogs(podName, options).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("reading log of %s: %w", podName, err)
+	}
+	defer stream.Close()
+	if _, err := io.Copy(w, stream); err != nil {
+		return fmt.Errorf("reading log of %s: %w", podName, err)
+	}
+	return nil
+}
+
+// LogsOptions selects the pod, and the part of its log, that kubectl gp logs prints
+type LogsOptions struct {
+	// Segment is the content ID of a segment, or -1 for the master
+	Segment int
+	// Mirror selects the segment-b pod of Segment, or the standby master
+	Mirror bool
+	Follow bool
+	// TailLines is how many lines to print from the end of the log. All lines are printed if it is negative.
+	TailLines int64
+}
+
+// Logs prints the log of the greenplum container of a master or segment pod. Since the container ships the
+// server and utility logs to stdout, this includes the pg_log of the pod.
+func (p *Plugin) Logs(ctx context.Context, options LogsOptions) error {
+	greenplumCluster, err := p.greenplumCluster(ctx)
+	if err != nil {
+		return err
+	}
+	podName, err := logsPodName(greenplumCluster, options)
+	if err != nil {
+		return err
+	}
+	logOptions := &corev1.PodLogOptions{Container: greenplumv1.AppName, Follow: options.Follow}
+	if options.TailLines >= 0 {
+		logOptions.TailLines = &options.TailLines
+	}
+	return p.LogStreamer.StreamLog(ctx, greenplumCluster.Namespace, podName, logOptions, p.Out)
+}
+
+func logsPodName(greenplumCluster *greenplumv1.GreenplumCluster, options LogsOptions) (string, error) {
+	if options.Segment == -1 {
+		master := greenplumCluster.Status.ActiveMaster
+		if master == "" {
+			master = "master-0"
+		}
+		if !options.Mirror {
+			return master, nil
+		}
+		if !strings.EqualFold(greenplumCluster.Spec.MasterAndStandby.Standby, "yes") {
+			return "", fmt.Errorf("GreenplumCluster %s has no standby master", greenplumCluster.Name)
+		}
+		return otherMaster(master), nil
+	}
+
+	segmentCount := int(greenplumCluster.Spec.Segments.PrimarySegmentCount)
+	if options.Segment < -1 || options.Segment >= segmentCount {
+		return "", fmt.Errorf("GreenplumCluster %s has no segment %d; its segments are 0 to %d, and -1 is the master",
+			greenplumCluster.Name, options.Segment, segmentCount-1)
+	}
+	if !options.Mirror {
+		return fmt.Sprintf("segment-a-%d", options.Segment), nil
+	}
+	if !strings.EqualFold(greenplumCluster.Spec.Segments.Mirrors, "yes") {
+		return "", fmt.Errorf("GreenplumCluster %s has no mirror segments", greenplumCluster.Name)
+	}
+	return fmt.Sprintf("segment-b-%d", options.Segment), nil
+}
@@ -0,0 +1,73 @@
+package kubectlgp_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+)
+
+var _ = Describe("Logs", func() {
+	var (
+		ctx  = context.Background()
+		logs *fakeLogStreamer
+	)
+
+	BeforeEach(func() {
+		logs = &fakeLogStreamer{logs: map[string]string{
+			"test-ns/master-0":    "master-0 log\n",
+			"test-ns/master-1":    "master-1 log\n",
+			"test-ns/segment-a-1": "segment-a-1 log\n",
+			"test-ns/segment-b-1": "segment-b-1 log\n",
+		}}
+	})
+
+	It("prints the log of the greenplum container of the segment", func() {
+		plugin, stdout, _ := newPlugin(nil, logs, exampleGreenplumCluster())
+		Expect(plugin.Logs(ctx, kubectlgp.LogsOptions{Segment: 1, TailLines: -1})).To(Succeed())
+		Expect(stdout.String()).To(Equal("segment-a-1 log\n"))
+		Expect(logs.options.Container).To(Equal(greenplumv1.AppName))
+		Expect(logs.options.TailLines).To(BeNil())
+		Expect(logs.options.Follow).To(BeFalse())
+	})
+
+	It("follows the end of the log", func() {
+		plugin, _, _ := newPlugin(nil, logs, exampleGreenplumCluster())
+		Expect(plugin.Logs(ctx, kubectlgp.LogsOptions{Segment: 1, Follow: true, TailLines: 100})).To(Succeed())
+		Expect(logs.options.TailLines).To(gstruct.PointTo(Equal(int64(100))))
+		Expect(logs.options.Follow).To(BeTrue())
+	})
+
+	DescribeTable("selects the pod",
+		func(activeMaster string, options kubectlgp.LogsOptions, expectedPod string) {
+			greenplumCluster := exampleGreenplumCluster()
+			greenplumCluster.Status.ActiveMaster = activeMaster
+			plugin, _, _ := newPlugin(nil, logs, greenplumCluster)
+			Expect(plugin.Logs(ctx, options)).To(Succeed())
+			Expect(logs.podName).To(Equal(expectedPod))
+		},
+		Entry("of a mirror", "master-0", kubectlgp.LogsOptions{Segment: 1, Mirror: true}, "segment-b-1"),
+		Entry("of the active master", "master-1", kubectlgp.LogsOptions{Segment: -1}, "master-1"),
+		Entry("of master-0 if no master is active", "", kubectlgp.LogsOptions{Segment: -1}, "master-0"),
+		Entry("of the standby master", "master-1", kubectlgp.LogsOptions{Segment: -1, Mirror: true}, "master-0"),
+	)
+
+	DescribeTable("fails",
+		func(modify func(*greenplumv1.GreenplumCluster), options kubectlgp.LogsOptions, expectedError string) {
+			greenplumCluster := exampleGreenplumCluster()
+			modify(greenplumCluster)
+			plugin, _, _ := newPlugin(nil, logs, greenplumCluster)
+			Expect(plugin.Logs(ctx, options)).To(MatchError(expectedError))
+		},
+		Entry("for a segment that does not exist", func(*greenplumv1.GreenplumCluster) {}, kubectlgp.LogsOptions{Segment: 2},
+			"GreenplumCluster my-greenplum has no segment 2; its segments are 0 to 1, and -1 is the master"),
+		Entry("for a mirror without mirrors", func(gc *greenplumv1.GreenplumCluster) { gc.Spec.Segments.Mirrors = "no" },
+			kubectlgp.LogsOptions{Segment: 0, Mirror: true}, "GreenplumCluster my-greenplum has no mirror segments"),
+		Entry("for the standby without a standby", func(gc *greenplumv1.GreenplumCluster) { gc.Spec.MasterAndStandby.Standby = "no" },
+			kubectlgp.LogsOptions{Segment: -1, Mirror: true}, "GreenplumCluster my-greenplum has no standby master"),
+	)
+})
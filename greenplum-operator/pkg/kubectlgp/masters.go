@@ -0,0 +1,128 @@
+package kubectlgp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+)
+
+const (
+	// masterDataDirectory is the data directory of both masters
+	masterDataDirectory = "/greenplum/data-1"
+	// sessionTimeout bounds a psql session, which the executor would otherwise end after executor.DefaultTimeout
+	sessionTimeout = 24 * time.Hour
+	// failoverTimeout bounds gpactivatestandby
+	failoverTimeout = 10 * time.Minute
+)
+
+// Psql runs psql as gpadmin on the active master, with args passed to it
+func (p *Plugin) Psql(ctx context.Context, database string, args []string) error {
+	greenplumCluster, err := p.greenplumCluster(ctx)
+	if err != nil {
+		return err
+	}
+	master, _, err := p.queryActiveMaster(ctx, greenplumCluster, "SELECT 1")
+	if err != nil {
+		return err
+	}
+	req := executor.ExecRequest{
+		Namespace:         greenplumCluster.Namespace,
+		PodName:           master,
+		Command:           psqlCommand(database, args),
+		Stdin:             p.In,
+		Stdout:            p.Out,
+		Stderr:            p.ErrOut,
+		TTY:               p.TTY,
+		TerminalSizeQueue: p.TerminalSize,
+		Timeout:           sessionTimeout,
+	}
+	if p.TTY {
+		req.Stderr = nil
+	}
+	return p.PodExec.Execute(ctx, req)
+}
+
+// psqlCommand runs psql on database. The arguments are passed to bash as positional arguments so that
+// they need no shell quoting.
+func psqlCommand(database string, args []string) []string {
+	return append([]string{
+		"/bin/bash",
+		"-c",
+		"--",
+		`source /usr/local/greenplum-db/greenplum_path.sh && exec psql -U gpadmin -d "$1" "${@:2}"`,
+		"psql",
+		database,
+	}, args...)
+}
+
+// FailoverResult is what kubectl gp failover prints
+type FailoverResult struct {
+	PreviousMaster string `json:"previousMaster"`
+	ActiveMaster   string `json:"activeMaster"`
+}
+
+// Failover promotes the standby master with gpactivatestandby. Unless force is set, the active master must have
+// stopped accepting connections, since promoting the standby of a running master splits the cluster in two.
+func (p *Plugin) Failover(ctx context.Context, force bool) error {
+	greenplumCluster, err := p.greenplumCluster(ctx)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(greenplumCluster.Spec.MasterAndStandby.Standby, "yes") {
+		return fmt.Errorf("GreenplumCluster %s has no standby master", greenplumCluster.Name)
+	}
+
+	previousMaster := greenplumCluster.Status.ActiveMaster
+	var accepting bool
+	if previousMaster == "" {
+		master, _, err := p.queryActiveMaster(ctx, greenplumCluster, "SELECT 1")
+		if err != nil {
+			return fmt.Errorf("cannot tell which master is the standby, since status.activeMaster is not set: %w", err)
+		}
+		previousMaster, accepting = master, true
+	} else {
+		_, err := executor.Query(ctx, p.PodExec, greenplumCluster.Namespace, previousMaster, "postgres", "SELECT 1")
+		accepting = err == nil
+	}
+	if accepting && !force {
+		return fmt.Errorf("%s is the active master and accepts connections; pass --force to promote %s anyway",
+			previousMaster, otherMaster(previousMaster))
+	}
+	standby := otherMaster(previousMaster)
+
+	p.progress("promoting %s to active master", standby)
+	gpactivatestandby := "source /usr/local/greenplum-db/greenplum_path.sh && gpactivatestandby -a -d " + masterDataDirectory
+	if force {
+		gpactivatestandby += " -f"
+	}
+	err = p.PodExec.Execute(ctx, executor.ExecRequest{
+		Namespace: greenplumCluster.Namespace,
+		PodName:   standby,
+		Command:   []string{"/bin/bash", "-c", "--", gpactivatestandby},
+		Stdout:    p.progressWriter(),
+		Stderr:    p.progressWriter(),
+		Timeout:   failoverTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("gpactivatestandby on %s failed: %w", standby, err)
+	}
+	if _, err := executor.Query(ctx, p.PodExec, greenplumCluster.Namespace, standby, "postgres", "SELECT 1"); err != nil {
+		return fmt.Errorf("gpactivatestandby succeeded, but %s does not accept connections: %w", standby, err)
+	}
+
+	result := FailoverResult{PreviousMaster: previousMaster, ActiveMaster: standby}
+	return p.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%s is the active master, instead of %s\n", result.ActiveMaster, result.PreviousMaster)
+	})
+}
+
+func otherMaster(master string) string {
+	if master == "master-1" {
+		return "master-0"
+	}
+	return "master-1"
+}
@@ -0,0 +1,148 @@
+package kubectlgp_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	fakePodExec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+)
+
+var _ = Describe("Psql", func() {
+	var (
+		ctx      = context.Background()
+		podExecs podExecs
+	)
+
+	BeforeEach(func() {
+		podExecs = map[string]*fakePodExec.PodExec{
+			"master-0": {CommandStdout: map[string]string{"SELECT 1": "1", "exec psql": " ?column? \n----------\n        2\n"}},
+			"master-1": refusesConnections(),
+		}
+	})
+
+	It("runs psql on the active master with the given arguments", func() {
+		plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		plugin.In = strings.NewReader("SELECT 2;")
+		Expect(plugin.Psql(ctx, "gpadmin", []string{"-v", "ON_ERROR_STOP=1"})).To(Succeed())
+		Expect(podExecs["master-0"].RecordedCommands).To(ContainElement(
+			`/bin/bash -c -- source /usr/local/greenplum-db/greenplum_path.sh && exec psql -U gpadmin -d "$1" "${@:2}" psql gpadmin -v ON_ERROR_STOP=1`))
+		Expect(podExecs["master-0"].RecordedStdin).To(Equal("SELECT 2;"))
+		Expect(podExecs["master-0"].RecordedTimeout).To(BeNumerically(">=", 24*time.Hour))
+		Expect(stdout.String()).To(ContainSubstring("2"))
+	})
+
+	It("connects to master-1 once it is the active master", func() {
+		podExecs["master-0"], podExecs["master-1"] = refusesConnections(), podExecs["master-0"]
+		plugin, _, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Psql(ctx, "postgres", nil)).To(Succeed())
+		Expect(podExecs["master-1"].RecordedCommands).To(ContainElement(ContainSubstring("psql postgres")))
+	})
+
+	It("fails if no master accepts connections", func() {
+		podExecs["master-0"] = refusesConnections()
+		plugin, _, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Psql(ctx, "gpadmin", nil)).To(MatchError(HavePrefix("no master accepts connections: master-0: ")))
+	})
+})
+
+var _ = Describe("Failover", func() {
+	var (
+		ctx      = context.Background()
+		podExecs podExecs
+	)
+
+	BeforeEach(func() {
+		podExecs = map[string]*fakePodExec.PodExec{
+			"master-0": refusesConnections(),
+			"master-1": {CommandStdout: map[string]string{
+				"gpactivatestandby": "gpactivatestandby:master-1:gpadmin-[INFO]:-The activation of the standby master has completed successfully.\n",
+				"SELECT 1":          "1",
+			}},
+		}
+	})
+
+	It("promotes the standby once the active master is down", func() {
+		plugin, stdout, stderr := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Failover(ctx, false)).To(Succeed())
+		Expect(podExecs["master-1"].RecordedCommands).To(ContainElement(
+			"/bin/bash -c -- source /usr/local/greenplum-db/greenplum_path.sh && gpactivatestandby -a -d /greenplum/data-1"))
+		Expect(stderr.String()).To(ContainSubstring("promoting master-1 to active master\n"))
+		Expect(stderr.String()).To(ContainSubstring("has completed successfully"))
+		Expect(stdout.String()).To(Equal("master-1 is the active master, instead of master-0\n"))
+	})
+
+	It("prints JSON", func() {
+		plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		plugin.Output = kubectlgp.OutputJSON
+		Expect(plugin.Failover(ctx, false)).To(Succeed())
+		var result kubectlgp.FailoverResult
+		Expect(json.Unmarshal(stdout.Bytes(), &result)).To(Succeed())
+		Expect(result).To(Equal(kubectlgp.FailoverResult{PreviousMaster: "master-0", ActiveMaster: "master-1"}))
+	})
+
+	It("promotes master-0 if master-1 was the active master", func() {
+		greenplumCluster := exampleGreenplumCluster()
+		greenplumCluster.Status.ActiveMaster = "master-1"
+		podExecs["master-0"], podExecs["master-1"] = podExecs["master-1"], refusesConnections()
+		plugin, stdout, _ := newPlugin(podExecs, nil, greenplumCluster)
+		Expect(plugin.Failover(ctx, false)).To(Succeed())
+		Expect(podExecs["master-0"].RecordedCommands).To(ContainElement(ContainSubstring("gpactivatestandby")))
+		Expect(stdout.String()).To(Equal("master-0 is the active master, instead of master-1\n"))
+	})
+
+	When("the active master accepts connections", func() {
+		BeforeEach(func() {
+			podExecs["master-0"] = &fakePodExec.PodExec{CommandStdout: map[string]string{"SELECT 1": "1"}}
+		})
+
+		It("refuses to promote the standby", func() {
+			plugin, _, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+			Expect(plugin.Failover(ctx, false)).To(MatchError(
+				"master-0 is the active master and accepts connections; pass --force to promote master-1 anyway"))
+			Expect(podExecs["master-1"].RecordedCommands).To(BeEmpty())
+		})
+
+		It("refuses to promote the standby if status.activeMaster is not set", func() {
+			greenplumCluster := exampleGreenplumCluster()
+			greenplumCluster.Status.ActiveMaster = ""
+			podExecs["master-1"] = refusesConnections()
+			plugin, _, _ := newPlugin(podExecs, nil, greenplumCluster)
+			Expect(plugin.Failover(ctx, false)).To(MatchError(
+				"master-0 is the active master and accepts connections; pass --force to promote master-1 anyway"))
+		})
+
+		It("forces gpactivatestandby with force", func() {
+			plugin, _, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+			Expect(plugin.Failover(ctx, true)).To(Succeed())
+			Expect(podExecs["master-1"].RecordedCommands).To(ContainElement(HaveSuffix("gpactivatestandby -a -d /greenplum/data-1 -f")))
+		})
+	})
+
+	It("fails without a standby master", func() {
+		greenplumCluster := exampleGreenplumCluster()
+		greenplumCluster.Spec.MasterAndStandby.Standby = "no"
+		plugin, _, _ := newPlugin(podExecs, nil, greenplumCluster)
+		Expect(plugin.Failover(ctx, false)).To(MatchError("GreenplumCluster my-greenplum has no standby master"))
+	})
+
+	It("fails if it cannot tell which master was active", func() {
+		greenplumCluster := exampleGreenplumCluster()
+		greenplumCluster.Status.ActiveMaster = ""
+		podExecs["master-1"] = refusesConnections()
+		plugin, _, _ := newPlugin(podExecs, nil, greenplumCluster)
+		Expect(plugin.Failover(ctx, false)).To(MatchError(HavePrefix(
+			"cannot tell which master is the standby, since status.activeMaster is not set: no master accepts connections")))
+	})
+
+	It("fails if gpactivatestandby fails", func() {
+		podExecs["master-1"].CommandErrors = map[string]string{"gpactivatestandby": "gpactivatestandby failed"}
+		plugin, _, stderr := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Failover(ctx, false)).To(MatchError("gpactivatestandby on master-1 failed: gpactivatestandby failed"))
+		Expect(stderr.String()).To(ContainSubstring("gpactivatestandby failed"))
+	})
+})
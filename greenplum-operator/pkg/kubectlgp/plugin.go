@@ -0,0 +1,117 @@
+// Package kubectlgp implements the subcommands of kubectl gp, a kubectl plugin for the day-2 operations of a
+// GreenplumCluster. It runs with the permissions of the current kubeconfig context, and reaches the masters and
+// segments by exec into their pods, so it needs no network access to the cluster.
+package kubectlgp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+var masters = []string{"master-0", "master-1"}
+
+// Plugin runs the subcommands against one GreenplumCluster
+type Plugin struct {
+	Client      client.Client
+	PodExec     executor.PodExecInterface
+	LogStreamer LogStreamer
+	Cluster     types.NamespacedName
+	// Output is OutputTable or OutputJSON
+	Output string
+	In     io.Reader
+	Out    io.Writer
+	// ErrOut receives the progress of long-running subcommands, so that Out only holds their result
+	ErrOut io.Writer
+	// TTY is set if In is a terminal. psql is then run in a terminal of its own.
+	TTY          bool
+	TerminalSize remotecommand.TerminalSizeQueue
+	// PollInterval is DefaultPollInterval if zero
+	PollInterval time.Duration
+}
+
+// FindCluster returns the name of the GreenplumCluster in namespace. The pods of a GreenplumCluster are
+// not named after it, so there is at most one per namespace.
+func FindCluster(ctx context.Context, reader client.Reader, namespace string) (string, error) {
+	var greenplumClusterList greenplumv1.GreenplumClusterList
+	if err := reader.List(ctx, &greenplumClusterList, client.InNamespace(namespace)); err != nil {
+		return "", fmt.Errorf("listing GreenplumClusters: %w", err)
+	}
+	switch len(greenplumClusterList.Items) {
+	case 0:
+		return "", fmt.Errorf("no GreenplumCluster found in namespace %s", namespace)
+	case 1:
+		return greenplumClusterList.Items[0].Name, nil
+	default:
+		return "", fmt.Errorf("%d GreenplumClusters found in namespace %s; pass --cluster", len(greenplumClusterList.Items), namespace)
+	}
+}
+
+func (p *Plugin) greenplumCluster(ctx context.Context) (*greenplumv1.GreenplumCluster, error) {
+	var greenplumCluster greenplumv1.GreenplumCluster
+	if err := p.Client.Get(ctx, p.Cluster, &greenplumCluster); err != nil {
+		return nil, fmt.Errorf("getting GreenplumCluster %s: %w", p.Cluster.Name, err)
+	}
+	return &greenplumCluster, nil
+}
+
+// queryActiveMaster runs sql on whichever master answers, starting with the one in status.activeMaster.
+// The standby master refuses connections, so the master that answers is the active one.
+func (p *Plugin) queryActiveMaster(ctx context.Context, greenplumCluster *greenplumv1.GreenplumCluster, sql string) (master, output string, err error) {
+	candidates := masters
+	if greenplumCluster.Status.ActiveMaster == "master-1" {
+		candidates = []string{"master-1", "master-0"}
+	}
+	var errs []string
+	for _, master := range candidates {
+		output, err := executor.Query(ctx, p.PodExec, greenplumCluster.Namespace, master, "postgres", sql)
+		if err == nil {
+			return master, output, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", master, err))
+	}
+	return "", "", fmt.Errorf("no master accepts connections: %s", strings.Join(errs, "; "))
+}
+
+// print writes v as JSON, or calls table to write it for humans
+func (p *Plugin) print(v interface{}, table func(w io.Writer)) error {
+	if p.Output == OutputJSON {
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.Out, "%s\n", out)
+		return err
+	}
+	w := tabwriter.NewWriter(p.Out, 0, 8, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// progress reports what a long-running subcommand is doing on ErrOut
+func (p *Plugin) progress(format string, args ...interface{}) {
+	fmt.Fprintf(p.progressWriter(), format+"\n", args...)
+}
+
+func (p *Plugin) progressWriter() io.Writer {
+	if p.ErrOut == nil {
+		return io.Discard
+	}
+	return p.ErrOut
+}
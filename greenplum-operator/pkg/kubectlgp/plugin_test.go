@@ -0,0 +1,102 @@
+package kubectlgp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor"
+	fakePodExec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// podExecs runs each command on the fake of its pod
+type podExecs map[string]*fakePodExec.PodExec
+
+func (p podExecs) Execute(ctx context.Context, req executor.ExecRequest) error {
+	podExec, ok := p[req.PodName]
+	if !ok {
+		return &executor.ExecError{PodName: req.PodName, ExitCode: -1, Err: errors.New("pods \"" + req.PodName + "\" not found")}
+	}
+	return podExec.Execute(ctx, req)
+}
+
+type fakeLogStreamer struct {
+	logs    map[string]string
+	podName string
+	options *corev1.PodLogOptions
+}
+
+func (s *fakeLogStreamer) StreamLog(_ context.Context, namespace, podName string, options *corev1.PodLogOptions, w io.Writer) error {
+	s.podName, s.options = podName, options
+	log, ok := s.logs[namespace+"/"+podName]
+	if !ok {
+		return errors.New("pod not found")
+	}
+	_, err := io.WriteString(w, log)
+	return err
+}
+
+// refusesConnections is a master whose psql fails, like a standby or a stopped master
+func refusesConnections() *fakePodExec.PodExec {
+	return &fakePodExec.PodExec{ErrorMsgOnCommand: "psql: FATAL:  the database system is in recovery mode"}
+}
+
+func exampleGreenplumCluster() *greenplumv1.GreenplumCluster {
+	greenplumCluster := &greenplumv1.GreenplumCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"}}
+	greenplumCluster.Spec.MasterAndStandby.Standby = "yes"
+	greenplumCluster.Spec.Segments.PrimarySegmentCount = 2
+	greenplumCluster.Spec.Segments.Mirrors = "yes"
+	greenplumCluster.Status.Phase = greenplumv1.GreenplumClusterPhaseRunning
+	greenplumCluster.Status.ActiveMaster = "master-0"
+	return greenplumCluster
+}
+
+// newPlugin returns a Plugin for the cluster in exampleGreenplumCluster, and its stdout and stderr
+func newPlugin(podExec executor.PodExecInterface, logs kubectlgp.LogStreamer, objects ...runtime.Object) (*kubectlgp.Plugin, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &kubectlgp.Plugin{
+		Client:       fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(objects...).Build(),
+		PodExec:      podExec,
+		LogStreamer:  logs,
+		Cluster:      types.NamespacedName{Namespace: "test-ns", Name: "my-greenplum"},
+		Output:       kubectlgp.OutputTable,
+		Out:          &stdout,
+		ErrOut:       &stderr,
+		PollInterval: time.Millisecond,
+	}, &stdout, &stderr
+}
+
+var _ = Describe("FindCluster", func() {
+	var ctx = context.Background()
+
+	It("returns the only GreenplumCluster of the namespace", func() {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(exampleGreenplumCluster()).Build()
+		Expect(kubectlgp.FindCluster(ctx, k8sClient, "test-ns")).To(Equal("my-greenplum"))
+	})
+
+	It("fails if the namespace has no GreenplumCluster", func() {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(exampleGreenplumCluster()).Build()
+		_, err := kubectlgp.FindCluster(ctx, k8sClient, "other-ns")
+		Expect(err).To(MatchError("no GreenplumCluster found in namespace other-ns"))
+	})
+
+	It("fails if the namespace has more than one GreenplumCluster", func() {
+		otherCluster := exampleGreenplumCluster()
+		otherCluster.Name = "other-greenplum"
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(exampleGreenplumCluster(), otherCluster).Build()
+		_, err := kubectlgp.FindCluster(ctx, k8sClient, "test-ns")
+		Expect(err).To(MatchError("2 GreenplumClusters found in namespace test-ns; pass --cluster"))
+	})
+})
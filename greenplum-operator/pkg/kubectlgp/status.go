@@ -0,0 +1,112 @@
+package kubectlgp
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+)
+
+// ClusterStatus is what kubectl gp status prints
+type ClusterStatus struct {
+	Name         string                            `json:"name"`
+	Namespace    string                            `json:"namespace"`
+	Phase        greenplumv1.GreenplumClusterPhase `json:"phase"`
+	ActiveMaster string                            `json:"activeMaster"`
+	// Segments is gp_segment_configuration, read on the active master
+	Segments []gpstatus.Segment `json:"segments"`
+	// SegmentsError is why Segments could not be read
+	SegmentsError string `json:"segmentsError,omitempty"`
+}
+
+// Status prints the phase of the cluster, and the role, mirror sync mode and status of each segment. A cluster
+// whose masters do not answer still gets its phase printed, so the command succeeds.
+func (p *Plugin) Status(ctx context.Context) error {
+	greenplumCluster, err := p.greenplumCluster(ctx)
+	if err != nil {
+		return err
+	}
+	status := ClusterStatus{
+		Name:         greenplumCluster.Name,
+		Namespace:    greenplumCluster.Namespace,
+		Phase:        greenplumCluster.Status.Phase,
+		ActiveMaster: greenplumCluster.Status.ActiveMaster,
+		Segments:     []gpstatus.Segment{},
+	}
+	master, output, err := p.queryActiveMaster(ctx, greenplumCluster, gpstatus.SegmentConfigurationQuery)
+	if err == nil {
+		status.ActiveMaster = master
+		status.Segments, err = gpstatus.ParseSegments(output)
+	}
+	if err != nil {
+		status.SegmentsError = err.Error()
+	}
+	return p.print(status, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tNAMESPACE\tPHASE\tACTIVE MASTER")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Name, status.Namespace, orNone(string(status.Phase)), orNone(status.ActiveMaster))
+		fmt.Fprintln(w)
+		if status.SegmentsError != "" {
+			fmt.Fprintf(w, "segments unavailable: %s\n", status.SegmentsError)
+			return
+		}
+		mirrored := map[int]bool{}
+		for _, segment := range status.Segments {
+			if segment.Role == "m" {
+				mirrored[segment.ContentID] = true
+			}
+		}
+		fmt.Fprintln(w, "CONTENT\tHOSTNAME\tPORT\tROLE\tPREFERRED ROLE\tMIRROR SYNC\tSTATUS")
+		for _, segment := range status.Segments {
+			sync := "-"
+			if mirrored[segment.ContentID] {
+				sync = describeMode(segment.Mode)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\n", segment.ContentID, segment.Hostname, segment.Port,
+				describeRole(segment.Role), describeRole(segment.PreferredRole), sync, describeStatus(segment.Status))
+		}
+	})
+}
+
+func describeRole(role string) string {
+	switch role {
+	case "p":
+		return "primary"
+	case "m":
+		return "mirror"
+	}
+	return role
+}
+
+// describeMode describes the replication mode of gp_segment_configuration. Greenplum 5 also has the modes r and c.
+func describeMode(mode string) string {
+	switch mode {
+	case "s":
+		return "synced"
+	case "n":
+		return "not synced"
+	case "r":
+		return "resyncing"
+	case "c":
+		return "change tracking"
+	}
+	return mode
+}
+
+func describeStatus(status string) string {
+	switch status {
+	case "u":
+		return "up"
+	case "d":
+		return "down"
+	}
+	return status
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
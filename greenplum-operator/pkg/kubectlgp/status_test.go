@@ -0,0 +1,103 @@
+package kubectlgp_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	fakePodExec "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/executor/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+)
+
+var _ = Describe("Status", func() {
+	const segmentConfiguration = "-1|p|p|s|u|master-0|5432\n-1|m|m|s|u|master-1|5432\n" +
+		"0|p|p|s|u|segment-a-0|40000\n0|m|m|s|u|segment-b-0|50000\n" +
+		"1|m|p|n|d|segment-a-1|40000\n1|p|m|n|u|segment-b-1|50000\n"
+	var (
+		ctx      = context.Background()
+		podExecs podExecs
+	)
+
+	BeforeEach(func() {
+		podExecs = map[string]*fakePodExec.PodExec{
+			"master-0": {CommandStdout: map[string]string{"gp_segment_configuration": segmentConfiguration}},
+			"master-1": refusesConnections(),
+		}
+	})
+
+	It("prints the segments with their roles, mirror sync and status", func() {
+		plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Status(ctx)).To(Succeed())
+		Expect(stdout.String()).To(Equal(
+			"NAME          NAMESPACE  PHASE    ACTIVE MASTER\n" +
+				"my-greenplum  test-ns    Running  master-0\n" +
+				"\n" +
+				"CONTENT  HOSTNAME     PORT   ROLE     PREFERRED ROLE  MIRROR SYNC  STATUS\n" +
+				"-1       master-0     5432   primary  primary         synced       up\n" +
+				"-1       master-1     5432   mirror   mirror          synced       up\n" +
+				"0        segment-a-0  40000  primary  primary         synced       up\n" +
+				"0        segment-b-0  50000  mirror   mirror          synced       up\n" +
+				"1        segment-a-1  40000  mirror   primary         not synced   down\n" +
+				"1        segment-b-1  50000  primary  mirror          not synced   up\n"))
+	})
+
+	It("does not describe the sync of a segment without a mirror", func() {
+		podExecs["master-0"].CommandStdout["gp_segment_configuration"] = "-1|p|p|n|u|master-0|5432\n0|p|p|n|u|segment-a-0|40000\n"
+		plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Status(ctx)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("0        segment-a-0  40000  primary  primary         -            up\n"))
+	})
+
+	It("prints JSON", func() {
+		plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		plugin.Output = kubectlgp.OutputJSON
+		Expect(plugin.Status(ctx)).To(Succeed())
+		var status kubectlgp.ClusterStatus
+		Expect(json.Unmarshal(stdout.Bytes(), &status)).To(Succeed())
+		Expect(status.Phase).To(BeEquivalentTo("Running"))
+		Expect(status.ActiveMaster).To(Equal("master-0"))
+		Expect(status.Segments).To(HaveLen(6))
+		Expect(status.Segments[4]).To(Equal(gpstatus.Segment{
+			ContentID: 1, Role: "m", PreferredRole: "p", Mode: "n", Status: "d", Hostname: "segment-a-1", Port: 40000,
+		}))
+	})
+
+	It("reads the segments on master-1 once it is the active master", func() {
+		podExecs["master-0"], podExecs["master-1"] = refusesConnections(), podExecs["master-0"]
+		plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+		Expect(plugin.Status(ctx)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("my-greenplum  test-ns    Running  master-1\n"))
+		Expect(stdout.String()).To(ContainSubstring("segment-b-1"))
+	})
+
+	When("no master accepts connections", func() {
+		BeforeEach(func() {
+			podExecs["master-0"] = refusesConnections()
+		})
+
+		It("still prints the phase of the cluster", func() {
+			plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+			Expect(plugin.Status(ctx)).To(Succeed())
+			Expect(stdout.String()).To(HavePrefix("NAME          NAMESPACE  PHASE    ACTIVE MASTER\n" +
+				"my-greenplum  test-ns    Running  master-0\n\n" +
+				"segments unavailable: no master accepts connections: master-0: "))
+		})
+
+		It("reports the error in JSON", func() {
+			plugin, stdout, _ := newPlugin(podExecs, nil, exampleGreenplumCluster())
+			plugin.Output = kubectlgp.OutputJSON
+			Expect(plugin.Status(ctx)).To(Succeed())
+			var status kubectlgp.ClusterStatus
+			Expect(json.Unmarshal(stdout.Bytes(), &status)).To(Succeed())
+			Expect(status.Segments).To(BeEmpty())
+			Expect(status.SegmentsError).To(HavePrefix("no master accepts connections"))
+		})
+	})
+
+	It("fails if the GreenplumCluster does not exist", func() {
+		plugin, _, _ := newPlugin(podExecs, nil)
+		Expect(plugin.Status(ctx)).To(MatchError(ContainSubstring("getting GreenplumCluster my-greenplum")))
+	})
+})
@@ -0,0 +1,55 @@
+package kubectlgp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/integrationutils/kubewait"
+)
+
+var phases = []greenplumv1.GreenplumClusterPhase{
+	greenplumv1.GreenplumClusterPhasePending,
+	greenplumv1.GreenplumClusterPhaseRunning,
+	greenplumv1.GreenplumClusterPhaseFailed,
+	greenplumv1.GreenplumClusterPhaseDeleting,
+}
+
+// ParsePhase returns the GreenplumClusterPhase named s, ignoring case
+func ParsePhase(s string) (greenplumv1.GreenplumClusterPhase, error) {
+	var names []string
+	for _, phase := range phases {
+		if strings.EqualFold(s, string(phase)) {
+			return phase, nil
+		}
+		names = append(names, string(phase))
+	}
+	return "", fmt.Errorf("unknown phase %q; expected one of %s", s, strings.Join(names, ", "))
+}
+
+// WaitResult is what kubectl gp wait prints
+type WaitResult struct {
+	Name  string                            `json:"name"`
+	Phase greenplumv1.GreenplumClusterPhase `json:"phase"`
+}
+
+// Wait waits until the cluster reaches phase, and reports the other phases that it passes through
+func (p *Plugin) Wait(ctx context.Context, phase greenplumv1.GreenplumClusterPhase, timeout time.Duration) error {
+	var lastPhase *greenplumv1.GreenplumClusterPhase
+	err := kubewait.ForGreenplumClusterPhase(ctx, p.Client, p.Cluster, phase, timeout, func(observed greenplumv1.GreenplumClusterPhase) {
+		if observed != phase && (lastPhase == nil || observed != *lastPhase) {
+			p.progress("greenplumcluster/%s is %s", p.Cluster.Name, orNone(string(observed)))
+		}
+		lastPhase = &observed
+	})
+	if err != nil {
+		return err
+	}
+	result := WaitResult{Name: p.Cluster.Name, Phase: phase}
+	return p.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "greenplumcluster/%s is %s\n", result.Name, result.Phase)
+	})
+}
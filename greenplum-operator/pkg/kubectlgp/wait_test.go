@@ -0,0 +1,59 @@
+package kubectlgp_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/kubectlgp"
+)
+
+var _ = Describe("ParsePhase", func() {
+	It("ignores case", func() {
+		Expect(kubectlgp.ParsePhase("running")).To(Equal(greenplumv1.GreenplumClusterPhaseRunning))
+	})
+
+	It("fails for an unknown phase", func() {
+		_, err := kubectlgp.ParsePhase("Ready")
+		Expect(err).To(MatchError(`unknown phase "Ready"; expected one of Pending, Running, Failed, Deleting`))
+	})
+})
+
+var _ = Describe("Wait", func() {
+	var ctx = context.Background()
+
+	It("returns once the cluster is in the phase", func() {
+		plugin, stdout, stderr := newPlugin(nil, nil, exampleGreenplumCluster())
+		Expect(plugin.Wait(ctx, greenplumv1.GreenplumClusterPhaseRunning, time.Minute)).To(Succeed())
+		Expect(stdout.String()).To(Equal("greenplumcluster/my-greenplum is Running\n"))
+		Expect(stderr.String()).To(BeEmpty())
+	})
+
+	It("prints JSON", func() {
+		plugin, stdout, _ := newPlugin(nil, nil, exampleGreenplumCluster())
+		plugin.Output = kubectlgp.OutputJSON
+		Expect(plugin.Wait(ctx, greenplumv1.GreenplumClusterPhaseRunning, time.Minute)).To(Succeed())
+		var result kubectlgp.WaitResult
+		Expect(json.Unmarshal(stdout.Bytes(), &result)).To(Succeed())
+		Expect(result).To(Equal(kubectlgp.WaitResult{Name: "my-greenplum", Phase: greenplumv1.GreenplumClusterPhaseRunning}))
+	})
+
+	It("reports the phase it is in until it times out", func() {
+		greenplumCluster := exampleGreenplumCluster()
+		greenplumCluster.Status.Phase = greenplumv1.GreenplumClusterPhasePending
+		plugin, stdout, stderr := newPlugin(nil, nil, greenplumCluster)
+		err := plugin.Wait(ctx, greenplumv1.GreenplumClusterPhaseRunning, 100*time.Millisecond)
+		Expect(err).To(MatchError(HavePrefix("waiting for GreenplumCluster test-ns/my-greenplum to be Running")))
+		Expect(stderr.String()).To(Equal("greenplumcluster/my-greenplum is Pending\n"))
+		Expect(stdout.String()).To(BeEmpty())
+	})
+
+	It("fails if the GreenplumCluster does not exist", func() {
+		plugin, _, _ := newPlugin(nil, nil)
+		err := plugin.Wait(ctx, greenplumv1.GreenplumClusterPhaseRunning, time.Minute)
+		Expect(err).To(MatchError(ContainSubstring(`greenplumclusters.greenplum.pivotal.io "my-greenplum" not found`)))
+	})
+})
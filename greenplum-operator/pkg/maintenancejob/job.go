@@ -0,0 +1,76 @@
+package maintenancejob
+
+import (
+	"strconv"
+
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func GenerateJob(image, hostname string, spec greenplumv1beta1.GreenplumMaintenanceSpec) (job batchv1.Job) {
+	job.Spec.BackoffLimit = heapvalue.NewInt32(0)
+
+	maintenancePod := &job.Spec.Template.Spec
+	maintenancePod.RestartPolicy = corev1.RestartPolicyNever
+
+	maintenancePod.Volumes = []corev1.Volume{
+		{
+			Name: "ssh-key",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  "ssh-secrets",
+					DefaultMode: heapvalue.NewInt32(0444),
+				},
+			},
+		},
+	}
+	maintenancePod.ImagePullSecrets = []corev1.LocalObjectReference{
+		{
+			Name: "regsecret",
+		},
+	}
+	maintenancePod.Containers = []corev1.Container{
+		{
+			Name:    "maintenance",
+			Image:   image,
+			Command: []string{"/home/gpadmin/tools/maintenance_job.sh"},
+			Args:    maintenanceArgs(spec),
+			Env: []corev1.EnvVar{
+				{
+					Name:  "MAINTENANCE_HOST",
+					Value: hostname,
+				},
+			},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "ssh-key",
+					MountPath: "/etc/ssh-key",
+				},
+			},
+		},
+	}
+
+	return
+}
+
+// maintenanceArgs are the options of greenplum-instance/scripts/maintenance.sh, followed by the tasks
+func maintenanceArgs(spec greenplumv1beta1.GreenplumMaintenanceSpec) []string {
+	concurrency := spec.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	args := []string{"-p", strconv.Itoa(int(concurrency))}
+	for _, database := range spec.Databases {
+		args = append(args, "-d", database)
+	}
+	for _, schema := range spec.Schemas {
+		args = append(args, "-s", string(schema))
+	}
+	for _, task := range spec.Tasks {
+		args = append(args, string(task))
+	}
+	return args
+}
@@ -0,0 +1,58 @@
+package maintenancejob
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
+	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("GenerateJob", func() {
+	It("sets properties on the job", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local",
+			greenplumv1beta1.GreenplumMaintenanceSpec{
+				Tasks: []greenplumv1beta1.GreenplumMaintenanceTask{
+					greenplumv1beta1.GreenplumMaintenanceTaskVacuumCatalog,
+					greenplumv1beta1.GreenplumMaintenanceTaskAnalyze,
+				},
+				Concurrency: 1,
+			})
+		Expect(job.Spec.BackoffLimit).To(gstruct.PointTo(Equal(int32(0))))
+
+		maintenancePod := job.Spec.Template.Spec
+		Expect(maintenancePod.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+
+		sshSecretVolume := maintenancePod.Volumes[0]
+		Expect(sshSecretVolume.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolume.VolumeSource.Secret.SecretName).To(Equal("ssh-secrets"))
+		Expect(sshSecretVolume.VolumeSource.Secret.DefaultMode).To(gstruct.PointTo(Equal(int32(0444))))
+
+		Expect(maintenancePod.ImagePullSecrets[0].Name).To(Equal("regsecret"))
+		maintenanceContainer := maintenancePod.Containers[0]
+		Expect(maintenanceContainer.Name).To(Equal("maintenance"))
+		Expect(maintenanceContainer.Env[0].Name).To(Equal("MAINTENANCE_HOST"))
+		Expect(maintenanceContainer.Env[0].Value).To(Equal("master-0.agent.default.svc.cluster.local"))
+		Expect(maintenanceContainer.Image).To(Equal("greenplum-for-kubernetes:magic"))
+		Expect(maintenanceContainer.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(maintenanceContainer.Command).To(Equal([]string{"/home/gpadmin/tools/maintenance_job.sh"}))
+		Expect(maintenanceContainer.Args).To(Equal([]string{"-p", "1", "vacuumCatalog", "analyze"}))
+
+		sshSecretVolumeMount := maintenanceContainer.VolumeMounts[0]
+		Expect(sshSecretVolumeMount.Name).To(Equal("ssh-key"))
+		Expect(sshSecretVolumeMount.MountPath).To(Equal("/etc/ssh-key"))
+	})
+
+	It("limits the maintenance to databases and schemas", func() {
+		job := GenerateJob("greenplum-for-kubernetes:magic", "master-0.agent.default.svc.cluster.local",
+			greenplumv1beta1.GreenplumMaintenanceSpec{
+				Tasks:       []greenplumv1beta1.GreenplumMaintenanceTask{greenplumv1beta1.GreenplumMaintenanceTaskReindexBloatedTables},
+				Databases:   []string{"sales", "hr"},
+				Schemas:     []greenplumv1beta1.GreenplumSchemaName{"public", "staging"},
+				Concurrency: 2,
+			})
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{
+			"-p", "2", "-d", "sales", "-d", "hr", "-s", "public", "-s", "staging", "reindexBloatedTables",
+		}))
+	})
+})
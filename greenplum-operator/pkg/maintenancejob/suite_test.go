@@ -0,0 +1,13 @@
+package maintenancejob
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMaintenancejob(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "maintenancejob Suite")
+}
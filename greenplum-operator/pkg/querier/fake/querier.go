@@ -0,0 +1,128 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+)
+
+const DefaultSegmentCount = 1 // Used as the primarySegmentCount of exampleGreenplumCluster
+
+type Querier struct {
+	// Ping of master-0 or master-1 fails with these messages
+	ErrorMsgOnMaster0 string
+	ErrorMsgOnMaster1 string
+	// PingErr is returned by Ping of the other masters, for example a *pq.Error that fails authentication
+	PingErr error
+	// PingErrCount limits PingErr to the first pings, if set
+	PingErrCount int
+	pings        int
+
+	SegmentCount    int
+	SegmentCountErr error
+
+	// Keyed by a substring of the query. A matching query is recorded and returns
+	// one row with the given values, or fails with the given error.
+	Results map[string][]interface{}
+	Errors  map[string]error
+
+	RecordedQueries []string
+	CalledMaster    querier.Master
+}
+
+var _ querier.GreenplumQuerier = &Querier{}
+
+func (f *Querier) Ping(ctx context.Context, master querier.Master) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.ErrorMsgOnMaster0 != "" && master.PodName == "master-0" {
+		return errors.New(f.ErrorMsgOnMaster0)
+	} else if f.ErrorMsgOnMaster1 != "" && master.PodName == "master-1" {
+		return errors.New(f.ErrorMsgOnMaster1)
+	}
+	f.pings++
+	if f.PingErrCount != 0 && f.pings > f.PingErrCount {
+		return nil
+	}
+	return f.PingErr
+}
+
+func (f *Querier) QueryRow(ctx context.Context, master querier.Master, query string, args ...interface{}) querier.Row {
+	rows, err := f.Query(ctx, master, query, args...)
+	if err != nil {
+		return &Rows{err: err}
+	}
+	r := rows.(*Rows)
+	if !r.Next() {
+		return &Rows{err: fmt.Errorf("fake: no rows for query %q", query)}
+	}
+	return r
+}
+
+func (f *Querier) Query(ctx context.Context, master querier.Master, query string, args ...interface{}) (querier.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.Contains(query, "FROM gp_segment_configuration") {
+		if f.SegmentCountErr != nil {
+			return nil, f.SegmentCountErr
+		}
+		segCount := DefaultSegmentCount
+		if f.SegmentCount != 0 {
+			segCount = f.SegmentCount
+		}
+		return &Rows{rows: [][]interface{}{{int64(segCount)}}}, nil
+	}
+
+	f.CalledMaster = master
+	f.RecordedQueries = append(f.RecordedQueries, query)
+	for key, err := range f.Errors {
+		if strings.Contains(query, key) {
+			return nil, err
+		}
+	}
+	for key, values := range f.Results {
+		if strings.Contains(query, key) {
+			return &Rows{rows: [][]interface{}{values}}, nil
+		}
+	}
+	return &Rows{}, nil
+}
+
+// Rows scans canned values, converting them to the types of the destinations
+type Rows struct {
+	rows [][]interface{}
+	row  int
+	err  error
+}
+
+func (r *Rows) Next() bool {
+	if r.row >= len(r.rows) {
+		return false
+	}
+	r.row++
+	return true
+}
+
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	values := r.rows[r.row-1]
+	if len(dest) != len(values) {
+		return fmt.Errorf("fake: expected %d destination arguments in Scan, not %d", len(values), len(dest))
+	}
+	for i, value := range values {
+		target := reflect.ValueOf(dest[i]).Elem()
+		target.Set(reflect.ValueOf(value).Convert(target.Type()))
+	}
+	return nil
+}
+
+func (r *Rows) Err() error   { return r.err }
+func (r *Rows) Close() error { return nil }
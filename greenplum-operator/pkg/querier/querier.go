@@ -0,0 +1,265 @@
+package querier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = ctrllog.Log.WithName("GreenplumQuerier")
+
+const (
+	Port = 5432
+
+	// Role is the role the operator connects as. It can log in, and has no other privileges.
+	Role = "gpoperator"
+	// Databases are the databases the operator connects to
+	Databases = "gpadmin,postgres"
+	// NetworksAnnotation on the gpoperator-password Secret records the networks of the pg_hba.conf entries of Role
+	NetworksAnnotation = "greenplum.pivotal.io/querier-networks"
+
+	// connectTimeout bounds connecting to a master that is down, since the driver does not dial with a context
+	connectTimeout = 10 * time.Second
+)
+
+// Master is a master pod of a Greenplum cluster
+type Master struct {
+	Namespace string
+	PodName   string
+	// Database defaults to gpadmin
+	Database string
+}
+
+// Row is a single result row. *sql.Row implements it.
+type Row interface {
+	Scan(dest ...interface{}) error
+}
+
+// Rows is the result of a query. *sql.Rows implements it.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// OperatorPodLabels select the pods of the operator Deployment
+var OperatorPodLabels = client.MatchingLabels{"app": "greenplum-operator"}
+
+// HBAEntry lets the operator connect to the masters from network as Role, with the password from the
+// gpoperator-password Secret. With tls, the master only accepts the connection over SSL.
+func HBAEntry(network string, tls bool) string {
+	connectionType := "host"
+	if tls {
+		connectionType = "hostssl"
+	}
+	return fmt.Sprintf("%s %s %s %s md5", connectionType, Databases, Role, network)
+}
+
+// OperatorNetworks returns a single-address network for each operator pod in namespace that has an IP.
+// The operator connects to the masters from these addresses, unless it is configured with another network.
+func OperatorNetworks(ctx context.Context, reader client.Reader, namespace string) ([]string, error) {
+	var pods corev1.PodList
+	if err := reader.List(ctx, &pods, client.InNamespace(namespace), OperatorPodLabels); err != nil {
+		return nil, fmt.Errorf("failed to list operator pods: %w", err)
+	}
+	var networks []string
+	for _, pod := range pods.Items {
+		ip := net.ParseIP(pod.Status.PodIP)
+		if ip == nil {
+			continue
+		}
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+		networks = append(networks, fmt.Sprintf("%s/%d", ip, bits))
+	}
+	sort.Strings(networks)
+	return networks, nil
+}
+
+// GreenplumQuerier runs read-only SQL as Role on a master of a Greenplum cluster
+type GreenplumQuerier interface {
+	// Ping connects to master and returns an error if it does not accept connections
+	Ping(ctx context.Context, master Master) error
+	QueryRow(ctx context.Context, master Master, query string, args ...interface{}) Row
+	Query(ctx context.Context, master Master, query string, args ...interface{}) (Rows, error)
+}
+
+// ActiveMaster returns the name of the master pod that accepts connections, or "" if neither does.
+// The standby master refuses connections before authentication, so a master that rejects the credentials of
+// Role is active, but cannot be queried. It is returned as an *AuthenticationError.
+func ActiveMaster(ctx context.Context, q GreenplumQuerier, namespace string) (string, error) {
+	for _, podName := range []string{"master-0", "master-1"} {
+		err := q.Ping(ctx, Master{Namespace: namespace, PodName: podName})
+		if err == nil {
+			return podName, nil
+		}
+		if IsAuthenticationError(err) {
+			return "", &AuthenticationError{PodName: podName, Err: err}
+		}
+		log.V(1).Info(podName+" is not active master", "namespace", namespace, "error", err)
+	}
+	return "", nil
+}
+
+// AuthenticationError is returned by ActiveMaster when the active master rejects the credentials of Role,
+// or has no pg_hba.conf entry for the operator
+type AuthenticationError struct {
+	PodName string
+	Err     error
+}
+
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("active master %s rejects the operator: %s", e.PodName, e.Err)
+}
+
+func (e *AuthenticationError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthenticationError reports whether err means that the master rejected the credentials of Role,
+// or has no pg_hba.conf entry for the operator
+func IsAuthenticationError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "28"
+}
+
+// PQQuerier is a GreenplumQuerier that connects with lib/pq. Connections are pooled per master and database.
+type PQQuerier struct {
+	// Reader reads the gpoperator-password Secret of a cluster
+	Reader client.Reader
+	// Address returns the host:port of master. The zero value uses the DNS name of the pod in the agent headless Service.
+	Address func(master Master) string
+	// SSLMode is the lib/pq sslmode of the connections: disable, require, verify-ca or verify-full.
+	// The zero value disables SSL.
+	SSLMode string
+
+	mu    sync.Mutex
+	pools map[Master]pool
+}
+
+type pool struct {
+	dsn string
+	db  *sql.DB
+}
+
+var _ GreenplumQuerier = &PQQuerier{}
+
+func (q *PQQuerier) Ping(ctx context.Context, master Master) error {
+	db, err := q.db(ctx, master)
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+func (q *PQQuerier) QueryRow(ctx context.Context, master Master, query string, args ...interface{}) Row {
+	db, err := q.db(ctx, master)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+func (q *PQQuerier) Query(ctx context.Context, master Master, query string, args ...interface{}) (Rows, error) {
+	db, err := q.db(ctx, master)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// db returns the pool of master. The pool is replaced when the password of Role changes.
+func (q *PQQuerier) db(ctx context.Context, master Master) (*sql.DB, error) {
+	if master.Database == "" {
+		master.Database = "gpadmin"
+	}
+	dsn, err := q.dataSourceName(ctx, master)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if p, ok := q.pools[master]; ok {
+		if p.dsn == dsn {
+			return p.db, nil
+		}
+		_ = p.db.Close()
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// Greenplum has few connection slots, so don't hold on to idle ones
+	db.SetMaxOpenConns(2)
+	db.SetConnMaxIdleTime(time.Minute)
+	if q.pools == nil {
+		q.pools = make(map[Master]pool)
+	}
+	q.pools[master] = pool{dsn: dsn, db: db}
+	return db, nil
+}
+
+func (q *PQQuerier) dataSourceName(ctx context.Context, master Master) (string, error) {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: master.Namespace, Name: SecretName}
+	if err := q.Reader.Get(ctx, secretKey, &secret); err != nil {
+		if apierrs.IsNotFound(err) {
+			return "", fmt.Errorf("secret %s not found", secretKey)
+		}
+		return "", fmt.Errorf("failed to get secret %s: %w", secretKey, err)
+	}
+
+	address := q.Address
+	if address == nil {
+		address = PodAddress
+	}
+	sslMode := q.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(Role, string(secret.Data[pgpass.PasswordKey])),
+		Host:   address(master),
+		Path:   "/" + master.Database,
+		RawQuery: url.Values{
+			"sslmode":          {sslMode},
+			"connect_timeout":  {strconv.Itoa(int(connectTimeout.Seconds()))},
+			"application_name": {"greenplum-operator"},
+		}.Encode(),
+	}
+	return dsn.String(), nil
+}
+
+// PodAddress is the address of master through the agent headless Service of its cluster
+func PodAddress(master Master) string {
+	return net.JoinHostPort(fmt.Sprintf("%s.agent.%s.svc", master.PodName, master.Namespace), strconv.Itoa(Port))
+}
+
+// errRow is the Row of a query that could not be sent
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...interface{}) error {
+	return r.err
+}
@@ -0,0 +1,13 @@
+package querier_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuerier(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Querier Suite")
+}
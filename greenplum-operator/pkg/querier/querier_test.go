@@ -0,0 +1,319 @@
+package querier_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	fakequerier "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier/fake"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/scheme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ActiveMaster", func() {
+	var fakeQuerier *fakequerier.Querier
+	BeforeEach(func() {
+		fakeQuerier = &fakequerier.Querier{}
+	})
+	It("returns master-0 when master-0 is active master", func() {
+		Expect(querier.ActiveMaster(context.Background(), fakeQuerier, "test-ns")).To(Equal("master-0"))
+	})
+	It("returns master-1 when master-1 is active master", func() {
+		fakeQuerier.ErrorMsgOnMaster0 = "pq: the database system is in recovery mode"
+		Expect(querier.ActiveMaster(context.Background(), fakeQuerier, "test-ns")).To(Equal("master-1"))
+	})
+	It("returns '' when neither master-0 nor master-1 is active", func() {
+		fakeQuerier.ErrorMsgOnMaster0 = "dial tcp: lookup master-0.agent.test-ns.svc: no such host"
+		fakeQuerier.ErrorMsgOnMaster1 = "pq: the database system is in recovery mode"
+		Expect(querier.ActiveMaster(context.Background(), fakeQuerier, "test-ns")).To(Equal(""))
+	})
+	It("returns an AuthenticationError when the active master rejects the operator", func() {
+		fakeQuerier.ErrorMsgOnMaster0 = "pq: the database system is in recovery mode"
+		fakeQuerier.PingErr = &pq.Error{Code: "28P01", Message: `password authentication failed for user "gpoperator"`}
+		activeMaster, err := querier.ActiveMaster(context.Background(), fakeQuerier, "test-ns")
+		Expect(activeMaster).To(Equal(""))
+		Expect(err).To(MatchError(`active master master-1 rejects the operator: pq: password authentication failed for user "gpoperator"`))
+		var authErr *querier.AuthenticationError
+		Expect(errors.As(err, &authErr)).To(BeTrue())
+		Expect(authErr.PodName).To(Equal("master-1"))
+		Expect(querier.IsAuthenticationError(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("IsAuthenticationError", func() {
+	It("is true for a wrong password", func() {
+		Expect(querier.IsAuthenticationError(&pq.Error{Code: "28P01"})).To(BeTrue())
+	})
+	It("is true for a missing pg_hba.conf entry", func() {
+		Expect(querier.IsAuthenticationError(&pq.Error{Code: "28000"})).To(BeTrue())
+	})
+	It("is false for a master that cannot accept connections", func() {
+		Expect(querier.IsAuthenticationError(&pq.Error{Code: "57P03"})).To(BeFalse())
+		Expect(querier.IsAuthenticationError(errors.New("connection refused"))).To(BeFalse())
+	})
+})
+
+var _ = Describe("HBAEntry", func() {
+	It("allows only the operator's role, to the databases it connects to, from network", func() {
+		Expect(querier.HBAEntry("10.244.0.0/16", false)).To(Equal("host gpadmin,postgres gpoperator 10.244.0.0/16 md5"))
+	})
+	It("only allows connections over SSL with tls", func() {
+		Expect(querier.HBAEntry("10.244.0.0/16", true)).To(Equal("hostssl gpadmin,postgres gpoperator 10.244.0.0/16 md5"))
+	})
+})
+
+var _ = Describe("OperatorNetworks", func() {
+	operatorPod := func(namespace, name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": "greenplum-operator"}},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+	It("returns the address of each operator pod in the namespace", func() {
+		kubeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			operatorPod("greenplum-system", "greenplum-operator-b", "10.244.1.7"),
+			operatorPod("greenplum-system", "greenplum-operator-a", "fd00::7"),
+			operatorPod("greenplum-system", "greenplum-operator-pending", ""),
+			operatorPod("other-ns", "greenplum-operator-c", "10.244.2.8"),
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "greenplum-system", Name: "other-app"},
+				Status:     corev1.PodStatus{PodIP: "10.244.1.9"},
+			},
+		).Build()
+		networks, err := querier.OperatorNetworks(context.Background(), kubeClient, "greenplum-system")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networks).To(Equal([]string{"10.244.1.7/32", "fd00::7/128"}))
+	})
+})
+
+var _ = Describe("PodAddress", func() {
+	It("is the DNS name of the pod in the agent headless Service", func() {
+		Expect(querier.PodAddress(querier.Master{Namespace: "test-ns", PodName: "master-1"})).To(Equal("master-1.agent.test-ns.svc:5432"))
+	})
+})
+
+var _ = Describe("PQQuerier", func() {
+	var (
+		ctx     context.Context
+		subject *querier.PQQuerier
+		secret  *corev1.Secret
+		server  *fakeMasterServer
+		master  querier.Master
+
+		kubeClient client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "gpoperator-password"},
+			Data:       map[string][]byte{"password": []byte("p@ss/word")},
+		}
+		server = newFakeMasterServer()
+		master = querier.Master{Namespace: "test-ns", PodName: "master-0"}
+	})
+
+	JustBeforeEach(func() {
+		kubeClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+		subject = &querier.PQQuerier{
+			Reader:  kubeClient,
+			Address: func(querier.Master) string { return server.Address() },
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("connects as gpoperator with the password from the gpoperator-password Secret", func() {
+		err := subject.Ping(ctx, master)
+		Expect(querier.IsAuthenticationError(err)).To(BeTrue())
+		startup := <-server.Startups
+		Expect(startup.Parameters).To(HaveKeyWithValue("user", "gpoperator"))
+		Expect(startup.Parameters).To(HaveKeyWithValue("database", "gpadmin"))
+		Expect(startup.Parameters).To(HaveKeyWithValue("application_name", "greenplum-operator"))
+		Expect(startup.Password).To(Equal("p@ss/word"))
+	})
+
+	It("does not use SSL by default", func() {
+		Expect(subject.Ping(ctx, master)).NotTo(Succeed())
+		Expect(<-server.Startups).NotTo(BeZero())
+	})
+
+	It("requests SSL with an SSLMode", func() {
+		subject.SSLMode = "require"
+		Expect(subject.Ping(ctx, master)).To(MatchError(pq.ErrSSLNotSupported))
+	})
+
+	It("connects to the database of the master", func() {
+		master.Database = "postgres"
+		err := subject.QueryRow(ctx, master, "SELECT 1").Scan(new(int))
+		Expect(querier.IsAuthenticationError(err)).To(BeTrue())
+		Expect((<-server.Startups).Parameters).To(HaveKeyWithValue("database", "postgres"))
+	})
+
+	It("uses the new password after the Secret changes", func() {
+		Expect(subject.Ping(ctx, master)).NotTo(Succeed())
+		Expect((<-server.Startups).Password).To(Equal("p@ss/word"))
+
+		secret.Data["password"] = []byte("rotated")
+		Expect(kubeClient.Update(ctx, secret)).To(Succeed())
+		Expect(subject.Ping(ctx, master)).NotTo(Succeed())
+		Expect((<-server.Startups).Password).To(Equal("rotated"))
+	})
+
+	When("the gpoperator-password Secret does not exist", func() {
+		BeforeEach(func() {
+			secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "other-ns", Name: "gpoperator-password"}}
+		})
+		It("returns an error without connecting", func() {
+			Expect(subject.Ping(ctx, master)).To(MatchError("secret test-ns/gpoperator-password not found"))
+			_, err := subject.Query(ctx, master, "SELECT 1")
+			Expect(err).To(MatchError("secret test-ns/gpoperator-password not found"))
+			Expect(subject.QueryRow(ctx, master, "SELECT 1").Scan(new(int))).To(MatchError("secret test-ns/gpoperator-password not found"))
+			Expect(server.Startups).To(BeEmpty())
+		})
+	})
+
+	When("a local Postgres stands in for Greenplum", func() {
+		// For example: QUERIER_TEST_POSTGRES_ADDRESS=localhost:5432 QUERIER_TEST_POSTGRES_PASSWORD=secret,
+		// with a gpoperator role that has that password and a gpadmin database
+		BeforeEach(func() {
+			if os.Getenv("QUERIER_TEST_POSTGRES_ADDRESS") == "" {
+				Skip("QUERIER_TEST_POSTGRES_ADDRESS is not set")
+			}
+			secret.Data["password"] = []byte(os.Getenv("QUERIER_TEST_POSTGRES_PASSWORD"))
+		})
+		JustBeforeEach(func() {
+			subject.Address = func(querier.Master) string { return os.Getenv("QUERIER_TEST_POSTGRES_ADDRESS") }
+		})
+		It("returns typed rows", func() {
+			Expect(subject.Ping(ctx, master)).To(Succeed())
+			var (
+				count int32
+				name  string
+				ok    bool
+			)
+			row := subject.QueryRow(ctx, master, "SELECT count(*), current_user, $1::bool FROM pg_database WHERE datname = $2", true, "gpadmin")
+			Expect(row.Scan(&count, &name, &ok)).To(Succeed())
+			Expect(count).To(Equal(int32(1)))
+			Expect(name).To(Equal("gpoperator"))
+			Expect(ok).To(BeTrue())
+
+			rows, err := subject.Query(ctx, master, "SELECT generate_series(1, 3)")
+			Expect(err).NotTo(HaveOccurred())
+			defer rows.Close()
+			var values []int
+			for rows.Next() {
+				var value int
+				Expect(rows.Scan(&value)).To(Succeed())
+				values = append(values, value)
+			}
+			Expect(rows.Err()).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]int{1, 2, 3}))
+		})
+	})
+})
+
+type startup struct {
+	Parameters map[string]string
+	Password   string
+}
+
+// fakeMasterServer speaks enough of the Postgres protocol to record how a client connects,
+// then rejects its password
+type fakeMasterServer struct {
+	listener net.Listener
+	Startups chan startup
+}
+
+func newFakeMasterServer() *fakeMasterServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	s := &fakeMasterServer{listener: listener, Startups: make(chan startup, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeMasterServer) Address() string { return s.listener.Addr().String() }
+func (s *fakeMasterServer) Close()          { _ = s.listener.Close() }
+
+func (s *fakeMasterServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		if received, err := handle(conn); err == nil {
+			s.Startups <- received
+		}
+		_ = conn.Close()
+	}
+}
+
+func handle(conn net.Conn) (startup, error) {
+	r := bufio.NewReader(conn)
+	received := startup{Parameters: map[string]string{}}
+
+	// StartupMessage: length, protocol version, then name/value pairs
+	var header struct{ Length, Version int32 }
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return received, err
+	}
+	body := make([]byte, header.Length-8)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return received, err
+	}
+	fields := strings.Split(strings.TrimRight(string(body), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		received.Parameters[fields[i]] = fields[i+1]
+	}
+
+	// AuthenticationCleartextPassword, answered by a PasswordMessage
+	if _, err := conn.Write(message('R', []byte{0, 0, 0, 3})); err != nil {
+		return received, err
+	}
+	var password struct {
+		Type   byte
+		Length int32
+	}
+	if err := binary.Read(r, binary.BigEndian, &password); err != nil {
+		return received, err
+	}
+	if password.Type != 'p' {
+		return received, fmt.Errorf("expected a PasswordMessage, got %q", password.Type)
+	}
+	body = make([]byte, password.Length-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return received, err
+	}
+	received.Password = strings.TrimRight(string(body), "\x00")
+
+	var errorResponse bytes.Buffer
+	for _, field := range []string{"SFATAL", "C28P01", `Mpassword authentication failed for user "gpoperator"`} {
+		errorResponse.WriteString(field + "\x00")
+	}
+	errorResponse.WriteByte(0)
+	_, err := conn.Write(message('E', errorResponse.Bytes()))
+	return received, err
+}
+
+func message(msgType byte, body []byte) []byte {
+	msg := []byte{msgType, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[1:], uint32(len(body)+4))
+	return append(msg, body...)
+}
@@ -0,0 +1,39 @@
+package querier
+
+import (
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SecretName is the Secret with the password of Role
+const SecretName = "gpoperator-password"
+
+// ModifySecret fills in a generated password for Role if the Secret does not have one.
+// A password that was put in the Secret by the user is kept.
+func ModifySecret(greenplumCluster *greenplumv1.GreenplumCluster, secret *corev1.Secret) error {
+	if len(secret.Data[pgpass.PasswordKey]) == 0 {
+		password, err := gpadminsecret.GeneratePassword()
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[pgpass.PasswordKey] = []byte(password)
+	}
+
+	labels := map[string]string{
+		"app":               greenplumv1.AppName,
+		"greenplum-cluster": greenplumCluster.Name,
+	}
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	for key, value := range labels {
+		secret.Labels[key] = value
+	}
+	secret.Type = corev1.SecretTypeOpaque
+	return nil
+}
@@ -0,0 +1,43 @@
+package querier_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/querier"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ModifySecret", func() {
+	var (
+		cluster *greenplumv1.GreenplumCluster
+		secret  *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		cluster = &greenplumv1.GreenplumCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "my-greenplum"},
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: querier.SecretName},
+		}
+	})
+
+	It("generates a password and labels the secret", func() {
+		Expect(querier.ModifySecret(cluster, secret)).To(Succeed())
+		Expect(secret.Data["password"]).To(HaveLen(gpadminsecret.PasswordLength))
+		Expect(secret.Labels).To(Equal(map[string]string{
+			"app":               "greenplum",
+			"greenplum-cluster": "my-greenplum",
+		}))
+		Expect(secret.Type).To(Equal(corev1.SecretTypeOpaque))
+	})
+
+	It("keeps a password that the secret has", func() {
+		secret.Data = map[string][]byte{"password": []byte("chosen-by-user")}
+		Expect(querier.ModifySecret(cluster, secret)).To(Succeed())
+		Expect(string(secret.Data["password"])).To(Equal("chosen-by-user"))
+	})
+})
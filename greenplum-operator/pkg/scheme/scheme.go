@@ -3,6 +3,7 @@ package scheme
 import (
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	greenplumv1beta1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1beta1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/volumesnapshot"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -21,4 +22,5 @@ func init() {
 	_ = apiserver.AddToScheme(Scheme)
 	_ = greenplumv1.AddToScheme(Scheme)
 	_ = greenplumv1beta1.AddToScheme(Scheme)
+	_ = volumesnapshot.AddToScheme(Scheme)
 }
@@ -29,4 +29,6 @@ func ModifyGreenplumAgentService(clusterName string, agentService *corev1.Servic
 	agentService.Spec.Selector = labels
 	agentService.Spec.Type = corev1.ServiceTypeClusterIP
 	agentService.Spec.ClusterIP = corev1.ClusterIPNone
+	// Pods must resolve each other before gpinitsystem makes them ready
+	agentService.Spec.PublishNotReadyAddresses = true
 }
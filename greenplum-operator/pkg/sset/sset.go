@@ -1,19 +1,22 @@
 package sset
 
 import (
-	"fmt"
-
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
+	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/gpadminsecret"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/instanceconfig"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const headlessServiceName = "agent"
 
+const terminationGracePeriodSeconds = 180
+
 type StatefulSetType string
 
 const (
@@ -93,31 +96,70 @@ func ModifyGreenplumStatefulSet(params *GreenplumStatefulSetParams, sset *appsv1
 		},
 	}
 	templateSpec.Containers = modifyGreenplumContainer(params, templateSpec.Containers)
+	// Leave the preStop hook time to run pg_ctl stop, which gives up after 150 seconds
+	templateSpec.TerminationGracePeriodSeconds = heapvalue.NewInt64(terminationGracePeriodSeconds)
 	templateSpec.Volumes = getVolumeDefinition()
 	if params.GpPodSpec.AntiAffinity == "yes" {
-		templateSpec.Affinity = getAffinityDefinition(params.Type, sset.Namespace)
+		topologyKey := params.GpPodSpec.AntiAffinityTopologyKey
+		if topologyKey == "" {
+			topologyKey = corev1.LabelHostname
+		}
+		templateSpec.Affinity = getAffinityDefinition(params.Type, params.ClusterName, topologyKey)
+		templateSpec.TopologySpreadConstraints = getTopologySpreadConstraints(params.Type, params.ClusterName, topologyKey)
 	}
 	templateSpec.ServiceAccountName = "greenplum-system-pod"
 }
 
 func modifyGreenplumPVC(params *GreenplumStatefulSetParams, pvcs []corev1.PersistentVolumeClaim) []corev1.PersistentVolumeClaim {
-	var pvc *corev1.PersistentVolumeClaim
-	if len(pvcs) == 0 {
-		pvcs = make([]corev1.PersistentVolumeClaim, 1)
+	volumes := params.additionalVolumes()
+	if len(pvcs) < 1+len(volumes) {
+		pvcs = append(pvcs, make([]corev1.PersistentVolumeClaim, 1+len(volumes)-len(pvcs))...)
+	}
+	pvcs = pvcs[:1+len(volumes)]
+	modifyPVC(&pvcs[0], params.ClusterName+"-pgdata", params.GpPodSpec.StorageClassName, params.GpPodSpec.Storage)
+	for i, volume := range volumes {
+		pvc := &pvcs[i+1]
+		modifyPVC(pvc, params.ClusterName+"-"+volume.Name, volume.StorageClassName, volume.Storage)
+		if pvc.Labels == nil {
+			pvc.Labels = make(map[string]string)
+		}
+		pvc.Labels["greenplum-volume"] = volume.Name
 	}
-	pvc = &pvcs[0]
-	pvc.Name = params.ClusterName + "-pgdata"
-	pvc.Spec.StorageClassName = &params.GpPodSpec.StorageClassName
+	return pvcs
+}
+
+func modifyPVC(pvc *corev1.PersistentVolumeClaim, name, storageClassName string, storage resource.Quantity) {
+	pvc.Name = name
+	pvc.Spec.StorageClassName = &storageClassName
 	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
 	pvc.Spec.Resources = corev1.ResourceRequirements{
 		Limits: corev1.ResourceList{
-			corev1.ResourceStorage: params.GpPodSpec.Storage,
+			corev1.ResourceStorage: storage,
 		},
 		Requests: corev1.ResourceList{
-			corev1.ResourceStorage: params.GpPodSpec.Storage,
+			corev1.ResourceStorage: storage,
 		},
 	}
-	return pvcs
+}
+
+// additionalVolumes returns the additional volumes that belong on this statefulset.
+// Mirror volumes only go on segment-b, which is where the mirrors live.
+func (params *GreenplumStatefulSetParams) additionalVolumes() []greenplumv1.GreenplumAdditionalVolume {
+	var volumes []greenplumv1.GreenplumAdditionalVolume
+	for _, volume := range params.GpPodSpec.AdditionalVolumes {
+		if volume.Usage == greenplumv1.GreenplumVolumeUsageMirror && params.Type != TypeSegmentB {
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+func additionalVolumeMountPath(volume greenplumv1.GreenplumAdditionalVolume) string {
+	if volume.Usage == greenplumv1.GreenplumVolumeUsageMirror {
+		return "/greenplum/mirror"
+	}
+	return instanceconfig.AdditionalVolumesPath + volume.Name
 }
 
 func modifyGreenplumContainer(params *GreenplumStatefulSetParams, containers []corev1.Container) []corev1.Container {
@@ -135,17 +177,31 @@ func modifyGreenplumContainer(params *GreenplumStatefulSetParams, containers []c
 			ContainerPort: 22,
 			Protocol:      corev1.ProtocolTCP,
 		},
+		{
+			Name:          "status",
+			ContainerPort: gpstatus.Port,
+			Protocol:      corev1.ProtocolTCP,
+		},
 	}
 
-	if container.ReadinessProbe == nil {
-		container.ReadinessProbe = &corev1.Probe{}
-	}
-	container.ReadinessProbe.ProbeHandler = corev1.ProbeHandler{
-		TCPSocket: &corev1.TCPSocketAction{
-			Port: intstr.FromInt(22),
+	container.ReadinessProbe = greenplumProbe(container.ReadinessProbe, "readiness")
+	container.ReadinessProbe.InitialDelaySeconds = 5
+
+	// Crash recovery can take a long time after a restart; the liveness probe only
+	// starts once the startup probe succeeds.
+	container.StartupProbe = greenplumProbe(container.StartupProbe, "startup")
+	container.StartupProbe.PeriodSeconds = 10
+	container.StartupProbe.FailureThreshold = 360
+
+	container.LivenessProbe = greenplumProbe(container.LivenessProbe, "liveness")
+	container.LivenessProbe.PeriodSeconds = 30
+	container.LivenessProbe.FailureThreshold = 4
+
+	container.Lifecycle = &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: []string{"/home/gpadmin/tools/preStop"}},
 		},
 	}
-	container.ReadinessProbe.InitialDelaySeconds = 5
 
 	if container.Resources.Limits == nil {
 		container.Resources.Limits = make(map[corev1.ResourceName]resource.Quantity)
@@ -170,6 +226,10 @@ func modifyGreenplumContainer(params *GreenplumStatefulSetParams, containers []c
 			Name:      "ssh-key-volume",
 			MountPath: "/etc/ssh-key",
 		},
+		{
+			Name:      "gpadmin-password-volume",
+			MountPath: pgpass.SecretMountPath,
+		},
 		{
 			Name:      "config-volume",
 			MountPath: "/etc/config",
@@ -187,6 +247,12 @@ func modifyGreenplumContainer(params *GreenplumStatefulSetParams, containers []c
 			MountPath: "/etc/podinfo",
 		},
 	}
+	for _, volume := range params.additionalVolumes() {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      params.ClusterName + "-" + volume.Name,
+			MountPath: additionalVolumeMountPath(volume),
+		})
+	}
 
 	return containers
 }
@@ -202,6 +268,15 @@ func getVolumeDefinition() []corev1.Volume {
 				},
 			},
 		},
+		{
+			Name: "gpadmin-password-volume",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  gpadminsecret.SecretName,
+					DefaultMode: heapvalue.NewInt32(0444),
+				},
+			},
+		},
 		{
 			Name: "config-volume",
 			VolumeSource: corev1.VolumeSource{
@@ -249,57 +324,79 @@ func getVolumeDefinition() []corev1.Volume {
 	}
 }
 
-func getAffinityDefinition(typ StatefulSetType, namespace string) *corev1.Affinity {
-	var nodeSelectorKey string
-	var nodeSelectorValues []string
-	var podAntiAffinity *corev1.PodAntiAffinity
+// podIndexLabel is the label that the StatefulSet controller gives each of its pods, since Kubernetes 1.28.
+// Its value is the ordinal of the pod, which for a segment is its content id.
+const podIndexLabel = "apps.kubernetes.io/pod-index"
+
+// getAffinityDefinition keeps the master and standby in different failure domains of topologyKey.
+// Segments are kept apart by getTopologySpreadConstraints instead.
+func getAffinityDefinition(typ StatefulSetType, clusterName, topologyKey string) *corev1.Affinity {
 	switch typ {
 	case TypeMaster:
-		nodeSelectorKey = fmt.Sprintf("greenplum-affinity-%s-master", namespace)
-		nodeSelectorValues = []string{"true"}
-		podAntiAffinity = &corev1.PodAntiAffinity{
+	case TypeSegmentA, TypeSegmentB:
+		return nil
+	default:
+		panic("unexpected value for StatefulSetType: " + typ)
+	}
+
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
 			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
 				{
 					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"greenplum-cluster": clusterName},
 						MatchExpressions: []metav1.LabelSelectorRequirement{
 							{
 								Key:      "type",
 								Operator: metav1.LabelSelectorOpIn,
-								Values:   []string{"master"},
+								Values:   []string{string(TypeMaster)},
 							},
 						},
 					},
-					TopologyKey: "kubernetes.io/hostname",
+					TopologyKey: topologyKey,
 				},
 			},
-		}
-	case TypeSegmentA:
-		nodeSelectorKey = fmt.Sprintf("greenplum-affinity-%s-segment", namespace)
-		nodeSelectorValues = []string{"a"}
-	case TypeSegmentB:
-		nodeSelectorKey = fmt.Sprintf("greenplum-affinity-%s-segment", namespace)
-		nodeSelectorValues = []string{"b"}
-	default:
-		panic("unexpected value for StatefulSetType: " + typ)
+		},
 	}
+}
 
-	return &corev1.Affinity{
-		NodeAffinity: &corev1.NodeAffinity{
-			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-				NodeSelectorTerms: []corev1.NodeSelectorTerm{
-					{
-						MatchExpressions: []corev1.NodeSelectorRequirement{
-							{
-								Key:      nodeSelectorKey,
-								Operator: corev1.NodeSelectorOpIn,
-								Values:   nodeSelectorValues,
-							},
-						},
-					},
+// getTopologySpreadConstraints keeps each primary and its mirror in different failure domains of topologyKey,
+// and spreads segments over the domains, so that losing one domain takes out as few segments as possible.
+// The first constraint only counts the two pods of the same content id, through matchLabelKeys on
+// podIndexLabel: with a skew of at most 1 and at least two domains, they can never share a domain.
+// Unlike anti-affinity between the whole segment-a and segment-b sets, it does not let the pods of one
+// set take every domain and leave the other set Pending.
+func getTopologySpreadConstraints(typ StatefulSetType, clusterName, topologyKey string) []corev1.TopologySpreadConstraint {
+	if typ == TypeMaster {
+		return nil
+	}
+	segmentSelector := func() *metav1.LabelSelector {
+		return &metav1.LabelSelector{
+			MatchLabels: map[string]string{"greenplum-cluster": clusterName},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{
+					Key:      "type",
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{string(TypeSegmentA), string(TypeSegmentB)},
 				},
 			},
+		}
+	}
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			MinDomains:        heapvalue.NewInt32(2),
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     segmentSelector(),
+			MatchLabelKeys:    []string{podIndexLabel},
+		},
+		{
+			MaxSkew:           1,
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     segmentSelector(),
 		},
-		PodAntiAffinity: podAntiAffinity,
 	}
 }
 
@@ -310,3 +407,18 @@ func generateGPClusterLabels(typ, clusterName string) map[string]string {
 		"greenplum-cluster": clusterName,
 	}
 }
+
+// greenplumProbe runs a probe subcommand of startGreenplumContainer
+func greenplumProbe(probe *corev1.Probe, kind string) *corev1.Probe {
+	if probe == nil {
+		probe = &corev1.Probe{}
+	}
+	probe.ProbeHandler = corev1.ProbeHandler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/home/gpadmin/tools/startGreenplumContainer", "probe", kind},
+		},
+	}
+	// pg_isready gives up after 5 seconds
+	probe.TimeoutSeconds = 10
+	return probe
+}
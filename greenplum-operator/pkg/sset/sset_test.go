@@ -2,10 +2,12 @@ package sset_test
 
 import (
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gstruct"
 	greenplumv1 "github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/api/v1"
 	"github.com/pivotal/greenplum-for-kubernetes/greenplum-operator/pkg/sset"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/heapvalue"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -76,6 +78,7 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 		Expect(len(greenplumPodSpec.Containers)).ToNot(BeZero())
 		Expect(len(greenplumPodSpec.Volumes)).ToNot(BeZero())
 		Expect(greenplumPodSpec.DNSConfig).To(Equal(&corev1.PodDNSConfig{Searches: []string{"agent.test-namespace.svc.cluster.local"}}))
+		Expect(greenplumPodSpec.TerminationGracePeriodSeconds).To(Equal(heapvalue.NewInt64(180)))
 	})
 
 	It("does not set NodeSelector by default", func() {
@@ -98,44 +101,81 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 	When("antiAffinity is specified", func() {
 		BeforeEach(func() {
 			greenplumParams.GpPodSpec.AntiAffinity = "yes"
+			greenplumParams.GpPodSpec.AntiAffinityTopologyKey = "topology.kubernetes.io/zone"
 		})
-		It("gets an affinity object for a master pod", func() {
+		antiAffinityTerm := func(antiAffineType string) corev1.PodAffinityTerm {
+			return corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"greenplum-cluster": "my-greenplum"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "type", Operator: metav1.LabelSelectorOpIn, Values: []string{antiAffineType}},
+					},
+				},
+				TopologyKey: "topology.kubernetes.io/zone",
+			}
+		}
+		segmentSelector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{"greenplum-cluster": "my-greenplum"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "type", Operator: metav1.LabelSelectorOpIn, Values: []string{"segment-a", "segment-b"}},
+			},
+		}
+		contentSpread := corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			MinDomains:        heapvalue.NewInt32(2),
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     segmentSelector,
+			MatchLabelKeys:    []string{"apps.kubernetes.io/pod-index"},
+		}
+		segmentSpread := corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     segmentSelector,
+		}
+		It("keeps the master away from the standby", func() {
 			greenplumParams.Type = sset.TypeMaster
 
 			sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
 			Expect(subject.Spec.Template.Spec.Affinity).ToNot(BeNil())
-			nodeSelectorMatchExpr := subject.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
-
-			Expect(nodeSelectorMatchExpr.Key).To(Equal("greenplum-affinity-test-namespace-master"))
-			Expect(nodeSelectorMatchExpr.Operator).To(Equal(corev1.NodeSelectorOpIn))
-
-			Expect(nodeSelectorMatchExpr.Values).To(Equal([]string{"true"}))
-
-			podAntiAffinitySelectorMatchExpr := subject.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].LabelSelector.MatchExpressions[0]
-			Expect(subject.Spec.Template.Spec.Affinity.PodAntiAffinity).ToNot(BeNil())
-			Expect(podAntiAffinitySelectorMatchExpr.Key).To(Equal("type"))
-			Expect(podAntiAffinitySelectorMatchExpr.Operator).To(Equal(metav1.LabelSelectorOpIn))
-			Expect(podAntiAffinitySelectorMatchExpr.Values).To(Equal([]string{"master"}))
+			Expect(subject.Spec.Template.Spec.Affinity.NodeAffinity).To(BeNil())
+			Expect(subject.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(
+				ConsistOf(antiAffinityTerm("master")))
+			Expect(subject.Spec.Template.Spec.TopologySpreadConstraints).To(BeEmpty())
 		})
-		It("gets a node affinity object for a segment-a pod", func() {
+		DescribeTable("keeps each segment away from the other segment of its content id, and spreads segments",
+			func(typ sset.StatefulSetType) {
+				greenplumParams.Type = typ
+
+				sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
+				// Anti-affinity between the whole sets would let one set take every failure domain
+				Expect(subject.Spec.Template.Spec.Affinity).To(BeNil())
+				Expect(subject.Spec.Template.Spec.TopologySpreadConstraints).To(ConsistOf(contentSpread, segmentSpread))
+			},
+			Entry("segment-a", sset.TypeSegmentA),
+			Entry("segment-b", sset.TypeSegmentB),
+		)
+		It("removes the anti-affinity between segment sets of earlier versions", func() {
 			greenplumParams.Type = sset.TypeSegmentA
+			subject.Spec.Template.Spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{antiAffinityTerm("segment-b")},
+			}}
 
 			sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
-			Expect(subject.Spec.Template.Spec.Affinity).ToNot(BeNil())
-			nodeSelectorMatchExpr := subject.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
-			Expect(nodeSelectorMatchExpr.Key).To(Equal("greenplum-affinity-test-namespace-segment"))
-			Expect(nodeSelectorMatchExpr.Operator).To(Equal(corev1.NodeSelectorOpIn))
-			Expect(nodeSelectorMatchExpr.Values).To(Equal([]string{"a"}))
+			Expect(subject.Spec.Template.Spec.Affinity).To(BeNil())
 		})
-		It("gets a node affinity object for a segment-b pod", func() {
-			greenplumParams.Type = sset.TypeSegmentB
+		When("antiAffinityTopologyKey is empty", func() {
+			BeforeEach(func() {
+				greenplumParams.GpPodSpec.AntiAffinityTopologyKey = ""
+			})
+			It("keeps pods on different hosts", func() {
+				greenplumParams.Type = sset.TypeMaster
 
-			sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
-			Expect(subject.Spec.Template.Spec.Affinity).ToNot(BeNil())
-			nodeSelectorMatchExpr := subject.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
-			Expect(nodeSelectorMatchExpr.Key).To(Equal("greenplum-affinity-test-namespace-segment"))
-			Expect(nodeSelectorMatchExpr.Operator).To(Equal(corev1.NodeSelectorOpIn))
-			Expect(nodeSelectorMatchExpr.Values).To(Equal([]string{"b"}))
+				sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
+				term := subject.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0]
+				Expect(term.TopologyKey).To(Equal("kubernetes.io/hostname"))
+			})
 		})
 	})
 
@@ -145,20 +185,45 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 				ContainerPort: 22,
 				Protocol:      corev1.ProtocolTCP,
 			},
+			{
+				Name:          "status",
+				ContainerPort: gpstatus.Port,
+				Protocol:      corev1.ProtocolTCP,
+			},
 		}
-		expectedProbe := &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				TCPSocket: &corev1.TCPSocketAction{
-					Port: intstr.FromInt(22),
+		probeHandler := func(kind string) corev1.ProbeHandler {
+			return corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"/home/gpadmin/tools/startGreenplumContainer", "probe", kind},
 				},
-			},
+			}
+		}
+		expectedReadinessProbe := &corev1.Probe{
+			ProbeHandler:        probeHandler("readiness"),
 			InitialDelaySeconds: 5,
+			TimeoutSeconds:      10,
+		}
+		expectedStartupProbe := &corev1.Probe{
+			ProbeHandler:     probeHandler("startup"),
+			TimeoutSeconds:   10,
+			PeriodSeconds:    10,
+			FailureThreshold: 360,
+		}
+		expectedLivenessProbe := &corev1.Probe{
+			ProbeHandler:     probeHandler("liveness"),
+			TimeoutSeconds:   10,
+			PeriodSeconds:    30,
+			FailureThreshold: 4,
 		}
 		expectedVolumeMounts := []corev1.VolumeMount{
 			{
 				Name:      "ssh-key-volume",
 				MountPath: "/etc/ssh-key",
 			},
+			{
+				Name:      "gpadmin-password-volume",
+				MountPath: "/etc/gpadmin-password",
+			},
 			{
 				Name:      "config-volume",
 				MountPath: "/etc/config",
@@ -187,10 +252,16 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 		Expect(containerDef[0].Name).To(Equal("greenplum"))
 		Expect(containerDef[0].Image).To(Equal("my-repo:my-tag"))
 		Expect(containerDef[0].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
-		Expect(len(containerDef[0].Ports)).To(Equal(1))
+		Expect(len(containerDef[0].Ports)).To(Equal(2))
 		Expect(containerDef[0].Ports).To(Equal(expectedPort))
-		Expect(containerDef[0].ReadinessProbe).ToNot(BeNil())
-		Expect(containerDef[0].ReadinessProbe).To(Equal(expectedProbe))
+		Expect(containerDef[0].ReadinessProbe).To(Equal(expectedReadinessProbe))
+		Expect(containerDef[0].StartupProbe).To(Equal(expectedStartupProbe))
+		Expect(containerDef[0].LivenessProbe).To(Equal(expectedLivenessProbe))
+		Expect(containerDef[0].Lifecycle).To(Equal(&corev1.Lifecycle{
+			PreStop: &corev1.LifecycleHandler{
+				Exec: &corev1.ExecAction{Command: []string{"/home/gpadmin/tools/preStop"}},
+			},
+		}))
 		Expect(containerDef[0].VolumeMounts).To(Equal(expectedVolumeMounts))
 		Expect(containerDef[0].Env).To(Equal(expectedEnvVars))
 		Expect(len(containerDef[0].Args)).To(Equal(1))
@@ -209,7 +280,7 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 					},
 				},
 				InitialDelaySeconds: 500,
-				TimeoutSeconds:      10,
+				TimeoutSeconds:      100,
 				PeriodSeconds:       11,
 				SuccessThreshold:    12,
 				FailureThreshold:    13,
@@ -218,13 +289,13 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 		})
 		It("reconciles only the fields we care about", func() {
 			reconciledProbe := subject.Spec.Template.Spec.Containers[0].ReadinessProbe
-			Expect(reconciledProbe.ProbeHandler.Exec).To(BeNil(), "should be deleted")
-			Expect(reconciledProbe.ProbeHandler.HTTPGet).To(BeNil(), "should be deleted")
-			Expect(reconciledProbe.ProbeHandler.TCPSocket).To(gstruct.PointTo(Equal(corev1.TCPSocketAction{
-				Port: intstr.FromInt(22), // overwrite
+			Expect(reconciledProbe.ProbeHandler.Exec).To(gstruct.PointTo(Equal(corev1.ExecAction{
+				Command: []string{"/home/gpadmin/tools/startGreenplumContainer", "probe", "readiness"}, // overwrite
 			})))
+			Expect(reconciledProbe.ProbeHandler.HTTPGet).To(BeNil(), "should be deleted")
+			Expect(reconciledProbe.ProbeHandler.TCPSocket).To(BeNil(), "should be deleted")
 			Expect(reconciledProbe.InitialDelaySeconds).To(BeNumerically("==", 5), "overwrite")
-			Expect(reconciledProbe.TimeoutSeconds).To(BeNumerically("==", 10), "preserve")
+			Expect(reconciledProbe.TimeoutSeconds).To(BeNumerically("==", 10), "overwrite")
 			Expect(reconciledProbe.PeriodSeconds).To(BeNumerically("==", 11), "preserve")
 			Expect(reconciledProbe.SuccessThreshold).To(BeNumerically("==", 12), "preserve")
 			Expect(reconciledProbe.FailureThreshold).To(BeNumerically("==", 13), "preserve")
@@ -242,6 +313,15 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 					},
 				},
 			},
+			{
+				Name: "gpadmin-password-volume",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName:  "gpadmin-password",
+						DefaultMode: heapvalue.NewInt32(SecretVolumeSourceDefaultMode),
+					},
+				},
+			},
 			{
 				Name: "config-volume",
 				VolumeSource: corev1.VolumeSource{
@@ -289,7 +369,7 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 		}
 
 		volumeDef := subject.Spec.Template.Spec.Volumes
-		Expect(len(volumeDef)).To(Equal(4))
+		Expect(len(volumeDef)).To(Equal(5))
 		Expect(volumeDef).To(Equal(expectedVolumes))
 	})
 
@@ -320,6 +400,110 @@ var _ = Describe("GreenplumCluster StatefulSet spec", func() {
 		Expect(volumeClaimTemplate).To(Equal(expectedVolumeClaimTemplate))
 	})
 
+	It("only has the pgdata persistent volume claim by default", func() {
+		Expect(subject.Spec.VolumeClaimTemplates).To(HaveLen(1))
+	})
+
+	When("additional volumes are specified", func() {
+		BeforeEach(func() {
+			greenplumParams.GpPodSpec.AdditionalVolumes = []greenplumv1.GreenplumAdditionalVolume{
+				{
+					Name:             "fast",
+					StorageClassName: "ssd",
+					Storage:          resource.MustParse("2G"),
+					Usage:            greenplumv1.GreenplumVolumeUsageTablespace,
+				},
+				{
+					Name:             "scratch",
+					StorageClassName: "local",
+					Storage:          resource.MustParse("1G"),
+					Usage:            greenplumv1.GreenplumVolumeUsageTemp,
+				},
+				{
+					Name:             "mirrordata",
+					StorageClassName: "cheap",
+					Storage:          resource.MustParse("10G"),
+					Usage:            greenplumv1.GreenplumVolumeUsageMirror,
+				},
+			}
+		})
+
+		When("the statefulset is not segment-b", func() {
+			BeforeEach(func() {
+				greenplumParams.Type = sset.TypeSegmentA
+				sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
+			})
+			It("creates a persistent volume claim for each tablespace and temp volume", func() {
+				storageClass := "ssd"
+				storageSize := resource.MustParse("2G")
+				Expect(subject.Spec.VolumeClaimTemplates).To(HaveLen(3))
+				Expect(subject.Spec.VolumeClaimTemplates[0].Name).To(Equal("my-greenplum-pgdata"))
+				Expect(subject.Spec.VolumeClaimTemplates[1]).To(Equal(corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "my-greenplum-fast",
+						Labels: map[string]string{"greenplum-volume": "fast"},
+					},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						AccessModes: []corev1.PersistentVolumeAccessMode{
+							corev1.ReadWriteOnce,
+						},
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{
+								corev1.ResourceStorage: storageSize,
+							},
+							Requests: corev1.ResourceList{
+								corev1.ResourceStorage: storageSize,
+							},
+						},
+						StorageClassName: &storageClass,
+					},
+				}))
+				Expect(subject.Spec.VolumeClaimTemplates[2].Name).To(Equal("my-greenplum-scratch"))
+				Expect(*subject.Spec.VolumeClaimTemplates[2].Spec.StorageClassName).To(Equal("local"))
+			})
+			It("mounts the volumes under /greenplum/volumes", func() {
+				volumeMounts := subject.Spec.Template.Spec.Containers[0].VolumeMounts
+				Expect(volumeMounts).To(ContainElements(
+					corev1.VolumeMount{Name: "my-greenplum-fast", MountPath: "/greenplum/volumes/fast"},
+					corev1.VolumeMount{Name: "my-greenplum-scratch", MountPath: "/greenplum/volumes/scratch"},
+				))
+				Expect(volumeMounts).To(HaveLen(8))
+			})
+		})
+
+		When("the statefulset is segment-b", func() {
+			BeforeEach(func() {
+				greenplumParams.Type = sset.TypeSegmentB
+				sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
+			})
+			It("creates a persistent volume claim for the mirror volume", func() {
+				Expect(subject.Spec.VolumeClaimTemplates).To(HaveLen(4))
+				Expect(subject.Spec.VolumeClaimTemplates[3].Name).To(Equal("my-greenplum-mirrordata"))
+				Expect(subject.Spec.VolumeClaimTemplates[3].Labels).To(Equal(map[string]string{"greenplum-volume": "mirrordata"}))
+			})
+			It("mounts the mirror volume at /greenplum/mirror", func() {
+				Expect(subject.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(
+					corev1.VolumeMount{Name: "my-greenplum-mirrordata", MountPath: "/greenplum/mirror"},
+				))
+			})
+		})
+
+		When("a volume is removed", func() {
+			BeforeEach(func() {
+				greenplumParams.Type = sset.TypeSegmentA
+				sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
+				greenplumParams.GpPodSpec.AdditionalVolumes = greenplumParams.GpPodSpec.AdditionalVolumes[:1]
+				sset.ModifyGreenplumStatefulSet(greenplumParams, subject)
+			})
+			It("drops its persistent volume claim and mount", func() {
+				Expect(subject.Spec.VolumeClaimTemplates).To(HaveLen(2))
+				Expect(subject.Spec.Template.Spec.Containers[0].VolumeMounts).NotTo(ContainElement(
+					gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{"Name": Equal("my-greenplum-scratch")}),
+				))
+			})
+		})
+	})
+
 	Context("resource limits tests", func() {
 		When("resource limits are not provided", func() {
 			It("does not apply pod resource limits if none are provided", func() {
@@ -108,7 +108,7 @@ func (r *Client) gvrForObject(obj client.Object) schema.GroupVersionResource {
 	Expect(kinds).To(HaveLen(1))
 	gvk := kinds[0]
 
-	rm, err := r.restMapper.RESTMapping(gvk.GroupKind())
+	rm, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	Expect(err).NotTo(HaveOccurred())
 	gvr := rm.Resource
 
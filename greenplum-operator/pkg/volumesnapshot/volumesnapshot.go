@@ -0,0 +1,76 @@
+// Package volumesnapshot has the parts of the CSI snapshot.storage.k8s.io/v1 API that the operator uses.
+// The types are defined here rather than imported, so that the operator does not depend on the
+// external-snapshotter client; the CRDs themselves are installed with the CSI snapshot controller.
+// +kubebuilder:object:generate=true
+// +kubebuilder:skip
+package volumesnapshot
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group version of VolumeSnapshots
+	GroupVersion = schema.GroupVersion{Group: "snapshot.storage.k8s.io", Version: "v1"}
+
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds VolumeSnapshots to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+// +kubebuilder:object:root=true
+
+// VolumeSnapshot is a snapshot of a PVC, taken by a CSI driver
+type VolumeSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeSnapshotSpec    `json:"spec"`
+	Status *VolumeSnapshotStatus `json:"status,omitempty"`
+}
+
+type VolumeSnapshotSpec struct {
+	Source                  VolumeSnapshotSource `json:"source"`
+	VolumeSnapshotClassName *string              `json:"volumeSnapshotClassName,omitempty"`
+}
+
+// VolumeSnapshotSource has exactly one of its fields set
+type VolumeSnapshotSource struct {
+	PersistentVolumeClaimName *string `json:"persistentVolumeClaimName,omitempty"`
+	VolumeSnapshotContentName *string `json:"volumeSnapshotContentName,omitempty"`
+}
+
+type VolumeSnapshotStatus struct {
+	BoundVolumeSnapshotContentName *string `json:"boundVolumeSnapshotContentName,omitempty"`
+
+	// When the snapshot was cut by the storage system. The volume may be written again from then on.
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// Whether the snapshot can be used to provision a new volume
+	ReadyToUse *bool `json:"readyToUse,omitempty"`
+
+	RestoreSize *resource.Quantity   `json:"restoreSize,omitempty"`
+	Error       *VolumeSnapshotError `json:"error,omitempty"`
+}
+
+type VolumeSnapshotError struct {
+	Time    *metav1.Time `json:"time,omitempty"`
+	Message *string      `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VolumeSnapshotList contains a list of VolumeSnapshot
+type VolumeSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VolumeSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VolumeSnapshot{}, &VolumeSnapshotList{})
+}
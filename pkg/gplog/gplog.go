@@ -13,7 +13,7 @@ import (
 func ForProd(debug bool) logr.Logger {
 	return ctrlzap.New(func(o *ctrlzap.Options) {
 		o.Development = debug
-		o.Encoder = zapcore.NewJSONEncoder(newCustomEncoderConfig())
+		o.Encoder = zapcore.NewJSONEncoder(NewEncoderConfig())
 		o.ZapOpts = append(o.ZapOpts, zap.Hooks(zapHookFlushKlogOnFatal))
 	})
 }
@@ -21,7 +21,7 @@ func ForProd(debug bool) logr.Logger {
 func ForTest(logDest io.Writer) logr.Logger {
 	return ctrlzap.New(func(o *ctrlzap.Options) {
 		o.Development = true
-		o.Encoder = zapcore.NewJSONEncoder(newCustomEncoderConfig())
+		o.Encoder = zapcore.NewJSONEncoder(NewEncoderConfig())
 		o.DestWritter = logDest
 		o.ZapOpts = append(o.ZapOpts, zap.Hooks(zapHookFlushKlogOnFatal))
 	})
@@ -30,7 +30,7 @@ func ForTest(logDest io.Writer) logr.Logger {
 func ForIntegration() logr.Logger {
 	return ctrlzap.New(func(o *ctrlzap.Options) {
 		o.Development = true
-		o.Encoder = zapcore.NewConsoleEncoder(newCustomEncoderConfig())
+		o.Encoder = zapcore.NewConsoleEncoder(NewEncoderConfig())
 		o.ZapOpts = append(o.ZapOpts, zap.Hooks(zapHookFlushKlogOnFatal))
 	})
 }
@@ -45,7 +45,8 @@ func zapHookFlushKlogOnFatal(entry zapcore.Entry) error {
 	return nil
 }
 
-func newCustomEncoderConfig() zapcore.EncoderConfig {
+// NewEncoderConfig is the encoder config of our loggers: development output, with the production keys
+func NewEncoderConfig() zapcore.EncoderConfig {
 	prodCfg := zap.NewProductionEncoderConfig()
 	c := zap.NewDevelopmentEncoderConfig()
 	c.TimeKey = prodCfg.TimeKey
@@ -0,0 +1,77 @@
+package gpstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultTimeout bounds a request to the status server of a pod
+const DefaultTimeout = 5 * time.Second
+
+// Getter reads the status of a Greenplum pod
+type Getter interface {
+	Get(ctx context.Context, namespace, podName string) (*Status, error)
+}
+
+// Client reads the status of Greenplum pods through the agent headless Service of their cluster
+type Client struct {
+	// HTTPClient is http.DefaultClient if nil
+	HTTPClient *http.Client
+	// Address returns the host:port of the status server of a pod. The zero value uses PodAddress.
+	Address func(namespace, podName string) string
+}
+
+var _ Getter = &Client{}
+
+func (c *Client) Get(ctx context.Context, namespace, podName string) (*Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	address := c.Address
+	if address == nil {
+		address = PodAddress
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address(namespace, podName)+Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %w", podName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get status of %s: %s", podName, resp.Status)
+	}
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode status of %s: %w", podName, err)
+	}
+	return &status, nil
+}
+
+// PodAddress is the address of the status server of a pod through the agent headless Service
+func PodAddress(namespace, podName string) string {
+	return net.JoinHostPort(fmt.Sprintf("%s.agent.%s.svc", podName, namespace), strconv.Itoa(Port))
+}
+
+// ActiveMaster returns the name and status of the master pod that serves as active master,
+// or "" and nil if neither does
+func ActiveMaster(ctx context.Context, g Getter, namespace string) (string, *Status) {
+	for _, podName := range []string{"master-0", "master-1"} {
+		status, err := g.Get(ctx, namespace, podName)
+		if err == nil && status.IsServing() {
+			return podName, status
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,90 @@
+package gpstatus_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		statuses map[string]*gpstatus.Status
+		subject  *gpstatus.Client
+	)
+
+	BeforeEach(func() {
+		statuses = map[string]*gpstatus.Status{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// The fake Address puts the pod name in the path
+			podName := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), gpstatus.Path)
+			status, ok := statuses[podName]
+			if !ok {
+				http.Error(w, "no such pod", http.StatusBadGateway)
+				return
+			}
+			Expect(json.NewEncoder(w).Encode(status)).To(Succeed())
+		}))
+		subject = &gpstatus.Client{
+			Address: func(namespace, podName string) string {
+				return strings.TrimPrefix(server.URL, "http://") + "/" + podName
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("gets the status of a pod", func() {
+		statuses["segment-a-0"] = &gpstatus.Status{Hostname: "segment-a-0", Role: gpstatus.RolePrimary, Postmaster: gpstatus.PostmasterRunning}
+		status, err := subject.Get(context.Background(), "test-ns", "segment-a-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(statuses["segment-a-0"]))
+	})
+
+	It("returns an error when the status server fails", func() {
+		_, err := subject.Get(context.Background(), "test-ns", "segment-a-0")
+		Expect(err).To(MatchError("failed to get status of segment-a-0: 502 Bad Gateway"))
+	})
+
+	It("returns an error when the pod cannot be reached", func() {
+		server.Close()
+		_, err := subject.Get(context.Background(), "test-ns", "segment-a-0")
+		Expect(err).To(MatchError(HavePrefix("failed to get status of segment-a-0: ")))
+	})
+
+	Describe("ActiveMaster", func() {
+		It("returns master-0 when it serves as active master", func() {
+			statuses["master-0"] = &gpstatus.Status{Role: gpstatus.RoleActiveMaster, Postmaster: gpstatus.PostmasterRunning}
+			statuses["master-1"] = &gpstatus.Status{Role: gpstatus.RoleStandbyMaster, Postmaster: gpstatus.PostmasterRunning}
+			activeMaster, status := gpstatus.ActiveMaster(context.Background(), subject, "test-ns")
+			Expect(activeMaster).To(Equal("master-0"))
+			Expect(status).To(Equal(statuses["master-0"]))
+		})
+		It("returns master-1 after it was promoted", func() {
+			statuses["master-0"] = &gpstatus.Status{Role: gpstatus.RoleActiveMaster, Postmaster: gpstatus.PostmasterStopped}
+			statuses["master-1"] = &gpstatus.Status{Role: gpstatus.RoleActiveMaster, Postmaster: gpstatus.PostmasterRunning}
+			activeMaster, _ := gpstatus.ActiveMaster(context.Background(), subject, "test-ns")
+			Expect(activeMaster).To(Equal("master-1"))
+		})
+		It("returns '' when neither master serves", func() {
+			statuses["master-1"] = &gpstatus.Status{Role: gpstatus.RoleStandbyMaster, Postmaster: gpstatus.PostmasterRunning}
+			activeMaster, status := gpstatus.ActiveMaster(context.Background(), subject, "test-ns")
+			Expect(activeMaster).To(BeEmpty())
+			Expect(status).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("PodAddress", func() {
+	It("is the DNS name of the pod in the agent headless Service", func() {
+		Expect(gpstatus.PodAddress("test-ns", "segment-b-3")).To(Equal("segment-b-3.agent.test-ns.svc:8080"))
+	})
+})
@@ -0,0 +1,44 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+)
+
+// Getter returns canned statuses, keyed by pod name
+type Getter struct {
+	Statuses map[string]*gpstatus.Status
+	Errors   map[string]error
+}
+
+var _ gpstatus.Getter = &Getter{}
+
+// NewHealthyCluster returns a Getter of a cluster whose active master is master-0 and whose segments are up
+func NewHealthyCluster() *Getter {
+	return &Getter{Statuses: map[string]*gpstatus.Status{
+		"master-0": {
+			Hostname:   "master-0",
+			Role:       gpstatus.RoleActiveMaster,
+			Postmaster: gpstatus.PostmasterRunning,
+			Segments: []gpstatus.Segment{
+				{ContentID: -1, Role: "p", PreferredRole: "p", Mode: "n", Status: "u", Hostname: "master-0", Port: 5432},
+				{ContentID: 0, Role: "p", PreferredRole: "p", Mode: "n", Status: "u", Hostname: "segment-a-0", Port: 40000},
+			},
+		},
+	}}
+}
+
+func (f *Getter) Get(ctx context.Context, namespace, podName string) (*gpstatus.Status, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err, ok := f.Errors[podName]; ok {
+		return nil, err
+	}
+	if status, ok := f.Statuses[podName]; ok {
+		return status, nil
+	}
+	return nil, fmt.Errorf("failed to get status of %s: dial tcp: lookup %s", podName, gpstatus.PodAddress(namespace, podName))
+}
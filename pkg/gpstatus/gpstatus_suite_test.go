@@ -0,0 +1,13 @@
+package gpstatus_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGpstatus(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gpstatus Suite")
+}
@@ -0,0 +1,166 @@
+// Package gpstatus describes the status that every Greenplum pod serves over HTTP, and reads it.
+package gpstatus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Port is the port of the status server in the greenplum container
+	Port = 8080
+	// Path is the URL path of the status
+	Path = "/status"
+)
+
+// Role is what a pod is in its Greenplum cluster, according to its data directory
+type Role string
+
+const (
+	RoleActiveMaster  Role = "ActiveMaster"
+	RoleStandbyMaster Role = "StandbyMaster"
+	RolePrimary       Role = "Primary"
+	RoleMirror        Role = "Mirror"
+	// RoleUnknown is the role of a pod whose data directory is not initialized
+	RoleUnknown Role = "Unknown"
+)
+
+// PostmasterState is whether the postmaster of the pod runs
+type PostmasterState string
+
+const (
+	PostmasterRunning PostmasterState = "Running"
+	PostmasterStopped PostmasterState = "Stopped"
+	// PostmasterUnknown means that pg_ctl status failed for another reason than a stopped server
+	PostmasterUnknown PostmasterState = "Unknown"
+)
+
+// Status of a Greenplum pod
+type Status struct {
+	Hostname      string              `json:"hostname"`
+	Role          Role                `json:"role"`
+	Postmaster    PostmasterState     `json:"postmaster"`
+	DataDirectory DataDirectoryStatus `json:"dataDirectory"`
+	Logs          LogUsage            `json:"logs"`
+	// Segments is the segment configuration as seen from the active master. It is only set on the active master.
+	Segments []Segment `json:"segments,omitempty"`
+	// SegmentsError is why Segments could not be read on the active master
+	SegmentsError string `json:"segmentsError,omitempty"`
+	// MasterStartup is how the master on this pod was last started. It is only set on masters of a cluster with a standby.
+	MasterStartup *MasterStartup `json:"masterStartup,omitempty"`
+}
+
+// DataDirectoryStatus is the health of the data directory of a pod
+type DataDirectoryStatus struct {
+	Path string `json:"path"`
+	// Initialized is true once the data directory holds a database cluster
+	Initialized bool `json:"initialized"`
+	// Writable is true if a file can be written next to the data directory, so the volume is neither full nor read-only
+	Writable bool   `json:"writable"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LogUsage is the space taken by the server and utility logs of a pod
+type LogUsage struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+	// Error is why the logs could not be listed
+	Error string `json:"error,omitempty"`
+}
+
+// MasterStartupDecision is what a master of a cluster with a standby did when its pod started
+type MasterStartupDecision string
+
+const (
+	// StartedCluster means that the master was active last, and ran gpstart
+	StartedCluster MasterStartupDecision = "StartedCluster"
+	// StartedStandby means that the master was the standby, and started as the standby again
+	StartedStandby MasterStartupDecision = "StartedStandby"
+	// ReinitializedStandby means that the master that was failed over from was re-initialized as the standby
+	ReinitializedStandby MasterStartupDecision = "ReinitializedStandby"
+	// WaitingForActiveMaster means that the master was failed over from, and is left to the active master
+	// to re-initialize as the standby once it starts
+	WaitingForActiveMaster MasterStartupDecision = "WaitingForActiveMaster"
+	// Ambiguous means that it could not be determined which master was active last, so neither was started.
+	// The cluster has to be started manually.
+	Ambiguous MasterStartupDecision = "Ambiguous"
+	// StartupFailed means that starting the master failed
+	StartupFailed MasterStartupDecision = "StartupFailed"
+)
+
+// MasterStartup is the last MasterStartupDecision of a master
+type MasterStartup struct {
+	Decision MasterStartupDecision `json:"decision"`
+	Message  string                `json:"message"`
+	Time     time.Time             `json:"time"`
+}
+
+// SegmentConfigurationQuery selects the columns of Segment. ParseSegments reads its unaligned, tuples-only psql output.
+const SegmentConfigurationQuery = "SELECT content, role, preferred_role, mode, status, hostname, port " +
+	"FROM gp_segment_configuration ORDER BY content, role DESC"
+
+// Segment is a row of gp_segment_configuration
+type Segment struct {
+	ContentID     int    `json:"contentID"`
+	Role          string `json:"role"`
+	PreferredRole string `json:"preferredRole"`
+	Mode          string `json:"mode"`
+	Status        string `json:"status"`
+	Hostname      string `json:"hostname"`
+	Port          int    `json:"port"`
+}
+
+// IsUp is true if gp_segment_configuration marks the segment as up
+func (s Segment) IsUp() bool {
+	return s.Status == "u"
+}
+
+// IsServing is true if the pod is the active master and its postmaster runs
+func (s *Status) IsServing() bool {
+	return s.Role == RoleActiveMaster && s.Postmaster == PostmasterRunning
+}
+
+// DownSegments returns the segments that gp_segment_configuration marks as down
+func (s *Status) DownSegments() []Segment {
+	var down []Segment
+	for _, segment := range s.Segments {
+		if !segment.IsUp() {
+			down = append(down, segment)
+		}
+	}
+	return down
+}
+
+// ParseSegments reads the output of psql -t -A for SegmentConfigurationQuery
+func ParseSegments(output string) ([]Segment, error) {
+	var segments []Segment
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("unexpected segment configuration row: %q", line)
+		}
+		contentID, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected segment configuration row: %q", line)
+		}
+		port, err := strconv.Atoi(fields[6])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected segment configuration row: %q", line)
+		}
+		segments = append(segments, Segment{
+			ContentID:     contentID,
+			Role:          fields[1],
+			PreferredRole: fields[2],
+			Mode:          fields[3],
+			Status:        fields[4],
+			Hostname:      fields[5],
+			Port:          port,
+		})
+	}
+	return segments, nil
+}
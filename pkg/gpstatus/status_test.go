@@ -0,0 +1,28 @@
+package gpstatus_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gpstatus"
+)
+
+var _ = Describe("ParseSegments", func() {
+	It("reads each row of the segment configuration", func() {
+		segments, err := gpstatus.ParseSegments("-1|p|p|n|u|master-0|5432\n0|p|p|s|u|segment-a-0|40000\n0|m|m|s|d|segment-b-0|50000\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(segments).To(Equal([]gpstatus.Segment{
+			{ContentID: -1, Role: "p", PreferredRole: "p", Mode: "n", Status: "u", Hostname: "master-0", Port: 5432},
+			{ContentID: 0, Role: "p", PreferredRole: "p", Mode: "s", Status: "u", Hostname: "segment-a-0", Port: 40000},
+			{ContentID: 0, Role: "m", PreferredRole: "m", Mode: "s", Status: "d", Hostname: "segment-b-0", Port: 50000},
+		}))
+	})
+
+	It("returns no segments for empty output", func() {
+		Expect(gpstatus.ParseSegments("\n")).To(BeEmpty())
+	})
+
+	It("fails on a row it cannot read", func() {
+		_, err := gpstatus.ParseSegments("0|p|p|s|u|segment-a-0|port")
+		Expect(err).To(MatchError(`unexpected segment configuration row: "0|p|p|s|u|segment-a-0|port"`))
+	})
+})
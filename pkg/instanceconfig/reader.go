@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/blang/vfs"
 )
@@ -11,6 +13,15 @@ import (
 const ConfigMapPathPrefix = "/etc/config/"
 const PodInfoPathPrefix = "/etc/podinfo/"
 
+// AdditionalVolumesPath is where a pod's additional volumes are mounted, one directory per volume.
+// Directories for volumes a pod does not have are created on its data volume instead.
+const AdditionalVolumesPath = "/greenplum/volumes/"
+
+// TablespaceLocation is the tablespace directory on the named volume. It is the same on every host.
+func TablespaceLocation(volumeName string) string {
+	return AdditionalVolumesPath + volumeName + "/tablespace"
+}
+
 type ConfigValues struct {
 	Namespace            string
 	GreenplumClusterName string
@@ -20,6 +31,13 @@ type ConfigValues struct {
 	PXFServiceName       string
 }
 
+// LogRetention is the policy for the logs in pg_log and gpAdminLogs. A limit of zero is not enforced.
+type LogRetention struct {
+	Enabled bool
+	MaxAge  time.Duration
+	MaxSize int64
+}
+
 type Reader interface {
 	GetNamespace() (string, error)
 	GetGreenplumClusterName() (string, error)
@@ -27,6 +45,9 @@ type Reader interface {
 	GetMirrors() (bool, error)
 	GetStandby() (bool, error)
 	GetPXFServiceName() (string, error)
+	GetTablespaceVolumes() ([]string, error)
+	GetTempTablespace() (string, error)
+	GetLogRetention() (LogRetention, error)
 	GetConfigValues() (ConfigValues, error)
 }
 
@@ -64,6 +85,50 @@ func (cr *fsReader) GetPXFServiceName() (string, error) {
 	return cr.readOptionalString(ConfigMapPathPrefix, "pxfServiceName")
 }
 
+func (cr *fsReader) GetTablespaceVolumes() ([]string, error) {
+	value, err := cr.readOptionalString(ConfigMapPathPrefix, "tablespaceVolumes")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(value), nil
+}
+
+func (cr *fsReader) GetTempTablespace() (string, error) {
+	return cr.readOptionalString(ConfigMapPathPrefix, "tempTablespace")
+}
+
+// GetLogRetention reads the log retention policy. It is disabled when it is missing, as in
+// a ConfigMap of an older operator.
+func (cr *fsReader) GetLogRetention() (LogRetention, error) {
+	enabled, err := cr.readOptionalString(ConfigMapPathPrefix, "logRetention")
+	if err != nil || enabled == "" {
+		return LogRetention{}, err
+	}
+	var logRetention LogRetention
+	if logRetention.Enabled, err = strconv.ParseBool(enabled); err != nil {
+		return LogRetention{}, fmt.Errorf("error parsing logRetention, must be a boolean, got: %s", enabled)
+	}
+	maxAge, err := cr.readOptionalString(ConfigMapPathPrefix, "logRetentionMaxAge")
+	if err != nil {
+		return LogRetention{}, err
+	}
+	if maxAge != "" {
+		if logRetention.MaxAge, err = time.ParseDuration(maxAge); err != nil {
+			return LogRetention{}, fmt.Errorf("error parsing logRetentionMaxAge: %w", err)
+		}
+	}
+	maxSize, err := cr.readOptionalString(ConfigMapPathPrefix, "logRetentionMaxSize")
+	if err != nil {
+		return LogRetention{}, err
+	}
+	if maxSize != "" {
+		if logRetention.MaxSize, err = strconv.ParseInt(maxSize, 10, 64); err != nil {
+			return LogRetention{}, fmt.Errorf("error parsing logRetentionMaxSize: %w", err)
+		}
+	}
+	return logRetention, nil
+}
+
 func (cr *fsReader) GetConfigValues() (ConfigValues, error) {
 	configValues := ConfigValues{}
 	var err error
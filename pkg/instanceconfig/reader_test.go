@@ -1,6 +1,8 @@
 package instanceconfig_test
 
 import (
+	"time"
+
 	"github.com/blang/vfs"
 	"github.com/blang/vfs/memfs"
 	. "github.com/onsi/ginkgo"
@@ -171,6 +173,89 @@ var _ = Describe("instanceconfig.Reader", func() {
 		})
 	})
 
+	Describe("GetTablespaceVolumes", func() {
+		When("tablespaceVolumes is defined", func() {
+			It("reads one volume name per line", func() {
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/tablespaceVolumes", []byte("fast\nscratch"), 0777)).To(Succeed())
+				names, err := subject.GetTablespaceVolumes()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names).To(Equal([]string{"fast", "scratch"}))
+			})
+		})
+		When("tablespaceVolumes is empty", func() {
+			It("returns no names without error", func() {
+				names, err := subject.GetTablespaceVolumes()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(names).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("GetTempTablespace", func() {
+		When("tempTablespace is defined", func() {
+			It("reads a string successfully", func() {
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/tempTablespace", []byte("scratch"), 0777)).To(Succeed())
+				name, err := subject.GetTempTablespace()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("scratch"))
+			})
+		})
+		When("tempTablespace is empty", func() {
+			It("returns empty string without error", func() {
+				name, err := subject.GetTempTablespace()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal(""))
+			})
+		})
+	})
+
+	Describe("GetLogRetention", func() {
+		When("logRetention is not defined", func() {
+			It("is disabled", func() {
+				logRetention, err := subject.GetLogRetention()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(logRetention).To(Equal(instanceconfig.LogRetention{}))
+			})
+		})
+		When("logRetention is enabled with limits", func() {
+			BeforeEach(func() {
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetention", []byte("true"), 0777)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetentionMaxAge", []byte("168h0m0s"), 0777)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetentionMaxSize", []byte("524288000"), 0777)).To(Succeed())
+			})
+			It("reads the limits", func() {
+				logRetention, err := subject.GetLogRetention()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(logRetention).To(Equal(instanceconfig.LogRetention{Enabled: true, MaxAge: 168 * time.Hour, MaxSize: 524288000}))
+			})
+			It("fails when maxAge is not a duration", func() {
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetentionMaxAge", []byte("a week"), 0777)).To(Succeed())
+				_, err := subject.GetLogRetention()
+				Expect(err).To(MatchError(`error parsing logRetentionMaxAge: time: invalid duration "a week"`))
+			})
+			It("fails when maxSize is not a number", func() {
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetentionMaxSize", []byte("1Gi"), 0777)).To(Succeed())
+				_, err := subject.GetLogRetention()
+				Expect(err).To(MatchError(`error parsing logRetentionMaxSize: strconv.ParseInt: parsing "1Gi": invalid syntax`))
+			})
+		})
+		When("logRetention is enabled without limits", func() {
+			It("has no limits", func() {
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetention", []byte("true"), 0777)).To(Succeed())
+				Expect(vfs.WriteFile(memoryfs, "/etc/config/logRetentionMaxAge", []byte(""), 0777)).To(Succeed())
+				logRetention, err := subject.GetLogRetention()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(logRetention).To(Equal(instanceconfig.LogRetention{Enabled: true}))
+			})
+		})
+	})
+
+	Describe("TablespaceLocation", func() {
+		It("is a directory inside the volume mount", func() {
+			Expect(instanceconfig.TablespaceLocation("fast")).To(Equal("/greenplum/volumes/fast/tablespace"))
+		})
+	})
+
 	Describe("GetConfigValues", func() {
 		BeforeEach(func() {
 			Expect(vfs.WriteFile(memoryfs, "/etc/podinfo/namespace", []byte("testns"), 0777)).To(Succeed())
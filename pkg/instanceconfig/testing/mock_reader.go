@@ -23,6 +23,15 @@ type MockReader struct {
 	Standby    bool
 	StandbyErr error
 
+	TablespaceVolumes    []string
+	TablespaceVolumesErr error
+
+	TempTablespace    string
+	TempTablespaceErr error
+
+	LogRetention    instanceconfig.LogRetention
+	LogRetentionErr error
+
 	ConfigMapValuesErr error
 }
 
@@ -52,6 +61,18 @@ func (cr *MockReader) GetStandby() (bool, error) {
 	return cr.Standby, cr.StandbyErr
 }
 
+func (cr *MockReader) GetTablespaceVolumes() ([]string, error) {
+	return cr.TablespaceVolumes, cr.TablespaceVolumesErr
+}
+
+func (cr *MockReader) GetTempTablespace() (string, error) {
+	return cr.TempTablespace, cr.TempTablespaceErr
+}
+
+func (cr *MockReader) GetLogRetention() (instanceconfig.LogRetention, error) {
+	return cr.LogRetention, cr.LogRetentionErr
+}
+
 func (cr *MockReader) GetConfigValues() (instanceconfig.ConfigValues, error) {
 	return instanceconfig.ConfigValues{
 		Namespace:            cr.NamespaceName,
@@ -1,6 +1,7 @@
 package kubewait
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -14,9 +15,11 @@ import (
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/integrationutils/kubeexecpsql"
 	"github.com/pivotal/greenplum-for-kubernetes/pkg/net/dns"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
 	k8serrors "k8s.io/apimachinery/pkg/util/errors"
 	apiwait "k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var log = ctrl.Log.WithName("kubewait")
@@ -198,6 +201,24 @@ func ForGreenplumClusterStatus(status greenplumv1.GreenplumClusterPhase) error {
 	}), "waiting for GreenplumCluster status %s", status)
 }
 
+// ForGreenplumClusterPhase polls the GreenplumCluster through reader, rather than kubectl, until it reaches phase.
+// observe is called with each phase read, so that a caller can report progress; it may be nil.
+func ForGreenplumClusterPhase(ctx context.Context, reader client.Reader, key types.NamespacedName, phase greenplumv1.GreenplumClusterPhase,
+	timeout time.Duration, observe func(greenplumv1.GreenplumClusterPhase)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return errors.Wrapf(apiwait.PollImmediateUntilWithContext(ctx, 1*time.Second, func(ctx context.Context) (bool, error) {
+		var greenplumCluster greenplumv1.GreenplumCluster
+		if err := reader.Get(ctx, key, &greenplumCluster); err != nil {
+			return false, err
+		}
+		if observe != nil {
+			observe(greenplumCluster.Status.Phase)
+		}
+		return greenplumCluster.Status.Phase == phase, nil
+	}), "waiting for GreenplumCluster %s to be %s", key, phase)
+}
+
 func ForReplicasReady(kind string, objectName string) error {
 	desiredReplicas, err := getDesiredReplicas(kind, objectName)
 	if err != nil {
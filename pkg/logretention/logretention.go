@@ -0,0 +1,277 @@
+// Package logretention compresses the Greenplum server and utility logs that are no longer written to,
+// and removes the oldest of them to keep the logs within an age and a size
+package logretention
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// quietPeriod is how long a log must not have been written to before it is compressed or removed.
+// The log shipper has shipped its last entries by then.
+const quietPeriod = time.Minute
+
+// dailyLogDate is the layout of the date in the names of daily logs
+const dailyLogDate = "20060102"
+
+// Dir is a directory of logs
+type Dir struct {
+	Path string
+	// Pattern matches the names of the logs, such as *.csv. Their compressed copies end with .gz.
+	Pattern string
+	// DailyLogs is set when each program writes a log per day, named with the date such as
+	// gpstart_20200601.log. Several programs write their logs of the day at once, so every log
+	// dated today is written to, rather than only the newest.
+	DailyLogs bool
+}
+
+// Policy limits the logs of a pod. A limit of zero is not enforced.
+type Policy struct {
+	MaxAge  time.Duration
+	MaxSize int64
+}
+
+// Usage is the space taken by the logs of a pod
+type Usage struct {
+	Files int
+	Bytes int64
+}
+
+// Result lists the logs that Enforce compressed and removed
+type Result struct {
+	Compressed []string
+	Removed    []string
+}
+
+// Enforcer applies a Policy to the logs in Dirs. The newest log of each directory is the one that is
+// written to, so it is neither compressed nor removed. Neither are the daily logs of today, nor the
+// logs written within the last minute.
+type Enforcer struct {
+	Fs   vfs.Filesystem
+	Dirs []Dir
+	// Now is time.Now if nil
+	Now func() time.Time
+	// UnderPressure reports whether the data volume is nearly full. While it is, the oldest logs are
+	// removed even though they are within the Policy. It is never under pressure if nil.
+	UnderPressure func() bool
+}
+
+type logFile struct {
+	path     string
+	size     int64
+	modTime  time.Time
+	gzipped  bool
+	inactive bool
+}
+
+// Usage returns the space taken by the logs
+func (e *Enforcer) Usage() (Usage, error) {
+	var usage Usage
+	for _, dir := range e.Dirs {
+		files, err := e.list(dir)
+		if err != nil {
+			return Usage{}, err
+		}
+		for _, file := range files {
+			usage.Files++
+			usage.Bytes += file.size
+		}
+	}
+	return usage, nil
+}
+
+// Enforce compresses the logs that are no longer written to, then removes the logs that are older
+// than policy.MaxAge, and the oldest logs while they take more than policy.MaxSize, or while the
+// volume is under pressure
+func (e *Enforcer) Enforce(policy Policy) (Result, error) {
+	var result Result
+	var all []*logFile
+	for _, dir := range e.Dirs {
+		files, err := e.list(dir)
+		if err != nil {
+			return result, err
+		}
+		for _, file := range files {
+			if file.inactive && !file.gzipped {
+				original := file.path
+				if err := e.compress(file); err != nil {
+					return result, err
+				}
+				result.Compressed = append(result.Compressed, original)
+			}
+		}
+		all = append(all, files...)
+	}
+
+	// Oldest first
+	sort.SliceStable(all, func(i, j int) bool { return all[i].modTime.Before(all[j].modTime) })
+	var total int64
+	for _, file := range all {
+		total += file.size
+	}
+	for _, file := range all {
+		if !file.inactive {
+			continue
+		}
+		expired := policy.MaxAge > 0 && e.now().Sub(file.modTime) > policy.MaxAge
+		oversize := policy.MaxSize > 0 && total > policy.MaxSize
+		if !expired && !oversize && !e.underPressure() {
+			continue
+		}
+		if err := e.Fs.Remove(file.path); err != nil {
+			return result, fmt.Errorf("removing %s: %w", file.path, err)
+		}
+		result.Removed = append(result.Removed, file.path)
+		total -= file.size
+	}
+	return result, nil
+}
+
+// list returns the logs of dir. A directory that does not exist yet has no logs.
+func (e *Enforcer) list(dir Dir) ([]*logFile, error) {
+	infos, err := e.Fs.ReadDir(dir.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir.Path, err)
+	}
+	var files []*logFile
+	var newest *logFile
+	today := e.now().Format(dailyLogDate)
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		name := info.Name()
+		gzipped := strings.HasSuffix(name, ".gz")
+		if matched, _ := filepath.Match(dir.Pattern, strings.TrimSuffix(name, ".gz")); !matched {
+			continue
+		}
+		file := &logFile{
+			path:     filepath.Join(dir.Path, name),
+			size:     info.Size(),
+			modTime:  info.ModTime(),
+			gzipped:  gzipped,
+			inactive: true,
+		}
+		if gzipped {
+			// A compressed log keeps the time its original was last written in its header
+			if modTime, err := e.gzipModTime(file.path); err == nil && !modTime.IsZero() {
+				file.modTime = modTime
+			}
+		} else {
+			if e.now().Sub(file.modTime) < quietPeriod || (dir.DailyLogs && strings.Contains(name, today)) {
+				file.inactive = false
+			}
+			if newest == nil || file.modTime.After(newest.modTime) {
+				newest = file
+			}
+		}
+		files = append(files, file)
+	}
+	if newest != nil && !dir.DailyLogs {
+		newest.inactive = false
+	}
+	return files, nil
+}
+
+func (e *Enforcer) gzipModTime(path string) (time.Time, error) {
+	f, err := e.Fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return reader.ModTime, nil
+}
+
+// compress replaces file with a gzipped copy. The copy is written under a temporary name first, so
+// that an interrupted run does not leave a truncated log. A compressed log of the same name, such as
+// the one of a utility log that was written to again after it was compressed, is kept.
+func (e *Enforcer) compress(file *logFile) error {
+	src, err := e.Fs.OpenFile(file.path, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", file.path, err)
+	}
+	defer src.Close()
+
+	gzPath, err := e.unusedGzPath(file.path)
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", file.path, err)
+	}
+	tmpPath := gzPath + ".tmp"
+	dst, err := e.Fs.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", file.path, err)
+	}
+	writer := gzip.NewWriter(dst)
+	writer.Name = filepath.Base(file.path)
+	writer.ModTime = file.modTime
+	_, err = io.Copy(writer, src)
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = e.Fs.Rename(tmpPath, gzPath)
+	}
+	if err != nil {
+		_ = e.Fs.Remove(tmpPath)
+		return fmt.Errorf("compressing %s: %w", file.path, err)
+	}
+	if err := e.Fs.Remove(file.path); err != nil {
+		return fmt.Errorf("compressing %s: %w", file.path, err)
+	}
+
+	info, err := e.Fs.Stat(gzPath)
+	if err != nil {
+		return fmt.Errorf("compressing %s: %w", file.path, err)
+	}
+	file.path = gzPath
+	file.size = info.Size()
+	file.gzipped = true
+	return nil
+}
+
+// unusedGzPath returns path.gz, or path with a number before its extension, such as
+// gpstart_20200601.1.log.gz, if a compressed log already has that name
+func (e *Enforcer) unusedGzPath(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	gzPath := path + ".gz"
+	for n := 1; ; n++ {
+		_, err := e.Fs.Stat(gzPath)
+		if os.IsNotExist(err) {
+			return gzPath, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		gzPath = fmt.Sprintf("%s.%d%s.gz", base, n, ext)
+	}
+}
+
+func (e *Enforcer) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+func (e *Enforcer) underPressure() bool {
+	return e.UnderPressure != nil && e.UnderPressure()
+}
@@ -0,0 +1,13 @@
+package logretention_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogretention(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logretention Suite")
+}
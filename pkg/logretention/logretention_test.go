@@ -0,0 +1,229 @@
+package logretention_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logretention"
+)
+
+var _ = Describe("Enforcer", func() {
+	var (
+		fs       vfs.Filesystem
+		now      time.Time
+		pressure bool
+		enforcer *logretention.Enforcer
+	)
+
+	writeLog := func(path, content string) {
+		Expect(vfs.WriteFile(fs, path, []byte(content), 0600)).To(Succeed())
+		// Keep the modification times of the logs apart
+		time.Sleep(time.Millisecond)
+	}
+
+	writeCompressedLog := func(path, content string, modTime time.Time) {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		writer.ModTime = modTime
+		_, err := writer.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		Expect(vfs.WriteFile(fs, path, buf.Bytes(), 0600)).To(Succeed())
+	}
+
+	exists := func(path string) bool {
+		_, err := fs.Stat(path)
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		fs = memfs.Create()
+		Expect(vfs.MkdirAll(fs, "/greenplum/data/pg_log", 0700)).To(Succeed())
+		Expect(vfs.MkdirAll(fs, "/home/gpadmin/gpAdminLogs", 0700)).To(Succeed())
+		now = time.Now().Add(time.Hour)
+		pressure = false
+		enforcer = &logretention.Enforcer{
+			Fs: fs,
+			Dirs: []logretention.Dir{
+				{Path: "/greenplum/data/pg_log", Pattern: "*.csv"},
+				{Path: "/home/gpadmin/gpAdminLogs", Pattern: "*.log", DailyLogs: true},
+			},
+			Now:           func() time.Time { return now },
+			UnderPressure: func() bool { return pressure },
+		}
+	})
+
+	Describe("compression", func() {
+		It("compresses the logs that are no longer written to", func() {
+			writeLog("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv", "old entries\n")
+			info, err := fs.Stat("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv")
+			Expect(err).NotTo(HaveOccurred())
+			writeLog("/greenplum/data/pg_log/gpdb-2020-06-02_000000.csv", "current entries\n")
+
+			result, err := enforcer.Enforce(logretention.Policy{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Compressed).To(Equal([]string{"/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv"}))
+			Expect(result.Removed).To(BeEmpty())
+
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv")).To(BeFalse())
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-02_000000.csv")).To(BeTrue())
+			f, err := fs.OpenFile("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz", os.O_RDONLY, 0)
+			Expect(err).NotTo(HaveOccurred())
+			defer f.Close()
+			reader, err := gzip.NewReader(f)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reader.ModTime).To(BeTemporally("~", info.ModTime(), time.Second))
+			content, err := io.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("old entries\n"))
+		})
+
+		It("does not compress a log that was written to recently", func() {
+			writeLog("/home/gpadmin/gpAdminLogs/gpstart_20200601.log", "gpstart\n")
+			writeLog("/home/gpadmin/gpAdminLogs/gpstate_20200601.log", "gpstate\n")
+			now = time.Now()
+
+			result, err := enforcer.Enforce(logretention.Policy{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Compressed).To(BeEmpty())
+		})
+
+		It("does not compress the daily logs of today", func() {
+			today := now.Format("20060102")
+			writeLog("/home/gpadmin/gpAdminLogs/gpstart_20200601.log", "gpstart\n")
+			writeLog("/home/gpadmin/gpAdminLogs/gpstate_"+today+".log", "gpstate\n")
+			writeLog("/home/gpadmin/gpAdminLogs/gpstart_"+today+".log", "gpstart\n")
+
+			result, err := enforcer.Enforce(logretention.Policy{MaxAge: time.Nanosecond})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Compressed).To(Equal([]string{"/home/gpadmin/gpAdminLogs/gpstart_20200601.log"}))
+			Expect(result.Removed).To(Equal([]string{"/home/gpadmin/gpAdminLogs/gpstart_20200601.log.gz"}))
+			Expect(exists("/home/gpadmin/gpAdminLogs/gpstate_" + today + ".log")).To(BeTrue())
+			Expect(exists("/home/gpadmin/gpAdminLogs/gpstart_" + today + ".log")).To(BeTrue())
+		})
+
+		It("keeps a compressed log of the same name", func() {
+			writeCompressedLog("/home/gpadmin/gpAdminLogs/gpstart_20200601.log.gz", "compressed first", now.Add(-time.Hour))
+			writeCompressedLog("/home/gpadmin/gpAdminLogs/gpstart_20200601.1.log.gz", "compressed second", now.Add(-time.Hour))
+			writeLog("/home/gpadmin/gpAdminLogs/gpstart_20200601.log", "written again\n")
+
+			result, err := enforcer.Enforce(logretention.Policy{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Compressed).To(Equal([]string{"/home/gpadmin/gpAdminLogs/gpstart_20200601.log"}))
+			Expect(exists("/home/gpadmin/gpAdminLogs/gpstart_20200601.log")).To(BeFalse())
+
+			read := func(path string) string {
+				f, err := fs.OpenFile(path, os.O_RDONLY, 0)
+				Expect(err).NotTo(HaveOccurred())
+				defer f.Close()
+				reader, err := gzip.NewReader(f)
+				Expect(err).NotTo(HaveOccurred())
+				content, err := io.ReadAll(reader)
+				Expect(err).NotTo(HaveOccurred())
+				return string(content)
+			}
+			Expect(read("/home/gpadmin/gpAdminLogs/gpstart_20200601.log.gz")).To(Equal("compressed first"))
+			Expect(read("/home/gpadmin/gpAdminLogs/gpstart_20200601.1.log.gz")).To(Equal("compressed second"))
+			Expect(read("/home/gpadmin/gpAdminLogs/gpstart_20200601.2.log.gz")).To(Equal("written again\n"))
+
+			usage, err := enforcer.Usage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(usage.Files).To(Equal(3))
+		})
+
+		It("ignores files that are not logs", func() {
+			writeLog("/greenplum/data/pg_log/notes.txt", "notes\n")
+			writeLog("/greenplum/data/pg_log/gpdb-2020-06-02_000000.csv", "current entries\n")
+
+			result, err := enforcer.Enforce(logretention.Policy{MaxSize: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(logretention.Result{}))
+			Expect(exists("/greenplum/data/pg_log/notes.txt")).To(BeTrue())
+		})
+	})
+
+	Describe("removal", func() {
+		BeforeEach(func() {
+			writeCompressedLog("/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz", "ten days old", now.Add(-240*time.Hour))
+			writeCompressedLog("/home/gpadmin/gpAdminLogs/gpstart_20200605.log.gz", "six days old", now.Add(-144*time.Hour))
+			writeCompressedLog("/greenplum/data/pg_log/gpdb-2020-06-09_000000.csv.gz", "two days old", now.Add(-48*time.Hour))
+			writeLog("/greenplum/data/pg_log/gpdb-2020-06-11_000000.csv", "current entries\n")
+		})
+
+		It("keeps every log without limits", func() {
+			result, err := enforcer.Enforce(logretention.Policy{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Removed).To(BeEmpty())
+		})
+
+		It("removes the logs older than maxAge", func() {
+			result, err := enforcer.Enforce(logretention.Policy{MaxAge: 7 * 24 * time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Removed).To(Equal([]string{"/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz"}))
+			Expect(exists("/home/gpadmin/gpAdminLogs/gpstart_20200605.log.gz")).To(BeTrue())
+		})
+
+		It("removes the oldest logs of all directories while the logs take more than maxSize", func() {
+			usage, err := enforcer.Usage()
+			Expect(err).NotTo(HaveOccurred())
+			info, err := fs.Stat("/greenplum/data/pg_log/gpdb-2020-06-09_000000.csv.gz")
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := enforcer.Enforce(logretention.Policy{MaxSize: usage.Bytes - 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Removed).To(Equal([]string{"/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz"}))
+
+			result, err = enforcer.Enforce(logretention.Policy{MaxSize: info.Size() + 16})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Removed).To(Equal([]string{"/home/gpadmin/gpAdminLogs/gpstart_20200605.log.gz"}))
+		})
+
+		It("does not remove the log that is written to", func() {
+			result, err := enforcer.Enforce(logretention.Policy{MaxAge: time.Nanosecond, MaxSize: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Removed).To(HaveLen(3))
+			Expect(exists("/greenplum/data/pg_log/gpdb-2020-06-11_000000.csv")).To(BeTrue())
+		})
+
+		It("removes the oldest logs while the volume is under pressure", func() {
+			removed := 0
+			enforcer.UnderPressure = func() bool {
+				removed++
+				return removed <= 2
+			}
+			result, err := enforcer.Enforce(logretention.Policy{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Removed).To(Equal([]string{
+				"/greenplum/data/pg_log/gpdb-2020-06-01_000000.csv.gz",
+				"/home/gpadmin/gpAdminLogs/gpstart_20200605.log.gz",
+			}))
+		})
+	})
+
+	Describe("Usage", func() {
+		It("counts the logs of all directories", func() {
+			writeLog("/greenplum/data/pg_log/gpdb-2020-06-11_000000.csv", "0123456789")
+			writeCompressedLog("/home/gpadmin/gpAdminLogs/gpstart_20200605.log.gz", "compressed", now)
+			info, err := fs.Stat("/home/gpadmin/gpAdminLogs/gpstart_20200605.log.gz")
+			Expect(err).NotTo(HaveOccurred())
+
+			usage, err := enforcer.Usage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(usage).To(Equal(logretention.Usage{Files: 2, Bytes: 10 + info.Size()}))
+		})
+
+		It("has no logs in directories that do not exist", func() {
+			enforcer.Dirs = []logretention.Dir{{Path: "/greenplum/data-1/pg_log", Pattern: "*.csv"}}
+			usage, err := enforcer.Usage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(usage).To(Equal(logretention.Usage{}))
+		})
+	})
+})
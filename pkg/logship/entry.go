@@ -0,0 +1,172 @@
+package logship
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is one entry of a server or utility log, ready to be encoded as a JSON line
+type Entry struct {
+	Time    time.Time
+	Level   zapcore.Level
+	Message string
+	Fields  []zap.Field
+}
+
+// The columns of the Greenplum CSV log format, as in gp_toolkit.gp_log_system
+const (
+	csvTime = iota
+	csvUser
+	csvDatabase
+	csvPID
+	csvThread
+	csvRemoteHost
+	csvRemotePort
+	csvSessionStart
+	csvTransaction
+	csvSession
+	csvCommandCount
+	csvSegment
+	csvSlice
+	csvDistributedTransaction
+	csvLocalTransaction
+	csvSubTransaction
+	csvSeverity
+	csvSQLState
+	csvMessage
+	csvDetail
+	csvHint
+	csvInternalQuery
+	csvInternalQueryPos
+	csvContext
+	csvQuery
+	csvCursorPos
+	csvFunction
+	csvFile
+	csvLine
+	csvStack
+	csvColumns
+)
+
+const csvTimeLayout = "2006-01-02 15:04:05.999999 MST"
+
+// csvFields are the columns that are added to each entry, when they are not empty
+var csvFields = []struct {
+	column int
+	key    string
+}{
+	{csvSeverity, "severity"},
+	{csvSQLState, "sqlState"},
+	{csvUser, "user"},
+	{csvDatabase, "database"},
+	{csvPID, "pid"},
+	{csvRemoteHost, "remoteHost"},
+	{csvSession, "session"},
+	{csvCommandCount, "commandCount"},
+	{csvSegment, "segment"},
+	{csvSlice, "slice"},
+	{csvDistributedTransaction, "distributedTransaction"},
+	{csvDetail, "detail"},
+	{csvHint, "hint"},
+	{csvContext, "context"},
+	{csvQuery, "query"},
+}
+
+// ParseCSVRecord returns the entry of one record of a pg_log CSV file
+func ParseCSVRecord(record []string, file string) Entry {
+	if len(record) < csvColumns {
+		// Not a server log record. Keep it rather than drop it.
+		return Entry{
+			Time:    time.Now(),
+			Level:   zapcore.InfoLevel,
+			Message: strings.Join(record, ","),
+			Fields:  []zap.Field{zap.String("file", file)},
+		}
+	}
+
+	entry := Entry{
+		Level:   severityLevel(record[csvSeverity]),
+		Message: record[csvMessage],
+	}
+	var err error
+	if entry.Time, err = time.Parse(csvTimeLayout, record[csvTime]); err != nil {
+		entry.Time = time.Now()
+	}
+	for _, field := range csvFields {
+		if value := record[field.column]; value != "" {
+			entry.Fields = append(entry.Fields, zap.String(field.key, value))
+		}
+	}
+	entry.Fields = append(entry.Fields, zap.String("file", file))
+	return entry
+}
+
+// severityLevel maps a server log severity to a level. FATAL and PANIC are errors of a session or
+// of the server, not of the log shipper, so they are logged as errors.
+func severityLevel(severity string) zapcore.Level {
+	switch {
+	case strings.HasPrefix(severity, "DEBUG"):
+		return zapcore.DebugLevel
+	case severity == "WARNING":
+		return zapcore.WarnLevel
+	case severity == "ERROR", severity == "FATAL", severity == "PANIC":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// gpAdminLogLine is the format of the lines that the management utilities write to gpAdminLogs:
+// <yyyymmdd:hh:mm:ss>:<pid> <program>:<host>:<user>-[<level>]:-<message>
+var gpAdminLogLine = regexp.MustCompile(`^(\d{8}:\d{2}:\d{2}:\d{2}):(\d+) ([^:]+):([^:]+):(.+?)-\[(\w+)\]:-(.*)$`)
+
+const gpAdminLogTimeLayout = "20060102:15:04:05"
+
+// ParseGpAdminLogLine returns the entry of one line of a gpAdminLogs file
+func ParseGpAdminLogLine(line, file string) Entry {
+	match := gpAdminLogLine.FindStringSubmatch(line)
+	if match == nil {
+		// Output of a command that a utility ran, such as a stack trace
+		return Entry{
+			Time:    time.Now(),
+			Level:   zapcore.InfoLevel,
+			Message: line,
+			Fields:  []zap.Field{zap.String("file", file)},
+		}
+	}
+
+	entry := Entry{
+		Level:   gpAdminLogLevel(match[6]),
+		Message: match[7],
+		Fields: []zap.Field{
+			zap.String("severity", match[6]),
+			zap.String("program", match[3]),
+			zap.String("pid", match[2]),
+			zap.String("host", match[4]),
+			zap.String("user", match[5]),
+			zap.String("file", file),
+		},
+	}
+	var err error
+	if entry.Time, err = time.Parse(gpAdminLogTimeLayout, match[1]); err != nil {
+		entry.Time = time.Now()
+	}
+	return entry
+}
+
+func gpAdminLogLevel(level string) zapcore.Level {
+	switch level {
+	case "DEBUG":
+		return zapcore.DebugLevel
+	case "WARNING":
+		return zapcore.WarnLevel
+	case "ERROR", "CRITICAL", "FATAL":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
@@ -0,0 +1,95 @@
+package logship_test
+
+import (
+	"encoding/csv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logship"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const errorRecord = `2020-06-01 12:00:00.123456 UTC,"gpadmin","postgres",p1234,th-12345,"10.0.0.5","51234",2020-06-01 11:59:58 UTC,0,con12,cmd3,seg-1,,dx5,x5,sx1,"ERROR","42P01","relation ""foo"" does not exist",,,,0,,"SELECT * FROM foo;",15,,"parse_relation.c",1159,`
+
+func parseRecord(line string) []string {
+	record, err := csv.NewReader(strings.NewReader(line)).Read()
+	Expect(err).NotTo(HaveOccurred())
+	return record
+}
+
+func withSeverity(severity string) []string {
+	record := parseRecord(errorRecord)
+	record[16] = severity
+	return record
+}
+
+var _ = Describe("ParseCSVRecord", func() {
+	It("parses a server log record", func() {
+		entry := logship.ParseCSVRecord(parseRecord(errorRecord), "/greenplum/data-1/pg_log/gpdb.csv")
+		Expect(entry.Time).To(Equal(time.Date(2020, 6, 1, 12, 0, 0, 123456000, time.UTC)))
+		Expect(entry.Level).To(Equal(zapcore.ErrorLevel))
+		Expect(entry.Message).To(Equal(`relation "foo" does not exist`))
+		Expect(entry.Fields).To(Equal([]zap.Field{
+			zap.String("severity", "ERROR"),
+			zap.String("sqlState", "42P01"),
+			zap.String("user", "gpadmin"),
+			zap.String("database", "postgres"),
+			zap.String("pid", "p1234"),
+			zap.String("remoteHost", "10.0.0.5"),
+			zap.String("session", "con12"),
+			zap.String("commandCount", "cmd3"),
+			zap.String("segment", "seg-1"),
+			zap.String("distributedTransaction", "dx5"),
+			zap.String("query", "SELECT * FROM foo;"),
+			zap.String("file", "/greenplum/data-1/pg_log/gpdb.csv"),
+		}))
+	})
+
+	DescribeTable("maps the severity to a level",
+		func(severity string, level zapcore.Level) {
+			Expect(logship.ParseCSVRecord(withSeverity(severity), "gpdb.csv").Level).To(Equal(level))
+		},
+		Entry("DEBUG1", "DEBUG1", zapcore.DebugLevel),
+		Entry("LOG", "LOG", zapcore.InfoLevel),
+		Entry("NOTICE", "NOTICE", zapcore.InfoLevel),
+		Entry("WARNING", "WARNING", zapcore.WarnLevel),
+		Entry("FATAL", "FATAL", zapcore.ErrorLevel),
+		Entry("PANIC", "PANIC", zapcore.ErrorLevel),
+	)
+
+	It("keeps a record that is not a server log record", func() {
+		entry := logship.ParseCSVRecord([]string{"not", "a log"}, "gpdb.csv")
+		Expect(entry.Level).To(Equal(zapcore.InfoLevel))
+		Expect(entry.Message).To(Equal("not,a log"))
+		Expect(entry.Fields).To(Equal([]zap.Field{zap.String("file", "gpdb.csv")}))
+	})
+})
+
+var _ = Describe("ParseGpAdminLogLine", func() {
+	It("parses a utility log line", func() {
+		line := "20200601:12:00:05:001234 gpstart:master-0:gpadmin-[WARNING]:-Standby master not configured"
+		entry := logship.ParseGpAdminLogLine(line, "gpstart_20200601.log")
+		Expect(entry.Time).To(Equal(time.Date(2020, 6, 1, 12, 0, 5, 0, time.UTC)))
+		Expect(entry.Level).To(Equal(zapcore.WarnLevel))
+		Expect(entry.Message).To(Equal("Standby master not configured"))
+		Expect(entry.Fields).To(Equal([]zap.Field{
+			zap.String("severity", "WARNING"),
+			zap.String("program", "gpstart"),
+			zap.String("pid", "001234"),
+			zap.String("host", "master-0"),
+			zap.String("user", "gpadmin"),
+			zap.String("file", "gpstart_20200601.log"),
+		}))
+	})
+
+	It("keeps a line that is not in the utility log format", func() {
+		entry := logship.ParseGpAdminLogLine("Traceback (most recent call last):", "gpstart_20200601.log")
+		Expect(entry.Level).To(Equal(zapcore.InfoLevel))
+		Expect(entry.Message).To(Equal("Traceback (most recent call last):"))
+		Expect(entry.Fields).To(Equal([]zap.Field{zap.String("file", "gpstart_20200601.log")}))
+	})
+})
@@ -0,0 +1,13 @@
+package logship_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogship(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logship Suite")
+}
@@ -0,0 +1,217 @@
+// Package logship tails the Greenplum server and utility logs, and writes each entry as a JSON line
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/gplog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Format int
+
+const (
+	// FormatCSV is the CSV format of the server logs in pg_log
+	FormatCSV Format = iota
+	// FormatGpAdminLog is the line format of the management utility logs in gpAdminLogs
+	FormatGpAdminLog
+)
+
+// Source is a directory of log files to tail
+type Source struct {
+	// Name of the logger of the entries, such as pg_log
+	Name    string
+	Dir     string
+	Pattern string
+	Format  Format
+}
+
+const (
+	// maxReadPerPoll bounds the memory used to catch up on a large file
+	maxReadPerPoll = 4 << 20
+	// maxPending bounds the incomplete entry that is kept until the rest of it is written.
+	// A longer entry is shipped as it is.
+	maxPending = 1 << 20
+)
+
+// Shipper writes the entries that are appended to the files of its sources to Out
+type Shipper struct {
+	Fs           vfs.Filesystem
+	Out          io.Writer
+	Sources      []Source
+	PollInterval time.Duration
+
+	core  zapcore.Core
+	files map[string]*tailedFile
+}
+
+type tailedFile struct {
+	source  *Source
+	offset  int64
+	pending []byte
+	seen    bool
+}
+
+func (s *Shipper) Run(ctx context.Context) error {
+	s.Start()
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.Poll()
+			return nil
+		case <-ticker.C:
+			s.Poll()
+		}
+	}
+}
+
+// Start skips the files that already exist to their end, so that a restarted container does
+// not ship their entries again. Files created later are shipped from their beginning.
+func (s *Shipper) Start() {
+	s.core = zapcore.NewCore(zapcore.NewJSONEncoder(gplog.NewEncoderConfig()), zapcore.AddSync(s.Out), zapcore.DebugLevel)
+	s.files = map[string]*tailedFile{}
+	for i := range s.Sources {
+		source := &s.Sources[i]
+		for path, info := range s.list(source) {
+			s.files[path] = &tailedFile{source: source, offset: info.Size()}
+		}
+	}
+}
+
+// Poll ships the entries written since the last poll. Errors, such as a directory that does not exist
+// until the cluster is initialized, are retried on the next poll.
+func (s *Shipper) Poll() {
+	for _, file := range s.files {
+		file.seen = false
+	}
+	for i := range s.Sources {
+		source := &s.Sources[i]
+		for path, info := range s.list(source) {
+			file, ok := s.files[path]
+			if !ok {
+				file = &tailedFile{source: source}
+				s.files[path] = file
+			}
+			file.seen = true
+			if info.Size() < file.offset {
+				// Truncated: start over
+				file.offset = 0
+				file.pending = nil
+			}
+			if info.Size() > file.offset {
+				s.ship(path, file)
+			}
+		}
+	}
+	for path, file := range s.files {
+		if !file.seen {
+			delete(s.files, path)
+		}
+	}
+}
+
+func (s *Shipper) list(source *Source) map[string]os.FileInfo {
+	infos, err := s.Fs.ReadDir(source.Dir)
+	if err != nil {
+		return nil
+	}
+	files := map[string]os.FileInfo{}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if matched, _ := filepath.Match(source.Pattern, info.Name()); matched {
+			files[filepath.Join(source.Dir, info.Name())] = info
+		}
+	}
+	return files
+}
+
+func (s *Shipper) ship(path string, file *tailedFile) {
+	f, err := s.Fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(file.offset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxReadPerPoll))
+	if err != nil {
+		return
+	}
+	file.offset += int64(len(data))
+
+	data = append(file.pending, data...)
+	// Only complete lines are parsed. The rest is parsed once it is written.
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	var entries []Entry
+	consumed := complete
+	switch file.source.Format {
+	case FormatCSV:
+		entries, consumed = parseCSV(data[:complete], path)
+	default:
+		entries = parseGpAdminLog(data[:complete], path)
+	}
+	file.pending = append([]byte(nil), data[consumed:]...)
+	if len(file.pending) > maxPending {
+		entries = append(entries, Entry{
+			Time:    time.Now(),
+			Level:   zapcore.InfoLevel,
+			Message: string(file.pending),
+			Fields:  []zap.Field{zap.String("file", path)},
+		})
+		file.pending = nil
+	}
+
+	for _, entry := range entries {
+		s.write(file.source.Name, entry)
+	}
+}
+
+// parseCSV parses the records of data, and returns how many bytes they take. A record with a quoted
+// field that spans lines may not be complete yet; it is left for the next poll.
+func parseCSV(data []byte, path string) (entries []Entry, consumed int) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			break
+		}
+		entries = append(entries, ParseCSVRecord(record, path))
+		consumed = int(reader.InputOffset())
+	}
+	return entries, consumed
+}
+
+func parseGpAdminLog(data []byte, path string) (entries []Entry) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entries = append(entries, ParseGpAdminLogLine(line, path))
+	}
+	return entries
+}
+
+func (s *Shipper) write(name string, entry Entry) {
+	// Writing to stdout only fails if the container runtime stopped reading it
+	_ = s.core.Write(zapcore.Entry{
+		LoggerName: name,
+		Time:       entry.Time,
+		Level:      entry.Level,
+		Message:    entry.Message,
+	}, entry.Fields)
+}
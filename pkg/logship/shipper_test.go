@@ -0,0 +1,146 @@
+package logship_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/logship"
+)
+
+const logRecord = `2020-06-01 12:00:01.000000 UTC,"gpadmin","postgres",p1234,th-12345,"10.0.0.5","51234",2020-06-01 11:59:58 UTC,0,con12,cmd4,seg-1,,,,,"LOG","00000","statement: SELECT 1",,,,0,,,0,,"postgres.c",1639,`
+
+var _ = Describe("Shipper", func() {
+	var (
+		fs      vfs.Filesystem
+		out     *bytes.Buffer
+		shipper *logship.Shipper
+	)
+
+	appendFile := func(path, data string) {
+		f, err := fs.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.Write([]byte(data))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+	}
+
+	shipped := func() (entries []map[string]interface{}) {
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed(), line)
+			entries = append(entries, entry)
+		}
+		out.Reset()
+		return entries
+	}
+
+	BeforeEach(func() {
+		fs = memfs.Create()
+		Expect(vfs.MkdirAll(fs, "/greenplum/data-1/pg_log", 0755)).To(Succeed())
+		out = &bytes.Buffer{}
+		shipper = &logship.Shipper{
+			Fs:  fs,
+			Out: out,
+			Sources: []logship.Source{
+				{Name: "pg_log", Dir: "/greenplum/data-1/pg_log", Pattern: "*.csv", Format: logship.FormatCSV},
+				{Name: "gpAdminLogs", Dir: "/home/gpadmin/gpAdminLogs", Pattern: "*.log", Format: logship.FormatGpAdminLog},
+			},
+		}
+	})
+
+	It("writes each appended server log entry as a JSON line", func() {
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", "")
+		shipper.Start()
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", errorRecord+"\n"+logRecord+"\n")
+		shipper.Poll()
+
+		entries := shipped()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0]).To(HaveKeyWithValue("ts", "2020-06-01T12:00:00.123Z"))
+		Expect(entries[0]).To(HaveKeyWithValue("level", "ERROR"))
+		Expect(entries[0]).To(HaveKeyWithValue("logger", "pg_log"))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", `relation "foo" does not exist`))
+		Expect(entries[0]).To(HaveKeyWithValue("severity", "ERROR"))
+		Expect(entries[0]).To(HaveKeyWithValue("session", "con12"))
+		Expect(entries[0]).To(HaveKeyWithValue("segment", "seg-1"))
+		Expect(entries[0]).To(HaveKeyWithValue("file", "/greenplum/data-1/pg_log/gpdb.csv"))
+		Expect(entries[1]).To(HaveKeyWithValue("level", "INFO"))
+		Expect(entries[1]).To(HaveKeyWithValue("msg", "statement: SELECT 1"))
+	})
+
+	It("does not ship the entries of files that existed when it started", func() {
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", errorRecord+"\n")
+		shipper.Start()
+		shipper.Poll()
+		Expect(shipped()).To(BeEmpty())
+
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", logRecord+"\n")
+		shipper.Poll()
+		entries := shipped()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", "statement: SELECT 1"))
+	})
+
+	It("ships a file created after it started from its beginning", func() {
+		shipper.Start()
+		appendFile("/greenplum/data-1/pg_log/gpdb-2020-06-02.csv", logRecord+"\n")
+		appendFile("/greenplum/data-1/pg_log/ignored.txt", "not a log\n")
+		shipper.Poll()
+		Expect(shipped()).To(HaveLen(1))
+	})
+
+	It("waits for the rest of a record that spans lines", func() {
+		shipper.Start()
+		record := strings.Replace(logRecord, "statement: SELECT 1", "statement: SELECT 1\nFROM foo", 1)
+		lines := strings.SplitAfterN(record, "\n", 2)
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", lines[0])
+		shipper.Poll()
+		Expect(shipped()).To(BeEmpty())
+
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", lines[1][:10])
+		shipper.Poll()
+		Expect(shipped()).To(BeEmpty())
+
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", lines[1][10:]+"\n")
+		shipper.Poll()
+		entries := shipped()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", "statement: SELECT 1\nFROM foo"))
+	})
+
+	It("starts over on a truncated file", func() {
+		appendFile("/greenplum/data-1/pg_log/gpdb.csv", errorRecord+"\n")
+		shipper.Start()
+		Expect(vfs.WriteFile(fs, "/greenplum/data-1/pg_log/gpdb.csv", []byte(logRecord+"\n"), 0644)).To(Succeed())
+		shipper.Poll()
+		entries := shipped()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", "statement: SELECT 1"))
+	})
+
+	It("ships the utility logs once their directory is created", func() {
+		shipper.Start()
+		shipper.Poll()
+		Expect(vfs.MkdirAll(fs, "/home/gpadmin/gpAdminLogs", 0755)).To(Succeed())
+		appendFile("/home/gpadmin/gpAdminLogs/gpstart_20200601.log",
+			"20200601:12:00:05:001234 gpstart:master-0:gpadmin-[INFO]:-Starting gpstart\n"+
+				"20200601:12:00:06:001234 gpstart:master-0:gpadmin-[ERROR]:-Failed to start segment\n")
+		shipper.Poll()
+
+		entries := shipped()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0]).To(HaveKeyWithValue("logger", "gpAdminLogs"))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", "Starting gpstart"))
+		Expect(entries[0]).To(HaveKeyWithValue("program", "gpstart"))
+		Expect(entries[1]).To(HaveKeyWithValue("level", "ERROR"))
+	})
+})
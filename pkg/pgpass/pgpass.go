@@ -0,0 +1,20 @@
+// Package pgpass formats the password file that psql and other libpq clients read for gpadmin.
+package pgpass
+
+import "strings"
+
+const (
+	// SecretMountPath is where the gpadmin password Secret is mounted in Greenplum pods
+	SecretMountPath = "/etc/gpadmin-password"
+	// PasswordKey is the key of the password in the Secret, and its file name under SecretMountPath
+	PasswordKey = "password"
+	// Path is the password file of gpadmin
+	Path = "/home/gpadmin/.pgpass"
+)
+
+var escaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`)
+
+// Entry returns a password file line that gives the password of user for any host, port and database.
+func Entry(user, password string) string {
+	return "*:*:*:" + escaper.Replace(user) + ":" + escaper.Replace(password) + "\n"
+}
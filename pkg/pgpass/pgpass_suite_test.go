@@ -0,0 +1,13 @@
+package pgpass_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPgpass(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pgpass Suite")
+}
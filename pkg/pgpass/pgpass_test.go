@@ -0,0 +1,16 @@
+package pgpass_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/greenplum-for-kubernetes/pkg/pgpass"
+)
+
+var _ = Describe("Entry", func() {
+	It("matches any host, port and database", func() {
+		Expect(pgpass.Entry("gpadmin", "s3cret")).To(Equal("*:*:*:gpadmin:s3cret\n"))
+	})
+	It("escapes colons and backslashes", func() {
+		Expect(pgpass.Entry("gpadmin", `a:b\c`)).To(Equal(`*:*:*:gpadmin:a\:b\\c` + "\n"))
+	})
+})